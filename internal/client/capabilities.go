@@ -0,0 +1,118 @@
+package restclient
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+type capability struct {
+	// allowed: the methods allowed by the API, nil if unknown
+	allowed text.StringSet
+	expires time.Time
+}
+
+// CapabilityCache caches the methods the API allows on the operation paths, as told by the Allow header of
+// the responses to OPTIONS, so that the methods described by the OAS but not allowed by the API are detected.
+type CapabilityCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]capability
+}
+
+// NewCapabilityCache returns the cache of the methods allowed by the APIs, probed again once older than ttl.
+func NewCapabilityCache(ttl time.Duration) *CapabilityCache {
+	return &CapabilityCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]capability{},
+	}
+}
+
+// get returns the cached capability of the operation path of the server, ok is false if not cached or expired.
+func (c *CapabilityCache) get(key string) (capability, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !c.now().Before(entry.expires) {
+		return capability{}, false
+	}
+	return entry, true
+}
+
+func (c *CapabilityCache) set(key string, allowed text.StringSet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = capability{allowed: allowed, expires: c.now().Add(c.ttl)}
+}
+
+// Probe sends an OPTIONS request to the path of the operation with the given method, caching the methods
+// the API allows on it in the Capabilities of the client, unless already cached. The methods are unknown,
+// thus all allowed, if the API does not answer with an Allow header (e.g. if it does not support OPTIONS).
+func (u *UnstructuredClient) Probe(ctx context.Context, cli *http.Client, method string, path string, opts *RequestConfiguration) {
+	if u.Capabilities == nil {
+		return
+	}
+	key := u.Server + path
+	if _, ok := u.Capabilities.get(key); ok {
+		return
+	}
+
+	var pathItem *v3.PathItem
+	if u.DocScheme != nil {
+		pathItem, _ = u.DocScheme.Model.Paths.PathItems.Get(path)
+	}
+	uri := buildURL(u.Server, path, pathItem, method, opts)
+	req, err := http.NewRequestWithContext(ctx, http.MethodOptions, uri.String(), nil)
+	if err != nil {
+		return
+	}
+	u.setRequestHeaders(req, opts)
+	if u.Auth != nil {
+		u.Auth.SetAuth(req)
+	}
+
+	var allowed text.StringSet
+	res, err := u.recordHeaders(cli).Do(req)
+	if err == nil {
+		res.Body.Close()
+		if res.StatusCode >= 200 && res.StatusCode < 300 {
+			allowed = parseAllow(res.Header.Get("Allow"))
+		}
+	}
+	u.Capabilities.set(key, allowed)
+}
+
+// Allows returns false if the probe of the path told the API does not allow the method on it,
+// true if it does or if the methods allowed are unknown.
+func (u *UnstructuredClient) Allows(method string, path string) bool {
+	if u.Capabilities == nil {
+		return true
+	}
+	entry, ok := u.Capabilities.get(u.Server + path)
+	if !ok || entry.allowed == nil {
+		return true
+	}
+	return entry.allowed.Contains(strings.ToUpper(method))
+}
+
+// parseAllow returns the methods listed by the Allow header, nil if none.
+func parseAllow(header string) text.StringSet {
+	var allowed text.StringSet
+	for _, method := range strings.Split(header, ",") {
+		if method = strings.ToUpper(strings.TrimSpace(method)); method != "" {
+			if allowed == nil {
+				allowed = text.NewStringSet()
+			}
+			allowed.Add(method)
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,66 @@
+package restclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProbe(t *testing.T) {
+	probes := 0
+	var probed string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		probes++
+		probed = r.URL.Path
+		if r.URL.Path == "/apps/legacy" {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		w.Header().Set("Allow", "GET, put,DELETE")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	u, err := parseDocument([]byte(deleteBodyOAS))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u.Server = srv.URL
+	if !u.Allows(http.MethodPatch, "/apps/{id}/scopes") {
+		t.Errorf("expected every method to be allowed without capabilities")
+	}
+
+	u.Capabilities = NewCapabilityCache(time.Hour)
+	now := time.Now()
+	u.Capabilities.now = func() time.Time { return now }
+	opts := &RequestConfiguration{Parameters: map[string]string{"id": "1"}}
+	for i := 0; i < 2; i++ {
+		u.Probe(context.Background(), srv.Client(), http.MethodDelete, "/apps/{id}/scopes", opts)
+	}
+	if probes != 1 || probed != "/apps/1/scopes" {
+		t.Errorf("expected a single probe of /apps/1/scopes, got %d of %s", probes, probed)
+	}
+	if u.Allows(http.MethodPatch, "/apps/{id}/scopes") || !u.Allows("put", "/apps/{id}/scopes") {
+		t.Errorf("expected the methods of the Allow header to be allowed only")
+	}
+
+	now = now.Add(time.Hour)
+	if !u.Allows(http.MethodPatch, "/apps/{id}/scopes") {
+		t.Errorf("expected the capabilities to expire")
+	}
+	u.Probe(context.Background(), srv.Client(), http.MethodDelete, "/apps/{id}/scopes", opts)
+	if probes != 2 {
+		t.Errorf("expected the path to be probed again once expired, got %d probes", probes)
+	}
+
+	u.Probe(context.Background(), srv.Client(), http.MethodGet, "/apps/legacy", &RequestConfiguration{})
+	if !u.Allows(http.MethodPatch, "/apps/legacy") {
+		t.Errorf("expected every method to be allowed if the API does not support OPTIONS")
+	}
+}
@@ -1,9 +1,12 @@
 package restclient
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -14,6 +17,7 @@ import (
 
 	stringset "github.com/krateoplatformops/rest-dynamic-controller/internal/text"
 	fgetter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/filegetter"
+	"github.com/lucasepe/httplib"
 	"github.com/pb33f/libopenapi"
 	"github.com/pb33f/libopenapi/datamodel/high/base"
 	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
@@ -31,6 +35,7 @@ const (
 	APICallsTypePatch  APICallType = "patch"
 	APICallsTypeFindBy APICallType = "findby"
 	APICallsTypePut    APICallType = "put"
+	APICallsTypeHead   APICallType = "head"
 )
 
 func (a APICallType) String() string {
@@ -53,6 +58,8 @@ func StringToApiCallType(ty string) (APICallType, error) {
 		return APICallsTypeFindBy, nil
 	case "put":
 		return APICallsTypePut, nil
+	case "head":
+		return APICallsTypeHead, nil
 	}
 	return "", fmt.Errorf("unknown api call type: %s", ty)
 }
@@ -62,6 +69,8 @@ type AuthType string
 const (
 	AuthTypeBasic  AuthType = "basic"
 	AuthTypeBearer AuthType = "bearer"
+	// AuthTypeWorkloadIdentity exchanges a token of the ServiceAccount of the controller for an API token
+	AuthTypeWorkloadIdentity AuthType = "workloadIdentity"
 )
 
 func (a AuthType) String() string {
@@ -73,18 +82,40 @@ func ToType(ty string) (AuthType, error) {
 		return AuthTypeBasic, nil
 	case "bearer":
 		return AuthTypeBearer, nil
+	case "workloadIdentity":
+		return AuthTypeWorkloadIdentity, nil
 	}
 	return "", fmt.Errorf("unknown auth type: %s", ty)
 }
 
+// fromJSON decodes the JSON response body into v, decoding the numbers as json.Number
+// so that large integers (e.g. 64-bit IDs) do not lose precision through float64.
+func fromJSON(v any) httplib.HandleResponseFunc {
+	return func(res *http.Response) error {
+		data, err := io.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		return dec.Decode(v)
+	}
+}
+
 func (e *APIError) Error() string {
+	if e.Message == "" && len(e.Errors) > 0 {
+		msgs := make([]string, 0, len(e.Errors))
+		for _, err := range e.Errors {
+			msgs = append(msgs, err.String())
+		}
+		return fmt.Sprintf("error: %s", strings.Join(msgs, "; "))
+	}
 	return fmt.Sprintf("error: %s (%s, %d)", e.Message, e.TypeKey, e.EventID)
 }
 
+// buildPath builds the URL replacing the path parameters, whose values must be already escaped.
 func buildPath(baseUrl string, path string, parameters map[string]string, query map[string]string) *url.URL {
-	for key, param := range parameters {
-		path = strings.Replace(path, fmt.Sprintf("{%s}", key), fmt.Sprintf("%v", param), 1)
-	}
+	path = expandPath(path, parameters)
 
 	params := url.Values{}
 
@@ -96,7 +127,13 @@ func buildPath(baseUrl string, path string, parameters map[string]string, query
 	if err != nil {
 		return nil
 	}
-	parsed.Path = parsed.Path + path
+	unescaped, err := url.PathUnescape(path)
+	if err != nil {
+		return nil
+	}
+	// keeping the escaped path so that the encoded slashes in the parameters are preserved
+	parsed.RawPath = parsed.EscapedPath() + path
+	parsed.Path = parsed.Path + unescaped
 	parsed.RawQuery = params.Encode()
 	return parsed
 }
@@ -117,18 +154,51 @@ func getValidResponseCode(codes *orderedmap.Map[string, *v3.Response]) ([]int, e
 	return validCodes, nil
 }
 
+// getOperation returns the operation of the path item for the given method.
+// HEAD operations not described in the OAS fall back to the GET ones, since
+// HEAD requests are identical to GET ones except for the response body.
+func getOperation(pathItem *v3.PathItem, httpMethod string) (*v3.Operation, bool) {
+	op, ok := pathItem.GetOperations().Get(strings.ToLower(httpMethod))
+	if !ok && strings.EqualFold(httpMethod, http.MethodHead) {
+		return pathItem.GetOperations().Get("get")
+	}
+	return op, ok
+}
+
+// CheckOperation returns an error if the OAS does not describe the operation with the given method and path.
+func (u *UnstructuredClient) CheckOperation(httpMethod string, path string) error {
+	pathItem, ok := u.DocScheme.Model.Paths.PathItems.Get(path)
+	if !ok {
+		return fmt.Errorf("path not found: %s", path)
+	}
+	if _, ok := getOperation(pathItem, httpMethod); !ok {
+		return fmt.Errorf("operation not found: %s %s", strings.ToUpper(httpMethod), path)
+	}
+	return nil
+}
+
+// validateRequest validates the request parameters against the OAS, requests sent to
+// a URL returned by the API (e.g. a link to the resource) are not validated.
+func (u *UnstructuredClient) validateRequest(httpMethod string, path string, opts *RequestConfiguration) error {
+	if opts.URL != "" {
+		return checkLink(u.Server, opts.URL)
+	}
+	return u.ValidateRequest(httpMethod, path, opts.Parameters, opts.Query)
+}
+
 func (u *UnstructuredClient) ValidateRequest(httpMethod string, path string, parameters map[string]string, query map[string]string) error {
 	pathItem, ok := u.DocScheme.Model.Paths.PathItems.Get(path)
 	if !ok {
 		return fmt.Errorf("path not found: %s", path)
 	}
-	getDoc, ok := pathItem.GetOperations().Get(strings.ToLower(httpMethod))
+	getDoc, ok := getOperation(pathItem, httpMethod)
 	if !ok {
 		return fmt.Errorf("operation not found: %s", httpMethod)
 	}
+	exprs := pathExprs(path)
 	for _, param := range getDoc.Parameters {
 		if param.Required != nil && *param.Required {
-			if param.In == "path" {
+			if param.In == "path" && !exprs[param.Name].optional {
 				if _, ok := parameters[param.Name]; !ok {
 					return fmt.Errorf("missing path parameter: %s", param.Name)
 				}
@@ -144,29 +214,20 @@ func (u *UnstructuredClient) ValidateRequest(httpMethod string, path string, par
 }
 
 func (u *UnstructuredClient) RequestedBody(httpMethod string, path string) (bodyParams stringset.StringSet, err error) {
-	pathItem, ok := u.DocScheme.Model.Paths.PathItems.Get(path)
-	if !ok {
-		return nil, fmt.Errorf("path not found: %s", path)
-	}
-	getDoc, ok := pathItem.GetOperations().Get(strings.ToLower(httpMethod))
-	if !ok {
-		return nil, fmt.Errorf("operation not found: %s", httpMethod)
+	schema, ok, err := u.requestBodySchema(httpMethod, path)
+	if err != nil || !ok {
+		return nil, err
 	}
 	bodyParams = stringset.NewStringSet()
-	if getDoc.RequestBody == nil {
-		return nil, nil
-	}
-	bodySchema, ok := getDoc.RequestBody.Content.Get("application/json")
-	if !ok {
+	if schema == nil {
 		return bodyParams, nil
 	}
-	schema, err := bodySchema.Schema.BuildSchema()
-	if err != nil {
-		return nil, fmt.Errorf("building schema for %s: %w", path, err)
-	}
-	populateFromAllOf(schema)
 
 	for sch := schema.Properties.First(); sch != nil; sch = sch.Next() {
+		// The read-only properties are never sent
+		if prop := sch.Value().Schema(); prop != nil && prop.ReadOnly != nil && *prop.ReadOnly {
+			continue
+		}
 		bodyParams.Add(sch.Key())
 	}
 
@@ -185,40 +246,158 @@ func (u *UnstructuredClient) RequestedBody(httpMethod string, path string) (body
 	return bodyParams, nil
 }
 
-// func PopulateFromAllOf() is a method that populates the schema with the properties from the allOf field.
-// the recursive function to populate the schema with the properties from the allOf field.
-func populateFromAllOf(schema *base.Schema) {
-	if len(schema.Type) > 0 && schema.Type[0] == "array" {
-		if schema.Items != nil {
-			if schema.Items.N == 0 {
-				sch, err := schema.Items.A.BuildSchema()
-				if err != nil {
-					return
-				}
+// requestBodySchema returns the JSON schema of the request body of the operation, ok is false
+// if the operation has no request body and the schema is nil if the body is not JSON.
+func (u *UnstructuredClient) requestBodySchema(httpMethod string, path string) (schema *base.Schema, ok bool, err error) {
+	pathItem, ok := u.DocScheme.Model.Paths.PathItems.Get(path)
+	if !ok {
+		return nil, false, fmt.Errorf("path not found: %s", path)
+	}
+	getDoc, ok := pathItem.GetOperations().Get(strings.ToLower(httpMethod))
+	if !ok {
+		return nil, false, fmt.Errorf("operation not found: %s", httpMethod)
+	}
+	if getDoc.RequestBody == nil {
+		return nil, false, nil
+	}
+	bodySchema, ok := getDoc.RequestBody.Content.Get("application/json")
+	if !ok {
+		return nil, true, nil
+	}
+	schema, err = buildSchema(bodySchema.Schema)
+	if err != nil {
+		return nil, false, fmt.Errorf("building schema for %s: %w", path, err)
+	}
+	return mergeAllOf(schema, 0), true, nil
+}
+
+// WriteOnlyFields returns the dot separated paths of the write-only properties of the request body,
+// which are never returned by the API.
+func (u *UnstructuredClient) WriteOnlyFields(httpMethod string, path string) (stringset.StringSet, error) {
+	return u.requestBodyFields(httpMethod, path, isWriteOnly)
+}
+
+// CreateOnlyFields returns the dot separated paths of the properties of the request body marked
+// with the x-create-only (or x-immutable) extension, which are accepted at creation but rejected on update.
+func (u *UnstructuredClient) CreateOnlyFields(httpMethod string, path string) (stringset.StringSet, error) {
+	return u.requestBodyFields(httpMethod, path, isCreateOnly)
+}
+
+// requestBodyFields returns the dot separated paths of the properties of the request body matching the schema predicate.
+func (u *UnstructuredClient) requestBodyFields(httpMethod string, path string, match func(*base.Schema) bool) (stringset.StringSet, error) {
+	schema, _, err := u.requestBodySchema(httpMethod, path)
+	if err != nil {
+		return nil, err
+	}
+	fields := stringset.NewStringSet()
+	if schema != nil {
+		collectFields(schema, "", fields, match, 0)
+	}
+	return fields, nil
+}
 
-				populateFromAllOf(sch)
+// maxSchemaDepth limits the visit of the recursive schemas
+const maxSchemaDepth = 16
+
+func collectFields(schema *base.Schema, prefix string, fields stringset.StringSet, match func(*base.Schema) bool, depth int) {
+	if depth > maxSchemaDepth {
+		return
+	}
+	if schema.Items != nil && schema.Items.IsA() {
+		if items := schema.Items.A.Schema(); items != nil {
+			collectFields(items, prefix, fields, match, depth+1)
+		}
+	}
+	for prop := schema.Properties.First(); prop != nil; prop = prop.Next() {
+		propSchema := prop.Value().Schema()
+		if propSchema == nil {
+			continue
+		}
+		name := prop.Key()
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		if match(propSchema) {
+			fields.Add(name)
+			continue
+		}
+		collectFields(propSchema, name, fields, match, depth+1)
+	}
+}
+
+func isWriteOnly(schema *base.Schema) bool {
+	return schema.WriteOnly != nil && *schema.WriteOnly
+}
+
+func isCreateOnly(schema *base.Schema) bool {
+	if schema.Extensions == nil {
+		return false
+	}
+	for _, name := range []string{"x-create-only", "x-immutable"} {
+		if node, ok := schema.Extensions.Get(name); ok && node != nil && node.Value == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// buildSchema returns the schema of the proxy. Unlike BuildSchema, the schema is built under the lock of the
+// proxy, whose document may be shared by the clients built from the cache.
+func buildSchema(proxy *base.SchemaProxy) (*base.Schema, error) {
+	schema := proxy.Schema()
+	if schema == nil {
+		return nil, fmt.Errorf("invalid schema")
+	}
+	return schema, nil
+}
+
+// mergeAllOf returns a copy of the schema holding, at every level, the properties of its allOf schemas as well.
+// The schemas of the document are never modified, since the document may be shared by the clients built from the cache.
+func mergeAllOf(schema *base.Schema, depth int) *base.Schema {
+	if schema == nil || depth > maxSchemaDepth {
+		return schema
+	}
+	merged := *schema
+	if len(schema.Type) > 0 && schema.Type[0] == "array" {
+		if schema.Items != nil && schema.Items.IsA() {
+			items, err := buildSchema(schema.Items.A)
+			if err != nil {
+				return schema
 			}
+			merged.Items = &base.DynamicValue[*base.SchemaProxy, bool]{A: base.CreateSchemaProxy(mergeAllOf(items, depth+1))}
 		}
-		return
+		return &merged
+	}
+
+	var properties *orderedmap.Map[string, *base.SchemaProxy]
+	set := func(name string, proxy *base.SchemaProxy) {
+		if properties == nil {
+			properties = orderedmap.New[string, *base.SchemaProxy]()
+		}
+		properties.Set(name, proxy)
 	}
 	for prop := schema.Properties.First(); prop != nil; prop = prop.Next() {
-		populateFromAllOf(prop.Value().Schema())
+		proxy := prop.Value()
+		if propSchema := proxy.Schema(); propSchema != nil {
+			proxy = base.CreateSchemaProxy(mergeAllOf(propSchema, depth+1))
+		}
+		set(prop.Key(), proxy)
 	}
 	for _, proxy := range schema.AllOf {
-		propSchema, err := proxy.BuildSchema()
-		populateFromAllOf(propSchema)
+		sub, err := buildSchema(proxy)
 		if err != nil {
-			return
+			break
 		}
-		// Iterate over the properties of the schema with First() and Next()
-		for prop := propSchema.Properties.First(); prop != nil; prop = prop.Next() {
-			if schema.Properties == nil {
-				schema.Properties = orderedmap.New[string, *base.SchemaProxy]()
-			}
-			// Add the property to the schema
-			schema.Properties.Set(prop.Key(), prop.Value())
+		// The properties of the merged schema are copies already
+		sub = mergeAllOf(sub, depth+1)
+		for prop := sub.Properties.First(); prop != nil; prop = prop.Next() {
+			set(prop.Key(), prop.Value())
 		}
 	}
+	if properties != nil {
+		merged.Properties = properties
+	}
+	return &merged
 }
 
 func (u *UnstructuredClient) RequestedParams(httpMethod string, path string) (parameters stringset.StringSet, query stringset.StringSet, err error) {
@@ -226,7 +405,7 @@ func (u *UnstructuredClient) RequestedParams(httpMethod string, path string) (pa
 	if !ok {
 		return nil, nil, fmt.Errorf("path not found: %s", path)
 	}
-	getDoc, ok := pathItem.GetOperations().Get(strings.ToLower(httpMethod))
+	getDoc, ok := getOperation(pathItem, httpMethod)
 	if !ok {
 		return nil, nil, fmt.Errorf("operation not found: %s", httpMethod)
 	}
@@ -244,7 +423,13 @@ func (u *UnstructuredClient) RequestedParams(httpMethod string, path string) (pa
 }
 
 // BuildClient is a function that builds partial client from a swagger file.
+// The documents fetched over HTTP or read from a ConfigMap are cached until they change (see DocumentCache).
 func BuildClient(ctx context.Context, kubeclient dynamic.Interface, swaggerPath string) (*UnstructuredClient, error) {
+	return documents.Build(ctx, kubeclient, swaggerPath)
+}
+
+// buildFromFile builds the partial client from a swagger file read from the filesystem.
+func buildFromFile(ctx context.Context, kubeclient dynamic.Interface, swaggerPath string) (*UnstructuredClient, error) {
 	basePath := "/tmp/rest-dynamic-controller"
 	err := os.MkdirAll(basePath, 0755)
 	defer os.RemoveAll(basePath)
@@ -263,6 +448,24 @@ func BuildClient(ctx context.Context, kubeclient dynamic.Interface, swaggerPath
 	}
 
 	contents, _ := os.ReadFile(filepath.Join(basePath, path.Base(swaggerPath)))
+	return parseDocument(contents)
+}
+
+// parseDocument builds the partial client from the contents of the OAS document.
+func parseDocument(contents []byte) (*UnstructuredClient, error) {
+	doc, err := parseModel(contents)
+	if err != nil {
+		return nil, err
+	}
+	return &UnstructuredClient{
+		Server:    doc.Model.Servers[0].URL,
+		DocScheme: doc,
+		Auth:      nil,
+	}, nil
+}
+
+// parseModel parses the OAS document, resolving its references.
+func parseModel(contents []byte) (*libopenapi.DocumentModel[v3.Document], error) {
 	d, err := libopenapi.NewDocument(contents)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
@@ -288,10 +491,5 @@ func BuildClient(ctx context.Context, kubeclient dynamic.Interface, swaggerPath
 	if len(doc.Model.Servers) == 0 {
 		return nil, fmt.Errorf("no servers found in the document")
 	}
-
-	return &UnstructuredClient{
-		Server:    doc.Model.Servers[0].URL,
-		DocScheme: doc,
-		Auth:      nil,
-	}, nil
+	return doc, nil
 }
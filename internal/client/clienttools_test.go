@@ -0,0 +1,250 @@
+package restclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestFromJSON(t *testing.T) {
+	res := &http.Response{}
+	res.Body = io.NopCloser(strings.NewReader(`{"id": 1234567890123456789, "price": 42.5, "items": [{"id": 9007199254740993}]}`))
+
+	var body map[string]interface{}
+	if err := fromJSON(&body)(res); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body["id"] != json.Number("1234567890123456789") {
+		t.Errorf("expected the big int to be preserved, got %v", body["id"])
+	}
+	if body["price"] != json.Number("42.5") {
+		t.Errorf("expected the float to be preserved, got %v", body["price"])
+	}
+	item := body["items"].([]interface{})[0].(map[string]interface{})
+	if item["id"] != json.Number("9007199254740993") {
+		t.Errorf("expected the nested big int to be preserved, got %v", item["id"])
+	}
+}
+
+const writeOnlySpec = `openapi: 3.0.0
+info:
+  title: test
+  version: "1.0"
+paths:
+  /users:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                id:
+                  type: string
+                  readOnly: true
+                name:
+                  type: string
+                password:
+                  type: string
+                  writeOnly: true
+                region:
+                  type: string
+                  x-create-only: true
+                settings:
+                  type: object
+                  properties:
+                    location:
+                      type: string
+                      x-immutable: true
+                    private:
+                      type: boolean
+                keys:
+                  type: array
+                  items:
+                    type: object
+                    properties:
+                      secret:
+                        type: string
+                        writeOnly: true
+      responses:
+        "201":
+          description: created
+`
+
+func TestRequestBodyReadWriteOnly(t *testing.T) {
+	d, err := libopenapi.NewDocument([]byte(writeOnlySpec))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc, errs := d.BuildV3Model()
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	u := &UnstructuredClient{DocScheme: doc}
+
+	body, err := u.RequestedBody("POST", "/users")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body.Contains("id") || !body.Contains("name") || !body.Contains("password") {
+		t.Errorf("expected the read-only fields to be skipped, got %v", body)
+	}
+
+	writeOnly, err := u.WriteOnlyFields("POST", "/users")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(writeOnly) != 2 || !writeOnly.Contains("password") || !writeOnly.Contains("keys.secret") {
+		t.Errorf("unexpected write-only fields: %v", writeOnly)
+	}
+}
+
+func TestCreateOnlyFields(t *testing.T) {
+	d, err := libopenapi.NewDocument([]byte(writeOnlySpec))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc, errs := d.BuildV3Model()
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	u := &UnstructuredClient{DocScheme: doc}
+
+	createOnly, err := u.CreateOnlyFields("POST", "/users")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(createOnly) != 2 || !createOnly.Contains("region") || !createOnly.Contains("settings.location") {
+		t.Errorf("unexpected create-only fields: %v", createOnly)
+	}
+}
+
+func FuzzBuildPath(f *testing.F) {
+	f.Add("https://api.github.com/v3", "/repos/{owner}/{repo}", "owner", "krateo%2Fplatform", "per_page", "100")
+	f.Add("", "", "", "", "", "")
+	f.Add("http://[::1]:80", "/{a.b}/%zz", "a.b", "ключ", "q;x", "a&b=c")
+
+	f.Fuzz(func(t *testing.T, baseUrl, path, param, value, queryKey, queryValue string) {
+		uri := buildPath(baseUrl, path, map[string]string{param: value}, map[string]string{queryKey: queryValue})
+		if uri == nil {
+			return
+		}
+		if got := uri.Query()[queryKey]; len(got) != 1 || got[0] != queryValue {
+			t.Fatalf("expected query %q=%q, got %v", queryKey, queryValue, got)
+		}
+	})
+}
+
+// benchmarkSpec returns an OAS document with the CRUD operations of the given number of resources.
+func benchmarkSpec(resources int) []byte {
+	var sb strings.Builder
+	sb.WriteString("openapi: 3.0.0\ninfo:\n  title: bench\n  version: \"1.0\"\nservers:\n  - url: https://api.example.com\npaths:\n")
+	for i := 0; i < resources; i++ {
+		fmt.Fprintf(&sb, `  /orgs/{org}/resources%[1]d:
+    post:
+      parameters:
+        - name: org
+          in: path
+          required: true
+          schema:
+            type: string
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Resource%[1]d'
+      responses:
+        "201":
+          description: created
+  /orgs/{org}/resources%[1]d/{id}:
+    get:
+      parameters:
+        - name: org
+          in: path
+          required: true
+          schema:
+            type: string
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Resource%[1]d'
+`, i)
+	}
+	sb.WriteString("components:\n  schemas:\n")
+	for i := 0; i < resources; i++ {
+		fmt.Fprintf(&sb, `    Resource%d:
+      type: object
+      properties:
+        id:
+          type: string
+          readOnly: true
+        name:
+          type: string
+        labels:
+          type: object
+          additionalProperties:
+            type: string
+        settings:
+          type: object
+          properties:
+            private:
+              type: boolean
+            tags:
+              type: array
+              items:
+                type: string
+`, i)
+	}
+	return []byte(sb.String())
+}
+
+func BenchmarkParseDocument(b *testing.B) {
+	spec := benchmarkSpec(100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := parseDocument(spec); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFindInItems(b *testing.B) {
+	items := make([]interface{}, 10000)
+	for i := range items {
+		items[i] = map[string]interface{}{
+			"id":   fmt.Sprintf("%d", i),
+			"name": fmt.Sprintf("item-%d", i),
+			"metadata": map[string]interface{}{
+				"labels": map[string]interface{}{"index": int64(i)},
+			},
+		}
+	}
+	u := &UnstructuredClient{
+		IdentifierFields: []string{"name"},
+		SpecFields: &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"name": "item-9999"},
+		}},
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		item, err := u.findInItems(items)
+		if err != nil || item == nil {
+			b.Fatalf("expected the last item to be found: %v", err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package restclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/metrics"
+	"github.com/pb33f/libopenapi"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// Results of the lookups of the OAS documents in the cache.
+const (
+	documentHit  = "hit"
+	documentMiss = "miss"
+)
+
+var configMapsGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+// documents caches the OAS documents the clients are built from by BuildClient.
+var documents = NewDocumentCache(http.DefaultClient)
+
+// RegisterDocumentMetrics registers the hits and the misses of the OAS documents cache of BuildClient.
+func RegisterDocumentMetrics(r *metrics.Registry) {
+	documents.Register(r)
+}
+
+type document struct {
+	// etag, lastModified: the validators of the document fetched over HTTP
+	etag, lastModified string
+	// expires: the time the document fetched over HTTP is fresh until, per its Cache-Control max-age
+	expires time.Time
+	// version: the resource version of the ConfigMap the document is read from
+	version string
+	// sum: the checksum of the contents, not to parse again the same contents
+	sum   [sha256.Size]byte
+	model *libopenapi.DocumentModel[v3.Document]
+}
+
+// DocumentCache caches the parsed OAS documents by path. The documents fetched over HTTP are revalidated with
+// their ETag and Last-Modified headers, once no longer fresh per their Cache-Control header, and the ones read
+// from a ConfigMap with its resource version, so that unchanged documents are neither downloaded nor parsed again.
+type DocumentCache struct {
+	client *http.Client
+	now    func() time.Time
+	lookup *metrics.Counter
+
+	mu      sync.Mutex
+	entries map[string]*document
+}
+
+// NewDocumentCache returns the cache of the OAS documents fetched through the HTTP client (http.DefaultClient if nil).
+func NewDocumentCache(client *http.Client) *DocumentCache {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &DocumentCache{
+		client:  client,
+		now:     time.Now,
+		lookup:  metrics.NewCounter("source", "result"),
+		entries: map[string]*document{},
+	}
+}
+
+// Register registers the hits and the misses of the cache, by source of the documents.
+func (c *DocumentCache) Register(r *metrics.Registry) {
+	r.RegisterCounter("rest_controller_oas_cache_lookups_total",
+		"Lookups of the OAS documents in the cache, by source (http, configmap) and result (hit if not downloaded again, miss otherwise).", c.lookup)
+}
+
+// Build builds the partial client from the OAS document at the path, downloading and parsing it
+// only if not cached or changed. The documents at other paths than HTTP URLs and ConfigMaps are not cached.
+func (c *DocumentCache) Build(ctx context.Context, kubeclient dynamic.Interface, swaggerPath string) (*UnstructuredClient, error) {
+	var model *libopenapi.DocumentModel[v3.Document]
+	var err error
+	switch {
+	case strings.HasPrefix(swaggerPath, "http://") || strings.HasPrefix(swaggerPath, "https://"):
+		model, err = c.fromURL(ctx, swaggerPath)
+	case strings.HasPrefix(swaggerPath, "configmap://"):
+		model, err = c.fromConfigMap(ctx, kubeclient, swaggerPath)
+	default:
+		return buildFromFile(ctx, kubeclient, swaggerPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &UnstructuredClient{
+		Server:    model.Model.Servers[0].URL,
+		DocScheme: model,
+		Auth:      nil,
+	}, nil
+}
+
+func (c *DocumentCache) cached(path string) *document {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries[path]
+}
+
+// store parses the contents, unless the same as the cached ones, and caches the document.
+func (c *DocumentCache) store(path string, prev, doc *document, contents []byte) (*libopenapi.DocumentModel[v3.Document], error) {
+	doc.sum = sha256.Sum256(contents)
+	if prev != nil && prev.sum == doc.sum {
+		doc.model = prev.model
+	} else {
+		model, err := parseModel(contents)
+		if err != nil {
+			return nil, err
+		}
+		doc.model = model
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[path] = doc
+	return doc.model, nil
+}
+
+// fromURL returns the cached document if fresh, revalidating it otherwise.
+func (c *DocumentCache) fromURL(ctx context.Context, src string) (*libopenapi.DocumentModel[v3.Document], error) {
+	prev := c.cached(src)
+	if prev != nil && c.now().Before(prev.expires) {
+		c.lookup.Inc("http", documentHit)
+		return prev.model, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: error creating request: %v", err)
+	}
+	if prev != nil {
+		if prev.etag != "" {
+			req.Header.Set("If-None-Match", prev.etag)
+		}
+		if prev.lastModified != "" {
+			req.Header.Set("If-Modified-Since", prev.lastModified)
+		}
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: error downloading file: %v", err)
+	}
+	defer resp.Body.Close()
+
+	maxAge, cacheable := cacheControl(resp.Header)
+	if resp.StatusCode == http.StatusNotModified && prev != nil {
+		c.lookup.Inc("http", documentHit)
+		doc := *prev
+		doc.expires = c.now().Add(maxAge)
+		c.mu.Lock()
+		c.entries[src] = &doc
+		c.mu.Unlock()
+		return doc.model, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download file: unexpected status code: %d", resp.StatusCode)
+	}
+	c.lookup.Inc("http", documentMiss)
+
+	contents, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: error reading response: %v", err)
+	}
+	if !cacheable {
+		return parseModel(contents)
+	}
+	return c.store(src, prev, &document{
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		expires:      c.now().Add(maxAge),
+	}, contents)
+}
+
+// fromConfigMap returns the cached document if the ConfigMap has not changed since it was read.
+func (c *DocumentCache) fromConfigMap(ctx context.Context, kubeclient dynamic.Interface, src string) (*libopenapi.DocumentModel[v3.Document], error) {
+	parts := strings.Split(strings.TrimPrefix(src, "configmap://"), "/")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("failed to download file: invalid configmap source: %s - must be formatted as configmap://<namespace>/<name>/<key>", src)
+	}
+	if kubeclient == nil {
+		return nil, fmt.Errorf("failed to download file: kube client not set")
+	}
+	namespace, name, key := parts[0], parts[1], parts[2]
+
+	cm, err := kubeclient.Resource(configMapsGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: error getting configmap: %v", err)
+	}
+	prev := c.cached(src)
+	if prev != nil && prev.version == cm.GetResourceVersion() && prev.version != "" {
+		c.lookup.Inc("configmap", documentHit)
+		return prev.model, nil
+	}
+	c.lookup.Inc("configmap", documentMiss)
+
+	data, ok, _ := unstructured.NestedString(cm.Object, "data", key)
+	if !ok {
+		return nil, fmt.Errorf("failed to download file: key not found in configmap: %s", key)
+	}
+	return c.store(src, prev, &document{version: cm.GetResourceVersion()}, []byte(data))
+}
+
+// cacheControl returns the time the response is fresh for, per its Cache-Control max-age,
+// and whether it can be cached at all.
+func cacheControl(header http.Header) (time.Duration, bool) {
+	var maxAge time.Duration
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store":
+			return 0, false
+		case directive == "no-cache":
+			// Cached, but revalidated on every use
+			return 0, true
+		case strings.HasPrefix(directive, "max-age="):
+			if secs, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil && secs > 0 {
+				maxAge = time.Duration(secs) * time.Second
+			}
+		}
+	}
+	return maxAge, true
+}
@@ -0,0 +1,261 @@
+package restclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/metrics"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+const documentOAS = `openapi: 3.0.0
+info:
+  title: repos
+  version: "1.0"
+servers:
+  - url: %s
+paths:
+  /repos:
+    get:
+      responses:
+        "200":
+          description: ok
+`
+
+func TestDocumentCacheHTTP(t *testing.T) {
+	server := "https://api.example.com"
+	cacheControl := ""
+	var downloads, revalidations int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := fmt.Sprintf("%q", server)
+		if r.Header.Get("If-None-Match") == etag {
+			revalidations++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", etag)
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		fmt.Fprintf(w, documentOAS, server)
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	c := NewDocumentCache(srv.Client())
+	c.now = func() time.Time { return now }
+	build := func() *UnstructuredClient {
+		t.Helper()
+		cli, err := c.Build(context.Background(), nil, srv.URL+"/openapi.yaml")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return cli
+	}
+
+	first := build()
+	second := build()
+	if downloads != 1 || revalidations != 1 {
+		t.Errorf("expected the document to be revalidated, got %d downloads and %d revalidations", downloads, revalidations)
+	}
+	if first.DocScheme != second.DocScheme || first == second {
+		t.Errorf("expected a new client sharing the parsed document")
+	}
+
+	server = "https://api.example.org"
+	if cli := build(); cli.Server != server || downloads != 2 {
+		t.Errorf("expected the changed document to be downloaded again, got %s after %d downloads", cli.Server, downloads)
+	}
+
+	server = "https://api.example.net"
+	cacheControl = "max-age=60"
+	build()
+	build()
+	if downloads != 3 || revalidations != 1 {
+		t.Errorf("expected the fresh document not to be revalidated, got %d downloads and %d revalidations", downloads, revalidations)
+	}
+	now = now.Add(2 * time.Minute)
+	build()
+	if downloads != 3 || revalidations != 2 {
+		t.Errorf("expected the stale document to be revalidated, got %d downloads and %d revalidations", downloads, revalidations)
+	}
+
+	server = "https://api.example.io"
+	cacheControl = "no-store"
+	now = now.Add(2 * time.Minute)
+	build()
+	build()
+	if downloads != 5 {
+		t.Errorf("expected the document not to be cached, got %d downloads", downloads)
+	}
+
+	r := metrics.NewRegistry()
+	c.Register(r)
+	var buf bytes.Buffer
+	r.Write(&buf)
+	for _, expected := range []string{
+		`rest_controller_oas_cache_lookups_total{result="hit",source="http"} 3`,
+		`rest_controller_oas_cache_lookups_total{result="miss",source="http"} 5`,
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected %s in metrics:\n%s", expected, buf.String())
+		}
+	}
+}
+
+func TestDocumentCacheConfigMap(t *testing.T) {
+	cm := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"namespace": "default", "name": "oas", "resourceVersion": "1"},
+		"data":       map[string]interface{}{"openapi.yaml": fmt.Sprintf(documentOAS, "https://api.example.com")},
+	}}
+	dyn := fake.NewSimpleDynamicClient(runtime.NewScheme(), cm)
+	c := NewDocumentCache(nil)
+
+	first, err := c.Build(context.Background(), dyn, "configmap://default/oas/openapi.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := c.Build(context.Background(), dyn, "configmap://default/oas/openapi.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.DocScheme != second.DocScheme {
+		t.Errorf("expected the unchanged document not to be parsed again")
+	}
+	if c.lookup.Value("configmap", documentHit) != 1 {
+		t.Errorf("expected a hit, got %v", c.lookup.Value("configmap", documentHit))
+	}
+
+	cm.SetResourceVersion("2")
+	_ = unstructured.SetNestedField(cm.Object, fmt.Sprintf(documentOAS, "https://api.example.org"), "data", "openapi.yaml")
+	if _, err := dyn.Resource(configMapsGVR).Namespace("default").Update(context.Background(), cm, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cli, err := c.Build(context.Background(), dyn, "configmap://default/oas/openapi.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cli.Server != "https://api.example.org" {
+		t.Errorf("expected the changed document to be read again, got %s", cli.Server)
+	}
+
+	if _, err := c.Build(context.Background(), dyn, "configmap://default/oas/missing.yaml"); err == nil {
+		t.Errorf("expected an error for a missing key")
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	tests := []struct {
+		header    string
+		maxAge    time.Duration
+		cacheable bool
+	}{
+		{header: "", cacheable: true},
+		{header: "public, max-age=300", maxAge: 5 * time.Minute, cacheable: true},
+		{header: "no-cache, max-age=300", cacheable: true},
+		{header: "private, no-store", cacheable: false},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		h.Set("Cache-Control", tt.header)
+		maxAge, cacheable := cacheControl(h)
+		if maxAge != tt.maxAge || cacheable != tt.cacheable {
+			t.Errorf("%q: expected %v and %v, got %v and %v", tt.header, tt.maxAge, tt.cacheable, maxAge, cacheable)
+		}
+	}
+}
+
+const allOfDocumentOAS = `openapi: 3.0.0
+info:
+  title: repos
+  version: "1.0"
+servers:
+  - url: https://api.example.com
+paths:
+  /repos:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              allOf:
+                - $ref: '#/components/schemas/Base'
+                - type: object
+                  properties:
+                    settings:
+                      allOf:
+                        - $ref: '#/components/schemas/Base'
+      responses:
+        "201":
+          description: created
+components:
+  schemas:
+    Base:
+      type: object
+      properties:
+        name:
+          type: string
+`
+
+// TestDocumentCacheConcurrentAllOf reads the request bodies merging allOf schemas concurrently, from the clients
+// sharing the cached document, which must not be modified (run with -race).
+func TestDocumentCacheConcurrentAllOf(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		fmt.Fprint(w, allOfDocumentOAS)
+	}))
+	defer srv.Close()
+
+	c := NewDocumentCache(srv.Client())
+	if _, err := c.Build(context.Background(), nil, srv.URL+"/openapi.yaml"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cli, err := c.Build(context.Background(), nil, srv.URL+"/openapi.yaml")
+			if err != nil {
+				errs <- err
+				return
+			}
+			body, err := cli.RequestedBody("POST", "/repos")
+			if err != nil {
+				errs <- err
+				return
+			}
+			if !body.Contains("name") || !body.Contains("settings") {
+				errs <- fmt.Errorf("expected the properties of the allOf schemas, got %v", body)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// The schema of the document is left as is
+	cli, _ := c.Build(context.Background(), nil, srv.URL+"/openapi.yaml")
+	pathItem, _ := cli.DocScheme.Model.Paths.PathItems.Get("/repos")
+	media, _ := pathItem.Post.RequestBody.Content.Get("application/json")
+	if props := media.Schema.Schema().Properties; props != nil && props.Len() > 0 {
+		t.Errorf("expected the cached schema not to be modified, got %d properties", props.Len())
+	}
+}
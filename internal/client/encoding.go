@@ -0,0 +1,114 @@
+package restclient
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/transform"
+)
+
+// acceptEncoding is the Accept-Encoding header of the requests, unless set by the custom headers:
+// asking for it explicitly disables the transparent gzip decompression of the http transport,
+// the responses being decompressed by decodeResponseBody instead.
+const acceptEncoding = "gzip, deflate"
+
+// decodeResponseBody replaces the body of the response with its decompressed (gzip or deflate Content-Encoding)
+// UTF-8 (charset of the Content-Type) representation, updating the headers accordingly.
+// Unknown encodings and charsets are left untouched.
+func decodeResponseBody(res *http.Response) {
+	if res == nil || res.Body == nil || res.Body == http.NoBody {
+		return
+	}
+
+	switch strings.ToLower(strings.TrimSpace(res.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		res.Body = &decompressReader{body: res.Body, open: func(r *bufio.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		}}
+	case "deflate":
+		res.Body = &decompressReader{body: res.Body, open: openDeflate}
+	default:
+		return
+	}
+	res.Header.Del("Content-Encoding")
+	res.Header.Del("Content-Length")
+	res.ContentLength = -1
+	res.Uncompressed = true
+}
+
+// decodeResponseCharset transcodes the body of the response to UTF-8 if the Content-Type declares another charset
+// (e.g. ISO-8859-1), updating the charset of the Content-Type accordingly.
+func decodeResponseCharset(res *http.Response) {
+	if res == nil || res.Body == nil || res.Body == http.NoBody {
+		return
+	}
+	mediaType, params, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if err != nil {
+		return
+	}
+	charset := strings.ToLower(params["charset"])
+	if charset == "" || charset == "utf-8" || charset == "utf8" || charset == "us-ascii" {
+		return
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return
+	}
+	res.Body = &readCloser{Reader: transform.NewReader(res.Body, enc.NewDecoder()), Closer: res.Body}
+	params["charset"] = "utf-8"
+	res.Header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+	res.Header.Del("Content-Length")
+	res.ContentLength = -1
+}
+
+// openDeflate opens the deflate stream, which is zlib wrapped according to the HTTP specification,
+// even though some servers send it raw.
+func openDeflate(r *bufio.Reader) (io.ReadCloser, error) {
+	header, err := r.Peek(2)
+	if len(header) == 0 {
+		return nil, io.EOF
+	}
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(r)
+	}
+	return flate.NewReader(r), nil
+}
+
+// decompressReader decompresses the body lazily, on the first read, so that the empty bodies
+// (e.g. of HEAD requests) do not fail.
+type decompressReader struct {
+	body io.ReadCloser
+	open func(*bufio.Reader) (io.ReadCloser, error)
+	r    io.ReadCloser
+	err  error
+}
+
+func (d *decompressReader) Read(p []byte) (int, error) {
+	if d.r == nil && d.err == nil {
+		d.r, d.err = d.open(bufio.NewReader(d.body))
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.r.Read(p)
+}
+
+func (d *decompressReader) Close() error {
+	if d.r != nil {
+		d.r.Close()
+	}
+	return d.body.Close()
+}
+
+// readCloser reads from the Reader and closes the Closer.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,62 @@
+package restclient
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestDecodeResponseBody(t *testing.T) {
+	const body = `{"name": "café"}`
+	compress := func(w io.WriteCloser) {
+		w.Write([]byte(body))
+		w.Close()
+	}
+
+	var gz, zl, raw bytes.Buffer
+	compress(gzip.NewWriter(&gz))
+	compress(zlib.NewWriter(&zl))
+	fw, _ := flate.NewWriter(&raw, flate.DefaultCompression)
+	compress(fw)
+
+	tests := []struct {
+		name        string
+		encoding    string
+		contentType string
+		data        []byte
+		expected    string
+	}{
+		{name: "identity", contentType: "application/json", data: []byte(body), expected: body},
+		{name: "gzip", encoding: "gzip", contentType: "application/json", data: gz.Bytes(), expected: body},
+		{name: "zlib deflate", encoding: "deflate", contentType: "application/json", data: zl.Bytes(), expected: body},
+		{name: "raw deflate", encoding: "deflate", contentType: "application/json", data: raw.Bytes(), expected: body},
+		{name: "empty gzip", encoding: "gzip", contentType: "application/json", data: nil, expected: ""},
+		{name: "latin1", contentType: "application/json; charset=ISO-8859-1", data: []byte("{\"name\": \"caf\xe9\"}"), expected: body},
+		{name: "unknown charset", contentType: "text/plain; charset=unknown", data: []byte("caf\xe9"), expected: "caf\xe9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &http.Response{Header: http.Header{}, Body: io.NopCloser(bytes.NewReader(tt.data)), ContentLength: int64(len(tt.data))}
+			res.Header.Set("Content-Type", tt.contentType)
+			if tt.encoding != "" {
+				res.Header.Set("Content-Encoding", tt.encoding)
+			}
+			decodeResponseBody(res)
+			decodeResponseCharset(res)
+			data, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, data)
+			}
+			if res.Header.Get("Content-Encoding") != "" {
+				t.Errorf("expected the content encoding to be removed")
+			}
+		})
+	}
+}
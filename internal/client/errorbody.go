@@ -0,0 +1,80 @@
+package restclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lucasepe/httplib"
+)
+
+// maxErrorTextLength is the length the text of the non-JSON error responses is truncated to.
+const maxErrorTextLength = 256
+
+// TextError is the error of a response whose body is not JSON (e.g. the text/html page of a proxy or gateway),
+// holding the text of the body truncated to maxErrorTextLength.
+type TextError struct {
+	ContentType string
+	Text        string
+}
+
+func (e *TextError) Error() string {
+	if e.Text == "" {
+		return fmt.Sprintf("empty %s response", e.ContentType)
+	}
+	return fmt.Sprintf("%s response: %s", e.ContentType, e.Text)
+}
+
+// errorResponse validates the response has an acceptable status code and if it's bad, decodes the JSON body
+// into apiErr, as httplib.ErrorJSON does. A body which is not JSON is returned as a TextError instead of a
+// decoding error, so that the status problem is not masked.
+func errorResponse(apiErr *APIError, acceptStatuses ...int) httplib.HandleResponseFunc {
+	return func(res *http.Response) error {
+		for _, code := range acceptStatuses {
+			if res.StatusCode == code {
+				return nil
+			}
+		}
+		if res.Body == nil {
+			return &httplib.StatusError{StatusCode: res.StatusCode}
+		}
+		data, err := io.ReadAll(res.Body)
+		if err != nil {
+			return &httplib.StatusError{StatusCode: res.StatusCode, Inner: err}
+		}
+
+		contentType := res.Header.Get("Content-Type")
+		if isJSONContentType(contentType) && json.Unmarshal(data, apiErr) == nil {
+			return &httplib.StatusError{StatusCode: res.StatusCode, Inner: apiErr}
+		}
+		if contentType == "" {
+			contentType = http.DetectContentType(data)
+		}
+		if mediaType, _, ok := strings.Cut(contentType, ";"); ok {
+			contentType = mediaType
+		}
+		return &httplib.StatusError{StatusCode: res.StatusCode, Inner: &TextError{
+			ContentType: strings.TrimSpace(contentType),
+			Text:        truncateText(data, maxErrorTextLength),
+		}}
+	}
+}
+
+// isJSONContentType returns true if the content type is JSON (e.g. application/problem+json) or missing,
+// the body being then decoded as JSON if possible.
+func isJSONContentType(contentType string) bool {
+	return contentType == "" || strings.Contains(strings.ToLower(contentType), "json")
+}
+
+// truncateText collapses the whitespaces of the text and truncates it to max runes.
+func truncateText(data []byte, max int) string {
+	text := strings.Join(strings.Fields(string(bytes.ToValidUTF8(data, nil))), " ")
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	return string([]rune(text)[:max]) + "..."
+}
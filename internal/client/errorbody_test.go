@@ -0,0 +1,80 @@
+package restclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lucasepe/httplib"
+)
+
+func TestErrorResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		expected    string
+	}{
+		{
+			name:   "accepted",
+			status: http.StatusOK,
+			body:   "<html>ok</html>",
+		},
+		{
+			name:        "json error",
+			status:      http.StatusBadRequest,
+			contentType: "application/json",
+			body:        `{"message":"invalid name","typeKey":"validation","eventId":1}`,
+			expected:    "unexpected status: 400: error: invalid name (validation, 1)",
+		},
+		{
+			name:        "html error",
+			status:      http.StatusBadGateway,
+			contentType: "text/html; charset=utf-8",
+			body:        "<html>\n  <body>502 Bad Gateway</body>\n</html>",
+			expected:    "unexpected status: 502: text/html response: <html> <body>502 Bad Gateway</body> </html>",
+		},
+		{
+			name:     "undeclared text error",
+			status:   http.StatusServiceUnavailable,
+			body:     "upstream connect error",
+			expected: "unexpected status: 503: text/plain response: upstream connect error",
+		},
+		{
+			name:        "truncated error",
+			status:      http.StatusInternalServerError,
+			contentType: "text/plain",
+			body:        strings.Repeat("a", maxErrorTextLength+10),
+			expected:    "unexpected status: 500: text/plain response: " + strings.Repeat("a", maxErrorTextLength) + "...",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &http.Response{
+				StatusCode: tt.status,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			}
+			if tt.contentType != "" {
+				res.Header.Set("Content-Type", tt.contentType)
+			}
+			err := errorResponse(&APIError{}, http.StatusOK)(res)
+			if tt.expected == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var statusErr *httplib.StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.status {
+				t.Fatalf("expected a status error %d, got %v", tt.status, err)
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
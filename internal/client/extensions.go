@@ -0,0 +1,90 @@
+package restclient
+
+import (
+	"strings"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// OAS operation extensions interpreted by the client on the responses.
+const (
+	// ExtensionResponseUnwrap: the dot separated path of the response field wrapping the resource
+	// or the collection (e.g. data), the response is replaced by its value
+	ExtensionResponseUnwrap = "x-kog-response-unwrap"
+	// ExtensionIDField: the dot separated path of the response field holding the ID of the resource
+	// (e.g. meta.uuid), copied to the first identifier field when the response lacks it
+	ExtensionIDField = "x-kog-id-field"
+)
+
+// operationExtension returns the value of the extension of the operation, empty if not set.
+func operationExtension(op *v3.Operation, name string) string {
+	if op == nil || op.Extensions == nil {
+		return ""
+	}
+	node, ok := op.Extensions.Get(name)
+	if !ok || node == nil {
+		return ""
+	}
+	return strings.TrimSpace(node.Value)
+}
+
+// transformResponse converts the values of the decoded response to the types declared by the response schema
+// of the operation (see normalizeTypes) and applies its response extensions: the envelope is unwrapped,
+// an unwrapped collection being returned under the items field, and the ID is copied to the first identifier field
+// of the resource or of the items of the collection.
+func (u *UnstructuredClient) transformResponse(op *v3.Operation, response interface{}) interface{} {
+	// The schema of the JSON:API responses describes their documents, flattened by decodeResponse
+	if u.JSONAPI == nil {
+		response = normalizeTypes(responseSchema(op), response, 0)
+	}
+
+	if unwrap := operationExtension(op, ExtensionResponseUnwrap); unwrap != "" {
+		if obj, ok := response.(map[string]interface{}); ok {
+			v, found, err := unstructured.NestedFieldNoCopy(obj, strings.Split(unwrap, ".")...)
+			if err == nil && found {
+				response = v
+			}
+		}
+		if items, ok := response.([]interface{}); ok {
+			response = map[string]interface{}{"items": items}
+		}
+	}
+
+	idField := operationExtension(op, ExtensionIDField)
+	if idField == "" {
+		return response
+	}
+	identifier := "id"
+	if len(u.IdentifierFields) > 0 {
+		identifier = u.IdentifierFields[0]
+	}
+	obj, ok := response.(map[string]interface{})
+	if !ok {
+		return response
+	}
+	if !copyID(obj, idField, identifier) {
+		// A collection, the ID is located in each of its items
+		for _, item := range listItems(&obj, "") {
+			if item, ok := item.(map[string]interface{}); ok {
+				copyID(item, idField, identifier)
+			}
+		}
+	}
+	return response
+}
+
+// copyID copies the value of the ID field to the identifier field of the object, unless already set.
+// It returns false if the object has no ID field.
+func copyID(obj map[string]interface{}, idField, identifier string) bool {
+	id, found, err := unstructured.NestedFieldCopy(obj, strings.Split(idField, ".")...)
+	if err != nil || !found {
+		return false
+	}
+	path := strings.Split(identifier, ".")
+	if _, found, _ := unstructured.NestedFieldNoCopy(obj, path...); found {
+		return true
+	}
+	unstructured.SetNestedField(obj, id, path...)
+	return true
+}
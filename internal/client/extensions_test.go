@@ -0,0 +1,102 @@
+package restclient
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+)
+
+const extensionsOAS = `openapi: 3.0.0
+info:
+  title: repos
+  version: "1.0"
+paths:
+  /repos:
+    get:
+      x-kog-response-unwrap: result.repos
+      x-kog-id-field: meta.uuid
+      responses:
+        "200":
+          description: ok
+  /repos/{id}:
+    get:
+      x-kog-response-unwrap: data
+      x-kog-id-field: meta.uuid
+      responses:
+        "200":
+          description: ok
+    delete:
+      responses:
+        "204":
+          description: deleted
+`
+
+func TestTransformResponse(t *testing.T) {
+	d, err := libopenapi.NewDocument([]byte(extensionsOAS))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc, errs := d.BuildV3Model()
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	u := &UnstructuredClient{DocScheme: doc, IdentifierFields: []string{"id", "name"}}
+	repos, _ := doc.Model.Paths.PathItems.Get("/repos")
+	repo, _ := doc.Model.Paths.PathItems.Get("/repos/{id}")
+
+	tests := []struct {
+		name     string
+		path     string
+		method   string
+		response interface{}
+		expected interface{}
+	}{
+		{
+			name:     "resource unwrapped",
+			path:     "/repos/{id}",
+			response: map[string]interface{}{"data": map[string]interface{}{"name": "a", "meta": map[string]interface{}{"uuid": "1"}}},
+			expected: map[string]interface{}{"id": "1", "name": "a", "meta": map[string]interface{}{"uuid": "1"}},
+		},
+		{
+			name:     "identifier kept",
+			path:     "/repos/{id}",
+			response: map[string]interface{}{"data": map[string]interface{}{"id": "2", "meta": map[string]interface{}{"uuid": "1"}}},
+			expected: map[string]interface{}{"id": "2", "meta": map[string]interface{}{"uuid": "1"}},
+		},
+		{
+			name: "collection unwrapped",
+			path: "/repos",
+			response: map[string]interface{}{"result": map[string]interface{}{"repos": []interface{}{
+				map[string]interface{}{"meta": map[string]interface{}{"uuid": "1"}},
+			}}},
+			expected: map[string]interface{}{"items": []interface{}{
+				map[string]interface{}{"id": "1", "meta": map[string]interface{}{"uuid": "1"}},
+			}},
+		},
+		{
+			name:     "no extensions",
+			path:     "/repos/{id}",
+			method:   "delete",
+			response: map[string]interface{}{"data": map[string]interface{}{"name": "a"}},
+			expected: map[string]interface{}{"data": map[string]interface{}{"name": "a"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pathItem := repo
+			if tt.path == "/repos" {
+				pathItem = repos
+			}
+			method := tt.method
+			if method == "" {
+				method = "get"
+			}
+			op, _ := pathItem.GetOperations().Get(method)
+			res := u.transformResponse(op, tt.response)
+			if !reflect.DeepEqual(res, tt.expected) {
+				t.Errorf("unexpected response: %v", res)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package restclient
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucasepe/httplib"
+)
+
+// headerRecorder is a RoundTripper counting the requests and recording the headers and the status code
+// of the responses in the client, whose bodies are decompressed and transcoded to UTF-8.
+type headerRecorder struct {
+	base http.RoundTripper
+	u    *UnstructuredClient
+}
+
+func (t *headerRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t.u.RequestCount++
+	// A request failing without a response leaves no status of a previous response behind
+	t.u.ResponseHeaders = nil
+	t.u.ResponseStatus = 0
+	res, err := base.RoundTrip(req)
+	if err == nil {
+		t.u.ResponseHeaders = res.Header.Clone()
+		t.u.ResponseStatus = res.StatusCode
+		decodeResponseBody(res)
+		decodeResponseCharset(res)
+	}
+	return res, err
+}
+
+// recordHeaders returns a copy of the http client recording the response headers in ResponseHeaders.
+func (u *UnstructuredClient) recordHeaders(cli *http.Client) *http.Client {
+	if cli == nil {
+		cli = http.DefaultClient
+	}
+	c := *cli
+	c.Transport = &headerRecorder{base: cli.Transport, u: u}
+	return &c
+}
+
+// setRequestHeaders sets the custom headers and cookies of the request configuration on the request.
+func (u *UnstructuredClient) setRequestHeaders(req *http.Request, opts *RequestConfiguration) {
+	if u.JSONAPI != nil {
+		req.Header.Set("Accept", jsonAPIMediaType)
+	}
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	for name, value := range opts.Headers {
+		req.Header.Set(name, value)
+	}
+	for name, value := range opts.Cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+}
+
+// RetryAfter returns the delay asked by the API in the Retry-After header of the last response,
+// if the request failed because the API is rate limiting (429) or unavailable (503).
+func (u *UnstructuredClient) RetryAfter(err error) (time.Duration, bool) {
+	var statusErr *httplib.StatusError
+	if !errors.As(err, &statusErr) {
+		return 0, false
+	}
+	if statusErr.StatusCode != http.StatusTooManyRequests && statusErr.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	value := strings.TrimSpace(u.ResponseHeaders.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, seconds > 0
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		delay := time.Until(at)
+		return delay, delay > 0
+	}
+	return 0, false
+}
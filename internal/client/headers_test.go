@@ -0,0 +1,95 @@
+package restclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lucasepe/httplib"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		retryAfter string
+		expected   time.Duration
+		ok         bool
+	}{
+		{name: "seconds", err: &httplib.StatusError{StatusCode: 429}, retryAfter: "30", expected: 30 * time.Second, ok: true},
+		{name: "wrapped", err: fmt.Errorf("calling: %w", &httplib.StatusError{StatusCode: 503}), retryAfter: "5", expected: 5 * time.Second, ok: true},
+		{name: "date", err: &httplib.StatusError{StatusCode: 429}, retryAfter: time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), ok: true},
+		{name: "past date", err: &httplib.StatusError{StatusCode: 429}, retryAfter: "Mon, 02 Jan 2006 15:04:05 GMT"},
+		{name: "not rate limited", err: &httplib.StatusError{StatusCode: 500}, retryAfter: "30"},
+		{name: "missing header", err: &httplib.StatusError{StatusCode: 429}},
+		{name: "invalid header", err: &httplib.StatusError{StatusCode: 429}, retryAfter: "soon"},
+		{name: "no error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &UnstructuredClient{ResponseHeaders: http.Header{}}
+			if tt.retryAfter != "" {
+				u.ResponseHeaders.Set("Retry-After", tt.retryAfter)
+			}
+			delay, ok := u.RetryAfter(tt.err)
+			if ok != tt.ok {
+				t.Fatalf("expected ok %t, got %t (%v)", tt.ok, ok, delay)
+			}
+			if tt.expected > 0 && delay != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, delay)
+			}
+			if tt.ok && delay <= 0 {
+				t.Errorf("expected a positive delay, got %v", delay)
+			}
+		})
+	}
+}
+
+// failingTransport fails the requests once failing is set, sending them through the default transport otherwise.
+type failingTransport struct {
+	failing bool
+}
+
+func (f *failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if f.failing {
+		return nil, errors.New("connection reset")
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestRecordHeadersResetOnError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request", "1")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	transport := &failingTransport{}
+	u := &UnstructuredClient{}
+	cli := u.recordHeaders(&http.Client{Transport: transport})
+
+	res, err := cli.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+	if u.ResponseStatus != http.StatusOK || u.ResponseHeaders.Get("X-Request") != "1" {
+		t.Fatalf("expected the response to be recorded, got %d %v", u.ResponseStatus, u.ResponseHeaders)
+	}
+
+	// The status of the previous response must not outlive a request failing without a response
+	transport.failing = true
+	if _, err := cli.Get(srv.URL); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if u.ResponseStatus != 0 || u.ResponseHeaders != nil {
+		t.Errorf("expected no response recorded, got %d %v", u.ResponseStatus, u.ResponseHeaders)
+	}
+	if u.RequestCount != 2 {
+		t.Errorf("expected 2 requests, got %d", u.RequestCount)
+	}
+}
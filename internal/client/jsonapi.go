@@ -0,0 +1,164 @@
+package restclient
+
+import (
+	"strings"
+)
+
+const jsonAPIMediaType = "application/vnd.api+json"
+
+// JSONAPI enables the JSON:API protocol mode (https://jsonapi.org), wrapping the request bodies
+// in the resource object envelope and flattening the resource objects of the responses.
+type JSONAPI struct {
+	// Type: the JSON:API type of the resource (e.g. articles)
+	Type string `json:"type"`
+	// Relationships: the fields sent as relationships, mapped to the JSON:API type of the related resource (e.g. author: people)
+	Relationships map[string]string `json:"relationships,omitempty"`
+}
+
+// JSONAPIError is an error object of a JSON:API error response.
+type JSONAPIError struct {
+	Status string `json:"status,omitempty"`
+	Code   string `json:"code,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func (e JSONAPIError) String() string {
+	parts := []string{}
+	for _, s := range []string{e.Status, e.Code, e.Title} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	res := strings.Join(parts, " ")
+	if e.Detail != "" {
+		if res != "" {
+			res += ": "
+		}
+		res += e.Detail
+	}
+	return res
+}
+
+// mediaType returns the media type of the request and response bodies.
+func (u *UnstructuredClient) mediaType() string {
+	if u.JSONAPI != nil {
+		return jsonAPIMediaType
+	}
+	return "application/json"
+}
+
+// requestBody returns the body to send, wrapped in the resource object envelope in JSON:API mode.
+func (u *UnstructuredClient) requestBody(opts *RequestConfiguration) interface{} {
+	fields, ok := opts.Body.(map[string]interface{})
+	if u.JSONAPI == nil || !ok {
+		return opts.Body
+	}
+
+	attributes := map[string]interface{}{}
+	relationships := map[string]interface{}{}
+	var id interface{}
+	for k, v := range fields {
+		if k == "id" {
+			id = v
+			continue
+		}
+		typ, ok := u.JSONAPI.Relationships[k]
+		if !ok {
+			attributes[k] = v
+			continue
+		}
+		switch ids := v.(type) {
+		case []interface{}:
+			data := make([]interface{}, 0, len(ids))
+			for _, id := range ids {
+				data = append(data, map[string]interface{}{"type": typ, "id": id})
+			}
+			relationships[k] = map[string]interface{}{"data": data}
+		default:
+			relationships[k] = map[string]interface{}{
+				"data": map[string]interface{}{"type": typ, "id": v},
+			}
+		}
+	}
+
+	data := map[string]interface{}{
+		"type":       u.JSONAPI.Type,
+		"attributes": attributes,
+	}
+	if len(relationships) > 0 {
+		data["relationships"] = relationships
+	}
+	if id == nil {
+		if param, ok := opts.Parameters["id"]; ok && param != "" {
+			id = param
+		}
+	}
+	if id != nil {
+		data["id"] = id
+	}
+	return map[string]interface{}{"data": data}
+}
+
+// decodeResponse flattens the resource objects of the JSON:API documents, merging their id,
+// attributes and relationship ids; collections are returned under the data key, along with their links.
+func (u *UnstructuredClient) decodeResponse(response interface{}) interface{} {
+	if u.JSONAPI == nil {
+		return response
+	}
+	doc, ok := response.(map[string]interface{})
+	if !ok {
+		return response
+	}
+
+	switch data := doc["data"].(type) {
+	case map[string]interface{}:
+		return flattenResourceObject(data)
+	case []interface{}:
+		items := make([]interface{}, 0, len(data))
+		for _, item := range data {
+			if obj, ok := item.(map[string]interface{}); ok {
+				items = append(items, flattenResourceObject(obj))
+			}
+		}
+		res := map[string]interface{}{"data": items}
+		if links, ok := doc["links"]; ok {
+			res["links"] = links
+		}
+		return res
+	}
+	return response
+}
+
+func flattenResourceObject(obj map[string]interface{}) map[string]interface{} {
+	res := map[string]interface{}{}
+	if attributes, ok := obj["attributes"].(map[string]interface{}); ok {
+		for k, v := range attributes {
+			res[k] = v
+		}
+	}
+	if relationships, ok := obj["relationships"].(map[string]interface{}); ok {
+		for name, rel := range relationships {
+			rel, ok := rel.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch data := rel["data"].(type) {
+			case map[string]interface{}:
+				res[name] = data["id"]
+			case []interface{}:
+				ids := make([]interface{}, 0, len(data))
+				for _, item := range data {
+					if item, ok := item.(map[string]interface{}); ok {
+						ids = append(ids, item["id"])
+					}
+				}
+				res[name] = ids
+			}
+		}
+	}
+	if id, ok := obj["id"]; ok {
+		res["id"] = id
+	}
+	return res
+}
@@ -0,0 +1,69 @@
+package restclient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONAPIRequestBody(t *testing.T) {
+	u := &UnstructuredClient{JSONAPI: &JSONAPI{
+		Type:          "articles",
+		Relationships: map[string]string{"author": "people"},
+	}}
+	body := u.requestBody(&RequestConfiguration{
+		Parameters: map[string]string{"id": "1"},
+		Body:       map[string]interface{}{"title": "Hello", "author": "9"},
+	})
+	expected := map[string]interface{}{
+		"data": map[string]interface{}{
+			"type":       "articles",
+			"id":         "1",
+			"attributes": map[string]interface{}{"title": "Hello"},
+			"relationships": map[string]interface{}{
+				"author": map[string]interface{}{
+					"data": map[string]interface{}{"type": "people", "id": "9"},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(body, expected) {
+		t.Errorf("unexpected body: %v", body)
+	}
+}
+
+func TestJSONAPIDecodeResponse(t *testing.T) {
+	u := &UnstructuredClient{JSONAPI: &JSONAPI{Type: "articles"}}
+	article := map[string]interface{}{
+		"type":       "articles",
+		"id":         "1",
+		"attributes": map[string]interface{}{"title": "Hello"},
+		"relationships": map[string]interface{}{
+			"author": map[string]interface{}{
+				"data": map[string]interface{}{"type": "people", "id": "9"},
+			},
+		},
+	}
+	flat := map[string]interface{}{"id": "1", "title": "Hello", "author": "9"}
+
+	res := u.decodeResponse(map[string]interface{}{"data": article})
+	if !reflect.DeepEqual(res, flat) {
+		t.Errorf("unexpected resource: %v", res)
+	}
+
+	links := map[string]interface{}{"next": "/articles?page[cursor]=2"}
+	res = u.decodeResponse(map[string]interface{}{"data": []interface{}{article}, "links": links})
+	expected := map[string]interface{}{"data": []interface{}{flat}, "links": links}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("unexpected collection: %v", res)
+	}
+}
+
+func TestJSONAPIError(t *testing.T) {
+	err := &APIError{Errors: []JSONAPIError{
+		{Status: "422", Title: "Invalid Attribute", Detail: "title must not be blank"},
+	}}
+	expected := "error: 422 Invalid Attribute: title must not be blank"
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+}
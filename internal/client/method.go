@@ -0,0 +1,112 @@
+package restclient
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	stringset "github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	"github.com/lucasepe/httplib"
+)
+
+// PathParams returns the parameters of the path template (e.g. /repos/{owner}/{repo} or /files/{path*}).
+func PathParams(path string) stringset.StringSet {
+	params := stringset.NewStringSet()
+	for name := range pathExprs(path) {
+		params.Add(name)
+	}
+	return params
+}
+
+// Raw returns the API call performing the requests with the given method as is, without looking up
+// the operation in the OAS, for APIs requiring non-standard methods (e.g. PROPFIND, REPORT).
+func (u *UnstructuredClient) Raw(method string) func(ctx context.Context, cli *http.Client, path string, opts *RequestConfiguration) (*map[string]interface{}, error) {
+	return func(ctx context.Context, cli *http.Client, path string, opts *RequestConfiguration) (*map[string]interface{}, error) {
+		if opts.URL != "" {
+			if err := checkLink(u.Server, opts.URL); err != nil {
+				return nil, err
+			}
+		}
+		uri := buildURL(u.Server, path, nil, method, opts)
+
+		var req *http.Request
+		var err error
+		if body, ok := opts.Body.(map[string]interface{}); ok && len(body) > 0 {
+			req, err = httplib.Post(uri.String(), httplib.ToJSON(u.requestBody(opts)))
+			if err == nil {
+				req.Header.Set("Content-Type", u.mediaType())
+			}
+		} else {
+			req, err = http.NewRequest(http.MethodPost, uri.String(), nil)
+		}
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(ctx)
+		req.Method = strings.ToUpper(method)
+		u.setRequestHeaders(req, opts)
+
+		apiErr := &APIError{}
+		var response any
+		rh := func(r *http.Response) error {
+			if r.ContentLength == 0 || r.StatusCode == http.StatusNoContent {
+				return nil
+			}
+			if !strings.Contains(r.Header.Get("Content-Type"), "json") {
+				return nil
+			}
+			return fromJSON(&response)(r)
+		}
+
+		err = httplib.Fire(u.recordHeaders(cli), req, httplib.FireOptions{
+			Verbose:         u.Verbose,
+			ResponseHandler: rh,
+			AuthMethod:      u.Auth,
+			Validators: []httplib.HandleResponseFunc{
+				errorResponse(apiErr, acceptedStatusCodes(rawStatusCodes, opts)...),
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		val, ok := u.decodeResponse(response).(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+		return &val, nil
+	}
+}
+
+// rawStatusCodes are the status codes accepted by the raw methods, not described by the OAS
+var rawStatusCodes = []int{http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent, http.StatusMultiStatus}
+
+// methodOverride is a RoundTripper sending the requests as POST, with the original method in the override header.
+type methodOverride struct {
+	base   http.RoundTripper
+	header string
+}
+
+func (t *methodOverride) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if req.Method == http.MethodPost {
+		return base.RoundTrip(req)
+	}
+	r := req.Clone(req.Context())
+	r.Header.Set(t.header, req.Method)
+	r.Method = http.MethodPost
+	return base.RoundTrip(r)
+}
+
+// MethodOverrideClient returns a copy of the http client sending the requests as POST,
+// with the original method in the given header (e.g. X-HTTP-Method-Override).
+func MethodOverrideClient(cli *http.Client, header string) *http.Client {
+	if cli == nil {
+		cli = http.DefaultClient
+	}
+	c := *cli
+	c.Transport = &methodOverride{base: cli.Transport, header: header}
+	return &c
+}
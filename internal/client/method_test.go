@@ -0,0 +1,54 @@
+package restclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lucasepe/httplib"
+)
+
+func TestMethodOverrideClient(t *testing.T) {
+	var method, override string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		override = r.Header.Get("X-HTTP-Method-Override")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	req, err := httplib.Patch(server.URL, httplib.ToJSON(map[string]any{"name": "test"}))
+	if err != nil {
+		t.Fatalf("creating request: %v", err)
+	}
+	err = httplib.Fire(MethodOverrideClient(http.DefaultClient, "X-HTTP-Method-Override"), req, httplib.FireOptions{})
+	if err != nil {
+		t.Fatalf("firing request: %v", err)
+	}
+	if method != http.MethodPost || override != http.MethodPatch {
+		t.Errorf("expected POST with PATCH override, got %s with %q", method, override)
+	}
+}
+
+func TestPathParams(t *testing.T) {
+	params := PathParams("/repos/{owner}/{repo}/hooks")
+	if len(params) != 2 || !params.Contains("owner") || !params.Contains("repo") {
+		t.Errorf("unexpected path params: %v", params)
+	}
+}
+
+func FuzzPathParams(f *testing.F) {
+	f.Add("/repos/{owner}/{repo}")
+	f.Add("/{}/{{a}}/{a.b}/{ключ}/{")
+	f.Add("/files/{path*}/{+rest}/{version?}{/format}")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, path string) {
+		for param := range PathParams(path) {
+			if param == "" || !strings.Contains(path, param) {
+				t.Fatalf("parameter %q not in path %q", param, path)
+			}
+		}
+	})
+}
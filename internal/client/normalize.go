@@ -0,0 +1,162 @@
+package restclient
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"strings"
+
+	stringset "github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// responseSchema returns the JSON schema of the first success response of the operation, nil if not described.
+func responseSchema(op *v3.Operation) *base.Schema {
+	if op == nil || op.Responses == nil || op.Responses.Codes == nil {
+		return nil
+	}
+	for code := op.Responses.Codes.First(); code != nil; code = code.Next() {
+		if !strings.HasPrefix(code.Key(), "2") || code.Value() == nil || code.Value().Content == nil {
+			continue
+		}
+		for media := code.Value().Content.First(); media != nil; media = media.Next() {
+			if !strings.Contains(media.Key(), "json") || media.Value() == nil || media.Value().Schema == nil {
+				continue
+			}
+			if schema := media.Value().Schema.Schema(); schema != nil {
+				return schema
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeTypes converts the scalar values of the response to the type declared by their schema, whatever
+// the way the server serializes them (e.g. "42" for an integer, 42 for a string or "true" for a boolean),
+// so that they are compared with the spec and populated in the status with their declared type.
+// The values that cannot be converted and the ones not described by the schema are left unchanged.
+func normalizeTypes(schema *base.Schema, value interface{}, depth int) interface{} {
+	if schema == nil || depth > maxSchemaDepth {
+		return value
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, el := range v {
+			v[k] = normalizeTypes(propertySchema(schema, k, 0), el, depth+1)
+		}
+		return v
+	case []interface{}:
+		var items *base.Schema
+		if schema.Items != nil && schema.Items.IsA() && schema.Items.A != nil {
+			items = schema.Items.A.Schema()
+		}
+		for i, el := range v {
+			v[i] = normalizeTypes(items, el, depth+1)
+		}
+		return v
+	case nil:
+		return v
+	}
+
+	types := schemaTypes(schema)
+	for _, t := range types {
+		if hasType(value, t) {
+			return value
+		}
+	}
+	for _, t := range types {
+		if res, ok := convertType(value, t); ok {
+			return res
+		}
+	}
+	return value
+}
+
+// propertySchema returns the schema of the property of the object schema, looked up in its allOf schemas
+// and falling back to its additionalProperties schema.
+func propertySchema(schema *base.Schema, name string, depth int) *base.Schema {
+	if schema == nil || depth > maxSchemaDepth {
+		return nil
+	}
+	if schema.Properties != nil {
+		if prop, ok := schema.Properties.Get(name); ok && prop != nil {
+			return prop.Schema()
+		}
+	}
+	for _, proxy := range schema.AllOf {
+		if prop := propertySchema(proxy.Schema(), name, depth+1); prop != nil {
+			return prop
+		}
+	}
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.IsA() && schema.AdditionalProperties.A != nil {
+		return schema.AdditionalProperties.A.Schema()
+	}
+	return nil
+}
+
+// schemaTypes returns the types of the schema, including the ones declared by its allOf schemas.
+func schemaTypes(schema *base.Schema) []string {
+	types := append([]string{}, schema.Type...)
+	for _, proxy := range schema.AllOf {
+		if s := proxy.Schema(); s != nil {
+			types = append(types, s.Type...)
+		}
+	}
+	return types
+}
+
+// hasType returns true if the scalar value already has the JSON schema type.
+func hasType(value interface{}, schemaType string) bool {
+	switch schemaType {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer", "number":
+		switch value.(type) {
+		case json.Number, float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			return true
+		}
+	}
+	return false
+}
+
+// convertType converts the scalar value to the JSON schema type, ok is false if it cannot be converted.
+func convertType(value interface{}, schemaType string) (interface{}, bool) {
+	switch schemaType {
+	case "string":
+		if _, ok := value.(string); ok {
+			return nil, false
+		}
+		s, err := stringset.GenericToString(value)
+		return s, err == nil
+	case "boolean":
+		s, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		b, err := strconv.ParseBool(strings.TrimSpace(s))
+		return b, err == nil
+	case "integer":
+		s, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		// Integers out of the int64 range are kept as json.Number to preserve their digits
+		if _, ok := new(big.Int).SetString(strings.TrimSpace(s), 10); ok {
+			return json.Number(strings.TrimSpace(s)), true
+		}
+	case "number":
+		s, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		if _, ok := new(big.Float).SetString(strings.TrimSpace(s)); ok {
+			return json.Number(strings.TrimSpace(s)), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,96 @@
+package restclient
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+)
+
+const normalizeOAS = `openapi: 3.0.0
+info:
+  title: repos
+  version: "1.0"
+paths:
+  /repos/{id}:
+    get:
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                allOf:
+                  - $ref: '#/components/schemas/Base'
+                  - type: object
+                    properties:
+                      private:
+                        type: boolean
+                      size:
+                        type: number
+                      topics:
+                        type: array
+                        items:
+                          type: string
+                      owner:
+                        type: object
+                        properties:
+                          id:
+                            type: integer
+                      labels:
+                        type: object
+                        additionalProperties:
+                          type: integer
+components:
+  schemas:
+    Base:
+      type: object
+      properties:
+        id:
+          type: integer
+        name:
+          type: string
+`
+
+func TestNormalizeTypes(t *testing.T) {
+	d, err := libopenapi.NewDocument([]byte(normalizeOAS))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc, errs := d.BuildV3Model()
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	pathItem, _ := doc.Model.Paths.PathItems.Get("/repos/{id}")
+	op, _ := pathItem.GetOperations().Get("get")
+	schema := responseSchema(op)
+	if schema == nil {
+		t.Fatalf("expected the response schema")
+	}
+
+	response := map[string]interface{}{
+		"id":      "18446744073709551616",
+		"name":    json.Number("42"),
+		"private": "true",
+		"size":    "1.5",
+		"topics":  []interface{}{json.Number("1"), "go", true},
+		"owner":   map[string]interface{}{"id": json.Number("7")},
+		"labels":  map[string]interface{}{"stars": "3", "forks": "n/a"},
+		"extra":   "10",
+	}
+	expected := map[string]interface{}{
+		"id":      json.Number("18446744073709551616"),
+		"name":    "42",
+		"private": true,
+		"size":    json.Number("1.5"),
+		"topics":  []interface{}{"1", "go", "true"},
+		"owner":   map[string]interface{}{"id": json.Number("7")},
+		"labels":  map[string]interface{}{"stars": json.Number("3"), "forks": "n/a"},
+		"extra":   "10",
+	}
+	res := normalizeTypes(schema, response, 0)
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %v, got %v", expected, res)
+	}
+}
@@ -0,0 +1,56 @@
+package restclient
+
+import (
+	"strings"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Ownership tells whether an item found by its identifiers belongs to the custom resource.
+type Ownership int
+
+const (
+	// Owned: the item carries the ownership markers of the custom resource, or the markers are not used
+	Owned Ownership = iota
+	// Unmarked: the item carries no ownership marker, e.g. created outside of the controller
+	Unmarked
+	// Foreign: the item carries the ownership markers of another custom resource
+	Foreign
+)
+
+// Owner tells where the controller writes the ownership markers of the custom resource on the external resources.
+type Owner struct {
+	// Field is the dot separated path of the object field holding the markers (e.g. tags or metadata.labels)
+	Field string
+	// Markers are the ownership markers of the custom resource, by key
+	Markers map[string]string
+}
+
+// ownership returns whether the item belongs to the custom resource, according to its ownership markers.
+func (u *UnstructuredClient) ownership(item map[string]interface{}) Ownership {
+	if u.Owner == nil || u.Owner.Field == "" || len(u.Owner.Markers) == 0 {
+		return Owned
+	}
+	val, _, _ := unstructured.NestedFieldNoCopy(item, strings.Split(u.Owner.Field, ".")...)
+	markers, ok := val.(map[string]interface{})
+	if !ok {
+		return Unmarked
+	}
+
+	marked := false
+	for key, expected := range u.Owner.Markers {
+		v, ok := markers[key]
+		if !ok {
+			continue
+		}
+		marked = true
+		if s, err := text.GenericToString(v); err != nil || s != expected {
+			return Foreign
+		}
+	}
+	if !marked {
+		return Unmarked
+	}
+	return Owned
+}
@@ -0,0 +1,63 @@
+package restclient
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestFindInItemsOwnership(t *testing.T) {
+	repo := func(id string, tags map[string]interface{}) interface{} {
+		item := map[string]interface{}{"id": id, "name": "repo1"}
+		if tags != nil {
+			item["tags"] = tags
+		}
+		return item
+	}
+	foreign := repo("1", map[string]interface{}{"krateo-namespace": "team-b", "krateo-name": "repo1"})
+	unmarked := repo("2", nil)
+	owned := repo("3", map[string]interface{}{"krateo-namespace": "team-a", "krateo-name": "repo1", "env": "prod"})
+
+	tests := []struct {
+		name  string
+		owner *Owner
+		items []interface{}
+		id    string
+	}{
+		{name: "owned preferred", owner: teamA(), items: []interface{}{foreign, unmarked, owned}, id: "3"},
+		{name: "unmarked adopted", owner: teamA(), items: []interface{}{foreign, unmarked}, id: "2"},
+		{name: "foreign skipped", owner: teamA(), items: []interface{}{foreign}},
+		{name: "markers not used", items: []interface{}{foreign, unmarked, owned}, id: "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &UnstructuredClient{
+				IdentifierFields: []string{"name"},
+				SpecFields: &unstructured.Unstructured{Object: map[string]interface{}{
+					"spec": map[string]interface{}{"name": "repo1"},
+				}},
+				Owner: tt.owner,
+			}
+			item, err := u.findInItems(tt.items)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.id == "" {
+				if item != nil {
+					t.Errorf("expected no item, got %v", *item)
+				}
+				return
+			}
+			if item == nil || (*item)["id"] != tt.id {
+				t.Errorf("expected item %s, got %v", tt.id, item)
+			}
+		})
+	}
+}
+
+func teamA() *Owner {
+	return &Owner{
+		Field:   "tags",
+		Markers: map[string]string{"krateo-namespace": "team-a", "krateo-name": "repo1"},
+	}
+}
@@ -0,0 +1,558 @@
+package restclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	"github.com/lucasepe/httplib"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type PaginationType string
+
+const (
+	// PaginationTypePage: the pages are selected by their number
+	PaginationTypePage PaginationType = "page"
+	// PaginationTypeCursor: the pages are selected by the cursor returned in the previous page
+	PaginationTypeCursor PaginationType = "cursor"
+	// PaginationTypeLink: the pages are requested following the link to the next page returned in the previous page
+	PaginationTypeLink PaginationType = "link"
+)
+
+// Pagination describes how the API paginates the items of a collection.
+type Pagination struct {
+	// Type: the pagination type [page, cursor, link]
+	Type PaginationType `json:"type"`
+	// PageParam: the query parameter holding the page number or the cursor (page and cursor pagination)
+	PageParam string `json:"pageParam,omitempty"`
+	// FirstPage: the number of the first page (page pagination), defaults to 1
+	FirstPage int `json:"firstPage,omitempty"`
+	// CursorField: the dot separated path of the response field holding the cursor or the link of the next page (cursor and link pagination);
+	// for link pagination, the link of the next page is read from the Link header of the response (RFC 8288) if empty
+	CursorField string `json:"cursorField,omitempty"`
+	// MaxPages: the maximum number of pages scanned by a single search, 0 means no limit
+	MaxPages int `json:"maxPages,omitempty"`
+	// MaxItems: the maximum number of items scanned by a single search, checked after each page, 0 means no limit
+	MaxItems int `json:"maxItems,omitempty"`
+	// Timeout: the maximum duration of a single search (e.g. 30s), no limit if empty
+	Timeout string `json:"timeout,omitempty"`
+	// Resume: if true, the page where the item was last found is persisted and the subsequent searches start from it
+	Resume bool `json:"resume,omitempty"`
+	// Concurrency: the number of pages fetched concurrently after the first one (page pagination), 0 or 1 means sequentially
+	Concurrency int `json:"concurrency,omitempty"`
+	// TotalPagesField: the dot separated path of the response field holding the number of pages (page pagination)
+	TotalPagesField string `json:"totalPagesField,omitempty"`
+	// TotalItemsField: the dot separated path of the response field holding the number of items, divided by the
+	// number of items of the first page to get the number of pages when TotalPagesField is not set (page pagination)
+	TotalItemsField string `json:"totalItemsField,omitempty"`
+}
+
+// Limits stopping the searches through the pages of a collection.
+const (
+	SearchLimitMaxPages = "maxPages"
+	SearchLimitMaxItems = "maxItems"
+	SearchLimitTimeout  = "timeout"
+)
+
+// SearchLimitError is returned by FindBy when the search reached one of the limits of the pagination
+// before finding the item or scanning the whole collection, so that the item is not known to be missing.
+type SearchLimitError struct {
+	// Limit: the limit reached [maxPages, maxItems, timeout]
+	Limit string
+	// Value: the configured value of the limit
+	Value string
+}
+
+func (e *SearchLimitError) Error() string {
+	return fmt.Sprintf("search limit exceeded: %s %s reached before finding the resource", e.Limit, e.Value)
+}
+
+// NotFoundError is returned by FindBy when the search through the collection completed without finding the item,
+// telling whether the collection was empty or how many items were searched; it is a 404 status error.
+type NotFoundError struct {
+	// Searched: the number of items searched
+	Searched int
+}
+
+func (e *NotFoundError) Error() string {
+	if e.Searched == 0 {
+		return "resource not found: the collection is empty"
+	}
+	return fmt.Sprintf("resource not found among the %d items searched", e.Searched)
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return &httplib.StatusError{StatusCode: http.StatusNotFound}
+}
+
+// CollectionNotFoundError is returned by FindBy when the API answered 404 listing the collection, unless
+// the request configuration tells to take it as an empty collection; it is not a 404 status error, since
+// the path of the collection is rather wrong than the item missing.
+type CollectionNotFoundError struct {
+	// Path: the path of the collection
+	Path string
+}
+
+func (e *CollectionNotFoundError) Error() string {
+	return fmt.Sprintf("collection not found: the API answered 404 listing %s", e.Path)
+}
+
+// pagination returns the pagination of the collections, the JSON:API links are followed by default in JSON:API mode.
+func (u *UnstructuredClient) pagination() *Pagination {
+	if u.Pagination == nil && u.JSONAPI != nil {
+		return &Pagination{Type: PaginationTypeLink, CursorField: "links.next"}
+	}
+	return u.Pagination
+}
+
+// findInPages scans the pages of the collection starting from the given page (the first one if empty)
+// and returns the first item matching the identifiers, recording the page it was found in.
+// The scan stops with a SearchLimitError once the maximum number of pages or items is scanned.
+func (u *UnstructuredClient) findInPages(ctx context.Context, cli *http.Client, path string, opts *RequestConfiguration, start string) (*map[string]interface{}, error) {
+	p := u.pagination()
+	if p.Type != PaginationTypePage && p.Type != PaginationTypeCursor && p.Type != PaginationTypeLink {
+		return nil, fmt.Errorf("unknown pagination type: %s", p.Type)
+	}
+
+	page := start
+	if page == "" && p.Type == PaginationTypePage {
+		first := p.FirstPage
+		if first == 0 {
+			first = 1
+		}
+		page = strconv.Itoa(first)
+	}
+
+	// last is the number of the last page told by the first page fetched, 0 if unknown (page pagination)
+	last := 0
+	// scanned is the number of items scanned
+	scanned := 0
+	seen := map[string]bool{}
+	for n := 1; ; n++ {
+		list, err := u.listPage(ctx, cli, path, opts, page)
+		if err != nil {
+			return nil, err
+		}
+		items := listItems(list, opts.ItemsPath)
+		if repeated(seen, items) {
+			return nil, nil
+		}
+		item, err := u.findInItems(items)
+		if err != nil {
+			return nil, err
+		}
+		if item != nil {
+			u.FoundPage = page
+			return item, nil
+		}
+		scanned += len(items)
+		u.searched += len(items)
+
+		page, err = p.nextPage(list, u.ResponseHeaders, items, page, n, &last)
+		if err != nil || page == "" {
+			return nil, err
+		}
+
+		// More pages are left to scan
+		if p.MaxPages > 0 && n >= p.MaxPages {
+			return nil, &SearchLimitError{Limit: SearchLimitMaxPages, Value: strconv.Itoa(p.MaxPages)}
+		}
+		if p.MaxItems > 0 && scanned >= p.MaxItems {
+			return nil, &SearchLimitError{Limit: SearchLimitMaxItems, Value: strconv.Itoa(p.MaxItems)}
+		}
+		// The pages after the first one are fetched concurrently, the first one telling the last page if the API exposes it
+		if p.Type == PaginationTypePage && p.Concurrency > 1 {
+			pages, items := 0, 0
+			if p.MaxPages > 0 {
+				pages = p.MaxPages - n
+			}
+			if p.MaxItems > 0 {
+				items = p.MaxItems - scanned
+			}
+			from, _ := strconv.Atoi(page)
+			return u.findInPagesConcurrently(ctx, cli, path, opts, from, last, pages, items)
+		}
+	}
+}
+
+// nextPage returns the page following the given one, the n-th scanned holding the items of the list and answered
+// with the given headers, empty once the whole collection is scanned; last is the number of the last page,
+// told by the first page (page pagination).
+func (p *Pagination) nextPage(list *map[string]interface{}, header http.Header, items []interface{}, page string, n int, last *int) (string, error) {
+	switch p.Type {
+	case PaginationTypePage:
+		if len(items) == 0 {
+			return "", nil
+		}
+		current, err := strconv.Atoi(page)
+		if err != nil {
+			return "", fmt.Errorf("invalid page number %q: %w", page, err)
+		}
+		if n == 1 {
+			*last = p.lastPage(list, len(items))
+		}
+		if *last > 0 && current >= *last {
+			return "", nil
+		}
+		return strconv.Itoa(current + 1), nil
+	case PaginationTypeCursor, PaginationTypeLink:
+		if p.Type == PaginationTypeLink && p.CursorField == "" {
+			return nextLink(header), nil
+		}
+		if list == nil {
+			return "", nil
+		}
+		next, ok, err := unstructured.NestedFieldNoCopy(*list, strings.Split(p.CursorField, ".")...)
+		if err != nil || !ok || next == nil {
+			return "", nil
+		}
+		if link, ok := next.(map[string]interface{}); ok {
+			next = link["href"]
+		}
+		return text.GenericToString(next)
+	}
+	return "", fmt.Errorf("unknown pagination type: %s", p.Type)
+}
+
+// repeated tells whether the items of a page are the same as the ones of a page already scanned, recording
+// them otherwise: some APIs answer the pages past the last one with the last page instead of an empty page,
+// so that the repeated page marks the end of the collection.
+func repeated(seen map[string]bool, items []interface{}) bool {
+	key, ok := pageKey(items)
+	if !ok {
+		return false
+	}
+	if seen[key] {
+		return true
+	}
+	seen[key] = true
+	return false
+}
+
+// pageKey returns the digest of the items of a page, false if the page is empty.
+func pageKey(items []interface{}) (string, bool) {
+	if len(items) == 0 {
+		return "", false
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), true
+}
+
+// nextLink returns the target of the link to the next page in the Link header (e.g. GitHub and GitLab),
+// empty if none.
+func nextLink(header http.Header) string {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if len(target) < 2 || target[0] != '<' || target[len(target)-1] != '>' {
+				continue
+			}
+			for _, param := range parts[1:] {
+				name, rels, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(rels), `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return target[1 : len(target)-1]
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// findInPagesConcurrently scans the pages of the collection from the given page number, fetching up to Concurrency
+// pages at a time until the first empty page or the last page (if not 0) is reached, and returns the first item found
+// matching the identifiers, cancelling the requests still in flight. The scan stops with a SearchLimitError once
+// the given number of pages or items (if not 0) is scanned.
+func (u *UnstructuredClient) findInPagesConcurrently(ctx context.Context, cli *http.Client, path string, opts *RequestConfiguration, from, last, pages, items int) (*map[string]interface{}, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu   sync.Mutex
+		next = from
+		// end is the first empty or repeated page fetched, 0 if none
+		end int
+		// seen are the pages fetched by the digest of their items
+		seen    = map[string]int{}
+		scanned int
+		found   *map[string]interface{}
+		// foundBy is the worker which found the item
+		foundBy int
+		err     error
+		// limited is the error of the limit the scan stopped at, nil if none
+		limited *SearchLimitError
+	)
+	// take returns the number of the next page to fetch, false if the scan is over
+	take := func() (int, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if found != nil || err != nil || (end > 0 && next >= end) || (last > 0 && next > last) {
+			return 0, false
+		}
+		if pages > 0 && next >= from+pages {
+			limited = &SearchLimitError{Limit: SearchLimitMaxPages, Value: strconv.Itoa(u.pagination().MaxPages)}
+			return 0, false
+		}
+		if items > 0 && scanned >= items {
+			limited = &SearchLimitError{Limit: SearchLimitMaxItems, Value: strconv.Itoa(u.pagination().MaxItems)}
+			return 0, false
+		}
+		next++
+		return next - 1, true
+	}
+
+	// Each worker records the requests sent and the headers received in its own copy of the client
+	workers := make([]UnstructuredClient, u.pagination().Concurrency)
+	for i := range workers {
+		workers[i] = *u
+		workers[i].RequestCount = 0
+	}
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := &workers[i]
+			for page, ok := take(); ok; page, ok = take() {
+				list, listErr := w.listPage(ctx, cli, path, opts, strconv.Itoa(page))
+				var pageItems []interface{}
+				var item *map[string]interface{}
+				if listErr == nil {
+					pageItems = listItems(list, opts.ItemsPath)
+					item, listErr = w.findInItems(pageItems)
+				}
+
+				mu.Lock()
+				switch {
+				case found != nil || err != nil:
+					// The scan is over, the requests cancelled failing
+				case listErr != nil:
+					err = listErr
+					cancel()
+				case item != nil:
+					found, foundBy = item, i
+					u.FoundPage = strconv.Itoa(page)
+					cancel()
+				case len(pageItems) == 0:
+					if end == 0 || page < end {
+						end = page
+					}
+				default:
+					key, _ := pageKey(pageItems)
+					other, ok := seen[key]
+					if !ok {
+						seen[key] = page
+						scanned += len(pageItems)
+						break
+					}
+					// The later of the two pages repeats the other one
+					repeat := page
+					if other > page {
+						seen[key], repeat = page, other
+					}
+					if end == 0 || repeat < end {
+						end = repeat
+					}
+				}
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range workers {
+		u.RequestCount += workers[i].RequestCount
+	}
+	u.searched += scanned
+	switch {
+	case found != nil:
+		u.ResponseHeaders = workers[foundBy].ResponseHeaders
+		u.ResponseStatus = workers[foundBy].ResponseStatus
+		return found, nil
+	case err != nil:
+		return nil, err
+	case end > 0 || limited == nil:
+		// The whole collection was scanned
+		return nil, nil
+	}
+	return nil, limited
+}
+
+// listPage lists the items of the given page of the collection, the link of the page for link pagination.
+func (u *UnstructuredClient) listPage(ctx context.Context, cli *http.Client, path string, opts *RequestConfiguration, page string) (*map[string]interface{}, error) {
+	p := u.pagination()
+	query := make(map[string]string, len(opts.Query)+1)
+	for k, v := range opts.Query {
+		query[k] = v
+	}
+	link := ""
+	if p.Type == PaginationTypeLink {
+		link = page
+	} else if page != "" {
+		query[p.PageParam] = page
+	}
+
+	return u.listCollection(ctx, cli, path, &RequestConfiguration{
+		URL:              link,
+		Parameters:       opts.Parameters,
+		ParameterObjects: opts.ParameterObjects,
+		Query:            query,
+		QueryObjects:     opts.QueryObjects,
+		Body:             opts.Body,
+		ItemsPath:        opts.ItemsPath,
+		NotFoundAsEmpty:  opts.NotFoundAsEmpty,
+	})
+}
+
+// listCollection lists the items of the collection searched by FindBy, the collection being empty if the API
+// answers 404 and the request configuration tells to take it as such.
+func (u *UnstructuredClient) listCollection(ctx context.Context, cli *http.Client, path string, opts *RequestConfiguration) (*map[string]interface{}, error) {
+	list, err := u.List(ctx, cli, path, opts)
+	if httplib.IsNotFoundError(err) {
+		if opts.NotFoundAsEmpty {
+			return nil, nil
+		}
+		return nil, &CollectionNotFoundError{Path: path}
+	}
+	return list, err
+}
+
+// ListAll lists the items of the whole collection searched by FindBy, following its pages if paginated.
+// The listing stops with a SearchLimitError, along with the items listed so far, once the maximum number
+// of pages or items of the pagination is listed.
+func (u *UnstructuredClient) ListAll(ctx context.Context, cli *http.Client, path string, opts *RequestConfiguration) ([]interface{}, error) {
+	p := u.pagination()
+	if p == nil {
+		list, err := u.listCollection(ctx, cli, path, opts)
+		if err != nil {
+			return nil, err
+		}
+		return listItems(list, opts.ItemsPath), nil
+	}
+	if p.Type != PaginationTypePage && p.Type != PaginationTypeCursor && p.Type != PaginationTypeLink {
+		return nil, fmt.Errorf("unknown pagination type: %s", p.Type)
+	}
+
+	page := ""
+	if p.Type == PaginationTypePage {
+		first := p.FirstPage
+		if first == 0 {
+			first = 1
+		}
+		page = strconv.Itoa(first)
+	}
+	last := 0
+	all := []interface{}{}
+	seen := map[string]bool{}
+	for n := 1; ; n++ {
+		list, err := u.listPage(ctx, cli, path, opts, page)
+		if err != nil {
+			return all, err
+		}
+		items := listItems(list, opts.ItemsPath)
+		if repeated(seen, items) {
+			return all, nil
+		}
+		all = append(all, items...)
+
+		page, err = p.nextPage(list, u.ResponseHeaders, items, page, n, &last)
+		if err != nil || page == "" {
+			return all, err
+		}
+		if p.MaxPages > 0 && n >= p.MaxPages {
+			return all, &SearchLimitError{Limit: SearchLimitMaxPages, Value: strconv.Itoa(p.MaxPages)}
+		}
+		if p.MaxItems > 0 && len(all) >= p.MaxItems {
+			return all, &SearchLimitError{Limit: SearchLimitMaxItems, Value: strconv.Itoa(p.MaxItems)}
+		}
+	}
+}
+
+// lastPage returns the number of the last page of the collection told by the total fields of a page holding
+// size items, 0 if unknown.
+func (p *Pagination) lastPage(list *map[string]interface{}, size int) int {
+	if list == nil {
+		return 0
+	}
+	first := p.FirstPage
+	if first == 0 {
+		first = 1
+	}
+	if total, ok := nestedInt(*list, p.TotalPagesField); ok {
+		return first + total - 1
+	}
+	if total, ok := nestedInt(*list, p.TotalItemsField); ok && size > 0 {
+		return first + (total+size-1)/size - 1
+	}
+	return 0
+}
+
+// nestedInt returns the integer value of the field at the dot separated path, false if missing or not an integer.
+func nestedInt(obj map[string]interface{}, path string) (int, bool) {
+	if path == "" {
+		return 0, false
+	}
+	v, ok, err := unstructured.NestedFieldNoCopy(obj, strings.Split(path, ".")...)
+	if err != nil || !ok || v == nil {
+		return 0, false
+	}
+	s, err := text.GenericToString(v)
+	if err != nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// itemsKeys are the keys commonly holding the items of the collections, preferred to the other arrays of the response
+var itemsKeys = []string{"items", "data", "results", "value"}
+
+// listItems returns the items of the collection held in the list response: the array at the given dot separated path
+// if any, otherwise the array under one of the common items keys or else the first array found in key order.
+func listItems(list *map[string]interface{}, path string) []interface{} {
+	if list == nil {
+		return nil
+	}
+	if path != "" {
+		v, _, _ := unstructured.NestedFieldNoCopy(*list, strings.Split(path, ".")...)
+		items, _ := v.([]interface{})
+		return items
+	}
+	for _, key := range itemsKeys {
+		if v, ok := (*list)[key].([]interface{}); ok {
+			return v
+		}
+	}
+	keys := make([]string, 0, len(*list))
+	for k := range *list {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if v, ok := (*list)[k].([]interface{}); ok {
+			return v
+		}
+	}
+	return nil
+}
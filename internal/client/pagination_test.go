@@ -0,0 +1,579 @@
+package restclient
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lucasepe/httplib"
+	"github.com/pb33f/libopenapi"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const paginationOAS = `openapi: 3.0.0
+info:
+  title: repos
+  version: "1.0"
+paths:
+  /repos:
+    get:
+      parameters:
+        - name: page
+          in: query
+          schema:
+            type: integer
+      responses:
+        "200":
+          description: ok
+`
+
+func TestFindInPages(t *testing.T) {
+	const pages, size = 20, 2
+
+	d, err := libopenapi.NewDocument([]byte(paginationOAS))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc, errs := d.BuildV3Model()
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	tests := []struct {
+		name        string
+		pagination  Pagination
+		repo        string
+		foundPage   string
+		limit       string
+		searched    int
+		delay       time.Duration
+		maxRequests int
+	}{
+		{
+			name:        "found sequentially",
+			pagination:  Pagination{Type: PaginationTypePage, PageParam: "page"},
+			repo:        "repo-15-1",
+			foundPage:   "15",
+			maxRequests: 15,
+		},
+		{
+			name:        "found concurrently",
+			pagination:  Pagination{Type: PaginationTypePage, PageParam: "page", Concurrency: 4, TotalItemsField: "total"},
+			repo:        "repo-15-1",
+			foundPage:   "15",
+			maxRequests: pages,
+		},
+		{
+			name:        "not found up to the last page",
+			pagination:  Pagination{Type: PaginationTypePage, PageParam: "page", Concurrency: 4, TotalItemsField: "total"},
+			repo:        "repo-21-0",
+			searched:    pages * size,
+			maxRequests: pages,
+		},
+		{
+			name:        "not found within the pages limit",
+			pagination:  Pagination{Type: PaginationTypePage, PageParam: "page", Concurrency: 4, MaxPages: 5},
+			repo:        "repo-15-1",
+			limit:       SearchLimitMaxPages,
+			maxRequests: 5,
+		},
+		{
+			name:        "not found within the items limit",
+			pagination:  Pagination{Type: PaginationTypePage, PageParam: "page", MaxItems: 7},
+			repo:        "repo-15-1",
+			limit:       SearchLimitMaxItems,
+			maxRequests: 4,
+		},
+		{
+			name:        "not found within the pages limit at the last page",
+			pagination:  Pagination{Type: PaginationTypePage, PageParam: "page", MaxPages: pages, TotalPagesField: "pages"},
+			repo:        "repo-21-0",
+			searched:    pages * size,
+			maxRequests: pages,
+		},
+		{
+			name:        "not found within the timeout",
+			pagination:  Pagination{Type: PaginationTypePage, PageParam: "page", Concurrency: 2, Timeout: "50ms"},
+			repo:        "repo-15-1",
+			limit:       SearchLimitTimeout,
+			delay:       20 * time.Millisecond,
+			maxRequests: 10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			fetched := map[int]int{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+				mu.Lock()
+				fetched[page]++
+				mu.Unlock()
+				time.Sleep(tt.delay)
+				items := ""
+				for i := 0; page <= pages && i < size; i++ {
+					if i > 0 {
+						items += ","
+					}
+					items += fmt.Sprintf(`{"name": "repo-%d-%d"}`, page, i)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, `{"items": [%s], "total": %d, "pages": %d}`, items, pages*size, pages)
+			}))
+			defer srv.Close()
+
+			u := &UnstructuredClient{
+				IdentifierFields: []string{"name"},
+				SpecFields: &unstructured.Unstructured{Object: map[string]interface{}{
+					"spec": map[string]interface{}{"name": tt.repo},
+				}},
+				Server:     srv.URL,
+				DocScheme:  doc,
+				Pagination: &tt.pagination,
+			}
+			item, err := u.FindBy(context.Background(), srv.Client(), "/repos", &RequestConfiguration{})
+			var limitErr *SearchLimitError
+			if tt.limit != "" {
+				if !errors.As(err, &limitErr) || limitErr.Limit != tt.limit {
+					t.Errorf("expected the %s limit to be exceeded, got %v, %v", tt.limit, item, err)
+				}
+			} else if tt.foundPage == "" {
+				var notFound *NotFoundError
+				if !httplib.IsNotFoundError(err) || !errors.As(err, &notFound) || notFound.Searched != tt.searched {
+					t.Errorf("expected not found among %d items, got %v, %v", tt.searched, item, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if (*item)["name"] != tt.repo || u.FoundPage != tt.foundPage {
+					t.Errorf("unexpected item %v found in page %s", *item, u.FoundPage)
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if u.RequestCount > tt.maxRequests || len(fetched) > u.RequestCount {
+				t.Errorf("expected at most %d requests, got %d for %v", tt.maxRequests, u.RequestCount, fetched)
+			}
+			for page, n := range fetched {
+				if n > 1 {
+					t.Errorf("expected page %d to be fetched once, got %d times", page, n)
+				}
+			}
+		})
+	}
+}
+
+func TestListItems(t *testing.T) {
+	repos := []interface{}{map[string]interface{}{"name": "repo"}}
+	warnings := []interface{}{"deprecated"}
+	tests := []struct {
+		name     string
+		list     map[string]interface{}
+		path     string
+		expected []interface{}
+	}{
+		{
+			name:     "items path",
+			list:     map[string]interface{}{"warnings": warnings, "result": map[string]interface{}{"repos": repos}},
+			path:     "result.repos",
+			expected: repos,
+		},
+		{
+			name:     "missing items path",
+			list:     map[string]interface{}{"warnings": warnings},
+			path:     "result.repos",
+			expected: nil,
+		},
+		{
+			name:     "common items key",
+			list:     map[string]interface{}{"a_warnings": warnings, "value": repos},
+			expected: repos,
+		},
+		{
+			name:     "first array in key order",
+			list:     map[string]interface{}{"warnings": warnings, "repos": repos},
+			expected: repos,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := listItems(&tt.list, tt.path)
+			if !reflect.DeepEqual(items, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, items)
+			}
+		})
+	}
+}
+
+func TestFindByCollectionNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "404 Not found"}`)
+	}))
+	defer srv.Close()
+
+	d, err := libopenapi.NewDocument([]byte(paginationOAS))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc, errs := d.BuildV3Model()
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	for _, pagination := range []*Pagination{nil, {Type: PaginationTypePage, PageParam: "page"}} {
+		u := &UnstructuredClient{
+			IdentifierFields: []string{"name"},
+			SpecFields: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{"name": "repo"},
+			}},
+			Server:     srv.URL,
+			DocScheme:  doc,
+			Pagination: pagination,
+		}
+
+		_, err = u.FindBy(context.Background(), srv.Client(), "/repos", &RequestConfiguration{})
+		var collectionErr *CollectionNotFoundError
+		if !errors.As(err, &collectionErr) || httplib.IsNotFoundError(err) {
+			t.Errorf("expected the collection not to be found, got %v", err)
+		}
+
+		_, err = u.FindBy(context.Background(), srv.Client(), "/repos", &RequestConfiguration{NotFoundAsEmpty: true})
+		var notFound *NotFoundError
+		if !errors.As(err, &notFound) || notFound.Searched != 0 {
+			t.Errorf("expected the collection to be empty, got %v", err)
+		}
+	}
+}
+
+func TestFindByCompressedPages(t *testing.T) {
+	const pages, size = 5, 500
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != acceptEncoding {
+			t.Errorf("unexpected Accept-Encoding %q", r.Header.Get("Accept-Encoding"))
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		items := []string{}
+		for i := 0; page <= pages && i < size; i++ {
+			// ISO-8859-1 encoded names
+			items = append(items, fmt.Sprintf("{\"name\": \"d\xe9p\xf4t-%d-%d\", \"description\": %q}", page, i, strings.Repeat("x", 100)))
+		}
+		w.Header().Set("Content-Type", "application/json; charset=ISO-8859-1")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		fmt.Fprintf(gz, `{"items": [%s]}`, strings.Join(items, ","))
+		gz.Close()
+	}))
+	defer srv.Close()
+
+	d, err := libopenapi.NewDocument([]byte(paginationOAS))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc, errs := d.BuildV3Model()
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	u := &UnstructuredClient{
+		IdentifierFields: []string{"name"},
+		SpecFields: &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"name": "dépôt-4-321"},
+		}},
+		Server:     srv.URL,
+		DocScheme:  doc,
+		Pagination: &Pagination{Type: PaginationTypePage, PageParam: "page"},
+	}
+	item, err := u.FindBy(context.Background(), srv.Client(), "/repos", &RequestConfiguration{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if (*item)["name"] != "dépôt-4-321" || u.FoundPage != "4" {
+		t.Errorf("unexpected item %v found in page %s", *item, u.FoundPage)
+	}
+}
+
+func TestListAll(t *testing.T) {
+	const pages, size = 5, 2
+
+	d, err := libopenapi.NewDocument([]byte(paginationOAS))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc, errs := d.BuildV3Model()
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		items := []string{}
+		for i := 0; page <= pages && i < size; i++ {
+			items = append(items, fmt.Sprintf(`{"name": "repo-%d-%d"}`, page, i))
+		}
+		next := ""
+		if page < pages {
+			next = strconv.Itoa(page + 1)
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s/repos?page=%s>; rel="next", <http://%s/repos?page=%d>; rel="last"`, r.Host, next, r.Host, pages))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"items": [%s], "next": %q}`, strings.Join(items, ","), next)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		pagination *Pagination
+		items      int
+		limit      string
+	}{
+		{name: "not paginated", items: size},
+		{name: "page", pagination: &Pagination{Type: PaginationTypePage, PageParam: "page"}, items: pages * size},
+		{name: "cursor", pagination: &Pagination{Type: PaginationTypeCursor, PageParam: "page", CursorField: "next"}, items: pages * size},
+		{name: "link header", pagination: &Pagination{Type: PaginationTypeLink}, items: pages * size},
+		{name: "pages limit", pagination: &Pagination{Type: PaginationTypePage, PageParam: "page", MaxPages: 2}, items: 2 * size, limit: SearchLimitMaxPages},
+		{name: "items limit", pagination: &Pagination{Type: PaginationTypePage, PageParam: "page", MaxItems: 5}, items: 6, limit: SearchLimitMaxItems},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &UnstructuredClient{Server: srv.URL, DocScheme: doc, Pagination: tt.pagination}
+			items, err := u.ListAll(context.Background(), srv.Client(), "/repos", &RequestConfiguration{})
+			var limitErr *SearchLimitError
+			if tt.limit != "" {
+				if !errors.As(err, &limitErr) || limitErr.Limit != tt.limit {
+					t.Errorf("expected the %s limit to be exceeded, got %v", tt.limit, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(items) != tt.items {
+				t.Errorf("expected %d items, got %d", tt.items, len(items))
+			}
+		})
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		want   string
+	}{
+		{name: "no header"},
+		{
+			name:   "github",
+			header: []string{`<https://api.github.com/organizations/1/repos?page=2>; rel="next", <https://api.github.com/organizations/1/repos?page=5>; rel="last"`},
+			want:   "https://api.github.com/organizations/1/repos?page=2",
+		},
+		{
+			name:   "next not first",
+			header: []string{`<https://gitlab.example.com/api/v4/projects?page=1>; rel="first"`, `<https://gitlab.example.com/api/v4/projects?page=3>; rel="next"`},
+			want:   "https://gitlab.example.com/api/v4/projects?page=3",
+		},
+		{
+			name:   "multiple relations",
+			header: []string{`</repos?page=2>; title="more"; rel="next last"`},
+			want:   "/repos?page=2",
+		},
+		{
+			name:   "last page",
+			header: []string{`<https://api.github.com/organizations/1/repos?page=4>; rel="prev", <https://api.github.com/organizations/1/repos?page=1>; rel="first"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for _, v := range tt.header {
+				header.Add("Link", v)
+			}
+			if got := nextLink(header); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFindByRepeatedPages(t *testing.T) {
+	const pages, size = 3, 2
+
+	d, err := libopenapi.NewDocument([]byte(paginationOAS))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc, errs := d.BuildV3Model()
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	// The pages past the last one are answered with the last page, never with an empty page
+	var mu sync.Mutex
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page > pages {
+			page = pages
+		}
+		items := []string{}
+		for i := 0; i < size; i++ {
+			items = append(items, fmt.Sprintf(`{"name": "repo-%d-%d"}`, page, i))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"items": [%s]}`, strings.Join(items, ","))
+	}))
+	defer srv.Close()
+
+	for _, pagination := range []Pagination{
+		{Type: PaginationTypePage, PageParam: "page"},
+		{Type: PaginationTypePage, PageParam: "page", Concurrency: 2},
+	} {
+		u := &UnstructuredClient{
+			IdentifierFields: []string{"name"},
+			SpecFields: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{"name": "repo-4-0"},
+			}},
+			Server:     srv.URL,
+			DocScheme:  doc,
+			Pagination: &pagination,
+		}
+		done := make(chan error, 1)
+		go func() {
+			_, err := u.FindBy(context.Background(), srv.Client(), "/repos", &RequestConfiguration{})
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			var notFound *NotFoundError
+			if !errors.As(err, &notFound) || notFound.Searched != pages*size {
+				t.Errorf("expected not found among %d items with concurrency %d, got %v", pages*size, pagination.Concurrency, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected the search to stop at the repeated page with concurrency %d", pagination.Concurrency)
+		}
+	}
+
+	u := &UnstructuredClient{Server: srv.URL, DocScheme: doc, Pagination: &Pagination{Type: PaginationTypePage, PageParam: "page"}}
+	items, err := u.ListAll(context.Background(), srv.Client(), "/repos", &RequestConfiguration{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != pages*size {
+		t.Errorf("expected %d items, got %d", pages*size, len(items))
+	}
+}
+
+func TestFindByStaleStartPage(t *testing.T) {
+	const pages, size = 3, 2
+
+	d, err := libopenapi.NewDocument([]byte(paginationOAS))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc, errs := d.BuildV3Model()
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	// The cursors are the page numbers, the ones of past searches being expired
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cursor := r.URL.Query().Get("page")
+		page := 1
+		if cursor != "" {
+			var err error
+			if page, err = strconv.Atoi(cursor); err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"message": "invalid cursor"}`)
+				return
+			}
+		}
+		items := []string{}
+		for i := 0; i < size; i++ {
+			items = append(items, fmt.Sprintf(`{"name": "repo-%d-%d"}`, page, i))
+		}
+		next := ""
+		if page < pages {
+			next = strconv.Itoa(page + 1)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"items": [%s], "next": %q}`, strings.Join(items, ","), next)
+	}))
+	defer srv.Close()
+
+	u := &UnstructuredClient{
+		IdentifierFields: []string{"name"},
+		SpecFields: &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"name": "repo-2-1"},
+		}},
+		Server:     srv.URL,
+		DocScheme:  doc,
+		Pagination: &Pagination{Type: PaginationTypeCursor, PageParam: "page", CursorField: "next", Resume: true},
+		StartPage:  "expired",
+	}
+	item, err := u.FindBy(context.Background(), srv.Client(), "/repos", &RequestConfiguration{})
+	if err != nil {
+		t.Fatalf("expected the search to start again from the first page, got %v", err)
+	}
+	if (*item)["name"] != "repo-2-1" || u.FoundPage != "2" {
+		t.Errorf("unexpected item %v found in page %s", *item, u.FoundPage)
+	}
+}
+
+func TestFindByLinkToOtherHost(t *testing.T) {
+	d, err := libopenapi.NewDocument([]byte(paginationOAS))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc, errs := d.BuildV3Model()
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	called := false
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"items": []}`)
+	}))
+	defer other.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", fmt.Sprintf(`<%s/repos?page=2>; rel="next"`, other.URL))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"items": [{"name": "repo-1-0"}]}`)
+	}))
+	defer srv.Close()
+
+	u := &UnstructuredClient{
+		IdentifierFields: []string{"name"},
+		SpecFields: &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"name": "repo-2-0"},
+		}},
+		Server:     srv.URL,
+		DocScheme:  doc,
+		Pagination: &Pagination{Type: PaginationTypeLink},
+	}
+	if _, err := u.FindBy(context.Background(), srv.Client(), "/repos", &RequestConfiguration{}); err == nil || httplib.IsNotFoundError(err) {
+		t.Errorf("expected the link to another host to be refused, got %v", err)
+	}
+	if called {
+		t.Errorf("expected the other host not to be called")
+	}
+}
@@ -0,0 +1,156 @@
+package restclient
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// buildURL builds the URL of the request, serializing the path and query parameters
+// according to the style and explode options of the operation parameters.
+func buildURL(baseUrl string, path string, pathItem *v3.PathItem, httpMethod string, opts *RequestConfiguration) *url.URL {
+	if opts.URL != "" {
+		base, err := url.Parse(baseUrl)
+		if err != nil {
+			return &url.URL{}
+		}
+		ref, err := url.Parse(opts.URL)
+		if err != nil {
+			return &url.URL{}
+		}
+		return base.ResolveReference(ref)
+	}
+
+	pathParams := operationParams(pathItem, httpMethod, "path")
+	exprs := pathExprs(path)
+
+	parameters := make(map[string]string, len(opts.Parameters))
+	for key, value := range opts.Parameters {
+		obj, ok := opts.ParameterObjects[key]
+		if !ok {
+			parameters[key] = escapePathValue(value, exprs[key].wildcard)
+			continue
+		}
+		style, explode := "simple", false
+		if param, ok := pathParams[key]; ok {
+			if param.Style != "" {
+				style = param.Style
+			}
+			if param.Explode != nil {
+				explode = *param.Explode
+			}
+		}
+		parameters[key] = serializePath(key, obj, style, explode)
+	}
+
+	uri := buildPath(baseUrl, path, parameters, nil)
+	if uri == nil {
+		return &url.URL{}
+	}
+	uri.RawQuery = encodeQuery(operationParams(pathItem, httpMethod, "query"), opts)
+	return uri
+}
+
+// checkLink fails if the link of the request (e.g. the link of the next page or of a related resource) leads
+// to another host than the server, since the credentials of the resource would be sent to it along the request,
+// or to another scheme, since they would be sent in clear text over http.
+func checkLink(server string, link string) error {
+	base, err := url.Parse(server)
+	if err != nil {
+		return fmt.Errorf("parsing server URL %q: %w", server, err)
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return fmt.Errorf("parsing link %q: %w", link, err)
+	}
+	target := base.ResolveReference(ref)
+	if !strings.EqualFold(target.Host, base.Host) {
+		return fmt.Errorf("refusing to follow the link %s: its host differs from the server %s", link, server)
+	}
+	if !strings.EqualFold(target.Scheme, base.Scheme) {
+		return fmt.Errorf("refusing to follow the link %s: its scheme differs from the server %s", link, server)
+	}
+	return nil
+}
+
+// operationParams returns the parameters of the operation in the given location (path, query), by name.
+func operationParams(pathItem *v3.PathItem, httpMethod string, in string) map[string]*v3.Parameter {
+	params := map[string]*v3.Parameter{}
+	if pathItem == nil {
+		return params
+	}
+	op, ok := getOperation(pathItem, httpMethod)
+	if !ok {
+		return params
+	}
+	for _, param := range op.Parameters {
+		if param.In == in {
+			params[param.Name] = param
+		}
+	}
+	return params
+}
+
+// serializePath serializes the array or object path parameter according to the OAS style (simple, label, matrix)
+// and explode options, escaping the values.
+func serializePath(name string, value interface{}, style string, explode bool) string {
+	prefix, sep := "", ","
+	switch style {
+	case "label":
+		prefix = "."
+		if explode {
+			sep = "."
+		}
+	case "matrix":
+		prefix = ";"
+		if explode {
+			sep = ";"
+		}
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			val := url.PathEscape(fmt.Sprintf("%v", item))
+			if style == "matrix" && explode {
+				val = name + "=" + val
+			}
+			values = append(values, val)
+		}
+		res := strings.Join(values, sep)
+		if style == "matrix" && !explode {
+			res = name + "=" + res
+		}
+		return prefix + res
+	case map[string]interface{}:
+		props := make([]string, 0, len(v))
+		for prop := range v {
+			props = append(props, prop)
+		}
+		sort.Strings(props)
+
+		values := make([]string, 0, len(props))
+		for _, prop := range props {
+			kv := url.PathEscape(prop) + "," + url.PathEscape(fmt.Sprintf("%v", v[prop]))
+			if explode {
+				kv = url.PathEscape(prop) + "=" + url.PathEscape(fmt.Sprintf("%v", v[prop]))
+			}
+			values = append(values, kv)
+		}
+		res := strings.Join(values, sep)
+		if style == "matrix" && !explode {
+			res = name + "=" + res
+		}
+		return prefix + res
+	default:
+		res := url.PathEscape(fmt.Sprintf("%v", v))
+		if style == "matrix" {
+			res = name + "=" + res
+		}
+		return prefix + res
+	}
+}
@@ -0,0 +1,73 @@
+package restclient
+
+import (
+	"testing"
+)
+
+func TestSerializePath(t *testing.T) {
+	ids := []interface{}{3, 4, 5}
+	color := map[string]interface{}{"R": 100, "G": 200}
+
+	tests := []struct {
+		style    string
+		explode  bool
+		value    interface{}
+		expected string
+	}{
+		{style: "simple", explode: false, value: ids, expected: "3,4,5"},
+		{style: "simple", explode: true, value: color, expected: "G=200,R=100"},
+		{style: "simple", explode: false, value: color, expected: "G,200,R,100"},
+		{style: "label", explode: false, value: ids, expected: ".3,4,5"},
+		{style: "label", explode: true, value: ids, expected: ".3.4.5"},
+		{style: "label", explode: true, value: color, expected: ".G=200.R=100"},
+		{style: "matrix", explode: false, value: ids, expected: ";id=3,4,5"},
+		{style: "matrix", explode: true, value: ids, expected: ";id=3;id=4;id=5"},
+		{style: "matrix", explode: true, value: color, expected: ";G=200;R=100"},
+		{style: "matrix", explode: false, value: "a/b", expected: ";id=a%2Fb"},
+	}
+
+	for _, tc := range tests {
+		got := serializePath("id", tc.value, tc.style, tc.explode)
+		if got != tc.expected {
+			t.Errorf("%s (explode: %v): expected %s, got %s", tc.style, tc.explode, tc.expected, got)
+		}
+	}
+}
+
+func TestBuildURL(t *testing.T) {
+	uri := buildURL("https://gitlab.example.com/api/v4", "/projects/{id}/issues/{title}", nil, "GET", &RequestConfiguration{
+		Parameters: map[string]string{
+			"id":    "group/project",
+			"title": "hello wörld",
+		},
+	})
+
+	expected := "https://gitlab.example.com/api/v4/projects/group%2Fproject/issues/hello%20w%C3%B6rld"
+	if uri.String() != expected {
+		t.Errorf("expected %s, got %s", expected, uri.String())
+	}
+}
+
+func TestCheckLink(t *testing.T) {
+	tests := []struct {
+		name    string
+		link    string
+		wantErr bool
+	}{
+		{name: "relative link", link: "/repos?page=2"},
+		{name: "same host", link: "https://API.example.com/v1/repos?page=2"},
+		{name: "other host", link: "https://evil.example.com/v1/repos?page=2", wantErr: true},
+		{name: "other port", link: "https://api.example.com:8443/v1/repos?page=2", wantErr: true},
+		{name: "protocol relative link to other host", link: "//evil.example.com/v1/repos", wantErr: true},
+		{name: "same scheme", link: "HTTPS://api.example.com/v1/repos?page=2"},
+		{name: "other scheme", link: "http://api.example.com/v1/repos?page=2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkLink("https://api.example.com/v1", tt.link)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package restclient
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// pathExprRegex matches the expressions of the path templates: the simple ones (e.g. {repo}), the wildcard
+// ones whose values may span multiple segments (e.g. {path*} or {+path}), and the optional ones removed with
+// their segment when the parameter is not set (e.g. {version?} or {/version}).
+var pathExprRegex = regexp.MustCompile(`\{([/+]?)([^{}/+*?][^{}*?]*)([*?]?)\}`)
+
+// pathExpr is an expression of a path template.
+type pathExpr struct {
+	// name of the parameter
+	name string
+	// wildcard parameters keep the slashes of their values, escaping each segment
+	wildcard bool
+	// optional parameters are removed with their segment when not set
+	optional bool
+	// segment expressions ({/name}) expand to a slash followed by the value, or to nothing when not set
+	segment bool
+}
+
+func parsePathExpr(match []string) pathExpr {
+	return pathExpr{
+		name:     match[2],
+		wildcard: match[3] == "*" || match[1] == "+",
+		optional: match[3] == "?" || match[1] == "/",
+		segment:  match[1] == "/",
+	}
+}
+
+// pathExprs returns the expressions of the path template, by parameter name.
+func pathExprs(path string) map[string]pathExpr {
+	exprs := map[string]pathExpr{}
+	for _, match := range pathExprRegex.FindAllStringSubmatch(path, -1) {
+		expr := parsePathExpr(match)
+		exprs[expr.name] = expr
+	}
+	return exprs
+}
+
+// OptionalPathParams returns the parameters of the path template that may be left unset (e.g. {version?}).
+func OptionalPathParams(path string) []string {
+	var optional []string
+	for name, expr := range pathExprs(path) {
+		if expr.optional {
+			optional = append(optional, name)
+		}
+	}
+	return optional
+}
+
+// escapePathValue escapes the value of a path parameter, escaping each segment of the wildcard ones
+// so that their slashes are kept (e.g. docs/readme.md for {path*}).
+func escapePathValue(value string, wildcard bool) string {
+	if !wildcard {
+		return url.PathEscape(value)
+	}
+	segments := strings.Split(value, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// expandPath replaces the expressions of the path template with the escaped values of the parameters,
+// removing the optional ones not set together with the slash preceding them; the expressions of the
+// other parameters not set are left as they are.
+func expandPath(path string, parameters map[string]string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range pathExprRegex.FindAllStringSubmatchIndex(path, -1) {
+		match := make([]string, 4)
+		for i := range match {
+			if loc[2*i] >= 0 {
+				match[i] = path[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		expr := parsePathExpr(match)
+		prefix := path[last:loc[0]]
+		last = loc[1]
+
+		value, ok := parameters[expr.name]
+		switch {
+		case ok && value != "":
+			sb.WriteString(prefix)
+			if expr.segment {
+				sb.WriteString("/")
+			}
+			sb.WriteString(value)
+		case expr.optional:
+			// Removing the whole segment of the optional parameter (e.g. /{version?}/ becomes /)
+			if !expr.segment && strings.HasSuffix(prefix, "/") && (last == len(path) || path[last] == '/') {
+				prefix = strings.TrimSuffix(prefix, "/")
+			}
+			sb.WriteString(prefix)
+		case ok:
+			sb.WriteString(prefix)
+		default:
+			sb.WriteString(prefix)
+			sb.WriteString(match[0])
+		}
+	}
+	sb.WriteString(path[last:])
+	return sb.String()
+}
@@ -0,0 +1,110 @@
+package restclient
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestExpandPath(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		parameters map[string]string
+		want       string
+	}{
+		{
+			name:       "simple parameters",
+			path:       "/repos/{owner}/{repo}",
+			parameters: map[string]string{"owner": "krateo", "repo": "core"},
+			want:       "/repos/krateo/core",
+		},
+		{
+			name:       "parameter not set is left as it is",
+			path:       "/repos/{owner}/{repo}",
+			parameters: map[string]string{"owner": "krateo"},
+			want:       "/repos/krateo/{repo}",
+		},
+		{
+			name:       "wildcard parameter",
+			path:       "/files/{path*}/raw",
+			parameters: map[string]string{"path": "docs/readme.md"},
+			want:       "/files/docs/readme.md/raw",
+		},
+		{
+			name:       "reserved wildcard parameter",
+			path:       "/files/{+path}",
+			parameters: map[string]string{"path": "docs/readme.md"},
+			want:       "/files/docs/readme.md",
+		},
+		{
+			name:       "optional parameter set",
+			path:       "/api/{version?}/items",
+			parameters: map[string]string{"version": "v2"},
+			want:       "/api/v2/items",
+		},
+		{
+			name: "optional parameter not set",
+			path: "/api/{version?}/items",
+			want: "/api/items",
+		},
+		{
+			name:       "optional parameter empty at the end",
+			path:       "/items/{id?}",
+			parameters: map[string]string{"id": ""},
+			want:       "/items",
+		},
+		{
+			name:       "optional parameter inside a segment",
+			path:       "/items/report{format?}",
+			parameters: map[string]string{},
+			want:       "/items/report",
+		},
+		{
+			name:       "optional segment set",
+			path:       "/items{/id}/tags",
+			parameters: map[string]string{"id": "42"},
+			want:       "/items/42/tags",
+		},
+		{
+			name: "optional segment not set",
+			path: "/items{/id}/tags",
+			want: "/items/tags",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expandPath(tt.path, tt.parameters); got != tt.want {
+				t.Errorf("expandPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapePathValue(t *testing.T) {
+	if got := escapePathValue("docs/read me.md", false); got != "docs%2Fread%20me.md" {
+		t.Errorf("unexpected simple value: %q", got)
+	}
+	if got := escapePathValue("docs/read me.md", true); got != "docs/read%20me.md" {
+		t.Errorf("unexpected wildcard value: %q", got)
+	}
+}
+
+func TestOptionalPathParams(t *testing.T) {
+	got := OptionalPathParams("/api/{version?}/files/{path*}{/format}/{id}")
+	sort.Strings(got)
+	if len(got) != 2 || got[0] != "format" || got[1] != "version" {
+		t.Errorf("unexpected optional params: %v", got)
+	}
+}
+
+func TestPathParamsModifiers(t *testing.T) {
+	params := PathParams("/files/{path*}/{+rest}/{version?}{/format}")
+	for _, name := range []string{"path", "rest", "version", "format"} {
+		if !params.Contains(name) {
+			t.Errorf("expected %s in path params: %v", name, params)
+		}
+	}
+	if len(params) != 4 {
+		t.Errorf("unexpected path params: %v", params)
+	}
+}
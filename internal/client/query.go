@@ -0,0 +1,92 @@
+package restclient
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// encodeQuery encodes the query parameters of the request, serializing the array and object ones
+// according to the style and explode options of the operation parameters (defaults to form, exploded).
+func encodeQuery(params map[string]*v3.Parameter, opts *RequestConfiguration) string {
+	keys := make([]string, 0, len(opts.Query))
+	for key := range opts.Query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := []string{}
+	for _, key := range keys {
+		value, ok := opts.QueryObjects[key]
+		if !ok {
+			parts = append(parts, url.QueryEscape(key)+"="+url.QueryEscape(opts.Query[key]))
+			continue
+		}
+		style, explode := "form", true
+		if param, ok := params[key]; ok {
+			if param.Style != "" {
+				style = param.Style
+			}
+			if param.Explode != nil {
+				explode = *param.Explode
+			}
+		}
+		parts = append(parts, serializeQuery(key, value, style, explode)...)
+	}
+	return strings.Join(parts, "&")
+}
+
+// serializeQuery serializes the array or object query parameter according to the OAS style and explode options.
+func serializeQuery(name string, value interface{}, style string, explode bool) []string {
+	key := url.QueryEscape(name)
+	switch v := value.(type) {
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, url.QueryEscape(fmt.Sprintf("%v", item)))
+		}
+		if explode && style != "spaceDelimited" && style != "pipeDelimited" {
+			parts := make([]string, 0, len(values))
+			for _, val := range values {
+				parts = append(parts, key+"="+val)
+			}
+			return parts
+		}
+		sep := ","
+		switch style {
+		case "spaceDelimited":
+			sep = "%20"
+		case "pipeDelimited":
+			sep = "|"
+		}
+		return []string{key + "=" + strings.Join(values, sep)}
+	case map[string]interface{}:
+		props := make([]string, 0, len(v))
+		for prop := range v {
+			props = append(props, prop)
+		}
+		sort.Strings(props)
+
+		parts := make([]string, 0, len(props))
+		for _, prop := range props {
+			val := url.QueryEscape(fmt.Sprintf("%v", v[prop]))
+			switch {
+			case style == "deepObject":
+				parts = append(parts, key+"["+url.QueryEscape(prop)+"]="+val)
+			case explode:
+				parts = append(parts, url.QueryEscape(prop)+"="+val)
+			default:
+				parts = append(parts, url.QueryEscape(prop)+","+val)
+			}
+		}
+		if style != "deepObject" && !explode {
+			return []string{key + "=" + strings.Join(parts, ",")}
+		}
+		return parts
+	default:
+		return []string{key + "=" + url.QueryEscape(fmt.Sprintf("%v", v))}
+	}
+}
@@ -0,0 +1,50 @@
+package restclient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSerializeQuery(t *testing.T) {
+	tags := []interface{}{"a", "b c"}
+	color := map[string]interface{}{"R": 100, "G": 200}
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		style    string
+		explode  bool
+		expected []string
+	}{
+		{name: "tags", value: tags, style: "form", explode: true, expected: []string{"tags=a", "tags=b+c"}},
+		{name: "tags", value: tags, style: "form", explode: false, expected: []string{"tags=a,b+c"}},
+		{name: "tags", value: tags, style: "spaceDelimited", explode: false, expected: []string{"tags=a%20b+c"}},
+		{name: "tags", value: tags, style: "pipeDelimited", explode: false, expected: []string{"tags=a|b+c"}},
+		{name: "color", value: color, style: "form", explode: true, expected: []string{"G=200", "R=100"}},
+		{name: "color", value: color, style: "form", explode: false, expected: []string{"color=G,200,R,100"}},
+		{name: "color", value: color, style: "deepObject", explode: true, expected: []string{"color[G]=200", "color[R]=100"}},
+		{name: "page", value: 2, style: "form", explode: true, expected: []string{"page=2"}},
+	}
+
+	for _, tc := range tests {
+		got := serializeQuery(tc.name, tc.value, tc.style, tc.explode)
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s (%s, explode: %v): expected %v, got %v", tc.name, tc.style, tc.explode, tc.expected, got)
+		}
+	}
+}
+
+func TestEncodeQuery(t *testing.T) {
+	got := encodeQuery(nil, &RequestConfiguration{
+		Query: map[string]string{
+			"sort": "name",
+			"tags": "[a b]",
+		},
+		QueryObjects: map[string]interface{}{
+			"tags": []interface{}{"a", "b"},
+		},
+	})
+	if expected := "sort=name&tags=a&tags=b"; got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
@@ -2,12 +2,15 @@ package restclient
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"reflect"
 	"strings"
+	"time"
 
 	"fmt"
 
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
 	unstructuredtools "github.com/krateoplatformops/unstructured-runtime/pkg/tools/unstructured"
 	"github.com/lucasepe/httplib"
 	"github.com/pb33f/libopenapi"
@@ -22,6 +25,27 @@ type UnstructuredClient struct {
 	DocScheme        *libopenapi.DocumentModel[v3.Document]
 	Auth             httplib.AuthMethod
 	Verbose          bool
+	// Pagination of the collections searched by FindBy, nil if not paginated
+	Pagination *Pagination
+	// StartPage is the page FindBy starts searching from, the first one if empty
+	StartPage string
+	// FoundPage is the page where FindBy last found the item
+	FoundPage string
+	// ResponseHeaders are the headers of the response to the last request, nil if none
+	ResponseHeaders http.Header
+	// ResponseStatus is the status code of the response to the last request, 0 if none (e.g. on a transport error)
+	ResponseStatus int
+	// RequestCount is the number of requests sent by the client
+	RequestCount int
+	// JSONAPI enables the JSON:API protocol mode, nil if disabled
+	JSONAPI *JSONAPI
+	// Capabilities caches the methods allowed by the API as probed by Probe, nil if not probed
+	Capabilities *CapabilityCache
+	// Owner tells FindBy the ownership markers of the custom resource, so that the items found by the same
+	// identifiers but marked by another custom resource are skipped; nil if the markers are not used
+	Owner *Owner
+	// searched is the number of items searched by the last FindBy
+	searched int
 }
 
 // 'field' could be in the format of 'spec.field1.field2'
@@ -42,6 +66,10 @@ func (u *UnstructuredClient) isInSpecFields(field, value string) (bool, error) {
 	if reflect.DeepEqual(val, value) {
 		return true, nil
 	}
+	// Comparing numeric and boolean spec values with their string representation
+	if str, err := text.GenericToString(val); err == nil && str == value {
+		return true, nil
+	}
 
 	return false, nil
 }
@@ -60,25 +88,43 @@ type APIError struct {
 	TypeKey   string `json:"typeKey"`
 	ErrorCode int    `json:"errorCode"`
 	EventID   int    `json:"eventId"`
+	// Errors holds the error objects of JSON:API error responses
+	Errors []JSONAPIError `json:"errors,omitempty"`
 }
 
 type RequestConfiguration struct {
 	Parameters map[string]string
 	Query      map[string]string
-	Body       interface{}
+	// ParameterObjects holds the array and object path parameters, serialized according to their OAS style
+	ParameterObjects map[string]interface{}
+	// QueryObjects holds the array and object query parameters, serialized according to their OAS style
+	QueryObjects map[string]interface{}
+	// Headers holds the custom headers of the request
+	Headers map[string]string
+	// Cookies holds the cookies of the request
+	Cookies map[string]string
+	// URL overrides the URL of the request (e.g. the link of the next page), resolved against the server
+	URL  string
+	Body interface{}
+	// ItemsPath is the dot separated path of the array holding the items of the collection listed by FindBy
+	ItemsPath string
+	// NotFoundAsEmpty tells FindBy to take a 404 answer listing the collection as an empty collection
+	NotFoundAsEmpty bool
+	// StatusCodes overrides the outcome of the response status codes described by the OAS
+	StatusCodes []StatusCode
 }
 
 func (u *UnstructuredClient) Get(ctx context.Context, cli *http.Client, path string, opts *RequestConfiguration) (*map[string]interface{}, error) {
-	uri := buildPath(u.Server, path, opts.Parameters, opts.Query)
 	pathItem, ok := u.DocScheme.Model.Paths.PathItems.Get(path)
 	if !ok {
 		return nil, fmt.Errorf("path not found - Get: %s", path)
 	}
+	uri := buildURL(u.Server, path, pathItem, "GET", opts)
 	if len(pathItem.Get.Servers) > 0 {
-		uri = buildPath(pathItem.Get.Servers[0].URL, path, opts.Parameters, opts.Query)
+		uri = buildURL(pathItem.Get.Servers[0].URL, path, pathItem, "GET", opts)
 	}
 
-	err := u.ValidateRequest("GET", path, opts.Parameters, opts.Query)
+	err := u.validateRequest("GET", path, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -86,6 +132,8 @@ func (u *UnstructuredClient) Get(ctx context.Context, cli *http.Client, path str
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
+	u.setRequestHeaders(req, opts)
 
 	var val map[string]interface{}
 	apiErr := &APIError{}
@@ -100,6 +148,7 @@ func (u *UnstructuredClient) Get(ctx context.Context, cli *http.Client, path str
 	if err != nil {
 		return nil, err
 	}
+	validStatusCodes = acceptedStatusCodes(validStatusCodes, opts)
 
 	var response any
 	rh := func(r *http.Response) error {
@@ -112,47 +161,92 @@ func (u *UnstructuredClient) Get(ctx context.Context, cli *http.Client, path str
 		if r.Body == nil {
 			return &httplib.StatusError{StatusCode: 404}
 		}
-		return httplib.FromJSON(&response)(r)
+		return fromJSON(&response)(r)
 	}
 
-	err = httplib.Fire(cli, req, httplib.FireOptions{
+	err = httplib.Fire(u.recordHeaders(cli), req, httplib.FireOptions{
 		Verbose:         u.Verbose,
 		ResponseHandler: rh,
 		AuthMethod:      u.Auth,
 		Validators: []httplib.HandleResponseFunc{
-			httplib.ErrorJSON(apiErr, validStatusCodes...),
+			errorResponse(apiErr, validStatusCodes...),
 		},
 	})
 	if err != nil {
 		return nil, err
 	}
-	val, ok = response.(map[string]interface{})
+	val, ok = u.transformResponse(getDoc, u.decodeResponse(response)).(map[string]interface{})
 	if !ok {
 		return nil, nil
 	}
 	return &val, nil
 }
 
+// Head checks the existence of the resource without downloading its representation.
+func (u *UnstructuredClient) Head(ctx context.Context, cli *http.Client, path string, opts *RequestConfiguration) (*map[string]interface{}, error) {
+	pathItem, ok := u.DocScheme.Model.Paths.PathItems.Get(path)
+	if !ok {
+		return nil, fmt.Errorf("path not found - Head: %s", path)
+	}
+
+	httpMethod := "HEAD"
+	headDoc, ok := getOperation(pathItem, httpMethod)
+	if !ok {
+		return nil, fmt.Errorf("operation not found: %s", httpMethod)
+	}
+	uri := buildURL(u.Server, path, pathItem, httpMethod, opts)
+	if len(headDoc.Servers) > 0 {
+		uri = buildURL(headDoc.Servers[0].URL, path, pathItem, httpMethod, opts)
+	}
+
+	err := u.validateRequest(httpMethod, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, uri.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	u.setRequestHeaders(req, opts)
+
+	validStatusCodes, err := getValidResponseCode(headDoc.Responses.Codes)
+	if err != nil {
+		return nil, err
+	}
+	validStatusCodes = acceptedStatusCodes(validStatusCodes, opts)
+
+	err = httplib.Fire(u.recordHeaders(cli), req, httplib.FireOptions{
+		Verbose:    u.Verbose,
+		AuthMethod: u.Auth,
+		Validators: []httplib.HandleResponseFunc{
+			httplib.CheckStatus(validStatusCodes...),
+		},
+	})
+	return nil, err
+}
+
 func (u *UnstructuredClient) Post(ctx context.Context, cli *http.Client, path string, opts *RequestConfiguration) (*map[string]interface{}, error) {
-	uri := buildPath(u.Server, path, opts.Parameters, opts.Query)
 	pathItem, ok := u.DocScheme.Model.Paths.PathItems.Get(path)
 	if !ok {
 		return nil, fmt.Errorf("path not found: %s", path)
 	}
+	uri := buildURL(u.Server, path, pathItem, "POST", opts)
 	if len(pathItem.Post.Servers) > 0 {
-		uri = buildPath(pathItem.Post.Servers[0].URL, path, opts.Parameters, opts.Query)
+		uri = buildURL(pathItem.Post.Servers[0].URL, path, pathItem, "POST", opts)
 	}
 
-	err := u.ValidateRequest("POST", path, opts.Parameters, opts.Query)
+	err := u.validateRequest("POST", path, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := httplib.Post(uri.String(), httplib.ToJSON(opts.Body))
+	req, err := httplib.Post(uri.String(), httplib.ToJSON(u.requestBody(opts)))
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Add("Content-Type", "application/json")
+	req = req.WithContext(ctx)
+	u.setRequestHeaders(req, opts)
+	req.Header.Set("Content-Type", u.mediaType())
 
 	var val map[string]interface{}
 	apiErr := &APIError{}
@@ -168,27 +262,28 @@ func (u *UnstructuredClient) Post(ctx context.Context, cli *http.Client, path st
 	if err != nil {
 		return nil, err
 	}
+	validStatusCodes = acceptedStatusCodes(validStatusCodes, opts)
 
 	var response any
 	rh := func(r *http.Response) error {
 		if r.ContentLength == 0 {
 			return nil
 		}
-		return httplib.FromJSON(&response)(r)
+		return fromJSON(&response)(r)
 	}
 
-	err = httplib.Fire(cli, req, httplib.FireOptions{
+	err = httplib.Fire(u.recordHeaders(cli), req, httplib.FireOptions{
 		Verbose:         u.Verbose,
 		ResponseHandler: rh,
 		AuthMethod:      u.Auth,
 		Validators: []httplib.HandleResponseFunc{
-			httplib.ErrorJSON(apiErr, validStatusCodes...),
+			errorResponse(apiErr, validStatusCodes...),
 		},
 	})
 	if err != nil {
 		return nil, err
 	}
-	val, ok = response.(map[string]interface{})
+	val, ok = u.transformResponse(getDoc, u.decodeResponse(response)).(map[string]interface{})
 	if !ok {
 		return nil, nil
 	}
@@ -196,16 +291,16 @@ func (u *UnstructuredClient) Post(ctx context.Context, cli *http.Client, path st
 }
 
 func (u *UnstructuredClient) List(ctx context.Context, cli *http.Client, path string, opts *RequestConfiguration) (*map[string]interface{}, error) {
-	uri := buildPath(u.Server, path, opts.Parameters, opts.Query)
 	pathItem, ok := u.DocScheme.Model.Paths.PathItems.Get(path)
 	if !ok {
 		return nil, fmt.Errorf("path not found - list: %s", path)
 	}
+	uri := buildURL(u.Server, path, pathItem, "GET", opts)
 	if len(pathItem.Get.Servers) > 0 {
-		uri = buildPath(pathItem.Get.Servers[0].URL, path, opts.Parameters, opts.Query)
+		uri = buildURL(pathItem.Get.Servers[0].URL, path, pathItem, "GET", opts)
 	}
 
-	err := u.ValidateRequest("GET", path, opts.Parameters, opts.Query)
+	err := u.validateRequest("GET", path, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -213,6 +308,8 @@ func (u *UnstructuredClient) List(ctx context.Context, cli *http.Client, path st
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
+	u.setRequestHeaders(req, opts)
 
 	var val map[string]interface{}
 	apiErr := &APIError{}
@@ -227,27 +324,33 @@ func (u *UnstructuredClient) List(ctx context.Context, cli *http.Client, path st
 	if err != nil {
 		return nil, err
 	}
+	validStatusCodes = acceptedStatusCodes(validStatusCodes, opts)
 
 	var response any
 	rh := func(r *http.Response) error {
 		if r.ContentLength == 0 {
 			return nil
 		}
-		return httplib.FromJSON(&response)(r)
+		return fromJSON(&response)(r)
 	}
 
-	err = httplib.Fire(cli, req, httplib.FireOptions{
+	err = httplib.Fire(u.recordHeaders(cli), req, httplib.FireOptions{
 		Verbose:         u.Verbose,
 		ResponseHandler: rh,
 		AuthMethod:      u.Auth,
 		Validators: []httplib.HandleResponseFunc{
-			httplib.ErrorJSON(apiErr, validStatusCodes...),
+			errorResponse(apiErr, validStatusCodes...),
 		},
 	})
 	if err != nil {
 		return nil, err
 	}
-	val, ok = response.(map[string]interface{})
+	transformed := u.transformResponse(getDoc, u.decodeResponse(response))
+	// A collection answered as a bare array (e.g. GitHub, GitLab) is listed under the items field
+	if items, ok := transformed.([]interface{}); ok {
+		transformed = map[string]interface{}{"items": items}
+	}
+	val, ok = transformed.(map[string]interface{})
 	if !ok {
 		return nil, nil
 	}
@@ -255,63 +358,138 @@ func (u *UnstructuredClient) List(ctx context.Context, cli *http.Client, path st
 }
 
 func (u *UnstructuredClient) FindBy(ctx context.Context, cli *http.Client, path string, opts *RequestConfiguration) (*map[string]interface{}, error) {
-	list, err := u.List(ctx, cli, path, opts)
+	u.searched = 0
+	if u.pagination() == nil {
+		list, err := u.listCollection(ctx, cli, path, opts)
+		if err != nil {
+			return nil, err
+		}
+		items := listItems(list, opts.ItemsPath)
+		item, err := u.findInItems(items)
+		if err != nil {
+			return nil, err
+		}
+		if item != nil {
+			return item, nil
+		}
+		return nil, &NotFoundError{Searched: len(items)}
+	}
+
+	p := u.pagination()
+	searchCtx := ctx
+	if p.Timeout != "" {
+		timeout, err := time.ParseDuration(p.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pagination timeout %q: %w", p.Timeout, err)
+		}
+		var cancel context.CancelFunc
+		searchCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	item, err := u.searchPages(searchCtx, cli, path, opts)
+	if err != nil && ctx.Err() == nil && errors.Is(searchCtx.Err(), context.DeadlineExceeded) {
+		return nil, &SearchLimitError{Limit: SearchLimitTimeout, Value: p.Timeout}
+	}
 	if err != nil {
 		return nil, err
 	}
-	for _, v := range *list {
-		if v, ok := v.([]interface{}); ok {
-			if len(v) > 0 {
-				for _, item := range v {
-					if item, ok := item.(map[string]interface{}); ok {
-
-						for _, ide := range u.IdentifierFields {
-							idepath := strings.Split(ide, ".") // split the identifier field by '.'
-							responseValue, _, err := unstructured.NestedString(item, idepath...)
-							if err != nil {
-								val, _, err := unstructured.NestedFieldCopy(item, idepath...)
-								if err != nil {
-									return nil, fmt.Errorf("error getting nested field: %w", err)
-								}
-								responseValue = fmt.Sprintf("%v", val)
-							}
-							ok, err = u.isInSpecFields(ide, responseValue)
-							if err != nil {
-								return nil, err
-							}
-							if ok {
-								return &item, nil
-							}
-						}
-					}
-				}
+	if item != nil {
+		return item, nil
+	}
+	return nil, &NotFoundError{Searched: u.searched}
+}
+
+// searchPages scans the pages of the collection, from the page where the item was last found if any,
+// and then from the first page, also when the page last found is rejected by the API (e.g. an expired cursor).
+func (u *UnstructuredClient) searchPages(ctx context.Context, cli *http.Client, path string, opts *RequestConfiguration) (*map[string]interface{}, error) {
+	if u.StartPage != "" {
+		item, err := u.findInPages(ctx, cli, path, opts, u.StartPage)
+		if err != nil && ctx.Err() != nil {
+			return nil, err
+		}
+		if item != nil {
+			return item, nil
+		}
+		// The whole collection is searched again
+		u.searched = 0
+	}
+	return u.findInPages(ctx, cli, path, opts, "")
+}
+
+// findInItems returns the first item matching the identifiers, nil if none matches. With ownership markers, the
+// items marked by other custom resources are skipped and the ones marked by the custom resource are preferred
+// to the unmarked ones.
+func (u *UnstructuredClient) findInItems(items []interface{}) (*map[string]interface{}, error) {
+	var unmarked *map[string]interface{}
+	for _, item := range items {
+		item, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ok, err := u.matchesIdentifiers(item)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		switch u.ownership(item) {
+		case Owned:
+			return &item, nil
+		case Unmarked:
+			if unmarked == nil {
+				unmarked = &item
+			}
+		}
+	}
+	return unmarked, nil
+}
+
+// matchesIdentifiers returns true if the value of an identifier of the item is the one in the spec.
+func (u *UnstructuredClient) matchesIdentifiers(item map[string]interface{}) (bool, error) {
+	for _, ide := range u.IdentifierFields {
+		idepath := strings.Split(ide, ".") // split the identifier field by '.'
+		responseValue, _, err := unstructured.NestedString(item, idepath...)
+		if err != nil {
+			val, _, err := unstructured.NestedFieldCopy(item, idepath...)
+			if err != nil {
+				return false, fmt.Errorf("error getting nested field: %w", err)
 			}
-			break
+			responseValue = fmt.Sprintf("%v", val)
+		}
+		ok, err := u.isInSpecFields(ide, responseValue)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
 		}
 	}
-	return nil, &httplib.StatusError{StatusCode: 404}
+	return false, nil
 }
 
 func (u *UnstructuredClient) Patch(ctx context.Context, cli *http.Client, path string, opts *RequestConfiguration) (*map[string]interface{}, error) {
-	uri := buildPath(u.Server, path, opts.Parameters, opts.Query)
 	pathItem, ok := u.DocScheme.Model.Paths.PathItems.Get(path)
 	if !ok {
 		return nil, fmt.Errorf("path not found: %s", path)
 	}
+	uri := buildURL(u.Server, path, pathItem, "PATCH", opts)
 	if len(pathItem.Patch.Servers) > 0 {
-		uri = buildPath(pathItem.Patch.Servers[0].URL, path, opts.Parameters, opts.Query)
+		uri = buildURL(pathItem.Patch.Servers[0].URL, path, pathItem, "PATCH", opts)
 	}
 
-	err := u.ValidateRequest("PATCH", path, opts.Parameters, opts.Query)
+	err := u.validateRequest("PATCH", path, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := httplib.Patch(uri.String(), httplib.ToJSON(opts.Body))
+	req, err := httplib.Patch(uri.String(), httplib.ToJSON(u.requestBody(opts)))
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Add("Content-Type", "application/json")
+	req = req.WithContext(ctx)
+	u.setRequestHeaders(req, opts)
+	req.Header.Set("Content-Type", u.mediaType())
 
 	var val map[string]interface{}
 	apiErr := &APIError{}
@@ -327,27 +505,28 @@ func (u *UnstructuredClient) Patch(ctx context.Context, cli *http.Client, path s
 	if err != nil {
 		return nil, err
 	}
+	validStatusCodes = acceptedStatusCodes(validStatusCodes, opts)
 
 	var response any
 	rh := func(r *http.Response) error {
 		if r.ContentLength == 0 {
 			return nil
 		}
-		return httplib.FromJSON(&response)(r)
+		return fromJSON(&response)(r)
 	}
 
-	err = httplib.Fire(cli, req, httplib.FireOptions{
+	err = httplib.Fire(u.recordHeaders(cli), req, httplib.FireOptions{
 		Verbose:         u.Verbose,
 		ResponseHandler: rh,
 		AuthMethod:      u.Auth,
 		Validators: []httplib.HandleResponseFunc{
-			httplib.ErrorJSON(apiErr, validStatusCodes...),
+			errorResponse(apiErr, validStatusCodes...),
 		},
 	})
 	if err != nil {
 		return nil, err
 	}
-	val, ok = response.(map[string]interface{})
+	val, ok = u.transformResponse(getDoc, u.decodeResponse(response)).(map[string]interface{})
 	if !ok {
 		return nil, nil
 	}
@@ -355,25 +534,27 @@ func (u *UnstructuredClient) Patch(ctx context.Context, cli *http.Client, path s
 }
 
 func (u *UnstructuredClient) Put(ctx context.Context, cli *http.Client, path string, opts *RequestConfiguration) (*map[string]interface{}, error) {
-	uri := buildPath(u.Server, path, opts.Parameters, opts.Query)
 	pathItem, ok := u.DocScheme.Model.Paths.PathItems.Get(path)
 	if !ok {
 		return nil, fmt.Errorf("path not found: %s", path)
 	}
+	uri := buildURL(u.Server, path, pathItem, "PUT", opts)
 	if len(pathItem.Put.Servers) > 0 {
-		uri = buildPath(pathItem.Put.Servers[0].URL, path, opts.Parameters, opts.Query)
+		uri = buildURL(pathItem.Put.Servers[0].URL, path, pathItem, "PUT", opts)
 	}
 
-	err := u.ValidateRequest("PUT", path, opts.Parameters, opts.Query)
+	err := u.validateRequest("PUT", path, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := httplib.Put(uri.String(), httplib.ToJSON(opts.Body))
+	req, err := httplib.Put(uri.String(), httplib.ToJSON(u.requestBody(opts)))
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Add("Content-Type", "application/json")
+	req = req.WithContext(ctx)
+	u.setRequestHeaders(req, opts)
+	req.Header.Set("Content-Type", u.mediaType())
 
 	var val map[string]interface{}
 	apiErr := &APIError{}
@@ -388,31 +569,32 @@ func (u *UnstructuredClient) Put(ctx context.Context, cli *http.Client, path str
 	if err != nil {
 		return nil, err
 	}
+	validStatusCodes = acceptedStatusCodes(validStatusCodes, opts)
 
 	var response any
 	rh := func(r *http.Response) error {
 		if r.ContentLength == 0 {
 			return nil
 		}
-		return httplib.FromJSON(&response)(r)
+		return fromJSON(&response)(r)
 	}
 
 	if containsStatusCode(http.StatusNoContent, validStatusCodes) {
 		rh = nil
 	}
 
-	err = httplib.Fire(cli, req, httplib.FireOptions{
+	err = httplib.Fire(u.recordHeaders(cli), req, httplib.FireOptions{
 		Verbose:         u.Verbose,
 		ResponseHandler: rh,
 		AuthMethod:      u.Auth,
 		Validators: []httplib.HandleResponseFunc{
-			httplib.ErrorJSON(apiErr, validStatusCodes...),
+			errorResponse(apiErr, validStatusCodes...),
 		},
 	})
 	if err != nil {
 		return nil, err
 	}
-	val, ok = response.(map[string]interface{})
+	val, ok = u.transformResponse(getDoc, u.decodeResponse(response)).(map[string]interface{})
 	if !ok {
 		return nil, nil
 	}
@@ -420,23 +602,37 @@ func (u *UnstructuredClient) Put(ctx context.Context, cli *http.Client, path str
 }
 
 func (u *UnstructuredClient) Delete(ctx context.Context, cli *http.Client, path string, opts *RequestConfiguration) (*map[string]interface{}, error) {
-	uri := buildPath(u.Server, path, opts.Parameters, opts.Query)
 	pathItem, ok := u.DocScheme.Model.Paths.PathItems.Get(path)
 	if !ok {
 		return nil, fmt.Errorf("path not found: %s", path)
 	}
+	uri := buildURL(u.Server, path, pathItem, "DELETE", opts)
 	if len(pathItem.Delete.Servers) > 0 {
-		uri = buildPath(pathItem.Delete.Servers[0].URL, path, opts.Parameters, opts.Query)
+		uri = buildURL(pathItem.Delete.Servers[0].URL, path, pathItem, "DELETE", opts)
 	}
 
-	err := u.ValidateRequest("DELETE", path, opts.Parameters, opts.Query)
+	err := u.validateRequest("DELETE", path, opts)
 	if err != nil {
 		return nil, err
 	}
-	req, err := httplib.Delete(uri.String())
+	// Some APIs require a body on DELETE (e.g. the reason of the deletion), sent only if not empty
+	var req *http.Request
+	if hasBody(opts.Body) {
+		req, err = httplib.Post(uri.String(), httplib.ToJSON(u.requestBody(opts)))
+		if err == nil {
+			req.Method = http.MethodDelete
+		}
+	} else {
+		req, err = httplib.Delete(uri.String())
+	}
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
+	u.setRequestHeaders(req, opts)
+	if hasBody(opts.Body) {
+		req.Header.Set("Content-Type", u.mediaType())
+	}
 
 	var val map[string]interface{}
 	apiErr := &APIError{}
@@ -451,28 +647,29 @@ func (u *UnstructuredClient) Delete(ctx context.Context, cli *http.Client, path
 	if err != nil {
 		return nil, err
 	}
+	validStatusCodes = acceptedStatusCodes(validStatusCodes, opts)
 
 	var response any
 	rh := func(r *http.Response) error {
 		if r.ContentLength == 0 {
 			return nil
 		}
-		return httplib.FromJSON(&response)(r)
+		return fromJSON(&response)(r)
 	}
 
-	err = httplib.Fire(cli, req, httplib.FireOptions{
+	err = httplib.Fire(u.recordHeaders(cli), req, httplib.FireOptions{
 		Verbose:         u.Verbose,
 		ResponseHandler: rh,
 		AuthMethod:      u.Auth,
 		Validators: []httplib.HandleResponseFunc{
-			httplib.ErrorJSON(apiErr, validStatusCodes...),
+			errorResponse(apiErr, validStatusCodes...),
 		},
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	val, ok = response.(map[string]interface{})
+	val, ok = u.transformResponse(getDoc, u.decodeResponse(response)).(map[string]interface{})
 	if !ok {
 		return nil, nil
 	}
@@ -487,3 +684,11 @@ func containsStatusCode(statusCode int, validStatusCodes []int) bool {
 	}
 	return false
 }
+
+// hasBody returns true if the body is worth sending, i.e. neither nil nor an empty object.
+func hasBody(body interface{}) bool {
+	if fields, ok := body.(map[string]interface{}); ok {
+		return len(fields) > 0
+	}
+	return body != nil
+}
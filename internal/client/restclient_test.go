@@ -0,0 +1,170 @@
+package restclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const deleteBodyOAS = `openapi: 3.0.0
+info:
+  title: scopes
+  version: "1.0"
+servers:
+  - url: http://localhost
+paths:
+  /apps/{id}/scopes:
+    delete:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                scopes:
+                  type: array
+                  items:
+                    type: string
+                reason:
+                  type: string
+      responses:
+        "204":
+          description: deleted
+`
+
+func TestDeleteBody(t *testing.T) {
+	var method, contentType, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, contentType, body = r.Method, r.Header.Get("Content-Type"), string(data)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	u, err := parseDocument([]byte(deleteBodyOAS))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u.Server = srv.URL
+
+	tests := []struct {
+		name        string
+		body        interface{}
+		expected    string
+		contentType string
+	}{
+		{
+			name:        "with body",
+			body:        map[string]interface{}{"scopes": []interface{}{"repo", "admin"}, "reason": "revoked"},
+			expected:    `{"reason":"revoked","scopes":["repo","admin"]}`,
+			contentType: "application/json",
+		},
+		{name: "empty body", body: map[string]interface{}{}},
+		{name: "without body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &RequestConfiguration{Parameters: map[string]string{"id": "1"}, Body: tt.body}
+			if _, err := u.Delete(context.Background(), srv.Client(), "/apps/{id}/scopes", opts); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if method != http.MethodDelete {
+				t.Errorf("expected DELETE, got %s", method)
+			}
+			if body != tt.expected || contentType != tt.contentType {
+				t.Errorf("expected body %q (%q), got %q (%q)", tt.expected, tt.contentType, body, contentType)
+			}
+		})
+	}
+}
+
+const hangingOAS = `openapi: 3.0.0
+info:
+  title: apps
+  version: "1.0"
+servers:
+  - url: http://localhost
+paths:
+  /apps/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: string
+    get:
+      responses:
+        "200":
+          description: ok
+    post:
+      responses:
+        "201":
+          description: created
+    put:
+      responses:
+        "200":
+          description: ok
+    patch:
+      responses:
+        "200":
+          description: ok
+    delete:
+      responses:
+        "204":
+          description: deleted
+`
+
+func TestCancelledContext(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	u, err := parseDocument([]byte(hangingOAS))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u.Server = srv.URL
+
+	verbs := map[string]func(context.Context, *http.Client, string, *RequestConfiguration) (*map[string]interface{}, error){
+		http.MethodGet:    u.Get,
+		http.MethodPost:   u.Post,
+		http.MethodPut:    u.Put,
+		http.MethodPatch:  u.Patch,
+		http.MethodDelete: u.Delete,
+	}
+	for method, call := range verbs {
+		t.Run(method, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			done := make(chan error, 1)
+			go func() {
+				_, err := call(ctx, srv.Client(), "/apps/{id}", &RequestConfiguration{Parameters: map[string]string{"id": "1"}})
+				done <- err
+			}()
+			select {
+			case err := <-done:
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("expected the call to be cancelled with its context, got %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("expected the call to return once its context is cancelled")
+			}
+		})
+	}
+}
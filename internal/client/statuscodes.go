@@ -0,0 +1,58 @@
+package restclient
+
+import "sort"
+
+type StatusOutcome string
+
+const (
+	// StatusOutcomeSuccess: the response status is accepted as a success
+	StatusOutcomeSuccess StatusOutcome = "Success"
+	// StatusOutcomePending: the response status is accepted, the resource being still provisioned
+	StatusOutcomePending StatusOutcome = "Pending"
+	// StatusOutcomeFailure: the response status is rejected, even if the OAS describes it as a success
+	StatusOutcomeFailure StatusOutcome = "Failure"
+)
+
+// StatusCode overrides the outcome of a response status described by the OAS, or missing from it.
+type StatusCode struct {
+	// Code: the HTTP status code of the response (e.g. 202)
+	Code int `json:"code"`
+	// Outcome: the outcome of the responses with the status code [Success, Pending, Failure], defaults to Success
+	Outcome StatusOutcome `json:"outcome,omitempty"`
+}
+
+// acceptedStatusCodes returns the status codes accepted by the operation, described as successes by the OAS,
+// with the status code overrides of the request configuration applied.
+func acceptedStatusCodes(codes []int, opts *RequestConfiguration) []int {
+	if opts == nil || len(opts.StatusCodes) == 0 {
+		return codes
+	}
+	accepted := make(map[int]bool, len(codes)+len(opts.StatusCodes))
+	for _, code := range codes {
+		accepted[code] = true
+	}
+	for _, sc := range opts.StatusCodes {
+		accepted[sc.Code] = sc.Outcome != StatusOutcomeFailure
+	}
+	res := make([]int, 0, len(accepted))
+	for code, ok := range accepted {
+		if ok {
+			res = append(res, code)
+		}
+	}
+	sort.Ints(res)
+	return res
+}
+
+// IsPending returns true if the status of the last response is marked as pending by the request configuration.
+func (u *UnstructuredClient) IsPending(opts *RequestConfiguration) bool {
+	if opts == nil {
+		return false
+	}
+	for _, sc := range opts.StatusCodes {
+		if sc.Code == u.ResponseStatus && sc.Outcome == StatusOutcomePending {
+			return true
+		}
+	}
+	return false
+}
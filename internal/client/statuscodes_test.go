@@ -0,0 +1,33 @@
+package restclient
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestAcceptedStatusCodes(t *testing.T) {
+	opts := &RequestConfiguration{StatusCodes: []StatusCode{
+		{Code: http.StatusCreated},
+		{Code: http.StatusAccepted, Outcome: StatusOutcomePending},
+		{Code: http.StatusPartialContent, Outcome: StatusOutcomeFailure},
+	}}
+	codes := acceptedStatusCodes([]int{http.StatusOK, http.StatusPartialContent}, opts)
+	expected := []int{http.StatusOK, http.StatusCreated, http.StatusAccepted}
+	if !reflect.DeepEqual(codes, expected) {
+		t.Errorf("expected %v, got %v", expected, codes)
+	}
+
+	if codes := acceptedStatusCodes([]int{http.StatusOK}, &RequestConfiguration{}); !reflect.DeepEqual(codes, []int{http.StatusOK}) {
+		t.Errorf("expected the OAS codes unchanged, got %v", codes)
+	}
+
+	u := &UnstructuredClient{ResponseStatus: http.StatusAccepted}
+	if !u.IsPending(opts) {
+		t.Errorf("expected the response to be pending")
+	}
+	u.ResponseStatus = http.StatusCreated
+	if u.IsPending(opts) {
+		t.Errorf("expected the response not to be pending")
+	}
+}
@@ -0,0 +1,109 @@
+package restResources
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/apiaction"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// AnnotationKeyTriggerPrefix is the prefix of the annotations that trigger
+// auxiliary actions not bound to a CR field, e.g. krateo.io/trigger-restart.
+const AnnotationKeyTriggerPrefix = "krateo.io/trigger-"
+
+const (
+	actionResultSucceeded = "Succeeded"
+	actionResultFailed    = "Failed"
+)
+
+// isLifecycleAction returns true if the action is part of the main resource lifecycle.
+func isLifecycleAction(action string) bool {
+	switch apiaction.APIAction(strings.ToLower(action)) {
+	case apiaction.Create, apiaction.Update, apiaction.Delete, apiaction.List,
+		apiaction.Get, apiaction.FindBy, apiaction.Exists:
+		return true
+	}
+	return false
+}
+
+// triggerValue returns the current value of the trigger of an auxiliary action.
+func triggerValue(mg *unstructured.Unstructured, descr getter.VerbsDescription) (string, bool, error) {
+	if descr.Trigger == "" {
+		val, ok := mg.GetAnnotations()[AnnotationKeyTriggerPrefix+strings.ToLower(descr.Action)]
+		return val, ok, nil
+	}
+
+	val, ok, err := unstructured.NestedFieldNoCopy(mg.Object, strings.Split(descr.Trigger, ".")...)
+	if err != nil || !ok || val == nil {
+		return "", false, err
+	}
+	str, err := text.GenericToString(val)
+	return str, true, err
+}
+
+// runAuxiliaryActions executes the auxiliary actions whose trigger changed since their last run
+// and records the outcome in status.actions.<action>. Failures are recorded in status only,
+// so they never affect the main lifecycle of the resource. The trigger found on first sight
+// is only recorded, never running the action.
+func (h *handler) runAuxiliaryActions(ctx context.Context, log logging.Logger, cli *restclient.UnstructuredClient, clientInfo *getter.Info, mg *unstructured.Unstructured, statusFields, specFields map[string]interface{}) {
+	for _, descr := range clientInfo.Resource.VerbsDescription {
+		if isLifecycleAction(descr.Action) {
+			continue
+		}
+		action := strings.ToLower(descr.Action)
+
+		trigger, ok, err := triggerValue(mg, descr)
+		if err != nil {
+			log.Debug("Getting action trigger", "action", action, "error", err)
+			continue
+		}
+		last, seen, _ := unstructured.NestedString(mg.Object, "status", "actions", action, "trigger")
+		if !seen {
+			// Recording the trigger found on first sight (e.g. set when the resource was created) as the
+			// baseline, so that the action only runs once the trigger changes
+			err = unstructured.SetNestedField(mg.Object, map[string]interface{}{"trigger": trigger}, "status", "actions", action)
+			if err != nil {
+				log.Debug("Recording auxiliary action trigger", "action", action, "error", err)
+			}
+			continue
+		}
+		if !ok || trigger == "" || last == trigger {
+			continue
+		}
+
+		result := map[string]interface{}{
+			"trigger": trigger,
+			"lastRun": time.Now().UTC().Format(time.RFC3339),
+			"result":  actionResultSucceeded,
+		}
+
+		apiCall, callInfo, err := APICallBuilder(cli, clientInfo, apiaction.APIAction(action))
+		if err == nil && apiCall == nil {
+			err = fmt.Errorf("API call not found for %s", action)
+		}
+		if err == nil {
+			var reqConfiguration *restclient.RequestConfiguration
+			reqConfiguration, err = BuildCallConfig(callInfo, statusFields, specFields)
+			if err == nil {
+				_, err = apiCall(ctx, h.mutationClient(ctx, mg, action), callInfo.Path, reqConfiguration)
+			}
+		}
+		if err != nil {
+			log.Debug("Performing auxiliary action", "action", action, "error", err)
+			result["result"] = actionResultFailed
+			result["message"] = err.Error()
+		}
+
+		err = unstructured.SetNestedField(mg.Object, result, "status", "actions", action)
+		if err != nil {
+			log.Debug("Recording auxiliary action result", "action", action, "error", err)
+		}
+	}
+}
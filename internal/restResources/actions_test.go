@@ -0,0 +1,153 @@
+package restResources
+
+import (
+	"context"
+	"testing"
+
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const reposRestartOAS = `openapi: 3.0.0
+info:
+  title: repos
+  version: "1.0"
+servers:
+  - url: %s
+paths:
+  /repos/{id}:
+    get:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  id:
+                    type: string
+                  name:
+                    type: string
+  /repos/{id}/restart:
+    post:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        "204":
+          description: restarted
+`
+
+var restartResource = getter.Resource{
+	Identifiers: []string{"id"},
+	VerbsDescription: []getter.VerbsDescription{
+		{Action: "get", Method: "GET", Path: "/repos/{id}"},
+		{Action: "restart", Method: "POST", Path: "/repos/{id}/restart"},
+	},
+}
+
+// observeActions observes the resource with the given restart trigger and recorded action status,
+// returning the calls of the restart action and the persisted status of the action.
+func observeActions(t *testing.T, mg *unstructured.Unstructured, trigger string, recorded map[string]interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	if trigger != "" {
+		mg.SetAnnotations(map[string]string{AnnotationKeyTriggerPrefix + "restart": trigger})
+	}
+	if recorded != nil {
+		_ = unstructured.SetNestedField(mg.Object, recorded, "status", "actions", "restart")
+	}
+	h, _, calls := observedHandlerFor(t, reposRestartOAS, restartResource, mg)
+
+	_, err := h.Observe(context.Background(), mg)
+	if err != nil && !apierrors.IsNotFound(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	restarts := 0
+	for _, call := range *calls {
+		if call == "POST /repos/42/restart" {
+			restarts++
+		}
+	}
+	latest, err := h.dynamicClient.Resource(reposGVR).Namespace(mg.GetNamespace()).Get(context.Background(), mg.GetName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	status, _, _ := unstructured.NestedMap(latest.Object, "status", "actions", "restart")
+	return restarts, status
+}
+
+func TestAuxiliaryActions(t *testing.T) {
+	tests := []struct {
+		name     string
+		trigger  string
+		recorded map[string]interface{}
+		restarts int
+		result   string
+	}{
+		{
+			name:    "trigger found on first sight",
+			trigger: "1",
+		},
+		{
+			name: "no trigger on first sight",
+		},
+		{
+			name:     "trigger set after first sight",
+			trigger:  "1",
+			recorded: map[string]interface{}{"trigger": ""},
+			restarts: 1,
+			result:   actionResultSucceeded,
+		},
+		{
+			name:     "trigger changed",
+			trigger:  "2",
+			recorded: map[string]interface{}{"trigger": "1"},
+			restarts: 1,
+			result:   actionResultSucceeded,
+		},
+		{
+			name:     "trigger unchanged",
+			trigger:  "1",
+			recorded: map[string]interface{}{"trigger": "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restarts, status := observeActions(t, observedResource(), tt.trigger, tt.recorded)
+			if restarts != tt.restarts {
+				t.Errorf("expected %d restarts, got %d", tt.restarts, restarts)
+			}
+			if status["trigger"] != tt.trigger {
+				t.Errorf("expected the trigger %q to be recorded, got %v", tt.trigger, status)
+			}
+			if result, _ := status["result"].(string); result != tt.result {
+				t.Errorf("expected the result %q, got %v", tt.result, status)
+			}
+		})
+	}
+}
+
+func TestAuxiliaryActionsDrifted(t *testing.T) {
+	mg := observedResource()
+	_ = unstructured.SetNestedField(mg.Object, "renamed", "spec", "name")
+
+	restarts, status := observeActions(t, mg, "2", map[string]interface{}{"trigger": "1"})
+	if restarts != 1 {
+		t.Fatalf("expected 1 restart, got %d", restarts)
+	}
+	if status["trigger"] != "2" || status["result"] != actionResultSucceeded {
+		t.Errorf("expected the result to be persisted despite the drift, got %v", status)
+	}
+}
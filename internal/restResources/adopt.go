@@ -0,0 +1,52 @@
+package restResources
+
+import (
+	"context"
+	"net/http"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/apiaction"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"github.com/lucasepe/httplib"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// findBeforeCreate searches the external resource with the findby action right before creating it, closing the
+// window in which another actor may have created it since the observation; nil if not found or if the resource
+// has no findby action. A search which fails (e.g. reaching its limits) fails the creation as well.
+func findBeforeCreate(ctx context.Context, log logging.Logger, cli *restclient.UnstructuredClient, clientInfo *getter.Info, specFields map[string]interface{}) (*map[string]interface{}, error) {
+	apiCall, callInfo, err := APICallBuilder(cli, clientInfo, apiaction.FindBy)
+	if apiCall == nil || err != nil {
+		log.Debug("Building API call, creation not checked", "action", apiaction.FindBy, "error", err)
+		return nil, err
+	}
+	reqConfiguration, err := BuildCallConfig(callInfo, nil, specFields)
+	if err != nil {
+		return nil, err
+	}
+	cli.IdentifierFields = clientInfo.Resource.Identifiers
+	cli.Pagination = clientInfo.Resource.Pagination
+	cli.StartPage = ""
+	body, err := apiCall(ctx, http.DefaultClient, callInfo.Path, reqConfiguration)
+	if httplib.IsNotFoundError(err) {
+		return nil, nil
+	}
+	return body, err
+}
+
+// adoptFound adopts the external resource found right before creating the resource, populating its status,
+// so that the next observation gets and compares it with the spec instead of creating a duplicate.
+func (h *handler) adoptFound(ctx context.Context, mg *unstructured.Unstructured, clientInfo *getter.Info, cli *restclient.UnstructuredClient, body *map[string]interface{}) error {
+	h.identifiers.Set(objectKey(mg), clientInfo.Resource.Identifiers, *body)
+	_, err := populateAnnotations(clientInfo, mg, body)
+	if err != nil {
+		return err
+	}
+	err = populateStatusFields(clientInfo, mg, body, cli.ResponseHeaders)
+	if err != nil {
+		return err
+	}
+	_, err = h.updateStatus(ctx, mg)
+	return err
+}
@@ -0,0 +1,104 @@
+package restResources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const createReposOAS = `openapi: 3.0.0
+info:
+  title: repos
+  version: "1.0"
+servers:
+  - url: %s
+paths:
+  /repos:
+    get:
+      parameters:
+        - name: name
+          in: query
+          schema:
+            type: string
+      responses:
+        "200":
+          description: ok
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                name:
+                  type: string
+      responses:
+        "201":
+          description: created
+`
+
+func TestCheckBeforeCreate(t *testing.T) {
+	tests := []struct {
+		name     string
+		check    bool
+		listed   string
+		creates  int
+		searches int
+		id       string
+	}{
+		{name: "adopted", check: true, listed: `[{"id":"42","name":"repo1"}]`, searches: 1, id: "42"},
+		{name: "not found", check: true, listed: `[]`, searches: 1, creates: 1, id: "7"},
+		{name: "unchecked", listed: `[{"id":"42","name":"repo1"}]`, creates: 1, id: "7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			searches, creates := 0, 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case r.URL.Path == "/openapi.yaml":
+					fmt.Fprintf(w, createReposOAS, "http://"+r.Host)
+				case r.Method == http.MethodGet:
+					searches++
+					fmt.Fprint(w, tt.listed)
+				case r.Method == http.MethodPost:
+					creates++
+					w.WriteHeader(http.StatusCreated)
+					fmt.Fprint(w, `{"id":"7","name":"repo1"}`)
+				}
+			}))
+			defer srv.Close()
+
+			mg := observedResource()
+			unstructured.RemoveNestedField(mg.Object, "status")
+			h, _ := observedHandler(t, getter.Resource{}, mg)
+			h.swaggerInfoGetter = staticInfo{info: &getter.Info{URL: srv.URL + "/openapi.yaml", Resource: getter.Resource{
+				Identifiers: []string{"id", "name"},
+				VerbsDescription: []getter.VerbsDescription{
+					{Action: "create", Method: "POST", Path: "/repos", CheckBeforeCreate: tt.check},
+					{Action: "findby", Method: "GET", Path: "/repos"},
+				},
+			}}}
+
+			if err := h.Create(context.Background(), mg); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if searches != tt.searches || creates != tt.creates {
+				t.Errorf("expected %d searches and %d creations, got %d and %d", tt.searches, tt.creates, searches, creates)
+			}
+			latest, err := h.dynamicClient.Resource(reposGVR).Namespace("default").Get(context.Background(), "repo1", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if id, _, _ := unstructured.NestedString(latest.Object, "status", "id"); id != tt.id {
+				t.Errorf("expected the status id %q, got %q", tt.id, id)
+			}
+		})
+	}
+}
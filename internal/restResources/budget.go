@@ -0,0 +1,79 @@
+package restResources
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// AnnotationKeyCallBudget is the key in the annotations map of a resource
+// overriding the number of external calls allowed for it within the window of the call budget
+const AnnotationKeyCallBudget = "krateo.io/call-budget"
+
+// quotaExceededError is returned instead of reconciling a resource whose call budget is exhausted.
+type quotaExceededError struct {
+	used       int
+	limit      int
+	window     time.Duration
+	retryAfter time.Duration
+}
+
+func (e *quotaExceededError) Error() string {
+	return fmt.Sprintf("%d external calls made within %s, exceeding the call budget of %d; available again in %s",
+		e.used, e.window, e.limit, e.retryAfter.Round(time.Second))
+}
+
+// callBudgetLimit returns the number of external calls allowed for the resource, 0 for the limit of the budget.
+// The annotation may only lower the limit of the budget, if any, not to let a resource exceed the limit set by the operator.
+func (h *handler) callBudgetLimit(mg *unstructured.Unstructured) int {
+	limit, err := strconv.Atoi(mg.GetAnnotations()[AnnotationKeyCallBudget])
+	if err != nil || limit < 0 {
+		return 0
+	}
+	if h.budget != nil && h.budget.Limit > 0 && limit > h.budget.Limit {
+		return h.budget.Limit
+	}
+	return limit
+}
+
+// checkBudget returns a quotaExceededError if the call budget of the resource is exhausted, requeueing the
+// resource once the budget is available again.
+func (h *handler) checkBudget(mg *unstructured.Unstructured) error {
+	limit := h.callBudgetLimit(mg)
+	exceeded, used, retryAfter := h.budget.Exceeded(objectKey(mg), limit, time.Now())
+	if !exceeded {
+		return nil
+	}
+	if limit <= 0 {
+		limit = h.budget.Limit
+	}
+	h.requeue.After(mg, retryAfter, "call-budget")
+	return &quotaExceededError{used: used, limit: limit, window: h.budget.Window, retryAfter: retryAfter}
+}
+
+// spendBudget records the external calls made by the reconcile of the resource.
+func (h *handler) spendBudget(sum *reconcileSummary, mg *unstructured.Unstructured) {
+	if sum == nil {
+		return
+	}
+	h.budget.Spend(objectKey(mg), sum.calls(), time.Now())
+}
+
+// withinBudget runs the reconcile of the resource unless its call budget is exhausted,
+// recording the external calls it made.
+func (h *handler) withinBudget(sum *reconcileSummary, mg *unstructured.Unstructured, reconcile func() error) error {
+	if err := h.checkBudget(mg); err != nil {
+		return err
+	}
+	defer h.spendBudget(sum, mg)
+	return reconcile()
+}
+
+// outsideBudget runs the reconcile of the resource even if its call budget is exhausted, recording the external
+// calls it made: the deletions are not skipped, not to leave the resources being deleted stuck in finalization.
+func (h *handler) outsideBudget(sum *reconcileSummary, mg *unstructured.Unstructured, reconcile func() error) error {
+	defer h.spendBudget(sum, mg)
+	return reconcile()
+}
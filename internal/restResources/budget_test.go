@@ -0,0 +1,86 @@
+package restResources
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/quota"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+)
+
+func TestWithinBudget(t *testing.T) {
+	h := &handler{logger: logging.NewNopLogger(), budget: quota.New(3, time.Hour)}
+	mg := summaryResource()
+	reconciles := 0
+	reconcile := func() error {
+		ctx, sum := h.startSummary(context.Background())
+		return h.withinBudget(sum, mg, func() error {
+			reconciles++
+			trackCalls(ctx, &restclient.UnstructuredClient{RequestCount: 2})
+			return nil
+		})
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := reconcile(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	err := reconcile()
+	var quotaErr *quotaExceededError
+	if !errors.As(err, &quotaErr) {
+		t.Fatalf("expected a quotaExceededError, got %v", err)
+	}
+	if reconciles != 2 || quotaErr.used != 4 || quotaErr.limit != 3 {
+		t.Errorf("expected the reconcile to be skipped, got %d reconciles and %+v", reconciles, quotaErr)
+	}
+
+	// The annotation cannot raise the budget above the limit of the operator
+	mg.SetAnnotations(map[string]string{AnnotationKeyCallBudget: "10"})
+	if err := reconcile(); !errors.As(err, &quotaErr) || quotaErr.limit != 3 {
+		t.Errorf("expected the annotation to be clamped to the limit, got %v", err)
+	}
+
+	// The deletions are not skipped
+	ctx, sum := h.startSummary(context.Background())
+	deleted := false
+	err = h.outsideBudget(sum, mg, func() error {
+		deleted = true
+		trackCalls(ctx, &restclient.UnstructuredClient{RequestCount: 1})
+		return nil
+	})
+	if err != nil || !deleted {
+		t.Errorf("expected the deletion to be run, got %v", err)
+	}
+}
+
+func TestCallBudgetLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		limit      int
+		annotation string
+		expected   int
+	}{
+		{name: "no annotation", limit: 10, expected: 0},
+		{name: "lowered", limit: 10, annotation: "5", expected: 5},
+		{name: "clamped", limit: 10, annotation: "50", expected: 10},
+		{name: "no global limit", annotation: "50", expected: 50},
+		{name: "invalid", limit: 10, annotation: "many", expected: 0},
+		{name: "negative", limit: 10, annotation: "-1", expected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &handler{budget: quota.New(tt.limit, time.Hour)}
+			mg := summaryResource()
+			if tt.annotation != "" {
+				mg.SetAnnotations(map[string]string{AnnotationKeyCallBudget: tt.annotation})
+			}
+			if got := h.callBudgetLimit(mg); got != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
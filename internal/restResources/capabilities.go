@@ -0,0 +1,57 @@
+package restResources
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/apiaction"
+	customcondition "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/condition"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/event"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const reasonMethodDowngraded event.Reason = "MethodDowngraded"
+
+// probeCapabilities probes the methods the API allows on the path of the update verb of the resource, unless
+// probed within the TTL of the capabilities, so that APICallBuilder falls back to another method or skips the
+// update if the API does not allow the one of the verb.
+func (h *handler) probeCapabilities(ctx context.Context, cli *restclient.UnstructuredClient, clientInfo *getter.Info, mg *unstructured.Unstructured) {
+	if cli.Capabilities == nil {
+		return
+	}
+	apiCall, callInfo, err := APICallBuilder(cli, clientInfo, apiaction.Update)
+	if err != nil || apiCall == nil || callInfo.RawMethod {
+		return
+	}
+	statusFields, _, _ := unstructured.NestedMap(mg.Object, "status")
+	specFields, _, _ := unstructured.NestedMap(mg.Object, "spec")
+	reqConfiguration, err := BuildCallConfig(callInfo, statusFields, specFields)
+	if err != nil {
+		return
+	}
+	cli.Probe(ctx, http.DefaultClient, callInfo.Method, callInfo.Path, reqConfiguration)
+}
+
+// setMethodDowngrade reports the method of the update verb the API does not allow, recording an event once
+// the downgrade appears or changes. The condition is written with the status.
+func (h *handler) setMethodDowngrade(mg *unstructured.Unstructured, callInfo *CallInfo) error {
+	if callInfo == nil || callInfo.Downgrade == "" {
+		if !h.isConditionTrue(mg, customcondition.TypeMethodDowngraded) {
+			return nil
+		}
+		return h.conditions.Set(mg, customcondition.MethodAllowed())
+	}
+	cond := customcondition.MethodDowngraded(callInfo.Downgrade)
+	if !h.conditionsChanged(mg, []metav1.Condition{cond}) {
+		return nil
+	}
+	h.objectLogger(mg).Info("Update method not allowed by the API", "name", mg.GetName(), "namespace", mg.GetNamespace(), "message", callInfo.Downgrade)
+	if h.recorder != nil {
+		h.recorder.Event(mg, event.Warning(reasonMethodDowngraded, errors.New(callInfo.Downgrade)))
+	}
+	return h.conditions.Set(mg, cond)
+}
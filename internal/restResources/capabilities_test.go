@@ -0,0 +1,132 @@
+package restResources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/apiaction"
+	customcondition "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/condition"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+)
+
+const capabilitiesOAS = `openapi: 3.0.0
+info:
+  title: repos
+  version: "1.0"
+servers:
+  - url: %s
+paths:
+  /repos/{id}:
+    patch:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                description:
+                  type: string
+      responses:
+        "200":
+          description: updated
+    put:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                description:
+                  type: string
+      responses:
+        "200":
+          description: updated
+`
+
+func TestProbeCapabilities(t *testing.T) {
+	allow := "GET, PUT"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/openapi.yaml":
+			fmt.Fprintf(w, capabilitiesOAS, "http://"+r.Host)
+		case r.Method == http.MethodOptions && r.URL.Path == "/repos/42":
+			w.Header().Set("Allow", allow)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	h := &handler{logger: logging.NewNopLogger()}
+	mg := summaryResource()
+	mg.Object["spec"] = map[string]interface{}{"description": "updated"}
+	mg.Object["status"] = map[string]interface{}{"id": "42"}
+	info := &getter.Info{Resource: getter.Resource{
+		VerbsDescription: []getter.VerbsDescription{{Action: "update", Method: "PATCH", Path: "/repos/{id}"}},
+	}}
+	build := func() (APIFuncDef, *CallInfo) {
+		cli, err := restclient.BuildClient(context.Background(), nil, srv.URL+"/openapi.yaml")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		cli.SpecFields = mg
+		cli.Capabilities = h.capabilities
+		h.probeCapabilities(context.Background(), cli, info, mg)
+		apiCall, callInfo, err := APICallBuilder(cli, info, apiaction.Update)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := h.setMethodDowngrade(mg, callInfo); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return apiCall, callInfo
+	}
+
+	if _, callInfo := build(); callInfo.Method != http.MethodPatch || callInfo.Downgrade != "" {
+		t.Errorf("expected the verb to be used as is without probes, got %s (%s)", callInfo.Method, callInfo.Downgrade)
+	}
+
+	h.capabilities = restclient.NewCapabilityCache(time.Hour)
+	_, callInfo := build()
+	if callInfo.Method != http.MethodPut || !callInfo.ReqParams.Body.Contains("description") {
+		t.Errorf("expected the update to fall back to PUT, got %s with %v", callInfo.Method, callInfo.ReqParams.Body)
+	}
+	if !h.isConditionTrue(mg, customcondition.TypeMethodDowngraded) {
+		t.Errorf("expected the downgrade to be reported")
+	}
+
+	allow = "GET"
+	h.capabilities = restclient.NewCapabilityCache(time.Hour)
+	apiCall, callInfo := build()
+	if apiCall != nil || !strings.Contains(callInfo.Downgrade, "update skipped") {
+		t.Errorf("expected the update to be skipped, got %q", callInfo.Downgrade)
+	}
+
+	allow = "GET, PATCH"
+	h.capabilities = restclient.NewCapabilityCache(time.Hour)
+	if _, callInfo := build(); callInfo.Method != http.MethodPatch {
+		t.Errorf("expected the verb to be used as is once allowed, got %s", callInfo.Method)
+	}
+	if h.isConditionTrue(mg, customcondition.TypeMethodDowngraded) {
+		t.Errorf("expected the downgrade to be cleared")
+	}
+}
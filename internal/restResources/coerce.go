@@ -0,0 +1,78 @@
+package restResources
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+)
+
+// coerce converts the scalar value between its string and native representations.
+func coerce(coercion getter.Coercion, value interface{}) (interface{}, error) {
+	switch value.(type) {
+	case nil, map[string]interface{}, []interface{}:
+		return value, nil
+	}
+	switch coercion {
+	case getter.CoercionString:
+		return text.GenericToString(value)
+	case getter.CoercionBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			return strconv.ParseBool(v)
+		}
+	case getter.CoercionNumber:
+		if isNumber(value) {
+			return value, nil
+		}
+		if s, ok := value.(string); ok {
+			if _, ok := new(big.Float).SetString(s); ok {
+				return json.Number(s), nil
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown coercion: %s", coercion)
+	}
+	return nil, fmt.Errorf("cannot convert %v to %s", value, coercion)
+}
+
+// coerceFields returns a copy of the fields with the values converted to the type expected by the API,
+// by the dot separated path of the field; the values that cannot be converted are left unchanged.
+func coerceFields(fields map[string]interface{}, coercions map[string]getter.Coercion, path ...string) map[string]interface{} {
+	if len(coercions) == 0 || fields == nil {
+		return fields
+	}
+	res := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		res[k] = coerceValue(v, coercions, append(append([]string{}, path...), k))
+	}
+	return res
+}
+
+func coerceValue(value interface{}, coercions map[string]getter.Coercion, path []string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return coerceFields(v, coercions, path...)
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, el := range v {
+			res[i] = coerceValue(el, coercions, path)
+		}
+		return res
+	}
+	coercion, ok := coercions[strings.Join(path, ".")]
+	if !ok {
+		return value
+	}
+	res, err := coerce(coercion, value)
+	if err != nil {
+		return value
+	}
+	return res
+}
@@ -0,0 +1,72 @@
+package restResources
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+)
+
+func TestBuildCallConfigCoercions(t *testing.T) {
+	callInfo := &CallInfo{
+		ReqParams: &RequestedParams{
+			Parameters: text.NewStringSet(),
+			Query:      text.NewStringSet(),
+			Body:       text.NewStringSet("ref", "inputs", "replicas"),
+		},
+		Coercions: map[string]getter.Coercion{
+			"inputs.debug":   getter.CoercionString,
+			"inputs.retries": getter.CoercionString,
+			"inputs.tags":    getter.CoercionString,
+			"replicas":       getter.CoercionNumber,
+		},
+	}
+	specFields := map[string]interface{}{
+		"ref": "main",
+		"inputs": map[string]interface{}{
+			"debug":   false,
+			"retries": int64(3),
+			"tags":    []interface{}{int64(1), true},
+			"version": "v1.2.3",
+		},
+		"replicas": "2",
+	}
+
+	conf, err := BuildCallConfig(callInfo, nil, specFields)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{
+		"ref": "main",
+		"inputs": map[string]interface{}{
+			"debug":   "false",
+			"retries": "3",
+			"tags":    []interface{}{"1", "true"},
+			"version": "v1.2.3",
+		},
+		"replicas": json.Number("2"),
+	}
+	if !reflect.DeepEqual(conf.Body, expected) {
+		t.Errorf("expected body %v, got %v", expected, conf.Body)
+	}
+}
+
+func TestCompareCoerced(t *testing.T) {
+	c := &comparer{coercions: map[string]getter.Coercion{
+		"debug":    getter.CoercionString,
+		"enabled":  getter.CoercionBoolean,
+		"replicas": getter.CoercionNumber,
+	}}
+	spec := map[string]interface{}{"debug": false, "enabled": true, "replicas": "2"}
+
+	res, err := c.compare(spec, map[string]interface{}{"debug": "false", "enabled": "true", "replicas": json.Number("2")}, "")
+	if err != nil || !res.IsEqual {
+		t.Errorf("expected equal, got %+v (%v)", res.Reason, err)
+	}
+	res, err = c.compare(spec, map[string]interface{}{"debug": "true", "enabled": "true", "replicas": json.Number("2")}, "")
+	if err != nil || res.IsEqual || res.Reason.Path != "debug" {
+		t.Errorf("expected difference at debug, got %+v (%v)", res.Reason, err)
+	}
+}
@@ -0,0 +1,255 @@
+package restResources
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+)
+
+type Reason struct {
+	Reason string
+	// Path is the dot separated path of the field the comparison stopped at (e.g. settings.tags[1])
+	Path        string
+	FirstValue  any
+	SecondValue any
+}
+
+type ComparisonResult struct {
+	IsEqual bool
+	Reason  *Reason
+	// Uncomparable holds the paths of the fields that could not be compared, in tolerant mode
+	Uncomparable []string
+}
+
+// ComparisonError is returned when a field of the CR cannot be compared with the remote one.
+type ComparisonError struct {
+	Path string
+	Err  error
+}
+
+func (e *ComparisonError) Error() string {
+	return fmt.Sprintf("comparing %s: %v", e.Path, e.Err)
+}
+
+func (e *ComparisonError) Unwrap() error {
+	return e.Err
+}
+
+// comparer compares the CR fields with the remote ones.
+type comparer struct {
+	// tolerant: if true, the fields that cannot be compared are recorded instead of aborting the comparison
+	tolerant bool
+	// arrayKeys: the key fields of the arrays of objects compared by key instead of by index, by array path
+	arrayKeys map[string]string
+	// normalizers: the normalizers applied to the values before comparing them, by field path
+	normalizers map[string]getter.Normalizer
+	// coercions: the types the values are converted to before comparing them, by field path
+	coercions map[string]getter.Coercion
+	// ignored: the paths of the fields not compared (e.g. the write-only ones)
+	ignored      text.StringSet
+	uncomparable []string
+}
+
+var indexPattern = regexp.MustCompile(`\[[^\]]*\]`)
+
+// fieldPath returns the path of the field without the array indexes (e.g. pipelines.stages for pipelines[1].stages).
+func fieldPath(path string) string {
+	return indexPattern.ReplaceAllString(path, "")
+}
+
+// compareExisting recursively compares the fields of the CR with the remote ones,
+// the fields missing remotely are not compared.
+func compareExisting(mg map[string]interface{}, rm map[string]interface{}, path ...string) (ComparisonResult, error) {
+	c := &comparer{}
+	return c.compare(mg, rm, strings.Join(path, "."))
+}
+
+func (c *comparer) compare(mg map[string]interface{}, rm map[string]interface{}, path string) (ComparisonResult, error) {
+	reason, err := c.compareMaps(mg, rm, path)
+	if err != nil {
+		var cerr *ComparisonError
+		if reason == nil {
+			reason = &Reason{Reason: "error comparing values"}
+			if errors.As(err, &cerr) {
+				reason.Path = cerr.Path
+			}
+		}
+		return ComparisonResult{IsEqual: false, Reason: reason, Uncomparable: c.uncomparable}, err
+	}
+	return ComparisonResult{IsEqual: reason == nil, Reason: reason, Uncomparable: c.uncomparable}, nil
+}
+
+// compareMaps returns the reason why the maps differ, nil if they are equal.
+func (c *comparer) compareMaps(mg map[string]interface{}, rm map[string]interface{}, path string) (*Reason, error) {
+	for key, value := range mg {
+		currentPath := joinPath(path, key)
+		if c.ignored.Contains(fieldPath(currentPath)) {
+			continue
+		}
+
+		rmValue, ok := rm[key]
+		if !ok {
+			if value == nil {
+				return &Reason{Reason: "null field missing remotely", Path: currentPath}, nil
+			}
+			continue
+		}
+
+		reason, err := c.compareValues(value, rmValue, currentPath)
+		if err != nil || reason != nil {
+			return reason, err
+		}
+	}
+	return nil, nil
+}
+
+// compareValues returns the reason why the values differ, nil if they are equal.
+func (c *comparer) compareValues(value, rmValue interface{}, path string) (*Reason, error) {
+	differ := func(reason string) *Reason {
+		return &Reason{Reason: reason, Path: path, FirstValue: value, SecondValue: rmValue}
+	}
+
+	// Null values are only equal to null values
+	if value == nil || rmValue == nil {
+		if value != rmValue {
+			return differ("values differ"), nil
+		}
+		return nil, nil
+	}
+
+	if coercion, ok := c.coercions[fieldPath(path)]; ok {
+		a, errA := coerce(coercion, value)
+		b, errB := coerce(coercion, rmValue)
+		if errA == nil && errB == nil {
+			value, rmValue = a, b
+		}
+	}
+
+	if normalizer, ok := c.normalizers[fieldPath(path)]; ok {
+		a, err := normalize(normalizer, value)
+		if err != nil {
+			return c.uncomparableField(differ("error normalizing values"), err)
+		}
+		b, err := normalize(normalizer, rmValue)
+		if err != nil {
+			return c.uncomparableField(differ("error normalizing values"), err)
+		}
+		if a != b {
+			return differ("values differ"), nil
+		}
+		return nil, nil
+	}
+
+	// Numbers are compared by value, whatever their decoded type (int64, float64, json.Number)
+	if isNumber(value) && isNumber(rmValue) {
+		if !compareNumbers(value, rmValue) {
+			return differ("values differ"), nil
+		}
+		return nil, nil
+	}
+
+	if reflect.TypeOf(value).Kind() != reflect.TypeOf(rmValue).Kind() {
+		return c.uncomparableField(differ("types differ"),
+			fmt.Errorf("types differ - %s is different from %s", reflect.TypeOf(value).Kind(), reflect.TypeOf(rmValue).Kind()))
+	}
+
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Map:
+		mgMap, ok1 := value.(map[string]interface{})
+		rmMap, ok2 := rmValue.(map[string]interface{})
+		if !ok1 || !ok2 {
+			return c.uncomparableField(differ("type assertion failed"), fmt.Errorf("type assertion failed for map"))
+		}
+		return c.compareMaps(mgMap, rmMap, path)
+	case reflect.Slice:
+		mgSlice, ok1 := value.([]interface{})
+		rmSlice, ok2 := rmValue.([]interface{})
+		if !ok1 || !ok2 {
+			return c.uncomparableField(differ("values are not both slices or type assertion failed"), fmt.Errorf("type assertion failed for slice"))
+		}
+		if key, ok := c.arrayKeys[fieldPath(path)]; ok {
+			return c.compareSlicesByKey(mgSlice, rmSlice, key, path)
+		}
+		for i, v := range mgSlice {
+			elemPath := fmt.Sprintf("%s[%d]", path, i)
+			if i >= len(rmSlice) {
+				return &Reason{Reason: "element missing remotely", Path: elemPath, FirstValue: v}, nil
+			}
+			reason, err := c.compareValues(v, rmSlice[i], elemPath)
+			if err != nil || reason != nil {
+				return reason, err
+			}
+		}
+		return nil, nil
+	default:
+		ok, err := compareAny(value, rmValue)
+		if err != nil {
+			return c.uncomparableField(differ("error comparing values"), err)
+		}
+		if !ok {
+			return differ("values differ"), nil
+		}
+		return nil, nil
+	}
+}
+
+// compareSlicesByKey compares the arrays of objects matching their elements by the key field,
+// regardless of their position; the elements missing on either side make the arrays differ.
+func (c *comparer) compareSlicesByKey(mgSlice, rmSlice []interface{}, key, path string) (*Reason, error) {
+	matched := make([]bool, len(rmSlice))
+	for i, v := range mgSlice {
+		mgMap, ok := v.(map[string]interface{})
+		if !ok || mgMap[key] == nil {
+			return c.uncomparableField(&Reason{Reason: "key field missing", Path: fmt.Sprintf("%s[%d]", path, i), FirstValue: v},
+				fmt.Errorf("key field %s missing", key))
+		}
+		elemPath := fmt.Sprintf("%s[%s=%v]", path, key, mgMap[key])
+
+		found := -1
+		for j, rv := range rmSlice {
+			rmMap, ok := rv.(map[string]interface{})
+			if !ok || matched[j] || rmMap[key] == nil {
+				continue
+			}
+			if equal, _ := compareAny(mgMap[key], rmMap[key]); equal {
+				found = j
+				break
+			}
+		}
+		if found < 0 {
+			return &Reason{Reason: "element missing remotely", Path: elemPath, FirstValue: v}, nil
+		}
+		matched[found] = true
+
+		reason, err := c.compareMaps(mgMap, rmSlice[found].(map[string]interface{}), elemPath)
+		if err != nil || reason != nil {
+			return reason, err
+		}
+	}
+	for j, rv := range rmSlice {
+		if matched[j] {
+			continue
+		}
+		elemPath := fmt.Sprintf("%s[%d]", path, j)
+		if rmMap, ok := rv.(map[string]interface{}); ok && rmMap[key] != nil {
+			elemPath = fmt.Sprintf("%s[%s=%v]", path, key, rmMap[key])
+		}
+		return &Reason{Reason: "element missing in spec", Path: elemPath, SecondValue: rv}, nil
+	}
+	return nil, nil
+}
+
+// uncomparableField records the field that cannot be compared in tolerant mode,
+// otherwise it returns the comparison error.
+func (c *comparer) uncomparableField(reason *Reason, err error) (*Reason, error) {
+	if c.tolerant {
+		c.uncomparable = append(c.uncomparable, reason.Path)
+		return nil, nil
+	}
+	return reason, &ComparisonError{Path: reason.Path, Err: err}
+}
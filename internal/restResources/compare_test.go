@@ -0,0 +1,244 @@
+package restResources
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+)
+
+func TestCompareExistingPath(t *testing.T) {
+	spec := map[string]interface{}{
+		"name": "repo",
+		"settings": map[string]interface{}{
+			"tags": []interface{}{"a", map[string]interface{}{"private": true}},
+		},
+	}
+
+	remote := map[string]interface{}{
+		"name": "repo",
+		"settings": map[string]interface{}{
+			"tags": []interface{}{"a", map[string]interface{}{"private": false}},
+		},
+	}
+	res, err := compareExisting(spec, remote)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.IsEqual || res.Reason.Path != "settings.tags[1].private" {
+		t.Errorf("expected difference at settings.tags[1].private, got %+v", res.Reason)
+	}
+
+	remote["settings"].(map[string]interface{})["tags"] = []interface{}{"a", "b"}
+	res, err = compareExisting(spec, remote)
+	var cerr *ComparisonError
+	if !errors.As(err, &cerr) || cerr.Path != "settings.tags[1]" {
+		t.Fatalf("expected comparison error at settings.tags[1], got %v", err)
+	}
+	if res.IsEqual || res.Reason.Path != "settings.tags[1]" {
+		t.Errorf("expected reason at settings.tags[1], got %+v", res.Reason)
+	}
+
+	remote["settings"].(map[string]interface{})["tags"] = []interface{}{"a"}
+	res, err = compareExisting(spec, remote)
+	if err != nil || res.IsEqual || res.Reason.Path != "settings.tags[1]" {
+		t.Errorf("expected missing element at settings.tags[1], got %+v (%v)", res.Reason, err)
+	}
+}
+
+func TestCompareTolerant(t *testing.T) {
+	spec := map[string]interface{}{
+		"name":     "repo",
+		"settings": map[string]interface{}{"visibility": "private"},
+		"topics":   []interface{}{"go"},
+	}
+	remote := map[string]interface{}{
+		"name":     "repo",
+		"settings": "private",
+		"topics":   []interface{}{"go"},
+	}
+
+	c := &comparer{tolerant: true}
+	res, err := c.compare(spec, remote, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.IsEqual {
+		t.Errorf("expected equal, got %+v", res.Reason)
+	}
+	if !reflect.DeepEqual(res.Uncomparable, []string{"settings"}) {
+		t.Errorf("expected settings to be reported as uncomparable, got %v", res.Uncomparable)
+	}
+
+	remote["name"] = "other"
+	c = &comparer{tolerant: true}
+	res, err = c.compare(spec, remote, "")
+	if err != nil || res.IsEqual || res.Reason.Path != "name" {
+		t.Errorf("expected difference at name, got %+v (%v)", res.Reason, err)
+	}
+}
+
+func TestCompareSlicesByKey(t *testing.T) {
+	spec := map[string]interface{}{
+		"pipelines": []interface{}{
+			map[string]interface{}{"id": int64(1), "name": "build"},
+			map[string]interface{}{"id": int64(2), "name": "deploy"},
+		},
+	}
+	c := &comparer{arrayKeys: map[string]string{"pipelines": "id"}}
+
+	tests := []struct {
+		name     string
+		remote   []interface{}
+		equal    bool
+		path     string
+		expected string
+	}{
+		{
+			name: "reordered",
+			remote: []interface{}{
+				map[string]interface{}{"id": json.Number("2"), "name": "deploy"},
+				map[string]interface{}{"id": json.Number("1"), "name": "build", "status": "ok"},
+			},
+			equal: true,
+		},
+		{
+			name: "element differs",
+			remote: []interface{}{
+				map[string]interface{}{"id": json.Number("2"), "name": "release"},
+				map[string]interface{}{"id": json.Number("1"), "name": "build"},
+			},
+			path:     "pipelines[id=2].name",
+			expected: "values differ",
+		},
+		{
+			name: "missing remotely",
+			remote: []interface{}{
+				map[string]interface{}{"id": json.Number("1"), "name": "build"},
+			},
+			path:     "pipelines[id=2]",
+			expected: "element missing remotely",
+		},
+		{
+			name: "missing in spec",
+			remote: []interface{}{
+				map[string]interface{}{"id": json.Number("1"), "name": "build"},
+				map[string]interface{}{"id": json.Number("3"), "name": "test"},
+				map[string]interface{}{"id": json.Number("2"), "name": "deploy"},
+			},
+			path:     "pipelines[id=3]",
+			expected: "element missing in spec",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := c.compare(spec, map[string]interface{}{"pipelines": tt.remote}, "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.IsEqual != tt.equal {
+				t.Fatalf("expected equal %v, got %v (%+v)", tt.equal, res.IsEqual, res.Reason)
+			}
+			if !tt.equal && (res.Reason.Path != tt.path || res.Reason.Reason != tt.expected) {
+				t.Errorf("expected %q at %s, got %q at %s", tt.expected, tt.path, res.Reason.Reason, res.Reason.Path)
+			}
+		})
+	}
+}
+
+func TestCompareIgnored(t *testing.T) {
+	c := &comparer{ignored: text.NewStringSet("password", "keys.secret")}
+	spec := map[string]interface{}{
+		"name":     "user",
+		"password": "secret",
+		"keys":     []interface{}{map[string]interface{}{"name": "ci", "secret": "abc"}},
+	}
+	remote := map[string]interface{}{
+		"name":     "user",
+		"password": "********",
+		"keys":     []interface{}{map[string]interface{}{"name": "ci"}},
+	}
+	res, err := c.compare(spec, remote, "")
+	if err != nil || !res.IsEqual {
+		t.Errorf("expected equal, got %+v (%v)", res.Reason, err)
+	}
+}
+
+// comparisonSeeds are nested objects with unusual keys and mixed types, used as seed corpus of the fuzz tests
+var comparisonSeeds = []string{
+	`{}`,
+	`{"": ""}`,
+	`{"a.b": {"c.d": [1, "1", true, null]}}`,
+	`{"ключ": {"名前": "値", "emoji😀": [[], {}]}}`,
+	`{"n": 1e308, "m": -0, "s": "", "l": [{"": null}, [null, [1.5]]]}`,
+	`{"[0]": {"a[1]": [{"id": 1}, {"id": "1"}]}}`,
+}
+
+func FuzzCompareExisting(f *testing.F) {
+	for _, a := range comparisonSeeds {
+		for _, b := range comparisonSeeds {
+			f.Add(a, b)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, a, b string) {
+		var mg, rm map[string]interface{}
+		if json.Unmarshal([]byte(a), &mg) != nil || json.Unmarshal([]byte(b), &rm) != nil {
+			t.Skip()
+		}
+
+		// The comparison must be reflexive
+		var cp map[string]interface{}
+		json.Unmarshal([]byte(a), &cp)
+		res, err := compareExisting(mg, cp)
+		if err != nil || !res.IsEqual {
+			t.Fatalf("expected %s to be equal to itself, got %+v (%v)", a, res.Reason, err)
+		}
+
+		// Comparing arbitrary objects must not panic, and the errors must carry the field path
+		res, err = compareExisting(mg, rm)
+		if err != nil {
+			var cerr *ComparisonError
+			if !errors.As(err, &cerr) {
+				t.Fatalf("expected a comparison error, got %T: %v", err, err)
+			}
+		}
+		if !res.IsEqual && res.Reason == nil {
+			t.Fatalf("expected a reason for the difference")
+		}
+
+		// In tolerant mode the values that cannot be compared are never an error
+		c := &comparer{tolerant: true, arrayKeys: map[string]string{"": "id", "[0].a": "id"}}
+		if _, err := c.compare(mg, rm, ""); err != nil {
+			t.Fatalf("unexpected error in tolerant mode: %v", err)
+		}
+	})
+}
+
+// largeObject returns an object with the given number of fields, each holding a nested object and an array.
+func largeObject(fields int) map[string]interface{} {
+	obj := map[string]interface{}{}
+	for i := 0; i < fields; i++ {
+		obj[fmt.Sprintf("field%d", i)] = map[string]interface{}{
+			"name":    fmt.Sprintf("name-%d", i),
+			"enabled": i%2 == 0,
+			"count":   int64(i),
+			"tags":    []interface{}{"a", "b", map[string]interface{}{"id": int64(i), "value": 1.5}},
+		}
+	}
+	return obj
+}
+
+func BenchmarkCompareExisting(b *testing.B) {
+	mg, rm := largeObject(1000), largeObject(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		res, err := compareExisting(mg, rm)
+		if err != nil || !res.IsEqual {
+			b.Fatalf("expected the objects to be equal: %v", err)
+		}
+	}
+}
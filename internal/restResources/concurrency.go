@@ -0,0 +1,154 @@
+package restResources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/apiaction"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/meta"
+	"github.com/lucasepe/httplib"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// AnnotationKeyETag is the key in the annotations map of a resource
+// holding the ETag of the last observed external resource.
+const AnnotationKeyETag = "krateo.io/etag"
+
+const defaultConflictRetries = 3
+
+// setETag stores the ETag of the observed external resource, returns true if it changed.
+func setETag(lock *getter.OptimisticLocking, mg *unstructured.Unstructured, etag string) bool {
+	if lock == nil || !lock.ETag || etag == "" {
+		return false
+	}
+	if mg.GetAnnotations()[AnnotationKeyETag] == etag {
+		return false
+	}
+	meta.AddAnnotations(mg, map[string]string{AnnotationKeyETag: etag})
+	return true
+}
+
+// setVersion stores the version field of the response body in the status.
+func setVersion(lock *getter.OptimisticLocking, mg *unstructured.Unstructured, body map[string]interface{}) error {
+	if lock == nil || lock.VersionField == "" {
+		return nil
+	}
+	version, ok := body[lock.VersionField]
+	if !ok || version == nil {
+		return nil
+	}
+	return unstructured.SetNestedField(mg.Object, version, "status", lock.VersionField)
+}
+
+// applyVersion sends the observed ETag in the If-Match header and the observed version
+// as the configured path or query parameter, or body field.
+func applyVersion(lock *getter.OptimisticLocking, mg *unstructured.Unstructured, callInfo *CallInfo, conf *restclient.RequestConfiguration) error {
+	if lock == nil {
+		return nil
+	}
+	if etag := mg.GetAnnotations()[AnnotationKeyETag]; lock.ETag && etag != "" {
+		if conf.Headers == nil {
+			conf.Headers = map[string]string{}
+		}
+		conf.Headers["If-Match"] = etag
+	}
+	if lock.VersionField == "" {
+		return nil
+	}
+	version, ok, err := unstructured.NestedFieldNoCopy(mg.Object, "status", lock.VersionField)
+	if err != nil || !ok || version == nil {
+		return err
+	}
+	param := lock.VersionParam
+	if param == "" {
+		param = lock.VersionField
+	}
+	switch {
+	case callInfo.ReqParams.Parameters.Contains(param), callInfo.ReqParams.Query.Contains(param):
+		stringValue, err := text.GenericToString(version)
+		if err != nil {
+			return err
+		}
+		if callInfo.ReqParams.Parameters.Contains(param) {
+			if conf.Parameters == nil {
+				conf.Parameters = map[string]string{}
+			}
+			conf.Parameters[param] = stringValue
+		} else {
+			if conf.Query == nil {
+				conf.Query = map[string]string{}
+			}
+			conf.Query[param] = stringValue
+		}
+	default:
+		body, ok := conf.Body.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		path := []string{param}
+		if callInfo.BodyRootPath != "" {
+			path = append(strings.Split(callInfo.BodyRootPath, "."), param)
+		}
+		conf.Body = withField(body, version, path...)
+	}
+	return nil
+}
+
+// withField returns a copy of the body with the field at the given path set, the body is not modified.
+func withField(body map[string]interface{}, value interface{}, path ...string) map[string]interface{} {
+	res := make(map[string]interface{}, len(body)+1)
+	for k, v := range body {
+		res[k] = v
+	}
+	if len(path) == 1 {
+		res[path[0]] = value
+		return res
+	}
+	child, _ := res[path[0]].(map[string]interface{})
+	res[path[0]] = withField(child, value, path[1:]...)
+	return res
+}
+
+// isConflict returns true if the update was rejected because the external resource changed meanwhile.
+func isConflict(lock *getter.OptimisticLocking, err error) bool {
+	return lock != nil && httplib.HasStatusErr(err, http.StatusPreconditionFailed, http.StatusConflict)
+}
+
+// conflictRetries returns the number of times a conflicting update is retried.
+func conflictRetries(lock *getter.OptimisticLocking) int {
+	if lock.MaxRetries > 0 {
+		return lock.MaxRetries
+	}
+	return defaultConflictRetries
+}
+
+// refreshVersion gets the external resource again, storing its current ETag and version on the resource.
+func refreshVersion(ctx context.Context, cli *restclient.UnstructuredClient, clientInfo *getter.Info, mg *unstructured.Unstructured, statusFields, specFields map[string]interface{}) error {
+	apiCall, callInfo, err := APICallBuilder(cli, clientInfo, apiaction.Get)
+	if err != nil {
+		return err
+	}
+	if apiCall == nil {
+		return fmt.Errorf("API call not found for %s", apiaction.Get)
+	}
+	reqConfiguration, err := BuildCallConfig(callInfo, statusFields, specFields)
+	if err != nil {
+		return err
+	}
+	followResponseLink(mg, callInfo, reqConfiguration)
+	body, err := apiCall(ctx, http.DefaultClient, callInfo.Path, reqConfiguration)
+	if err != nil {
+		return err
+	}
+	lock := clientInfo.Resource.OptimisticLocking
+	setETag(lock, mg, cli.ResponseHeaders.Get("ETag"))
+	if body != nil {
+		return setVersion(lock, mg, *body)
+	}
+	return nil
+}
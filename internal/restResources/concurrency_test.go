@@ -0,0 +1,70 @@
+package restResources
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/lucasepe/httplib"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestApplyVersion(t *testing.T) {
+	mg := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{"revision": int64(7)},
+	}}
+	lock := &getter.OptimisticLocking{ETag: true, VersionField: "revision"}
+	if !setETag(lock, mg, `"abc"`) {
+		t.Fatalf("expected ETag to be stored")
+	}
+
+	body := map[string]interface{}{"repository": map[string]interface{}{"name": "test"}}
+	callInfo := &CallInfo{
+		ReqParams: &RequestedParams{
+			Parameters: text.StringSet{},
+			Query:      text.StringSet{},
+			Body:       text.StringSet{},
+		},
+		BodyRootPath: "repository",
+	}
+	conf := &restclient.RequestConfiguration{Body: body}
+	if err := applyVersion(lock, mg, callInfo, conf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if conf.Headers["If-Match"] != `"abc"` {
+		t.Errorf("expected If-Match header, got %v", conf.Headers)
+	}
+	expected := map[string]interface{}{"repository": map[string]interface{}{"name": "test", "revision": int64(7)}}
+	if !reflect.DeepEqual(conf.Body, expected) {
+		t.Errorf("expected body %v, got %v", expected, conf.Body)
+	}
+	if _, ok := body["repository"].(map[string]interface{})["revision"]; ok {
+		t.Errorf("expected the original body not to be modified")
+	}
+
+	lock.VersionParam = "version"
+	callInfo.ReqParams.Query = text.NewStringSet("version")
+	conf = &restclient.RequestConfiguration{Body: body}
+	if err := applyVersion(lock, mg, callInfo, conf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if conf.Query["version"] != "7" {
+		t.Errorf("expected version query parameter, got %v", conf.Query)
+	}
+}
+
+func TestIsConflict(t *testing.T) {
+	lock := &getter.OptimisticLocking{ETag: true}
+	if !isConflict(lock, fmt.Errorf("updating: %w", &httplib.StatusError{StatusCode: 412})) {
+		t.Errorf("expected 412 to be a conflict")
+	}
+	if isConflict(nil, &httplib.StatusError{StatusCode: 409}) {
+		t.Errorf("expected no conflict without optimistic locking")
+	}
+	if isConflict(lock, &httplib.StatusError{StatusCode: 500}) {
+		t.Errorf("expected 500 not to be a conflict")
+	}
+}
@@ -0,0 +1,222 @@
+package restResources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	customcondition "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/condition"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/event"
+	unstructuredtools "github.com/krateoplatformops/unstructured-runtime/pkg/tools/unstructured"
+	"github.com/lucasepe/httplib"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// problemConditions returns the conditions reporting the problem of the external API the error comes from,
+// nil if the error does not come from the external API (e.g. a failed update of the CR).
+// The problem conditions other than the one reported are cleared, since the API got past them.
+func problemConditions(err error) []metav1.Condition {
+	var downErr *externalAPIDownError
+	errors.As(err, &downErr)
+
+	var problem metav1.Condition
+	var authErr *getter.AuthError
+	var statusErr *httplib.StatusError
+	var urlErr *url.Error
+	var limitErr *restclient.SearchLimitError
+	var collectionErr *restclient.CollectionNotFoundError
+	var panicErr *PanicError
+	var quotaErr *quotaExceededError
+	var loopErr *updateLoopError
+	if errors.As(err, &loopErr) {
+		// Not an API problem: the previously reported ones are left as they are
+		return []metav1.Condition{customcondition.PossibleUpdateLoop(loopErr.Error())}
+	}
+	switch {
+	case errors.As(err, &panicErr):
+		problem = customcondition.InternalError(panicErr.Error())
+	case errors.As(err, &quotaErr):
+		problem = customcondition.QuotaExceeded(quotaErr.Error())
+	case errors.As(err, &limitErr):
+		problem = customcondition.SearchLimitExceeded(limitErr.Error())
+	case errors.As(err, &collectionErr):
+		problem = customcondition.ExternalError(customcondition.ReasonCollectionNotFound, collectionErr.Error())
+	case errors.As(err, &authErr):
+		problem = customcondition.AuthFailed(customcondition.ReasonCredentialsUnresolved, authErr.Error())
+	case errors.As(err, &statusErr):
+		switch statusErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			problem = customcondition.AuthFailed(customcondition.ReasonCredentialsRejected, statusErr.Error())
+		case http.StatusTooManyRequests:
+			problem = customcondition.RateLimited(statusErr.Error())
+		default:
+			problem = customcondition.ExternalError(customcondition.ReasonAPIError, statusErr.Error())
+		}
+	case errors.As(err, &urlErr):
+		problem = customcondition.ExternalError(customcondition.ReasonAPIUnreachable, urlErr.Error())
+	default:
+		return nil
+	}
+
+	conds := []metav1.Condition{}
+	for _, cond := range clearedConditions() {
+		switch {
+		case cond.Type == problem.Type:
+			cond = problem
+		case cond.Type == customcondition.TypeExternalAPIDown && downErr != nil:
+			cond = customcondition.ExternalAPIDown(fmt.Sprintf("%s: %s", downErr.server, downErr.message))
+		case cond.Type == customcondition.TypeDegraded && downErr != nil:
+			cond = customcondition.Degraded(customcondition.TypeExternalAPIDown, downErr.Error())
+		case cond.Type == customcondition.TypeDegraded:
+			cond = customcondition.Degraded(problem.Type, problem.Message)
+		}
+		conds = append(conds, cond)
+	}
+	return conds
+}
+
+// clearedConditions returns the conditions reporting that the external API has no problem.
+func clearedConditions() []metav1.Condition {
+	return []metav1.Condition{
+		customcondition.Authenticated(),
+		customcondition.NotRateLimited(),
+		customcondition.NoExternalError(),
+		customcondition.SearchCompleted(),
+		customcondition.NoInternalError(),
+		customcondition.WithinQuota(),
+		customcondition.ExternalAPIUp(),
+		customcondition.NotDegraded(),
+	}
+}
+
+// updateProblemConditions sets the conditions reporting the problem of the external API the reconcile failed with,
+// clearing the ones previously reported once the reconcile succeeds. The status is updated only if they changed.
+func (h *handler) updateProblemConditions(ctx context.Context, mg *unstructured.Unstructured, err error) {
+	var conds []metav1.Condition
+	if err == nil {
+		// Clearing only the conditions previously reported, not to add them to every resource (see clearProblems)
+		for _, cond := range clearedConditions() {
+			if h.isConditionTrue(mg, cond.Type) {
+				conds = append(conds, cond)
+			}
+		}
+	} else {
+		conds = problemConditions(err)
+	}
+	if !h.conditionsChanged(mg, conds) {
+		return
+	}
+
+	if err := h.setConditions(ctx, mg, conds...); err != nil {
+		h.objectLogger(mg).Debug("Updating problem conditions", "error", err)
+	}
+}
+
+// setConditions sets the conditions on the latest version of the resource and updates its status.
+func (h *handler) setConditions(ctx context.Context, mg *unstructured.Unstructured, conds ...metav1.Condition) error {
+	return h.updateLatestStatus(ctx, mg, func(latest *unstructured.Unstructured) error {
+		for _, cond := range conds {
+			if err := h.conditions.Set(latest, cond); err != nil {
+				return err
+			}
+		}
+		return h.setReadiness(latest)
+	})
+}
+
+// isConditionTrue returns true if the condition of the given type, as translated through the vocabulary, is true.
+func (h *handler) isConditionTrue(mg *unstructured.Unstructured, condType string) bool {
+	for _, el := range h.conditions.Map(metav1.Condition{Type: condType}) {
+		for _, co := range unstructuredtools.GetConditions(mg) {
+			if co.Type == el.Type && co.Status == metav1.ConditionTrue {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// conditionsChanged returns true if any of the conditions, as translated through the vocabulary,
+// differs from the one set on the resource.
+func (h *handler) conditionsChanged(mg *unstructured.Unstructured, conds []metav1.Condition) bool {
+	current := unstructuredtools.GetConditions(mg)
+	for _, cond := range conds {
+		for _, el := range h.conditions.Map(cond) {
+			found := false
+			for _, co := range current {
+				if co.Type == el.Type {
+					found = co.Status == el.Status && co.Reason == el.Reason && co.Message == el.Message
+					break
+				}
+			}
+			if !found {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// driftMessage summarizes the difference between the resource and its external resource.
+func driftMessage(res ComparisonResult) string {
+	if res.Reason == nil {
+		return "Resource differs from the external resource"
+	}
+	return fmt.Sprintf("%s at %s - spec value: %v, remote value: %v", res.Reason.Reason, res.Reason.Path, res.Reason.FirstValue, res.Reason.SecondValue)
+}
+
+// setReadiness sets the Ready, Reconciling and Stalled conditions of the kstatus conventions,
+// computed from the other conditions of the resource, and the phase summarizing them if enabled.
+func (h *handler) setReadiness(mg *unstructured.Unstructured) error {
+	for _, cond := range customcondition.Kstatus(unstructuredtools.GetConditions(mg), generationObserved(mg)) {
+		if err := h.conditions.Set(mg, cond); err != nil {
+			return err
+		}
+	}
+	if !h.statusPhase {
+		return nil
+	}
+	phase, message := customcondition.Phase(unstructuredtools.GetConditions(mg))
+	if err := unstructured.SetNestedField(mg.Object, phase, "status", "phase"); err != nil {
+		return err
+	}
+	return unstructured.SetNestedField(mg.Object, message, "status", "message")
+}
+
+// clearProblems clears the problem conditions previously reported, once the resource is reconciled.
+func (h *handler) clearProblems(mg *unstructured.Unstructured) error {
+	for _, cond := range clearedConditions() {
+		if !h.isConditionTrue(mg, cond.Type) {
+			continue
+		}
+		if err := h.conditions.Set(mg, cond); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setDefinitionAmbiguity reports the RestDefinitions managing the resource besides the selected one,
+// recording an event once the ambiguity appears or changes. The condition is written with the status.
+func (h *handler) setDefinitionAmbiguity(mg *unstructured.Unstructured, clientInfo *getter.Info) error {
+	if clientInfo.Ambiguity == "" {
+		if !h.isConditionTrue(mg, customcondition.TypeAmbiguousDefinition) {
+			return nil
+		}
+		return h.conditions.Set(mg, customcondition.UnambiguousDefinition())
+	}
+	cond := customcondition.AmbiguousDefinition(clientInfo.Ambiguity)
+	if !h.conditionsChanged(mg, []metav1.Condition{cond}) {
+		return nil
+	}
+	h.objectLogger(mg).Info("Multiple RestDefinitions manage the resource", "name", mg.GetName(), "namespace", mg.GetNamespace(), "message", clientInfo.Ambiguity)
+	if h.recorder != nil {
+		h.recorder.Event(mg, event.Warning(reasonAmbiguousDefinition, errors.New(clientInfo.Ambiguity)))
+	}
+	return h.conditions.Set(mg, cond)
+}
@@ -0,0 +1,186 @@
+package restResources
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	customcondition "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/condition"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	unstructuredtools "github.com/krateoplatformops/unstructured-runtime/pkg/tools/unstructured"
+	"github.com/lucasepe/httplib"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestProblemConditions(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		condType   string
+		reason     string
+		noProblems bool
+	}{
+		{
+			name:     "credentials unresolved",
+			err:      &getter.AuthError{Err: errors.New("secret not found")},
+			condType: customcondition.TypeAuthFailed,
+			reason:   customcondition.ReasonCredentialsUnresolved,
+		},
+		{
+			name:     "credentials rejected",
+			err:      fmt.Errorf("performing call: %w", &httplib.StatusError{StatusCode: http.StatusUnauthorized}),
+			condType: customcondition.TypeAuthFailed,
+			reason:   customcondition.ReasonCredentialsRejected,
+		},
+		{
+			name:     "rate limited",
+			err:      &httplib.StatusError{StatusCode: http.StatusTooManyRequests},
+			condType: customcondition.TypeRateLimited,
+			reason:   customcondition.ReasonRateLimited,
+		},
+		{
+			name:     "server error",
+			err:      &httplib.StatusError{StatusCode: http.StatusBadGateway},
+			condType: customcondition.TypeExternalError,
+			reason:   customcondition.ReasonAPIError,
+		},
+		{
+			name:     "unreachable",
+			err:      &url.Error{Op: "Get", URL: "https://api.example.com", Err: errors.New("connection refused")},
+			condType: customcondition.TypeExternalError,
+			reason:   customcondition.ReasonAPIUnreachable,
+		},
+		{
+			name:     "search limit exceeded",
+			err:      fmt.Errorf("performing call: %w", &restclient.SearchLimitError{Limit: restclient.SearchLimitMaxPages, Value: "10"}),
+			condType: customcondition.TypeSearchLimitExceeded,
+			reason:   customcondition.ReasonSearchLimitReached,
+		},
+		{
+			name:     "collection not found",
+			err:      &restclient.CollectionNotFoundError{Path: "/repos"},
+			condType: customcondition.TypeExternalError,
+			reason:   customcondition.ReasonCollectionNotFound,
+		},
+		{
+			name:     "panic",
+			err:      &PanicError{Value: "assignment to entry in nil map"},
+			condType: customcondition.TypeInternalError,
+			reason:   customcondition.ReasonPanic,
+		},
+		{
+			name:     "quota exceeded",
+			err:      &quotaExceededError{used: 120, limit: 100, window: time.Hour, retryAfter: 10 * time.Minute},
+			condType: customcondition.TypeQuotaExceeded,
+			reason:   customcondition.ReasonCallBudgetExhausted,
+		},
+		{
+			name:       "not an API error",
+			err:        errors.New("updating CR"),
+			noProblems: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conds := problemConditions(tt.err)
+			if tt.noProblems {
+				if conds != nil {
+					t.Errorf("expected no conditions, got %v", conds)
+				}
+				return
+			}
+			if len(conds) != 8 {
+				t.Fatalf("expected 8 conditions, got %d", len(conds))
+			}
+			for _, co := range conds {
+				switch co.Type {
+				case tt.condType:
+					if co.Status != metav1.ConditionTrue || co.Reason != tt.reason {
+						t.Errorf("unexpected %s condition: %+v", co.Type, co)
+					}
+				case customcondition.TypeDegraded:
+					if co.Status != metav1.ConditionTrue || co.Reason != tt.condType {
+						t.Errorf("unexpected degraded condition: %+v", co)
+					}
+				default:
+					if co.Status != metav1.ConditionFalse {
+						t.Errorf("expected %s to be cleared: %+v", co.Type, co)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestProblemConditionsUpdateLoop(t *testing.T) {
+	until := time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC)
+	conds := problemConditions(fmt.Errorf("updating: %w", &updateLoopError{until: until}))
+	if len(conds) != 1 {
+		t.Fatalf("expected only the update loop condition, got %v", conds)
+	}
+	co := conds[0]
+	if co.Type != customcondition.TypePossibleUpdateLoop || co.Status != metav1.ConditionTrue || co.Message != "possible update loop detected, updates skipped until 2024-01-01T10:15:00Z" {
+		t.Errorf("unexpected condition: %+v", co)
+	}
+}
+
+func TestConditionsChanged(t *testing.T) {
+	h := &handler{}
+	mg := summaryResource()
+	rateLimited := customcondition.RateLimited("unexpected status: 429:")
+
+	if !h.conditionsChanged(mg, []metav1.Condition{rateLimited}) {
+		t.Errorf("expected a condition not set to be changed")
+	}
+	if err := unstructuredtools.SetCondition(mg, rateLimited); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h.conditionsChanged(mg, []metav1.Condition{rateLimited}) {
+		t.Errorf("expected the same condition not to be changed")
+	}
+	if !h.isConditionTrue(mg, customcondition.TypeRateLimited) {
+		t.Errorf("expected the rate limited condition to be true")
+	}
+	if !h.conditionsChanged(mg, []metav1.Condition{customcondition.NotRateLimited()}) {
+		t.Errorf("expected the cleared condition to be changed")
+	}
+}
+
+func TestDriftMessage(t *testing.T) {
+	res := ComparisonResult{Reason: &Reason{Reason: "values differ", Path: "description", FirstValue: "a", SecondValue: "b"}}
+	if got := driftMessage(res); got != "values differ at description - spec value: a, remote value: b" {
+		t.Errorf("unexpected drift message: %s", got)
+	}
+}
+
+func TestSetDefinitionAmbiguity(t *testing.T) {
+	h := &handler{logger: logging.NewNopLogger()}
+	mg := summaryResource()
+
+	if err := h.setDefinitionAmbiguity(mg, &getter.Info{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(unstructuredtools.GetConditions(mg)) != 0 {
+		t.Errorf("expected no condition for an unambiguous definition")
+	}
+
+	msg := "RestDefinitions repos-v2, repos manage kind Repo of group gen.github.com, using the newest one (repos-v2)"
+	if err := h.setDefinitionAmbiguity(mg, &getter.Info{Ambiguity: msg}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !h.isConditionTrue(mg, customcondition.TypeAmbiguousDefinition) {
+		t.Errorf("expected the ambiguous definition to be reported")
+	}
+
+	if err := h.setDefinitionAmbiguity(mg, &getter.Info{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h.isConditionTrue(mg, customcondition.TypeAmbiguousDefinition) {
+		t.Errorf("expected the ambiguity to be cleared")
+	}
+}
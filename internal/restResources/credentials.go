@@ -0,0 +1,35 @@
+package restResources
+
+import (
+	"context"
+	"errors"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// reportCredentials reports the outcome of the last call made with the credentials in the authentication object,
+// tracking the secrets they are read from.
+func (h *handler) reportCredentials(ctx context.Context, mg *unstructured.Unstructured, clientInfo *getter.Info, cli *restclient.UnstructuredClient) {
+	h.rotation.Track(mg, clientInfo.AuthRef, clientInfo.AuthSecrets)
+	h.authStatus.Called(ctx, clientInfo.AuthRef, cli.Auth, cli.ResponseStatus)
+}
+
+// credentialsFailed reports the credentials that could not be resolved, tracking the secrets they are read from
+// so that the resource is reconciled again once the secrets are fixed.
+func (h *handler) credentialsFailed(ctx context.Context, mg *unstructured.Unstructured, err error) {
+	var authErr *getter.AuthError
+	if errors.As(err, &authErr) {
+		h.rotation.Track(mg, &authErr.Ref, authErr.Secrets)
+	}
+	h.authStatus.Failed(ctx, err)
+}
+
+// secretRotated enqueues the resource whose credentials are read from a rotated secret, reconciled right away
+// rather than at its next resync, skipping the resync gate.
+func (h *handler) secretRotated(mg *unstructured.Unstructured, ref getter.AuthRef) {
+	h.resync.forget(mg)
+	h.authStatus.Forget(ref)
+	h.requeue.Add(mg, "secret-rotated")
+}
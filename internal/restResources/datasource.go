@@ -0,0 +1,60 @@
+package restResources
+
+import (
+	"context"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/lifecycle"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// isDataSource returns true if the kind is a read-only data source, whose external resource is never
+// created, updated nor deleted.
+func isDataSource(clientInfo *getter.Info) bool {
+	return clientInfo.Resource.DataSource
+}
+
+// dataSourceNotFound reports the external resource of the data source as not found instead of creating it,
+// the resource being observed again at the next resync.
+func (h *handler) dataSourceNotFound(ctx context.Context, log logging.Logger, mg *unstructured.Unstructured) error {
+	log.Debug("External resource of the data source not found, not created", "kind", mg.GetKind())
+	_, err := h.transition(mg, lifecycle.NotFound, "", "The external resource read by the data source was not found")
+	if err != nil {
+		log.Debug("Setting condition", "error", err)
+		return err
+	}
+	err = setObservedGeneration(mg)
+	if err != nil {
+		log.Debug("Setting observed generation", "error", err)
+		return err
+	}
+	err = h.setReadiness(mg)
+	if err != nil {
+		log.Debug("Setting readiness", "error", err)
+		return err
+	}
+	_, err = h.updateStatus(ctx, mg)
+	if err != nil {
+		log.Debug("Updating status", "error", err)
+	}
+	return err
+}
+
+// reservedStatusFields are the status fields written by the controller, never overwritten by the projection
+// of a data source.
+var reservedStatusFields = map[string]bool{"conditions": true, "observedGeneration": true, "phase": true, "message": true}
+
+// projectDataSource projects the fields of the external resource of the data source into the status, the ones
+// not allowed by the status schema of the CRD being stored in the status overflow.
+func projectDataSource(mg *unstructured.Unstructured, body map[string]interface{}) error {
+	for k, v := range body {
+		if reservedStatusFields[k] || v == nil {
+			continue
+		}
+		if err := unstructured.SetNestedField(mg.Object, v, "status", k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
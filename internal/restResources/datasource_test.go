@@ -0,0 +1,59 @@
+package restResources
+
+import (
+	"context"
+	"testing"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/lifecycle"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	unstructuredtools "github.com/krateoplatformops/unstructured-runtime/pkg/tools/unstructured"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/tools/unstructured/condition"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func dataSource() getter.Resource {
+	return getter.Resource{
+		Identifiers:      []string{"id"},
+		VerbsDescription: []getter.VerbsDescription{{Action: "get", Method: "GET", Path: "/repos/{id}"}},
+		DataSource:       true,
+	}
+}
+
+func TestObserveDataSource(t *testing.T) {
+	mg := observedResource()
+	_ = unstructured.SetNestedField(mg.Object, "renamed", "spec", "name")
+	h, writes := observedHandler(t, dataSource(), mg)
+
+	obs, err := h.Observe(context.Background(), mg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !obs.ResourceExists || !obs.ResourceUpToDate {
+		t.Fatalf("expected the data source to be up-to-date whatever its spec, got %+v", obs)
+	}
+	if name, _, _ := unstructured.NestedString(mg.Object, "status", "name"); name != "repo1" {
+		t.Errorf("expected the external resource projected into the status, got name %q", name)
+	}
+	if writes.status != 1 {
+		t.Errorf("expected 1 status write, got %d", writes.status)
+	}
+}
+
+func TestCreateDataSource(t *testing.T) {
+	mg := observedResource()
+	unstructured.RemoveNestedField(mg.Object, "status")
+	h, writes := observedHandler(t, dataSource(), mg)
+
+	if err := h.Create(context.Background(), mg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h.lifecycleState(mg) != lifecycle.Unknown {
+		t.Errorf("expected the data source not to be created, got state %s", h.lifecycleState(mg))
+	}
+	if cond := unstructuredtools.GetCondition(mg, condition.TypeReady, condition.ReasonUnavailable); cond == nil || cond.Message == "" {
+		t.Errorf("expected the data source reported as not found, got %+v", cond)
+	}
+	if writes.status != 1 {
+		t.Errorf("expected 1 status write, got %d", writes.status)
+	}
+}
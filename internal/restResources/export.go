@@ -0,0 +1,257 @@
+package restResources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/apiaction"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// ExportOptions configures the export of the external resources as manifests of custom resources.
+type ExportOptions struct {
+	DynamicClient dynamic.Interface
+	// SwaggerInfoGetter resolves the RestDefinition and the credentials of the exported resources
+	SwaggerInfoGetter getter.Getter
+	// GVK of the custom resources
+	GVK schema.GroupVersionKind
+	// Namespace of the custom resources, where their RestDefinition and credentials are looked up
+	Namespace string
+	// Spec: the fields shared by the spec of the custom resources, i.e. their authenticationRefs and the
+	// parameters of the findby verb (e.g. the org the repositories are listed from)
+	Spec map[string]interface{}
+	// Output the manifests are written to, as a YAML stream
+	Output io.Writer
+	// HTTPClient lists the external resources, defaults to http.DefaultClient
+	HTTPClient *http.Client
+	Logger     logging.Logger
+}
+
+// Export lists the external resources with the findby verb of their RestDefinition and writes the manifest of
+// a custom resource for each of them, easing the import of the existing resources under management: the spec is
+// populated from the fields of the listed items accepted by the create verb, directly or through its field mapping.
+// The resources are then adopted by the findby action once the manifests are applied.
+// It returns the number of manifests written; the ones listed within the pagination limits are written anyway.
+func Export(ctx context.Context, opts ExportOptions) (int, error) {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	if opts.Logger == nil {
+		opts.Logger = logging.NewNopLogger()
+	}
+	if opts.SwaggerInfoGetter == nil {
+		return 0, fmt.Errorf("swagger info getter must be specified")
+	}
+
+	template := &unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{}}}
+	if opts.Spec != nil {
+		template.Object["spec"] = opts.Spec
+	}
+	template.SetGroupVersionKind(opts.GVK)
+	template.SetNamespace(opts.Namespace)
+	template.SetName(strings.ToLower(opts.GVK.Kind))
+
+	clientInfo, err := opts.SwaggerInfoGetter.Get(template)
+	if err != nil {
+		return 0, fmt.Errorf("getting REST client info: %w", err)
+	}
+	if clientInfo == nil {
+		return 0, fmt.Errorf("no RestDefinition found for %s in namespace %q", opts.GVK, opts.Namespace)
+	}
+	cli, err := restclient.BuildClient(ctx, opts.DynamicClient, clientInfo.URL)
+	if err != nil {
+		return 0, fmt.Errorf("building REST client: %w", err)
+	}
+	cli.Auth = clientInfo.Auth
+	applyServerURL(cli, clientInfo)
+	cli.SpecFields = template
+	cli.Pagination = clientInfo.Resource.Pagination
+	cli.JSONAPI = clientInfo.Resource.JSONAPI
+
+	apiCall, callInfo, err := APICallBuilder(cli, clientInfo, apiaction.FindBy)
+	if err != nil {
+		return 0, fmt.Errorf("building API call: %w", err)
+	}
+	if apiCall == nil {
+		return 0, fmt.Errorf("the RestDefinition of %s has no %s verb listing the external resources", opts.GVK.Kind, apiaction.FindBy)
+	}
+	specFields, _ := template.Object["spec"].(map[string]interface{})
+	reqConfiguration, err := BuildCallConfig(callInfo, nil, specFields)
+	if err != nil {
+		return 0, fmt.Errorf("building call configuration: %w", err)
+	}
+	cli.Auth = clientInfo.AuthFor(apiaction.FindBy.String())
+	items, err := cli.ListAll(ctx, opts.HTTPClient, callInfo.Path, reqConfiguration)
+	var limitErr *restclient.SearchLimitError
+	if errors.As(err, &limitErr) {
+		opts.Logger.Info("Exporting the external resources listed so far.", "limit", limitErr.Limit, "value", limitErr.Value)
+	} else if err != nil {
+		return 0, fmt.Errorf("listing the external resources: %w", err)
+	}
+
+	fields, err := exportedFields(cli, clientInfo)
+	if err != nil {
+		return 0, err
+	}
+	rootPath := responseRootPath(clientInfo, apiaction.FindBy)
+	names := text.StringSet{}
+	n := 0
+	for i, el := range items {
+		item, ok := el.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if rootPath != "" {
+			item = *unwrapBody(item, rootPath)
+		}
+		mg := exportedManifest(opts, fields, item)
+		mg.SetName(uniqueName(names, manifestName(clientInfo.Resource.Identifiers, item, opts.GVK.Kind, i)))
+
+		data, err := yaml.Marshal(mg.Object)
+		if err != nil {
+			return n, fmt.Errorf("encoding the manifest of %s: %w", mg.GetName(), err)
+		}
+		if _, err := fmt.Fprintf(opts.Output, "---\n%s", data); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// exportedField is a spec field populated from the listed items.
+type exportedField struct {
+	// spec: the dot separated path of the field in the spec
+	spec string
+	// response: the dot separated path of the field in the listed items
+	response string
+}
+
+// exportedFields returns the spec fields populated from the listed items: the body fields and parameters of
+// the create verb, and the spec fields mapped to them by its field mapping.
+func exportedFields(cli *restclient.UnstructuredClient, clientInfo *getter.Info) ([]exportedField, error) {
+	apiCall, callInfo, err := APICallBuilder(cli, clientInfo, apiaction.Create)
+	if err != nil {
+		return nil, fmt.Errorf("building API call: %w", err)
+	}
+	if apiCall == nil {
+		return nil, fmt.Errorf("the RestDefinition has no %s verb telling the spec fields", apiaction.Create)
+	}
+
+	fields := []exportedField{}
+	mapped := text.StringSet{}
+	for _, m := range callInfo.FieldMapping {
+		if !strings.HasPrefix(m.InCustomResource, "spec.") {
+			continue
+		}
+		response := m.InBody
+		if response == "" {
+			response = m.InPath
+		}
+		if response == "" {
+			response = m.InQuery
+		}
+		if response == "" {
+			continue
+		}
+		mapped.Add(response)
+		fields = append(fields, exportedField{spec: strings.TrimPrefix(m.InCustomResource, "spec."), response: response})
+	}
+	if callInfo.ReqParams != nil {
+		for _, set := range []text.StringSet{callInfo.ReqParams.Body, callInfo.ReqParams.Parameters, callInfo.ReqParams.Query} {
+			keys := make([]string, 0, len(set))
+			for field := range set {
+				keys = append(keys, field)
+			}
+			sort.Strings(keys)
+			for _, field := range keys {
+				if !mapped.Contains(field) {
+					mapped.Add(field)
+					fields = append(fields, exportedField{spec: field, response: field})
+				}
+			}
+		}
+	}
+	return fields, nil
+}
+
+// exportedManifest returns the manifest of the custom resource of the listed item, its spec populated from the
+// shared spec of the options and from the exported fields of the item.
+func exportedManifest(opts ExportOptions, fields []exportedField, item map[string]interface{}) *unstructured.Unstructured {
+	spec := map[string]interface{}{}
+	if opts.Spec != nil {
+		spec = runtime.DeepCopyJSON(opts.Spec)
+	}
+	for _, f := range fields {
+		v, ok, err := unstructured.NestedFieldNoCopy(item, strings.Split(f.response, ".")...)
+		if err != nil || !ok || v == nil {
+			continue
+		}
+		_ = unstructured.SetNestedField(spec, v, strings.Split(f.spec, ".")...)
+	}
+
+	mg := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	mg.SetGroupVersionKind(opts.GVK)
+	mg.SetNamespace(opts.Namespace)
+	return mg
+}
+
+// responseRootPath returns the response root path of the verb of the action, empty if none.
+func responseRootPath(clientInfo *getter.Info, action apiaction.APIAction) string {
+	for _, descr := range clientInfo.Resource.VerbsDescription {
+		if strings.EqualFold(descr.Action, action.String()) {
+			return descr.ResponseRootPath
+		}
+	}
+	return ""
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// manifestName returns the name of the custom resource of the listed item, derived from the value of its first
+// identifier and made a valid DNS subdomain name, or else from the kind and the index of the item.
+func manifestName(identifiers []string, item map[string]interface{}, kind string, i int) string {
+	for _, id := range identifiers {
+		v, ok, err := unstructured.NestedFieldNoCopy(item, strings.Split(id, ".")...)
+		if err != nil || !ok || v == nil {
+			continue
+		}
+		s, err := text.GenericToString(v)
+		if err != nil {
+			continue
+		}
+		name := invalidNameChars.ReplaceAllString(strings.ToLower(s), "-")
+		name = strings.Trim(name, ".-")
+		if len(name) > 253 {
+			name = strings.Trim(name[:253], ".-")
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return fmt.Sprintf("%s-%d", strings.ToLower(kind), i+1)
+}
+
+// uniqueName returns the name, suffixed with a counter if already taken, and records it as taken.
+func uniqueName(taken text.StringSet, name string) string {
+	unique := name
+	for n := 2; taken.Contains(unique); n++ {
+		unique = fmt.Sprintf("%s-%d", name, n)
+	}
+	taken.Add(unique)
+	return unique
+}
@@ -0,0 +1,138 @@
+package restResources
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	"sigs.k8s.io/yaml"
+)
+
+const exportOAS = `openapi: 3.0.0
+info:
+  title: repos
+  version: "1.0"
+servers:
+  - url: %s
+paths:
+  /orgs/{org}/repos:
+    get:
+      parameters:
+        - name: org
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: ok
+    post:
+      parameters:
+        - name: org
+          in: path
+          required: true
+          schema:
+            type: string
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                name:
+                  type: string
+                private:
+                  type: boolean
+      responses:
+        "201":
+          description: created
+`
+
+func TestExport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/openapi.yaml":
+			fmt.Fprintf(w, exportOAS, "http://"+r.Host)
+		case "/orgs/krateo/repos":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"items": [
+				{"id": 1, "name": "Repo_One", "private": true, "stars": 3},
+				{"id": 2, "name": "repo-one", "private": false},
+				{"id": 3, "private": false}
+			]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	resource := getter.Resource{
+		Kind:        "Repo",
+		Identifiers: []string{"name"},
+		VerbsDescription: []getter.VerbsDescription{
+			{Action: "create", Method: "POST", Path: "/orgs/{org}/repos"},
+			{Action: "findby", Method: "GET", Path: "/orgs/{org}/repos"},
+		},
+	}
+	var out bytes.Buffer
+	n, err := Export(context.Background(), ExportOptions{
+		DynamicClient:     fake.NewSimpleDynamicClient(runtime.NewScheme()),
+		SwaggerInfoGetter: staticInfo{info: &getter.Info{URL: srv.URL + "/openapi.yaml", Resource: resource}},
+		GVK:               schema.GroupVersionKind{Group: "github.krateo.io", Version: "v1alpha1", Kind: "Repo"},
+		Namespace:         "default",
+		Spec: map[string]interface{}{
+			"org":                "krateo",
+			"authenticationRefs": map[string]interface{}{"bearerAuthRef": "github"},
+		},
+		Output:     &out,
+		HTTPClient: srv.Client(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 manifests, got %d", n)
+	}
+
+	docs := strings.Split(strings.TrimPrefix(out.String(), "---\n"), "---\n")
+	expected := []struct {
+		name string
+		spec map[string]interface{}
+	}{
+		{name: "repo-one", spec: map[string]interface{}{"name": "Repo_One", "private": true}},
+		{name: "repo-one-2", spec: map[string]interface{}{"name": "repo-one", "private": false}},
+		{name: "repo-3", spec: map[string]interface{}{"private": false}},
+	}
+	for i, doc := range docs {
+		mg := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(doc), &mg.Object); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if mg.GetKind() != "Repo" || mg.GetAPIVersion() != "github.krateo.io/v1alpha1" || mg.GetNamespace() != "default" {
+			t.Errorf("unexpected manifest %v", mg.Object)
+		}
+		if mg.GetName() != expected[i].name {
+			t.Errorf("expected name %s, got %s", expected[i].name, mg.GetName())
+		}
+		for k, v := range expected[i].spec {
+			if got, _, _ := unstructured.NestedFieldNoCopy(mg.Object, "spec", k); got != v {
+				t.Errorf("expected spec.%s %v, got %v", k, v, got)
+			}
+		}
+		if org, _, _ := unstructured.NestedString(mg.Object, "spec", "org"); org != "krateo" {
+			t.Errorf("expected the shared spec fields, got %v", mg.Object["spec"])
+		}
+		if _, ok, _ := unstructured.NestedFieldNoCopy(mg.Object, "spec", "stars"); ok {
+			t.Errorf("expected only the fields accepted by the create verb, got %v", mg.Object["spec"])
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package restResources
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/apiaction"
+	customcondition "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/condition"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// getFindByPage returns the page where the findby action last found the resource, if persisted in the status.
+func getFindByPage(statusFields map[string]interface{}) string {
+	page, _, _ := unstructured.NestedString(statusFields, "findBy", "page")
+	return page
+}
+
+// setFindByPage persists in the status the page where the findby action found the resource.
+func setFindByPage(mg *unstructured.Unstructured, page string) error {
+	return unstructured.SetNestedField(mg.Object, page, "status", "findBy", "page")
+}
+
+// identifierCache keeps in memory the identifiers resolved by the findby action,
+// so that the resources can be got by their identifiers even before they are persisted in the status.
+type identifierCache struct {
+	mu    sync.RWMutex
+	items map[string]map[string]interface{}
+}
+
+func newIdentifierCache() *identifierCache {
+	return &identifierCache{items: map[string]map[string]interface{}{}}
+}
+
+// Get returns the identifiers cached for the object key.
+func (c *identifierCache) Get(key string) (map[string]interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ids, ok := c.items[key]
+	return ids, ok
+}
+
+// Set caches the values of the identifiers found in the body for the object key.
+func (c *identifierCache) Set(key string, identifiers []string, body map[string]interface{}) {
+	if c == nil {
+		return
+	}
+	ids := identifierValues(identifiers, body)
+	if len(ids) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items[key] = ids
+}
+
+// Delete evicts the identifiers cached for the object key.
+func (c *identifierCache) Delete(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.items, key)
+}
+
+// identifierValues returns the values of the identifiers found in the body, as strings,
+// the identifiers being dot separated paths of the body fields.
+func identifierValues(identifiers []string, body map[string]interface{}) map[string]interface{} {
+	ids := map[string]interface{}{}
+	for _, identifier := range identifiers {
+		v, ok, err := unstructured.NestedFieldNoCopy(body, strings.Split(identifier, ".")...)
+		if err != nil || !ok {
+			continue
+		}
+		s, err := text.GenericToString(v)
+		if err != nil {
+			continue
+		}
+		ids[identifier] = s
+	}
+	return ids
+}
+
+// withIdentifiers returns a copy of the status fields with the given identifiers set.
+func withIdentifiers(statusFields map[string]interface{}, ids map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(statusFields)+len(ids))
+	for k, v := range statusFields {
+		res[k] = v
+	}
+	for k, v := range ids {
+		res[k] = v
+	}
+	return res
+}
+
+// withoutIdentifiers returns a copy of the status fields without the given identifiers.
+func withoutIdentifiers(statusFields map[string]interface{}, identifiers []string) map[string]interface{} {
+	res := make(map[string]interface{}, len(statusFields))
+	for k, v := range statusFields {
+		res[k] = v
+	}
+	for _, identifier := range identifiers {
+		delete(res, identifier)
+	}
+	return res
+}
+
+// setSearchMiss reports how the search of the findby action completed without finding the resource,
+// the condition being written with the status by the creation of the resource.
+func (h *handler) setSearchMiss(mg *unstructured.Unstructured, err error) error {
+	var notFound *restclient.NotFoundError
+	if !errors.As(err, &notFound) {
+		return nil
+	}
+	reason := customcondition.ReasonNoMatchingItem
+	if notFound.Searched == 0 {
+		reason = customcondition.ReasonCollectionEmpty
+	}
+	return h.conditions.Set(mg, customcondition.SearchMissed(reason, notFound.Error()))
+}
+
+// canFindBy returns true if the resource can be searched by the findby action.
+func (h *handler) canFindBy(cli *restclient.UnstructuredClient, clientInfo *getter.Info) bool {
+	apiCall, _, err := APICallBuilder(cli, clientInfo, apiaction.FindBy)
+	return apiCall != nil && err == nil
+}
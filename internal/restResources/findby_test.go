@@ -0,0 +1,96 @@
+package restResources
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	customcondition "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/condition"
+	unstructuredtools "github.com/krateoplatformops/unstructured-runtime/pkg/tools/unstructured"
+	"github.com/lucasepe/httplib"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIdentifierCache(t *testing.T) {
+	c := newIdentifierCache()
+
+	c.Set("gen.github.com/v1alpha1/Repo/default/repo", []string{"id", "name"}, map[string]interface{}{
+		"id":          float64(42),
+		"name":        "repo",
+		"description": "test",
+	})
+
+	ids, ok := c.Get("gen.github.com/v1alpha1/Repo/default/repo")
+	if !ok {
+		t.Fatalf("expected cached identifiers")
+	}
+	expected := map[string]interface{}{"id": "42", "name": "repo"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected %v, got %v", expected, ids)
+	}
+
+	status := withIdentifiers(map[string]interface{}{"id": "1", "other": "x"}, ids)
+	if status["id"] != "42" || status["other"] != "x" {
+		t.Errorf("unexpected status fields: %v", status)
+	}
+	status = withoutIdentifiers(status, []string{"id", "name"})
+	if !reflect.DeepEqual(status, map[string]interface{}{"other": "x"}) {
+		t.Errorf("unexpected status fields: %v", status)
+	}
+
+	c.Delete("gen.github.com/v1alpha1/Repo/default/repo")
+	if _, ok := c.Get("gen.github.com/v1alpha1/Repo/default/repo"); ok {
+		t.Errorf("expected identifiers to be evicted")
+	}
+}
+
+func TestIdentifierCacheNestedIdentifiers(t *testing.T) {
+	c := newIdentifierCache()
+
+	c.Set("gen.github.com/v1alpha1/Repo/default/repo", []string{"metadata.id", "name"}, map[string]interface{}{
+		"metadata": map[string]interface{}{"id": float64(42)},
+		"name":     "repo",
+	})
+
+	ids, ok := c.Get("gen.github.com/v1alpha1/Repo/default/repo")
+	if !ok {
+		t.Fatalf("expected cached identifiers")
+	}
+	expected := map[string]interface{}{"metadata.id": "42", "name": "repo"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected %v, got %v", expected, ids)
+	}
+}
+
+func TestSetSearchMiss(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		reason string
+	}{
+		{"collection empty", &restclient.NotFoundError{}, customcondition.ReasonCollectionEmpty},
+		{"no matching item", fmt.Errorf("finding: %w", &restclient.NotFoundError{Searched: 42}), customcondition.ReasonNoMatchingItem},
+		{"resource not found", &httplib.StatusError{StatusCode: http.StatusNotFound}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &handler{}
+			mg := summaryResource()
+			if err := h.setSearchMiss(mg, tt.err); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			conds := unstructuredtools.GetConditions(mg)
+			if tt.reason == "" {
+				if len(conds) != 0 {
+					t.Errorf("expected no condition, got %v", conds)
+				}
+				return
+			}
+			if len(conds) != 1 || conds[0].Type != customcondition.TypeSearchLimitExceeded || conds[0].Status != metav1.ConditionFalse || conds[0].Reason != tt.reason || conds[0].Message == "" {
+				t.Errorf("unexpected conditions: %v", conds)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package restResources
+
+import (
+	"net/http"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/lucasepe/httplib"
+)
+
+// notFoundIfForbidden returns a not found error in place of the 403 answer to a call whose verb takes it as a 404,
+// the error as is otherwise. The answer is recorded as a 404 as well, so that the credentials are not reported as
+// rejected by the API.
+func notFoundIfForbidden(cli *restclient.UnstructuredClient, callInfo *CallInfo, err error) error {
+	if callInfo == nil || !callInfo.ForbiddenAsNotFound || !httplib.HasStatusErr(err, http.StatusForbidden) {
+		return err
+	}
+	cli.ResponseStatus = http.StatusNotFound
+	return &httplib.StatusError{StatusCode: http.StatusNotFound, Inner: err}
+}
@@ -0,0 +1,111 @@
+package restResources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+)
+
+const forbiddenReposOAS = `openapi: 3.0.0
+info:
+  title: repos
+  version: "1.0"
+servers:
+  - url: %s
+paths:
+  /repos/{id}:
+    get:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: ok
+    delete:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        "204":
+          description: deleted
+`
+
+func TestForbiddenAsNotFound(t *testing.T) {
+	for _, forbiddenAsNotFound := range []bool{false, true} {
+		t.Run(fmt.Sprintf("forbiddenAsNotFound=%t", forbiddenAsNotFound), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/openapi.yaml" {
+					fmt.Fprintf(w, forbiddenReposOAS, "http://"+r.Host)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(w, `{"message":"forbidden"}`)
+			}))
+			defer srv.Close()
+
+			mg := observedResource()
+			h, _ := observedHandler(t, getter.Resource{}, mg)
+			h.swaggerInfoGetter = staticInfo{info: &getter.Info{URL: srv.URL + "/openapi.yaml", Resource: getter.Resource{
+				Identifiers: []string{"id"},
+				VerbsDescription: []getter.VerbsDescription{
+					{Action: "get", Method: "GET", Path: "/repos/{id}", ForbiddenAsNotFound: forbiddenAsNotFound},
+					{Action: "delete", Method: "DELETE", Path: "/repos/{id}", ForbiddenAsNotFound: forbiddenAsNotFound},
+				},
+			}}}
+
+			obs, err := h.Observe(context.Background(), mg.DeepCopy())
+			if forbiddenAsNotFound && (err != nil || obs.ResourceExists) {
+				t.Errorf("expected the resource not to exist, got %+v and error %v", obs, err)
+			}
+			if !forbiddenAsNotFound && err == nil {
+				t.Errorf("expected the 403 answer to fail the observation")
+			}
+
+			err = h.Delete(context.Background(), mg.DeepCopy())
+			if forbiddenAsNotFound && err != nil {
+				t.Errorf("expected the resource to be taken as already deleted, got %v", err)
+			}
+			if !forbiddenAsNotFound && err == nil {
+				t.Errorf("expected the 403 answer to fail the deletion")
+			}
+		})
+	}
+}
+
+func TestDeleteNotFoundWithForbiddenAsNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/openapi.yaml" {
+			fmt.Fprintf(w, forbiddenReposOAS, "http://"+r.Host)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"not found"}`)
+	}))
+	defer srv.Close()
+
+	mg := observedResource()
+	h, _ := observedHandler(t, getter.Resource{}, mg)
+	h.swaggerInfoGetter = staticInfo{info: &getter.Info{URL: srv.URL + "/openapi.yaml", Resource: getter.Resource{
+		Identifiers: []string{"id"},
+		VerbsDescription: []getter.VerbsDescription{
+			{Action: "delete", Method: "DELETE", Path: "/repos/{id}", ForbiddenAsNotFound: true},
+		},
+	}}}
+
+	// Only the 403 answers are translated, a 404 answer fails the deletion as without the option
+	if err := h.Delete(context.Background(), mg.DeepCopy()); err == nil {
+		t.Errorf("expected the 404 answer to fail the deletion")
+	}
+}
@@ -0,0 +1,24 @@
+package restResources
+
+import (
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// setObservedGeneration records in the status the generation of the spec the external resource was reconciled with,
+// so that the standard tooling (e.g. kstatus) can tell whether the latest spec was reconciled.
+func setObservedGeneration(mg *unstructured.Unstructured) error {
+	return unstructured.SetNestedField(mg.Object, mg.GetGeneration(), "status", "observedGeneration")
+}
+
+// generationObserved returns true if the current generation of the spec was already reconciled.
+func generationObserved(mg *unstructured.Unstructured) bool {
+	observed, ok, err := unstructured.NestedInt64(mg.Object, "status", "observedGeneration")
+	return err == nil && ok && observed == mg.GetGeneration()
+}
+
+// ignoreDrift returns true if the drift of the external resource is not to be remediated: with the ObserveOnly
+// drift policy the external resource is updated only to apply a new generation of the spec.
+func ignoreDrift(clientInfo *getter.Info, mg *unstructured.Unstructured) bool {
+	return clientInfo.Resource.DriftPolicy == getter.DriftPolicyObserveOnly && generationObserved(mg)
+}
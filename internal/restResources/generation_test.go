@@ -0,0 +1,55 @@
+package restResources
+
+import (
+	"testing"
+
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestObservedGeneration(t *testing.T) {
+	mg := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	mg.SetGeneration(3)
+	if generationObserved(mg) {
+		t.Errorf("expected the generation not to be observed yet")
+	}
+	if err := setObservedGeneration(mg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _, _ := unstructured.NestedInt64(mg.Object, "status", "observedGeneration"); got != 3 {
+		t.Errorf("expected observed generation 3, got %d", got)
+	}
+	if !generationObserved(mg) {
+		t.Errorf("expected the generation to be observed")
+	}
+	mg.SetGeneration(4)
+	if generationObserved(mg) {
+		t.Errorf("expected the new generation not to be observed")
+	}
+}
+
+func TestIgnoreDrift(t *testing.T) {
+	mg := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	mg.SetGeneration(2)
+	if err := setObservedGeneration(mg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		policy     getter.DriftPolicy
+		generation int64
+		expected   bool
+	}{
+		{policy: "", generation: 2, expected: false},
+		{policy: getter.DriftPolicyRemediate, generation: 2, expected: false},
+		{policy: getter.DriftPolicyObserveOnly, generation: 2, expected: true},
+		{policy: getter.DriftPolicyObserveOnly, generation: 3, expected: false},
+	}
+	for _, tt := range tests {
+		mg.SetGeneration(tt.generation)
+		clientInfo := &getter.Info{Resource: getter.Resource{DriftPolicy: tt.policy}}
+		if got := ignoreDrift(clientInfo, mg); got != tt.expected {
+			t.Errorf("policy %q, generation %d: expected %t, got %t", tt.policy, tt.generation, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package restResources
+
+import (
+	"context"
+	"fmt"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+)
+
+type serverKey struct{}
+
+// externalAPIDownError is an error of the external API whose server failed its health probe.
+type externalAPIDownError struct {
+	server  string
+	message string
+	err     error
+}
+
+func (e *externalAPIDownError) Error() string {
+	return fmt.Sprintf("external API server %s is down (%s): %v", e.server, e.message, e.err)
+}
+
+func (e *externalAPIDownError) Unwrap() error {
+	return e.err
+}
+
+// withServer returns the context recording the server of the external API called by the reconcile.
+func withServer(ctx context.Context) (context.Context, *string) {
+	server := new(string)
+	return context.WithValue(ctx, serverKey{}, server), server
+}
+
+// trackServer adds the server of the client to the ones probed for their health,
+// recording it in the context of the reconcile, if any.
+func (h *handler) trackServer(ctx context.Context, cli *restclient.UnstructuredClient, clientInfo *getter.Info) {
+	h.health.Track(cli.Server, clientInfo.Resource.HealthPath)
+	if server, ok := ctx.Value(serverKey{}).(*string); ok && server != nil {
+		*server = cli.Server
+	}
+}
+
+// attributeOutage wraps the error of the external API if its server failed the last health probe,
+// so that the failure is reported as an outage of the provider rather than as a problem of the resource.
+func (h *handler) attributeOutage(server string, err error) error {
+	if err == nil || server == "" || problemConditions(err) == nil {
+		return err
+	}
+	msg, down := h.health.Down(server)
+	if !down {
+		return err
+	}
+	return &externalAPIDownError{server: server, message: msg, err: err}
+}
@@ -0,0 +1,83 @@
+package restResources
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	customcondition "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/condition"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/healthprobe"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"github.com/lucasepe/httplib"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAttributeOutage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	prober := healthprobe.New(logging.NewNopLogger(), 10*time.Millisecond, 0)
+	h := &handler{health: prober}
+	ctx, server := withServer(context.Background())
+	h.trackServer(ctx, &restclient.UnstructuredClient{Server: srv.URL}, &getter.Info{})
+	if *server != srv.URL {
+		t.Fatalf("expected the server to be recorded, got %q", *server)
+	}
+
+	apiErr := &httplib.StatusError{StatusCode: http.StatusServiceUnavailable}
+	if err := h.attributeOutage(*server, apiErr); err != apiErr {
+		t.Fatalf("expected the error of a server not probed yet to be kept, got %v", err)
+	}
+
+	probeCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go prober.Run(probeCtx)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, down := prober.Down(srv.URL); down {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the server to be probed down")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	notAPIErr := errors.New("updating CR")
+	if err := h.attributeOutage(*server, notAPIErr); err != notAPIErr {
+		t.Errorf("expected an error not coming from the API to be kept, got %v", err)
+	}
+
+	err := h.attributeOutage(*server, apiErr)
+	var downErr *externalAPIDownError
+	if !errors.As(err, &downErr) || !errors.Is(err, apiErr) {
+		t.Fatalf("expected the error to be attributed to the outage, got %v", err)
+	}
+	for _, co := range problemConditions(err) {
+		switch co.Type {
+		case customcondition.TypeExternalAPIDown:
+			if co.Status != metav1.ConditionTrue || co.Reason != customcondition.ReasonProbeFailed {
+				t.Errorf("unexpected %s condition: %+v", co.Type, co)
+			}
+		case customcondition.TypeDegraded:
+			if co.Status != metav1.ConditionTrue || co.Reason != customcondition.TypeExternalAPIDown {
+				t.Errorf("expected the degradation to be attributed to the outage: %+v", co)
+			}
+		case customcondition.TypeExternalError:
+			if co.Status != metav1.ConditionTrue {
+				t.Errorf("expected the API error to be reported: %+v", co)
+			}
+		}
+	}
+
+	if err := (&handler{}).attributeOutage(*server, apiErr); err != apiErr {
+		t.Errorf("expected no attribution without prober, got %v", err)
+	}
+}
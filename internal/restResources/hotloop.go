@@ -0,0 +1,15 @@
+package restResources
+
+import (
+	"fmt"
+	"time"
+)
+
+// updateLoopError is returned instead of updating a resource cooling down after a possible update loop was detected.
+type updateLoopError struct {
+	until time.Time
+}
+
+func (e *updateLoopError) Error() string {
+	return fmt.Sprintf("possible update loop detected, updates skipped until %s", e.until.UTC().Format(time.RFC3339))
+}
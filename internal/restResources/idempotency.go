@@ -0,0 +1,131 @@
+package restResources
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/apiaction"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/meta"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/tools"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// AnnotationKeyIdempotencyKey is the key in the annotations map of a resource holding the idempotency key
+// of its creation, kept across the attempts to create the external resource until one succeeds.
+const AnnotationKeyIdempotencyKey = "krateo.io/idempotency-key"
+
+// idempotencyKey returns the idempotency key of the requests of the action for the current generation of the CR,
+// formatted as a UUID derived from its UID: the retries of the same change share the key, while the changes of
+// the next generations get new ones. Empty if the CR has no UID.
+func idempotencyKey(mg *unstructured.Unstructured, action apiaction.APIAction) string {
+	if mg == nil || mg.GetUID() == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%d", mg.GetUID(), action, mg.GetGeneration())))
+	// Custom UUID (version 8)
+	return formatUUID(sum[:16], 0x80)
+}
+
+// updateKeys holds the idempotency keys of the updates which failed, in memory: the retries of a failed update at
+// the same generation reuse its key, in case the update reached the API, while every other update (e.g. correcting
+// a drift at the same generation as the previous successful update) gets a new random key.
+type updateKeys struct {
+	mu      sync.Mutex
+	pending map[types.UID]pendingKey
+}
+
+type pendingKey struct {
+	generation int64
+	key        string
+}
+
+// attempt returns the idempotency key of the update of the resource: the key of its failed update at the same
+// generation, if any, or else a new random one kept until the update succeeds. Empty if the CR has no UID.
+func (k *updateKeys) attempt(mg *unstructured.Unstructured) string {
+	if mg == nil || mg.GetUID() == "" {
+		return ""
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if p, ok := k.pending[mg.GetUID()]; ok && p.generation == mg.GetGeneration() {
+		return p.key
+	}
+	if k.pending == nil {
+		k.pending = map[types.UID]pendingKey{}
+	}
+	key := randomIdempotencyKey()
+	k.pending[mg.GetUID()] = pendingKey{generation: mg.GetGeneration(), key: key}
+	return key
+}
+
+// forget drops the key of the update of the resource, once succeeded or once the resource is deleted.
+func (k *updateKeys) forget(mg *unstructured.Unstructured) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.pending, mg.GetUID())
+}
+
+// randomIdempotencyKey returns a random UUID (version 4).
+func randomIdempotencyKey() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return formatUUID(b, 0x40)
+}
+
+// formatUUID formats the 16 bytes as a UUID of the given version and of the RFC 4122 variant.
+func formatUUID(b []byte, version byte) string {
+	b[6] = b[6]&0x0f | version
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// setIdempotencyKey sets the idempotency key of the call of the verb, if it asks for one.
+func setIdempotencyKey(callInfo *CallInfo, descr getter.VerbsDescription, mg *unstructured.Unstructured, action apiaction.APIAction) {
+	if descr.IdempotencyKeyHeader == "" {
+		return
+	}
+	callInfo.IdempotencyKeyHeader = descr.IdempotencyKeyHeader
+	callInfo.IdempotencyKey = idempotencyKey(mg, action)
+}
+
+// createIdempotencyKey sets the idempotency key of the creation of the resource, if its create verb asks for one:
+// the key of the annotation of the resource, or else a random one stored in the annotation before the external
+// resource is created, so that the retries of a creation which failed after reaching the API (e.g. timed out) share
+// the key, even if the spec changed meanwhile, and the API does not create the external resource twice.
+func (h *handler) createIdempotencyKey(ctx context.Context, mg *unstructured.Unstructured, callInfo *CallInfo) (*unstructured.Unstructured, error) {
+	if callInfo.IdempotencyKeyHeader == "" {
+		return mg, nil
+	}
+	if key := mg.GetAnnotations()[AnnotationKeyIdempotencyKey]; key != "" {
+		callInfo.IdempotencyKey = key
+		return mg, nil
+	}
+
+	key := randomIdempotencyKey()
+	meta.AddAnnotations(mg, map[string]string{AnnotationKeyIdempotencyKey: key})
+	updated, err := tools.Update(ctx, mg, tools.UpdateOptions{
+		Pluralizer:    h.pluralizer,
+		DynamicClient: h.dynamicClient,
+	})
+	if err != nil {
+		return mg, err
+	}
+	callInfo.IdempotencyKey = key
+	return updated, nil
+}
+
+// forgetIdempotencyKey removes the idempotency key of the creation from the annotations of the resource, once
+// created, so that a later creation (e.g. if the external resource is deleted) gets a new one.
+func forgetIdempotencyKey(mg *unstructured.Unstructured) {
+	annotations := mg.GetAnnotations()
+	if _, ok := annotations[AnnotationKeyIdempotencyKey]; !ok {
+		return
+	}
+	delete(annotations, AnnotationKeyIdempotencyKey)
+	mg.SetAnnotations(annotations)
+}
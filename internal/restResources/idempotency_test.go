@@ -0,0 +1,193 @@
+package restResources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"testing"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/apiaction"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	mg := summaryResource()
+	if key := idempotencyKey(mg, apiaction.Update); key != "" {
+		t.Errorf("expected no key without UID, got %s", key)
+	}
+
+	mg.SetUID(types.UID("6f1c2a"))
+	mg.SetGeneration(3)
+	key := idempotencyKey(mg, apiaction.Update)
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-8[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(key) {
+		t.Errorf("expected a UUID, got %s", key)
+	}
+	if idempotencyKey(mg, apiaction.Update) != key {
+		t.Errorf("expected the retries to share the key")
+	}
+	if idempotencyKey(mg, apiaction.Create) == key {
+		t.Errorf("expected the actions to have their own keys")
+	}
+	mg.SetGeneration(4)
+	if idempotencyKey(mg, apiaction.Update) == key {
+		t.Errorf("expected the next generation to have a new key")
+	}
+}
+
+func TestUpdateKeys(t *testing.T) {
+	var keys updateKeys
+	mg := summaryResource()
+	if key := keys.attempt(mg); key != "" {
+		t.Errorf("expected no key without UID, got %s", key)
+	}
+
+	mg.SetUID(types.UID("6f1c2a"))
+	mg.SetGeneration(3)
+	key := keys.attempt(mg)
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(key) {
+		t.Errorf("expected a UUID, got %s", key)
+	}
+	if keys.attempt(mg) != key {
+		t.Errorf("expected the retries of the failed update to share the key")
+	}
+
+	// A drift corrected at the same generation once the update succeeded
+	keys.forget(mg)
+	remediation := keys.attempt(mg)
+	if remediation == "" || remediation == key {
+		t.Errorf("expected a new key for the next update, got %s", remediation)
+	}
+	mg.SetGeneration(4)
+	if keys.attempt(mg) == remediation {
+		t.Errorf("expected the next generation to have a new key")
+	}
+}
+
+const postUpdateOAS = `openapi: 3.0.0
+info:
+  title: repos
+  version: "1.0"
+servers:
+  - url: %s
+paths:
+  /repos/{id}/set:
+    post:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                description:
+                  type: string
+      responses:
+        "200":
+          description: updated
+`
+
+func TestAPICallBuilderPostUpdate(t *testing.T) {
+	var method, path, key string
+	var received map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/openapi.yaml" {
+			fmt.Fprintf(w, postUpdateOAS, "http://"+r.Host)
+			return
+		}
+		method, path, key = r.Method, r.URL.Path, r.Header.Get("Idempotency-Key")
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"42","description":"updated"}`)
+	}))
+	defer srv.Close()
+
+	cli, err := restclient.BuildClient(context.Background(), nil, srv.URL+"/openapi.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mg := summaryResource()
+	mg.SetUID(types.UID("6f1c2a"))
+	mg.SetGeneration(2)
+	cli.SpecFields = mg
+	info := &getter.Info{Resource: getter.Resource{
+		VerbsDescription: []getter.VerbsDescription{
+			{Action: "update", Method: "POST", Path: "/repos/{id}/set", IdempotencyKeyHeader: "Idempotency-Key"},
+		},
+	}}
+
+	apiCall, callInfo, err := APICallBuilder(cli, info, apiaction.Update)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conf, err := BuildCallConfig(callInfo, map[string]interface{}{"id": "42"}, map[string]interface{}{"description": "updated"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := apiCall(context.Background(), srv.Client(), callInfo.Path, conf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if method != http.MethodPost || path != "/repos/42/set" {
+		t.Errorf("expected POST /repos/42/set, got %s %s", method, path)
+	}
+	if key == "" || key != idempotencyKey(mg, apiaction.Update) {
+		t.Errorf("expected the idempotency key of the update, got %q", key)
+	}
+	if expected := map[string]interface{}{"description": "updated"}; !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected body %v, got %v", expected, received)
+	}
+}
+
+func TestCreateIdempotencyKey(t *testing.T) {
+	mg := summaryResource()
+	mg.SetUID(types.UID("6f1c2a"))
+	h, writes := observedHandler(t, getter.Resource{}, mg)
+
+	mg, err := h.createIdempotencyKey(context.Background(), mg, &CallInfo{})
+	if err != nil || writes.spec != 0 {
+		t.Fatalf("expected no key without header, got %d writes (%v)", writes.spec, err)
+	}
+
+	callInfo := &CallInfo{IdempotencyKeyHeader: "Idempotency-Key"}
+	mg, err = h.createIdempotencyKey(context.Background(), mg, callInfo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	key := mg.GetAnnotations()[AnnotationKeyIdempotencyKey]
+	if key == "" || callInfo.IdempotencyKey != key || writes.spec != 1 {
+		t.Fatalf("expected the key to be stored before the creation, got %q (%q) with %d writes", callInfo.IdempotencyKey, key, writes.spec)
+	}
+
+	// A retry after a failed creation, the spec having changed meanwhile
+	mg.SetGeneration(2)
+	retry := &CallInfo{IdempotencyKeyHeader: "Idempotency-Key", IdempotencyKey: idempotencyKey(mg, apiaction.Create)}
+	mg, err = h.createIdempotencyKey(context.Background(), mg, retry)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if retry.IdempotencyKey != key || writes.spec != 1 {
+		t.Errorf("expected the retry to reuse the stored key, got %q with %d writes", retry.IdempotencyKey, writes.spec)
+	}
+	conf, err := BuildCallConfig(retry, nil, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if conf.Headers["Idempotency-Key"] != key {
+		t.Errorf("expected the key in the header, got %v", conf.Headers)
+	}
+
+	forgetIdempotencyKey(mg)
+	if _, ok := mg.GetAnnotations()[AnnotationKeyIdempotencyKey]; ok {
+		t.Errorf("expected the key to be forgotten once created")
+	}
+}
@@ -0,0 +1,178 @@
+package restResources
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/apiaction"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// lateInitFields returns the spec fields eligible for late initialization,
+// that is the body fields of the update action (or of the create one if update is not defined).
+func lateInitFields(cli *restclient.UnstructuredClient, clientInfo *getter.Info) text.StringSet {
+	for _, action := range []apiaction.APIAction{apiaction.Update, apiaction.Create} {
+		apiCall, callInfo, err := APICallBuilder(cli, clientInfo, action)
+		if err != nil || apiCall == nil {
+			continue
+		}
+		return callInfo.ReqParams.Body
+	}
+	return nil
+}
+
+// writeOnlyFields returns the write-only fields of the update and create request bodies,
+// which are never returned by the API and so are not compared.
+func writeOnlyFields(cli *restclient.UnstructuredClient, clientInfo *getter.Info) text.StringSet {
+	fields := text.NewStringSet()
+	for _, action := range []apiaction.APIAction{apiaction.Update, apiaction.Create} {
+		apiCall, callInfo, err := APICallBuilder(cli, clientInfo, action)
+		if err != nil || apiCall == nil || callInfo.RawMethod {
+			continue
+		}
+		writeOnly, err := cli.WriteOnlyFields(callInfo.Method, callInfo.Path)
+		if err != nil {
+			continue
+		}
+		for field := range writeOnly {
+			fields.Add(field)
+		}
+	}
+	return fields
+}
+
+// createOnlyFields returns the fields accepted at creation but rejected on update: the ones listed in the
+// RestDefinition and the ones marked as create-only in the OAS create request body.
+func createOnlyFields(cli *restclient.UnstructuredClient, clientInfo *getter.Info) []string {
+	fields := append([]string{}, clientInfo.Resource.CreateOnlyFields...)
+	apiCall, callInfo, err := APICallBuilder(cli, clientInfo, apiaction.Create)
+	if err == nil && apiCall != nil && !callInfo.RawMethod {
+		createOnly, err := cli.CreateOnlyFields(callInfo.Method, callInfo.Path)
+		if err == nil {
+			for field := range createOnly {
+				if !slices.Contains(fields, field) {
+					fields = append(fields, field)
+				}
+			}
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// uncomparedFields returns the fields not compared to detect drift: the write-only ones, never returned by the API,
+// and the create-only ones, which cannot be updated and so are only informational.
+func uncomparedFields(cli *restclient.UnstructuredClient, clientInfo *getter.Info) text.StringSet {
+	fields := writeOnlyFields(cli, clientInfo)
+	for _, field := range createOnlyFields(cli, clientInfo) {
+		fields.Add(field)
+	}
+	return fields
+}
+
+// lateInitialize writes into the mg spec the remote values of the fields the user left empty,
+// so server generated defaults are not flagged as drift. Returns true if the spec changed.
+func lateInitialize(mg *unstructured.Unstructured, fields text.StringSet, remote map[string]interface{}) (bool, error) {
+	if len(fields) == 0 || remote == nil {
+		return false, nil
+	}
+
+	spec, _, err := unstructured.NestedMap(mg.Object, "spec")
+	if err != nil {
+		return false, err
+	}
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+
+	changed := false
+	for field := range fields {
+		rv, ok := remote[field]
+		if !ok || isEmptyValue(rv) {
+			continue
+		}
+		changed = lateInitializeField(spec, field, rv) || changed
+	}
+	if !changed {
+		return false, nil
+	}
+	return true, unstructured.SetNestedMap(mg.Object, spec, "spec")
+}
+
+// lateInitializeField writes the remote value of the field into the spec if the user left it empty,
+// or the remote values of its nested fields left empty if it is an object. Returns true if the spec changed.
+func lateInitializeField(spec map[string]interface{}, field string, rv interface{}) bool {
+	if isEmptyValue(rv) {
+		return false
+	}
+	sv, ok := spec[field]
+	if !ok || isEmptyValue(sv) {
+		spec[field] = runtime.DeepCopyJSONValue(normalizeJSONValue(rv))
+		return true
+	}
+	sm, sok := sv.(map[string]interface{})
+	rm, rok := rv.(map[string]interface{})
+	if !sok || !rok {
+		return false
+	}
+	changed := false
+	for key, nested := range rm {
+		changed = lateInitializeField(sm, key, nested) || changed
+	}
+	return changed
+}
+
+func isEmptyValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Map, reflect.Slice:
+		return rv.Len() == 0
+	}
+	return false
+}
+
+// normalizeJSONValue converts the values decoded from a JSON response to
+// the types accepted by runtime.DeepCopyJSONValue (e.g. float64 integers to int64).
+func normalizeJSONValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(val))
+		for k, el := range val {
+			res[k] = normalizeJSONValue(el)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(val))
+		for i, el := range val {
+			res[i] = normalizeJSONValue(el)
+		}
+		return res
+	case float64:
+		if val == float64(int64(val)) {
+			return int64(val)
+		}
+		return val
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		// Integers out of the int64 range are kept as json.Number to preserve their digits
+		if strings.ContainsAny(val.String(), ".eE") {
+			if f, err := val.Float64(); err == nil {
+				return f
+			}
+		}
+		return val
+	}
+	return v
+}
@@ -0,0 +1,87 @@
+package restResources
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestNormalizeJSONValue(t *testing.T) {
+	value := map[string]interface{}{
+		"price":    float64(42.5),
+		"quota":    float64(42),
+		"id":       json.Number("1234567890123456789"),
+		"ratio":    json.Number("0.75"),
+		"huge":     json.Number("123456789012345678901234567890"),
+		"metrics":  []interface{}{float64(1.5), json.Number("2")},
+		"disabled": false,
+	}
+	expected := map[string]interface{}{
+		"price":    float64(42.5),
+		"quota":    int64(42),
+		"id":       int64(1234567890123456789),
+		"ratio":    float64(0.75),
+		"huge":     json.Number("123456789012345678901234567890"),
+		"metrics":  []interface{}{float64(1.5), int64(2)},
+		"disabled": false,
+	}
+
+	res := normalizeJSONValue(value)
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %v, got %v", expected, res)
+	}
+	// The normalized value must be accepted by the unstructured helpers
+	runtime.DeepCopyJSONValue(res)
+}
+
+func TestLateInitialize(t *testing.T) {
+	fields := text.NewStringSet()
+	for _, field := range []string{"name", "visibility", "settings", "topics"} {
+		fields.Add(field)
+	}
+	remote := map[string]interface{}{
+		"id":         "42",
+		"name":       "remote-name",
+		"visibility": "private",
+		"settings": map[string]interface{}{
+			"branch":   "main",
+			"archived": false,
+			"merge":    map[string]interface{}{"squash": true, "rebase": false},
+		},
+		"topics": []interface{}{"go"},
+	}
+
+	mg := summaryResource()
+	mg.Object["spec"] = map[string]interface{}{
+		"name": "repo1",
+		"settings": map[string]interface{}{
+			"branch": "",
+			"merge":  map[string]interface{}{"squash": false},
+		},
+	}
+	changed, err := lateInitialize(mg, fields, remote)
+	if err != nil || !changed {
+		t.Fatalf("expected the spec to be late initialized, got %t (%v)", changed, err)
+	}
+	expected := map[string]interface{}{
+		"name":       "repo1",
+		"visibility": "private",
+		"settings": map[string]interface{}{
+			"branch":   "main",
+			"archived": false,
+			"merge":    map[string]interface{}{"squash": false, "rebase": false},
+		},
+		"topics": []interface{}{"go"},
+	}
+	if !reflect.DeepEqual(mg.Object["spec"], expected) {
+		t.Errorf("expected %v, got %v", expected, mg.Object["spec"])
+	}
+
+	changed, err = lateInitialize(mg, fields, remote)
+	if err != nil || changed {
+		t.Errorf("expected the initialized spec not to change, got %t (%v)", changed, err)
+	}
+}
@@ -0,0 +1,51 @@
+package restResources
+
+import (
+	customcondition "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/condition"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/lifecycle"
+	unstructuredtools "github.com/krateoplatformops/unstructured-runtime/pkg/tools/unstructured"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// lifecycleState returns the lifecycle state of the resource, recognized from its Available condition, or from
+// its Ready condition if the resource was not reconciled since the Available condition is set.
+func (h *handler) lifecycleState(mg *unstructured.Unstructured) lifecycle.State {
+	conditions := lifecycle.Conditions
+	if !hasCondition(mg, customcondition.TypeAvailable) {
+		conditions = lifecycle.LegacyConditions
+	}
+	for _, s := range lifecycle.States {
+		for _, cond := range conditions(s) {
+			if h.conditions.IsSet(mg, cond) {
+				return s
+			}
+		}
+	}
+	return lifecycle.Unknown
+}
+
+// hasCondition returns true if the resource has a condition of the type.
+func hasCondition(mg *unstructured.Unstructured, condType string) bool {
+	for _, co := range unstructuredtools.GetConditions(mg) {
+		if co.Type == condType {
+			return true
+		}
+	}
+	return false
+}
+
+// transition moves the resource to the lifecycle state reached on the event, setting the Available condition
+// reporting it with the given reason (the default one of the state if empty) and message.
+// An event not expected in the state of the resource is logged, the resource following the API anyway.
+func (h *handler) transition(mg *unstructured.Unstructured, event lifecycle.Event, reason, message string) (lifecycle.State, error) {
+	from := h.lifecycleState(mg)
+	to, err := lifecycle.Transition(from, event)
+	if err != nil && h.logger != nil {
+		h.objectLogger(mg).Debug("Lifecycle transition", "from", from, "event", event, "to", to, "error", err)
+	}
+	cond, ok := lifecycle.Condition(to, reason, message)
+	if !ok {
+		return to, nil
+	}
+	return to, h.conditions.Set(mg, cond)
+}
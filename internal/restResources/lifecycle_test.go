@@ -0,0 +1,81 @@
+package restResources
+
+import (
+	"net/http"
+	"testing"
+
+	customcondition "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/condition"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/lifecycle"
+	unstructuredtools "github.com/krateoplatformops/unstructured-runtime/pkg/tools/unstructured"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/tools/unstructured/condition"
+	"github.com/lucasepe/httplib"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestLifecycleStateSurvivesReadiness(t *testing.T) {
+	tests := []struct {
+		name       string
+		state      lifecycle.State
+		event      lifecycle.Event
+		degraded   bool
+		generation int64
+		reason     string
+	}{
+		{name: "degraded creating", state: lifecycle.Creating, event: lifecycle.Created, degraded: true, generation: 1, reason: customcondition.TypeRateLimited},
+		{name: "degraded available", state: lifecycle.Available, event: lifecycle.UpToDate, degraded: true, generation: 1, reason: customcondition.TypeRateLimited},
+		{name: "new generation creating", state: lifecycle.Creating, event: lifecycle.Created, generation: 2, reason: customcondition.ReasonNewGeneration},
+		{name: "new generation available", state: lifecycle.Available, event: lifecycle.UpToDate, generation: 2, reason: customcondition.ReasonNewGeneration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &handler{}
+			mg := &unstructured.Unstructured{Object: map[string]interface{}{}}
+			mg.SetGeneration(1)
+			if err := setObservedGeneration(mg); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := h.transition(mg, tt.event, "", ""); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.degraded {
+				for _, cond := range problemConditions(&httplib.StatusError{StatusCode: http.StatusTooManyRequests}) {
+					if err := h.conditions.Set(mg, cond); err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+				}
+			}
+			mg.SetGeneration(tt.generation)
+			if err := h.setReadiness(mg); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, co := range unstructuredtools.GetConditions(mg) {
+				if co.Type == customcondition.TypeReady && (co.Status != metav1.ConditionFalse || co.Reason != tt.reason) {
+					t.Errorf("expected Ready False with reason %s, got %+v", tt.reason, co)
+				}
+			}
+			if got := h.lifecycleState(mg); got != tt.state || !got.Provisioned() {
+				t.Errorf("expected the state %s to survive, got %s", tt.state, got)
+			}
+		})
+	}
+}
+
+func TestLifecycleStateLegacyReady(t *testing.T) {
+	h := &handler{}
+	mg := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	if err := h.conditions.Set(mg, condition.Creating()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := h.lifecycleState(mg); got != lifecycle.Creating {
+		t.Errorf("expected the state to be recognized from the Ready condition, got %s", got)
+	}
+
+	if _, err := h.transition(mg, lifecycle.UpToDate, "", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := h.lifecycleState(mg); got != lifecycle.Available {
+		t.Errorf("expected the state to be recognized from the Available condition, got %s", got)
+	}
+}
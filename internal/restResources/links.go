@@ -0,0 +1,120 @@
+package restResources
+
+import (
+	"encoding/json"
+	"fmt"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/apiaction"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/meta"
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// AnnotationKeyResponseLinks is the key in the annotations map of a resource
+// holding the HAL links (_links) of the observed external resource, by relation.
+const AnnotationKeyResponseLinks = "krateo.io/response-links"
+
+// linkRelations returns the HAL link relations followed by the action, in order of preference.
+func linkRelations(action apiaction.APIAction) []string {
+	if action == apiaction.Delete {
+		return []string{"delete", "self"}
+	}
+	return []string{"self"}
+}
+
+// usesResponseLinks returns true if any verb of the resource follows the response links.
+func usesResponseLinks(info *getter.Info) bool {
+	for _, descr := range info.Resource.VerbsDescription {
+		if descr.UseResponseLinks {
+			return true
+		}
+	}
+	return false
+}
+
+// responseLinks returns the hrefs of the HAL links of the response body, by relation;
+// for relations holding an array of links, the first one is taken.
+func responseLinks(body map[string]interface{}) map[string]string {
+	raw, ok := body["_links"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	links := make(map[string]string, len(raw))
+	for rel, link := range raw {
+		if arr, ok := link.([]interface{}); ok && len(arr) > 0 {
+			link = arr[0]
+		}
+		obj, ok := link.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if href, ok := obj["href"].(string); ok && href != "" {
+			links[rel] = href
+		}
+	}
+	return links
+}
+
+// getResponseLinks returns the HAL links of the observed external resource, if any.
+func getResponseLinks(mg *unstructured.Unstructured) (map[string]string, error) {
+	raw, ok := mg.GetAnnotations()[AnnotationKeyResponseLinks]
+	if !ok || raw == "" {
+		return nil, nil
+	}
+	var links map[string]string
+	if err := json.Unmarshal([]byte(raw), &links); err != nil {
+		return nil, fmt.Errorf("decoding %s annotation: %w", AnnotationKeyResponseLinks, err)
+	}
+	return links, nil
+}
+
+// setResponseLinks stores the HAL links of the response body on the resource, returns true if they changed.
+func setResponseLinks(mg *unstructured.Unstructured, body map[string]interface{}) (bool, error) {
+	links := responseLinks(body)
+	if len(links) == 0 {
+		return false, nil
+	}
+	b, err := json.Marshal(links)
+	if err != nil {
+		return false, fmt.Errorf("encoding %s annotation: %w", AnnotationKeyResponseLinks, err)
+	}
+	if mg.GetAnnotations()[AnnotationKeyResponseLinks] == string(b) {
+		return false, nil
+	}
+	meta.AddAnnotations(mg, map[string]string{AnnotationKeyResponseLinks: string(b)})
+	return true, nil
+}
+
+// followResponseLink sets the URL of the request to the stored link of the action, if the verb
+// follows the response links; returns true if a link was found.
+func followResponseLink(mg *unstructured.Unstructured, callInfo *CallInfo, conf *restclient.RequestConfiguration) bool {
+	if len(callInfo.LinkRelations) == 0 || conf == nil {
+		return false
+	}
+	links, err := getResponseLinks(mg)
+	if err != nil {
+		log.Err(err).Msg("Getting response links")
+		return false
+	}
+	for _, rel := range callInfo.LinkRelations {
+		if href, ok := links[rel]; ok {
+			conf.URL = href
+			return true
+		}
+	}
+	return false
+}
+
+// reachableByLink returns true if the action follows the response links and a link to the resource is stored.
+func reachableByLink(cli *restclient.UnstructuredClient, info *getter.Info, mg *unstructured.Unstructured, action apiaction.APIAction) bool {
+	if !usesResponseLinks(info) {
+		return false
+	}
+	apiCall, callInfo, err := APICallBuilder(cli, info, action)
+	if apiCall == nil || err != nil {
+		return false
+	}
+	return followResponseLink(mg, callInfo, &restclient.RequestConfiguration{})
+}
@@ -0,0 +1,43 @@
+package restResources
+
+import (
+	"testing"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/apiaction"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestFollowResponseLink(t *testing.T) {
+	mg := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	changed, err := setResponseLinks(mg, map[string]interface{}{
+		"name": "order",
+		"_links": map[string]interface{}{
+			"self":   map[string]interface{}{"href": "https://api.example.com/orders/42"},
+			"delete": []interface{}{map[string]interface{}{"href": "/orders/42/cancel"}},
+		},
+	})
+	if err != nil || !changed {
+		t.Fatalf("expected links to be stored, got %v (%v)", changed, err)
+	}
+
+	tests := []struct {
+		action   apiaction.APIAction
+		expected string
+	}{
+		{apiaction.Get, "https://api.example.com/orders/42"},
+		{apiaction.Delete, "/orders/42/cancel"},
+	}
+	for _, tt := range tests {
+		conf := &restclient.RequestConfiguration{}
+		ok := followResponseLink(mg, &CallInfo{LinkRelations: linkRelations(tt.action)}, conf)
+		if !ok || conf.URL != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.action, tt.expected, conf.URL)
+		}
+	}
+
+	conf := &restclient.RequestConfiguration{}
+	if followResponseLink(mg, &CallInfo{}, conf) || conf.URL != "" {
+		t.Errorf("expected links not to be followed, got %q", conf.URL)
+	}
+}
@@ -0,0 +1,39 @@
+package restResources
+
+import (
+	"strings"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// logLevelAnnotation overrides the log level of the controller for a single resource (e.g. debug),
+// so that a resource can be debugged without enabling the debug logs of all the others.
+const logLevelAnnotation = "krateo.io/log-level"
+
+// objectLogger returns the logger of the handler for the resource, honoring the log level of its annotation.
+func (h *handler) objectLogger(mg *unstructured.Unstructured) logging.Logger {
+	switch strings.ToLower(strings.TrimSpace(mg.GetAnnotations()[logLevelAnnotation])) {
+	case "debug":
+		return debugLogger{base: h.logger.WithValues("logLevel", "debug")}
+	default:
+		return h.logger
+	}
+}
+
+// debugLogger logs the debug messages at Info level, so that they are emitted whatever the level of the controller.
+type debugLogger struct {
+	base logging.Logger
+}
+
+func (l debugLogger) Info(msg string, keysAndValues ...any) {
+	l.base.Info(msg, keysAndValues...)
+}
+
+func (l debugLogger) Debug(msg string, keysAndValues ...any) {
+	l.base.Info(msg, keysAndValues...)
+}
+
+func (l debugLogger) WithValues(keysAndValues ...any) logging.Logger {
+	return debugLogger{base: l.base.WithValues(keysAndValues...)}
+}
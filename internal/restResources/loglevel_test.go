@@ -0,0 +1,24 @@
+package restResources
+
+import (
+	"testing"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+)
+
+func TestObjectLogger(t *testing.T) {
+	rec := &infoRecorder{Logger: logging.NewNopLogger()}
+	h := &handler{logger: rec}
+
+	mg := summaryResource()
+	h.objectLogger(mg).Debug("Observing resource")
+	if len(rec.messages) != 0 {
+		t.Errorf("expected the debug messages not to be raised without the annotation")
+	}
+
+	mg.SetAnnotations(map[string]string{logLevelAnnotation: "Debug"})
+	h.objectLogger(mg).WithValues("op", "Observe").Debug("Observing resource")
+	if len(rec.messages) != 1 || rec.messages[0]["msg"] != "Observing resource" {
+		t.Errorf("expected the debug message to be logged at Info level, got %v", rec.messages)
+	}
+}
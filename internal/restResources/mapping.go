@@ -0,0 +1,120 @@
+package restResources
+
+import (
+	"fmt"
+	"strings"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/template"
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// applyFieldMapping populates the request configuration with the CR fields (mappings source) or the ConfigMap values
+// (config source) explicitly mapped to the body fields, or else to the path and query parameters, headers and cookies.
+// The mappings are applied in order, the ones from the status last, so that the identifiers of the existing resource
+// win over the spec for the same target.
+func applyFieldMapping(callInfo *CallInfo, source getter.ValueSource, body bool, statusFields map[string]interface{}, specFields map[string]interface{}, reqConfiguration *restclient.RequestConfiguration, mapBody map[string]interface{}) {
+	if callInfo == nil || len(callInfo.FieldMapping) == 0 {
+		return
+	}
+
+	cr := map[string]interface{}{
+		"spec":   specFields,
+		"status": statusFields,
+	}
+	for _, mapping := range orderedMappings(callInfo.FieldMapping) {
+		if (mapping.ConfigMapKeyRef != nil) != (source == getter.ValueSourceConfig) {
+			continue
+		}
+		if body && mapping.InBody == "" || !body && !mapsToParams(mapping) {
+			continue
+		}
+		if mapping.Condition != "" {
+			include, err := template.EvalBool(mapping.Condition, exprFields(specFields, statusFields))
+			if err != nil {
+				log.Err(err).Msg("Evaluating field mapping condition")
+				continue
+			}
+			if !include {
+				continue
+			}
+		}
+		value, ok := mappedValue(cr, mapping)
+		if !ok {
+			continue
+		}
+		stringVal, err := text.GenericToString(value)
+		if err != nil {
+			stringVal = fmt.Sprintf("%v", value)
+		}
+
+		if body {
+			unstructured.SetNestedField(mapBody, value, strings.Split(mapping.InBody, ".")...)
+			continue
+		}
+		if mapping.InPath != "" {
+			reqConfiguration.Parameters[mapping.InPath] = stringVal
+		}
+		if mapping.InQuery != "" {
+			reqConfiguration.Query[mapping.InQuery] = stringVal
+		}
+		if mapping.InHeader != "" {
+			reqConfiguration.Headers[mapping.InHeader] = stringVal
+		}
+		if mapping.InCookie != "" {
+			reqConfiguration.Cookies[mapping.InCookie] = stringVal
+		}
+	}
+}
+
+// mapsToParams returns true if the mapping populates a path or query parameter, a header or a cookie.
+func mapsToParams(mapping getter.RequestFieldMapping) bool {
+	return mapping.InPath != "" || mapping.InQuery != "" || mapping.InHeader != "" || mapping.InCookie != ""
+}
+
+// orderedMappings returns the mappings in the order they are applied: the ones from the status after the others,
+// each group in the declared order.
+func orderedMappings(mappings []getter.RequestFieldMapping) []getter.RequestFieldMapping {
+	res := make([]getter.RequestFieldMapping, 0, len(mappings))
+	for _, fromStatus := range []bool{false, true} {
+		for _, mapping := range mappings {
+			if mapping.FromStatus == fromStatus {
+				res = append(res, mapping)
+			}
+		}
+	}
+	return res
+}
+
+// mappedValue returns the value resolved from the ConfigMap key of the mapping, or the one of the CR field.
+func mappedValue(cr map[string]interface{}, mapping getter.RequestFieldMapping) (interface{}, bool) {
+	if mapping.ConfigMapKeyRef != nil {
+		return mapping.Value, true
+	}
+	path := mapping.InCustomResource
+	if mapping.FromStatus {
+		path = "status." + statusPath(mapping)
+	}
+	value, ok, err := unstructured.NestedFieldNoCopy(cr, strings.Split(path, ".")...)
+	if err != nil || !ok || value == nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// statusPath returns the dot separated path of the status field a mapping from the status reads the value from:
+// its CR field, relative to the status, or else its target (e.g. id for inBody: id).
+func statusPath(mapping getter.RequestFieldMapping) string {
+	if path := strings.TrimPrefix(mapping.InCustomResource, "status."); path != "" {
+		return path
+	}
+	for _, target := range []string{mapping.InBody, mapping.InPath, mapping.InQuery, mapping.InHeader, mapping.InCookie} {
+		if target != "" {
+			return target
+		}
+	}
+	return ""
+}
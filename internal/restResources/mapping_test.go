@@ -0,0 +1,187 @@
+package restResources
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+)
+
+func TestBuildCallConfigFieldMapping(t *testing.T) {
+	callInfo := &CallInfo{
+		Path: "/projects/{project}/repos",
+		ReqParams: &RequestedParams{
+			Parameters: text.NewStringSet("project"),
+			Query:      text.NewStringSet(),
+			Body:       text.NewStringSet("name"),
+		},
+		FieldMapping: []getter.RequestFieldMapping{
+			{InCustomResource: "spec.projectId", InHeader: "X-Project-Id"},
+			{InCustomResource: "spec.session", InCookie: "session"},
+			{InCustomResource: "spec.settings.private", InBody: "repository.private"},
+			{InCustomResource: "status.id", InQuery: "id"},
+			{InCustomResource: "spec.missing", InHeader: "X-Missing"},
+		},
+	}
+	specFields := map[string]interface{}{
+		"project":   "krateo",
+		"name":      "repo",
+		"projectId": int64(42),
+		"session":   "abc",
+		"settings":  map[string]interface{}{"private": true},
+	}
+	statusFields := map[string]interface{}{"id": "1234"}
+
+	conf, err := BuildCallConfig(callInfo, statusFields, specFields)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Parameters["project"] != "krateo" {
+		t.Errorf("unexpected path parameters: %v", conf.Parameters)
+	}
+	if !reflect.DeepEqual(conf.Headers, map[string]string{"X-Project-Id": "42"}) {
+		t.Errorf("unexpected headers: %v", conf.Headers)
+	}
+	if !reflect.DeepEqual(conf.Cookies, map[string]string{"session": "abc"}) {
+		t.Errorf("unexpected cookies: %v", conf.Cookies)
+	}
+	if conf.Query["id"] != "1234" {
+		t.Errorf("unexpected query: %v", conf.Query)
+	}
+	expected := map[string]interface{}{
+		"name":       "repo",
+		"repository": map[string]interface{}{"private": true},
+	}
+	if !reflect.DeepEqual(conf.Body, expected) {
+		t.Errorf("expected body %v, got %v", expected, conf.Body)
+	}
+}
+
+func TestBuildCallConfigFieldMappingCondition(t *testing.T) {
+	callInfo := &CallInfo{
+		ReqParams: &RequestedParams{
+			Parameters: text.NewStringSet(),
+			Query:      text.NewStringSet(),
+			Body:       text.NewStringSet(),
+		},
+		FieldMapping: []getter.RequestFieldMapping{
+			{InCustomResource: "spec.webhook", InBody: "webhook", Condition: "spec.enabled == true"},
+			{InCustomResource: "spec.name", InBody: "name", Condition: "has(spec.name)"},
+		},
+	}
+
+	conf, err := BuildCallConfig(callInfo, nil, map[string]interface{}{
+		"enabled": false,
+		"webhook": "https://example.com",
+		"name":    "repo",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{"name": "repo"}
+	if !reflect.DeepEqual(conf.Body, expected) {
+		t.Errorf("expected body %v, got %v", expected, conf.Body)
+	}
+}
+
+func TestBuildCallConfigFieldMappingConfigMap(t *testing.T) {
+	callInfo := &CallInfo{
+		Path: "/orgs/{org}/repos",
+		ReqParams: &RequestedParams{
+			Parameters: text.NewStringSet("org"),
+			Query:      text.NewStringSet(),
+			Body:       text.NewStringSet("name"),
+		},
+		FieldMapping: []getter.RequestFieldMapping{
+			{ConfigMapKeyRef: &getter.ConfigMapKeySelector{Name: "env", Key: "org"}, Value: "krateoplatformops", InPath: "org"},
+			{ConfigMapKeyRef: &getter.ConfigMapKeySelector{Name: "env", Key: "team"}, Value: "platform", InBody: "team.slug"},
+		},
+	}
+
+	conf, err := BuildCallConfig(callInfo, nil, map[string]interface{}{"name": "repo"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Parameters["org"] != "krateoplatformops" {
+		t.Errorf("unexpected path parameters: %v", conf.Parameters)
+	}
+	expected := map[string]interface{}{
+		"name": "repo",
+		"team": map[string]interface{}{"slug": "platform"},
+	}
+	if !reflect.DeepEqual(conf.Body, expected) {
+		t.Errorf("expected body %v, got %v", expected, conf.Body)
+	}
+}
+
+func TestBuildCallConfigFieldMappingFromStatus(t *testing.T) {
+	callInfo := &CallInfo{
+		Path: "/repos/{repoId}",
+		ReqParams: &RequestedParams{
+			Parameters: text.NewStringSet("repoId"),
+			Query:      text.NewStringSet(),
+			Body:       text.NewStringSet("id", "name"),
+		},
+		FieldMapping: []getter.RequestFieldMapping{
+			{FromStatus: true, InBody: "id"},
+			{FromStatus: true, InCustomResource: "status.id", InPath: "repoId"},
+			{FromStatus: true, InCustomResource: "etag", InHeader: "If-Match"},
+			{FromStatus: true, InCustomResource: "owner.id", InBody: "owner.id"},
+			// Declared after the mapping from the status, it is still overridden by it
+			{InCustomResource: "spec.legacyId", InBody: "id"},
+		},
+	}
+	specFields := map[string]interface{}{
+		"id":       "from-spec",
+		"legacyId": "legacy",
+		"name":     "repo",
+		"repoId":   "from-spec",
+	}
+
+	tests := []struct {
+		name         string
+		statusFields map[string]interface{}
+		repoID       string
+		headers      map[string]string
+		body         map[string]interface{}
+	}{
+		{
+			name:         "existing resource",
+			statusFields: map[string]interface{}{"id": "42", "owner": map[string]interface{}{"id": int64(7)}},
+			repoID:       "42",
+			headers:      map[string]string{},
+			body: map[string]interface{}{
+				"id":    "42",
+				"name":  "repo",
+				"owner": map[string]interface{}{"id": int64(7)},
+			},
+		},
+		{
+			name:         "status fields not set",
+			statusFields: map[string]interface{}{"etag": `"v1"`},
+			repoID:       "from-spec",
+			headers:      map[string]string{"If-Match": `"v1"`},
+			body:         map[string]interface{}{"id": "legacy", "name": "repo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, err := BuildCallConfig(callInfo, tt.statusFields, specFields)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if conf.Parameters["repoId"] != tt.repoID {
+				t.Errorf("expected repoId %q, got %v", tt.repoID, conf.Parameters)
+			}
+			if !reflect.DeepEqual(conf.Headers, tt.headers) {
+				t.Errorf("expected headers %v, got %v", tt.headers, conf.Headers)
+			}
+			if !reflect.DeepEqual(conf.Body, tt.body) {
+				t.Errorf("expected body %v, got %v", tt.body, conf.Body)
+			}
+		})
+	}
+}
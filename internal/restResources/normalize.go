@@ -0,0 +1,114 @@
+package restResources
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+)
+
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	time.RFC1123Z,
+	time.RFC1123,
+	"2006-01-02",
+}
+
+// normalize converts the value to a canonical representation, so that values differing
+// only in their representation are compared as equal.
+func normalize(normalizer getter.Normalizer, value interface{}) (interface{}, error) {
+	switch normalizer {
+	case getter.NormalizerTimestamp:
+		return normalizeTimestamp(value)
+	case getter.NormalizerDuration:
+		return normalizeDuration(value)
+	case getter.NormalizerURL:
+		return normalizeURL(value)
+	case getter.NormalizerLowercase:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%v is not a string", value)
+		}
+		return strings.ToLower(s), nil
+	}
+	return nil, fmt.Errorf("unknown normalizer: %s", normalizer)
+}
+
+// normalizeTimestamp converts the RFC3339 (and similar) timestamps and the epoch ones,
+// in seconds or milliseconds, to UTC nanoseconds.
+func normalizeTimestamp(value interface{}) (interface{}, error) {
+	if s, ok := value.(string); ok {
+		for _, layout := range timestampLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t.UTC().UnixNano(), nil
+			}
+		}
+	}
+	f, err := epochNumber(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp: %v", value)
+	}
+	// Epochs beyond year 33658 in seconds are assumed to be in milliseconds
+	if f > 1e12 {
+		return time.UnixMilli(int64(f)).UTC().UnixNano(), nil
+	}
+	return time.Unix(0, int64(f*float64(time.Second))).UTC().UnixNano(), nil
+}
+
+// normalizeDuration converts the Go durations (e.g. 1h30m) and the numbers of seconds to nanoseconds.
+func normalizeDuration(value interface{}) (interface{}, error) {
+	if s, ok := value.(string); ok {
+		if d, err := time.ParseDuration(s); err == nil {
+			return int64(d), nil
+		}
+	}
+	f, err := epochNumber(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration: %v", value)
+	}
+	return int64(f * float64(time.Second)), nil
+}
+
+// normalizeURL lowercases the scheme and host of the URL, dropping the default ports and the trailing slash.
+func normalizeURL(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a string", value)
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		host = host + ":" + port
+	}
+	u.Host = host
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// epochNumber returns the numeric value, also when held in a string.
+func epochNumber(value interface{}) (float64, error) {
+	if s, ok := value.(string); ok {
+		return strconv.ParseFloat(s, 64)
+	}
+	f, ok := toBigFloat(value)
+	if !ok {
+		return 0, fmt.Errorf("%v is not a number", value)
+	}
+	res, _ := f.Float64()
+	return res, nil
+}
@@ -0,0 +1,66 @@
+package restResources
+
+import (
+	"encoding/json"
+	"testing"
+
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		normalizer getter.Normalizer
+		a, b       interface{}
+		equal      bool
+	}{
+		{getter.NormalizerTimestamp, "2024-05-01T10:00:00Z", json.Number("1714557600"), true},
+		{getter.NormalizerTimestamp, "2024-05-01T12:00:00+02:00", int64(1714557600000), true},
+		{getter.NormalizerTimestamp, "2024-05-01T10:00:00Z", "2024-05-01 10:00:00", true},
+		{getter.NormalizerTimestamp, "2024-05-01T10:00:00Z", "2024-05-01T10:00:01Z", false},
+		{getter.NormalizerDuration, "1h", json.Number("3600"), true},
+		{getter.NormalizerDuration, "90m", "1h30m", true},
+		{getter.NormalizerDuration, "1h", "3601", false},
+		{getter.NormalizerURL, "https://Example.com:443/repos/", "https://example.com/repos", true},
+		{getter.NormalizerURL, "https://example.com/Repos", "https://example.com/repos", false},
+		{getter.NormalizerLowercase, "Private", "PRIVATE", true},
+	}
+	for _, tt := range tests {
+		a, err := normalize(tt.normalizer, tt.a)
+		if err != nil {
+			t.Fatalf("%s: unexpected error normalizing %v: %v", tt.normalizer, tt.a, err)
+		}
+		b, err := normalize(tt.normalizer, tt.b)
+		if err != nil {
+			t.Fatalf("%s: unexpected error normalizing %v: %v", tt.normalizer, tt.b, err)
+		}
+		if (a == b) != tt.equal {
+			t.Errorf("%s: expected %v and %v equal %v, got %v and %v", tt.normalizer, tt.a, tt.b, tt.equal, a, b)
+		}
+	}
+
+	if _, err := normalize(getter.NormalizerTimestamp, "yesterday"); err == nil {
+		t.Errorf("expected error for invalid timestamp")
+	}
+}
+
+func TestCompareNormalized(t *testing.T) {
+	c := &comparer{normalizers: map[string]getter.Normalizer{
+		"authorizedOn":   getter.NormalizerTimestamp,
+		"settings.ttl":   getter.NormalizerDuration,
+		"hooks.endpoint": getter.NormalizerURL,
+	}}
+	spec := map[string]interface{}{
+		"authorizedOn": "2024-05-01T10:00:00Z",
+		"settings":     map[string]interface{}{"ttl": "1h"},
+		"hooks":        []interface{}{map[string]interface{}{"endpoint": "https://Example.com/hook/"}},
+	}
+	remote := map[string]interface{}{
+		"authorizedOn": json.Number("1714557600"),
+		"settings":     map[string]interface{}{"ttl": json.Number("3600")},
+		"hooks":        []interface{}{map[string]interface{}{"endpoint": "https://example.com/hook"}},
+	}
+	res, err := c.compare(spec, remote, "")
+	if err != nil || !res.IsEqual {
+		t.Errorf("expected equal, got %+v (%v)", res.Reason, err)
+	}
+}
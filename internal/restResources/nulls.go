@@ -0,0 +1,37 @@
+package restResources
+
+import (
+	"strings"
+
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+)
+
+// nullPolicy returns the null policy of the field at the given path, or the default one if not configured.
+func nullPolicy(policies map[string]getter.NullPolicy, def getter.NullPolicy, path ...string) getter.NullPolicy {
+	if policy, ok := policies[strings.Join(path, ".")]; ok {
+		return policy
+	}
+	return def
+}
+
+// dropNulls returns a copy of the fields without the null values whose policy is unset,
+// the fields without a policy get the default one.
+func dropNulls(fields map[string]interface{}, policies map[string]getter.NullPolicy, def getter.NullPolicy, path ...string) map[string]interface{} {
+	if fields == nil {
+		return nil
+	}
+	res := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		currentPath := append(append([]string{}, path...), k)
+		switch val := v.(type) {
+		case nil:
+			if nullPolicy(policies, def, currentPath...) == getter.NullPolicyUnset {
+				continue
+			}
+		case map[string]interface{}:
+			v = dropNulls(val, policies, def, currentPath...)
+		}
+		res[k] = v
+	}
+	return res
+}
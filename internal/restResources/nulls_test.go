@@ -0,0 +1,70 @@
+package restResources
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestBuildCallConfigNullFields(t *testing.T) {
+	callInfo := &CallInfo{
+		ReqParams: &RequestedParams{
+			Parameters: text.NewStringSet(),
+			Query:      text.NewStringSet(),
+			Body:       text.NewStringSet("name", "description", "homepage"),
+		},
+		NullFields: map[string]getter.NullPolicy{"description": getter.NullPolicyUnset},
+	}
+	specFields := map[string]interface{}{
+		"name":        "repo",
+		"description": nil,
+		"homepage":    nil,
+	}
+
+	conf, err := BuildCallConfig(callInfo, nil, specFields)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{"name": "repo", "homepage": nil}
+	if !reflect.DeepEqual(conf.Body, expected) {
+		t.Errorf("expected body %v, got %v", expected, conf.Body)
+	}
+}
+
+func TestIsCRUpdatedNullFields(t *testing.T) {
+	mg := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"name":        "repo",
+			"description": nil,
+			"homepage":    nil,
+		},
+	}}
+
+	tests := []struct {
+		name     string
+		policies map[string]getter.NullPolicy
+		remote   map[string]interface{}
+		expected bool
+	}{
+		{"nulls ignored by default", nil, map[string]interface{}{"name": "repo", "description": "test"}, true},
+		{"null remote ignored by default", nil, map[string]interface{}{"name": "repo", "description": nil}, true},
+		{"null value equal", map[string]getter.NullPolicy{"homepage": getter.NullPolicyValue}, map[string]interface{}{"name": "repo", "homepage": nil}, true},
+		{"null value differs", map[string]getter.NullPolicy{"homepage": getter.NullPolicyValue}, map[string]interface{}{"name": "repo", "homepage": "x"}, false},
+		{"null value missing remotely", map[string]getter.NullPolicy{"homepage": getter.NullPolicyValue}, map[string]interface{}{"name": "repo"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientInfo := &getter.Info{Resource: getter.Resource{NullFields: tt.policies}}
+			res, err := isCRUpdated(clientInfo, mg, tt.remote, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.IsEqual != tt.expected {
+				t.Errorf("expected %v, got %v (%v)", tt.expected, res.IsEqual, res.Reason)
+			}
+		})
+	}
+}
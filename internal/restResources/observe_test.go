@@ -0,0 +1,319 @@
+package restResources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/lifecycle"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/requeue"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/pluralizer"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+const reposOAS = `openapi: 3.0.0
+info:
+  title: repos
+  version: "1.0"
+servers:
+  - url: %s
+paths:
+  /repos/{id}:
+    get:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  id:
+                    type: string
+                  name:
+                    type: string
+                  html_url:
+                    type: string
+`
+
+type staticInfo struct {
+	info *getter.Info
+}
+
+func (g staticInfo) Get(*unstructured.Unstructured) (*getter.Info, error) {
+	return g.info, nil
+}
+
+// writeCounter counts the updates of the resources and of their status.
+type writeCounter struct {
+	spec, status int
+}
+
+func observedHandler(t *testing.T, resource getter.Resource, mg *unstructured.Unstructured) (*handler, *writeCounter) {
+	h, writes, _ := observedHandlerFor(t, reposOAS, resource, mg)
+	return h, writes
+}
+
+// observedHandlerFor returns the handler observing the repos of the given OAS, and the calls made to the repos.
+func observedHandlerFor(t *testing.T, oas string, resource getter.Resource, mg *unstructured.Unstructured) (*handler, *writeCounter, *[]string) {
+	t.Helper()
+	calls := &[]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/openapi.yaml":
+			fmt.Fprintf(w, oas, "http://"+r.Host)
+		case "/plurals":
+			fmt.Fprint(w, `{"plural":"repos","singular":"repo"}`)
+		case "/repos/42/restart":
+			*calls = append(*calls, r.Method+" "+r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		case "/repos/42":
+			*calls = append(*calls, r.Method+" "+r.URL.Path)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"id":"42","name":"repo1","html_url":"https://example.com/repo1"}`)
+		default:
+			*calls = append(*calls, r.Method+" "+r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	plurals := srv.URL + "/plurals"
+	pl := *pluralizer.New(&plurals, srv.Client())
+
+	dyn := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		reposGVR: "RepoList",
+	}, mg.DeepCopy())
+	writes := &writeCounter{}
+	dyn.PrependReactor("update", "repos", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() == "status" {
+			writes.status++
+		} else {
+			writes.spec++
+		}
+		return false, nil, nil
+	})
+
+	log := logging.NewNopLogger()
+	h := &handler{
+		pluralizer:        pl,
+		logger:            log,
+		dynamicClient:     dyn,
+		swaggerInfoGetter: staticInfo{info: &getter.Info{URL: srv.URL + "/openapi.yaml", Resource: resource}},
+		identifiers:       newIdentifierCache(),
+		requeue:           requeue.New(dyn, pl.GVKtoGVR, log),
+		resync:            newResyncGate(0, 0),
+	}
+	return h, writes, calls
+}
+
+func observedResource() *unstructured.Unstructured {
+	mg := summaryResource()
+	mg.SetGeneration(1)
+	_ = unstructured.SetNestedField(mg.Object, "repo1", "spec", "name")
+	_ = unstructured.SetNestedField(mg.Object, "42", "status", "id")
+	return mg
+}
+
+func TestObserveWrites(t *testing.T) {
+	get := getter.VerbsDescription{Action: "get", Method: "GET", Path: "/repos/{id}"}
+	tests := []struct {
+		name     string
+		resource getter.Resource
+		spec     int
+	}{
+		{
+			name:     "status only",
+			resource: getter.Resource{Identifiers: []string{"id"}, VerbsDescription: []getter.VerbsDescription{get}},
+		},
+		{
+			name: "annotations from response",
+			resource: getter.Resource{
+				Identifiers:             []string{"id"},
+				VerbsDescription:        []getter.VerbsDescription{get},
+				AnnotationsFromResponse: map[string]string{"krateo.io/web-url": "html_url"},
+			},
+			spec: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mg := observedResource()
+			h, writes := observedHandler(t, tt.resource, mg)
+
+			obs, err := h.Observe(context.Background(), mg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !obs.ResourceExists || !obs.ResourceUpToDate {
+				t.Fatalf("expected the resource to be up-to-date, got %+v", obs)
+			}
+			if writes.spec != tt.spec || writes.status != 1 {
+				t.Errorf("expected %d spec and 1 status writes, got %d and %d", tt.spec, writes.spec, writes.status)
+			}
+		})
+	}
+}
+
+func TestObserveWritesNotUpToDate(t *testing.T) {
+	mg := observedResource()
+	_ = unstructured.SetNestedField(mg.Object, "renamed", "spec", "name")
+	h, writes := observedHandler(t, getter.Resource{
+		Identifiers:      []string{"id"},
+		VerbsDescription: []getter.VerbsDescription{{Action: "get", Method: "GET", Path: "/repos/{id}"}},
+	}, mg)
+
+	obs, err := h.Observe(context.Background(), mg)
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("expected the resource to be updated, got %v", err)
+	}
+	if !obs.ResourceExists || obs.ResourceUpToDate {
+		t.Fatalf("expected the resource not to be up-to-date, got %+v", obs)
+	}
+	if writes.spec != 0 || writes.status != 1 {
+		t.Errorf("expected 0 spec and 1 status writes, got %d and %d", writes.spec, writes.status)
+	}
+	stored, err := h.dynamicClient.Resource(reposGVR).Namespace(mg.GetNamespace()).Get(context.Background(), mg.GetName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := h.lifecycleState(stored); got != lifecycle.Drifted {
+		t.Errorf("expected the Drifted state to be written with the status, got %s", got)
+	}
+}
+
+const reposHeadOAS = `openapi: 3.0.0
+info:
+  title: repos
+  version: "1.0"
+servers:
+  - url: %s
+paths:
+  /repos/{id}:
+    head:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: ok
+    get:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  id:
+                    type: string
+                  name:
+                    type: string
+`
+
+func TestObserveExists(t *testing.T) {
+	get := getter.VerbsDescription{Action: "get", Method: "GET", Path: "/repos/{id}"}
+	exists := getter.VerbsDescription{Action: "exists", Method: "HEAD", Path: "/repos/{id}"}
+	tests := []struct {
+		name     string
+		verbs    []getter.VerbsDescription
+		id       string
+		calls    []string
+		exists   bool
+		upToDate bool
+	}{
+		{
+			name:     "exists only",
+			verbs:    []getter.VerbsDescription{exists},
+			id:       "42",
+			calls:    []string{"HEAD /repos/42"},
+			exists:   true,
+			upToDate: true,
+		},
+		{
+			name:  "exists only, not found",
+			verbs: []getter.VerbsDescription{exists},
+			id:    "43",
+			calls: []string{"HEAD /repos/43"},
+		},
+		{
+			name:     "exists and get",
+			verbs:    []getter.VerbsDescription{exists, get},
+			id:       "42",
+			calls:    []string{"GET /repos/42"},
+			exists:   true,
+			upToDate: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mg := observedResource()
+			_ = unstructured.SetNestedField(mg.Object, tt.id, "status", "id")
+			h, _, calls := observedHandlerFor(t, reposHeadOAS, getter.Resource{Identifiers: []string{"id"}, VerbsDescription: tt.verbs}, mg)
+
+			obs, err := h.Observe(context.Background(), mg)
+			if err != nil && !apierrors.IsNotFound(err) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if obs.ResourceExists != tt.exists || obs.ResourceUpToDate != tt.upToDate {
+				t.Errorf("expected exists %t and up-to-date %t, got %+v", tt.exists, tt.upToDate, obs)
+			}
+			if fmt.Sprint(*calls) != fmt.Sprint(tt.calls) {
+				t.Errorf("expected the calls %v, got %v", tt.calls, *calls)
+			}
+		})
+	}
+}
+
+func TestObserveAnnotationsFromResponse(t *testing.T) {
+	mg := observedResource()
+	h, writes := observedHandler(t, getter.Resource{
+		Identifiers:             []string{"id"},
+		VerbsDescription:        []getter.VerbsDescription{{Action: "get", Method: "GET", Path: "/repos/{id}"}},
+		AnnotationsFromResponse: map[string]string{"krateo.io/web-url": "html_url"},
+	}, mg)
+
+	if _, err := h.Observe(context.Background(), mg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	latest, err := h.dynamicClient.Resource(reposGVR).Namespace("default").Get(context.Background(), "repo1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if url := latest.GetAnnotations()["krateo.io/web-url"]; url != "https://example.com/repo1" {
+		t.Fatalf("expected the annotation to be persisted, got %q", url)
+	}
+
+	// Observing the same response again does not write the resource
+	if _, err := h.Observe(context.Background(), latest); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if writes.spec != 1 {
+		t.Errorf("expected a single spec write, got %d", writes.spec)
+	}
+}
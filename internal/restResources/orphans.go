@@ -0,0 +1,383 @@
+package restResources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/apiaction"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/audit"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/metrics"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/provenance"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/quota"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// DefaultOrphanScanInterval is the interval the external resources are scanned for orphans at
+const DefaultOrphanScanInterval = time.Hour
+
+// OrphanScanOptions configures the scan of the external resources left behind by the deleted custom resources.
+type OrphanScanOptions struct {
+	DynamicClient dynamic.Interface
+	// SwaggerInfoGetter resolves the RestDefinition and the credentials of the custom resources
+	SwaggerInfoGetter getter.Getter
+	// GVR of the custom resources
+	GVR schema.GroupVersionResource
+	// Namespace of the custom resources, all if empty; the external resources marked by the custom resources of
+	// the other namespaces are ignored
+	Namespace string
+	// ClusterName is the name of the cluster in the ownership markers, the external resources marked by the
+	// custom resources of the other clusters are ignored
+	ClusterName string
+	// Interval is the interval the external resources are scanned at, DefaultOrphanScanInterval if not positive
+	Interval time.Duration
+	// Delete deletes the orphans with the delete verb of their RestDefinition, instead of only reporting them
+	Delete bool
+	// AuditSink records the deletions of the orphans, nil disables the audit
+	AuditSink audit.Sink
+	// Provenance stamps the deletions of the orphans with the provenance headers, nil disables the stamps
+	Provenance *provenance.Stamper
+	// CallBudget limits the external calls made to delete the orphans of each custom resource, nil disables the limit
+	CallBudget *quota.Budget
+	// HTTPClient lists the external resources, defaults to http.DefaultClient
+	HTTPClient *http.Client
+	Logger     logging.Logger
+}
+
+// Orphan is an external resource whose ownership markers name a custom resource which no longer exists.
+type Orphan struct {
+	// Kind of the custom resource
+	Kind string
+	// Namespace and Name of the custom resource in the ownership markers
+	Namespace string
+	Name      string
+	// Identifiers of the external resource, by identifier
+	Identifiers map[string]interface{}
+	// Deleted tells whether the orphan has been deleted
+	Deleted bool
+	// Error is the failure of the deletion, empty if none
+	Error string
+}
+
+// OrphanScanner scans at regular intervals the external resources listed by the findby verb of the custom resources
+// whose RestDefinition has an ownership field, reporting (or deleting) the ones whose ownership markers name a
+// custom resource which no longer exists, e.g. left behind by a deletion which failed. The collections are listed
+// with the spec and the credentials of the existing custom resources, so the orphans of a collection no custom
+// resource lists anymore are not found.
+type OrphanScanner struct {
+	opts    OrphanScanOptions
+	deleted *metrics.Counter
+	// mutations makes the deletions as the handler makes its mutations
+	mutations *handler
+
+	mu      sync.Mutex
+	orphans []Orphan
+}
+
+// NewOrphanScanner returns the scanner of the orphaned external resources.
+func NewOrphanScanner(opts OrphanScanOptions) *OrphanScanner {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultOrphanScanInterval
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	if opts.Logger == nil {
+		opts.Logger = logging.NewNopLogger()
+	}
+	return &OrphanScanner{
+		opts:    opts,
+		deleted: metrics.NewCounter("kind"),
+		mutations: &handler{
+			logger:     opts.Logger,
+			auditSink:  opts.AuditSink,
+			provenance: opts.Provenance,
+			budget:     opts.CallBudget,
+		},
+	}
+}
+
+// Run scans the external resources at every interval until the context is done.
+func (s *OrphanScanner) Run(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Scan(ctx); err != nil {
+				s.opts.Logger.Info("Scanning the external resources for orphans failed.", "error", err.Error())
+			}
+		}
+	}
+}
+
+// Orphans returns the orphans found by the last scan.
+func (s *OrphanScanner) Orphans() []Orphan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Orphan{}, s.orphans...)
+}
+
+// Register registers the orphans found by the last scan and the ones deleted as metrics.
+func (s *OrphanScanner) Register(r *metrics.Registry) {
+	if s == nil {
+		return
+	}
+	r.Func("rest_controller_orphaned_external_resources", "External resources whose ownership markers name a deleted custom resource, as found by the last scan.", metrics.TypeGauge,
+		func() []metrics.Sample {
+			samples := []metrics.Sample{}
+			for _, o := range s.Orphans() {
+				if o.Deleted {
+					continue
+				}
+				samples = append(samples, metrics.Sample{
+					Labels: map[string]string{"kind": o.Kind, "namespace": o.Namespace, "name": o.Name},
+					Value:  1,
+				})
+			}
+			return samples
+		})
+	r.RegisterCounter("rest_controller_orphaned_external_resources_deleted_total", "Orphaned external resources deleted by the scans.", s.deleted)
+}
+
+// Scan lists the external resources and returns the orphans found, deleting them if enabled. The collections which
+// cannot be listed are skipped, the error joining their failures.
+func (s *OrphanScanner) Scan(ctx context.Context) ([]Orphan, error) {
+	if s.opts.SwaggerInfoGetter == nil {
+		return nil, fmt.Errorf("swagger info getter must be specified")
+	}
+	list, err := s.opts.DynamicClient.Resource(s.opts.GVR).Namespace(s.opts.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("listing the custom resources: %w", err)
+	}
+	existing := map[string]bool{}
+	for _, mg := range list.Items {
+		existing[mg.GetNamespace()+"/"+mg.GetName()] = true
+	}
+
+	orphans := []Orphan{}
+	listed := map[string]bool{}
+	found := map[string]bool{}
+	var errs []error
+	for i := range list.Items {
+		template := &list.Items[i]
+		items, collection, err := s.listCollection(ctx, template, listed)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("listing the external resources of %s/%s: %w", template.GetNamespace(), template.GetName(), err))
+			continue
+		}
+		for _, item := range items {
+			namespace, name, ok := s.ownedBy(collection.clientInfo, item)
+			if !ok || existing[namespace+"/"+name] {
+				continue
+			}
+			o := Orphan{
+				Kind:        template.GetKind(),
+				Namespace:   namespace,
+				Name:        name,
+				Identifiers: identifierValues(collection.clientInfo.Resource.Identifiers, item),
+			}
+			key := fmt.Sprintf("%s/%s %v", namespace, name, o.Identifiers)
+			if found[key] {
+				continue
+			}
+			found[key] = true
+			// The custom resource may have been created since it was listed
+			if s.exists(ctx, namespace, name) {
+				continue
+			}
+			if s.opts.Delete {
+				s.deleteOrphan(ctx, collection, template, item, &o)
+			}
+			s.report(o)
+			orphans = append(orphans, o)
+		}
+	}
+
+	s.mu.Lock()
+	s.orphans = orphans
+	s.mu.Unlock()
+	s.opts.Logger.Info("Scanned the external resources for orphans.", "resource", s.opts.GVR.String(), "orphans", len(orphans))
+	return orphans, errors.Join(errs...)
+}
+
+// collection is a collection of external resources listed by the findby verb of a custom resource.
+type collection struct {
+	clientInfo *getter.Info
+	cli        *restclient.UnstructuredClient
+}
+
+// listCollection lists the external resources with the findby verb of the custom resource, nil if the RestDefinition
+// has no ownership field or if the same collection has already been listed by another custom resource.
+func (s *OrphanScanner) listCollection(ctx context.Context, template *unstructured.Unstructured, listed map[string]bool) ([]map[string]interface{}, *collection, error) {
+	clientInfo, err := s.opts.SwaggerInfoGetter.Get(template)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting REST client info: %w", err)
+	}
+	if clientInfo == nil || clientInfo.Resource.OwnershipField == "" || isDataSource(clientInfo) {
+		return nil, nil, nil
+	}
+	cli, err := restclient.BuildClient(ctx, s.opts.DynamicClient, clientInfo.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("building REST client: %w", err)
+	}
+	applyServerURL(cli, clientInfo)
+	cli.SpecFields = template
+	cli.Pagination = clientInfo.Resource.Pagination
+	cli.JSONAPI = clientInfo.Resource.JSONAPI
+
+	apiCall, callInfo, err := APICallBuilder(cli, clientInfo, apiaction.FindBy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("building API call: %w", err)
+	}
+	if apiCall == nil {
+		return nil, nil, nil
+	}
+	specFields, _ := template.Object["spec"].(map[string]interface{})
+	reqConfiguration, err := BuildCallConfig(callInfo, nil, specFields)
+	if err != nil {
+		return nil, nil, fmt.Errorf("building call configuration: %w", err)
+	}
+	// The collections are listed once per set of parameters and credentials
+	key := fmt.Sprintf("%s %s %v %v %v", cli.Server, callInfo.Path, reqConfiguration.Parameters, reqConfiguration.Query, specFields["authenticationRefs"])
+	if listed[key] {
+		return nil, nil, nil
+	}
+	listed[key] = true
+
+	cli.Auth = clientInfo.AuthFor(apiaction.FindBy.String())
+	list, err := cli.ListAll(ctx, s.opts.HTTPClient, callInfo.Path, reqConfiguration)
+	var limitErr *restclient.SearchLimitError
+	if errors.As(err, &limitErr) {
+		s.opts.Logger.Info("Scanning the external resources listed so far for orphans.", "limit", limitErr.Limit, "value", limitErr.Value)
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	rootPath := responseRootPath(clientInfo, apiaction.FindBy)
+	items := make([]map[string]interface{}, 0, len(list))
+	for _, el := range list {
+		item, ok := el.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if rootPath != "" {
+			item = *unwrapBody(item, rootPath)
+		}
+		items = append(items, item)
+	}
+	return items, &collection{clientInfo: clientInfo, cli: cli}, nil
+}
+
+// ownedBy returns the namespace and the name of the custom resource in the ownership markers of the item, false
+// if the item is not marked by a custom resource of the cluster and of the namespace scanned.
+func (s *OrphanScanner) ownedBy(clientInfo *getter.Info, item map[string]interface{}) (string, string, bool) {
+	val, _, _ := unstructured.NestedFieldNoCopy(item, strings.Split(clientInfo.Resource.OwnershipField, ".")...)
+	markers, ok := val.(map[string]interface{})
+	if !ok {
+		return "", "", false
+	}
+	marker := func(key string) string {
+		s, _ := markers[key].(string)
+		return s
+	}
+	namespace, name := marker(OwnerMarkerNamespace), marker(OwnerMarkerName)
+	if namespace == "" || name == "" || marker(OwnerMarkerCluster) != s.opts.ClusterName {
+		return "", "", false
+	}
+	if s.opts.Namespace != "" && namespace != s.opts.Namespace {
+		return "", "", false
+	}
+	return namespace, name, true
+}
+
+// exists returns true if the custom resource exists, or if its existence cannot be told.
+func (s *OrphanScanner) exists(ctx context.Context, namespace, name string) bool {
+	_, err := s.opts.DynamicClient.Resource(s.opts.GVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	return !apierrors.IsNotFound(err)
+}
+
+// deleteOrphan deletes the orphan with the delete verb of its RestDefinition, through a custom resource rebuilt
+// from the listed item: its spec is the one of the custom resource the collection is listed with, overridden by
+// the fields of the item accepted by the create verb, and its status holds the identifiers of the item. The deletion
+// is audited, stamped and counted in the call budget of the custom resource as the mutations of the handler.
+func (s *OrphanScanner) deleteOrphan(ctx context.Context, c *collection, template *unstructured.Unstructured, item map[string]interface{}, o *Orphan) {
+	err := func() error {
+		fields, err := exportedFields(c.cli, c.clientInfo)
+		if err != nil {
+			return err
+		}
+		specFields, _ := template.Object["spec"].(map[string]interface{})
+		mg := exportedManifest(ExportOptions{GVK: template.GroupVersionKind(), Namespace: o.Namespace, Spec: specFields}, fields, item)
+		mg.SetName(o.Name)
+		statusFields := withIdentifiers(map[string]interface{}{}, o.Identifiers)
+		mg.Object["status"] = statusFields
+
+		// A client of its own, counting only the calls of the deletion
+		cli := *c.cli
+		cli.RequestCount = 0
+		cli.SpecFields = mg
+		apiCall, callInfo, err := APICallBuilder(&cli, c.clientInfo, apiaction.Delete)
+		if err != nil {
+			return fmt.Errorf("building API call: %w", err)
+		}
+		if apiCall == nil {
+			return fmt.Errorf("the RestDefinition of %s has no %s verb", o.Kind, apiaction.Delete)
+		}
+		spec, _ := mg.Object["spec"].(map[string]interface{})
+		reqConfiguration, err := BuildCallConfig(callInfo, statusFields, spec)
+		if err != nil {
+			return fmt.Errorf("building call configuration: %w", err)
+		}
+		cli.Auth = c.clientInfo.AuthFor(apiaction.Delete.String())
+
+		ctx = provenance.WithReconcileID(ctx)
+		ctx, sum := s.mutations.startSummary(ctx)
+		trackCalls(ctx, &cli)
+		return s.mutations.withinBudget(sum, mg, func() error {
+			_, err := apiCall(ctx, s.mutations.mutationClient(ctx, mg, apiaction.Delete.String()), callInfo.Path, reqConfiguration)
+			return err
+		})
+	}()
+	if err != nil {
+		o.Error = err.Error()
+		return
+	}
+	o.Deleted = true
+	s.deleted.Inc(o.Kind)
+}
+
+// report logs the orphan, with the outcome of its deletion if enabled.
+func (s *OrphanScanner) report(o Orphan) {
+	ids := make([]string, 0, len(o.Identifiers))
+	for k, v := range o.Identifiers {
+		ids = append(ids, fmt.Sprintf("%s=%v", k, v))
+	}
+	sort.Strings(ids)
+	kv := []interface{}{"kind", o.Kind, "namespace", o.Namespace, "name", o.Name, "identifiers", strings.Join(ids, ",")}
+	switch {
+	case o.Deleted:
+		s.opts.Logger.Info("Orphaned external resource deleted.", kv...)
+	case o.Error != "":
+		s.opts.Logger.Info("Deleting orphaned external resource failed.", append(kv, "error", o.Error)...)
+	default:
+		s.opts.Logger.Info("Orphaned external resource found.", kv...)
+	}
+}
@@ -0,0 +1,166 @@
+package restResources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/provenance"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/quota"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+const orphansOAS = `openapi: 3.0.0
+info:
+  title: repos
+  version: "1.0"
+servers:
+  - url: %s
+paths:
+  /orgs/{org}/repos:
+    get:
+      parameters:
+        - name: org
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: ok
+    post:
+      parameters:
+        - name: org
+          in: path
+          required: true
+          schema:
+            type: string
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                name:
+                  type: string
+      responses:
+        "201":
+          description: created
+  /orgs/{org}/repos/{name}:
+    delete:
+      parameters:
+        - name: org
+          in: path
+          required: true
+          schema:
+            type: string
+        - name: name
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        "204":
+          description: deleted
+`
+
+func TestOrphanScanner(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "github.krateo.io", Version: "v1alpha1", Resource: "repos"}
+	for _, del := range []bool{false, true} {
+		t.Run(fmt.Sprintf("delete=%t", del), func(t *testing.T) {
+			var deleted, clusters []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/openapi.yaml":
+					fmt.Fprintf(w, orphansOAS, "http://"+r.Host)
+				case r.Method == http.MethodDelete:
+					deleted = append(deleted, r.URL.Path)
+					clusters = append(clusters, r.Header.Get(provenance.HeaderCluster))
+					w.WriteHeader(http.StatusNoContent)
+				case r.URL.Path == "/orgs/krateo/repos":
+					w.Header().Set("Content-Type", "application/json")
+					fmt.Fprint(w, `[
+						{"id": 1, "name": "repo1", "tags": {"krateo-cluster": "prod-eu", "krateo-namespace": "default", "krateo-name": "repo1"}},
+						{"id": 2, "name": "old", "tags": {"krateo-cluster": "prod-eu", "krateo-namespace": "default", "krateo-name": "old"}},
+						{"id": 3, "name": "other", "tags": {"krateo-cluster": "prod-us", "krateo-namespace": "default", "krateo-name": "other"}},
+						{"id": 4, "name": "manual"}
+					]`)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer srv.Close()
+
+			mg := &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{"org": "krateo", "name": "repo1"},
+			}}
+			mg.SetGroupVersionKind(schema.GroupVersionKind{Group: gvr.Group, Version: gvr.Version, Kind: "Repo"})
+			mg.SetNamespace("default")
+			mg.SetName("repo1")
+			// A second resource listing the same collection
+			other := mg.DeepCopy()
+			other.SetName("repo2")
+			dyn := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{gvr: "RepoList"}, mg, other)
+
+			budget := quota.New(10, time.Hour)
+			s := NewOrphanScanner(OrphanScanOptions{
+				DynamicClient: dyn,
+				SwaggerInfoGetter: staticInfo{info: &getter.Info{URL: srv.URL + "/openapi.yaml", Resource: getter.Resource{
+					Kind:           "Repo",
+					Identifiers:    []string{"name"},
+					OwnershipField: "tags",
+					VerbsDescription: []getter.VerbsDescription{
+						{Action: "create", Method: "POST", Path: "/orgs/{org}/repos"},
+						{Action: "findby", Method: "GET", Path: "/orgs/{org}/repos"},
+						{Action: "delete", Method: "DELETE", Path: "/orgs/{org}/repos/{name}"},
+					},
+				}}},
+				GVR:         gvr,
+				ClusterName: "prod-eu",
+				Delete:      del,
+				HTTPClient:  srv.Client(),
+				Provenance:  provenance.New("prod-eu", nil),
+				CallBudget:  budget,
+			})
+			orphans, err := s.Scan(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(orphans) != 1 || orphans[0].Name != "old" || orphans[0].Identifiers["name"] != "old" || orphans[0].Kind != "Repo" {
+				t.Fatalf("expected the external resource of the deleted resource to be the only orphan, got %+v", orphans)
+			}
+			if orphans[0].Deleted != del || orphans[0].Error != "" {
+				t.Errorf("unexpected outcome of the deletion: %+v", orphans[0])
+			}
+			if !del && len(deleted) != 0 {
+				t.Errorf("expected the orphans to be only reported, got deletions %v", deleted)
+			}
+			if del && (len(deleted) != 1 || deleted[0] != "/orgs/krateo/repos/old") {
+				t.Errorf("expected the orphan to be deleted, got deletions %v", deleted)
+			}
+			// The deletions are stamped and counted in the call budget as the mutations of the handler
+			orphan := &unstructured.Unstructured{}
+			orphan.SetGroupVersionKind(mg.GroupVersionKind())
+			orphan.SetNamespace("default")
+			orphan.SetName("old")
+			if _, used, _ := budget.Exceeded(objectKey(orphan), 0, time.Now()); used != len(deleted) {
+				t.Errorf("expected %d calls counted in the budget, got %d", len(deleted), used)
+			}
+			for _, cluster := range clusters {
+				if cluster != "prod-eu" {
+					t.Errorf("expected the deletion to be stamped, got cluster %q", cluster)
+				}
+			}
+			if s.deleted.Value("Repo") != float64(len(deleted)) {
+				t.Errorf("expected %d deletions counted, got %v", len(deleted), s.deleted.Value("Repo"))
+			}
+		})
+	}
+}
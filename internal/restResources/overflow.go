@@ -0,0 +1,160 @@
+package restResources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	customcondition "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/condition"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/statusschema"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/tools"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// statusOverflowAnnotation holds the status fields not allowed by the status schema of the CRD,
+// as a JSON object mapping their dot separated path to their value.
+const statusOverflowAnnotation = "krateo.io/status-overflow"
+
+// writeStatus updates the status of the resource, storing the fields not allowed by the status schema of the CRD
+// in the overflow annotation instead, and returns the updated resource with the overflow fields back in its status.
+func (h *handler) writeStatus(ctx context.Context, mg *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	opts := tools.UpdateOptions{
+		Pluralizer:    h.pluralizer,
+		DynamicClient: h.dynamicClient,
+	}
+	sch, err := h.statusSchemaOf(ctx, mg)
+	if err != nil {
+		return nil, err
+	}
+	if sch == nil {
+		return tools.UpdateStatus(ctx, mg, opts)
+	}
+
+	changed, err := h.setStatusOverflow(mg, sch)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		updated, err := tools.Update(ctx, mg, opts)
+		if err != nil {
+			return nil, err
+		}
+		mg = updated
+	}
+
+	status, _, err := unstructured.NestedMap(mg.Object, "status")
+	if err != nil {
+		return nil, err
+	}
+	kept, _ := sch.Split(status)
+	mg = mg.DeepCopy()
+	mg.Object["status"] = kept
+	res, err := tools.UpdateStatus(ctx, mg, opts)
+	if err != nil {
+		return nil, err
+	}
+	restoreStatusOverflow(res)
+	return res, nil
+}
+
+// stageStatusOverflow stores the status fields not allowed by the status schema of the CRD in the overflow annotation,
+// without updating the resource, and returns true if the annotation changed.
+func (h *handler) stageStatusOverflow(ctx context.Context, mg *unstructured.Unstructured) (bool, error) {
+	sch, err := h.statusSchemaOf(ctx, mg)
+	if err != nil || sch == nil {
+		return false, err
+	}
+	return h.setStatusOverflow(mg, sch)
+}
+
+// statusSchemaOf returns the status schema of the resource, nil if every field is accepted.
+func (h *handler) statusSchemaOf(ctx context.Context, mg *unstructured.Unstructured) (*statusschema.Schema, error) {
+	if h.statusSchema == nil {
+		return nil, nil
+	}
+	gvr, err := h.pluralizer.GVKtoGVR(mg.GroupVersionKind())
+	if err != nil {
+		return nil, err
+	}
+	return h.statusSchema.Status(ctx, gvr), nil
+}
+
+// setStatusOverflow sets the StatusOverflow condition and the overflow annotation, returning true if the annotation changed.
+func (h *handler) setStatusOverflow(mg *unstructured.Unstructured, sch *statusschema.Schema) (bool, error) {
+	status, _, err := unstructured.NestedMap(mg.Object, "status")
+	if err != nil {
+		return false, err
+	}
+	_, overflow := sch.Split(status)
+	if len(overflow) > 0 {
+		err = h.conditions.Set(mg, customcondition.StatusOverflow(overflowMessage(overflow)))
+	} else if h.isConditionTrue(mg, customcondition.TypeStatusOverflow) {
+		err = h.conditions.Set(mg, customcondition.NoStatusOverflow())
+	}
+	if err != nil {
+		return false, err
+	}
+	status, _, err = unstructured.NestedMap(mg.Object, "status")
+	if err != nil {
+		return false, err
+	}
+	_, overflow = sch.Split(status)
+
+	annotation := ""
+	if len(overflow) > 0 {
+		data, err := json.Marshal(overflow)
+		if err != nil {
+			return false, err
+		}
+		annotation = string(data)
+	}
+	if mg.GetAnnotations()[statusOverflowAnnotation] == annotation {
+		return false, nil
+	}
+	annotations := mg.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if annotation == "" {
+		delete(annotations, statusOverflowAnnotation)
+	} else {
+		annotations[statusOverflowAnnotation] = annotation
+	}
+	mg.SetAnnotations(annotations)
+	return true, nil
+}
+
+// restoreStatusOverflow copies the status fields stored in the overflow annotation back into the status,
+// so that they are read as any other status field (e.g. the identifiers of the external resource).
+func restoreStatusOverflow(mg *unstructured.Unstructured) {
+	if mg == nil {
+		return
+	}
+	annotation, ok := mg.GetAnnotations()[statusOverflowAnnotation]
+	if !ok || annotation == "" {
+		return
+	}
+	overflow := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(annotation), &overflow); err != nil {
+		return
+	}
+	for path, value := range overflow {
+		fields := append([]string{"status"}, strings.Split(path, ".")...)
+		if _, found, _ := unstructured.NestedFieldNoCopy(mg.Object, fields...); found {
+			// The status written by the API server is more recent
+			continue
+		}
+		_ = unstructured.SetNestedField(mg.Object, value, fields...)
+	}
+}
+
+func overflowMessage(overflow map[string]interface{}) string {
+	paths := make([]string, 0, len(overflow))
+	for path := range overflow {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return fmt.Sprintf("Status fields not allowed by the CRD schema stored in the %s annotation: %s", statusOverflowAnnotation, strings.Join(paths, ", "))
+}
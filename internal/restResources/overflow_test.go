@@ -0,0 +1,36 @@
+package restResources
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestRestoreStatusOverflow(t *testing.T) {
+	mg := summaryResource()
+	mg.SetAnnotations(map[string]string{
+		statusOverflowAnnotation: `{"id":"42","settings.archived":false,"name":"stale"}`,
+	})
+	if err := unstructured.SetNestedField(mg.Object, "repo1", "status", "name"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	restoreStatusOverflow(mg)
+	if id, _, _ := unstructured.NestedString(mg.Object, "status", "id"); id != "42" {
+		t.Errorf("expected the id to be restored, got %q", id)
+	}
+	if archived, found, _ := unstructured.NestedBool(mg.Object, "status", "settings", "archived"); !found || archived {
+		t.Errorf("expected the nested field to be restored")
+	}
+	if name, _, _ := unstructured.NestedString(mg.Object, "status", "name"); name != "repo1" {
+		t.Errorf("expected the status written by the API server to be kept, got %q", name)
+	}
+}
+
+func TestOverflowMessage(t *testing.T) {
+	got := overflowMessage(map[string]interface{}{"settings.archived": false, "id": "42"})
+	want := "Status fields not allowed by the CRD schema stored in the krateo.io/status-overflow annotation: id, settings.archived"
+	if got != want {
+		t.Errorf("unexpected message: %s", got)
+	}
+}
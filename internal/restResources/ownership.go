@@ -0,0 +1,77 @@
+package restResources
+
+import (
+	"strings"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// The keys of the ownership markers written on the external resources, valid as the tag or label keys of most APIs.
+const (
+	OwnerMarkerCluster   = "krateo-cluster"
+	OwnerMarkerNamespace = "krateo-namespace"
+	OwnerMarkerName      = "krateo-name"
+)
+
+// owner returns where the ownership markers of the CR are written on the external resource, nil if the
+// RestDefinition has no ownership field.
+func (h *handler) owner(clientInfo *getter.Info, mg *unstructured.Unstructured) *restclient.Owner {
+	if clientInfo == nil || clientInfo.Resource.OwnershipField == "" {
+		return nil
+	}
+	markers := map[string]string{
+		OwnerMarkerNamespace: mg.GetNamespace(),
+		OwnerMarkerName:      mg.GetName(),
+	}
+	if h.clusterName != "" {
+		markers[OwnerMarkerCluster] = h.clusterName
+	}
+	return &restclient.Owner{Field: clientInfo.Resource.OwnershipField, Markers: markers}
+}
+
+// markOwnership writes the ownership markers in the ownership field of the request body, merged with the tags
+// or labels set by the CR. The body is copied along the field, so that the applied body taken beforehand is left
+// without the markers and the three-way comparison never tells them removed from the spec.
+func markOwnership(reqConfiguration *restclient.RequestConfiguration, callInfo *CallInfo, owner *restclient.Owner) {
+	if owner == nil {
+		return
+	}
+	body, ok := reqConfiguration.Body.(map[string]interface{})
+	if !ok {
+		return
+	}
+	path := strings.Split(owner.Field, ".")
+	if callInfo.BodyRootPath != "" && callInfo.BodyTemplate == nil {
+		path = append(strings.Split(callInfo.BodyRootPath, "."), path...)
+	}
+	reqConfiguration.Body = withMarkers(body, path, owner.Markers)
+}
+
+// withMarkers returns a copy of the object with the markers merged in the object field at the path;
+// the object itself if the field is set to something else than an object.
+func withMarkers(obj map[string]interface{}, path []string, markers map[string]string) map[string]interface{} {
+	res := make(map[string]interface{}, len(obj)+1)
+	for k, v := range obj {
+		res[k] = v
+	}
+	child, ok := obj[path[0]].(map[string]interface{})
+	if !ok && obj[path[0]] != nil {
+		return obj
+	}
+	if len(path) > 1 {
+		res[path[0]] = withMarkers(child, path[1:], markers)
+		return res
+	}
+
+	merged := make(map[string]interface{}, len(child)+len(markers))
+	for k, v := range child {
+		merged[k] = v
+	}
+	for k, v := range markers {
+		merged[k] = v
+	}
+	res[path[0]] = merged
+	return res
+}
@@ -0,0 +1,55 @@
+package restResources
+
+import (
+	"reflect"
+	"testing"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestMarkOwnership(t *testing.T) {
+	mg := &unstructured.Unstructured{}
+	mg.SetNamespace("team-a")
+	mg.SetName("repo1")
+	info := &getter.Info{Resource: getter.Resource{OwnershipField: "metadata.labels"}}
+
+	h := &handler{clusterName: "prod-eu"}
+	owner := h.owner(info, mg)
+	expected := map[string]string{OwnerMarkerCluster: "prod-eu", OwnerMarkerNamespace: "team-a", OwnerMarkerName: "repo1"}
+	if owner == nil || owner.Field != "metadata.labels" || !reflect.DeepEqual(owner.Markers, expected) {
+		t.Fatalf("unexpected owner: %+v", owner)
+	}
+	if (&handler{}).owner(info, mg).Markers[OwnerMarkerCluster] != "" {
+		t.Errorf("expected no cluster marker without cluster name")
+	}
+	if h.owner(&getter.Info{}, mg) != nil {
+		t.Errorf("expected no owner without ownership field")
+	}
+
+	callInfo := &CallInfo{BodyRootPath: "repo"}
+	reqConfiguration := &restclient.RequestConfiguration{Body: map[string]interface{}{
+		"repo": map[string]interface{}{
+			"name":     "repo1",
+			"metadata": map[string]interface{}{"labels": map[string]interface{}{"env": "prod"}},
+		},
+	}}
+	applied := appliedBody(callInfo, reqConfiguration, map[string]interface{}{"name": "repo1", "metadata": map[string]interface{}{}})
+	markOwnership(reqConfiguration, callInfo, owner)
+
+	labels, _, _ := unstructured.NestedMap(reqConfiguration.Body.(map[string]interface{}), "repo", "metadata", "labels")
+	if labels["env"] != "prod" || labels[OwnerMarkerNamespace] != "team-a" || labels[OwnerMarkerCluster] != "prod-eu" {
+		t.Errorf("expected the markers merged with the labels, got %v", labels)
+	}
+	appliedLabels, _, _ := unstructured.NestedMap(applied.(map[string]interface{}), "metadata", "labels")
+	if !reflect.DeepEqual(appliedLabels, map[string]interface{}{"env": "prod"}) {
+		t.Errorf("expected the applied body without the markers, got %v", appliedLabels)
+	}
+
+	unsupported := &restclient.RequestConfiguration{Body: map[string]interface{}{"metadata": "none"}}
+	markOwnership(unsupported, &CallInfo{}, owner)
+	if unsupported.Body.(map[string]interface{})["metadata"] != "none" {
+		t.Errorf("expected a field which is not an object to be left as is, got %v", unsupported.Body)
+	}
+}
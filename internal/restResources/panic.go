@@ -0,0 +1,56 @@
+package restResources
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/metrics"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// panics counts the panics recovered reconciling the resources, by operation and kind.
+var panics = metrics.NewCounter("operation", "kind")
+
+// RegisterPanicMetrics registers the panics recovered reconciling the resources.
+func RegisterPanicMetrics(r *metrics.Registry) {
+	r.RegisterCounter("rest_controller_panics_total",
+		"Panics recovered reconciling the resources, by operation (observe, create, update, delete) and kind.", panics)
+}
+
+// PanicError is the error a panic recovered reconciling a resource is converted to.
+type PanicError struct {
+	// Value: the value the reconcile panicked with
+	Value interface{}
+	// Stack: the stack trace of the goroutine that panicked
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// recoverPanic recovers the panic of the operation, if any, converting it into the error returned by the operation
+// instead of crashing the controller: the stack is logged, the panic counted and reported by the InternalError
+// condition of the resource. It must be deferred by the operation.
+func (h *handler) recoverPanic(ctx context.Context, mg *unstructured.Unstructured, op string, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	panicErr := &PanicError{Value: r, Stack: debug.Stack()}
+	*err = panicErr
+	panics.Inc(op, mg.GetKind())
+	if h.logger != nil {
+		h.logger.Info("Recovered from panic", "op", op, "kind", mg.GetKind(), "name", mg.GetName(), "namespace", mg.GetNamespace(),
+			"panic", fmt.Sprint(r), "stack", string(panicErr.Stack))
+	}
+
+	// Reporting the panic must not panic in turn
+	defer func() {
+		if r := recover(); r != nil && h.logger != nil {
+			h.logger.Info("Reporting recovered panic", "op", op, "name", mg.GetName(), "namespace", mg.GetNamespace(), "panic", fmt.Sprint(r))
+		}
+	}()
+	h.updateProblemConditions(ctx, mg, panicErr)
+}
@@ -0,0 +1,50 @@
+package restResources
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	customcondition "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/condition"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	unstructuredtools "github.com/krateoplatformops/unstructured-runtime/pkg/tools/unstructured"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type panickingGetter struct{}
+
+func (panickingGetter) Get(un *unstructured.Unstructured) (*getter.Info, error) {
+	var info map[string]*getter.Info
+	info["repo"].URL = "https://api.github.com"
+	return nil, nil
+}
+
+func TestRecoverPanic(t *testing.T) {
+	h, dyn, _ := conflictingHandler(t, 0)
+	h.swaggerInfoGetter = panickingGetter{}
+	before := panics.Value("create", "Repo")
+
+	err := h.Create(context.Background(), summaryResource())
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || len(panicErr.Stack) == 0 {
+		t.Fatalf("expected the panic to be recovered, got %v", err)
+	}
+	if n := panics.Value("create", "Repo"); n != before+1 {
+		t.Errorf("expected the panic to be counted, got %v", n)
+	}
+
+	latest, err := dyn.Resource(reposGVR).Namespace("default").Get(context.Background(), "repo1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	found := false
+	for _, co := range unstructuredtools.GetConditions(latest) {
+		if co.Type == customcondition.TypeInternalError {
+			found = co.Status == metav1.ConditionTrue && co.Reason == customcondition.ReasonPanic
+		}
+	}
+	if !found {
+		t.Errorf("expected the InternalError condition, got %v", unstructuredtools.GetConditions(latest))
+	}
+}
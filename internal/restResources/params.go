@@ -0,0 +1,97 @@
+package restResources
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+)
+
+// missingParamsError reports the path parameters of a call not provided by the CR fields nor by the field mappings.
+type missingParamsError struct {
+	method string
+	path   string
+	// params maps each missing parameter to the places it was looked up in
+	params map[string][]string
+}
+
+func (e *missingParamsError) Error() string {
+	names := make([]string, 0, len(e.params))
+	for name := range e.params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	missing := make([]string, 0, len(names))
+	for _, name := range names {
+		missing = append(missing, fmt.Sprintf("%s (looked up in %s)", name, strings.Join(e.params[name], ", ")))
+	}
+	call := strings.TrimSpace(e.method + " " + e.path)
+	return fmt.Sprintf("missing required path parameters of %s: %s", call, strings.Join(missing, "; "))
+}
+
+// checkPathParams returns a missingParamsError if a required path parameter of the call is not set or empty.
+func checkPathParams(callInfo *CallInfo, reqConfiguration *restclient.RequestConfiguration, statusFields, specFields map[string]interface{}) error {
+	if callInfo.ReqParams == nil {
+		return nil
+	}
+	optional := text.NewStringSet(restclient.OptionalPathParams(callInfo.Path)...)
+	var err *missingParamsError
+	for param := range callInfo.ReqParams.Parameters {
+		if param == "" || optional.Contains(param) || isSet(reqConfiguration.Parameters[param]) {
+			continue
+		}
+		if err == nil {
+			err = &missingParamsError{method: callInfo.Method, path: callInfo.Path, params: map[string][]string{}}
+		}
+		err.params[param] = paramSources(callInfo, param, statusFields, specFields)
+	}
+	if err == nil {
+		return nil
+	}
+	return err
+}
+
+func isSet(value string) bool {
+	return value != "" && value != "<nil>"
+}
+
+// paramSources describes where the value of the path parameter was looked up, and why it was not found there.
+func paramSources(callInfo *CallInfo, param string, statusFields, specFields map[string]interface{}) []string {
+	sources := []string{
+		fieldSource("spec", param, specFields),
+		fieldSource("status", param, statusFields),
+	}
+	for _, mapping := range callInfo.FieldMapping {
+		if mapping.InPath != param {
+			continue
+		}
+		var source string
+		if ref := mapping.ConfigMapKeyRef; ref != nil {
+			source = fmt.Sprintf("configmap %s key %s", ref.Name, ref.Key)
+		} else if mapping.FromStatus {
+			source = "status." + statusPath(mapping)
+		} else {
+			source = mapping.InCustomResource
+		}
+		if mapping.Condition != "" {
+			source = fmt.Sprintf("%s when %s", source, mapping.Condition)
+		}
+		sources = append(sources, "field mapping from "+source)
+	}
+	return sources
+}
+
+func fieldSource(prefix, param string, fields map[string]interface{}) string {
+	source := prefix + "." + param
+	value, ok := fields[param]
+	if !ok {
+		return source + " (not set)"
+	}
+	if value == nil || value == "" {
+		return source + " (empty)"
+	}
+	return source
+}
@@ -0,0 +1,61 @@
+package restResources
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+)
+
+func TestBuildCallConfigMissingPathParams(t *testing.T) {
+	callInfo := &CallInfo{
+		Method: "DELETE",
+		Path:   "/orgs/{org}/repos/{id}",
+		ReqParams: &RequestedParams{
+			Parameters: text.NewStringSet("org", "id"),
+			Query:      text.NewStringSet(),
+			Body:       text.NewStringSet(),
+		},
+		FieldMapping: []getter.RequestFieldMapping{
+			{InCustomResource: "status.remote.id", InPath: "id"},
+		},
+	}
+
+	_, err := BuildCallConfig(callInfo, map[string]interface{}{"id": ""}, map[string]interface{}{"org": "krateo"})
+	var missing *missingParamsError
+	if !errors.As(err, &missing) {
+		t.Fatalf("expected a missing params error, got %v", err)
+	}
+	expected := "missing required path parameters of DELETE /orgs/{org}/repos/{id}: " +
+		"id (looked up in spec.id (not set), status.id (empty), field mapping from status.remote.id)"
+	if err.Error() != expected {
+		t.Errorf("expected error %q, got %q", expected, err.Error())
+	}
+
+	conf, err := BuildCallConfig(callInfo, map[string]interface{}{
+		"remote": map[string]interface{}{"id": int64(42)},
+	}, map[string]interface{}{"org": "krateo"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if conf.Parameters["id"] != "42" {
+		t.Errorf("expected the id to be mapped from the status, got %v", conf.Parameters)
+	}
+}
+
+func TestBuildCallConfigResponseLinks(t *testing.T) {
+	callInfo := &CallInfo{
+		Path: "/repos/{id}",
+		ReqParams: &RequestedParams{
+			Parameters: text.NewStringSet("id"),
+			Query:      text.NewStringSet(),
+			Body:       text.NewStringSet(),
+		},
+		LinkRelations: []string{"self"},
+	}
+
+	if _, err := BuildCallConfig(callInfo, nil, nil); err != nil {
+		t.Errorf("expected the calls following the links not to require the path parameters, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package restResources
+
+import (
+	"fmt"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+)
+
+var (
+	// defaultParamSources are the sources of the parameters, from the highest precedence: the identifiers
+	// of the existing resource in the status win over the spec
+	defaultParamSources = []getter.ValueSource{getter.ValueSourceMappings, getter.ValueSourceConfig, getter.ValueSourceStatus, getter.ValueSourceSpec}
+	// defaultBodySources are the sources of the body fields, from the highest precedence: the desired state
+	// in the spec wins over the observed one in the status
+	defaultBodySources = []getter.ValueSource{getter.ValueSourceMappings, getter.ValueSourceConfig, getter.ValueSourceSpec, getter.ValueSourceStatus}
+)
+
+// valueSources returns the sources of the parameters and of the body fields, from the highest precedence.
+func valueSources(precedence *getter.Precedence) (params, body []getter.ValueSource, err error) {
+	if precedence == nil {
+		return defaultParamSources, defaultBodySources, nil
+	}
+	params, err = orderedSources(precedence.Parameters, defaultParamSources)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid precedence of the parameters: %w", err)
+	}
+	body, err = orderedSources(precedence.Body, defaultBodySources)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid precedence of the body: %w", err)
+	}
+	return params, body, nil
+}
+
+// orderedSources returns the configured sources followed by the omitted ones, in the default order.
+func orderedSources(configured, defaults []getter.ValueSource) ([]getter.ValueSource, error) {
+	res := make([]getter.ValueSource, 0, len(defaults))
+	seen := map[getter.ValueSource]bool{}
+	for _, source := range configured {
+		switch source {
+		case getter.ValueSourceMappings, getter.ValueSourceConfig, getter.ValueSourceStatus, getter.ValueSourceSpec:
+		default:
+			return nil, fmt.Errorf("unknown source %q", source)
+		}
+		if seen[source] {
+			return nil, fmt.Errorf("source %q listed more than once", source)
+		}
+		seen[source] = true
+		res = append(res, source)
+	}
+	for _, source := range defaults {
+		if !seen[source] {
+			res = append(res, source)
+		}
+	}
+	return res, nil
+}
+
+// applySource populates the body fields, or else the parameters, headers and cookies, with the values of the source.
+func applySource(callInfo *CallInfo, source getter.ValueSource, body bool, statusFields, specFields map[string]interface{}, reqConfiguration *restclient.RequestConfiguration, mapBody map[string]interface{}) {
+	switch source {
+	case getter.ValueSourceSpec, getter.ValueSourceStatus:
+		fields := specFields
+		if source == getter.ValueSourceStatus {
+			fields = statusFields
+		}
+		if body {
+			processBody(callInfo, fields, mapBody)
+		} else {
+			processParams(callInfo, fields, reqConfiguration)
+		}
+	case getter.ValueSourceMappings, getter.ValueSourceConfig:
+		applyFieldMapping(callInfo, source, body, statusFields, specFields, reqConfiguration, mapBody)
+	}
+}
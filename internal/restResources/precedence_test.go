@@ -0,0 +1,101 @@
+package restResources
+
+import (
+	"testing"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+)
+
+func TestBuildCallConfigPrecedence(t *testing.T) {
+	specFields := map[string]interface{}{
+		"repo":  "spec-repo",
+		"name":  "spec-name",
+		"alias": "mapped",
+	}
+	statusFields := map[string]interface{}{
+		"repo": "status-repo",
+		"name": "status-name",
+	}
+
+	tests := []struct {
+		name       string
+		precedence *getter.Precedence
+		repo       string
+		body       string
+		err        bool
+	}{
+		{name: "default", repo: "mapped", body: "mapped"},
+		{
+			name:       "spec first",
+			precedence: &getter.Precedence{Parameters: []getter.ValueSource{getter.ValueSourceSpec}},
+			repo:       "spec-repo",
+			body:       "mapped",
+		},
+		{
+			name:       "config over status",
+			precedence: &getter.Precedence{Parameters: []getter.ValueSource{getter.ValueSourceConfig, getter.ValueSourceStatus}},
+			repo:       "config",
+			body:       "mapped",
+		},
+		{
+			name:       "status first in body",
+			precedence: &getter.Precedence{Body: []getter.ValueSource{getter.ValueSourceStatus}},
+			repo:       "mapped",
+			body:       "status-name",
+		},
+		{
+			name: "mappings last",
+			precedence: &getter.Precedence{
+				Parameters: []getter.ValueSource{getter.ValueSourceStatus, getter.ValueSourceSpec},
+				Body:       []getter.ValueSource{getter.ValueSourceSpec, getter.ValueSourceStatus},
+			},
+			repo: "status-repo",
+			body: "spec-name",
+		},
+		{
+			name:       "unknown source",
+			precedence: &getter.Precedence{Parameters: []getter.ValueSource{"cluster"}},
+			err:        true,
+		},
+		{
+			name:       "duplicated source",
+			precedence: &getter.Precedence{Body: []getter.ValueSource{getter.ValueSourceSpec, getter.ValueSourceSpec}},
+			err:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callInfo := &CallInfo{
+				Path: "/repos/{repo}",
+				ReqParams: &RequestedParams{
+					Parameters: text.NewStringSet("repo"),
+					Query:      text.NewStringSet(),
+					Body:       text.NewStringSet("name"),
+				},
+				FieldMapping: []getter.RequestFieldMapping{
+					{ConfigMapKeyRef: &getter.ConfigMapKeySelector{Name: "env", Key: "repo"}, Value: "config", InPath: "repo"},
+					{InCustomResource: "spec.alias", InPath: "repo"},
+					{InCustomResource: "spec.alias", InBody: "name"},
+				},
+				Precedence: tt.precedence,
+			}
+			conf, err := BuildCallConfig(callInfo, statusFields, specFields)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", conf)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if conf.Parameters["repo"] != tt.repo {
+				t.Errorf("expected repo %q, got %q", tt.repo, conf.Parameters["repo"])
+			}
+			if body, _ := conf.Body.(map[string]interface{}); body["name"] != tt.body {
+				t.Errorf("expected name %q, got %v", tt.body, conf.Body)
+			}
+		})
+	}
+}
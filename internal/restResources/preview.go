@@ -0,0 +1,187 @@
+package restResources
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/meta"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/tools"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// AnnotationKeyLastAppliedBody is the key in the annotations map of a resource
+	// holding the fingerprint of the last request body successfully sent to the external API.
+	AnnotationKeyLastAppliedBody = "krateo.io/last-applied-body"
+
+	// AnnotationKeyStoreUpdatePreview is the key in the annotations map of a resource
+	// that, when set to `true`, makes the controller store the update preview
+	// in the AnnotationKeyUpdatePreview annotation.
+	AnnotationKeyStoreUpdatePreview = "krateo.io/store-update-preview"
+
+	// AnnotationKeyUpdatePreview is the key in the annotations map of a resource
+	// holding the differences between the last applied body and the body of the last update.
+	AnnotationKeyUpdatePreview = "krateo.io/update-preview"
+)
+
+// fingerprintPrefix marks the values of the fingerprint of a body.
+const fingerprintPrefix = "sha256:"
+
+// UpdatePreview describes the body the controller is about to send
+// compared to the last successfully sent one.
+type UpdatePreview struct {
+	Size    int      `json:"size"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+}
+
+func (p UpdatePreview) String() string {
+	return fmt.Sprintf("size: %d, added: %v, removed: %v, changed: %v",
+		p.Size, p.Added, p.Removed, p.Changed)
+}
+
+// getLastAppliedBody returns the fingerprint of the last request body successfully sent for the resource, if any.
+func getLastAppliedBody(mg *unstructured.Unstructured) (map[string]interface{}, error) {
+	raw, ok := mg.GetAnnotations()[AnnotationKeyLastAppliedBody]
+	if !ok || raw == "" {
+		return nil, nil
+	}
+	var body map[string]interface{}
+	if err := unmarshalJSON([]byte(raw), &body); err != nil {
+		return nil, fmt.Errorf("decoding %s annotation: %w", AnnotationKeyLastAppliedBody, err)
+	}
+	return body, nil
+}
+
+// unmarshalJSON decodes the JSON data into v, decoding the numbers as json.Number to preserve their precision.
+func unmarshalJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// fingerprintBody returns the body with its values replaced by their hashes: the fields stay nested as in the
+// body, so that the added, removed and changed ones can still be told apart, but the values (e.g. passwords
+// sent as write-only fields) are never stored in plaintext on the resource.
+func fingerprintBody(body interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := unmarshalJSON(b, &m); err != nil {
+		return nil, err
+	}
+	return fingerprintFields(m)
+}
+
+func fingerprintFields(fields map[string]interface{}) (map[string]interface{}, error) {
+	res := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if nested, ok := value.(map[string]interface{}); ok {
+			fingerprint, err := fingerprintFields(nested)
+			if err != nil {
+				return nil, err
+			}
+			res[key] = fingerprint
+			continue
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(b)
+		res[key] = fingerprintPrefix + hex.EncodeToString(sum[:8])
+	}
+	return res, nil
+}
+
+// setLastAppliedBody stores the fingerprint of the request body successfully sent for the resource.
+func setLastAppliedBody(mg *unstructured.Unstructured, body interface{}) error {
+	fingerprint, err := fingerprintBody(body)
+	if err != nil {
+		return fmt.Errorf("encoding %s annotation: %w", AnnotationKeyLastAppliedBody, err)
+	}
+	b, err := json.Marshal(fingerprint)
+	if err != nil {
+		return fmt.Errorf("encoding %s annotation: %w", AnnotationKeyLastAppliedBody, err)
+	}
+	meta.AddAnnotations(mg, map[string]string{AnnotationKeyLastAppliedBody: string(b)})
+	return nil
+}
+
+// storeAppliedBody records the body successfully sent to the external API on the resource, along with the other
+// annotations set by the reconcile. The resource is only written if its annotations differ from the stored ones,
+// as read before the call, so applying the same body again costs no write.
+func (h *handler) storeAppliedBody(ctx context.Context, mg *unstructured.Unstructured, stored map[string]string, body interface{}) (*unstructured.Unstructured, error) {
+	err := setLastAppliedBody(mg, body)
+	if err != nil {
+		return mg, err
+	}
+	if reflect.DeepEqual(mg.GetAnnotations(), stored) {
+		return mg, nil
+	}
+	return tools.Update(ctx, mg, tools.UpdateOptions{
+		Pluralizer:    h.pluralizer,
+		DynamicClient: h.dynamicClient,
+	})
+}
+
+// previewUpdate computes the differences between the fingerprint of the last applied body and the next body.
+func previewUpdate(last map[string]interface{}, next interface{}) (UpdatePreview, error) {
+	b, err := json.Marshal(next)
+	if err != nil {
+		return UpdatePreview{}, err
+	}
+	nextFingerprint, err := fingerprintBody(next)
+	if err != nil {
+		return UpdatePreview{}, err
+	}
+
+	preview := UpdatePreview{Size: len(b)}
+	diffBodies(last, nextFingerprint, "", &preview)
+	sort.Strings(preview.Added)
+	sort.Strings(preview.Removed)
+	sort.Strings(preview.Changed)
+	return preview, nil
+}
+
+func diffBodies(last, next map[string]interface{}, prefix string, preview *UpdatePreview) {
+	for k, nv := range next {
+		path := joinPath(prefix, k)
+		lv, ok := last[k]
+		if !ok {
+			preview.Added = append(preview.Added, path)
+			continue
+		}
+		lm, lok := lv.(map[string]interface{})
+		nm, nok := nv.(map[string]interface{})
+		if lok && nok {
+			diffBodies(lm, nm, path, preview)
+			continue
+		}
+		if !reflect.DeepEqual(lv, nv) {
+			preview.Changed = append(preview.Changed, path)
+		}
+	}
+	for k := range last {
+		if _, ok := next[k]; !ok {
+			preview.Removed = append(preview.Removed, joinPath(prefix, k))
+		}
+	}
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return strings.Join([]string{prefix, key}, ".")
+}
@@ -0,0 +1,111 @@
+package restResources
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestSetLastAppliedBody(t *testing.T) {
+	mg := summaryResource()
+	body := map[string]interface{}{
+		"name":     "repo1",
+		"password": "s3cr3t",
+		"settings": map[string]interface{}{"private": true, "size": 1.5},
+	}
+	if err := setLastAppliedBody(mg, body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	raw := mg.GetAnnotations()[AnnotationKeyLastAppliedBody]
+	for _, value := range []string{"repo1", "s3cr3t", "1.5"} {
+		if strings.Contains(raw, value) {
+			t.Errorf("expected the value %q not to be stored in plaintext, got %s", value, raw)
+		}
+	}
+
+	last, err := getLastAppliedBody(mg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	settings, ok := last["settings"].(map[string]interface{})
+	if !ok || len(last) != 3 || len(settings) != 2 {
+		t.Fatalf("expected the fields to stay nested as in the body, got %v", last)
+	}
+	if !strings.HasPrefix(last["password"].(string), fingerprintPrefix) {
+		t.Errorf("expected the value to be hashed, got %v", last["password"])
+	}
+}
+
+func TestPreviewUpdate(t *testing.T) {
+	last, err := fingerprintBody(map[string]interface{}{
+		"name":     "repo1",
+		"password": "s3cr3t",
+		"settings": map[string]interface{}{"private": true, "size": 1.5},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	preview, err := previewUpdate(last, map[string]interface{}{
+		"name":     "repo1",
+		"password": "n3w",
+		"settings": map[string]interface{}{"size": 1.5},
+		"topics":   []interface{}{"go"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := UpdatePreview{
+		Size:    preview.Size,
+		Added:   []string{"topics"},
+		Removed: []string{"settings.private"},
+		Changed: []string{"password"},
+	}
+	if preview.Size == 0 || !reflect.DeepEqual(preview, expected) {
+		t.Errorf("expected %v, got %v", expected, preview)
+	}
+
+	// The numbers decoded from the annotation hash as the ones of the body
+	preview, err = previewUpdate(last, map[string]interface{}{
+		"name":     "repo1",
+		"password": "s3cr3t",
+		"settings": map[string]interface{}{"private": true, "size": 1.5},
+	})
+	if err != nil || len(preview.Added)+len(preview.Removed)+len(preview.Changed) != 0 {
+		t.Errorf("expected no differences, got %v (%v)", preview, err)
+	}
+}
+
+func TestStoreAppliedBody(t *testing.T) {
+	h, dyn, _ := conflictingHandler(t, 0)
+	writes := 0
+	dyn.PrependReactor("update", "repos", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() == "" {
+			writes++
+		}
+		return false, nil, nil
+	})
+
+	body := map[string]interface{}{"name": "repo1"}
+	mg := summaryResource()
+	mg, err := h.storeAppliedBody(context.Background(), mg, mg.GetAnnotations(), body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if writes != 1 {
+		t.Fatalf("expected the new body to be written, got %d writes", writes)
+	}
+
+	// Applying the same body again does not write the resource
+	_, err = h.storeAppliedBody(context.Background(), mg, mg.GetAnnotations(), body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if writes != 1 {
+		t.Errorf("expected no write for the same body, got %d writes", writes)
+	}
+}
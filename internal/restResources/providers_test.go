@@ -0,0 +1,483 @@
+package restResources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/requeue"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/rest-dynamic-controller/pkg/conformance"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/pluralizer"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	"sigs.k8s.io/yaml"
+)
+
+// providersDir holds the fixtures of the providers, one directory each.
+const providersDir = "../../providers"
+
+// providerScenario describes the mock server reproducing the API of a provider fixture (scenario.yaml).
+type providerScenario struct {
+	// Collection: the path listing and creating the items
+	Collection string `json:"collection"`
+	// Item: the path of an item, the {field} segment matching the value of the field of the item
+	Item string `json:"item"`
+	// IDField: the field holding the ID assigned to the created items
+	IDField string `json:"idField"`
+	// IDType: the type of the IDs [integer, string]
+	IDType string `json:"idType"`
+	List   struct {
+		// ItemsField: the field wrapping the items of the collection, a bare array if empty
+		ItemsField string `json:"itemsField"`
+		// CountField: the field holding the number of items of the collection, none if empty
+		CountField string `json:"countField"`
+		// Pagination: how the collection is paginated [page, link], not paginated if empty
+		Pagination string `json:"pagination"`
+		// PageParam: the query parameter holding the page number, defaults to page
+		PageParam string `json:"pageParam"`
+		// PageSize: the number of items in each page
+		PageSize int `json:"pageSize"`
+		// SearchParam: the query parameter filtering the items by name, none if empty
+		SearchParam string `json:"searchParam"`
+	} `json:"list"`
+	Create providerOperation `json:"create"`
+	Update providerOperation `json:"update"`
+	Delete providerOperation `json:"delete"`
+	// Seed: the items existing before the test
+	Seed []map[string]interface{} `json:"seed"`
+	// Mutate: the spec fields changed to check the update
+	Mutate map[string]interface{} `json:"mutate"`
+	// Drift: the fields of the created item changed out of band to check the drift detection
+	Drift map[string]interface{} `json:"drift"`
+}
+
+// providerOperation describes how the API answers an operation on an item.
+type providerOperation struct {
+	// Method: the HTTP method of the update
+	Method string `json:"method"`
+	// Status: the status code of the response
+	Status int `json:"status"`
+	// Operation: the response is the reference of a queued operation instead of the item
+	Operation bool `json:"operation"`
+	// Pending: the number of reads of the item before the operation completes
+	Pending int `json:"pending"`
+	// Fields: the fields of the item while the operation is pending
+	Fields map[string]interface{} `json:"fields"`
+	// Ready: the fields of the item once the operation completed
+	Ready map[string]interface{} `json:"ready"`
+}
+
+// mockItem is an item of the mock server.
+type mockItem struct {
+	fields map[string]interface{}
+	// op is the operation the item is pending for, nil if none
+	op *providerOperation
+	// reads are the reads left before the pending operation completes
+	reads int
+}
+
+// providerServer is a stateful mock of the API of a provider fixture.
+type providerServer struct {
+	scenario providerScenario
+	mu       sync.Mutex
+	items    []*mockItem
+	// created is the item created by the test
+	created *mockItem
+	nextID  int
+	// pages counts the pages of the collection listed
+	pages map[int]int
+}
+
+func newProviderServer(scenario providerScenario) *providerServer {
+	s := &providerServer{scenario: scenario, nextID: 1000, pages: map[int]int{}}
+	for _, fields := range scenario.Seed {
+		item := &mockItem{fields: runtime.DeepCopyJSON(fields)}
+		item.fields[scenario.IDField] = s.newID()
+		s.items = append(s.items, item)
+	}
+	return s
+}
+
+func (s *providerServer) newID() interface{} {
+	s.nextID++
+	if s.scenario.IDType == "integer" {
+		return int64(s.nextID)
+	}
+	return fmt.Sprintf("%08d-0000-4000-8000-%012d", s.nextID, s.nextID)
+}
+
+// read returns the representation of the item, completing its pending operation once its reads are over;
+// false if the item was deleted.
+func (s *providerServer) read(item *mockItem) (map[string]interface{}, bool) {
+	if item.op != nil && item.reads > 0 {
+		item.reads--
+		return s.represent(item), true
+	}
+	if item.op == &s.scenario.Delete {
+		s.remove(item)
+		return nil, false
+	}
+	if item.op != nil {
+		for k, v := range item.op.Ready {
+			item.fields[k] = v
+		}
+		item.op = nil
+	}
+	return s.represent(item), true
+}
+
+func (s *providerServer) represent(item *mockItem) map[string]interface{} {
+	res := runtime.DeepCopyJSON(item.fields)
+	if item.op != nil {
+		for k, v := range item.op.Fields {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+func (s *providerServer) remove(item *mockItem) {
+	for i, it := range s.items {
+		if it == item {
+			s.items = append(s.items[:i], s.items[i+1:]...)
+			return
+		}
+	}
+}
+
+// find returns the item at the path, nil if none.
+func (s *providerServer) find(path string) *mockItem {
+	tmpl := strings.Split(s.scenario.Item, "/")
+	segments := strings.Split(path, "/")
+	if len(tmpl) != len(segments) {
+		return nil
+	}
+	field, value := "", ""
+	for i, seg := range tmpl {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			field = strings.Trim(seg, "{}")
+			value, _ = url.PathUnescape(segments[i])
+		} else if seg != segments[i] {
+			return nil
+		}
+	}
+	for _, item := range s.items {
+		if fmt.Sprint(item.fields[field]) == value {
+			return item
+		}
+	}
+	return nil
+}
+
+func (s *providerServer) drift() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range s.scenario.Drift {
+		s.created.fields[k] = v
+	}
+}
+
+func (s *providerServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == "/plurals" {
+		fmt.Fprintf(w, `{"plural":"%ss","singular":"%s"}`, strings.ToLower(r.URL.Query().Get("kind")), strings.ToLower(r.URL.Query().Get("kind")))
+		return
+	}
+	if r.URL.Path == s.scenario.Collection {
+		switch r.Method {
+		case http.MethodGet:
+			s.list(w, r)
+		case http.MethodPost:
+			s.create(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	item := s.find(r.URL.Path)
+	if item == nil {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"message": "Not Found"})
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		res, ok := s.read(item)
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"message": "Not Found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, res)
+	case s.scenario.Update.Method:
+		body := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"message": err.Error()})
+			return
+		}
+		for k, v := range body {
+			item.fields[k] = v
+		}
+		s.answer(w, r, &s.scenario.Update, item)
+	case http.MethodDelete:
+		if s.scenario.Delete.Pending > 0 {
+			item.op, item.reads = &s.scenario.Delete, s.scenario.Delete.Pending
+		} else {
+			s.remove(item)
+		}
+		s.answer(w, r, &s.scenario.Delete, item)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *providerServer) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	items := []interface{}{}
+	for _, item := range append([]*mockItem{}, s.items...) {
+		if search := s.scenario.List.SearchParam; search != "" && query.Get(search) != "" &&
+			!strings.Contains(fmt.Sprint(item.fields["name"]), query.Get(search)) {
+			continue
+		}
+		if res, ok := s.read(item); ok {
+			items = append(items, res)
+		}
+	}
+
+	if size := s.scenario.List.PageSize; size > 0 && s.scenario.List.Pagination != "" {
+		param := s.scenario.List.PageParam
+		if param == "" {
+			param = "page"
+		}
+		page, _ := strconv.Atoi(query.Get(param))
+		if page < 1 {
+			page = 1
+		}
+		s.pages[page]++
+		from, to := min((page-1)*size, len(items)), min(page*size, len(items))
+		if s.scenario.List.Pagination == "link" && to < len(items) {
+			query.Set(param, strconv.Itoa(page+1))
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?%s>; rel="next"`, r.Host, r.URL.Path, query.Encode()))
+		}
+		items = items[from:to]
+	}
+
+	if s.scenario.List.ItemsField == "" {
+		writeJSON(w, http.StatusOK, items)
+		return
+	}
+	res := map[string]interface{}{s.scenario.List.ItemsField: items}
+	if s.scenario.List.CountField != "" {
+		res[s.scenario.List.CountField] = len(items)
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *providerServer) create(w http.ResponseWriter, r *http.Request) {
+	fields := map[string]interface{}{}
+	if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"message": err.Error()})
+		return
+	}
+	fields[s.scenario.IDField] = s.newID()
+	item := &mockItem{fields: fields}
+	if op := &s.scenario.Create; op.Pending > 0 {
+		item.op, item.reads = op, op.Pending
+	} else {
+		for k, v := range op.Ready {
+			item.fields[k] = v
+		}
+	}
+	s.items = append(s.items, item)
+	s.created = item
+	s.answer(w, r, &s.scenario.Create, item)
+}
+
+// answer writes the response of the operation on the item: the reference of the queued operation,
+// the representation of the item, or no content.
+func (s *providerServer) answer(w http.ResponseWriter, r *http.Request, op *providerOperation, item *mockItem) {
+	switch {
+	case op.Operation:
+		id := s.newID()
+		writeJSON(w, op.Status, map[string]interface{}{
+			"id":     fmt.Sprint(id),
+			"status": "queued",
+			"url":    fmt.Sprintf("http://%s/_apis/operations/%v", r.Host, id),
+		})
+	case op.Status == http.StatusNoContent:
+		w.WriteHeader(op.Status)
+	case op == &s.scenario.Delete:
+		writeJSON(w, op.Status, map[string]interface{}{"message": fmt.Sprintf("%d %s", op.Status, http.StatusText(op.Status))})
+	default:
+		writeJSON(w, op.Status, s.represent(item))
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// loadProviderFile decodes the YAML file of the provider fixture.
+func loadProviderFile(t *testing.T, provider, name string, v interface{}) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(providersDir, provider, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != nil {
+		if err := yaml.Unmarshal(data, v); err != nil {
+			t.Fatalf("decoding %s of %s: %v", name, provider, err)
+		}
+	}
+	return data
+}
+
+// loadProviderDefinition returns the info of the RestDefinition of the provider fixture, its OAS document
+// read from the ConfigMap of its oasPath.
+func loadProviderDefinition(t *testing.T, provider string) (*getter.Info, *unstructured.Unstructured) {
+	t.Helper()
+	def := &unstructured.Unstructured{}
+	loadProviderFile(t, provider, "restdefinition.yaml", &def.Object)
+
+	oasPath, _, _ := unstructured.NestedString(def.Object, "spec", "oasPath")
+	ref := strings.Split(strings.TrimPrefix(oasPath, "configmap://"), "/")
+	if len(ref) != 3 {
+		t.Fatalf("the oasPath of %s is not a ConfigMap: %s", provider, oasPath)
+	}
+	cm := &unstructured.Unstructured{}
+	cm.SetAPIVersion("v1")
+	cm.SetKind("ConfigMap")
+	cm.SetNamespace(ref[0])
+	cm.SetName(ref[1])
+	_ = unstructured.SetNestedField(cm.Object, string(loadProviderFile(t, provider, "openapi.yaml", nil)), "data", ref[2])
+
+	res, _, _ := unstructured.NestedMap(def.Object, "spec", "resource")
+	data, err := json.Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &getter.Info{URL: oasPath}
+	if err := json.Unmarshal(data, &info.Resource); err != nil {
+		t.Fatalf("decoding the resource of the RestDefinition of %s: %v", provider, err)
+	}
+	return info, cm
+}
+
+// TestProviders runs the lifecycle of the sample resource of each provider fixture against a mock server
+// reproducing the shapes of the API of the provider.
+func TestProviders(t *testing.T) {
+	entries, err := os.ReadDir(providersDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		provider := entry.Name()
+		t.Run(provider, func(t *testing.T) {
+			var scenario providerScenario
+			loadProviderFile(t, provider, "scenario.yaml", &scenario)
+			mg, err := conformance.LoadResource(filepath.Join(providersDir, provider, "resource.yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, cm := loadProviderDefinition(t, provider)
+
+			mock := newProviderServer(scenario)
+			srv := httptest.NewServer(mock)
+			t.Cleanup(srv.Close)
+			info.Resource.ServerURL = srv.URL
+
+			plurals := srv.URL + "/plurals"
+			pl := *pluralizer.New(&plurals, srv.Client())
+			gvr, err := pl.GVKtoGVR(mg.GroupVersionKind())
+			if err != nil {
+				t.Fatal(err)
+			}
+			dyn := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+				gvr:                                     mg.GetKind() + "List",
+				{Version: "v1", Resource: "configmaps"}: "ConfigMapList",
+			}, cm, mg.DeepCopy())
+
+			log := logging.NewNopLogger()
+			h := &handler{
+				pluralizer:        pl,
+				logger:            log,
+				dynamicClient:     dyn,
+				swaggerInfoGetter: staticInfo{info: info},
+				identifiers:       newIdentifierCache(),
+				requeue:           requeue.New(dyn, pl.GVKtoGVR, log),
+				resync:            newResyncGate(0, 0),
+			}
+
+			cluster := dyn.Resource(gvr).Namespace(mg.GetNamespace())
+			suite := &conformance.Suite{
+				Client:   h,
+				Resource: mg,
+				Refresh: func(ctx context.Context, mg *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+					return cluster.Get(ctx, mg.GetName(), metav1.GetOptions{})
+				},
+				Mutate: func(ctx context.Context, mg *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+					for k, v := range scenario.Mutate {
+						if err := unstructured.SetNestedField(mg.Object, v, "spec", k); err != nil {
+							return mg, err
+						}
+					}
+					return cluster.Update(ctx, mg, metav1.UpdateOptions{})
+				},
+				Drift: func(context.Context) error {
+					mock.drift()
+					return nil
+				},
+				Adopt: func(ctx context.Context, mg *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+					adopted := &unstructured.Unstructured{Object: map[string]interface{}{"spec": mg.Object["spec"]}}
+					adopted.SetGroupVersionKind(mg.GroupVersionKind())
+					adopted.SetNamespace(mg.GetNamespace())
+					adopted.SetName(mg.GetName() + "-adopted")
+					return cluster.Create(ctx, adopted, metav1.CreateOptions{})
+				},
+				Timeout:  5 * time.Second,
+				Interval: time.Millisecond,
+			}
+
+			report := suite.Run(context.Background())
+			if failed := report.Failed(); len(failed) > 0 {
+				t.Fatalf("unexpected failures:\n%s", report)
+			}
+			capabilities := []conformance.Capability{
+				conformance.CapabilityCreate, conformance.CapabilityObserve, conformance.CapabilityUpdate,
+				conformance.CapabilityDrift, conformance.CapabilityAdopt, conformance.CapabilityDelete,
+			}
+			if scenario.Create.Pending > 0 || scenario.Delete.Pending > 0 {
+				capabilities = append(capabilities, conformance.CapabilityAsync)
+			}
+			for _, c := range capabilities {
+				if !report.Supported(c) {
+					t.Errorf("expected %s to be supported:\n%s", c, report)
+				}
+			}
+			if scenario.List.Pagination != "" && len(mock.pages) < 2 {
+				t.Errorf("expected the pages of the collection to be followed, listed %v", mock.pages)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package restResources
+
+import (
+	"time"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/template"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const defaultPendingRequeueAfter = 10 * time.Second
+
+// requeueOnRetryAfter requeues the resource after the delay asked by the rate-limited or unavailable API, if any.
+func (h *handler) requeueOnRetryAfter(mg *unstructured.Unstructured, cli *restclient.UnstructuredClient, err error) {
+	if delay, ok := cli.RetryAfter(err); ok {
+		h.requeue.After(mg, delay, "retry-after")
+	}
+}
+
+// requeueIfPending requeues the resource still being provisioned, as told by the pending condition on the response.
+// It returns true if the resource is pending.
+func (h *handler) requeueIfPending(clientInfo *getter.Info, mg *unstructured.Unstructured, body map[string]interface{}) bool {
+	pending := clientInfo.Resource.Pending
+	if pending == nil || pending.Condition == "" {
+		return false
+	}
+	ok, err := isPending(pending, mg, body)
+	if err != nil {
+		h.objectLogger(mg).Debug("Evaluating pending condition", "error", err)
+		return false
+	}
+	if ok {
+		h.requeue.After(mg, pendingDelay(pending), "pending")
+	}
+	return ok
+}
+
+// requeueIfPendingStatus requeues the resource whose last response status is marked as pending by the verb
+// (e.g. 202 Accepted), after the delay of the pending configuration of the resource if any.
+// It returns true if the resource is pending.
+func (h *handler) requeueIfPendingStatus(clientInfo *getter.Info, mg *unstructured.Unstructured, cli *restclient.UnstructuredClient, reqConfiguration *restclient.RequestConfiguration) bool {
+	if !cli.IsPending(reqConfiguration) {
+		return false
+	}
+	delay := defaultPendingRequeueAfter
+	if pending := clientInfo.Resource.Pending; pending != nil {
+		delay = pendingDelay(pending)
+	}
+	h.requeue.After(mg, delay, "pending")
+	return true
+}
+
+// isPending evaluates the pending condition on the response and on the CR fields.
+func isPending(pending *getter.Pending, mg *unstructured.Unstructured, body map[string]interface{}) (bool, error) {
+	vars := crFields(mg)
+	vars["response"] = nonNilFields(body)
+	return template.EvalBool(pending.Condition, vars)
+}
+
+// pendingDelay returns the delay after which the pending resource is observed again.
+func pendingDelay(pending *getter.Pending) time.Duration {
+	if pending.RequeueAfter == "" {
+		return defaultPendingRequeueAfter
+	}
+	d, err := time.ParseDuration(pending.RequeueAfter)
+	if err != nil || d <= 0 {
+		return defaultPendingRequeueAfter
+	}
+	return d
+}
@@ -0,0 +1,42 @@
+package restResources
+
+import (
+	"testing"
+	"time"
+
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestIsPending(t *testing.T) {
+	mg := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"name": "repo"},
+	}}
+	pending := &getter.Pending{Condition: `response.state == "provisioning" && spec.name == "repo"`}
+
+	ok, err := isPending(pending, mg, map[string]interface{}{"state": "provisioning"})
+	if err != nil || !ok {
+		t.Errorf("expected the resource to be pending, got %t (%v)", ok, err)
+	}
+	ok, err = isPending(pending, mg, map[string]interface{}{"state": "ready"})
+	if err != nil || ok {
+		t.Errorf("expected the resource not to be pending, got %t (%v)", ok, err)
+	}
+}
+
+func TestPendingDelay(t *testing.T) {
+	tests := []struct {
+		requeueAfter string
+		expected     time.Duration
+	}{
+		{requeueAfter: "", expected: defaultPendingRequeueAfter},
+		{requeueAfter: "30s", expected: 30 * time.Second},
+		{requeueAfter: "-1s", expected: defaultPendingRequeueAfter},
+		{requeueAfter: "soon", expected: defaultPendingRequeueAfter},
+	}
+	for _, tt := range tests {
+		if got := pendingDelay(&getter.Pending{RequeueAfter: tt.requeueAfter}); got != tt.expected {
+			t.Errorf("%q: expected %v, got %v", tt.requeueAfter, tt.expected, got)
+		}
+	}
+}
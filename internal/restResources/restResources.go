@@ -2,64 +2,245 @@ package restResources
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 
 	"github.com/gobuffalo/flect"
 	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
 	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/apiaction"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/audit"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/authstatus"
+	customcondition "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/condition"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/healthprobe"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/hotloop"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/lifecycle"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/provenance"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/quota"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/requeue"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/rotation"
 	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/statusschema"
 	"github.com/krateoplatformops/unstructured-runtime/pkg/controller"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/event"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/eventrecorder"
 	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
 	"github.com/krateoplatformops/unstructured-runtime/pkg/meta"
 	"github.com/krateoplatformops/unstructured-runtime/pkg/pluralizer"
-	"github.com/krateoplatformops/unstructured-runtime/pkg/tools/unstructured/condition"
 	"github.com/lucasepe/httplib"
 
-	"github.com/krateoplatformops/unstructured-runtime/pkg/tools"
 	unstructuredtools "github.com/krateoplatformops/unstructured-runtime/pkg/tools/unstructured"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
+	memory "k8s.io/client-go/discovery/cached"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
 )
 
+const (
+	reasonPossibleUpdateLoop  event.Reason = "PossibleUpdateLoop"
+	reasonAmbiguousDefinition event.Reason = "AmbiguousDefinition"
+)
+
 var _ controller.ExternalClient = (*handler)(nil)
 
-func NewHandler(cfg *rest.Config, log logging.Logger, swg getter.Getter, pluralizer pluralizer.Pluralizer) controller.ExternalClient {
+// Options configures the handler reconciling the custom resources with the external REST API.
+type Options struct {
+	// Config of the kubernetes cluster
+	Config *rest.Config
+	// Logger of the handler
+	Logger logging.Logger
+	// SwaggerInfoGetter resolves the RestDefinition info of the custom resources
+	SwaggerInfoGetter getter.Getter
+	// Pluralizer of the custom resources kinds
+	Pluralizer pluralizer.Pluralizer
+	// Discovery is the cached discovery client shared with the controller, nil creates a new one
+	Discovery discovery.CachedDiscoveryInterface
+	// AuditSink records the mutations of the external resources, nil disables the audit
+	AuditSink audit.Sink
+	// HotLoop detects the update loops, nil disables the detection
+	HotLoop *hotloop.Detector
+	// Conditions maps the conditions to a custom vocabulary, nil keeps the controller ones
+	Conditions *customcondition.Vocabulary
+	// ResyncInterval is the interval the resources are observed again at once up-to-date
+	ResyncInterval time.Duration
+	// ResyncJitter is the percentage of the resync interval the observations are randomly spread by, each resource being
+	// requeued at its jittered time; 0 or a nil Requeue disables the jitter
+	ResyncJitter int
+	// Requeue reconciles the resources again sooner than the resync interval (e.g. while being provisioned,
+	// or once the delay asked by a rate-limited API is over) once run, nil disables the requeues
+	Requeue *requeue.Requeuer
+	// AuthStatus reports the state of the credentials in the authentication objects, nil disables the reports
+	AuthStatus *authstatus.Reporter
+	// SecretRotation watches the secrets the credentials are read from, reconciling the resources through
+	// the Requeue as soon as they are rotated, nil disables the detection
+	SecretRotation *rotation.Watcher
+	// Summaries selects the reconciles summarized at Info level, none if empty
+	Summaries SummaryLevel
+	// StatusPhase writes status.phase and status.message summarizing the conditions, for the CRDs whose status schema allows them
+	StatusPhase bool
+	// StatusSchema reads the status schemas of the CRDs, storing the status fields they do not allow in an annotation,
+	// nil writes the status as is
+	StatusSchema *statusschema.Reader
+	// HealthProbe probes the servers of the external APIs, attributing the failures of the resources
+	// to the outage of their server, nil disables the attribution
+	HealthProbe *healthprobe.Prober
+	// Provenance stamps the mutations of the external resources with the provenance headers, nil disables the stamps
+	Provenance *provenance.Stamper
+	// CallBudget limits the external calls made for each resource within its window, nil disables the limit
+	CallBudget *quota.Budget
+	// Capabilities caches the methods allowed by the APIs, probed before the updates; nil disables the probes
+	Capabilities *restclient.CapabilityCache
+	// ClusterName is the name of the cluster written in the ownership markers of the external resources, omitted if empty
+	ClusterName string
+}
+
+func NewHandler(cfg *rest.Config, log logging.Logger, swg getter.Getter, pluralizer pluralizer.Pluralizer, auditSink audit.Sink, hotLoop *hotloop.Detector, conditions *customcondition.Vocabulary) controller.ExternalClient {
+	return New(Options{
+		Config:            cfg,
+		Logger:            log,
+		SwaggerInfoGetter: swg,
+		Pluralizer:        pluralizer,
+		AuditSink:         auditSink,
+		HotLoop:           hotLoop,
+		Conditions:        conditions,
+	})
+}
+
+// New returns the handler reconciling the custom resources with the external REST API.
+func New(opts Options) controller.ExternalClient {
+	cfg, log := opts.Config, opts.Logger
+
 	dyn, err := dynamic.NewForConfig(cfg)
 	if err != nil {
 		log.Debug("Creating dynamic client", "error", err)
 	}
 
-	dis, err := discovery.NewDiscoveryClientForConfig(cfg)
+	dis := opts.Discovery
+	if dis == nil {
+		raw, err := discovery.NewDiscoveryClientForConfig(cfg)
+		if err != nil {
+			log.Debug("Creating discovery client", "error", err)
+		} else {
+			dis = memory.NewMemCacheClient(raw)
+		}
+	}
+
+	var recorder event.Recorder = event.NewNopRecorder()
+	rec, err := eventrecorder.Create(cfg)
 	if err != nil {
-		log.Debug("Creating discovery client", "error", err)
+		log.Debug("Creating event recorder", "error", err)
+	} else {
+		recorder = event.NewAPIRecorder(rec)
 	}
 
-	return &handler{
-		pluralizer:        pluralizer,
+	h := &handler{
+		pluralizer:        opts.Pluralizer,
 		logger:            log,
 		dynamicClient:     dyn,
 		discoveryClient:   dis,
-		swaggerInfoGetter: swg,
+		swaggerInfoGetter: opts.SwaggerInfoGetter,
+		auditSink:         opts.AuditSink,
+		hotLoop:           opts.HotLoop,
+		recorder:          recorder,
+		conditions:        opts.Conditions,
+		identifiers:       newIdentifierCache(),
+		requeue:           opts.Requeue,
+		authStatus:        opts.AuthStatus,
+		rotation:          opts.SecretRotation,
+		summaries:         opts.Summaries,
+		statusPhase:       opts.StatusPhase,
+		statusSchema:      opts.StatusSchema,
+		health:            opts.HealthProbe,
+		provenance:        opts.Provenance,
+		budget:            opts.CallBudget,
+		capabilities:      opts.Capabilities,
+		clusterName:       opts.ClusterName,
 	}
+	// The resyncs of the runtime skipped by the gate are made up for by the requeues
+	if opts.Requeue != nil {
+		h.resync = newResyncGate(opts.ResyncInterval, opts.ResyncJitter)
+	}
+	h.rotation.OnRotation(h.secretRotated)
+	return h
 }
 
 type handler struct {
 	pluralizer        pluralizer.Pluralizer
 	logger            logging.Logger
 	dynamicClient     dynamic.Interface
-	discoveryClient   *discovery.DiscoveryClient
+	discoveryClient   discovery.CachedDiscoveryInterface
 	swaggerInfoGetter getter.Getter
+	auditSink         audit.Sink
+	hotLoop           *hotloop.Detector
+	recorder          event.Recorder
+	conditions        *customcondition.Vocabulary
+	identifiers       *identifierCache
+	requeue           *requeue.Requeuer
+	resync            *resyncGate
+	authStatus        *authstatus.Reporter
+	rotation          *rotation.Watcher
+	summaries         SummaryLevel
+	statusPhase       bool
+	statusSchema      *statusschema.Reader
+	health            *healthprobe.Prober
+	provenance        *provenance.Stamper
+	budget            *quota.Budget
+	capabilities      *restclient.CapabilityCache
+	clusterName       string
+	updateKeys        updateKeys
+}
+
+// mutationClient returns the http client auditing and stamping the mutations made for the resource by the action.
+func (h *handler) mutationClient(ctx context.Context, mg *unstructured.Unstructured, action string) *http.Client {
+	return provenance.Client(ctx, h.provenance, audit.Client(h.auditSink, mg, action), mg)
+}
+
+func (h *handler) Observe(ctx context.Context, mg *unstructured.Unstructured) (obs controller.ExternalObservation, err error) {
+	defer h.recoverPanic(ctx, mg, "observe", &err)
+	ctx = provenance.WithReconcileID(ctx)
+	ctx = withStatusBase(ctx, mg)
+	if h.resync.skip(mg) {
+		h.objectLogger(mg).Debug("Skipping resync, next observation not due yet", "name", mg.GetName(), "namespace", mg.GetNamespace())
+		return controller.ExternalObservation{
+			ResourceExists:   true,
+			ResourceUpToDate: true,
+		}, nil
+	}
+
+	ctx, sum := h.startSummary(ctx)
+	ctx, server := withServer(ctx)
+	err = h.withinBudget(sum, mg, func() (err error) {
+		obs, err = h.observe(ctx, mg)
+		return err
+	})
+	err = h.attributeOutage(*server, err)
+	if err == nil && !obs.ResourceExists {
+		h.transition(mg, lifecycle.NotFound, "", "")
+	}
+	if err != nil || !obs.ResourceExists {
+		// The status written observing an existing resource already clears the problem conditions
+		h.updateProblemConditions(ctx, mg, err)
+	}
+	h.summarize(sum, mg, "observe", observationResult(obs, err), err)
+	if err == nil && obs.ResourceExists && obs.ResourceUpToDate {
+		if delay, ok := h.resync.observed(mg); ok {
+			h.requeue.After(mg, delay, "resync")
+		}
+	} else {
+		h.resync.forget(mg)
+	}
+	return obs, err
 }
 
-func (h *handler) Observe(ctx context.Context, mg *unstructured.Unstructured) (controller.ExternalObservation, error) {
-	log := h.logger.WithValues("op", "Observe").
+func (h *handler) observe(ctx context.Context, mg *unstructured.Unstructured) (controller.ExternalObservation, error) {
+	log := h.objectLogger(mg).WithValues("op", "Observe").
 		WithValues("apiVersion", mg.GetAPIVersion()).
 		WithValues("kind", mg.GetKind()).
 		WithValues("name", mg.GetName()).
@@ -71,18 +252,17 @@ func (h *handler) Observe(ctx context.Context, mg *unstructured.Unstructured) (c
 	clientInfo, err := h.swaggerInfoGetter.Get(mg)
 	if err != nil {
 		log.Debug("Getting REST client info", "error", err)
+		h.credentialsFailed(ctx, mg, err)
 		return controller.ExternalObservation{}, err
 	}
 	if clientInfo == nil {
 		log.Debug("Swagger info is nil")
 		return controller.ExternalObservation{}, fmt.Errorf("swagger info is nil")
 	}
-	mg, err = tools.Update(ctx, mg, tools.UpdateOptions{
-		Pluralizer:    h.pluralizer,
-		DynamicClient: h.dynamicClient,
-	})
+	restoreStatusOverflow(mg)
+	err = h.setDefinitionAmbiguity(mg, clientInfo)
 	if err != nil {
-		log.Debug("Updating CR", "error", err)
+		log.Debug("Setting condition", "error", err)
 		return controller.ExternalObservation{}, err
 	}
 
@@ -92,9 +272,16 @@ func (h *handler) Observe(ctx context.Context, mg *unstructured.Unstructured) (c
 		return controller.ExternalObservation{}, err
 	}
 	cli.Auth = clientInfo.Auth
+	applyServerURL(cli, clientInfo)
+	h.trackServer(ctx, cli, clientInfo)
+	defer h.reportCredentials(ctx, mg, clientInfo, cli)
+	trackCalls(ctx, cli)
 	cli.Verbose = meta.IsVerbose(mg)
 	cli.IdentifierFields = clientInfo.Resource.Identifiers
 	cli.SpecFields = mg
+	cli.Pagination = clientInfo.Resource.Pagination
+	cli.JSONAPI = clientInfo.Resource.JSONAPI
+	cli.Owner = h.owner(clientInfo, mg)
 	specFields, err := unstructuredtools.GetFieldsFromUnstructured(mg, "spec")
 	if err != nil {
 		log.Debug("Getting spec", "error", err)
@@ -105,11 +292,105 @@ func (h *handler) Observe(ctx context.Context, mg *unstructured.Unstructured) (c
 		log.Debug("Error getting status.", "error", err)
 	}
 	var body *map[string]interface{}
+	var findByPage *string
+	var etag string
+	// driftMsg summarizes the drift left unremediated by the drift policy, empty if none
+	var driftMsg string
+	// pending is true if the external resource is still being provisioned
+	var pending bool
 	isKnown := isResourceKnown(cli, log, clientInfo, statusFields, specFields)
+	if !isKnown {
+		// Using the identifiers previously resolved by FindBy, if not yet persisted in the status
+		if ids, ok := h.identifiers.Get(objectKey(mg)); ok {
+			statusFields = withIdentifiers(statusFields, ids)
+			isKnown = isResourceKnown(cli, log, clientInfo, statusFields, specFields)
+		}
+	}
+	if !isKnown {
+		// Reaching the external resource through the link returned by the API
+		isKnown = reachableByLink(cli, clientInfo, mg, apiaction.Get) || reachableByLink(cli, clientInfo, mg, apiaction.Exists)
+	}
+
+	var existsCall APIFuncDef
+	if isKnown {
+		// Checking the existence of the external resource when it has no get verb reading its full representation,
+		// which tells the existence as well, not to call the API twice
+		var getCall APIFuncDef
+		getCall, _, err = APICallBuilder(cli, clientInfo, apiaction.Get)
+		if err != nil {
+			log.Debug("Building API call", "error", err)
+			return controller.ExternalObservation{}, err
+		}
+		var existsInfo *CallInfo
+		if getCall == nil {
+			existsCall, existsInfo, err = APICallBuilder(cli, clientInfo, apiaction.Exists)
+			if err != nil {
+				log.Debug("Building API call", "error", err)
+				return controller.ExternalObservation{}, err
+			}
+		}
+		if existsCall != nil {
+			var reqConfiguration *restclient.RequestConfiguration
+			reqConfiguration, err = BuildCallConfig(existsInfo, statusFields, specFields)
+			if err != nil {
+				log.Debug("Building call configuration", "error", err)
+				return controller.ExternalObservation{}, err
+			}
+			followResponseLink(mg, existsInfo, reqConfiguration)
+			_, err = existsCall(ctx, http.DefaultClient, existsInfo.Path, reqConfiguration)
+			err = notFoundIfForbidden(cli, existsInfo, err)
+			if httplib.IsNotFoundError(err) {
+				log.Debug("External resource not found", "kind", mg.GetKind())
+				if h.canFindBy(cli, clientInfo) {
+					isKnown = false
+				} else {
+					return controller.ExternalObservation{
+						ResourceExists:   false,
+						ResourceUpToDate: false,
+					}, nil
+				}
+			} else if err != nil {
+				log.Debug("Performing REST call", "error", err)
+				h.requeueOnRetryAfter(mg, cli, err)
+				return controller.ExternalObservation{}, err
+			}
+		}
+	}
 
 	if isKnown {
 		// Getting the external resource by its identifier
 		apiCall, callInfo, err := APICallBuilder(cli, clientInfo, apiaction.Get)
+		if apiCall == nil && existsCall != nil {
+			log.Debug("API call not found", "action", apiaction.Get)
+			log.Debug("Resource exists and is assumed to be up-to-date.")
+			_, err = h.transition(mg, lifecycle.AssumedUpToDate, "", "Resource is assumed to be up-to-date. API call not found for Get.")
+			if err != nil {
+				log.Debug("Setting condition", "error", err)
+				return controller.ExternalObservation{}, err
+			}
+			err = setObservedGeneration(mg)
+			if err != nil {
+				log.Debug("Setting observed generation", "error", err)
+				return controller.ExternalObservation{}, err
+			}
+			err = h.clearProblems(mg)
+			if err != nil {
+				log.Debug("Clearing problem conditions", "error", err)
+				return controller.ExternalObservation{}, err
+			}
+			err = h.setReadiness(mg)
+			if err != nil {
+				log.Debug("Setting readiness", "error", err)
+				return controller.ExternalObservation{}, err
+			}
+
+			_, err = h.updateStatus(ctx, mg)
+
+			return controller.ExternalObservation{
+				ResourceExists:   true,
+				ResourceUpToDate: true,
+			}, err
+		}
 		if apiCall == nil {
 			log.Debug("API call not found", "action", apiaction.Get)
 			return controller.ExternalObservation{}, fmt.Errorf("API call not found for %s", apiaction.Get)
@@ -118,43 +399,69 @@ func (h *handler) Observe(ctx context.Context, mg *unstructured.Unstructured) (c
 			log.Debug("Building API call", "error", err)
 			return controller.ExternalObservation{}, err
 		}
-		reqConfiguration := BuildCallConfig(callInfo, statusFields, specFields)
-		if reqConfiguration == nil {
-			return controller.ExternalObservation{}, fmt.Errorf("error building call configuration")
+		var reqConfiguration *restclient.RequestConfiguration
+		reqConfiguration, err = BuildCallConfig(callInfo, statusFields, specFields)
+		if err != nil {
+			log.Debug("Building call configuration", "error", err)
+			return controller.ExternalObservation{}, err
 		}
+		followResponseLink(mg, callInfo, reqConfiguration)
+		applySparseFields(cli, clientInfo, callInfo, mg, reqConfiguration)
 		body, err = apiCall(ctx, http.DefaultClient, callInfo.Path, reqConfiguration)
+		err = notFoundIfForbidden(cli, callInfo, err)
 		if httplib.IsNotFoundError(err) {
 			log.Debug("External resource not found", "kind", mg.GetKind())
-			return controller.ExternalObservation{
-				ResourceExists:   false,
-				ResourceUpToDate: false,
-			}, nil
-		}
-		if err != nil {
+			if !h.canFindBy(cli, clientInfo) {
+				return controller.ExternalObservation{
+					ResourceExists:   false,
+					ResourceUpToDate: false,
+				}, nil
+			}
+			isKnown = false
+		} else if err != nil {
 			log.Debug("Performing REST call", "error", err)
+			h.requeueOnRetryAfter(mg, cli, err)
 			return controller.ExternalObservation{}, err
 		}
-	} else {
+		etag = cli.ResponseHeaders.Get("ETag")
+		pending = h.requeueIfPendingStatus(clientInfo, mg, cli, reqConfiguration)
+	}
+
+	if !isKnown {
+		// The identifiers are unknown or stale, searching the external resource
+		h.identifiers.Delete(objectKey(mg))
+		statusFields = withoutIdentifiers(statusFields, clientInfo.Resource.Identifiers)
+
 		apiCall, callInfo, err := APICallBuilder(cli, clientInfo, apiaction.FindBy)
 		if apiCall == nil {
-			if !unstructuredtools.IsConditionSet(mg, condition.Creating()) && !unstructuredtools.IsConditionSet(mg, condition.Available()) {
+			if !h.lifecycleState(mg).Provisioned() {
 				log.Debug("External resource is being created", "kind", mg.GetKind())
 				return controller.ExternalObservation{}, nil
 			}
 			log.Debug("API call not found", "action", apiaction.FindBy)
 			log.Debug("Resource is assumed to be up-to-date.")
-			cond := condition.Available()
-			cond.Message = "Resource is assumed to be up-to-date. API call not found for FindBy."
-			err = unstructuredtools.SetCondition(mg, cond)
+			_, err = h.transition(mg, lifecycle.AssumedUpToDate, "", "Resource is assumed to be up-to-date. API call not found for FindBy.")
 			if err != nil {
 				log.Debug("Setting condition", "error", err)
 				return controller.ExternalObservation{}, err
 			}
+			err = setObservedGeneration(mg)
+			if err != nil {
+				log.Debug("Setting observed generation", "error", err)
+				return controller.ExternalObservation{}, err
+			}
+			err = h.clearProblems(mg)
+			if err != nil {
+				log.Debug("Clearing problem conditions", "error", err)
+				return controller.ExternalObservation{}, err
+			}
+			err = h.setReadiness(mg)
+			if err != nil {
+				log.Debug("Setting readiness", "error", err)
+				return controller.ExternalObservation{}, err
+			}
 
-			_, err = tools.UpdateStatus(ctx, mg, tools.UpdateOptions{
-				Pluralizer:    h.pluralizer,
-				DynamicClient: h.dynamicClient,
-			})
+			_, err = h.updateStatus(ctx, mg)
 
 			return controller.ExternalObservation{
 				ResourceExists:   true,
@@ -165,49 +472,143 @@ func (h *handler) Observe(ctx context.Context, mg *unstructured.Unstructured) (c
 			log.Debug("Building API call", "error", err)
 			return controller.ExternalObservation{}, err
 		}
-		reqConfiguration := BuildCallConfig(callInfo, statusFields, specFields)
-		if reqConfiguration == nil {
-			log.Debug("Building call configuration", "error", "error building call configuration")
-			return controller.ExternalObservation{}, fmt.Errorf("error building call configuration")
+		var reqConfiguration *restclient.RequestConfiguration
+		reqConfiguration, err = BuildCallConfig(callInfo, statusFields, specFields)
+		if err != nil {
+			log.Debug("Building call configuration", "error", err)
+			return controller.ExternalObservation{}, err
+		}
+		resume := cli.Pagination != nil && cli.Pagination.Resume
+		if resume {
+			cli.StartPage = getFindByPage(statusFields)
 		}
 		body, err = apiCall(ctx, http.DefaultClient, callInfo.Path, reqConfiguration)
 		if httplib.IsNotFoundError(err) {
-			log.Debug("External resource not found", "kind", mg.GetKind())
-			return controller.ExternalObservation{}, nil
+			log.Debug("External resource not found", "kind", mg.GetKind(), "reason", err.Error())
+			return controller.ExternalObservation{}, h.setSearchMiss(mg, err)
 		}
 		if err != nil {
 			log.Debug("Performing REST call", "error", err)
+			h.requeueOnRetryAfter(mg, cli, err)
 			return controller.ExternalObservation{}, err
 		}
+		if resume {
+			findByPage = &cli.FoundPage
+		}
+		pending = h.requeueIfPendingStatus(clientInfo, mg, cli, reqConfiguration)
+		if body != nil {
+			h.identifiers.Set(objectKey(mg), clientInfo.Resource.Identifiers, *body)
+		}
 	}
 
+	// Running the auxiliary actions before the comparison, so that their results are written
+	// in the status whether the external resource drifted or not
+	h.runAuxiliaryActions(ctx, log, cli, clientInfo, mg, statusFields, specFields)
+
 	if body != nil {
-		err = populateStatusFields(clientInfo, mg, body)
+		pending = h.requeueIfPending(clientInfo, mg, *body) || pending
+		changed, err := populateAnnotations(clientInfo, mg, body)
 		if err != nil {
-			log.Debug("Updating identifiers", "error", err)
+			log.Debug("Updating annotations", "error", err)
 			return controller.ExternalObservation{}, err
 		}
+		if usesResponseLinks(clientInfo) {
+			linksChanged, err := setResponseLinks(mg, *body)
+			if err != nil {
+				log.Debug("Updating response links", "error", err)
+				return controller.ExternalObservation{}, err
+			}
+			changed = changed || linksChanged
+		}
+		changed = setETag(clientInfo.Resource.OptimisticLocking, mg, etag) || changed
+		if clientInfo.Resource.LateInitialize {
+			initialized, err := lateInitialize(mg, lateInitFields(cli, clientInfo), *body)
+			if err != nil {
+				log.Debug("Late initializing spec", "error", err)
+				return controller.ExternalObservation{}, err
+			}
+			if initialized {
+				log.Debug("Spec late initialized from the external resource", "kind", mg.GetKind())
+			}
+			changed = changed || initialized
+		}
 
-		mg, err = tools.UpdateStatus(ctx, mg, tools.UpdateOptions{
-			Pluralizer:    h.pluralizer,
-			DynamicClient: h.dynamicClient,
-		})
+		err = populateStatusFields(clientInfo, mg, body, cli.ResponseHeaders)
 		if err != nil {
-			log.Debug("Updating status", "error", err)
+			log.Debug("Updating identifiers", "error", err)
 			return controller.ExternalObservation{}, err
 		}
-		res, err := isCRUpdated(mg, *body)
+		if isDataSource(clientInfo) {
+			err = projectDataSource(mg, *body)
+			if err != nil {
+				log.Debug("Projecting data source", "error", err)
+				return controller.ExternalObservation{}, err
+			}
+		}
+		if findByPage != nil {
+			err = setFindByPage(mg, *findByPage)
+			if err != nil {
+				log.Debug("Setting findby page", "error", err)
+				return controller.ExternalObservation{}, err
+			}
+		}
+		overflowChanged, err := h.stageStatusOverflow(ctx, mg)
 		if err != nil {
-			log.Debug("Checking if CR is updated", "error", err)
+			log.Debug("Storing status overflow", "error", err)
 			return controller.ExternalObservation{}, err
 		}
-		if !res.IsEqual {
-			cond := condition.Unavailable()
+		changed = changed || overflowChanged
+		if changed {
+			// A single write of the metadata and spec, the status is written once at the end of the observation
+			mg, err = h.updateSpec(ctx, mg)
+			if err != nil {
+				log.Debug("Updating CR", "error", err)
+				return controller.ExternalObservation{}, err
+			}
+		}
+
+		// The external resource of a data source is only projected into the status, never compared with the spec
+		res := ComparisonResult{IsEqual: true}
+		if !isDataSource(clientInfo) {
+			res, err = isCRUpdated(clientInfo, mg, *body, uncomparedFields(cli, clientInfo))
+			if err != nil {
+				log.Debug("Checking if CR is updated", "error", err)
+				return controller.ExternalObservation{}, err
+			}
+		}
+		if len(res.Uncomparable) > 0 {
+			log.Debug("Fields not compared", "fields", strings.Join(res.Uncomparable, ", "))
+		}
+		if !res.IsEqual && ignoreDrift(clientInfo, mg) {
+			log.Debug("External resource drifted, not remediated with the ObserveOnly drift policy", "kind", mg.GetKind())
+			driftMsg = driftMessage(res)
+		} else if !res.IsEqual {
+			message := "Resource is not up-to-date"
 			if res.Reason != nil {
-				cond.Reason = fmt.Sprintf("Resource is not up-to-date due to %s - spec value: %s, remote value: %s", res.Reason.Reason, res.Reason.FirstValue, res.Reason.SecondValue)
+				message = fmt.Sprintf("Resource is not up-to-date due to %s at %s - spec value: %v, remote value: %v", res.Reason.Reason, res.Reason.Path, res.Reason.FirstValue, res.Reason.SecondValue)
+			}
+			if len(res.Uncomparable) > 0 {
+				message = fmt.Sprintf("%s. Fields not compared: %s", message, strings.Join(res.Uncomparable, ", "))
 			}
 
-			unstructuredtools.SetCondition(mg, cond)
+			// Writing the observed status, the drift and the Drifted state in a single update
+			if _, err := h.transition(mg, lifecycle.Diverged, "", message); err != nil {
+				log.Debug("Setting condition", "error", err)
+				return controller.ExternalObservation{}, err
+			}
+			if drifted := customcondition.Drifted(driftMessage(res)); h.conditionsChanged(mg, []metav1.Condition{drifted}) {
+				if err := h.conditions.Set(mg, drifted); err != nil {
+					log.Debug("Setting drifted condition", "error", err)
+				}
+			}
+			if err := h.setReadiness(mg); err != nil {
+				log.Debug("Setting readiness", "error", err)
+			}
+			mg, err = h.updateStatus(ctx, mg)
+			if err != nil {
+				log.Debug("Updating status", "error", err)
+				return controller.ExternalObservation{}, err
+			}
 			log.Debug("External resource not up-to-date", "kind", mg.GetKind())
 			return controller.ExternalObservation{
 					ResourceExists:   true,
@@ -219,15 +620,48 @@ func (h *handler) Observe(ctx context.Context, mg *unstructured.Unstructured) (c
 		}
 	}
 	log.Debug("Setting condition", "kind", mg.GetKind())
-	err = unstructuredtools.SetCondition(mg, condition.Available())
+	if pending {
+		_, err = h.transition(mg, lifecycle.ObservedPending, "", "The external resource is still being provisioned")
+	} else {
+		_, err = h.transition(mg, lifecycle.UpToDate, "", "")
+	}
 	if err != nil {
 		log.Debug("Setting condition", "error", err)
 		return controller.ExternalObservation{}, err
 	}
-	mg, err = tools.UpdateStatus(ctx, mg, tools.UpdateOptions{
-		Pluralizer:    h.pluralizer,
-		DynamicClient: h.dynamicClient,
-	})
+	if driftMsg != "" {
+		err = h.conditions.Set(mg, customcondition.Drifted(driftMsg))
+	} else if h.isConditionTrue(mg, customcondition.TypeDrifted) {
+		err = h.conditions.Set(mg, customcondition.InSync())
+	}
+	if err != nil {
+		log.Debug("Setting condition", "error", err)
+		return controller.ExternalObservation{}, err
+	}
+	err = setObservedGeneration(mg)
+	if err != nil {
+		log.Debug("Setting observed generation", "error", err)
+		return controller.ExternalObservation{}, err
+	}
+	err = h.clearProblems(mg)
+	if err != nil {
+		log.Debug("Clearing problem conditions", "error", err)
+		return controller.ExternalObservation{}, err
+	}
+	err = h.setReadiness(mg)
+	if err != nil {
+		log.Debug("Setting readiness", "error", err)
+		return controller.ExternalObservation{}, err
+	}
+	h.hotLoop.Reset(objectKey(mg))
+	if unstructuredtools.GetCondition(mg, customcondition.TypePossibleUpdateLoop, customcondition.ReasonUpdateLoopDetected) != nil {
+		err = h.conditions.Set(mg, customcondition.NoUpdateLoop())
+		if err != nil {
+			log.Debug("Setting condition", "error", err)
+			return controller.ExternalObservation{}, err
+		}
+	}
+	mg, err = h.updateStatus(ctx, mg)
 	if err != nil {
 		log.Debug("Updating status", "error", err)
 		return controller.ExternalObservation{}, err
@@ -241,8 +675,21 @@ func (h *handler) Observe(ctx context.Context, mg *unstructured.Unstructured) (c
 	}, nil
 }
 
-func (h *handler) Create(ctx context.Context, mg *unstructured.Unstructured) error {
-	log := h.logger.WithValues("op", "Create").
+func (h *handler) Create(ctx context.Context, mg *unstructured.Unstructured) (err error) {
+	defer h.recoverPanic(ctx, mg, "create", &err)
+	ctx = provenance.WithReconcileID(ctx)
+	ctx = withStatusBase(ctx, mg)
+	ctx, sum := h.startSummary(ctx)
+	ctx, server := withServer(ctx)
+	err = h.attributeOutage(*server, h.withinBudget(sum, mg, func() error { return h.create(ctx, mg) }))
+	h.updateProblemConditions(ctx, mg, err)
+	h.summarize(sum, mg, "create", mutationResult(err), err)
+	return err
+}
+
+func (h *handler) create(ctx context.Context, mg *unstructured.Unstructured) error {
+	restoreStatusOverflow(mg)
+	log := h.objectLogger(mg).WithValues("op", "Create").
 		WithValues("apiVersion", mg.GetAPIVersion()).
 		WithValues("kind", mg.GetKind()).
 		WithValues("name", mg.GetName()).
@@ -255,8 +702,12 @@ func (h *handler) Create(ctx context.Context, mg *unstructured.Unstructured) err
 	clientInfo, err := h.swaggerInfoGetter.Get(mg)
 	if err != nil {
 		log.Debug("Getting REST client info", "error", err)
+		h.credentialsFailed(ctx, mg, err)
 		return err
 	}
+	if isDataSource(clientInfo) {
+		return h.dataSourceNotFound(ctx, log, mg)
+	}
 
 	cli, err := restclient.BuildClient(ctx, h.dynamicClient, clientInfo.URL)
 	if err != nil {
@@ -264,7 +715,14 @@ func (h *handler) Create(ctx context.Context, mg *unstructured.Unstructured) err
 		return err
 	}
 	cli.Auth = clientInfo.Auth
+	applyServerURL(cli, clientInfo)
+	h.trackServer(ctx, cli, clientInfo)
+	defer h.reportCredentials(ctx, mg, clientInfo, cli)
+	trackCalls(ctx, cli)
 	cli.Verbose = meta.IsVerbose(mg)
+	cli.SpecFields = mg
+	cli.JSONAPI = clientInfo.Resource.JSONAPI
+	cli.Owner = h.owner(clientInfo, mg)
 
 	specFields, err := unstructuredtools.GetFieldsFromUnstructured(mg, "spec")
 	if err != nil {
@@ -276,32 +734,96 @@ func (h *handler) Create(ctx context.Context, mg *unstructured.Unstructured) err
 		log.Debug("Building API call", "error", err)
 		return err
 	}
-	reqConfiguration := BuildCallConfig(callInfo, nil, specFields)
-	body, err := apiCall(ctx, http.DefaultClient, callInfo.Path, reqConfiguration)
+	if callInfo != nil && callInfo.CheckBeforeCreate {
+		found, err := findBeforeCreate(ctx, log, cli, clientInfo, specFields)
+		if err != nil {
+			log.Debug("Searching external resource before creation", "error", err)
+			h.requeueOnRetryAfter(mg, cli, err)
+			return err
+		}
+		if found != nil {
+			log.Info("External resource found before creation, adopted", "kind", mg.GetKind(), "name", mg.GetName(), "namespace", mg.GetNamespace())
+			return h.adoptFound(ctx, mg, clientInfo, cli, found)
+		}
+	}
+	mg, err = h.createIdempotencyKey(ctx, mg, callInfo)
+	if err != nil {
+		log.Debug("Storing idempotency key", "error", err)
+		return err
+	}
+	stored := mg.GetAnnotations()
+	cli.SpecFields = mg
+	reqConfiguration, err := BuildCallConfig(callInfo, nil, specFields)
+	if err != nil {
+		log.Debug("Building call configuration", "error", err)
+		return err
+	}
+	applied := appliedBody(callInfo, reqConfiguration, specFields)
+	markOwnership(reqConfiguration, callInfo, cli.Owner)
+	body, err := apiCall(ctx, h.mutationClient(ctx, mg, apiaction.Create.String()), callInfo.Path, reqConfiguration)
 	if err != nil {
 		log.Debug("Performing REST call", "error", err)
+		h.requeueOnRetryAfter(mg, cli, err)
+		return err
+	}
+	created := lifecycle.Created
+	if h.requeueIfPendingStatus(clientInfo, mg, cli, reqConfiguration) {
+		created = lifecycle.CreateAccepted
+	}
+	if callInfo.VerifyAfterCreate {
+		body = verifyCreation(ctx, log, cli, clientInfo, mg, body, specFields)
+	}
+
+	_, err = populateAnnotations(clientInfo, mg, body)
+	if err != nil {
+		log.Debug("Updating annotations", "error", err)
+		return err
+	}
+	if body != nil && usesResponseLinks(clientInfo) {
+		_, err = setResponseLinks(mg, *body)
+		if err != nil {
+			log.Debug("Updating response links", "error", err)
+			return err
+		}
+	}
+
+	forgetIdempotencyKey(mg)
+	mg, err = h.storeAppliedBody(ctx, mg, stored, applied)
+	if err != nil {
+		log.Debug("Storing last applied body", "error", err)
 		return err
 	}
 
 	log.Debug("Creating external resource", "kind", mg.GetKind())
 
-	err = unstructuredtools.SetCondition(mg, condition.Creating())
+	_, err = h.transition(mg, created, "", "")
 	if err != nil {
 		log.Debug("Setting condition", "error", err)
 		return err
 	}
+	err = setObservedGeneration(mg)
+	if err != nil {
+		log.Debug("Setting observed generation", "error", err)
+		return err
+	}
+	err = h.clearProblems(mg)
+	if err != nil {
+		log.Debug("Clearing problem conditions", "error", err)
+		return err
+	}
+	err = h.setReadiness(mg)
+	if err != nil {
+		log.Debug("Setting readiness", "error", err)
+		return err
+	}
 
-	err = populateStatusFields(clientInfo, mg, body)
+	err = populateStatusFields(clientInfo, mg, body, cli.ResponseHeaders)
 	if err != nil {
 		log.Debug("Updating identifiers", "error", err)
 		return err
 	}
 
-	_, err = tools.UpdateStatus(ctx, mg, tools.UpdateOptions{
-
-		Pluralizer:    h.pluralizer,
-		DynamicClient: h.dynamicClient,
-	})
+	_, err = h.updateStatus(ctx, mg)
 	if err != nil {
 		log.Debug("Updating status", "error", err)
 		return err
@@ -310,8 +832,22 @@ func (h *handler) Create(ctx context.Context, mg *unstructured.Unstructured) err
 	return nil
 }
 
-func (h *handler) Update(ctx context.Context, mg *unstructured.Unstructured) error {
-	log := h.logger.WithValues("op", "Update").
+func (h *handler) Update(ctx context.Context, mg *unstructured.Unstructured) (err error) {
+	defer h.recoverPanic(ctx, mg, "update", &err)
+	ctx = provenance.WithReconcileID(ctx)
+	ctx = withStatusBase(ctx, mg)
+	ctx, sum := h.startSummary(ctx)
+	ctx, server := withServer(ctx)
+	err = h.attributeOutage(*server, h.withinBudget(sum, mg, func() error { return h.update(ctx, mg) }))
+	h.updateProblemConditions(ctx, mg, err)
+	h.summarize(sum, mg, "update", mutationResult(err), err)
+	return err
+}
+
+func (h *handler) update(ctx context.Context, mg *unstructured.Unstructured) error {
+	stored := mg.GetAnnotations()
+	restoreStatusOverflow(mg)
+	log := h.objectLogger(mg).WithValues("op", "Update").
 		WithValues("apiVersion", mg.GetAPIVersion()).
 		WithValues("kind", mg.GetKind()).
 		WithValues("name", mg.GetName()).
@@ -322,11 +858,21 @@ func (h *handler) Update(ctx context.Context, mg *unstructured.Unstructured) err
 		return fmt.Errorf("swagger info getter must be specified")
 	}
 
+	if ok, until := h.hotLoop.CoolingDown(objectKey(mg), time.Now()); ok {
+		log.Debug("Possible update loop detected, skipping update", "until", until.String())
+		return &updateLoopError{until: until}
+	}
+
 	clientInfo, err := h.swaggerInfoGetter.Get(mg)
 	if err != nil {
 		log.Debug("Getting REST client info", "error", err)
+		h.credentialsFailed(ctx, mg, err)
 		return err
 	}
+	if isDataSource(clientInfo) {
+		log.Debug("Data source, external resource not updated", "kind", mg.GetKind())
+		return nil
+	}
 
 	cli, err := restclient.BuildClient(ctx, h.dynamicClient, clientInfo.URL)
 	if err != nil {
@@ -334,58 +880,172 @@ func (h *handler) Update(ctx context.Context, mg *unstructured.Unstructured) err
 		return err
 	}
 	cli.Auth = clientInfo.Auth
+	applyServerURL(cli, clientInfo)
+	h.trackServer(ctx, cli, clientInfo)
+	defer h.reportCredentials(ctx, mg, clientInfo, cli)
+	trackCalls(ctx, cli)
 	cli.Verbose = meta.IsVerbose(mg)
+	cli.SpecFields = mg
+	cli.JSONAPI = clientInfo.Resource.JSONAPI
+	cli.Capabilities = h.capabilities
 
 	specFields, err := unstructuredtools.GetFieldsFromUnstructured(mg, "spec")
 	if err != nil {
 		log.Debug("Getting spec", "error", err)
 		return err
 	}
+	h.probeCapabilities(ctx, cli, clientInfo, mg)
 	apiCall, callInfo, err := APICallBuilder(cli, clientInfo, apiaction.Update)
 	if err != nil {
 		log.Debug("Building API call", "error", err)
 		return err
 	}
+	err = h.setMethodDowngrade(mg, callInfo)
+	if err != nil {
+		log.Debug("Setting condition", "error", err)
+		return err
+	}
+	if apiCall == nil {
+		log.Debug("No update allowed by the API, external resource not updated", "kind", mg.GetKind())
+		_, err = h.updateStatus(ctx, mg)
+		return err
+	}
 
 	statusFields, err := unstructuredtools.GetFieldsFromUnstructured(mg, "status")
 	if err == fmt.Errorf("%s not found", "status") {
 		log.Debug("External resource not created yet", "kind", mg.GetKind())
 		return err
 	}
-	reqConfiguration := BuildCallConfig(callInfo, statusFields, specFields)
-	body, err := apiCall(ctx, http.DefaultClient, callInfo.Path, reqConfiguration)
+	if callInfo.IdempotencyKeyHeader != "" {
+		callInfo.IdempotencyKey = h.updateKeys.attempt(mg)
+	}
+	reqConfiguration, err := BuildCallConfig(callInfo, statusFields, specFields)
+	if err != nil {
+		log.Debug("Building call configuration", "error", err)
+		return err
+	}
+	followResponseLink(mg, callInfo, reqConfiguration)
+
+	lastApplied, err := getLastAppliedBody(mg)
+	if err != nil {
+		log.Debug("Getting last applied body", "error", err)
+	}
+	preview, err := previewUpdate(lastApplied, appliedBody(callInfo, reqConfiguration, specFields))
+	if err != nil {
+		log.Debug("Computing update preview", "error", err)
+	} else {
+		log.Debug("Update preview", "preview", preview.String())
+		if mg.GetAnnotations()[AnnotationKeyStoreUpdatePreview] == "true" {
+			meta.AddAnnotations(mg, map[string]string{AnnotationKeyUpdatePreview: preview.String()})
+		}
+	}
+
+	lock := clientInfo.Resource.OptimisticLocking
+	applied := appliedBody(callInfo, reqConfiguration, specFields)
+	markOwnership(reqConfiguration, callInfo, h.owner(clientInfo, mg))
+	err = applyVersion(lock, mg, callInfo, reqConfiguration)
+	if err != nil {
+		log.Debug("Setting resource version", "error", err)
+		return err
+	}
+	body, err := apiCall(ctx, h.mutationClient(ctx, mg, apiaction.Update.String()), callInfo.Path, reqConfiguration)
+	for retry := 1; isConflict(lock, err) && retry <= conflictRetries(lock); retry++ {
+		log.Debug("External resource changed meanwhile, retrying update", "retry", retry)
+		err = refreshVersion(ctx, cli, clientInfo, mg, statusFields, specFields)
+		if err != nil {
+			log.Debug("Getting external resource", "error", err)
+			return err
+		}
+		statusFields, err = unstructuredtools.GetFieldsFromUnstructured(mg, "status")
+		if err != nil {
+			log.Debug("Getting status", "error", err)
+			return err
+		}
+		reqConfiguration, err = BuildCallConfig(callInfo, statusFields, specFields)
+		if err != nil {
+			log.Debug("Building call configuration", "error", err)
+			return err
+		}
+		followResponseLink(mg, callInfo, reqConfiguration)
+		applied = appliedBody(callInfo, reqConfiguration, specFields)
+		markOwnership(reqConfiguration, callInfo, h.owner(clientInfo, mg))
+		err = applyVersion(lock, mg, callInfo, reqConfiguration)
+		if err != nil {
+			log.Debug("Setting resource version", "error", err)
+			return err
+		}
+		body, err = apiCall(ctx, h.mutationClient(ctx, mg, apiaction.Update.String()), callInfo.Path, reqConfiguration)
+	}
 	if err != nil {
 		log.Debug("Performing REST call", "error", err)
+		h.requeueOnRetryAfter(mg, cli, err)
 		return err
 	}
+	h.updateKeys.forget(mg)
+	h.requeueIfPendingStatus(clientInfo, mg, cli, reqConfiguration)
+	setETag(lock, mg, cli.ResponseHeaders.Get("ETag"))
 
-	err = populateStatusFields(clientInfo, mg, body)
+	_, err = populateAnnotations(clientInfo, mg, body)
+	if err != nil {
+		log.Debug("Updating annotations", "error", err)
+		return err
+	}
+	if body != nil && usesResponseLinks(clientInfo) {
+		_, err = setResponseLinks(mg, *body)
+		if err != nil {
+			log.Debug("Updating response links", "error", err)
+			return err
+		}
+	}
+
+	mg, err = h.storeAppliedBody(ctx, mg, stored, applied)
+	if err != nil {
+		log.Debug("Storing last applied body", "error", err)
+		return err
+	}
+
+	if h.hotLoop.Record(objectKey(mg), time.Now()) {
+		msg := fmt.Sprintf("Resource updated %d times within %s, backing off for %s", h.hotLoop.Threshold, h.hotLoop.Window, h.hotLoop.Cooldown)
+		log.Debug("Possible update loop detected", "preview", preview.String())
+		h.recorder.Event(mg, event.Warning(reasonPossibleUpdateLoop, errors.New(msg)))
+		err = h.conditions.Set(mg, customcondition.PossibleUpdateLoop(msg))
+		if err != nil {
+			log.Debug("Setting condition", "error", err)
+			return err
+		}
+	}
+
+	err = populateStatusFields(clientInfo, mg, body, cli.ResponseHeaders)
 	if err != nil {
 		log.Debug("Updating identifiers", "error", err)
 		return err
 	}
 
-	log.Debug("Creating external resource", "kind", mg.GetKind())
+	updated, reason, message := updatedEvent(clientInfo, mg, body, cli.IsPending(reqConfiguration), uncomparedFields(cli, clientInfo))
+	log.Debug("External resource updated", "kind", mg.GetKind(), "event", updated, "reason", reason)
 
-	err = unstructuredtools.SetCondition(mg, condition.Creating())
+	_, err = h.transition(mg, updated, reason, message)
 	if err != nil {
 		log.Debug("Setting condition", "error", err)
 		return err
 	}
-
-	mg, err = tools.UpdateStatus(ctx, mg, tools.UpdateOptions{
-		Pluralizer:    h.pluralizer,
-		DynamicClient: h.dynamicClient,
-	})
+	err = setObservedGeneration(mg)
 	if err != nil {
-		log.Debug("Updating status", "error", err)
+		log.Debug("Setting observed generation", "error", err)
+		return err
+	}
+	err = h.clearProblems(mg)
+	if err != nil {
+		log.Debug("Clearing problem conditions", "error", err)
+		return err
+	}
+	err = h.setReadiness(mg)
+	if err != nil {
+		log.Debug("Setting readiness", "error", err)
 		return err
 	}
 
-	mg, err = tools.UpdateStatus(ctx, mg, tools.UpdateOptions{
-		Pluralizer:    h.pluralizer,
-		DynamicClient: h.dynamicClient,
-	})
+	mg, err = h.updateStatus(ctx, mg)
 	if err != nil {
 		log.Debug("Updating status", "error", err)
 		return err
@@ -396,8 +1056,29 @@ func (h *handler) Update(ctx context.Context, mg *unstructured.Unstructured) err
 
 }
 
-func (h *handler) Delete(ctx context.Context, mg *unstructured.Unstructured) error {
-	log := h.logger.WithValues("op", "Delete").
+func (h *handler) Delete(ctx context.Context, mg *unstructured.Unstructured) (err error) {
+	defer h.recoverPanic(ctx, mg, "delete", &err)
+	ctx = provenance.WithReconcileID(ctx)
+	ctx = withStatusBase(ctx, mg)
+	ctx, sum := h.startSummary(ctx)
+	ctx, server := withServer(ctx)
+	err = h.attributeOutage(*server, h.outsideBudget(sum, mg, func() error { return h.delete(ctx, mg) }))
+	if err != nil {
+		h.updateProblemConditions(ctx, mg, err)
+	} else {
+		// The secrets are still watched while the deletion is retried, so that it uses the rotated credentials
+		h.rotation.Untrack(mg)
+		h.budget.Forget(objectKey(mg))
+		h.updateKeys.forget(mg)
+	}
+	h.summarize(sum, mg, "delete", mutationResult(err), err)
+	return err
+}
+
+func (h *handler) delete(ctx context.Context, mg *unstructured.Unstructured) error {
+	restoreStatusOverflow(mg)
+	h.resync.forget(mg)
+	log := h.objectLogger(mg).WithValues("op", "Delete").
 		WithValues("apiVersion", mg.GetAPIVersion()).
 		WithValues("kind", mg.GetKind()).
 		WithValues("name", mg.GetName()).
@@ -412,8 +1093,14 @@ func (h *handler) Delete(ctx context.Context, mg *unstructured.Unstructured) err
 	clientInfo, err := h.swaggerInfoGetter.Get(mg)
 	if err != nil {
 		log.Debug("Getting REST client info", "error", err)
+		h.credentialsFailed(ctx, mg, err)
 		return err
 	}
+	if isDataSource(clientInfo) {
+		log.Debug("Data source, external resource not deleted", "kind", mg.GetKind())
+		h.identifiers.Delete(objectKey(mg))
+		return removeFinalizersAndUpdate(ctx, log, h.pluralizer, h.dynamicClient, mg)
+	}
 
 	cli, err := restclient.BuildClient(ctx, h.dynamicClient, clientInfo.URL)
 	if err != nil {
@@ -421,7 +1108,13 @@ func (h *handler) Delete(ctx context.Context, mg *unstructured.Unstructured) err
 		return err
 	}
 	cli.Auth = clientInfo.Auth
+	applyServerURL(cli, clientInfo)
+	h.trackServer(ctx, cli, clientInfo)
+	defer h.reportCredentials(ctx, mg, clientInfo, cli)
+	trackCalls(ctx, cli)
 	cli.Verbose = true
+	cli.SpecFields = mg
+	cli.JSONAPI = clientInfo.Resource.JSONAPI
 
 	specFields, err := unstructuredtools.GetFieldsFromUnstructured(mg, "spec")
 	if err != nil {
@@ -442,20 +1135,29 @@ func (h *handler) Delete(ctx context.Context, mg *unstructured.Unstructured) err
 		log.Debug("Building API call", "error", err)
 		return err
 	}
-	reqConfiguration := BuildCallConfig(callInfo, statusFields, specFields)
-	if reqConfiguration == nil {
-		return fmt.Errorf("error building call configuration")
+	reqConfiguration, err := BuildCallConfig(callInfo, statusFields, specFields)
+	if err != nil {
+		log.Debug("Building call configuration", "error", err)
+		return err
 	}
+	followResponseLink(mg, callInfo, reqConfiguration)
 
-	_, err = apiCall(ctx, http.DefaultClient, callInfo.Path, reqConfiguration)
+	_, err = apiCall(ctx, h.mutationClient(ctx, mg, apiaction.Delete.String()), callInfo.Path, reqConfiguration)
+	// Only the 403 answers taken as 404 tell the resource is already deleted, a 404 fails the deletion as usual
+	if notFound := notFoundIfForbidden(cli, callInfo, err); notFound != err {
+		log.Debug("External resource no longer accessible, taken as already deleted", "kind", mg.GetKind())
+		err = nil
+	}
 	if err != nil {
 		log.Debug("Performing REST call", "error", err)
+		h.requeueOnRetryAfter(mg, cli, err)
 		return err
 	}
+	h.identifiers.Delete(objectKey(mg))
 
 	log.Debug("Setting condition", "kind", mg.GetKind())
 
-	err = unstructuredtools.SetCondition(mg, condition.Deleting())
+	_, err = h.transition(mg, lifecycle.DeleteRequested, "", "")
 	if err != nil {
 		log.Debug("Setting condition", "error", err)
 		return err
@@ -0,0 +1,100 @@
+package restResources
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// resyncGate spreads the periodic observations of the resources over the resync interval: once a resource
+// is observed up-to-date, it is requeued after the interval plus or minus a random jitter, and the periodic
+// resyncs of the runtime delivered before then are skipped, avoiding bursts of calls against the external API.
+type resyncGate struct {
+	interval time.Duration
+	// jitter: the fraction of the interval the next observation is randomly moved by
+	jitter float64
+	random func() float64
+
+	mu      sync.Mutex
+	entries map[string]resyncEntry
+}
+
+type resyncEntry struct {
+	fingerprint string
+	next        time.Time
+}
+
+// newResyncGate returns the gate jittering the resync interval by the given percentage, nil if disabled.
+func newResyncGate(interval time.Duration, jitterPercent int) *resyncGate {
+	if interval <= 0 || jitterPercent <= 0 {
+		return nil
+	}
+	if jitterPercent > 100 {
+		jitterPercent = 100
+	}
+	return &resyncGate{
+		interval: interval,
+		jitter:   float64(jitterPercent) / 100,
+		random:   rand.Float64,
+		entries:  map[string]resyncEntry{},
+	}
+}
+
+// skip returns true if the resource is unchanged since it was last observed and its next observation is not due yet.
+func (g *resyncGate) skip(mg *unstructured.Unstructured) bool {
+	if g == nil || mg.GetDeletionTimestamp() != nil {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	entry, ok := g.entries[objectKey(mg)]
+	return ok && entry.fingerprint == resyncFingerprint(mg) && time.Now().Before(entry.next)
+}
+
+// observed records the resource observed up-to-date, returning the delay of its next observation.
+func (g *resyncGate) observed(mg *unstructured.Unstructured) (time.Duration, bool) {
+	if g == nil {
+		return 0, false
+	}
+	delay := time.Duration(float64(g.interval) * (1 + g.jitter*(2*g.random()-1)))
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.entries[objectKey(mg)] = resyncEntry{
+		fingerprint: resyncFingerprint(mg),
+		next:        time.Now().Add(delay),
+	}
+	return delay, true
+}
+
+// forget drops the resource, whose next event is always observed.
+func (g *resyncGate) forget(mg *unstructured.Unstructured) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.entries, objectKey(mg))
+}
+
+// resyncFingerprint identifies the changes of the resource requiring a new observation: its spec, through
+// the generation, and its annotations (e.g. the action triggers).
+func resyncFingerprint(mg *unstructured.Unstructured) string {
+	annotations := mg.GetAnnotations()
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d", mg.GetGeneration())
+	for _, key := range keys {
+		fmt.Fprintf(&sb, "\x00%s=%s", key, annotations[key])
+	}
+	return sb.String()
+}
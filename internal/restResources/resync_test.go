@@ -0,0 +1,147 @@
+package restResources
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/controller"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestResyncGate(t *testing.T) {
+	if newResyncGate(time.Minute, 0) != nil || newResyncGate(0, 20) != nil {
+		t.Fatalf("expected the gate to be disabled")
+	}
+
+	g := newResyncGate(time.Minute, 20)
+	mg := &unstructured.Unstructured{}
+	mg.SetAPIVersion("gen.github.com/v1alpha1")
+	mg.SetKind("Repo")
+	mg.SetName("repo1")
+	mg.SetGeneration(1)
+
+	if g.skip(mg) {
+		t.Errorf("expected a resource never observed not to be skipped")
+	}
+
+	for _, random := range []float64{0, 0.5, 1} {
+		g.random = func() float64 { return random }
+		delay, ok := g.observed(mg)
+		if !ok || delay < 48*time.Second || delay > 72*time.Second {
+			t.Errorf("expected the delay within 20%% of the interval, got %v", delay)
+		}
+	}
+	if !g.skip(mg) {
+		t.Errorf("expected the unchanged resource to be skipped")
+	}
+
+	changed := mg.DeepCopy()
+	changed.SetGeneration(2)
+	if g.skip(changed) {
+		t.Errorf("expected the resource with a changed spec not to be skipped")
+	}
+
+	triggered := mg.DeepCopy()
+	triggered.SetAnnotations(map[string]string{"krateo.io/trigger-restart": "1"})
+	if g.skip(triggered) {
+		t.Errorf("expected the resource with changed annotations not to be skipped")
+	}
+
+	deleting := mg.DeepCopy()
+	now := metav1.Now()
+	deleting.SetDeletionTimestamp(&now)
+	if g.skip(deleting) {
+		t.Errorf("expected the resource being deleted not to be skipped")
+	}
+
+	g.forget(mg)
+	if g.skip(mg) {
+		t.Errorf("expected the forgotten resource not to be skipped")
+	}
+}
+
+// observeCounter counts the observations of the handler it wraps.
+type observeCounter struct {
+	*handler
+
+	mu    sync.Mutex
+	count int
+}
+
+func (c *observeCounter) Observe(ctx context.Context, mg *unstructured.Unstructured) (controller.ExternalObservation, error) {
+	c.mu.Lock()
+	c.count++
+	c.mu.Unlock()
+	return c.handler.Observe(ctx, mg)
+}
+
+func (c *observeCounter) observations() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.count
+}
+
+func TestResyncGateRequeues(t *testing.T) {
+	get := getter.VerbsDescription{Action: "get", Method: "GET", Path: "/repos/{id}"}
+	mg := observedResource()
+	h, _ := observedHandler(t, getter.Resource{Identifiers: []string{"id"}, VerbsDescription: []getter.VerbsDescription{get}}, mg)
+	h.resync = newResyncGate(100*time.Millisecond, 50)
+
+	obs, err := h.Observe(context.Background(), mg)
+	if err != nil || !obs.ResourceExists || !obs.ResourceUpToDate {
+		t.Fatalf("expected the resource to be up-to-date, got %+v (%v)", obs, err)
+	}
+	// The resync of the runtime before the jittered time is skipped
+	if !h.resync.skip(mg) {
+		t.Fatalf("expected the next resync to be skipped")
+	}
+
+	counter := &observeCounter{handler: h}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go h.requeue.Run(ctx, counter)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for counter.observations() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if counter.observations() == 0 {
+		t.Errorf("expected the resource to be observed again at its jittered time")
+	}
+}
+
+// TestSecretRotatedBeforeResync rotates the credentials of a resource whose next observation is an hour away:
+// the resource is observed again right away.
+func TestSecretRotatedBeforeResync(t *testing.T) {
+	get := getter.VerbsDescription{Action: "get", Method: "GET", Path: "/repos/{id}"}
+	mg := observedResource()
+	h, _ := observedHandler(t, getter.Resource{Identifiers: []string{"id"}, VerbsDescription: []getter.VerbsDescription{get}}, mg)
+	h.resync = newResyncGate(time.Hour, 20)
+
+	obs, err := h.Observe(context.Background(), mg)
+	if err != nil || !obs.ResourceExists || !obs.ResourceUpToDate {
+		t.Fatalf("expected the resource to be up-to-date, got %+v (%v)", obs, err)
+	}
+
+	counter := &observeCounter{handler: h}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go h.requeue.Run(ctx, counter)
+
+	h.secretRotated(mg, getter.AuthRef{Namespace: mg.GetNamespace(), Name: "token"})
+	if h.resync.skip(mg) {
+		t.Errorf("expected the next event of the resource not to be skipped")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for counter.observations() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if counter.observations() == 0 {
+		t.Errorf("expected the resource to be observed before its resync")
+	}
+}
@@ -0,0 +1,68 @@
+package restResources
+
+import (
+	"sort"
+	"strings"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// sparseFieldset returns the fields requested to the API when observing the resource: the configured ones,
+// the identifiers, the spec and last applied fields compared for drift and the fields read from the response.
+func sparseFieldset(cli *restclient.UnstructuredClient, clientInfo *getter.Info, sparse *getter.SparseFields, mg *unstructured.Unstructured) []string {
+	fields := text.NewStringSet(sparse.Fields...)
+	for _, identifier := range clientInfo.Resource.Identifiers {
+		fields.Add(identifier)
+	}
+
+	spec, _, _ := unstructured.NestedMap(mg.Object, "spec")
+	for k := range spec {
+		fields.Add(k)
+	}
+	last, _ := getLastAppliedBody(mg)
+	for k := range last {
+		fields.Add(k)
+	}
+	for _, path := range clientInfo.Resource.AnnotationsFromResponse {
+		fields.Add(strings.Split(path, ".")[0])
+	}
+	if lock := clientInfo.Resource.OptimisticLocking; lock != nil && lock.VersionField != "" {
+		fields.Add(lock.VersionField)
+	}
+	if usesResponseLinks(clientInfo) {
+		fields.Add("_links")
+	}
+	if clientInfo.Resource.LateInitialize {
+		for k := range lateInitFields(cli, clientInfo) {
+			fields.Add(k)
+		}
+	}
+	// The authentication references are not fields of the external resource
+	fields.Remove("authenticationRefs")
+
+	res := make([]string, 0, len(fields))
+	for k := range fields {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// applySparseFields sets the sparse fieldset query parameter of the request, if the verb supports it.
+func applySparseFields(cli *restclient.UnstructuredClient, clientInfo *getter.Info, callInfo *CallInfo, mg *unstructured.Unstructured, conf *restclient.RequestConfiguration) {
+	sparse := callInfo.SparseFields
+	if sparse == nil || sparse.Param == "" {
+		return
+	}
+	sep := sparse.Separator
+	if sep == "" {
+		sep = ","
+	}
+	if conf.Query == nil {
+		conf.Query = map[string]string{}
+	}
+	conf.Query[sparse.Param] = strings.Join(sparseFieldset(cli, clientInfo, sparse, mg), sep)
+}
@@ -0,0 +1,40 @@
+package restResources
+
+import (
+	"testing"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestApplySparseFields(t *testing.T) {
+	mg := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"name":               "repo",
+			"description":        "test",
+			"authenticationRefs": map[string]interface{}{"bearerAuthRef": "gh"},
+		},
+	}}
+	if err := setLastAppliedBody(mg, map[string]interface{}{"name": "repo", "homepage": "x"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clientInfo := &getter.Info{Resource: getter.Resource{
+		Identifiers:             []string{"id"},
+		AnnotationsFromResponse: map[string]string{"krateo.io/web-url": "links.html"},
+	}}
+	callInfo := &CallInfo{SparseFields: &getter.SparseFields{Param: "fields", Fields: []string{"status"}}}
+
+	conf := &restclient.RequestConfiguration{}
+	applySparseFields(&restclient.UnstructuredClient{}, clientInfo, callInfo, mg, conf)
+	expected := "description,homepage,id,links,name,status"
+	if conf.Query["fields"] != expected {
+		t.Errorf("expected %q, got %q", expected, conf.Query["fields"])
+	}
+
+	conf = &restclient.RequestConfiguration{}
+	applySparseFields(&restclient.UnstructuredClient{}, clientInfo, &CallInfo{}, mg, conf)
+	if len(conf.Query) != 0 {
+		t.Errorf("expected no sparse fieldset, got %v", conf.Query)
+	}
+}
@@ -0,0 +1,207 @@
+package restResources
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/tools"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+)
+
+type statusBaseKey struct{}
+
+// statusBase is the status the reconcile started from, advanced to the status it writes.
+type statusBase struct {
+	status map[string]interface{}
+}
+
+// withStatusBase returns the context of a reconcile recording the status of the resource it starts from,
+// so that a status update conflicting with another writer re-applies only the changes made by the reconcile.
+func withStatusBase(ctx context.Context, mg *unstructured.Unstructured) context.Context {
+	base := mg.DeepCopy()
+	restoreStatusOverflow(base)
+	status, _, _ := unstructured.NestedMap(base.Object, "status")
+	return context.WithValue(ctx, statusBaseKey{}, &statusBase{status: status})
+}
+
+// updateStatus updates the status of the resource. On conflicts (e.g. the resource changed during the reconcile),
+// the changes made by the reconcile to the status it started from are applied again on the latest version of the
+// resource, keeping the ones made meanwhile by other writers; without the status the reconcile started from,
+// the whole status is applied again.
+func (h *handler) updateStatus(ctx context.Context, mg *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	status, _, _ := unstructured.NestedMap(mg.Object, "status")
+	_, hasStatus := mg.Object["status"]
+	base, hasBase := ctx.Value(statusBaseKey{}).(*statusBase)
+
+	var res *unstructured.Unstructured
+	attempt := mg
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var err error
+		res, err = h.writeStatus(ctx, attempt)
+		if err == nil || !apierrors.IsConflict(err) {
+			return err
+		}
+		h.objectLogger(mg).Debug("Conflict updating status, retrying on the latest version", "name", mg.GetName(), "namespace", mg.GetNamespace())
+		latest, gerr := h.getLatest(ctx, mg)
+		if gerr != nil {
+			return gerr
+		}
+		restoreStatusOverflow(latest)
+		if hasBase {
+			latestStatus, _, _ := unstructured.NestedMap(latest.Object, "status")
+			latest.Object["status"] = applyStatusChanges(latestStatus, base.status, status)
+		} else if hasStatus {
+			latest.Object["status"] = runtime.DeepCopyJSONValue(status)
+		} else {
+			delete(latest.Object, "status")
+		}
+		attempt = latest
+		return err
+	})
+	if err == nil && hasBase && res != nil {
+		base.status, _, _ = unstructured.NestedMap(res.Object, "status")
+	}
+	return res, err
+}
+
+// applyStatusChanges applies to the latest status the changes from the base status to the current one: the fields
+// set or changed are set, the fields removed are removed, the objects being merged field by field and the
+// conditions condition by condition. The other fields of the latest status are kept.
+func applyStatusChanges(latest, base, current map[string]interface{}) map[string]interface{} {
+	if latest == nil {
+		latest = map[string]interface{}{}
+	}
+	for k, v := range current {
+		old, found := base[k]
+		if found && reflect.DeepEqual(old, v) {
+			continue
+		}
+		cur, isMap := v.(map[string]interface{})
+		oldMap, wasMap := old.(map[string]interface{})
+		latestMap, latestIsMap := latest[k].(map[string]interface{})
+		switch {
+		case isMap && wasMap && latestIsMap:
+			latest[k] = applyStatusChanges(latestMap, oldMap, cur)
+		case k == "conditions":
+			latest[k] = applyConditionChanges(latest[k], old, v)
+		default:
+			latest[k] = runtime.DeepCopyJSONValue(v)
+		}
+	}
+	for k := range base {
+		if _, found := current[k]; !found {
+			delete(latest, k)
+		}
+	}
+	return latest
+}
+
+// applyConditionChanges applies to the latest conditions the conditions set, changed or removed
+// from the base conditions to the current ones, keeping the other latest conditions.
+func applyConditionChanges(latest, base, current interface{}) interface{} {
+	latestConds, ok := latest.([]interface{})
+	baseConds, _ := base.([]interface{})
+	currentConds, _ := current.([]interface{})
+	if !ok {
+		return runtime.DeepCopyJSONValue(current)
+	}
+
+	byType := func(conds []interface{}) map[string]interface{} {
+		m := map[string]interface{}{}
+		for _, c := range conds {
+			if cond, ok := c.(map[string]interface{}); ok {
+				if t, ok := cond["type"].(string); ok {
+					m[t] = cond
+				}
+			}
+		}
+		return m
+	}
+	baseByType, currentByType := byType(baseConds), byType(currentConds)
+
+	res := make([]interface{}, 0, len(latestConds)+len(currentConds))
+	seen := map[string]bool{}
+	for _, c := range latestConds {
+		cond, _ := c.(map[string]interface{})
+		t, _ := cond["type"].(string)
+		cur, inCurrent := currentByType[t]
+		old, inBase := baseByType[t]
+		switch {
+		case cond == nil || t == "":
+			res = append(res, c)
+		case inCurrent && !(inBase && reflect.DeepEqual(old, cur)):
+			res = append(res, runtime.DeepCopyJSONValue(cur))
+		case inBase && !inCurrent:
+			// Removed by the reconcile
+		default:
+			res = append(res, c)
+		}
+		seen[t] = true
+	}
+	for _, c := range currentConds {
+		cond, _ := c.(map[string]interface{})
+		t, _ := cond["type"].(string)
+		if t == "" || seen[t] {
+			continue
+		}
+		if old, inBase := baseByType[t]; inBase && reflect.DeepEqual(old, cond) {
+			// Removed meanwhile by another writer
+			continue
+		}
+		res = append(res, runtime.DeepCopyJSONValue(c))
+	}
+	return res
+}
+
+// updateLatestStatus applies the mutation to the status of the latest version of the resource and updates it,
+// applying the mutation again on conflicts.
+func (h *handler) updateLatestStatus(ctx context.Context, mg *unstructured.Unstructured, mutate func(latest *unstructured.Unstructured) error) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := h.getLatest(ctx, mg)
+		if err != nil {
+			return err
+		}
+		restoreStatusOverflow(latest)
+		if err := mutate(latest); err != nil {
+			return err
+		}
+		_, err = h.writeStatus(ctx, latest)
+		return err
+	})
+}
+
+// updateSpec updates the metadata and the spec of the resource, keeping the status set in memory
+// to be written by the status update.
+func (h *handler) updateSpec(ctx context.Context, mg *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	status, hasStatus := mg.Object["status"]
+	res, err := tools.Update(ctx, mg, tools.UpdateOptions{
+		Pluralizer:    h.pluralizer,
+		DynamicClient: h.dynamicClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if hasStatus {
+		res.Object["status"] = status
+	} else {
+		delete(res.Object, "status")
+	}
+	return res, nil
+}
+
+// getLatest returns the latest version of the resource from the API server.
+func (h *handler) getLatest(ctx context.Context, mg *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	if h.dynamicClient == nil {
+		return nil, fmt.Errorf("dynamic client is nil")
+	}
+	gvr, err := h.pluralizer.GVKtoGVR(mg.GroupVersionKind())
+	if err != nil {
+		return nil, err
+	}
+	return h.dynamicClient.Resource(gvr).Namespace(mg.GetNamespace()).Get(ctx, mg.GetName(), metav1.GetOptions{})
+}
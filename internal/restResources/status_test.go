@@ -0,0 +1,185 @@
+package restResources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	customcondition "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/condition"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/pluralizer"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+var reposGVR = schema.GroupVersionResource{Group: "gen.github.com", Version: "v1alpha1", Resource: "repos"}
+
+// conflictingHandler returns a handler whose first status updates fail with a conflict.
+func conflictingHandler(t *testing.T, conflicts int) (*handler, *fake.FakeDynamicClient, *int) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"plural":"repos","singular":"repo"}`)
+	}))
+	t.Cleanup(srv.Close)
+	url := srv.URL
+
+	dyn := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		reposGVR: "RepoList",
+	}, summaryResource())
+	attempts := 0
+	dyn.PrependReactor("update", "repos", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "status" {
+			return false, nil, nil
+		}
+		attempts++
+		if attempts <= conflicts {
+			return true, nil, apierrors.NewConflict(reposGVR.GroupResource(), "repo1", fmt.Errorf("the object has been modified"))
+		}
+		return false, nil, nil
+	})
+
+	h := &handler{
+		logger:        logging.NewNopLogger(),
+		dynamicClient: dyn,
+		pluralizer:    *pluralizer.New(&url, srv.Client()),
+	}
+	return h, dyn, &attempts
+}
+
+func TestUpdateStatusRetriesOnConflict(t *testing.T) {
+	h, dyn, attempts := conflictingHandler(t, 2)
+
+	mg := summaryResource()
+	if err := unstructured.SetNestedField(mg.Object, "42", "status", "id"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The resource changed since it was read
+	latest, _ := dyn.Resource(reposGVR).Namespace("default").Get(context.Background(), "repo1", metav1.GetOptions{})
+	latest.SetLabels(map[string]string{"team": "platform"})
+	if _, err := dyn.Resource(reposGVR).Namespace("default").Update(context.Background(), latest, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res, err := h.updateStatus(context.Background(), mg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", *attempts)
+	}
+	if id, _, _ := unstructured.NestedString(res.Object, "status", "id"); id != "42" {
+		t.Errorf("expected the status to be applied, got %q", id)
+	}
+	if res.GetLabels()["team"] != "platform" {
+		t.Errorf("expected the latest version of the resource to be updated, got labels %v", res.GetLabels())
+	}
+}
+
+func TestUpdateStatusKeepsOtherWriters(t *testing.T) {
+	h, dyn, _ := conflictingHandler(t, 1)
+
+	mg := summaryResource()
+	_ = unstructured.SetNestedField(mg.Object, "old", "status", "state")
+	_ = unstructured.SetNestedField(mg.Object, "gone", "status", "stale")
+	ctx := withStatusBase(context.Background(), mg)
+
+	// Another writer updates the status while the reconcile runs
+	latest, _ := dyn.Resource(reposGVR).Namespace("default").Get(context.Background(), "repo1", metav1.GetOptions{})
+	latest.Object["status"] = map[string]interface{}{
+		"state": "old",
+		"stale": "gone",
+		"other": "written meanwhile",
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "Foreign", "status": "True"},
+		},
+	}
+	if _, err := dyn.Resource(reposGVR).Namespace("default").Update(context.Background(), latest, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = unstructured.SetNestedField(mg.Object, "new", "status", "state")
+	unstructured.RemoveNestedField(mg.Object, "status", "stale")
+	_ = unstructured.SetNestedSlice(mg.Object, []interface{}{
+		map[string]interface{}{"type": "Ready", "status": "True"},
+	}, "status", "conditions")
+
+	res, err := h.updateStatus(ctx, mg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	status, _, _ := unstructured.NestedMap(res.Object, "status")
+	if status["state"] != "new" || status["other"] != "written meanwhile" {
+		t.Errorf("expected the changes of both writers, got %v", status)
+	}
+	if _, found := status["stale"]; found {
+		t.Errorf("expected the field removed by the reconcile to be removed, got %v", status)
+	}
+	conds, _ := status["conditions"].([]interface{})
+	if len(conds) != 2 {
+		t.Errorf("expected the conditions of both writers, got %v", conds)
+	}
+}
+
+func TestApplyStatusChanges(t *testing.T) {
+	base := map[string]interface{}{
+		"id":  "42",
+		"obj": map[string]interface{}{"a": "1", "b": "1"},
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "Ready", "status": "False"},
+			map[string]interface{}{"type": "Synced", "status": "True"},
+		},
+	}
+	current := map[string]interface{}{
+		"id":  "42",
+		"obj": map[string]interface{}{"a": "2", "b": "1"},
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "Ready", "status": "True"},
+		},
+	}
+	latest := map[string]interface{}{
+		"id":  "43",
+		"obj": map[string]interface{}{"a": "1", "b": "3"},
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "Ready", "status": "False"},
+			map[string]interface{}{"type": "Synced", "status": "True"},
+			map[string]interface{}{"type": "Foreign", "status": "True"},
+		},
+	}
+
+	got := applyStatusChanges(latest, base, current)
+	expected := map[string]interface{}{
+		"id":  "43",
+		"obj": map[string]interface{}{"a": "2", "b": "3"},
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "Ready", "status": "True"},
+			map[string]interface{}{"type": "Foreign", "status": "True"},
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestSetConditionsRetriesOnConflict(t *testing.T) {
+	h, dyn, attempts := conflictingHandler(t, 1)
+
+	err := h.setConditions(context.Background(), summaryResource(), customcondition.RateLimited("unexpected status: 429:"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", *attempts)
+	}
+	latest, _ := dyn.Resource(reposGVR).Namespace("default").Get(context.Background(), "repo1", metav1.GetOptions{})
+	if !h.isConditionTrue(latest, customcondition.TypeRateLimited) {
+		t.Errorf("expected the condition to be set, got %v", latest.Object["status"])
+	}
+}
@@ -0,0 +1,117 @@
+package restResources
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/controller"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// SummaryLevel selects the reconciles summarized at Info level, one line each with the action taken, its result,
+// its duration and the number of HTTP calls made, so that the reconciles can be followed without the debug logs.
+type SummaryLevel string
+
+const (
+	// SummaryNone disables the summaries
+	SummaryNone SummaryLevel = "none"
+	// SummaryChanges summarizes the creations, updates and deletions of the external resources and the failed observations
+	SummaryChanges SummaryLevel = "changes"
+	// SummaryAll also summarizes the successful observations
+	SummaryAll SummaryLevel = "all"
+)
+
+// ParseSummaryLevel returns the summary level with the given name, case-insensitive.
+func ParseSummaryLevel(name string) (SummaryLevel, error) {
+	switch level := SummaryLevel(strings.ToLower(strings.TrimSpace(name))); level {
+	case SummaryNone, SummaryChanges, SummaryAll:
+		return level, nil
+	case "":
+		return SummaryNone, nil
+	default:
+		return SummaryNone, fmt.Errorf("unknown summary level %q (expected one of %s, %s, %s)", name, SummaryNone, SummaryChanges, SummaryAll)
+	}
+}
+
+type summaryKey struct{}
+
+// reconcileSummary collects the clients used by a reconcile, to count the HTTP calls they made.
+type reconcileSummary struct {
+	start   time.Time
+	clients []*restclient.UnstructuredClient
+}
+
+// startSummary returns the context collecting the summary of the reconcile, nil if neither the summaries
+// nor the call budget are enabled.
+func (h *handler) startSummary(ctx context.Context) (context.Context, *reconcileSummary) {
+	if (h.summaries == "" || h.summaries == SummaryNone) && h.budget == nil {
+		return ctx, nil
+	}
+	sum := &reconcileSummary{start: time.Now()}
+	return context.WithValue(ctx, summaryKey{}, sum), sum
+}
+
+// trackCalls counts the HTTP calls of the client in the summary of the reconcile, if any.
+func trackCalls(ctx context.Context, cli *restclient.UnstructuredClient) {
+	sum, ok := ctx.Value(summaryKey{}).(*reconcileSummary)
+	if !ok || sum == nil || cli == nil {
+		return
+	}
+	sum.clients = append(sum.clients, cli)
+}
+
+func (s *reconcileSummary) calls() int {
+	calls := 0
+	for _, cli := range s.clients {
+		calls += cli.RequestCount
+	}
+	return calls
+}
+
+// summarize logs the summary of the reconcile of the resource at Info level, if its level allows it.
+func (h *handler) summarize(sum *reconcileSummary, mg *unstructured.Unstructured, action, result string, err error) {
+	if sum == nil || h.summaries == "" || h.summaries == SummaryNone {
+		return
+	}
+	if action == "observe" && err == nil && h.summaries != SummaryAll {
+		return
+	}
+	kv := []any{
+		"kind", mg.GetKind(),
+		"name", mg.GetName(),
+		"namespace", mg.GetNamespace(),
+		"action", action,
+		"result", result,
+		"duration", time.Since(sum.start).Round(time.Millisecond).String(),
+		"httpCalls", sum.calls(),
+	}
+	if err != nil {
+		kv = append(kv, "error", err.Error())
+	}
+	h.logger.Info("Reconcile summary", kv...)
+}
+
+// observationResult describes the outcome of the observation of the resource.
+func observationResult(obs controller.ExternalObservation, err error) string {
+	switch {
+	case err != nil:
+		return "failed"
+	case !obs.ResourceExists:
+		return "not-found"
+	case !obs.ResourceUpToDate:
+		return "out-of-date"
+	default:
+		return "up-to-date"
+	}
+}
+
+// mutationResult describes the outcome of the creation, update or deletion of the resource.
+func mutationResult(err error) string {
+	if err != nil {
+		return "failed"
+	}
+	return "succeeded"
+}
@@ -0,0 +1,98 @@
+package restResources
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/controller"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type infoRecorder struct {
+	logging.Logger
+	messages []map[string]any
+}
+
+func (r *infoRecorder) Info(msg string, keysAndValues ...any) {
+	fields := map[string]any{"msg": msg}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		fields[keysAndValues[i].(string)] = keysAndValues[i+1]
+	}
+	r.messages = append(r.messages, fields)
+}
+
+func (r *infoRecorder) WithValues(...any) logging.Logger {
+	return r
+}
+
+func summaryResource() *unstructured.Unstructured {
+	mg := &unstructured.Unstructured{}
+	mg.SetAPIVersion("gen.github.com/v1alpha1")
+	mg.SetKind("Repo")
+	mg.SetNamespace("default")
+	mg.SetName("repo1")
+	return mg
+}
+
+func TestSummarize(t *testing.T) {
+	rec := &infoRecorder{Logger: logging.NewNopLogger()}
+	h := &handler{logger: rec, summaries: SummaryChanges}
+
+	ctx, sum := h.startSummary(context.Background())
+	trackCalls(ctx, &restclient.UnstructuredClient{RequestCount: 2})
+	trackCalls(ctx, &restclient.UnstructuredClient{RequestCount: 1})
+	h.summarize(sum, summaryResource(), "update", mutationResult(nil), nil)
+
+	if len(rec.messages) != 1 {
+		t.Fatalf("expected 1 summary, got %d", len(rec.messages))
+	}
+	got := rec.messages[0]
+	if got["kind"] != "Repo" || got["name"] != "repo1" || got["action"] != "update" || got["result"] != "succeeded" {
+		t.Errorf("unexpected summary: %v", got)
+	}
+	if got["httpCalls"] != 3 {
+		t.Errorf("expected 3 http calls, got %v", got["httpCalls"])
+	}
+	if _, ok := got["duration"]; !ok {
+		t.Errorf("expected the duration in the summary: %v", got)
+	}
+}
+
+func TestSummarizeLevels(t *testing.T) {
+	upToDate := controller.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}
+	tests := []struct {
+		level    SummaryLevel
+		obsErr   error
+		expected int
+	}{
+		{level: SummaryNone, expected: 0},
+		{level: SummaryNone, obsErr: errors.New("boom"), expected: 0},
+		{level: SummaryChanges, expected: 0},
+		{level: SummaryChanges, obsErr: errors.New("boom"), expected: 1},
+		{level: SummaryAll, expected: 1},
+	}
+	for _, tt := range tests {
+		rec := &infoRecorder{Logger: logging.NewNopLogger()}
+		h := &handler{logger: rec, summaries: tt.level}
+
+		_, sum := h.startSummary(context.Background())
+		h.summarize(sum, summaryResource(), "observe", observationResult(upToDate, tt.obsErr), tt.obsErr)
+		if len(rec.messages) != tt.expected {
+			t.Errorf("%s (error %v): expected %d summaries, got %d", tt.level, tt.obsErr, tt.expected, len(rec.messages))
+		}
+	}
+}
+
+func TestParseSummaryLevel(t *testing.T) {
+	for name, expected := range map[string]SummaryLevel{"": SummaryNone, "All": SummaryAll, " changes ": SummaryChanges} {
+		if got, err := ParseSummaryLevel(name); err != nil || got != expected {
+			t.Errorf("%q: expected %s, got %s (%v)", name, expected, got, err)
+		}
+	}
+	if _, err := ParseSummaryLevel("verbose"); err == nil {
+		t.Errorf("expected an error for an unknown level")
+	}
+}
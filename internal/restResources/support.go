@@ -2,19 +2,26 @@ package restResources
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
+	"math/big"
 	"net/http"
+	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
 
 	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
 	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
 	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/apiaction"
 	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/template"
 	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
 	"github.com/krateoplatformops/unstructured-runtime/pkg/pluralizer"
 	"github.com/krateoplatformops/unstructured-runtime/pkg/tools"
 	unstructuredtools "github.com/krateoplatformops/unstructured-runtime/pkg/tools/unstructured"
+	"github.com/lucasepe/httplib"
 	"github.com/rs/zerolog/log"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/dynamic"
@@ -28,8 +35,44 @@ type RequestedParams struct {
 
 type CallInfo struct {
 	Path             string
+	Method           string
+	RawMethod        bool
 	ReqParams        *RequestedParams
 	IdentifierFields []string
+	FieldMapping     []getter.RequestFieldMapping
+	BodyTemplate     *getter.BodyTemplate
+	BodyRootPath     string
+	// ItemsPath is the dot separated path of the items of the collection searched by the findby action
+	ItemsPath string
+	// NotFoundAsEmpty takes a 404 answer listing the collection searched by the findby action as an empty collection
+	NotFoundAsEmpty bool
+	// ForbiddenAsNotFound takes a 403 answer to the call as a 404
+	ForbiddenAsNotFound bool
+	// StatusCodes overrides the outcome of the response status codes described by the OAS
+	StatusCodes []restclient.StatusCode
+	// VerifyAfterCreate gets the resource by the identifiers of the create response right after its creation
+	VerifyAfterCreate bool
+	// CheckBeforeCreate searches the resource with the findby action right before creating it, adopting it if found
+	CheckBeforeCreate bool
+	// LinkRelations are the HAL link relations followed by the call, in order of preference
+	LinkRelations []string
+	// SparseFields is the sparse fieldset requested by the call, if supported
+	SparseFields *getter.SparseFields
+	// NullFields are the null policies of the fields
+	NullFields map[string]getter.NullPolicy
+	// Coercions are the types the API expects for the fields
+	Coercions map[string]getter.Coercion
+	// Precedence tells which source wins when several ones provide the value of the same parameter or body field
+	Precedence *getter.Precedence
+	// OmitFields are the dot separated paths of the body fields never sent by the call (e.g. the create-only ones on update)
+	OmitFields []string
+	// Downgrade tells the method of the verb the API does not allow despite the OAS and its fallback, empty if allowed
+	Downgrade string
+	// IdempotencyKeyHeader is the header carrying the IdempotencyKey of the call, empty if none
+	IdempotencyKeyHeader string
+	// IdempotencyKey is the idempotency key of the call, derived from the UID and the generation of the CR
+	// (random for the creations and the updates, see createIdempotencyKey and updateKeys)
+	IdempotencyKey string
 }
 
 type APIFuncDef func(ctx context.Context, cli *http.Client, path string, conf *restclient.RequestConfiguration) (*map[string]interface{}, error)
@@ -39,72 +82,344 @@ func APICallBuilder(cli *restclient.UnstructuredClient, info *getter.Info, actio
 	identifierFields := info.Resource.Identifiers
 	for _, descr := range info.Resource.VerbsDescription {
 		if strings.EqualFold(descr.Action, action.String()) {
-			method, err := restclient.StringToApiCallType(descr.Method)
-			if action == apiaction.FindBy {
-				method = restclient.APICallsTypeFindBy
-			}
-			if err != nil {
-				return nil, nil, fmt.Errorf("error converting method to api call type: %s", err)
+			if descr.Condition != "" {
+				ok, err := template.EvalBool(descr.Condition, crFields(cli.SpecFields))
+				if err != nil {
+					return nil, nil, fmt.Errorf("error evaluating condition of action %s: %s", action, err)
+				}
+				if !ok {
+					continue
+				}
 			}
-			params, query, err := cli.RequestedParams(descr.Method, descr.Path)
-			if err != nil {
-				return nil, nil, fmt.Errorf("error retrieving requested params: %s", err)
+			var relations []string
+			if descr.UseResponseLinks {
+				relations = linkRelations(action)
 			}
-			var body text.StringSet
-			if descr.Method == "POST" || descr.Method == "PUT" || descr.Method == "PATCH" {
-				body, err = cli.RequestedBody(descr.Method, descr.Path)
+			var (
+				call      APIFuncDef
+				reqParams *RequestedParams
+				downgrade string
+			)
+			if descr.RawMethod {
+				call = cli.Raw(descr.Method)
+				reqParams = &RequestedParams{
+					Parameters: restclient.PathParams(descr.Path),
+					Query:      text.StringSet{},
+					Body:       text.StringSet{},
+				}
+			} else {
+				if action == apiaction.Update && !cli.Allows(descr.Method, descr.Path) {
+					fallback := updateFallback(cli, descr.Method, descr.Path)
+					if fallback == "" {
+						downgrade = fmt.Sprintf("%s %s not allowed by the API, update skipped", descr.Method, descr.Path)
+						return nil, &CallInfo{Path: descr.Path, Method: descr.Method, Downgrade: downgrade}, nil
+					}
+					downgrade = fmt.Sprintf("%s %s not allowed by the API, updated with %s", descr.Method, descr.Path, fallback)
+					descr.Method = fallback
+				}
+				method, err := restclient.StringToApiCallType(descr.Method)
+				if action == apiaction.FindBy {
+					method = restclient.APICallsTypeFindBy
+				}
 				if err != nil {
-					return nil, nil, fmt.Errorf("error retrieving requested body params: %s", err)
+					return nil, nil, fmt.Errorf("error converting method to api call type: %s", err)
 				}
-				if body == nil {
-					body = text.StringSet{}
+				params, query, err := cli.RequestedParams(descr.Method, descr.Path)
+				if err != nil {
+					return nil, nil, fmt.Errorf("error retrieving requested params: %s", err)
 				}
-			}
-
-			callInfo := &CallInfo{
-				Path: descr.Path,
-				ReqParams: &RequestedParams{
+				var body text.StringSet
+				if descr.Method == "POST" || descr.Method == "PUT" || descr.Method == "PATCH" || descr.Method == "DELETE" {
+					body, err = cli.RequestedBody(descr.Method, descr.Path)
+					if err != nil {
+						return nil, nil, fmt.Errorf("error retrieving requested body params: %s", err)
+					}
+					if body == nil {
+						body = text.StringSet{}
+					}
+					body = filterBody(body, descr.IncludeFields, descr.ExcludeFields)
+				}
+				reqParams = &RequestedParams{
 					Parameters: params,
 					Query:      query,
 					Body:       body,
-				},
-				IdentifierFields: identifierFields,
+				}
+
+				switch method {
+				case restclient.APICallsTypeGet:
+					call = cli.Get
+				case restclient.APICallsTypePost:
+					call = cli.Post
+				case restclient.APICallsTypeList:
+					call = cli.List
+				case restclient.APICallsTypeDelete:
+					call = cli.Delete
+				case restclient.APICallsTypePatch:
+					call = cli.Patch
+				case restclient.APICallsTypeFindBy:
+					call = cli.FindBy
+				case restclient.APICallsTypePut:
+					call = cli.Put
+				case restclient.APICallsTypeHead:
+					call = cli.Head
+				default:
+					continue
+				}
+			}
+
+			callInfo := &CallInfo{
+				Path:                descr.Path,
+				Method:              descr.Method,
+				RawMethod:           descr.RawMethod,
+				ReqParams:           reqParams,
+				IdentifierFields:    identifierFields,
+				FieldMapping:        descr.RequestFieldMapping,
+				BodyTemplate:        descr.BodyTemplate,
+				BodyRootPath:        descr.BodyRootPath,
+				ItemsPath:           descr.ItemsPath,
+				NotFoundAsEmpty:     descr.NotFoundAsEmpty,
+				ForbiddenAsNotFound: descr.ForbiddenAsNotFound,
+				StatusCodes:         descr.StatusCodes,
+				VerifyAfterCreate:   descr.VerifyAfterCreate,
+				CheckBeforeCreate:   descr.CheckBeforeCreate,
+				LinkRelations:       relations,
+				SparseFields:        descr.SparseFields,
+				NullFields:          info.Resource.NullFields,
+				Coercions:           info.Resource.Coercions,
+				Precedence:          info.Resource.Precedence,
+				Downgrade:           downgrade,
 			}
-			switch method {
-			case restclient.APICallsTypeGet:
-				return cli.Get, callInfo, nil
-			case restclient.APICallsTypePost:
-				return cli.Post, callInfo, nil
-			case restclient.APICallsTypeList:
-				return cli.List, callInfo, nil
-			case restclient.APICallsTypeDelete:
-				return cli.Delete, callInfo, nil
-			case restclient.APICallsTypePatch:
-				return cli.Patch, callInfo, nil
-			case restclient.APICallsTypeFindBy:
-				return cli.FindBy, callInfo, nil
-			case restclient.APICallsTypePut:
-				return cli.Put, callInfo, nil
+			if action == apiaction.Update {
+				callInfo.OmitFields = createOnlyFields(cli, info)
 			}
+			setIdempotencyKey(callInfo, descr, cli.SpecFields, action)
+			return withAuth(withResponseRoot(withMethodOverride(call, descr.MethodOverrideHeader), descr.ResponseRootPath), cli, info.ActionAuth[action.String()]), callInfo, nil
 		}
 	}
 	return nil, nil, nil
 }
 
-// BuildCallConfig builds the request configuration based on the callInfo and the fields from the status and spec
-func BuildCallConfig(callInfo *CallInfo, statusFields map[string]interface{}, specFields map[string]interface{}) *restclient.RequestConfiguration {
+// updateFallback returns the method updating the resource on the path in place of the given one, not allowed
+// by the API: PUT for PATCH and vice versa, if described by the OAS and allowed as well; empty if none.
+func updateFallback(cli *restclient.UnstructuredClient, method string, path string) string {
+	fallback := map[string]string{"PATCH": "PUT", "PUT": "PATCH"}[strings.ToUpper(method)]
+	if fallback == "" || cli.DocScheme == nil || !cli.Allows(fallback, path) {
+		return ""
+	}
+	pathItem, ok := cli.DocScheme.Model.Paths.PathItems.Get(path)
+	if !ok {
+		return ""
+	}
+	if _, ok := pathItem.GetOperations().Get(strings.ToLower(fallback)); !ok {
+		return ""
+	}
+	return fallback
+}
+
+// wrapBody nests the body under the given dot separated root path, if any
+func wrapBody(body map[string]interface{}, rootPath string) interface{} {
+	if rootPath == "" {
+		return body
+	}
+	fields := strings.Split(rootPath, ".")
+	var wrapped interface{} = body
+	for i := len(fields) - 1; i >= 0; i-- {
+		wrapped = map[string]interface{}{fields[i]: wrapped}
+	}
+	return wrapped
+}
+
+// appliedBody returns the fields of the body sourced from the CR spec, as comparable with the CR spec: the fields
+// sourced from the status, the field mappings or the configuration, never set by the spec, are left out, so that
+// they are not taken as removed from the spec. Nil for bodies rendered from a template, whose shape is unrelated to the spec
+func appliedBody(callInfo *CallInfo, reqConfiguration *restclient.RequestConfiguration, specFields map[string]interface{}) interface{} {
+	if callInfo.BodyTemplate != nil {
+		return nil
+	}
+	body, ok := reqConfiguration.Body.(map[string]interface{})
+	if !ok {
+		return reqConfiguration.Body
+	}
+	if callInfo.BodyRootPath != "" {
+		val, _, _ := unstructured.NestedFieldNoCopy(body, strings.Split(callInfo.BodyRootPath, ".")...)
+		if body, ok = val.(map[string]interface{}); !ok {
+			return val
+		}
+	}
+	applied := make(map[string]interface{}, len(body))
+	for key, value := range body {
+		if spec, ok := specFields[key]; ok && spec != nil {
+			applied[key] = value
+		}
+	}
+	return applied
+}
+
+// filterBody restricts the body fields to the included ones, if any, and removes the excluded ones
+func filterBody(body text.StringSet, include []string, exclude []string) text.StringSet {
+	if len(include) > 0 {
+		filtered := text.NewStringSet()
+		for _, field := range include {
+			if body.Contains(field) {
+				filtered.Add(field)
+			}
+		}
+		body = filtered
+	}
+	for _, field := range exclude {
+		body.Remove(field)
+	}
+	return body
+}
+
+// omitField removes the field from the body, copying the nested objects along its path
+// since they are shared with the CR fields
+func omitField(body map[string]interface{}, fields []string) {
+	if len(fields) == 1 {
+		delete(body, fields[0])
+		return
+	}
+	nested, ok := body[fields[0]].(map[string]interface{})
+	if !ok {
+		return
+	}
+	copied := make(map[string]interface{}, len(nested))
+	for k, v := range nested {
+		copied[k] = v
+	}
+	omitField(copied, fields[1:])
+	body[fields[0]] = copied
+}
+
+// withMethodOverride wraps the API call so that its requests are sent as POST with the method in the override header, if any
+func withMethodOverride(apifunc APIFuncDef, header string) APIFuncDef {
+	if header == "" {
+		return apifunc
+	}
+	return func(ctx context.Context, cli *http.Client, path string, conf *restclient.RequestConfiguration) (*map[string]interface{}, error) {
+		return apifunc(ctx, restclient.MethodOverrideClient(cli, header), path, conf)
+	}
+}
+
+// withAuth returns the API call authenticated with the given method instead of the one of the client, if any
+// (e.g. a token with write permissions); the method of the client is restored once the call returns.
+func withAuth(apifunc APIFuncDef, u *restclient.UnstructuredClient, auth httplib.AuthMethod) APIFuncDef {
+	if auth == nil {
+		return apifunc
+	}
+	return func(ctx context.Context, cli *http.Client, path string, conf *restclient.RequestConfiguration) (*map[string]interface{}, error) {
+		prev := u.Auth
+		u.Auth = auth
+		defer func() { u.Auth = prev }()
+		return apifunc(ctx, cli, path, conf)
+	}
+}
+
+// withResponseRoot returns the API call returning the object found under the given dot separated root path
+// of the response (e.g. data or result), if any; the responses lacking it are returned as they are.
+func withResponseRoot(apifunc APIFuncDef, rootPath string) APIFuncDef {
+	if rootPath == "" {
+		return apifunc
+	}
+	return func(ctx context.Context, cli *http.Client, path string, conf *restclient.RequestConfiguration) (*map[string]interface{}, error) {
+		body, err := apifunc(ctx, cli, path, conf)
+		if err != nil || body == nil {
+			return body, err
+		}
+		return unwrapBody(*body, rootPath), nil
+	}
+}
+
+// unwrapBody returns the object found under the dot separated root path of the body, the body itself if none.
+func unwrapBody(body map[string]interface{}, rootPath string) *map[string]interface{} {
+	val, _, _ := unstructured.NestedFieldNoCopy(body, strings.Split(rootPath, ".")...)
+	if obj, ok := val.(map[string]interface{}); ok {
+		return &obj
+	}
+	return &body
+}
+
+// BuildCallConfig builds the request configuration based on the callInfo and the fields from the status and spec;
+// it fails if a path parameter is not provided by any of them, instead of issuing a request to a malformed URL
+func BuildCallConfig(callInfo *CallInfo, statusFields map[string]interface{}, specFields map[string]interface{}) (*restclient.RequestConfiguration, error) {
 	reqConfiguration := &restclient.RequestConfiguration{}
 	reqConfiguration.Parameters = make(map[string]string)
 	reqConfiguration.Query = make(map[string]string)
+	reqConfiguration.ParameterObjects = make(map[string]interface{})
+	reqConfiguration.QueryObjects = make(map[string]interface{})
+	reqConfiguration.Headers = make(map[string]string)
+	reqConfiguration.Cookies = make(map[string]string)
 	mapBody := make(map[string]interface{})
+	// Set first, so that a header mapped from the CR fields overrides the key
+	if callInfo.IdempotencyKeyHeader != "" && callInfo.IdempotencyKey != "" {
+		reqConfiguration.Headers[callInfo.IdempotencyKeyHeader] = callInfo.IdempotencyKey
+	}
+
+	params, body, err := valueSources(callInfo.Precedence)
+	if err != nil {
+		return nil, err
+	}
+	// The sources are applied from the lowest precedence, each one overriding the values of the previous ones
+	for i := len(params) - 1; i >= 0; i-- {
+		applySource(callInfo, params[i], false, statusFields, specFields, reqConfiguration, mapBody)
+	}
+	for i := len(body) - 1; i >= 0; i-- {
+		applySource(callInfo, body[i], true, statusFields, specFields, reqConfiguration, mapBody)
+	}
+	for _, field := range callInfo.OmitFields {
+		omitField(mapBody, strings.Split(field, "."))
+	}
+	mapBody = dropNulls(mapBody, callInfo.NullFields, getter.NullPolicyValue)
+	mapBody = coerceFields(mapBody, callInfo.Coercions)
+	reqConfiguration.Body = wrapBody(mapBody, callInfo.BodyRootPath)
+	reqConfiguration.ItemsPath = callInfo.ItemsPath
+	reqConfiguration.NotFoundAsEmpty = callInfo.NotFoundAsEmpty
+	reqConfiguration.StatusCodes = callInfo.StatusCodes
+
+	if callInfo.BodyTemplate != nil {
+		body, err := template.Render(callInfo.BodyTemplate.Type, callInfo.BodyTemplate.Template, exprFields(specFields, statusFields))
+		if err != nil {
+			return nil, fmt.Errorf("rendering body template: %w", err)
+		}
+		reqConfiguration.Body = body
+	}
+
+	// The calls following the response links may not need the path parameters
+	if len(callInfo.LinkRelations) == 0 {
+		if err := checkPathParams(callInfo, reqConfiguration, statusFields, specFields); err != nil {
+			return nil, err
+		}
+	}
+	return reqConfiguration, nil
+}
+
+// crFields returns the spec and status of the CR, to be used as variables of the expressions
+func crFields(mg *unstructured.Unstructured) map[string]interface{} {
+	var specFields, statusFields map[string]interface{}
+	if mg != nil {
+		specFields, _, _ = unstructured.NestedMap(mg.Object, "spec")
+		statusFields, _, _ = unstructured.NestedMap(mg.Object, "status")
+	}
+	return exprFields(specFields, statusFields)
+}
+
+// exprFields returns the spec and status fields to be used as variables of the expressions
+func exprFields(specFields, statusFields map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"spec":   nonNilFields(specFields),
+		"status": nonNilFields(statusFields),
+	}
+}
 
-	processFields(callInfo, specFields, reqConfiguration, mapBody)
-	processFields(callInfo, statusFields, reqConfiguration, mapBody)
-	reqConfiguration.Body = mapBody
-	return reqConfiguration
+func nonNilFields(fields map[string]interface{}) map[string]interface{} {
+	if fields == nil {
+		return map[string]interface{}{}
+	}
+	return fields
 }
 
-func processFields(callInfo *CallInfo, fields map[string]interface{}, reqConfiguration *restclient.RequestConfiguration, mapBody map[string]interface{}) {
+// processParams populates the path and query parameters named as the fields.
+func processParams(callInfo *CallInfo, fields map[string]interface{}, reqConfiguration *restclient.RequestConfiguration) {
 	for field, value := range fields {
 		if field == "" {
 			continue
@@ -115,22 +430,51 @@ func processFields(callInfo *CallInfo, fields map[string]interface{}, reqConfigu
 				continue
 			}
 			reqConfiguration.Parameters[field] = stringVal
+			switch value.(type) {
+			case []interface{}, map[string]interface{}:
+				reqConfiguration.ParameterObjects[field] = value
+			default:
+				delete(reqConfiguration.ParameterObjects, field)
+			}
 		} else if callInfo.ReqParams.Query.Contains(field) {
 			stringVal := fmt.Sprintf("%v", value)
 			if stringVal == "" && reqConfiguration.Query[field] != "" {
 				continue
 			}
 			reqConfiguration.Query[field] = stringVal
-		} else if callInfo.ReqParams.Body.Contains(field) {
-			if mapBody[field] == nil {
-				mapBody[field] = value
+			switch value.(type) {
+			case []interface{}, map[string]interface{}:
+				reqConfiguration.QueryObjects[field] = value
+			default:
+				delete(reqConfiguration.QueryObjects, field)
 			}
 		}
 	}
 }
 
+// processBody populates the body fields named as the fields, but the ones named as parameters;
+// a null value does not override the one of a previous source.
+func processBody(callInfo *CallInfo, fields map[string]interface{}, mapBody map[string]interface{}) {
+	for field, value := range fields {
+		if field == "" {
+			continue
+		}
+		if _, ok := mapBody[field]; ok && value == nil {
+			continue
+		}
+		if callInfo.ReqParams.Parameters.Contains(field) || callInfo.ReqParams.Query.Contains(field) {
+			continue
+		}
+		if callInfo.ReqParams.Body.Contains(field) {
+			mapBody[field] = value
+		}
+	}
+}
+
 // isCRUpdated checks if the CR was updated by comparing the fields in the CR with the response from the API call, if existing cr fields are different from the response, it returns false
-func isCRUpdated(mg *unstructured.Unstructured, rm map[string]interface{}) (ComparisonResult, error) {
+// when the last applied body is known, fields removed from the CR since the last apply but still set remotely make it return false too;
+// the ignored fields (e.g. the write-only and create-only ones) are not compared
+func isCRUpdated(clientInfo *getter.Info, mg *unstructured.Unstructured, rm map[string]interface{}, ignored text.StringSet) (ComparisonResult, error) {
 	m, err := unstructuredtools.GetFieldsFromUnstructured(mg, "spec")
 	if err != nil {
 		return ComparisonResult{
@@ -141,243 +485,122 @@ func isCRUpdated(mg *unstructured.Unstructured, rm map[string]interface{}) (Comp
 		}, fmt.Errorf("error getting spec fields: %w", err)
 	}
 
-	return compareExisting(m, rm)
-}
-
-type Reason struct {
-	Reason      string
-	FirstValue  any
-	SecondValue any
-}
-
-type ComparisonResult struct {
-	IsEqual bool
-	Reason  *Reason
-}
-
-// compareExisting recursively compares fields between two maps and logs differences.
-func compareExisting(mg map[string]interface{}, rm map[string]interface{}, path ...string) (ComparisonResult, error) {
-	for key, value := range mg {
-		currentPath := append(path, key)
-		pathStr := fmt.Sprintf("%v", currentPath)
-
-		rmValue, ok := rm[key]
-		if !ok {
-			continue
-		}
-
-		// fmt.Println("Comparing", pathStr, value, rmValue)
-
-		if reflect.TypeOf(value).Kind() != reflect.TypeOf(rmValue).Kind() {
-			return ComparisonResult{
-				IsEqual: false,
-				Reason: &Reason{
-					Reason:      "types differ",
-					FirstValue:  value,
-					SecondValue: rmValue,
-				},
-			}, fmt.Errorf("types differ at %s - %s is different from %s", pathStr, reflect.TypeOf(value).Kind(), reflect.TypeOf(rmValue).Kind())
-		}
+	// The null fields whose policy is unset (the default) are not compared
+	nulls := clientInfo.Resource.NullFields
+	rm = dropNulls(rm, nulls, getter.NullPolicyUnset)
+	c := &comparer{}
+	if opts := clientInfo.Resource.Comparison; opts != nil {
+		c.tolerant = opts.Tolerant
+		c.arrayKeys = opts.ArrayKeys
+		c.normalizers = opts.Normalizers
+	}
+	c.coercions = clientInfo.Resource.Coercions
+	c.ignored = ignored
+	res, err := c.compare(dropNulls(m, nulls, getter.NullPolicyUnset), rm, "")
+	if err != nil || !res.IsEqual {
+		return res, err
+	}
 
-		switch reflect.TypeOf(value).Kind() {
-		case reflect.Map:
-			mgMap, ok1 := value.(map[string]interface{})
-			if !ok1 {
-				// fmt.Printf("Type assertion failed for map at '%s'\n", pathStr)
-				return ComparisonResult{
-					IsEqual: false,
-					Reason: &Reason{
-						Reason:      "type assertion failed",
-						FirstValue:  value,
-						SecondValue: rmValue,
-					},
-				}, fmt.Errorf("type assertion failed for map at %s", pathStr)
-			}
-			rmMap, ok2 := rmValue.(map[string]interface{})
-			if !ok2 {
-				// fmt.Printf("Type assertion failed for map at '%s'\n", pathStr)
-				return ComparisonResult{
-					IsEqual: false,
-					Reason: &Reason{
-						Reason:      "type assertion failed",
-						FirstValue:  value,
-						SecondValue: rmValue,
-					},
-				}, fmt.Errorf("type assertion failed for map at %s", pathStr)
-			}
-			res, err := compareExisting(mgMap, rmMap, currentPath...)
-			if err != nil {
-				return ComparisonResult{
-					IsEqual: false,
-					Reason: &Reason{
-						Reason:      "error comparing maps",
-						FirstValue:  value,
-						SecondValue: rmValue,
-					},
-				}, err
-			}
-			if !res.IsEqual {
-				// fmt.Printf("Values differ at '%s'\n", pathStr)
-				return ComparisonResult{
-					IsEqual: false,
-					Reason: &Reason{
-						Reason:      "values differ",
-						FirstValue:  value,
-						SecondValue: rmValue,
-					},
-				}, nil
-			}
-		case reflect.Slice:
-			valueSlice, ok1 := value.([]interface{})
-			if !ok1 || reflect.TypeOf(rmValue).Kind() != reflect.Slice {
-				// fmt.Printf("Values are not both slices or type assertion failed at '%s'\n", pathStr)
-				return ComparisonResult{
-					IsEqual: false,
-					Reason: &Reason{
-						Reason:      "values are not both slices or type assertion failed",
-						FirstValue:  value,
-						SecondValue: rmValue,
-					},
-				}, fmt.Errorf("values are not both slices or type assertion failed at %s", pathStr)
-			}
-			rmSlice, ok2 := rmValue.([]interface{})
-			if !ok2 {
-				// fmt.Printf("Type assertion failed for slice at '%s'\n", pathStr)
-				return ComparisonResult{
-					IsEqual: false,
-					Reason: &Reason{
-						Reason:      "values are not both slices or type assertion failed",
-						FirstValue:  value,
-						SecondValue: rmValue,
-					},
-				}, fmt.Errorf("type assertion failed for slice at %s", pathStr)
-			}
-			for i, v := range valueSlice {
-				if reflect.TypeOf(v).Kind() == reflect.Map {
-					mgMap, ok1 := v.(map[string]interface{})
-					if !ok1 {
-						// fmt.Printf("Type assertion failed for map at '%s'\n", pathStr)
-						return ComparisonResult{
-							IsEqual: false,
-							Reason: &Reason{
-								Reason:      "type assertion failed",
-								FirstValue:  value,
-								SecondValue: rmValue,
-							},
-						}, fmt.Errorf("type assertion failed for map at %s", pathStr)
-					}
-					rmMap, ok2 := rmSlice[i].(map[string]interface{})
-					if !ok2 {
-						// fmt.Printf("Type assertion failed for map at '%s'\n", pathStr)
-						return ComparisonResult{
-							IsEqual: false,
-							Reason: &Reason{
-								Reason:      "type assertion failed",
-								FirstValue:  value,
-								SecondValue: rmValue,
-							},
-						}, fmt.Errorf("type assertion failed for map at %s", pathStr)
-					}
-					res, err := compareExisting(mgMap, rmMap, currentPath...)
-					if err != nil {
-						return ComparisonResult{
-							IsEqual: false,
-							Reason: &Reason{
-								Reason:      "error comparing maps",
-								FirstValue:  value,
-								SecondValue: rmValue,
-							},
-						}, err
-					}
-					if !res.IsEqual {
-						// fmt.Printf("Values differ at '%s'\n", pathStr)
-						return ComparisonResult{
-							IsEqual: false,
-							Reason: &Reason{
-								Reason:      "values differ",
-								FirstValue:  value,
-								SecondValue: rmValue,
-							},
-						}, nil
-					}
-				} else if v != rmSlice[i] {
-					// fmt.Printf("Values differ at '%s'\n", pathStr)
-					return ComparisonResult{
-						IsEqual: false,
-						Reason: &Reason{
-							Reason:      "values differ",
-							FirstValue:  value,
-							SecondValue: rmValue,
-						},
-					}, nil
-				}
-			}
-		default:
-			ok, err := compareAny(value, rmValue)
-			if err != nil {
-				return ComparisonResult{
-					IsEqual: false,
-					Reason: &Reason{
-						Reason:      "error comparing values",
-						FirstValue:  value,
-						SecondValue: rmValue,
-					},
-				}, err
-			}
-			if !ok {
-				// fmt.Printf("Values differ at '%s' %s %s\n", pathStr, value, rmValue)
-				return ComparisonResult{
-					IsEqual: false,
-					Reason: &Reason{
-						Reason:      "values differ",
-						FirstValue:  value,
-						SecondValue: rmValue,
-					},
-				}, nil
-			}
+	last, err := getLastAppliedBody(mg)
+	if err != nil || last == nil {
+		return res, nil
+	}
+	removed := []string{}
+	for _, field := range removedFields(m, last, rm) {
+		if !ignored.Contains(field) {
+			removed = append(removed, field)
 		}
 	}
-
-	return ComparisonResult{IsEqual: true}, nil
+	if len(removed) > 0 {
+		return ComparisonResult{
+			IsEqual: false,
+			Reason: &Reason{
+				Reason:      "fields removed since last apply",
+				FirstValue:  strings.Join(removed, ", "),
+				SecondValue: "still set",
+			},
+		}, nil
+	}
+	return res, nil
 }
-func numberCaster(value interface{}) int64 {
+
+// toBigFloat converts the numeric value to an arbitrary precision float, so that
+// large integers (e.g. 64-bit IDs decoded as json.Number) are compared exactly.
+// The floats are converted from their shortest decimal representation, as they are written in JSON,
+// so that a float64 equals the json.Number of the same decimal (e.g. 0.1).
+func toBigFloat(value interface{}) (*big.Float, bool) {
+	f := new(big.Float).SetPrec(256)
 	switch v := value.(type) {
 	case int:
-		return int64(v)
+		return f.SetInt64(int64(v)), true
 	case int8:
-		return int64(v)
+		return f.SetInt64(int64(v)), true
 	case int16:
-		return int64(v)
+		return f.SetInt64(int64(v)), true
 	case int32:
-		return int64(v)
+		return f.SetInt64(int64(v)), true
 	case int64:
-		return v // No conversion needed since v is already int64
+		return f.SetInt64(v), true
 	case uint:
-		return int64(v)
+		return f.SetUint64(uint64(v)), true
 	case uint8:
-		return int64(v)
+		return f.SetUint64(uint64(v)), true
 	case uint16:
-		return int64(v)
+		return f.SetUint64(uint64(v)), true
 	case uint32:
-		return int64(v)
+		return f.SetUint64(uint64(v)), true
 	case uint64:
-		return int64(v)
+		return f.SetUint64(v), true
 	case float32:
-		return int64(v)
+		return setFloat(f, float64(v), 32)
 	case float64:
-		return int64(v)
-	default:
-		return -999999 // Return a default value if none of the cases match
+		return setFloat(f, v, 64)
+	case json.Number:
+		if _, ok := f.SetString(v.String()); ok {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// setFloat sets f to the shortest decimal representation of the float of the given bit size, false if NaN.
+func setFloat(f *big.Float, v float64, bitSize int) (*big.Float, bool) {
+	if math.IsNaN(v) {
+		return nil, false
+	}
+	if math.IsInf(v, 0) {
+		return f.SetInf(v < 0), true
+	}
+	if _, ok := f.SetString(strconv.FormatFloat(v, 'g', -1, bitSize)); !ok {
+		return nil, false
 	}
+	return f, true
+}
+
+// isNumber returns true if the value is a number.
+func isNumber(value interface{}) bool {
+	_, ok := toBigFloat(value)
+	return ok
+}
+
+// compareNumbers returns true if the two values are numbers with the same value, regardless of their type.
+func compareNumbers(a any, b any) bool {
+	fa, ok := toBigFloat(a)
+	if !ok {
+		return false
+	}
+	fb, ok := toBigFloat(b)
+	if !ok {
+		return false
+	}
+	return fa.Cmp(fb) == 0
 }
 
 func compareAny(a any, b any) (bool, error) {
 	//if is number compare as number
 	switch a.(type) {
-	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
-		ia := numberCaster(a)
-		ib := numberCaster(b)
-		return ia == ib, nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+		return compareNumbers(a, b), nil
 	case string:
 		sa, ok := a.(string)
 		if !ok {
@@ -403,6 +626,18 @@ func compareAny(a any, b any) (bool, error) {
 	}
 }
 
+// applyServerURL points the client to the server URL of the resource, if set, instead of the servers of the OAS document.
+func applyServerURL(cli *restclient.UnstructuredClient, clientInfo *getter.Info) {
+	if clientInfo.Resource.ServerURL != "" {
+		cli.Server = strings.TrimSuffix(clientInfo.Resource.ServerURL, "/")
+	}
+}
+
+// objectKey returns the key identifying the given resource in the handler's in-memory state.
+func objectKey(mg *unstructured.Unstructured) string {
+	return fmt.Sprintf("%s/%s/%s/%s", mg.GetAPIVersion(), mg.GetKind(), mg.GetNamespace(), mg.GetName())
+}
+
 func removeFinalizersAndUpdate(ctx context.Context, log logging.Logger, pluralizer pluralizer.Pluralizer, dynamic dynamic.Interface, mg *unstructured.Unstructured) error {
 	mg.SetFinalizers([]string{})
 	_, err := tools.Update(ctx, mg, tools.UpdateOptions{
@@ -417,7 +652,7 @@ func removeFinalizersAndUpdate(ctx context.Context, log logging.Logger, pluraliz
 }
 
 // populateStatusFields populates the status fields in the mg object with the values from the body
-func populateStatusFields(clientInfo *getter.Info, mg *unstructured.Unstructured, body *map[string]interface{}) error {
+func populateStatusFields(clientInfo *getter.Info, mg *unstructured.Unstructured, body *map[string]interface{}, headers http.Header) error {
 	if body != nil {
 		for k, v := range *body {
 			for _, identifier := range clientInfo.Resource.Identifiers {
@@ -436,12 +671,91 @@ func populateStatusFields(clientInfo *getter.Info, mg *unstructured.Unstructured
 			}
 		}
 	}
+	if body != nil {
+		err := setVersion(clientInfo.Resource.OptimisticLocking, mg, *body)
+		if err != nil {
+			log.Err(err).Msg("Setting version")
+			return err
+		}
+	}
+	for _, rule := range clientInfo.Resource.IdentifiersFromHeaders {
+		value, ok := headerIdentifier(headers.Get(rule.Header), rule.Segment)
+		if !ok {
+			continue
+		}
+		err := unstructured.SetNestedField(mg.Object, value, "status", rule.Identifier)
+		if err != nil {
+			log.Err(err).Msg("Setting identifier")
+			return err
+		}
+	}
 	return nil
 }
 
+// headerIdentifier extracts the identifier from the header value, taking the given path segment if any
+func headerIdentifier(value string, segment *int) (string, bool) {
+	if value == "" {
+		return "", false
+	}
+	if segment == nil {
+		return value, true
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return "", false
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	idx := *segment
+	if idx < 0 {
+		idx += len(segments)
+	}
+	if idx < 0 || idx >= len(segments) || segments[idx] == "" {
+		return "", false
+	}
+	return segments[idx], true
+}
+
+// populateAnnotations sets the annotations mapped from the response fields in the mg object, returns true if any annotation changed
+func populateAnnotations(clientInfo *getter.Info, mg *unstructured.Unstructured, body *map[string]interface{}) (bool, error) {
+	if body == nil || len(clientInfo.Resource.AnnotationsFromResponse) == 0 {
+		return false, nil
+	}
+
+	annotations := mg.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	changed := false
+	for key, field := range clientInfo.Resource.AnnotationsFromResponse {
+		val, ok, err := unstructured.NestedFieldNoCopy(*body, strings.Split(field, ".")...)
+		if err != nil || !ok || val == nil {
+			continue
+		}
+		stringValue, err := text.GenericToString(val)
+		if err != nil {
+			log.Err(err).Msg("Converting value to string")
+			return false, err
+		}
+		if annotations[key] != stringValue {
+			annotations[key] = stringValue
+			changed = true
+		}
+	}
+	if changed {
+		mg.SetAnnotations(annotations)
+	}
+	return changed, nil
+}
+
 // tries to find the resource in the cluster, with the given statusFields and specFields values, if it is able to validate the GET request, returns true
+// if the GET request is not defined, the request of the exists action is validated instead
 func isResourceKnown(cli *restclient.UnstructuredClient, log logging.Logger, clientInfo *getter.Info, statusFields map[string]interface{}, specFields map[string]interface{}) bool {
-	apiCall, callInfo, err := APICallBuilder(cli, clientInfo, apiaction.Get)
+	action := apiaction.Get
+	apiCall, callInfo, err := APICallBuilder(cli, clientInfo, action)
+	if apiCall == nil && err == nil {
+		action = apiaction.Exists
+		apiCall, callInfo, err = APICallBuilder(cli, clientInfo, action)
+	}
 	if apiCall == nil {
 		return false
 	}
@@ -449,16 +763,25 @@ func isResourceKnown(cli *restclient.UnstructuredClient, log logging.Logger, cli
 		log.Debug("Building API call", "error", err)
 		return false
 	}
-	reqConfiguration := BuildCallConfig(callInfo, statusFields, specFields)
-	if reqConfiguration == nil {
+	reqConfiguration, err := BuildCallConfig(callInfo, statusFields, specFields)
+	if err != nil {
+		log.Debug("Building call configuration", "error", err)
 		return false
 	}
 
-	actionGetMethod := "GET"
-	for _, descr := range clientInfo.Resource.VerbsDescription {
-		if strings.EqualFold(descr.Action, apiaction.Get.String()) {
-			actionGetMethod = descr.Method
+	if callInfo.RawMethod {
+		// raw methods are not described in the OAS, only the path parameters can be checked
+		for param := range callInfo.ReqParams.Parameters {
+			if _, ok := reqConfiguration.Parameters[param]; !ok {
+				return false
+			}
 		}
+		return true
+	}
+
+	actionGetMethod := "GET"
+	if callInfo.Method != "" {
+		actionGetMethod = callInfo.Method
 	}
 
 	return cli.ValidateRequest(actionGetMethod, callInfo.Path, reqConfiguration.Parameters, reqConfiguration.Query) == nil
@@ -0,0 +1,525 @@
+package restResources
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/apiaction"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/lucasepe/httplib"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestHeaderIdentifier(t *testing.T) {
+	last, first := -1, 1
+	tests := []struct {
+		value    string
+		segment  *int
+		expected string
+		ok       bool
+	}{
+		{value: "1234", expected: "1234", ok: true},
+		{value: "https://api.example.com/v1/repos/1234", segment: &last, expected: "1234", ok: true},
+		{value: "/v1/repos/1234/", segment: &first, expected: "repos", ok: true},
+		{value: "", ok: false},
+		{value: "/v1", segment: &first, ok: false},
+	}
+
+	for _, tc := range tests {
+		got, ok := headerIdentifier(tc.value, tc.segment)
+		if ok != tc.ok || got != tc.expected {
+			t.Errorf("%q: expected (%q, %v), got (%q, %v)", tc.value, tc.expected, tc.ok, got, ok)
+		}
+	}
+}
+
+func TestAPICallBuilderCondition(t *testing.T) {
+	info := &getter.Info{
+		Resource: getter.Resource{
+			VerbsDescription: []getter.VerbsDescription{
+				{Action: "create", Method: "POST", Path: "/orgs/{org}/repos", RawMethod: true, Condition: `spec.type == "org"`},
+				{Action: "create", Method: "POST", Path: "/user/repos", RawMethod: true},
+			},
+		},
+	}
+
+	for typ, expected := range map[string]string{"org": "/orgs/{org}/repos", "user": "/user/repos"} {
+		mg := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"type": typ},
+		}}
+		cli := &restclient.UnstructuredClient{SpecFields: mg}
+
+		apiCall, callInfo, err := APICallBuilder(cli, info, apiaction.Create)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if apiCall == nil || callInfo.Path != expected {
+			t.Errorf("%s: expected path %s, got %+v", typ, expected, callInfo)
+		}
+	}
+}
+
+func TestCreateOnlyFieldsOmittedOnUpdate(t *testing.T) {
+	info := &getter.Info{
+		Resource: getter.Resource{
+			VerbsDescription: []getter.VerbsDescription{
+				{Action: "create", Method: "POST", Path: "/repos", RawMethod: true},
+				{Action: "update", Method: "PATCH", Path: "/repos/{id}", RawMethod: true},
+			},
+			CreateOnlyFields: []string{"settings.region", "name"},
+		},
+	}
+	cli := &restclient.UnstructuredClient{SpecFields: &unstructured.Unstructured{Object: map[string]interface{}{}}}
+	spec := map[string]interface{}{
+		"name":        "repo",
+		"description": "updated",
+		"settings":    map[string]interface{}{"region": "eu", "private": true},
+	}
+
+	_, createInfo, err := APICallBuilder(cli, info, apiaction.Create)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(createInfo.OmitFields) > 0 {
+		t.Errorf("expected no fields to be omitted on create, got %v", createInfo.OmitFields)
+	}
+
+	_, updateInfo, err := APICallBuilder(cli, info, apiaction.Update)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(updateInfo.OmitFields, []string{"name", "settings.region"}) {
+		t.Fatalf("unexpected omitted fields: %v", updateInfo.OmitFields)
+	}
+	updateInfo.ReqParams.Body = text.NewStringSet("name", "description", "settings")
+
+	conf, err := BuildCallConfig(updateInfo, map[string]interface{}{"id": "1"}, spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{
+		"description": "updated",
+		"settings":    map[string]interface{}{"private": true},
+	}
+	if !reflect.DeepEqual(conf.Body, expected) {
+		t.Errorf("expected body %v, got %v", expected, conf.Body)
+	}
+	if _, ok := spec["settings"].(map[string]interface{})["region"]; !ok {
+		t.Errorf("expected the spec to be left untouched")
+	}
+}
+
+func TestFilterBody(t *testing.T) {
+	body := text.NewStringSet("name", "description", "etag", "updated_at")
+
+	got := filterBody(body, nil, []string{"etag", "updated_at"})
+	if !reflect.DeepEqual(got, text.NewStringSet("name", "description")) {
+		t.Errorf("unexpected body fields: %v", got)
+	}
+
+	got = filterBody(text.NewStringSet("name", "description", "etag"), []string{"name", "etag", "missing"}, []string{"etag"})
+	if !reflect.DeepEqual(got, text.NewStringSet("name")) {
+		t.Errorf("unexpected body fields: %v", got)
+	}
+}
+
+func TestWrapBody(t *testing.T) {
+	body := map[string]interface{}{"name": "repo"}
+
+	callInfo := &CallInfo{BodyRootPath: "data.attributes"}
+	wrapped := wrapBody(body, callInfo.BodyRootPath)
+	expected := map[string]interface{}{
+		"data": map[string]interface{}{
+			"attributes": map[string]interface{}{"name": "repo"},
+		},
+	}
+	if !reflect.DeepEqual(wrapped, expected) {
+		t.Errorf("expected %v, got %v", expected, wrapped)
+	}
+
+	applied := appliedBody(callInfo, &restclient.RequestConfiguration{Body: wrapped}, map[string]interface{}{"name": "repo"})
+	if !reflect.DeepEqual(applied, body) {
+		t.Errorf("expected applied body %v, got %v", body, applied)
+	}
+
+	if !reflect.DeepEqual(wrapBody(body, ""), body) {
+		t.Errorf("expected body not to be wrapped")
+	}
+}
+
+func TestBuildCallConfigBodyTemplate(t *testing.T) {
+	specFields := map[string]interface{}{"name": "repo"}
+
+	reqParams := &RequestedParams{Parameters: text.StringSet{}, Query: text.StringSet{}, Body: text.StringSet{}}
+	callInfo := &CallInfo{ReqParams: reqParams, BodyTemplate: &getter.BodyTemplate{Template: `{"repo": {"name": "{{ .spec.name }}"}}`}}
+	reqConfiguration, err := BuildCallConfig(callInfo, nil, specFields)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{"repo": map[string]interface{}{"name": "repo"}}
+	if !reflect.DeepEqual(reqConfiguration.Body, expected) {
+		t.Errorf("expected body %v, got %v", expected, reqConfiguration.Body)
+	}
+
+	// A template failing to render fails the call instead of sending a request without a configuration
+	callInfo = &CallInfo{ReqParams: reqParams, BodyTemplate: &getter.BodyTemplate{Template: `{"name": "{{ .spec.name }"}`}}
+	reqConfiguration, err = BuildCallConfig(callInfo, nil, specFields)
+	if err == nil || reqConfiguration != nil {
+		t.Errorf("expected the rendering to fail, got %v", reqConfiguration)
+	}
+}
+
+func TestWithResponseRoot(t *testing.T) {
+	response := map[string]interface{}{"result": map[string]interface{}{"name": "repo"}}
+	call := func(ctx context.Context, cli *http.Client, path string, conf *restclient.RequestConfiguration) (*map[string]interface{}, error) {
+		return &response, nil
+	}
+
+	body, err := withResponseRoot(call, "result")(context.Background(), http.DefaultClient, "/repos", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := map[string]interface{}{"name": "repo"}; !reflect.DeepEqual(*body, expected) {
+		t.Errorf("expected %v, got %v", expected, *body)
+	}
+
+	body, err = withResponseRoot(call, "data")(context.Background(), http.DefaultClient, "/repos", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(*body, response) {
+		t.Errorf("expected the response lacking the root path unchanged, got %v", *body)
+	}
+}
+
+func TestAPICallBuilderActionAuth(t *testing.T) {
+	var authorization string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	read := &httplib.TokenAuth{Token: "read-token"}
+	info := &getter.Info{
+		Resource: getter.Resource{
+			VerbsDescription: []getter.VerbsDescription{
+				{Action: "get", Method: "GET", Path: "/repos/1", RawMethod: true},
+				{Action: "create", Method: "POST", Path: "/repos", RawMethod: true},
+			},
+		},
+		Auth:       read,
+		ActionAuth: map[string]httplib.AuthMethod{"create": &httplib.TokenAuth{Token: "write-token"}},
+	}
+	cli := &restclient.UnstructuredClient{Server: srv.URL, Auth: read, SpecFields: &unstructured.Unstructured{Object: map[string]interface{}{}}}
+
+	for _, action := range []apiaction.APIAction{apiaction.Create, apiaction.Get} {
+		apiCall, callInfo, err := APICallBuilder(cli, info, action)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := apiCall(context.Background(), srv.Client(), callInfo.Path, &restclient.RequestConfiguration{}); err != nil {
+			t.Fatalf("%s: unexpected error: %v", action, err)
+		}
+		expected := "Bearer read-token"
+		if action == apiaction.Create {
+			expected = "Bearer write-token"
+		}
+		if authorization != expected {
+			t.Errorf("%s: expected %q, got %q", action, expected, authorization)
+		}
+	}
+	if cli.Auth != read {
+		t.Errorf("expected the authentication of the client to be restored, got %+v", cli.Auth)
+	}
+}
+
+func TestCompareAnyNumbers(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     any
+		expected bool
+	}{
+		{"big int equal", int64(1234567890123456789), json.Number("1234567890123456789"), true},
+		{"big int differing in last digit", int64(1234567890123456789), json.Number("1234567890123456788"), false},
+		{"big uint", uint64(18446744073709551615), json.Number("18446744073709551615"), true},
+		{"beyond uint64", json.Number("123456789012345678901234567890"), json.Number("123456789012345678901234567890"), true},
+		{"int and integral float", int64(42), json.Number("42.0"), true},
+		{"int and float64", int64(42), float64(42), true},
+		{"float equal", float64(42.5), json.Number("42.5"), true},
+		{"float not truncated", int64(42), json.Number("42.5"), false},
+		{"float differing", float64(0.1), json.Number("0.2"), false},
+		{"decimal float", float64(0.1), json.Number("0.1"), true},
+		{"decimal float greater than one", float64(1.1), json.Number("1.1"), true},
+		{"decimal float32", float32(1.1), json.Number("1.1"), true},
+		{"decimal float differing in last digit", float64(1.1), json.Number("1.1000000000000001"), false},
+		{"exponent", json.Number("1e3"), int64(1000), true},
+		{"number and string", int64(42), "42", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := compareAny(tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ok != tt.expected {
+				t.Errorf("compareAny(%v, %v) = %v, expected %v", tt.a, tt.b, ok, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCompareExistingNumbers(t *testing.T) {
+	spec := map[string]interface{}{
+		"id":    int64(1234567890123456789),
+		"price": float64(42.5),
+		"tags":  []interface{}{int64(9007199254740993)},
+	}
+	remote := map[string]interface{}{
+		"id":    json.Number("1234567890123456789"),
+		"price": json.Number("42.5"),
+		"tags":  []interface{}{json.Number("9007199254740993")},
+	}
+	res, err := compareExisting(spec, remote)
+	if err != nil || !res.IsEqual {
+		t.Errorf("expected equal, got %v (%v)", res.Reason, err)
+	}
+
+	remote["tags"] = []interface{}{json.Number("9007199254740992")}
+	res, err = compareExisting(spec, remote)
+	if err != nil || res.IsEqual {
+		t.Errorf("expected the big int in the array to differ, got %v (%v)", res.IsEqual, err)
+	}
+}
+
+func FuzzBuildCallConfig(f *testing.F) {
+	for _, seed := range comparisonSeeds {
+		f.Add(seed, seed, "a.b", "a.b", "data")
+	}
+	f.Add(`{"name": "repo", "org": "krateo"}`, `{"id": 1}`, "spec.name", "repo.name", "")
+	f.Add(`{"a": {"b": 1}}`, `{}`, ".", "..", "a..b")
+	f.Add(`{"a": [1, 2]}`, `{"a": "x"}`, "spec.a.0", "a.b.c", ".")
+
+	f.Fuzz(func(t *testing.T, spec, status, inCustomResource, inBody, rootPath string) {
+		var specFields, statusFields map[string]interface{}
+		if json.Unmarshal([]byte(spec), &specFields) != nil || json.Unmarshal([]byte(status), &statusFields) != nil {
+			t.Skip()
+		}
+
+		params, query, body := text.NewStringSet(), text.NewStringSet(), text.NewStringSet()
+		i := 0
+		for key := range specFields {
+			switch i % 3 {
+			case 0:
+				params.Add(key)
+			case 1:
+				query.Add(key)
+			default:
+				body.Add(key)
+			}
+			i++
+		}
+		callInfo := &CallInfo{
+			ReqParams: &RequestedParams{Parameters: params, Query: query, Body: body},
+			FieldMapping: []getter.RequestFieldMapping{
+				{InCustomResource: inCustomResource, InBody: inBody, InPath: inBody, InQuery: inBody, InHeader: inBody},
+			},
+			BodyRootPath: rootPath,
+			NullFields:   map[string]getter.NullPolicy{inBody: getter.NullPolicyUnset},
+			Coercions:    map[string]getter.Coercion{inBody: getter.CoercionString},
+		}
+
+		conf, err := BuildCallConfig(callInfo, statusFields, specFields)
+		var missing *missingParamsError
+		if errors.As(err, &missing) {
+			// The path parameters set to null or empty values are missing
+			return
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for key := range specFields {
+			if key != "" && params.Contains(key) {
+				if _, ok := conf.Parameters[key]; !ok {
+					t.Fatalf("expected the path parameter %q to be set", key)
+				}
+			}
+		}
+		if rootPath == "" {
+			if _, ok := conf.Body.(map[string]interface{}); !ok {
+				t.Fatalf("expected a body object, got %T", conf.Body)
+			}
+		}
+	})
+}
+
+func BenchmarkBuildCallConfig(b *testing.B) {
+	specFields := largeObject(100)
+	specFields["org"] = "krateoplatformops"
+	specFields["per_page"] = int64(100)
+	body := text.NewStringSet()
+	for key := range specFields {
+		body.Add(key)
+	}
+	callInfo := &CallInfo{
+		ReqParams: &RequestedParams{
+			Parameters: text.NewStringSet("org"),
+			Query:      text.NewStringSet("per_page"),
+			Body:       body,
+		},
+		FieldMapping: []getter.RequestFieldMapping{
+			{InCustomResource: "status.id", InPath: "id"},
+		},
+	}
+	statusFields := map[string]interface{}{"id": "42"}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := BuildCallConfig(callInfo, statusFields, specFields); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestApplyServerURL(t *testing.T) {
+	cli := &restclient.UnstructuredClient{Server: "https://api.example.com"}
+	applyServerURL(cli, &getter.Info{})
+	if cli.Server != "https://api.example.com" {
+		t.Errorf("expected the OAS server, got %s", cli.Server)
+	}
+	applyServerURL(cli, &getter.Info{Resource: getter.Resource{ServerURL: "https://staging.example.com/"}})
+	if cli.Server != "https://staging.example.com" {
+		t.Errorf("expected the overridden server, got %s", cli.Server)
+	}
+}
+
+const deleteBodyOAS = `openapi: 3.0.0
+info:
+  title: scopes
+  version: "1.0"
+servers:
+  - url: %s
+paths:
+  /apps/{id}/scopes:
+    delete:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                scopes:
+                  type: array
+                  items:
+                    type: string
+                reason:
+                  type: string
+      responses:
+        "204":
+          description: deleted
+`
+
+func TestAPICallBuilderDeleteBody(t *testing.T) {
+	var method string
+	var received map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/openapi.yaml" {
+			fmt.Fprintf(w, deleteBodyOAS, "http://"+r.Host)
+			return
+		}
+		method = r.Method
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cli, err := restclient.BuildClient(context.Background(), nil, srv.URL+"/openapi.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	spec := map[string]interface{}{"name": "app", "scopes": []interface{}{"repo"}, "reason": "decommissioned"}
+	cli.SpecFields = &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	info := &getter.Info{Resource: getter.Resource{
+		VerbsDescription: []getter.VerbsDescription{{Action: "delete", Method: "DELETE", Path: "/apps/{id}/scopes"}},
+	}}
+
+	apiCall, callInfo, err := APICallBuilder(cli, info, apiaction.Delete)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !callInfo.ReqParams.Body.Contains("scopes") || !callInfo.ReqParams.Body.Contains("reason") {
+		t.Fatalf("expected the request body of the DELETE operation, got %v", callInfo.ReqParams.Body)
+	}
+	conf, err := BuildCallConfig(callInfo, map[string]interface{}{"id": "1"}, spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := apiCall(context.Background(), srv.Client(), callInfo.Path, conf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{"scopes": []interface{}{"repo"}, "reason": "decommissioned"}
+	if method != http.MethodDelete || !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected DELETE with body %v, got %s with %v", expected, method, received)
+	}
+}
+
+func TestPopulateAnnotations(t *testing.T) {
+	clientInfo := &getter.Info{Resource: getter.Resource{AnnotationsFromResponse: map[string]string{
+		"krateo.io/web-url":  "links.html",
+		"krateo.io/stars":    "stars",
+		"krateo.io/archived": "archived",
+		"krateo.io/missing":  "missing",
+	}}}
+	body := map[string]interface{}{
+		"links":    map[string]interface{}{"html": "https://example.com/repo1"},
+		"stars":    json.Number("42.5"),
+		"archived": false,
+		"missing":  nil,
+	}
+
+	mg := summaryResource()
+	mg.SetAnnotations(map[string]string{"team": "platform"})
+	changed, err := populateAnnotations(clientInfo, mg, &body)
+	if err != nil || !changed {
+		t.Fatalf("expected the annotations to change, got %t (%v)", changed, err)
+	}
+	expected := map[string]string{
+		"team":               "platform",
+		"krateo.io/web-url":  "https://example.com/repo1",
+		"krateo.io/stars":    "42.5",
+		"krateo.io/archived": "false",
+	}
+	if !reflect.DeepEqual(mg.GetAnnotations(), expected) {
+		t.Errorf("expected %v, got %v", expected, mg.GetAnnotations())
+	}
+
+	changed, err = populateAnnotations(clientInfo, mg, &body)
+	if err != nil || changed {
+		t.Errorf("expected the same response not to change the annotations, got %t (%v)", changed, err)
+	}
+
+	body["stars"] = json.Number("43")
+	changed, err = populateAnnotations(clientInfo, mg, &body)
+	if err != nil || !changed || mg.GetAnnotations()["krateo.io/stars"] != "43" {
+		t.Errorf("expected the changed field to be projected, got %t (%v): %v", changed, err, mg.GetAnnotations())
+	}
+
+	changed, err = populateAnnotations(clientInfo, mg, nil)
+	if err != nil || changed {
+		t.Errorf("expected no response not to change the annotations, got %t (%v)", changed, err)
+	}
+}
@@ -0,0 +1,37 @@
+package restResources
+
+import (
+	"sort"
+	"strings"
+)
+
+// removedFields performs the three-way part of the comparison between the desired spec,
+// the last applied body and the remote resource: it returns the paths of the fields
+// the user removed from the spec since the last apply that are still set remotely.
+// Fields the user never set (i.e. not in the last applied body) but the server
+// populates are ignored, so they are never treated as drift.
+func removedFields(desired, last, remote map[string]interface{}, path ...string) []string {
+	removed := []string{}
+	for key, lv := range last {
+		rv, ok := remote[key]
+		if !ok || rv == nil {
+			continue
+		}
+		currentPath := append(append([]string{}, path...), key)
+
+		dv, ok := desired[key]
+		if !ok || dv == nil {
+			removed = append(removed, strings.Join(currentPath, "."))
+			continue
+		}
+
+		dm, dok := dv.(map[string]interface{})
+		lm, lok := lv.(map[string]interface{})
+		rm, rok := rv.(map[string]interface{})
+		if dok && lok && rok {
+			removed = append(removed, removedFields(dm, lm, rm, currentPath...)...)
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
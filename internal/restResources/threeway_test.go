@@ -0,0 +1,94 @@
+package restResources
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+)
+
+func TestRemovedFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		desired  map[string]interface{}
+		last     map[string]interface{}
+		remote   map[string]interface{}
+		expected []string
+	}{
+		{
+			name:     "server populated field is ignored",
+			desired:  map[string]interface{}{"name": "repo"},
+			last:     map[string]interface{}{"name": "repo"},
+			remote:   map[string]interface{}{"name": "repo", "visibility": "public"},
+			expected: []string{},
+		},
+		{
+			name:     "field removed by the user is reported",
+			desired:  map[string]interface{}{"name": "repo"},
+			last:     map[string]interface{}{"name": "repo", "description": "test"},
+			remote:   map[string]interface{}{"name": "repo", "description": "test"},
+			expected: []string{"description"},
+		},
+		{
+			name:     "field removed by the user and remotely is ignored",
+			desired:  map[string]interface{}{"name": "repo"},
+			last:     map[string]interface{}{"name": "repo", "description": "test"},
+			remote:   map[string]interface{}{"name": "repo"},
+			expected: []string{},
+		},
+		{
+			name:     "nested field removed by the user is reported",
+			desired:  map[string]interface{}{"settings": map[string]interface{}{"a": true}},
+			last:     map[string]interface{}{"settings": map[string]interface{}{"a": true, "b": false}},
+			remote:   map[string]interface{}{"settings": map[string]interface{}{"a": true, "b": false, "c": 1}},
+			expected: []string{"settings.b"},
+		},
+	}
+
+	for _, tc := range tests {
+		got := removedFields(tc.desired, tc.last, tc.remote)
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestAppliedBodyStatusSourcedField(t *testing.T) {
+	callInfo := &CallInfo{
+		ReqParams: &RequestedParams{
+			Parameters: text.NewStringSet(),
+			Query:      text.NewStringSet(),
+			Body:       text.NewStringSet(),
+		},
+	}
+	callInfo.ReqParams.Body.Add("name")
+	callInfo.ReqParams.Body.Add("id")
+	specFields := map[string]interface{}{"name": "repo1"}
+	statusFields := map[string]interface{}{"id": "42"}
+
+	reqConfiguration, err := BuildCallConfig(callInfo, statusFields, specFields)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body := reqConfiguration.Body.(map[string]interface{}); body["id"] != "42" {
+		t.Fatalf("expected the status field to be sent, got %v", body)
+	}
+	applied := appliedBody(callInfo, reqConfiguration, specFields)
+	if !reflect.DeepEqual(applied, specFields) {
+		t.Fatalf("expected only the spec fields to be applied, got %v", applied)
+	}
+
+	mg := summaryResource()
+	mg.Object["spec"] = map[string]interface{}{"name": "repo1"}
+	if err := setLastAppliedBody(mg, applied); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res, err := isCRUpdated(&getter.Info{}, mg, map[string]interface{}{"name": "repo1", "id": "42"}, text.NewStringSet())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.IsEqual {
+		t.Errorf("expected the status field not to be taken as removed, got %v", res.Reason)
+	}
+}
@@ -0,0 +1,29 @@
+package restResources
+
+import (
+	"fmt"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/lifecycle"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// updatedEvent returns the lifecycle event of the update of the resource: Updated if the representation answered
+// by the API confirms the resource is up-to-date, UpdateAccepted otherwise, the reason and message telling why.
+func updatedEvent(clientInfo *getter.Info, mg *unstructured.Unstructured, body *map[string]interface{}, pending bool, ignored text.StringSet) (ev lifecycle.Event, reason, message string) {
+	if pending {
+		return lifecycle.UpdateAccepted, lifecycle.ReasonUpdatePending, "The update was accepted and is still being applied"
+	}
+	if body == nil || len(*body) == 0 {
+		return lifecycle.UpdateAccepted, lifecycle.ReasonUpdateUnconfirmed, "The update is confirmed by the next observation"
+	}
+	res, err := isCRUpdated(clientInfo, mg, *body, ignored)
+	if err != nil {
+		return lifecycle.UpdateAccepted, lifecycle.ReasonUpdateUnconfirmed, fmt.Sprintf("Comparing the updated resource: %s", err)
+	}
+	if !res.IsEqual {
+		return lifecycle.UpdateAccepted, lifecycle.ReasonUpdateNotApplied, driftMessage(res)
+	}
+	return lifecycle.Updated, "", ""
+}
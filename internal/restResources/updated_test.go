@@ -0,0 +1,50 @@
+package restResources
+
+import (
+	"testing"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/lifecycle"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+)
+
+func TestUpdatedEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    *map[string]interface{}
+		pending bool
+		event   lifecycle.Event
+		reason  string
+	}{
+		{
+			name:  "up-to-date",
+			body:  &map[string]interface{}{"id": "42", "name": "repo1"},
+			event: lifecycle.Updated,
+		},
+		{
+			name:    "pending",
+			body:    &map[string]interface{}{"id": "42", "name": "repo1"},
+			pending: true,
+			event:   lifecycle.UpdateAccepted,
+			reason:  lifecycle.ReasonUpdatePending,
+		},
+		{
+			name:   "no content",
+			event:  lifecycle.UpdateAccepted,
+			reason: lifecycle.ReasonUpdateUnconfirmed,
+		},
+		{
+			name:   "not applied",
+			body:   &map[string]interface{}{"id": "42", "name": "repo0"},
+			event:  lifecycle.UpdateAccepted,
+			reason: lifecycle.ReasonUpdateNotApplied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev, reason, _ := updatedEvent(&getter.Info{}, observedResource(), tt.body, tt.pending, nil)
+			if ev != tt.event || reason != tt.reason {
+				t.Errorf("expected %s with reason %q, got %s with reason %q", tt.event, tt.reason, ev, reason)
+			}
+		})
+	}
+}
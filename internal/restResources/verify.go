@@ -0,0 +1,49 @@
+package restResources
+
+import (
+	"context"
+	"net/http"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/apiaction"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	unstructuredtools "github.com/krateoplatformops/unstructured-runtime/pkg/tools/unstructured"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// verifyCreation gets the resource just created by the identifiers of the create response, confirming it materialized
+// and returning its authoritative representation, since some APIs answer the creation with a partial one.
+// The create response is returned as is if it has no identifiers, the resource has no get action or cannot be got yet,
+// the creation having succeeded anyway: the next observation gets the resource by the identifiers written in the status.
+func verifyCreation(ctx context.Context, log logging.Logger, cli *restclient.UnstructuredClient, clientInfo *getter.Info, mg *unstructured.Unstructured, body *map[string]interface{}, specFields map[string]interface{}) *map[string]interface{} {
+	if body == nil {
+		return body
+	}
+	ids := identifierValues(clientInfo.Resource.Identifiers, *body)
+	if len(ids) == 0 {
+		log.Debug("Create response without identifiers, creation not verified", "kind", mg.GetKind())
+		return body
+	}
+	apiCall, callInfo, err := APICallBuilder(cli, clientInfo, apiaction.Get)
+	if apiCall == nil || err != nil {
+		log.Debug("Building API call, creation not verified", "action", apiaction.Get, "error", err)
+		return body
+	}
+	statusFields, _ := unstructuredtools.GetFieldsFromUnstructured(mg, "status")
+	reqConfiguration, err := BuildCallConfig(callInfo, withIdentifiers(statusFields, ids), specFields)
+	if err != nil {
+		log.Debug("Building call configuration, creation not verified", "error", err)
+		return body
+	}
+	got, err := apiCall(ctx, http.DefaultClient, callInfo.Path, reqConfiguration)
+	if err != nil {
+		log.Debug("Verifying creation", "identifiers", ids, "error", err)
+		return body
+	}
+	if got == nil {
+		return body
+	}
+	log.Debug("Creation verified", "kind", mg.GetKind(), "identifiers", ids)
+	return got
+}
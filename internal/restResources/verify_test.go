@@ -0,0 +1,70 @@
+package restResources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+)
+
+func TestVerifyCreation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/openapi.yaml":
+			fmt.Fprintf(w, reposOAS, "http://"+r.Host)
+		case "/repos/42":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"id":"42","name":"repo1","html_url":"https://example.com/repo1"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cli, err := restclient.BuildClient(context.Background(), nil, srv.URL+"/openapi.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mg := observedResource()
+	cli.SpecFields = mg
+	info := &getter.Info{Resource: getter.Resource{
+		Identifiers:      []string{"id"},
+		VerbsDescription: []getter.VerbsDescription{{Action: "get", Method: "GET", Path: "/repos/{id}"}},
+	}}
+
+	tests := []struct {
+		name     string
+		body     map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name:     "verified",
+			body:     map[string]interface{}{"id": "42"},
+			expected: map[string]interface{}{"id": "42", "name": "repo1", "html_url": "https://example.com/repo1"},
+		},
+		{
+			name:     "without identifiers",
+			body:     map[string]interface{}{"name": "repo1"},
+			expected: map[string]interface{}{"name": "repo1"},
+		},
+		{
+			name:     "not materialized yet",
+			body:     map[string]interface{}{"id": "7"},
+			expected: map[string]interface{}{"id": "7"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := verifyCreation(context.Background(), logging.NewNopLogger(), cli, info, mg, &tt.body, map[string]interface{}{"name": "repo1"})
+			if body == nil || !reflect.DeepEqual(*body, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, body)
+			}
+		})
+	}
+}
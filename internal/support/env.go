@@ -59,6 +59,17 @@ func EnvDuration(key string, defaultValue time.Duration) time.Duration {
 	return res
 }
 
+// SplitList returns the non-empty items of the comma separated list.
+func SplitList(value string) []string {
+	var res []string
+	for _, el := range strings.Split(value, ",") {
+		if el = strings.TrimSpace(el); el != "" {
+			res = append(res, el)
+		}
+	}
+	return res
+}
+
 func FixKubernetesServicePortEventually() {
 	const key = "KUBERNETES_SERVICE_PORT"
 	// hack to fix wrong kubernetes service port env var
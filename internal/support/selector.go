@@ -0,0 +1,22 @@
+package support
+
+import (
+	"fmt"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/controller"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ListWatcher returns the configuration of the list-watcher of the controller restricted to the resources
+// matching the label selector, or watching all the resources if the selector is empty.
+func ListWatcher(labelSelector string) (controller.ListWatcherConfiguration, error) {
+	var listWatcher controller.ListWatcherConfiguration
+	if len(labelSelector) == 0 {
+		return listWatcher, nil
+	}
+	if _, err := labels.Parse(labelSelector); err != nil {
+		return listWatcher, fmt.Errorf("parsing label selector: %w", err)
+	}
+	listWatcher.LabelSelector = &labelSelector
+	return listWatcher, nil
+}
@@ -0,0 +1,65 @@
+package support
+
+import (
+	"testing"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/listwatcher"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestListWatcher(t *testing.T) {
+	if _, err := ListWatcher("tier in (prod"); err == nil {
+		t.Errorf("expected an error for an invalid selector")
+	}
+
+	conf, err := ListWatcher("")
+	if err != nil || conf.LabelSelector != nil {
+		t.Errorf("expected no selector, got %v (%v)", conf.LabelSelector, err)
+	}
+
+	gvr := schema.GroupVersionResource{Group: "gen.github.com", Version: "v1alpha1", Resource: "repos"}
+	repo := func(name, tier string) runtime.Object {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("gen.github.com/v1alpha1")
+		obj.SetKind("Repo")
+		obj.SetNamespace("default")
+		obj.SetName(name)
+		obj.SetLabels(map[string]string{"tier": tier})
+		return obj
+	}
+	dyn := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		gvr: "RepoList",
+	}, repo("repo1", "prod"), repo("repo2", "dev"), repo("repo3", "prod"))
+
+	conf, err = ListWatcher("tier=prod")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lw, err := listwatcher.Create(listwatcher.CreateOption{
+		Client:        dyn,
+		GVR:           gvr,
+		Namespace:     "default",
+		LabelSelector: conf.LabelSelector,
+		FieldSelector: conf.FieldSelector,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res, err := lw.List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	list := res.(*unstructured.UnstructuredList)
+	if len(list.Items) != 2 {
+		t.Fatalf("expected the 2 prod resources, got %d", len(list.Items))
+	}
+	for _, item := range list.Items {
+		if item.GetLabels()["tier"] != "prod" {
+			t.Errorf("expected only the prod resources, got %s", item.GetName())
+		}
+	}
+}
@@ -4,14 +4,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strconv"
 )
 
 func GenericToString(i interface{}) (string, error) {
+	if n, ok := i.(json.Number); ok {
+		return n.String(), nil
+	}
 	if reflect.TypeOf(i).Kind() == reflect.String {
 		return i.(string), nil
 	}
-	if reflect.TypeOf(i).Kind() == reflect.Float32 || reflect.TypeOf(i).Kind() == reflect.Float64 {
-		return fmt.Sprintf("%d", int(i.(float64))), nil
+	// Floats keep their decimals, integral ones are formatted as integers (e.g. 42.5 as "42.5", 42.0 as "42")
+	if reflect.TypeOf(i).Kind() == reflect.Float32 {
+		return strconv.FormatFloat(float64(i.(float32)), 'f', -1, 32), nil
+	}
+	if reflect.TypeOf(i).Kind() == reflect.Float64 {
+		return strconv.FormatFloat(i.(float64), 'f', -1, 64), nil
 	}
 	if reflect.TypeOf(i).Kind() == reflect.Int || reflect.TypeOf(i).Kind() == reflect.Int32 || reflect.TypeOf(i).Kind() == reflect.Int64 || reflect.TypeOf(i).Kind() == reflect.Uint || reflect.TypeOf(i).Kind() == reflect.Uint32 || reflect.TypeOf(i).Kind() == reflect.Uint64 {
 		return fmt.Sprintf("%d", i), nil
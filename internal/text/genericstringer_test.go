@@ -1,6 +1,7 @@
 package text
 
 import (
+	"encoding/json"
 	"testing"
 )
 
@@ -12,10 +13,15 @@ func TestGenericToString(t *testing.T) {
 	}{
 		{"hello", "hello", false},
 		{123, "123", false},
-		{123.456, "123", false},
+		{123.456, "123.456", false},
+		{42.0, "42", false},
+		{float32(42.5), "42.5", false},
+		{float64(1e21), "1000000000000000000000", false},
 		{true, "true", false},
 		{false, "false", false},
 		{[]int{1, 2, 3}, "[1,2,3]", false},
+		{json.Number("1234567890123456789"), "1234567890123456789", false},
+		{json.Number("42.5"), "42.5", false},
 	}
 
 	for _, test := range tests {
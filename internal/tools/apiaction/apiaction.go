@@ -14,6 +14,7 @@ const (
 	List   APIAction = "list"
 	Get    APIAction = "get"
 	FindBy APIAction = "findby"
+	Exists APIAction = "exists"
 )
 
 func StringToProviderRuntimeToAction(action string) (APIAction, error) {
@@ -29,6 +30,8 @@ func StringToProviderRuntimeToAction(action string) (APIAction, error) {
 		return List, nil
 	case "findby":
 		return FindBy, nil
+	case "exists":
+		return Exists, nil
 	}
 	return "", fmt.Errorf("invalid action %s", action)
 }
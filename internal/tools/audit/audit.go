@@ -0,0 +1,313 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+type SinkType string
+
+const (
+	SinkNone      SinkType = "none"
+	SinkStdout    SinkType = "stdout"
+	SinkFile      SinkType = "file"
+	SinkConfigMap SinkType = "configmap"
+)
+
+func (s SinkType) String() string {
+	return string(s)
+}
+
+func ToSinkType(ty string) (SinkType, error) {
+	switch strings.ToLower(ty) {
+	case "", "none":
+		return SinkNone, nil
+	case "stdout":
+		return SinkStdout, nil
+	case "file":
+		return SinkFile, nil
+	case "configmap":
+		return SinkConfigMap, nil
+	}
+	return "", fmt.Errorf("unknown audit sink type: %s", ty)
+}
+
+// ObjectRef identifies the custom resource that originated an external call.
+type ObjectRef struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+}
+
+// RefFor returns the ObjectRef of the given custom resource.
+func RefFor(mg *unstructured.Unstructured) ObjectRef {
+	return ObjectRef{
+		APIVersion: mg.GetAPIVersion(),
+		Kind:       mg.GetKind(),
+		Name:       mg.GetName(),
+		Namespace:  mg.GetNamespace(),
+	}
+}
+
+// Record is a single audited external mutation.
+type Record struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Resource    ObjectRef `json:"resource"`
+	Action      string    `json:"action"`
+	Method      string    `json:"method"`
+	URL         string    `json:"url"`
+	StatusCode  int       `json:"statusCode,omitempty"`
+	RequestHash string    `json:"requestHash,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Sink stores audit records.
+type Sink interface {
+	Write(ctx context.Context, rec Record) error
+}
+
+type Options struct {
+	Type SinkType
+	// Path of the file used by the file sink
+	Path string
+	// Namespace and Name of the ConfigMap used by the configmap sink
+	Namespace string
+	Name      string
+	// Size is the maximum number of records kept by the configmap sink
+	Size          int
+	DynamicClient dynamic.Interface
+}
+
+// New returns the Sink described by the given options.
+// It returns a nil Sink if auditing is disabled.
+func New(opts Options) (Sink, error) {
+	switch opts.Type {
+	case SinkStdout:
+		return NewWriterSink(os.Stdout), nil
+	case SinkFile:
+		if opts.Path == "" {
+			return nil, fmt.Errorf("missing path for audit file sink")
+		}
+		f, err := os.OpenFile(opts.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("opening audit file: %w", err)
+		}
+		return NewWriterSink(f), nil
+	case SinkConfigMap:
+		if opts.DynamicClient == nil {
+			return nil, fmt.Errorf("missing dynamic client for audit configmap sink")
+		}
+		if opts.Name == "" {
+			return nil, fmt.Errorf("missing name for audit configmap sink")
+		}
+		return NewConfigMapSink(opts.DynamicClient, opts.Namespace, opts.Name, opts.Size), nil
+	case SinkNone, "":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown audit sink type: %s", opts.Type)
+}
+
+var _ Sink = (*writerSink)(nil)
+
+type writerSink struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewWriterSink returns a Sink that writes one JSON record per line to out.
+func NewWriterSink(out io.Writer) Sink {
+	return &writerSink{out: out}
+}
+
+func (s *writerSink) Write(_ context.Context, rec Record) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.out.Write(append(b, '\n'))
+	return err
+}
+
+const configMapKey = "records"
+
+var _ Sink = (*configMapSink)(nil)
+
+type configMapSink struct {
+	mu        sync.Mutex
+	dyn       dynamic.Interface
+	namespace string
+	name      string
+	size      int
+}
+
+// NewConfigMapSink returns a Sink that keeps the last size records
+// in the given ConfigMap, as a ring buffer of JSON lines.
+func NewConfigMapSink(dyn dynamic.Interface, namespace, name string, size int) Sink {
+	if size <= 0 {
+		size = 100
+	}
+	return &configMapSink{dyn: dyn, namespace: namespace, name: name, size: size}
+}
+
+func (s *configMapSink) Write(ctx context.Context, rec Record) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cli := s.dyn.Resource(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}).
+		Namespace(s.namespace)
+
+	cm, err := cli.Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cm = &unstructured.Unstructured{}
+		cm.SetAPIVersion("v1")
+		cm.SetKind("ConfigMap")
+		cm.SetName(s.name)
+		cm.SetNamespace(s.namespace)
+		err = unstructured.SetNestedField(cm.Object, string(b), "data", configMapKey)
+		if err != nil {
+			return err
+		}
+		_, err = cli.Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	existing, _, err := unstructured.NestedString(cm.Object, "data", configMapKey)
+	if err != nil {
+		return err
+	}
+	lines := appendRing(strings.Split(existing, "\n"), string(b), s.size)
+
+	err = unstructured.SetNestedField(cm.Object, strings.Join(lines, "\n"), "data", configMapKey)
+	if err != nil {
+		return err
+	}
+	_, err = cli.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+// appendRing appends line to lines dropping the oldest entries beyond size.
+func appendRing(lines []string, line string, size int) []string {
+	res := make([]string, 0, size)
+	for _, l := range lines {
+		if strings.TrimSpace(l) != "" {
+			res = append(res, l)
+		}
+	}
+	res = append(res, line)
+	if len(res) > size {
+		res = res[len(res)-size:]
+	}
+	return res
+}
+
+// HashBody returns the hex encoded SHA-256 of the request body, if any.
+func HashBody(req *http.Request) string {
+	if req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil || body == nil {
+		return ""
+	}
+	defer body.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+// Transport is an http.RoundTripper that writes an audit Record
+// for every non read-only request performed through it.
+type Transport struct {
+	Base     http.RoundTripper
+	Sink     Sink
+	Resource ObjectRef
+	Action   string
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if t.Sink == nil || isReadOnly(req.Method) {
+		return base.RoundTrip(req)
+	}
+
+	rec := Record{
+		Timestamp:   time.Now().UTC(),
+		Resource:    t.Resource,
+		Action:      t.Action,
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestHash: HashBody(req),
+	}
+
+	res, err := base.RoundTrip(req)
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	if res != nil {
+		rec.StatusCode = res.StatusCode
+	}
+
+	// Audit failures must never break the reconcile loop.
+	_ = t.Sink.Write(req.Context(), rec)
+
+	return res, err
+}
+
+func isReadOnly(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// Client returns an http.Client auditing the calls performed
+// on behalf of the given resource and action.
+func Client(sink Sink, mg *unstructured.Unstructured, action string) *http.Client {
+	if sink == nil {
+		return http.DefaultClient
+	}
+	return &http.Client{
+		Transport: &Transport{
+			Base:     http.DefaultTransport,
+			Sink:     sink,
+			Resource: RefFor(mg),
+			Action:   action,
+		},
+	}
+}
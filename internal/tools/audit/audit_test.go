@@ -0,0 +1,118 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lucasepe/httplib"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	mg := &unstructured.Unstructured{}
+	mg.SetAPIVersion("gen.github.com/v1alpha1")
+	mg.SetKind("Repo")
+	mg.SetName("gh-repo1")
+	mg.SetNamespace("default")
+
+	var buf bytes.Buffer
+	cli := Client(NewWriterSink(&buf), mg, "create")
+
+	get, err := httplib.Get(server.URL)
+	if err != nil {
+		t.Fatalf("creating request: %v", err)
+	}
+	if err := httplib.Fire(cli, get, httplib.FireOptions{}); err != nil {
+		t.Fatalf("firing GET: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no audit record for GET, got %q", buf.String())
+	}
+
+	post, err := httplib.Post(server.URL+"/repos", httplib.ToJSON(map[string]any{"name": "test"}))
+	if err != nil {
+		t.Fatalf("creating request: %v", err)
+	}
+	if err := httplib.Fire(cli, post, httplib.FireOptions{}); err != nil {
+		t.Fatalf("firing POST: %v", err)
+	}
+
+	var rec Record
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("decoding audit record: %v", err)
+	}
+	if rec.Method != http.MethodPost || rec.StatusCode != http.StatusCreated || rec.Action != "create" {
+		t.Errorf("unexpected audit record: %+v", rec)
+	}
+	if rec.URL != server.URL+"/repos" {
+		t.Errorf("expected url %s, got %s", server.URL+"/repos", rec.URL)
+	}
+	if rec.Resource.Name != "gh-repo1" || rec.Resource.Kind != "Repo" {
+		t.Errorf("unexpected resource reference: %+v", rec.Resource)
+	}
+	if len(rec.RequestHash) != 64 {
+		t.Errorf("expected sha256 request hash, got %q", rec.RequestHash)
+	}
+}
+
+func TestConfigMapSink(t *testing.T) {
+	scheme := runtime.NewScheme()
+	corev1.AddToScheme(scheme)
+	dyn := fake.NewSimpleDynamicClient(scheme)
+
+	sink := NewConfigMapSink(dyn, "default", "audit", 2)
+	for _, action := range []string{"create", "update", "delete"} {
+		if err := sink.Write(context.Background(), Record{Action: action}); err != nil {
+			t.Fatalf("writing record: %v", err)
+		}
+	}
+
+	cm, err := dyn.Resource(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}).
+		Namespace("default").Get(context.Background(), "audit", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("getting configmap: %v", err)
+	}
+	data, _, _ := unstructured.NestedString(cm.Object, "data", configMapKey)
+	lines := strings.Split(data, "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(lines))
+	}
+	if !strings.Contains(lines[0], `"update"`) || !strings.Contains(lines[1], `"delete"`) {
+		t.Errorf("expected oldest record to be dropped, got %v", lines)
+	}
+}
+
+func TestNew(t *testing.T) {
+	sink, err := New(Options{Type: SinkNone})
+	if err != nil || sink != nil {
+		t.Errorf("expected auditing disabled, got %v (%v)", sink, err)
+	}
+	if _, err := New(Options{Type: SinkFile}); err == nil {
+		t.Errorf("expected an error for the file sink without a path")
+	}
+	if _, err := New(Options{Type: SinkType("syslog")}); err == nil {
+		t.Errorf("expected an error for an unknown sink type")
+	}
+	if _, err := ToSinkType("syslog"); err == nil {
+		t.Errorf("expected an error parsing an unknown sink type")
+	}
+}
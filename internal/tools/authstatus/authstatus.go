@@ -0,0 +1,194 @@
+// Package authstatus reports the state of the credentials in the status of the authentication objects
+// (e.g. BearerAuth, BasicAuth) referenced by the resources, so that credential problems are visible
+// where the credentials are configured instead of only in the logs of the controller.
+package authstatus
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"github.com/lucasepe/httplib"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// DefaultInterval is the minimum interval between the reports of an unchanged state
+	DefaultInterval = time.Minute
+
+	patchTimeout = 10 * time.Second
+)
+
+// Status is the state of the credentials written in the status of the authentication object.
+type Status struct {
+	// CredentialsResolved: whether the credentials were resolved from the object and its secrets
+	CredentialsResolved bool `json:"credentialsResolved"`
+	// LastAuthenticatedCall: time of the last call accepted by the API with the credentials
+	LastAuthenticatedCall *metav1.Time `json:"lastAuthenticatedCall,omitempty"`
+	// TokenExpiry: expiration time of the token, if it is a JWT
+	TokenExpiry *metav1.Time `json:"tokenExpiry,omitempty"`
+	// Message: the last credential problem, empty if none
+	Message string `json:"message"`
+}
+
+type report struct {
+	status Status
+	at     time.Time
+}
+
+// Reporter writes the state of the credentials in the authentication objects, reporting an unchanged
+// state at most once per interval for each object.
+type Reporter struct {
+	dynamic  dynamic.Interface
+	log      logging.Logger
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	reports map[getter.AuthRef]report
+}
+
+// New returns the reporter patching the authentication objects through the dynamic client.
+func New(dyn dynamic.Interface, log logging.Logger, interval time.Duration) *Reporter {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Reporter{
+		dynamic:  dyn,
+		log:      log,
+		interval: interval,
+		now:      time.Now,
+		reports:  map[getter.AuthRef]report{},
+	}
+}
+
+// Failed reports that the credentials could not be resolved, if err is an AuthError.
+func (r *Reporter) Failed(ctx context.Context, err error) {
+	var authErr *getter.AuthError
+	if r == nil || !errors.As(err, &authErr) {
+		return
+	}
+	r.report(ctx, authErr.Ref, Status{
+		CredentialsResolved: false,
+		Message:             authErr.Err.Error(),
+	})
+}
+
+// Called reports the outcome of a call made with the credentials resolved from the object,
+// given the status code of its response (0 if no response was received).
+func (r *Reporter) Called(ctx context.Context, ref *getter.AuthRef, auth httplib.AuthMethod, statusCode int) {
+	if r == nil || ref == nil {
+		return
+	}
+	status := Status{CredentialsResolved: true}
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		status.Message = "credentials rejected by the API: " + http.StatusText(statusCode)
+	case statusCode >= 200 && statusCode < 300:
+		now := metav1.NewTime(r.now())
+		status.LastAuthenticatedCall = &now
+	default:
+		// The response tells nothing about the credentials
+		return
+	}
+	if token, ok := auth.(*httplib.TokenAuth); ok && token != nil {
+		if exp, ok := tokenExpiry(token.Token); ok {
+			status.TokenExpiry = &exp
+		}
+	}
+	r.report(ctx, *ref, status)
+}
+
+// Forget drops the state last reported for the object (e.g. once its credentials are rotated),
+// so that the next outcome is reported right away.
+func (r *Reporter) Forget(ref getter.AuthRef) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.reports, ref)
+}
+
+func (r *Reporter) report(ctx context.Context, ref getter.AuthRef, status Status) {
+	r.mu.Lock()
+	prev, ok := r.reports[ref]
+	if ok && sameState(prev.status, status) && r.now().Sub(prev.at) < r.interval {
+		r.mu.Unlock()
+		return
+	}
+	if status.LastAuthenticatedCall == nil && ok {
+		// Keeping the last authenticated call reported
+		status.LastAuthenticatedCall = prev.status.LastAuthenticatedCall
+	}
+	r.reports[ref] = report{status: status, at: r.now()}
+	r.mu.Unlock()
+
+	if err := r.patch(ctx, ref, status); err != nil {
+		r.log.Debug("Reporting credentials status", "ref", ref.String(), "error", err)
+		// Reporting again at the next call
+		r.mu.Lock()
+		delete(r.reports, ref)
+		r.mu.Unlock()
+	}
+}
+
+func (r *Reporter) patch(ctx context.Context, ref getter.AuthRef, status Status) error {
+	if r.dynamic == nil {
+		return nil
+	}
+	data, err := json.Marshal(map[string]interface{}{"status": status})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), patchTimeout)
+	defer cancel()
+
+	cli := r.dynamic.Resource(ref.GVR).Namespace(ref.Namespace)
+	_, err = cli.Patch(ctx, ref.Name, types.MergePatchType, data, metav1.PatchOptions{}, "status")
+	if apierrors.IsNotFound(err) {
+		// The object has no status subresource
+		_, err = cli.Patch(ctx, ref.Name, types.MergePatchType, data, metav1.PatchOptions{})
+	}
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// sameState reports whether the statuses differ only by the time of the last authenticated call.
+func sameState(a, b Status) bool {
+	return a.CredentialsResolved == b.CredentialsResolved &&
+		a.Message == b.Message &&
+		a.TokenExpiry.Equal(b.TokenExpiry)
+}
+
+// tokenExpiry returns the expiration time in the exp claim of the token, if it is a JWT.
+func tokenExpiry(token string) (metav1.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return metav1.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return metav1.Time{}, false
+	}
+	var claims struct {
+		Exp *float64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == nil {
+		return metav1.Time{}, false
+	}
+	return metav1.NewTime(time.Unix(int64(*claims.Exp), 0).UTC()), true
+}
@@ -0,0 +1,172 @@
+package authstatus
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"github.com/lucasepe/httplib"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+var ref = getter.AuthRef{
+	GVR:       schema.GroupVersionResource{Group: "gen.github.com", Version: "v1alpha1", Resource: "bearerauths"},
+	Namespace: "default",
+	Name:      "bearer-gh-ref",
+}
+
+func newBearerAuth() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("gen.github.com/v1alpha1")
+	obj.SetKind("BearerAuth")
+	obj.SetNamespace(ref.Namespace)
+	obj.SetName(ref.Name)
+	return obj
+}
+
+func newReporter(t *testing.T) (*Reporter, *fake.FakeDynamicClient) {
+	t.Helper()
+	dyn := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		ref.GVR: "BearerAuthList",
+	}, newBearerAuth())
+	return New(dyn, logging.NewNopLogger(), time.Minute), dyn
+}
+
+func getStatus(t *testing.T, r *Reporter) map[string]interface{} {
+	t.Helper()
+	got, err := r.dynamic.Resource(ref.GVR).Namespace(ref.Namespace).Get(context.Background(), ref.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	status, _, _ := unstructured.NestedMap(got.Object, "status")
+	return status
+}
+
+func jwt(exp int64) string {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." +
+		enc.EncodeToString([]byte(`{"sub":"bot","exp":`+strconv.FormatInt(exp, 10)+`}`)) + ".sig"
+}
+
+func TestCalled(t *testing.T) {
+	r, _ := newReporter(t)
+	exp := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	r.Called(context.Background(), &ref, &httplib.TokenAuth{Token: jwt(exp.Unix())}, http.StatusOK)
+
+	status := getStatus(t, r)
+	if status["credentialsResolved"] != true {
+		t.Errorf("expected the credentials to be resolved, got %v", status)
+	}
+	if status["lastAuthenticatedCall"] == nil {
+		t.Errorf("expected the last authenticated call to be reported, got %v", status)
+	}
+	if status["tokenExpiry"] != exp.Format(time.RFC3339) {
+		t.Errorf("expected token expiry %s, got %v", exp.Format(time.RFC3339), status["tokenExpiry"])
+	}
+}
+
+func TestCalledRejected(t *testing.T) {
+	r, _ := newReporter(t)
+
+	r.Called(context.Background(), &ref, &httplib.BasicAuth{Username: "bot"}, http.StatusOK)
+	r.Called(context.Background(), &ref, &httplib.BasicAuth{Username: "bot"}, http.StatusUnauthorized)
+
+	status := getStatus(t, r)
+	if status["message"] != "credentials rejected by the API: Unauthorized" {
+		t.Errorf("unexpected message: %v", status["message"])
+	}
+	if status["lastAuthenticatedCall"] == nil {
+		t.Errorf("expected the last authenticated call to be kept, got %v", status)
+	}
+}
+
+func TestCalledIgnored(t *testing.T) {
+	r, _ := newReporter(t)
+
+	r.Called(context.Background(), &ref, nil, http.StatusNotFound)
+	r.Called(context.Background(), &ref, nil, 0)
+	r.Called(context.Background(), nil, nil, http.StatusOK)
+
+	if status := getStatus(t, r); status != nil {
+		t.Errorf("expected no status to be reported, got %v", status)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	r, dyn := newReporter(t)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	patches := func() int {
+		n := 0
+		for _, a := range dyn.Actions() {
+			if a.GetVerb() == "patch" {
+				n++
+			}
+		}
+		return n
+	}
+
+	r.Called(context.Background(), &ref, nil, http.StatusOK)
+	now = now.Add(time.Second)
+	r.Called(context.Background(), &ref, nil, http.StatusOK)
+	if got := patches(); got != 1 {
+		t.Errorf("expected an unchanged state to be throttled, got %d patches", got)
+	}
+
+	r.Called(context.Background(), &ref, nil, http.StatusForbidden)
+	if got := patches(); got != 2 {
+		t.Errorf("expected a changed state to be reported, got %d patches", got)
+	}
+
+	now = now.Add(2 * time.Minute)
+	r.Called(context.Background(), &ref, nil, http.StatusForbidden)
+	if got := patches(); got != 3 {
+		t.Errorf("expected the state to be reported again after the interval, got %d patches", got)
+	}
+}
+
+func TestFailed(t *testing.T) {
+	r, _ := newReporter(t)
+
+	r.Failed(context.Background(), errors.New("not an auth error"))
+	if status := getStatus(t, r); status != nil {
+		t.Fatalf("expected no status to be reported, got %v", status)
+	}
+
+	r.Failed(context.Background(), &getter.AuthError{Ref: ref, Err: errors.New("secret not found")})
+	status := getStatus(t, r)
+	if status["credentialsResolved"] != false || status["message"] != "secret not found" {
+		t.Errorf("unexpected status: %v", status)
+	}
+}
+
+func TestNilReporter(t *testing.T) {
+	var r *Reporter
+	r.Failed(context.Background(), &getter.AuthError{Ref: ref, Err: errors.New("secret not found")})
+	r.Called(context.Background(), &ref, nil, http.StatusOK)
+}
+
+func TestTokenExpiry(t *testing.T) {
+	if _, ok := tokenExpiry("opaque-token"); ok {
+		t.Errorf("expected no expiry for an opaque token")
+	}
+	if _, ok := tokenExpiry("a.!!!.c"); ok {
+		t.Errorf("expected no expiry for an invalid payload")
+	}
+	got, ok := tokenExpiry(jwt(1700000000))
+	if !ok || got.Unix() != 1700000000 {
+		t.Errorf("expected expiry 1700000000, got %v", got)
+	}
+}
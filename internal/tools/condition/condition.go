@@ -0,0 +1,309 @@
+package condition
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types.
+const (
+	// TypePossibleUpdateLoop resources are believed to be updated over and over
+	// by the controller without ever becoming up-to-date.
+	TypePossibleUpdateLoop string = "PossibleUpdateLoop"
+)
+
+// Reasons a resource is or is not in an update loop.
+const (
+	ReasonUpdateLoopDetected string = "UpdateLoopDetected"
+	ReasonNoUpdateLoop       string = "NoUpdateLoop"
+)
+
+// PossibleUpdateLoop returns a condition that indicates the resource
+// is updated repeatedly and the controller is backing off.
+func PossibleUpdateLoop(message string) metav1.Condition {
+	return metav1.Condition{
+		Type:               TypePossibleUpdateLoop,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonUpdateLoopDetected,
+		Message:            message,
+	}
+}
+
+// NoUpdateLoop returns a condition that indicates the resource
+// is not in an update loop.
+func NoUpdateLoop() metav1.Condition {
+	return metav1.Condition{
+		Type:               TypePossibleUpdateLoop,
+		Status:             metav1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNoUpdateLoop,
+	}
+}
+
+// Condition types reporting the problems of the external resources and of the API managing them.
+const (
+	// TypeAuthFailed resources cannot be reconciled because their credentials
+	// could not be resolved or were rejected by the API.
+	TypeAuthFailed string = "AuthFailed"
+	// TypeRateLimited resources cannot be reconciled because the API is
+	// rate limiting the controller.
+	TypeRateLimited string = "RateLimited"
+	// TypeExternalError resources cannot be reconciled because the API
+	// failed or could not be reached.
+	TypeExternalError string = "ExternalError"
+	// TypeDrifted resources differ from their external resource.
+	TypeDrifted string = "Drifted"
+	// TypeDegraded resources cannot be reconciled because of a problem
+	// reported by one of the other condition types.
+	TypeDegraded string = "Degraded"
+)
+
+// Reasons of the conditions reporting the problems of the external resources.
+const (
+	ReasonCredentialsUnresolved string = "CredentialsUnresolved"
+	ReasonCredentialsRejected   string = "CredentialsRejected"
+	ReasonAuthenticated         string = "Authenticated"
+	ReasonRateLimited           string = "RateLimited"
+	ReasonNotRateLimited        string = "NotRateLimited"
+	ReasonAPIError              string = "APIError"
+	ReasonAPIUnreachable        string = "APIUnreachable"
+	ReasonCollectionNotFound    string = "CollectionNotFound"
+	ReasonNoExternalError       string = "NoExternalError"
+	ReasonDriftDetected         string = "DriftDetected"
+	ReasonInSync                string = "InSync"
+	ReasonHealthy               string = "Healthy"
+)
+
+// AuthFailed returns a condition that indicates the credentials of the resource
+// could not be resolved (ReasonCredentialsUnresolved) or were rejected by the API
+// (ReasonCredentialsRejected).
+func AuthFailed(reason, message string) metav1.Condition {
+	return problem(TypeAuthFailed, reason, message)
+}
+
+// Authenticated returns a condition that indicates the credentials of the resource
+// are not known to be failing.
+func Authenticated() metav1.Condition {
+	return noProblem(TypeAuthFailed, ReasonAuthenticated)
+}
+
+// RateLimited returns a condition that indicates the API is rate limiting the controller.
+func RateLimited(message string) metav1.Condition {
+	return problem(TypeRateLimited, ReasonRateLimited, message)
+}
+
+// NotRateLimited returns a condition that indicates the API is not rate limiting the controller.
+func NotRateLimited() metav1.Condition {
+	return noProblem(TypeRateLimited, ReasonNotRateLimited)
+}
+
+// ExternalError returns a condition that indicates the API failed (ReasonAPIError), could not be reached
+// (ReasonAPIUnreachable) or answered 404 listing the collection searched by the findby action (ReasonCollectionNotFound).
+func ExternalError(reason, message string) metav1.Condition {
+	return problem(TypeExternalError, reason, message)
+}
+
+// NoExternalError returns a condition that indicates the last calls to the API succeeded.
+func NoExternalError() metav1.Condition {
+	return noProblem(TypeExternalError, ReasonNoExternalError)
+}
+
+// Drifted returns a condition that indicates the resource differs from its external resource,
+// the message summarizing the difference.
+func Drifted(message string) metav1.Condition {
+	return problem(TypeDrifted, ReasonDriftDetected, message)
+}
+
+// InSync returns a condition that indicates the resource matches its external resource.
+func InSync() metav1.Condition {
+	return noProblem(TypeDrifted, ReasonInSync)
+}
+
+// Degraded returns a condition that indicates the resource cannot be reconciled,
+// the reason being the type of the condition reporting the problem.
+func Degraded(reason, message string) metav1.Condition {
+	return problem(TypeDegraded, reason, message)
+}
+
+// NotDegraded returns a condition that indicates the resource is reconciled normally.
+func NotDegraded() metav1.Condition {
+	return noProblem(TypeDegraded, ReasonHealthy)
+}
+
+func problem(condType, reason, message string) metav1.Condition {
+	return metav1.Condition{
+		Type:               condType,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	}
+}
+
+func noProblem(condType, reason string) metav1.Condition {
+	return metav1.Condition{
+		Type:               condType,
+		Status:             metav1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+	}
+}
+
+// TypeStatusOverflow resources have status fields not allowed by the status schema of their CRD,
+// stored in an annotation instead.
+const TypeStatusOverflow string = "StatusOverflow"
+
+// Reasons the status fields of a resource are or are not stored in an annotation.
+const (
+	ReasonFieldsNotInSchema string = "FieldsNotInSchema"
+	ReasonFieldsInSchema    string = "FieldsInSchema"
+)
+
+// StatusOverflow returns a condition that indicates the status fields not allowed by the CRD schema
+// are stored in an annotation.
+func StatusOverflow(message string) metav1.Condition {
+	return problem(TypeStatusOverflow, ReasonFieldsNotInSchema, message)
+}
+
+// NoStatusOverflow returns a condition that indicates all the status fields are allowed by the CRD schema.
+func NoStatusOverflow() metav1.Condition {
+	return noProblem(TypeStatusOverflow, ReasonFieldsInSchema)
+}
+
+// TypeAmbiguousDefinition resources are managed by more than one RestDefinition declaring their kind and group,
+// one of which is selected.
+const TypeAmbiguousDefinition string = "AmbiguousDefinition"
+
+// Reasons the RestDefinition of a resource is or is not ambiguous.
+const (
+	ReasonMultipleDefinitions string = "MultipleDefinitions"
+	ReasonSingleDefinition    string = "SingleDefinition"
+)
+
+// AmbiguousDefinition returns a condition that indicates more than one RestDefinition manages the resource.
+func AmbiguousDefinition(message string) metav1.Condition {
+	return problem(TypeAmbiguousDefinition, ReasonMultipleDefinitions, message)
+}
+
+// UnambiguousDefinition returns a condition that indicates a single RestDefinition manages the resource.
+func UnambiguousDefinition() metav1.Condition {
+	return noProblem(TypeAmbiguousDefinition, ReasonSingleDefinition)
+}
+
+// TypeExternalAPIDown resources cannot be reconciled because the server of their external API
+// failed its health probe, so that their failures are attributed to the outage of the provider.
+const TypeExternalAPIDown string = "ExternalAPIDown"
+
+// Reasons the server of the external API of a resource is or is not down.
+const (
+	ReasonProbeFailed    string = "ProbeFailed"
+	ReasonProbeSucceeded string = "ProbeSucceeded"
+)
+
+// ExternalAPIDown returns a condition that indicates the server of the external API failed its health probe.
+func ExternalAPIDown(message string) metav1.Condition {
+	return problem(TypeExternalAPIDown, ReasonProbeFailed, message)
+}
+
+// ExternalAPIUp returns a condition that indicates the server of the external API is not known to be down.
+func ExternalAPIUp() metav1.Condition {
+	return noProblem(TypeExternalAPIDown, ReasonProbeSucceeded)
+}
+
+// TypeSearchLimitExceeded resources cannot be found by the findby action because the search through the pages
+// of the collection reached one of its limits, so that they are not known to be missing.
+const TypeSearchLimitExceeded string = "SearchLimitExceeded"
+
+// Reasons the search of a resource did or did not reach its limits.
+const (
+	ReasonSearchLimitReached string = "SearchLimitReached"
+	ReasonSearchCompleted    string = "SearchCompleted"
+)
+
+// SearchLimitExceeded returns a condition that indicates the search of the resource reached one of its limits,
+// the message telling which one.
+func SearchLimitExceeded(message string) metav1.Condition {
+	return problem(TypeSearchLimitExceeded, ReasonSearchLimitReached, message)
+}
+
+// SearchCompleted returns a condition that indicates the search of the resource did not reach its limits.
+func SearchCompleted() metav1.Condition {
+	return noProblem(TypeSearchLimitExceeded, ReasonSearchCompleted)
+}
+
+// Reasons the search of a resource completed without finding it.
+const (
+	ReasonCollectionEmpty string = "CollectionEmpty"
+	ReasonNoMatchingItem  string = "NoMatchingItem"
+)
+
+// SearchMissed returns a condition that indicates the search of the resource completed within its limits without
+// finding it, the reason telling whether the collection was empty or without a matching item.
+func SearchMissed(reason, message string) metav1.Condition {
+	cond := noProblem(TypeSearchLimitExceeded, reason)
+	cond.Message = message
+	return cond
+}
+
+// TypeInternalError resources cannot be reconciled because the controller panicked reconciling them,
+// the panic being recovered instead of crashing the controller.
+const TypeInternalError string = "InternalError"
+
+// Reasons the reconcile of a resource did or did not panic.
+const (
+	ReasonPanic           string = "Panic"
+	ReasonNoInternalError string = "NoInternalError"
+)
+
+// InternalError returns a condition that indicates the controller panicked reconciling the resource,
+// the message telling the value of the panic.
+func InternalError(message string) metav1.Condition {
+	return problem(TypeInternalError, ReasonPanic, message)
+}
+
+// NoInternalError returns a condition that indicates the last reconcile of the resource did not panic.
+func NoInternalError() metav1.Condition {
+	return noProblem(TypeInternalError, ReasonNoInternalError)
+}
+
+// TypeQuotaExceeded resources are not reconciled because the external calls made for them within the window
+// reached their call budget, protecting the pay-per-call APIs from runaway reconcile loops.
+const TypeQuotaExceeded string = "QuotaExceeded"
+
+// Reasons the call budget of a resource is or is not exhausted.
+const (
+	ReasonCallBudgetExhausted string = "CallBudgetExhausted"
+	ReasonWithinCallBudget    string = "WithinCallBudget"
+)
+
+// QuotaExceeded returns a condition that indicates the call budget of the resource is exhausted,
+// the message telling the calls made and when the budget is available again.
+func QuotaExceeded(message string) metav1.Condition {
+	return problem(TypeQuotaExceeded, ReasonCallBudgetExhausted, message)
+}
+
+// WithinQuota returns a condition that indicates the call budget of the resource is not exhausted.
+func WithinQuota() metav1.Condition {
+	return noProblem(TypeQuotaExceeded, ReasonWithinCallBudget)
+}
+
+// TypeMethodDowngraded resources are updated with another method than the one of their update verb, or not
+// updated at all, because the API does not allow it despite its OAS, as told by probing the API.
+const TypeMethodDowngraded string = "MethodDowngraded"
+
+// Reasons the method of the update verb of a resource is or is not allowed by the API.
+const (
+	ReasonMethodNotAllowed string = "MethodNotAllowed"
+	ReasonMethodAllowed    string = "MethodAllowed"
+)
+
+// MethodDowngraded returns a condition that indicates the API does not allow the method of the update verb,
+// the message telling the fallback method if any.
+func MethodDowngraded(message string) metav1.Condition {
+	return problem(TypeMethodDowngraded, ReasonMethodNotAllowed, message)
+}
+
+// MethodAllowed returns a condition that indicates the API allows the method of the update verb.
+func MethodAllowed() metav1.Condition {
+	return noProblem(TypeMethodDowngraded, ReasonMethodAllowed)
+}
@@ -0,0 +1,108 @@
+package condition
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types of the kstatus conventions, read by the tools computing the health of the resources
+// (e.g. Argo CD, Flux, kubectl wait --for=condition=Ready).
+const (
+	// TypeReady resources are reconciled and available.
+	TypeReady string = "Ready"
+	// TypeReconciling resources are being reconciled with their spec.
+	TypeReconciling string = "Reconciling"
+	// TypeStalled resources cannot be reconciled without an intervention.
+	TypeStalled string = "Stalled"
+	// TypeAvailable resources report the state of the lifecycle of their external resource, Ready being derived
+	// from it; unlike Ready, it is never rewritten by the problems of the reconciles.
+	TypeAvailable string = "Available"
+)
+
+// Reasons of the kstatus conditions not taken from the other conditions.
+const (
+	ReasonProgressing    string = "Progressing"
+	ReasonNewGeneration  string = "NewGeneration"
+	ReasonNotStalled     string = "NotStalled"
+	ReasonNotReconciling string = "NotReconciling"
+)
+
+// Kstatus returns the Ready, Reconciling and Stalled conditions following the kstatus conventions, computed from
+// the conditions of the resource and whether its current generation was reconciled:
+//   - Ready reports the Available condition set by the controller (or the Ready condition set before the Available
+//     one was, for the resources not reconciled since), unless the resource is Degraded or its generation
+//     was not reconciled yet;
+//   - Stalled is true while the credentials fail, since they have to be fixed for the reconcile to progress;
+//   - Reconciling is true while the resource is not Ready and not Stalled.
+//
+// Reconciling and Stalled are returned false only if already set, not to add them to every resource.
+func Kstatus(conds []metav1.Condition, generationObserved bool) []metav1.Condition {
+	byType := map[string]metav1.Condition{}
+	for _, co := range conds {
+		byType[co.Type] = co
+	}
+
+	ready, ok := byType[TypeAvailable]
+	if ok {
+		ready.Type = TypeReady
+	} else {
+		ready, ok = byType[TypeReady]
+	}
+	switch degraded := byType[TypeDegraded]; {
+	case degraded.Status == metav1.ConditionTrue:
+		ready = metav1.Condition{Type: TypeReady, Status: metav1.ConditionFalse, Reason: degraded.Reason, Message: degraded.Message}
+	case !generationObserved:
+		ready = metav1.Condition{Type: TypeReady, Status: metav1.ConditionFalse, Reason: ReasonNewGeneration,
+			Message: "The current generation of the spec was not reconciled yet"}
+	case !ok:
+		ready = metav1.Condition{Type: TypeReady, Status: metav1.ConditionFalse, Reason: ReasonProgressing}
+	}
+	if prev, ok := byType[TypeReady]; !ok || prev.Status != ready.Status || prev.LastTransitionTime.IsZero() {
+		ready.LastTransitionTime = metav1.Now()
+	} else {
+		ready.LastTransitionTime = prev.LastTransitionTime
+	}
+	res := []metav1.Condition{ready}
+
+	authFailed := byType[TypeAuthFailed]
+	stalled := authFailed.Status == metav1.ConditionTrue
+	if stalled {
+		res = append(res, problem(TypeStalled, authFailed.Reason, authFailed.Message))
+	} else if _, ok := byType[TypeStalled]; ok {
+		res = append(res, noProblem(TypeStalled, ReasonNotStalled))
+	}
+
+	if ready.Status != metav1.ConditionTrue && !stalled {
+		res = append(res, problem(TypeReconciling, ready.Reason, ready.Message))
+	} else if _, ok := byType[TypeReconciling]; ok {
+		res = append(res, noProblem(TypeReconciling, ReasonNotReconciling))
+	}
+	return res
+}
+
+// Phases of the resources written in status.phase, summarizing their conditions for the tools not reading them
+// (e.g. the custom health checks of Argo CD).
+const (
+	PhaseReady       string = "Ready"
+	PhaseProgressing string = "Progressing"
+	PhaseDegraded    string = "Degraded"
+	PhaseStalled     string = "Stalled"
+)
+
+// Phase returns the phase of the resource and the message explaining it, computed from its conditions
+// (see Kstatus): Stalled, Degraded, Ready or else Progressing.
+func Phase(conds []metav1.Condition) (string, string) {
+	byType := map[string]metav1.Condition{}
+	for _, co := range conds {
+		byType[co.Type] = co
+	}
+	switch {
+	case byType[TypeStalled].Status == metav1.ConditionTrue:
+		return PhaseStalled, byType[TypeStalled].Message
+	case byType[TypeDegraded].Status == metav1.ConditionTrue:
+		return PhaseDegraded, byType[TypeDegraded].Message
+	case byType[TypeReady].Status == metav1.ConditionTrue:
+		return PhaseReady, byType[TypeReady].Message
+	default:
+		return PhaseProgressing, byType[TypeReady].Message
+	}
+}
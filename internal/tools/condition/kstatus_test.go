@@ -0,0 +1,135 @@
+package condition
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func available() metav1.Condition {
+	return metav1.Condition{Type: TypeAvailable, Status: metav1.ConditionTrue, Reason: "Available", LastTransitionTime: metav1.Now()}
+}
+
+func byType(conds []metav1.Condition) map[string]metav1.Condition {
+	res := map[string]metav1.Condition{}
+	for _, co := range conds {
+		res[co.Type] = co
+	}
+	return res
+}
+
+func TestKstatusReady(t *testing.T) {
+	got := byType(Kstatus([]metav1.Condition{available()}, true))
+	if got[TypeReady].Status != metav1.ConditionTrue || got[TypeReady].Reason != "Available" {
+		t.Errorf("expected the resource to be ready, got %+v", got[TypeReady])
+	}
+	if len(got) != 1 {
+		t.Errorf("expected no Reconciling nor Stalled conditions, got %v", got)
+	}
+}
+
+func TestKstatusLegacyReady(t *testing.T) {
+	legacy := available()
+	legacy.Type = TypeReady
+	got := byType(Kstatus([]metav1.Condition{legacy}, true))
+	if got[TypeReady].Status != metav1.ConditionTrue || got[TypeReady].Reason != "Available" {
+		t.Errorf("expected the Ready condition set before the Available one to be kept, got %+v", got[TypeReady])
+	}
+
+	// The Available condition prevails over the Ready one it is derived into
+	unavailable := metav1.Condition{Type: TypeAvailable, Status: metav1.ConditionFalse, Reason: "Creating"}
+	got = byType(Kstatus([]metav1.Condition{legacy, unavailable}, true))
+	if got[TypeReady].Status != metav1.ConditionFalse || got[TypeReady].Reason != "Creating" {
+		t.Errorf("expected Ready to be derived from the Available condition, got %+v", got[TypeReady])
+	}
+	if _, ok := got[TypeAvailable]; ok {
+		t.Errorf("expected the Available condition not to be rewritten, got %+v", got[TypeAvailable])
+	}
+}
+
+func TestKstatusDegraded(t *testing.T) {
+	conds := []metav1.Condition{
+		available(),
+		RateLimited("unexpected status: 429:"),
+		Degraded(TypeRateLimited, "unexpected status: 429:"),
+	}
+	got := byType(Kstatus(conds, true))
+	if got[TypeReady].Status != metav1.ConditionFalse || got[TypeReady].Reason != TypeRateLimited {
+		t.Errorf("expected the resource not to be ready, got %+v", got[TypeReady])
+	}
+	if got[TypeReconciling].Status != metav1.ConditionTrue {
+		t.Errorf("expected the resource to be reconciling, got %+v", got[TypeReconciling])
+	}
+	if _, ok := got[TypeStalled]; ok {
+		t.Errorf("expected no Stalled condition, got %+v", got[TypeStalled])
+	}
+}
+
+func TestKstatusStalled(t *testing.T) {
+	conds := []metav1.Condition{
+		available(),
+		AuthFailed(ReasonCredentialsRejected, "unexpected status: 401:"),
+		Degraded(TypeAuthFailed, "unexpected status: 401:"),
+	}
+	got := byType(Kstatus(conds, true))
+	if got[TypeStalled].Status != metav1.ConditionTrue || got[TypeStalled].Reason != ReasonCredentialsRejected {
+		t.Errorf("expected the resource to be stalled, got %+v", got[TypeStalled])
+	}
+	if _, ok := got[TypeReconciling]; ok {
+		t.Errorf("expected no Reconciling condition, got %+v", got[TypeReconciling])
+	}
+}
+
+func TestKstatusNewGeneration(t *testing.T) {
+	conds := []metav1.Condition{available(), problem(TypeStalled, ReasonCredentialsRejected, "")}
+	got := byType(Kstatus(conds, false))
+	if got[TypeReady].Status != metav1.ConditionFalse || got[TypeReady].Reason != ReasonNewGeneration {
+		t.Errorf("expected the new generation not to be ready, got %+v", got[TypeReady])
+	}
+	if got[TypeReconciling].Status != metav1.ConditionTrue || got[TypeReconciling].Reason != ReasonNewGeneration {
+		t.Errorf("expected the resource to be reconciling, got %+v", got[TypeReconciling])
+	}
+	if got[TypeStalled].Status != metav1.ConditionFalse {
+		t.Errorf("expected the previous Stalled condition to be cleared, got %+v", got[TypeStalled])
+	}
+}
+
+func TestPhase(t *testing.T) {
+	ready := metav1.Condition{Type: TypeReady, Status: metav1.ConditionTrue, Reason: "Available"}
+	tests := []struct {
+		name    string
+		conds   []metav1.Condition
+		phase   string
+		message string
+	}{
+		{name: "no conditions", phase: PhaseProgressing},
+		{name: "ready", conds: []metav1.Condition{ready}, phase: PhaseReady},
+		{
+			name:    "creating",
+			conds:   []metav1.Condition{{Type: TypeReady, Status: metav1.ConditionFalse, Reason: "Creating", Message: "creating"}},
+			phase:   PhaseProgressing,
+			message: "creating",
+		},
+		{
+			name:    "degraded",
+			conds:   []metav1.Condition{ready, Degraded(TypeExternalError, "unexpected status: 502:")},
+			phase:   PhaseDegraded,
+			message: "unexpected status: 502:",
+		},
+		{
+			name: "stalled",
+			conds: []metav1.Condition{
+				Degraded(TypeAuthFailed, "unexpected status: 401:"),
+				problem(TypeStalled, ReasonCredentialsRejected, "unexpected status: 401:"),
+			},
+			phase:   PhaseStalled,
+			message: "unexpected status: 401:",
+		},
+	}
+	for _, tt := range tests {
+		phase, message := Phase(tt.conds)
+		if phase != tt.phase || message != tt.message {
+			t.Errorf("%s: expected %s (%q), got %s (%q)", tt.name, tt.phase, tt.message, phase, message)
+		}
+	}
+}
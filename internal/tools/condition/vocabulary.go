@@ -0,0 +1,123 @@
+package condition
+
+import (
+	"fmt"
+	"os"
+
+	unstructuredtools "github.com/krateoplatformops/unstructured-runtime/pkg/tools/unstructured"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+type Mode string
+
+const (
+	// ModeAdditional emits the mapped conditions alongside the controller ones.
+	ModeAdditional Mode = "additional"
+	// ModeReplace emits the mapped conditions instead of the controller ones.
+	ModeReplace Mode = "replace"
+)
+
+// ConditionRef selects the conditions emitted by the controller.
+type ConditionRef struct {
+	// Type: the condition type to match
+	Type string `json:"type"`
+	// Reason: the condition reason to match, empty matches any reason
+	// +optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// Target describes the condition emitted in place of (or in addition to) the matched one.
+// The status of the matched condition is preserved.
+type Target struct {
+	Type string `json:"type"`
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+type Mapping struct {
+	From ConditionRef `json:"from"`
+	To   Target       `json:"to"`
+}
+
+// Vocabulary maps the conditions emitted by the controller to
+// the condition types and messages expected by platform teams
+// (e.g. the Crossplane "Synced"/"Ready" vocabulary).
+type Vocabulary struct {
+	// Mode: whether mapped conditions are added to or replace the controller ones
+	// +optional
+	Mode     Mode      `json:"mode,omitempty"`
+	Mappings []Mapping `json:"mappings"`
+}
+
+// LoadVocabulary reads a Vocabulary from a YAML or JSON file.
+func LoadVocabulary(path string) (*Vocabulary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading condition vocabulary: %w", err)
+	}
+
+	voc := &Vocabulary{}
+	if err := yaml.Unmarshal(data, voc); err != nil {
+		return nil, fmt.Errorf("decoding condition vocabulary: %w", err)
+	}
+	switch voc.Mode {
+	case "":
+		voc.Mode = ModeAdditional
+	case ModeAdditional, ModeReplace:
+	default:
+		return nil, fmt.Errorf("unknown condition vocabulary mode: %s", voc.Mode)
+	}
+	return voc, nil
+}
+
+// Map returns the conditions to emit for the given controller condition.
+func (v *Vocabulary) Map(co metav1.Condition) []metav1.Condition {
+	if v == nil {
+		return []metav1.Condition{co}
+	}
+
+	mapped := []metav1.Condition{}
+	for _, m := range v.Mappings {
+		if m.From.Type != co.Type || (m.From.Reason != "" && m.From.Reason != co.Reason) {
+			continue
+		}
+		el := co
+		el.Type = m.To.Type
+		if m.To.Reason != "" {
+			el.Reason = m.To.Reason
+		}
+		if m.To.Message != "" {
+			el.Message = m.To.Message
+		}
+		mapped = append(mapped, el)
+	}
+
+	if v.Mode == ModeReplace && len(mapped) > 0 {
+		return mapped
+	}
+	return append([]metav1.Condition{co}, mapped...)
+}
+
+// Set sets the given controller condition on the resource, translated through the vocabulary.
+func (v *Vocabulary) Set(un *unstructured.Unstructured, co metav1.Condition) error {
+	for _, el := range v.Map(co) {
+		if err := unstructuredtools.SetCondition(un, el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsSet returns true if the given controller condition, translated through the vocabulary, is set on the resource.
+func (v *Vocabulary) IsSet(un *unstructured.Unstructured, co metav1.Condition) bool {
+	for _, el := range v.Map(co) {
+		if unstructuredtools.IsConditionSet(un, el) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package condition
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestVocabularyMap(t *testing.T) {
+	available := metav1.Condition{Type: "Ready", Status: metav1.ConditionTrue, Reason: "Available"}
+	creating := metav1.Condition{Type: "Ready", Status: metav1.ConditionFalse, Reason: "Creating"}
+
+	mappings := []Mapping{
+		{From: ConditionRef{Type: "Ready", Reason: "Available"}, To: Target{Type: "Synced", Reason: "ReconcileSuccess"}},
+	}
+
+	tests := []struct {
+		name     string
+		voc      *Vocabulary
+		input    metav1.Condition
+		expected []string
+	}{
+		{name: "nil vocabulary", voc: nil, input: available, expected: []string{"Ready/Available"}},
+		{name: "additional", voc: &Vocabulary{Mode: ModeAdditional, Mappings: mappings}, input: available, expected: []string{"Ready/Available", "Synced/ReconcileSuccess"}},
+		{name: "replace", voc: &Vocabulary{Mode: ModeReplace, Mappings: mappings}, input: available, expected: []string{"Synced/ReconcileSuccess"}},
+		{name: "replace without match", voc: &Vocabulary{Mode: ModeReplace, Mappings: mappings}, input: creating, expected: []string{"Ready/Creating"}},
+	}
+
+	for _, tc := range tests {
+		got := tc.voc.Map(tc.input)
+		if len(got) != len(tc.expected) {
+			t.Fatalf("%s: expected %d conditions, got %d", tc.name, len(tc.expected), len(got))
+		}
+		for i, co := range got {
+			if co.Type+"/"+co.Reason != tc.expected[i] {
+				t.Errorf("%s: expected %s, got %s/%s", tc.name, tc.expected[i], co.Type, co.Reason)
+			}
+			if co.Status != tc.input.Status {
+				t.Errorf("%s: expected status %s to be preserved, got %s", tc.name, tc.input.Status, co.Status)
+			}
+		}
+	}
+}
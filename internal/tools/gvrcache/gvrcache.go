@@ -0,0 +1,136 @@
+// Package gvrcache memoizes the GVK to GVR resolutions of the pluralizer, which otherwise calls
+// the plurals endpoint on every lookup. The pluralizer is configured with the caching HTTP client.
+package gvrcache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is the time the resolutions are cached for
+const DefaultTTL = 10 * time.Minute
+
+type entry struct {
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// Cache is an http.RoundTripper caching the successful GET responses by URL.
+type Cache struct {
+	next http.RoundTripper
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+// New returns the cache of the responses of the next round tripper (http.DefaultTransport if nil).
+// A ttl lower than or equal to zero disables the cache.
+func New(next http.RoundTripper, ttl time.Duration) *Cache {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Cache{
+		next:    next,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]entry{},
+	}
+}
+
+// Client returns the HTTP client caching the responses.
+func (c *Cache) Client() *http.Client {
+	return &http.Client{Transport: c}
+}
+
+// RoundTrip returns the cached response if not expired, otherwise performs the request
+// caching the response if successful. Failed responses are never cached.
+func (c *Cache) RoundTrip(req *http.Request) (*http.Response, error) {
+	if c.ttl <= 0 || req.Method != http.MethodGet {
+		return c.next.RoundTrip(req)
+	}
+	key := req.URL.String()
+
+	c.mu.Lock()
+	cached, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && c.now().Before(cached.expires) {
+		return cached.response(req), nil
+	}
+
+	resp, err := c.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	cached = entry{
+		status:  resp.StatusCode,
+		header:  resp.Header.Clone(),
+		body:    body,
+		expires: c.now().Add(c.ttl),
+	}
+	c.mu.Lock()
+	c.entries[key] = cached
+	c.mu.Unlock()
+	return cached.response(req), nil
+}
+
+// Invalidate drops the cached responses, resolving again every GVK on the next lookup
+// (e.g. once the CRDs changed).
+func (c *Cache) Invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]entry{}
+}
+
+// Invalidator is a cache that can be invalidated (e.g. the cached discovery client).
+type Invalidator interface {
+	Invalidate()
+}
+
+// InvalidateEvery invalidates the caches at every interval until the context is done.
+func InvalidateEvery(ctx context.Context, interval time.Duration, caches ...Invalidator) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, c := range caches {
+				c.Invalidate()
+			}
+		}
+	}
+}
+
+func (e entry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        http.StatusText(e.status),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
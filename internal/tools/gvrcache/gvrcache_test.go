@@ -0,0 +1,95 @@
+package gvrcache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/pluralizer"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCachedResolutions(t *testing.T) {
+	calls := 0
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"plural":"%ss","singular":"%s"}`, r.URL.Query().Get("kind"), r.URL.Query().Get("kind"))
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	cache := New(nil, time.Minute)
+	cache.now = func() time.Time { return now }
+	url := srv.URL
+	p := pluralizer.New(&url, cache.Client())
+
+	repo := schema.GroupVersionKind{Group: "gen.github.com", Version: "v1alpha1", Kind: "repo"}
+	for i := 0; i < 3; i++ {
+		gvr, err := p.GVKtoGVR(repo)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if gvr.Resource != "repos" {
+			t.Fatalf("unexpected resource: %s", gvr.Resource)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected a single call, got %d", calls)
+	}
+
+	if _, err := p.GVKtoGVR(schema.GroupVersionKind{Group: "gen.github.com", Version: "v1alpha1", Kind: "team"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected another kind to be resolved, got %d calls", calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	fail = true
+	if _, err := p.GVKtoGVR(repo); err == nil {
+		t.Errorf("expected the expired resolution to be resolved again")
+	}
+	fail = false
+	if _, err := p.GVKtoGVR(repo); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 4 {
+		t.Errorf("expected the failed resolution not to be cached, got %d calls", calls)
+	}
+
+	cache.Invalidate()
+	if _, err := p.GVKtoGVR(repo); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 5 {
+		t.Errorf("expected the invalidated resolution to be resolved again, got %d calls", calls)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"plural":"repos"}`)
+	}))
+	defer srv.Close()
+
+	cli := New(nil, 0).Client()
+	for i := 0; i < 2; i++ {
+		resp, err := cli.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}
+	if calls != 2 {
+		t.Errorf("expected the cache to be disabled, got %d calls", calls)
+	}
+}
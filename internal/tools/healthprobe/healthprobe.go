@@ -0,0 +1,229 @@
+// Package healthprobe probes the servers of the external APIs at regular intervals, so that the failures
+// of the resources of a server which is down are attributed to the outage of the provider instead of
+// being reported as unrelated problems of each resource.
+package healthprobe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/metrics"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+)
+
+const (
+	// DefaultInterval is the interval the servers are probed at
+	DefaultInterval = time.Minute
+	// DefaultTimeout is the time a probe is given to complete
+	DefaultTimeout = 10 * time.Second
+)
+
+// Status is the result of the last probe of a server.
+type Status struct {
+	// Up: whether the server answered the probe
+	Up bool
+	// Message: the failure of the probe, empty if up
+	Message string
+	// Latency: the duration of the probe
+	Latency time.Duration
+	// Checked: the time of the probe
+	Checked time.Time
+}
+
+type target struct {
+	healthPath string
+	status     *Status
+}
+
+// Prober probes the servers it tracks, keeping the result of the last probe of each one.
+type Prober struct {
+	client   *http.Client
+	log      logging.Logger
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	targets map[string]*target
+}
+
+// New returns the prober of the servers, probed at every interval within the timeout.
+func New(log logging.Logger, interval, timeout time.Duration) *Prober {
+	if log == nil {
+		log = logging.NewNopLogger()
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Prober{
+		client:   &http.Client{Timeout: timeout},
+		log:      log,
+		interval: interval,
+		now:      time.Now,
+		targets:  map[string]*target{},
+	}
+}
+
+// Track adds the server to the probed ones, probed on the health path if not empty.
+// The server is first probed at the next interval.
+func (p *Prober) Track(server, healthPath string) {
+	if p == nil || server == "" {
+		return
+	}
+	server = strings.TrimSuffix(server, "/")
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if t, ok := p.targets[server]; ok {
+		t.healthPath = healthPath
+		return
+	}
+	p.targets[server] = &target{healthPath: healthPath}
+}
+
+// Status returns the result of the last probe of the server, false if not probed yet.
+func (p *Prober) Status(server string) (Status, bool) {
+	if p == nil {
+		return Status{}, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t, ok := p.targets[strings.TrimSuffix(server, "/")]
+	if !ok || t.status == nil {
+		return Status{}, false
+	}
+	return *t.status, true
+}
+
+// Down returns the failure of the last probe of the server, false if the server is up or not probed yet.
+func (p *Prober) Down(server string) (string, bool) {
+	status, ok := p.Status(server)
+	if !ok || status.Up {
+		return "", false
+	}
+	return status.Message, true
+}
+
+// Run probes the tracked servers at every interval until the context is done.
+func (p *Prober) Run(ctx context.Context) {
+	if p == nil {
+		return
+	}
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.probeAll(ctx)
+		}
+	}
+}
+
+// Register registers the results of the probes as metrics.
+func (p *Prober) Register(r *metrics.Registry) {
+	if p == nil {
+		return
+	}
+	r.Func("rest_controller_external_api_up", "Whether the server of the external API answered the last probe.", metrics.TypeGauge,
+		p.samples(func(s Status) float64 {
+			if s.Up {
+				return 1
+			}
+			return 0
+		}))
+	r.Func("rest_controller_external_api_probe_duration_seconds", "Duration of the last probe of the server of the external API.", metrics.TypeGauge,
+		p.samples(func(s Status) float64 { return s.Latency.Seconds() }))
+	r.Func("rest_controller_external_api_probe_timestamp_seconds", "Time of the last probe of the server of the external API.", metrics.TypeGauge,
+		p.samples(func(s Status) float64 { return float64(s.Checked.UnixNano()) / 1e9 }))
+}
+
+func (p *Prober) samples(value func(Status) float64) func() []metrics.Sample {
+	return func() []metrics.Sample {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		samples := make([]metrics.Sample, 0, len(p.targets))
+		for server, t := range p.targets {
+			if t.status == nil {
+				continue
+			}
+			samples = append(samples, metrics.Sample{Labels: map[string]string{"server": server}, Value: value(*t.status)})
+		}
+		return samples
+	}
+}
+
+// probeAll probes the tracked servers concurrently, logging the ones going down or back up.
+func (p *Prober) probeAll(ctx context.Context) {
+	p.mu.Lock()
+	targets := make(map[string]string, len(p.targets))
+	for server, t := range p.targets {
+		targets[server] = t.healthPath
+	}
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for server, healthPath := range targets {
+		wg.Add(1)
+		go func(server, healthPath string) {
+			defer wg.Done()
+			status := p.probe(ctx, server, healthPath)
+
+			p.mu.Lock()
+			t, ok := p.targets[server]
+			var prev *Status
+			if ok {
+				prev = t.status
+				t.status = &status
+			}
+			p.mu.Unlock()
+
+			switch {
+			case !status.Up && (prev == nil || prev.Up):
+				p.log.Info("External API server is down.", "server", server, "message", status.Message)
+			case status.Up && prev != nil && !prev.Up:
+				p.log.Info("External API server is back up.", "server", server)
+			}
+		}(server, healthPath)
+	}
+	wg.Wait()
+}
+
+// probe sends a HEAD request to the server, up if it answers without a server error,
+// or a GET request to its health path, up if it answers successfully.
+func (p *Prober) probe(ctx context.Context, server, healthPath string) Status {
+	method, url := http.MethodHead, server
+	if healthPath != "" {
+		method, url = http.MethodGet, server+"/"+strings.TrimPrefix(healthPath, "/")
+	}
+
+	start := p.now()
+	status := Status{Checked: start}
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		status.Message = fmt.Sprintf("%s %s: %v", method, url, err)
+		return status
+	}
+	res, err := p.client.Do(req)
+	status.Latency = p.now().Sub(start)
+	if err != nil {
+		status.Message = fmt.Sprintf("%s %s: %v", method, url, err)
+		return status
+	}
+	res.Body.Close()
+
+	status.Up = res.StatusCode < http.StatusInternalServerError
+	if healthPath != "" {
+		status.Up = res.StatusCode >= 200 && res.StatusCode < 400
+	}
+	if !status.Up {
+		status.Message = fmt.Sprintf("%s %s: %s", method, url, res.Status)
+	}
+	return status
+}
@@ -0,0 +1,101 @@
+package healthprobe
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/metrics"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+)
+
+func TestProbe(t *testing.T) {
+	code := http.StatusNotFound
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method+" "+r.URL.Path)
+		if r.URL.Path == "/healthz" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(code)
+	}))
+	defer srv.Close()
+
+	p := New(logging.NewNopLogger(), 0, 0)
+	if _, ok := p.Status(srv.URL); ok {
+		t.Fatalf("expected no status for an untracked server")
+	}
+	p.Track(srv.URL+"/", "")
+	if _, down := p.Down(srv.URL); down {
+		t.Fatalf("expected a server not probed yet not to be down")
+	}
+
+	p.probeAll(context.Background())
+	if msg, down := p.Down(srv.URL); down {
+		t.Errorf("expected a server answering with a client error to be up, got %s", msg)
+	}
+
+	code = http.StatusBadGateway
+	p.probeAll(context.Background())
+	msg, down := p.Down(srv.URL)
+	if !down || !strings.Contains(msg, "502") {
+		t.Errorf("expected a server answering with a server error to be down, got %q", msg)
+	}
+
+	code = http.StatusOK
+	p.Track(srv.URL, "/healthz")
+	p.probeAll(context.Background())
+	if _, down := p.Down(srv.URL); !down {
+		t.Errorf("expected a server failing its health path to be down")
+	}
+	if methods[len(methods)-1] != "GET /healthz" || methods[0] != "HEAD /" {
+		t.Errorf("unexpected probes: %v", methods)
+	}
+
+	srv.Close()
+	p.Track(srv.URL, "")
+	p.probeAll(context.Background())
+	if _, down := p.Down(srv.URL); !down {
+		t.Errorf("expected an unreachable server to be down")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	p := New(logging.NewNopLogger(), 0, 0)
+	p.Track(srv.URL, "")
+	p.Track("http://127.0.0.1:1", "")
+	p.probeAll(context.Background())
+
+	r := metrics.NewRegistry()
+	p.Register(r)
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		`rest_controller_external_api_up{server="` + srv.URL + `"} 1`,
+		`rest_controller_external_api_up{server="http://127.0.0.1:1"} 0`,
+		`rest_controller_external_api_probe_duration_seconds{server="` + srv.URL + `"}`,
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected %s in metrics:\n%s", expected, buf.String())
+		}
+	}
+}
+
+func TestNilProber(t *testing.T) {
+	var p *Prober
+	p.Track("https://api.github.com", "")
+	p.Register(metrics.NewRegistry())
+	p.Run(context.Background())
+	if _, down := p.Down("https://api.github.com"); down {
+		t.Errorf("expected no server down without prober")
+	}
+}
@@ -0,0 +1,116 @@
+package hotloop
+
+import (
+	"sync"
+	"time"
+)
+
+// Detector tracks the updates performed on each object and reports
+// when an object is updated too many times within a time window,
+// which usually means the controller is fighting with the external API
+// (server normalizing values, comparison bugs, etc.).
+type Detector struct {
+	// Window is the period in which updates are counted
+	Window time.Duration
+	// Threshold is the number of updates within Window that triggers the detection
+	Threshold int
+	// Cooldown is the period during which updates are skipped once a loop is detected
+	Cooldown time.Duration
+
+	mu        sync.Mutex
+	updates   map[string][]time.Time
+	cooldowns map[string]time.Time
+	// pruned is the time the keys were last pruned
+	pruned time.Time
+}
+
+// New returns a Detector. A threshold lower than or equal to zero disables the detection.
+func New(window time.Duration, threshold int, cooldown time.Duration) *Detector {
+	return &Detector{
+		Window:    window,
+		Threshold: threshold,
+		Cooldown:  cooldown,
+		updates:   map[string][]time.Time{},
+		cooldowns: map[string]time.Time{},
+	}
+}
+
+// Record registers an update for the given key and returns true
+// if the number of updates within the window reached the threshold.
+// When a loop is detected the key enters the cooldown period.
+func (d *Detector) Record(key string, now time.Time) bool {
+	if d == nil || d.Threshold <= 0 {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.prune(now)
+
+	recent := []time.Time{}
+	for _, t := range d.updates[key] {
+		if now.Sub(t) < d.Window {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, now)
+
+	if len(recent) >= d.Threshold {
+		delete(d.updates, key)
+		d.cooldowns[key] = now.Add(d.Cooldown)
+		return true
+	}
+	d.updates[key] = recent
+	return false
+}
+
+// CoolingDown returns true and the end of the cooldown period if the given key is in it.
+func (d *Detector) CoolingDown(key string, now time.Time) (bool, time.Time) {
+	if d == nil {
+		return false, time.Time{}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	until, ok := d.cooldowns[key]
+	if !ok {
+		return false, time.Time{}
+	}
+	if !now.Before(until) {
+		delete(d.cooldowns, key)
+		return false, time.Time{}
+	}
+	return true, until
+}
+
+// prune forgets the keys whose updates all left the window and whose cooldown is over,
+// e.g. of the objects deleted meanwhile, at most once per window.
+func (d *Detector) prune(now time.Time) {
+	if now.Sub(d.pruned) < d.Window {
+		return
+	}
+	d.pruned = now
+	for key, updates := range d.updates {
+		if len(updates) == 0 || now.Sub(updates[len(updates)-1]) >= d.Window {
+			delete(d.updates, key)
+		}
+	}
+	for key, until := range d.cooldowns {
+		if !now.Before(until) {
+			delete(d.cooldowns, key)
+		}
+	}
+}
+
+// Reset forgets the history of the given key, e.g. once the object is up-to-date.
+func (d *Detector) Reset(key string) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.updates, key)
+}
@@ -0,0 +1,68 @@
+package hotloop
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetector(t *testing.T) {
+	now := time.Now()
+	d := New(time.Minute, 3, 5*time.Minute)
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		expected bool
+	}{
+		{name: "first update", at: now, expected: false},
+		{name: "second update", at: now.Add(10 * time.Second), expected: false},
+		{name: "update outside the window", at: now.Add(2 * time.Minute), expected: false},
+		{name: "second update in the new window", at: now.Add(2*time.Minute + 10*time.Second), expected: false},
+		{name: "third update in the new window", at: now.Add(2*time.Minute + 20*time.Second), expected: true},
+	}
+
+	for _, tc := range tests {
+		if got := d.Record("default/repo", tc.at); got != tc.expected {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+
+	if ok, _ := d.CoolingDown("default/repo", now.Add(3*time.Minute)); !ok {
+		t.Errorf("expected key to be cooling down")
+	}
+	if ok, _ := d.CoolingDown("default/other", now.Add(3*time.Minute)); ok {
+		t.Errorf("expected other key not to be cooling down")
+	}
+	if ok, _ := d.CoolingDown("default/repo", now.Add(8*time.Minute)); ok {
+		t.Errorf("expected cooldown to be expired")
+	}
+}
+
+func TestDetectorDisabled(t *testing.T) {
+	d := New(time.Minute, 0, time.Minute)
+	for i := 0; i < 10; i++ {
+		if d.Record("default/repo", time.Now()) {
+			t.Fatalf("expected detection to be disabled")
+		}
+	}
+}
+
+func TestDetectorPrune(t *testing.T) {
+	now := time.Now()
+	d := New(time.Minute, 2, 5*time.Minute)
+	d.Record("default/deleted", now)
+	d.Record("default/looping", now)
+	d.Record("default/looping", now.Add(time.Second))
+
+	// Neither key is seen again once the window and the cooldown are over
+	d.Record("default/other", now.Add(10*time.Minute))
+	if _, ok := d.updates["default/deleted"]; ok {
+		t.Errorf("expected the updates out of the window to be pruned")
+	}
+	if _, ok := d.cooldowns["default/looping"]; ok {
+		t.Errorf("expected the expired cooldown to be pruned")
+	}
+	if _, ok := d.updates["default/other"]; !ok {
+		t.Errorf("expected the recent updates to be kept")
+	}
+}
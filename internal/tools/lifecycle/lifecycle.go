@@ -0,0 +1,264 @@
+// Package lifecycle formalizes the lifecycle of the external resources as a state machine: the events observed
+// reconciling a resource move it through its states, each one reported by the Available condition of the resource,
+// which the Ready condition is derived from.
+// The failures of the reconciles do not change the state, being reported by the problem conditions instead.
+package lifecycle
+
+import (
+	"fmt"
+
+	customcondition "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/condition"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/tools/unstructured/condition"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// State is a state of the lifecycle of an external resource.
+type State string
+
+const (
+	// Unknown: the external resource is not known to exist, it is created unless found
+	Unknown State = "Unknown"
+	// Creating: the external resource was created, its creation is confirmed by the next observation
+	Creating State = "Creating"
+	// Pending: the external resource exists but is still being provisioned by the API
+	Pending State = "Pending"
+	// Available: the external resource is up-to-date with the spec
+	Available State = "Available"
+	// Drifted: the external resource differs from the spec and is going to be updated
+	Drifted State = "Drifted"
+	// Updating: the external resource was updated, the update is confirmed by the next observation
+	Updating State = "Updating"
+	// Deleting: the deletion of the external resource was requested
+	Deleting State = "Deleting"
+	// Gone: the external resource was deleted, the final state
+	Gone State = "Gone"
+)
+
+// States are the states of the lifecycle, in the order they are recognized from the conditions of a resource.
+var States = []State{Creating, Pending, Available, Drifted, Updating, Deleting, Unknown}
+
+// Event is an outcome of the reconcile of an external resource, moving it to another state.
+type Event string
+
+const (
+	// NotFound: the observation did not find the external resource
+	NotFound Event = "NotFound"
+	// Created: the API created the external resource
+	Created Event = "Created"
+	// CreateAccepted: the API accepted the creation of the external resource, carried out asynchronously (e.g. 202)
+	CreateAccepted Event = "CreateAccepted"
+	// ObservedPending: the observation found the external resource still being provisioned
+	ObservedPending Event = "ObservedPending"
+	// UpToDate: the observation found the external resource up-to-date with the spec,
+	// adopting it if it was not known to exist (e.g. found by the findby action)
+	UpToDate Event = "UpToDate"
+	// AssumedUpToDate: the observation found the external resource but cannot compare it with the spec,
+	// since the RestDefinition lacks the verb getting it
+	AssumedUpToDate Event = "AssumedUpToDate"
+	// Diverged: the observation found the external resource differing from the spec
+	Diverged Event = "Diverged"
+	// Updated: the API updated the external resource, answering with its up-to-date representation
+	Updated Event = "Updated"
+	// UpdateAccepted: the API accepted the update of the external resource without confirming it is up-to-date
+	UpdateAccepted Event = "UpdateAccepted"
+	// DeleteRequested: the API accepted the deletion of the external resource
+	DeleteRequested Event = "DeleteRequested"
+)
+
+// Reasons of the Available condition reporting the states other than the ones of the unstructured runtime
+// (Available, Creating, Deleting and Unavailable for Unknown).
+const (
+	ReasonPending string = "Pending"
+	ReasonDrifted string = "Drifted"
+	// ReasonUpdatePending: the API accepted the update without applying it yet (e.g. 202)
+	ReasonUpdatePending string = "UpdatePending"
+	// ReasonUpdateUnconfirmed: the API answered the update without the representation of the resource
+	ReasonUpdateUnconfirmed string = "UpdateUnconfirmed"
+	// ReasonUpdateNotApplied: the representation of the resource answered by the API still differs from the spec
+	ReasonUpdateNotApplied string = "UpdateNotApplied"
+)
+
+// transitions are the states reached on each event, by the state the event happens in.
+var transitions = map[Event]map[State]State{
+	NotFound: {
+		Unknown:   Unknown,
+		Creating:  Unknown,
+		Pending:   Unknown,
+		Available: Unknown,
+		Drifted:   Unknown,
+		Updating:  Unknown,
+		Deleting:  Gone,
+	},
+	Created: {
+		Unknown: Creating,
+	},
+	CreateAccepted: {
+		Unknown: Pending,
+	},
+	ObservedPending: {
+		Unknown:   Pending,
+		Creating:  Pending,
+		Pending:   Pending,
+		Available: Pending,
+		Drifted:   Pending,
+		Updating:  Pending,
+		Deleting:  Deleting,
+	},
+	UpToDate: {
+		Unknown:   Available,
+		Creating:  Available,
+		Pending:   Available,
+		Available: Available,
+		Drifted:   Available,
+		Updating:  Available,
+		Deleting:  Deleting,
+	},
+	AssumedUpToDate: {
+		Creating:  Available,
+		Pending:   Available,
+		Available: Available,
+		Drifted:   Available,
+		Updating:  Available,
+		Deleting:  Deleting,
+	},
+	Diverged: {
+		Unknown:   Drifted,
+		Creating:  Drifted,
+		Pending:   Drifted,
+		Available: Drifted,
+		Drifted:   Drifted,
+		Updating:  Drifted,
+		Deleting:  Deleting,
+	},
+	Updated: {
+		Pending:   Available,
+		Available: Available,
+		Drifted:   Available,
+		Updating:  Available,
+	},
+	UpdateAccepted: {
+		Pending:   Updating,
+		Available: Updating,
+		Drifted:   Updating,
+		Updating:  Updating,
+	},
+	DeleteRequested: {
+		Unknown:   Deleting,
+		Creating:  Deleting,
+		Pending:   Deleting,
+		Available: Deleting,
+		Drifted:   Deleting,
+		Updating:  Deleting,
+		Deleting:  Deleting,
+	},
+}
+
+// targets are the states reached on each event from the states it is not expected in.
+var targets = map[Event]State{
+	NotFound:        Unknown,
+	Created:         Creating,
+	CreateAccepted:  Pending,
+	ObservedPending: Pending,
+	UpToDate:        Available,
+	AssumedUpToDate: Available,
+	Diverged:        Drifted,
+	Updated:         Available,
+	UpdateAccepted:  Updating,
+	DeleteRequested: Deleting,
+}
+
+// TransitionError is returned for an event not expected in the state of the resource
+// (e.g. the state read from its conditions is stale).
+type TransitionError struct {
+	From  State
+	Event Event
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("unexpected event %s in state %s", e.Event, e.From)
+}
+
+// Transition returns the state reached from the state on the event. For an event not expected in the state,
+// it returns the state the event leads to from any state along with a TransitionError,
+// so that the state of the resource follows the API anyway.
+func Transition(from State, event Event) (State, error) {
+	if to, ok := transitions[event][from]; ok {
+		return to, nil
+	}
+	to, ok := targets[event]
+	if !ok {
+		return from, fmt.Errorf("unknown event: %s", event)
+	}
+	return to, &TransitionError{From: from, Event: event}
+}
+
+// Provisioned returns true if the external resource was created or found, so that it is not created again
+// when the RestDefinition lacks the verbs to observe it.
+func (s State) Provisioned() bool {
+	switch s {
+	case Creating, Pending, Available, Drifted, Updating:
+		return true
+	}
+	return false
+}
+
+// Conditions returns the Available conditions reporting the state, the first one being set on the resource
+// and the others recognized as the state too; none for the Gone state.
+func Conditions(s State) []metav1.Condition {
+	conds := readyConditions(s)
+	for i := range conds {
+		conds[i].Type = customcondition.TypeAvailable
+	}
+	return conds
+}
+
+// LegacyConditions returns the Ready conditions reporting the state, set by the controller before the Available
+// condition was, so that the state of the resources not reconciled since is still recognized.
+func LegacyConditions(s State) []metav1.Condition {
+	return readyConditions(s)
+}
+
+// readyConditions returns the conditions of the unstructured runtime reporting the state.
+func readyConditions(s State) []metav1.Condition {
+	unavailable := func(reason string) metav1.Condition {
+		cond := condition.Unavailable()
+		cond.Reason = reason
+		return cond
+	}
+	switch s {
+	case Unknown:
+		return []metav1.Condition{condition.Unavailable()}
+	case Creating:
+		return []metav1.Condition{condition.Creating()}
+	case Pending:
+		return []metav1.Condition{unavailable(ReasonPending)}
+	case Available:
+		return []metav1.Condition{condition.Available()}
+	case Drifted:
+		return []metav1.Condition{unavailable(ReasonDrifted)}
+	case Updating:
+		return []metav1.Condition{
+			unavailable(ReasonUpdateUnconfirmed),
+			unavailable(ReasonUpdatePending),
+			unavailable(ReasonUpdateNotApplied),
+		}
+	case Deleting:
+		return []metav1.Condition{condition.Deleting()}
+	}
+	return nil
+}
+
+// Condition returns the Available condition reporting the state, with the given reason and message if not empty;
+// ok is false for the Gone state, reported by no condition.
+func Condition(s State, reason, message string) (metav1.Condition, bool) {
+	conds := Conditions(s)
+	if len(conds) == 0 {
+		return metav1.Condition{}, false
+	}
+	cond := conds[0]
+	if reason != "" {
+		cond.Reason = reason
+	}
+	cond.Message = message
+	return cond, true
+}
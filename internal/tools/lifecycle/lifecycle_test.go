@@ -0,0 +1,135 @@
+package lifecycle
+
+import (
+	"errors"
+	"testing"
+
+	customcondition "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/condition"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/tools/unstructured/condition"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		name       string
+		from       State
+		event      Event
+		to         State
+		unexpected bool
+	}{
+		// Creation
+		{name: "not found", from: Unknown, event: NotFound, to: Unknown},
+		{name: "create", from: Unknown, event: Created, to: Creating},
+		{name: "async create", from: Unknown, event: CreateAccepted, to: Pending},
+		{name: "create confirmed", from: Creating, event: UpToDate, to: Available},
+		{name: "create still pending", from: Creating, event: ObservedPending, to: Pending},
+		{name: "async create provisioned", from: Pending, event: UpToDate, to: Available},
+		{name: "async create still pending", from: Pending, event: ObservedPending, to: Pending},
+		{name: "created resource lost", from: Creating, event: NotFound, to: Unknown},
+		{name: "create again", from: Creating, event: Created, to: Creating, unexpected: true},
+
+		// Adoption of the resources found but not created by the controller
+		{name: "adopt", from: Unknown, event: UpToDate, to: Available},
+		{name: "adopt pending", from: Unknown, event: ObservedPending, to: Pending},
+		{name: "adopt drifted", from: Unknown, event: Diverged, to: Drifted},
+
+		// Missing verbs
+		{name: "no get after create", from: Creating, event: AssumedUpToDate, to: Available},
+		{name: "no get", from: Available, event: AssumedUpToDate, to: Available},
+		{name: "no get while deleting", from: Deleting, event: AssumedUpToDate, to: Deleting},
+		{name: "assumed unknown", from: Unknown, event: AssumedUpToDate, to: Available, unexpected: true},
+
+		// Drift and update
+		{name: "drift", from: Available, event: Diverged, to: Drifted},
+		{name: "update", from: Drifted, event: Updated, to: Available},
+		{name: "async update", from: Drifted, event: UpdateAccepted, to: Updating},
+		{name: "update confirmed", from: Updating, event: UpToDate, to: Available},
+		{name: "update not applied", from: Updating, event: Diverged, to: Drifted},
+		{name: "updated resource lost", from: Updating, event: NotFound, to: Unknown},
+		{name: "update unknown", from: Unknown, event: Updated, to: Available, unexpected: true},
+		{name: "async update unknown", from: Unknown, event: UpdateAccepted, to: Updating, unexpected: true},
+
+		// Deletion
+		{name: "delete", from: Available, event: DeleteRequested, to: Deleting},
+		{name: "delete pending", from: Pending, event: DeleteRequested, to: Deleting},
+		{name: "delete unknown", from: Unknown, event: DeleteRequested, to: Deleting},
+		{name: "delete again", from: Deleting, event: DeleteRequested, to: Deleting},
+		{name: "deleted", from: Deleting, event: NotFound, to: Gone},
+		{name: "deleting still found", from: Deleting, event: UpToDate, to: Deleting},
+		{name: "deleting drifted", from: Deleting, event: Diverged, to: Deleting},
+		{name: "deleting pending", from: Deleting, event: ObservedPending, to: Deleting},
+		{name: "update while deleting", from: Deleting, event: Updated, to: Available, unexpected: true},
+		{name: "create while deleting", from: Deleting, event: Created, to: Creating, unexpected: true},
+
+		// Gone is final
+		{name: "gone not found", from: Gone, event: NotFound, to: Unknown, unexpected: true},
+		{name: "gone found", from: Gone, event: UpToDate, to: Available, unexpected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			to, err := Transition(tt.from, tt.event)
+			if to != tt.to {
+				t.Errorf("expected %s, got %s", tt.to, to)
+			}
+			var transitionErr *TransitionError
+			if tt.unexpected != errors.As(err, &transitionErr) {
+				t.Errorf("expected unexpected transition %v, got error %v", tt.unexpected, err)
+			}
+		})
+	}
+}
+
+func TestTransitionUnknownEvent(t *testing.T) {
+	to, err := Transition(Available, Event("Exploded"))
+	if err == nil || to != Available {
+		t.Errorf("expected an error and the state unchanged, got %s and %v", to, err)
+	}
+}
+
+func TestCondition(t *testing.T) {
+	tests := []struct {
+		state  State
+		reason string
+		status metav1.ConditionStatus
+	}{
+		{state: Unknown, reason: condition.ReasonUnavailable, status: metav1.ConditionFalse},
+		{state: Creating, reason: condition.ReasonCreating, status: metav1.ConditionFalse},
+		{state: Pending, reason: ReasonPending, status: metav1.ConditionFalse},
+		{state: Available, reason: condition.ReasonAvailable, status: metav1.ConditionTrue},
+		{state: Drifted, reason: ReasonDrifted, status: metav1.ConditionFalse},
+		{state: Updating, reason: ReasonUpdateUnconfirmed, status: metav1.ConditionFalse},
+		{state: Deleting, reason: condition.ReasonDeleting, status: metav1.ConditionFalse},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.state), func(t *testing.T) {
+			cond, ok := Condition(tt.state, "", "message")
+			if !ok || cond.Type != customcondition.TypeAvailable || cond.Reason != tt.reason || cond.Status != tt.status || cond.Message != "message" {
+				t.Errorf("expected Available %s with reason %s, got %+v", tt.status, tt.reason, cond)
+			}
+			if legacy := LegacyConditions(tt.state); len(legacy) == 0 || legacy[0].Type != condition.TypeReady || legacy[0].Reason != tt.reason {
+				t.Errorf("expected Ready with reason %s, got %+v", tt.reason, legacy)
+			}
+		})
+	}
+
+	cond, _ := Condition(Updating, ReasonUpdatePending, "")
+	if cond.Reason != ReasonUpdatePending {
+		t.Errorf("expected reason %s, got %s", ReasonUpdatePending, cond.Reason)
+	}
+	if _, ok := Condition(Gone, "", ""); ok {
+		t.Errorf("expected no condition for the Gone state")
+	}
+}
+
+func TestProvisioned(t *testing.T) {
+	for _, s := range []State{Creating, Pending, Available, Drifted, Updating} {
+		if !s.Provisioned() {
+			t.Errorf("expected %s to be provisioned", s)
+		}
+	}
+	for _, s := range []State{Unknown, Deleting, Gone} {
+		if s.Provisioned() {
+			t.Errorf("expected %s not to be provisioned", s)
+		}
+	}
+}
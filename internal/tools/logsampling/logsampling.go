@@ -0,0 +1,131 @@
+// Package logsampling limits the repeated identical log messages of high-churn objects: within each window
+// only the first messages of a kind are logged, and the number of the ones dropped is reported with the
+// first message logged in the next window.
+// Messages are identical when they have the same level, text and structured data, including the values
+// added with WithValues (e.g. the name of the object).
+package logsampling
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+)
+
+type entry struct {
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+// sampler counts the messages logged within the window, shared by the loggers derived with WithValues.
+type sampler struct {
+	window time.Duration
+	burst  int
+	now    func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastPrune time.Time
+}
+
+// allow returns true if the message is to be logged, along with the number of identical messages dropped
+// in the previous window.
+func (s *sampler) allow(key string) (bool, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastPrune) >= s.window {
+		for k, e := range s.entries {
+			// Keeping the count of the dropped messages for one more window, to be reported
+			if age := now.Sub(e.start); age >= 2*s.window || (age >= s.window && e.suppressed == 0) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastPrune = now
+	}
+
+	e, ok := s.entries[key]
+	if !ok {
+		s.entries[key] = &entry{start: now, count: 1}
+		return true, 0
+	}
+	if now.Sub(e.start) >= s.window {
+		suppressed := e.suppressed
+		*e = entry{start: now, count: 1}
+		return true, suppressed
+	}
+	if e.count < s.burst {
+		e.count++
+		return true, 0
+	}
+	e.suppressed++
+	return false, 0
+}
+
+type sampledLogger struct {
+	base    logging.Logger
+	sampler *sampler
+	// values added with WithValues, part of the identity of the messages
+	values string
+}
+
+// New returns the logger logging at most burst identical messages per window, log itself if the window
+// or the burst are not positive.
+func New(log logging.Logger, window time.Duration, burst int) logging.Logger {
+	if window <= 0 || burst <= 0 {
+		return log
+	}
+	return &sampledLogger{
+		base: log,
+		sampler: &sampler{
+			window:  window,
+			burst:   burst,
+			now:     time.Now,
+			entries: map[string]*entry{},
+		},
+	}
+}
+
+func (l *sampledLogger) Info(msg string, keysAndValues ...any) {
+	if kv, ok := l.sample("info", msg, keysAndValues); ok {
+		l.base.Info(msg, kv...)
+	}
+}
+
+func (l *sampledLogger) Debug(msg string, keysAndValues ...any) {
+	if kv, ok := l.sample("debug", msg, keysAndValues); ok {
+		l.base.Debug(msg, kv...)
+	}
+}
+
+func (l *sampledLogger) WithValues(keysAndValues ...any) logging.Logger {
+	return &sampledLogger{
+		base:    l.base.WithValues(keysAndValues...),
+		sampler: l.sampler,
+		values:  l.values + formatValues(keysAndValues),
+	}
+}
+
+// sample returns the structured data to log the message with, false if the message is dropped.
+func (l *sampledLogger) sample(level, msg string, keysAndValues []any) ([]any, bool) {
+	ok, suppressed := l.sampler.allow(level + "\x00" + l.values + "\x00" + msg + "\x00" + formatValues(keysAndValues))
+	if !ok {
+		return nil, false
+	}
+	if suppressed > 0 {
+		keysAndValues = append(keysAndValues[:len(keysAndValues):len(keysAndValues)], "suppressed", suppressed)
+	}
+	return keysAndValues, true
+}
+
+func formatValues(keysAndValues []any) string {
+	var sb strings.Builder
+	for _, v := range keysAndValues {
+		fmt.Fprintf(&sb, "%v\x00", v)
+	}
+	return sb.String()
+}
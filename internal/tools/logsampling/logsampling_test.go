@@ -0,0 +1,81 @@
+package logsampling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+)
+
+type record struct {
+	level         string
+	msg           string
+	keysAndValues []any
+}
+
+type recorder struct {
+	records *[]record
+	values  []any
+}
+
+func (r recorder) Info(msg string, keysAndValues ...any) {
+	*r.records = append(*r.records, record{level: "info", msg: msg, keysAndValues: append(r.values, keysAndValues...)})
+}
+
+func (r recorder) Debug(msg string, keysAndValues ...any) {
+	*r.records = append(*r.records, record{level: "debug", msg: msg, keysAndValues: append(r.values, keysAndValues...)})
+}
+
+func (r recorder) WithValues(keysAndValues ...any) logging.Logger {
+	return recorder{records: r.records, values: append(append([]any{}, r.values...), keysAndValues...)}
+}
+
+func newSampled(t *testing.T, burst int) (logging.Logger, *[]record, *time.Time) {
+	t.Helper()
+	records := &[]record{}
+	log := New(recorder{records: records}, time.Minute, burst)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	log.(*sampledLogger).sampler.now = func() time.Time { return now }
+	return log, records, &now
+}
+
+func TestSampling(t *testing.T) {
+	log, records, now := newSampled(t, 2)
+
+	for i := 0; i < 5; i++ {
+		log.Debug("Observing resource", "name", "repo1")
+	}
+	log.Debug("Observing resource", "name", "repo2")
+	log.Info("Observing resource", "name", "repo1")
+	if len(*records) != 4 {
+		t.Fatalf("expected 4 messages logged, got %d", len(*records))
+	}
+
+	*now = now.Add(time.Minute)
+	log.Debug("Observing resource", "name", "repo1")
+	if len(*records) != 5 {
+		t.Fatalf("expected 5 messages logged, got %d", len(*records))
+	}
+	last := (*records)[4]
+	if n := len(last.keysAndValues); n != 4 || last.keysAndValues[2] != "suppressed" || last.keysAndValues[3] != 3 {
+		t.Errorf("expected the suppressed messages to be reported, got %v", last.keysAndValues)
+	}
+}
+
+func TestSamplingWithValues(t *testing.T) {
+	log, records, _ := newSampled(t, 1)
+
+	log.WithValues("name", "repo1").Debug("Observing resource")
+	log.WithValues("name", "repo2").Debug("Observing resource")
+	log.WithValues("name", "repo1").Debug("Observing resource")
+	if len(*records) != 2 {
+		t.Errorf("expected the messages of each object to be sampled separately, got %d", len(*records))
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	base := recorder{records: &[]record{}}
+	if _, ok := New(base, time.Minute, 0).(recorder); !ok {
+		t.Errorf("expected the logger not to be wrapped")
+	}
+}
@@ -0,0 +1,246 @@
+// Package metrics exposes the metrics of the controller in the Prometheus text format on a dedicated address,
+// without depending on a metrics library.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// Metric types.
+const (
+	TypeCounter = "counter"
+	TypeGauge   = "gauge"
+)
+
+// Sample is a value of a metric with its labels.
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+type family struct {
+	name, help, kind string
+	collect          func() []Sample
+}
+
+// Registry collects the metrics of the controller.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]family
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]family{}}
+}
+
+// Func registers the metric whose samples are collected by the function on every scrape,
+// replacing the metric registered with the same name, if any.
+func (r *Registry) Func(name, help, kind string, collect func() []Sample) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.families[name] = family{name: name, help: help, kind: kind, collect: collect}
+}
+
+// Counter registers and returns the counter with the given label names.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := NewCounter(labels...)
+	r.RegisterCounter(name, help, c)
+	return c
+}
+
+// RegisterCounter registers the counter, so that the counts made before registering it are collected too.
+func (r *Registry) RegisterCounter(name, help string, c *Counter) {
+	if c == nil {
+		return
+	}
+	r.Func(name, help, TypeCounter, c.samples)
+}
+
+// Write writes the metrics in the Prometheus text format, sorted by name.
+func (r *Registry) Write(w io.Writer) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	families := make([]family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.Unlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	for _, f := range families {
+		samples := f.collect()
+		sort.SliceStable(samples, func(i, j int) bool { return formatLabels(samples[i].Labels) < formatLabels(samples[j].Labels) })
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escape(f.help, false), f.name, f.kind); err != nil {
+			return err
+		}
+		for _, s := range samples {
+			if _, err := fmt.Fprintf(w, "%s%s %s\n", f.name, formatLabels(s.Labels), formatValue(s.Value)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Handler returns the handler serving the metrics under /metrics.
+func (r *Registry) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+	return mux
+}
+
+// Serve serves the metrics on the address in background until the context is done.
+// An empty address disables the metrics endpoint.
+func Serve(ctx context.Context, log logging.Logger, addr string, r *Registry) {
+	if addr == "" || r == nil {
+		return
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           r.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		log.Info("Serving metrics.", "address", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Debug("Serving metrics.", "error", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+}
+
+type counterValue struct {
+	labels map[string]string
+	value  float64
+}
+
+// Counter is a monotonic counter partitioned by the values of its labels.
+type Counter struct {
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+// NewCounter returns the counter with the given label names, not registered yet.
+func NewCounter(labels ...string) *Counter {
+	return &Counter{labels: labels, values: map[string]*counterValue{}}
+}
+
+// Inc increments by one the counter of the label values, given in the order of the label names.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds the delta to the counter of the label values, given in the order of the label names.
+func (c *Counter) Add(delta float64, values ...string) {
+	if c == nil {
+		return
+	}
+	key := strings.Join(values, "\xff")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.values[key]
+	if !ok {
+		v = &counterValue{labels: map[string]string{}}
+		for i, name := range c.labels {
+			if i < len(values) {
+				v.labels[name] = values[i]
+			} else {
+				v.labels[name] = ""
+			}
+		}
+		c.values[key] = v
+	}
+	v.value += delta
+}
+
+// Value returns the counter of the label values.
+func (c *Counter) Value(values ...string) float64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v, ok := c.values[strings.Join(values, "\xff")]; ok {
+		return v.value
+	}
+	return 0
+}
+
+func (c *Counter) samples() []Sample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	samples := make([]Sample, 0, len(c.values))
+	for _, v := range c.values {
+		samples = append(samples, Sample{Labels: v.labels, Value: v.value})
+	}
+	return samples
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", name, escape(labels[name], true)))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escape escapes the backslashes and the new lines, and the double quotes of the label values.
+func escape(s string, quotes bool) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if quotes {
+		s = strings.ReplaceAll(s, `"`, `\"`)
+	}
+	return s
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	calls := r.Counter("rest_controller_calls_total", "Calls to the external APIs.", "server", "code")
+	calls.Inc("https://api.github.com", "200")
+	calls.Inc("https://api.github.com", "200")
+	calls.Add(3, "https://gitlab.com/api", "503")
+	r.Func("rest_controller_up", "Whether the\ncontroller is up.", TypeGauge, func() []Sample {
+		return []Sample{{Labels: map[string]string{"name": `a "quoted" \ name`}, Value: 1}}
+	})
+
+	if v := calls.Value("https://api.github.com", "200"); v != 2 {
+		t.Errorf("expected 2 calls, got %v", v)
+	}
+
+	srv := httptest.NewServer(r.Handler())
+	defer srv.Close()
+	res, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+
+	expected := strings.Join([]string{
+		"# HELP rest_controller_calls_total Calls to the external APIs.",
+		"# TYPE rest_controller_calls_total counter",
+		`rest_controller_calls_total{code="200",server="https://api.github.com"} 2`,
+		`rest_controller_calls_total{code="503",server="https://gitlab.com/api"} 3`,
+		`# HELP rest_controller_up Whether the\ncontroller is up.`,
+		"# TYPE rest_controller_up gauge",
+		`rest_controller_up{name="a \"quoted\" \\ name"} 1`,
+		"",
+	}, "\n")
+	if string(body) != expected {
+		t.Errorf("unexpected metrics:\n%s\nexpected:\n%s", body, expected)
+	}
+}
+
+func TestNilRegistry(t *testing.T) {
+	var r *Registry
+	c := r.Counter("rest_controller_calls_total", "Calls to the external APIs.")
+	c.Inc()
+	if err := r.Write(io.Discard); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	var nilCounter *Counter
+	nilCounter.Inc()
+	if v := nilCounter.Value(); v != 0 {
+		t.Errorf("expected no value, got %v", v)
+	}
+}
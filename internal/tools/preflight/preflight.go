@@ -0,0 +1,134 @@
+// Package preflight verifies on startup that the resources of the controller can be managed: their
+// RestDefinitions are found, their OAS documents are fetched and parsed, their verbs are described by
+// the documents and, optionally, the servers are reachable with the resources credentials.
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"github.com/lucasepe/httplib"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// Options configures the preflight checks.
+type Options struct {
+	DynamicClient dynamic.Interface
+	// GVR and Kind of the resources managed by the controller
+	GVR  schema.GroupVersionResource
+	Kind string
+	// Namespace of the resources, all the namespaces if empty
+	Namespace string
+	// Connectivity: if true, the servers are called with the credentials of the first resource found
+	Connectivity bool
+	// SwaggerInfoGetter resolves the credentials of the resources, required by the connectivity check
+	SwaggerInfoGetter getter.Getter
+	// HTTPClient performs the connectivity check, defaults to http.DefaultClient
+	HTTPClient *http.Client
+	Logger     logging.Logger
+	// buildClient builds the client from the OAS document, defaults to restclient.BuildClient
+	buildClient func(ctx context.Context, dyn dynamic.Interface, oasPath string) (*restclient.UnstructuredClient, error)
+}
+
+// Run runs the preflight checks, returning the problems found.
+func Run(ctx context.Context, opts Options) error {
+	if opts.buildClient == nil {
+		opts.buildClient = restclient.BuildClient
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	if opts.Logger == nil {
+		opts.Logger = logging.NewNopLogger()
+	}
+
+	definitions, err := getter.ListDefinitions(ctx, opts.DynamicClient, opts.Namespace, opts.GVR.Group, opts.Kind)
+	if err != nil {
+		return fmt.Errorf("listing RestDefinitions: %w", err)
+	}
+	if len(definitions) == 0 {
+		return fmt.Errorf("no RestDefinition found for %s %s in namespace %q", opts.GVR.Group, opts.Kind, opts.Namespace)
+	}
+
+	var errs []error
+	for _, def := range definitions {
+		log := opts.Logger.WithValues("restDefinition", def.Namespace+"/"+def.Name)
+		if err := checkDefinition(ctx, opts, def); err != nil {
+			errs = append(errs, fmt.Errorf("RestDefinition %s/%s: %w", def.Namespace, def.Name, err))
+			continue
+		}
+		log.Info("Preflight checks passed.", "oasPath", def.URL)
+	}
+	return errors.Join(errs...)
+}
+
+func checkDefinition(ctx context.Context, opts Options, def getter.Definition) error {
+	cli, err := opts.buildClient(ctx, opts.DynamicClient, def.URL)
+	if err != nil {
+		return fmt.Errorf("loading OAS %s: %w", def.URL, err)
+	}
+
+	var errs []error
+	for _, verb := range def.Resource.VerbsDescription {
+		if verb.RawMethod {
+			continue
+		}
+		if err := cli.CheckOperation(verb.Method, verb.Path); err != nil {
+			errs = append(errs, fmt.Errorf("action %s: %w", verb.Action, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	if opts.Connectivity {
+		return checkConnectivity(ctx, opts, cli)
+	}
+	return nil
+}
+
+// checkConnectivity calls the server with the credentials of the first resource found, if any;
+// any response but an authentication failure proves the server reachable.
+func checkConnectivity(ctx context.Context, opts Options, cli *restclient.UnstructuredClient) error {
+	var auth httplib.AuthMethod
+	list, err := opts.DynamicClient.Resource(opts.GVR).Namespace(opts.Namespace).List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return fmt.Errorf("listing resources: %w", err)
+	}
+	if len(list.Items) > 0 && opts.SwaggerInfoGetter != nil {
+		info, err := opts.SwaggerInfoGetter.Get(&list.Items[0])
+		if err != nil {
+			return fmt.Errorf("resolving credentials of %s/%s: %w", list.Items[0].GetNamespace(), list.Items[0].GetName(), err)
+		}
+		if info != nil {
+			auth = info.Auth
+			if info.Resource.ServerURL != "" {
+				cli.Server = info.Resource.ServerURL
+			}
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, cli.Server, nil)
+	if err != nil {
+		return fmt.Errorf("calling server %s: %w", cli.Server, err)
+	}
+	if auth != nil {
+		auth.SetAuth(req)
+	}
+	res, err := opts.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling server %s: %w", cli.Server, err)
+	}
+	res.Body.Close()
+	if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("calling server %s: authentication failed (%d)", cli.Server, res.StatusCode)
+	}
+	return nil
+}
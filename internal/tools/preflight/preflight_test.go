@@ -0,0 +1,142 @@
+package preflight
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/lucasepe/httplib"
+	"github.com/pb33f/libopenapi"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+const spec = `openapi: 3.0.0
+info:
+  title: test
+  version: "1.0"
+paths:
+  /repos/{owner}/{repo}:
+    get:
+      responses:
+        "200":
+          description: ok
+    delete:
+      responses:
+        "204":
+          description: deleted
+`
+
+var gvr = schema.GroupVersionResource{Group: "gen.github.com", Version: "v1alpha1", Resource: "repos"}
+
+func definition(name string, verbs ...interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "swaggergen.krateo.io/v1alpha1",
+		"kind":       "RestDefinition",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "default"},
+		"spec": map[string]interface{}{
+			"oasPath":       "https://example.com/openapi.yaml",
+			"resourceGroup": "gen.github.com",
+			"resource": map[string]interface{}{
+				"kind":             "Repo",
+				"identifiers":      []interface{}{"id"},
+				"verbsDescription": verbs,
+			},
+		},
+	}}
+}
+
+func verb(action, method, path string) map[string]interface{} {
+	return map[string]interface{}{"action": action, "method": method, "path": path}
+}
+
+type staticAuth struct {
+	info *getter.Info
+}
+
+func (g staticAuth) Get(*unstructured.Unstructured) (*getter.Info, error) {
+	return g.info, nil
+}
+
+func newOptions(t *testing.T, server string, objects ...runtime.Object) Options {
+	scheme := runtime.NewScheme()
+	dyn := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, map[schema.GroupVersionResource]string{
+		{Group: "swaggergen.krateo.io", Version: "v1alpha1", Resource: "restdefinitions"}: "RestDefinitionList",
+		gvr: "RepoList",
+	}, objects...)
+
+	return Options{
+		DynamicClient: dyn,
+		GVR:           gvr,
+		Kind:          "Repo",
+		Namespace:     "default",
+		buildClient: func(context.Context, dynamic.Interface, string) (*restclient.UnstructuredClient, error) {
+			d, err := libopenapi.NewDocument([]byte(spec))
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc, errs := d.BuildV3Model()
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			return &restclient.UnstructuredClient{Server: server, DocScheme: doc}, nil
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	ok := definition("def-ok", verb("get", "GET", "/repos/{owner}/{repo}"), verb("delete", "DELETE", "/repos/{owner}/{repo}"))
+	if err := Run(context.Background(), newOptions(t, "", ok)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	broken := definition("def-broken", verb("create", "POST", "/repos/{owner}/{repo}"), verb("update", "PATCH", "/orgs/{org}"))
+	err := Run(context.Background(), newOptions(t, "", broken))
+	if err == nil {
+		t.Fatalf("expected the missing operations to be reported")
+	}
+	for _, expected := range []string{"def-broken", "action create: operation not found: POST", "action update: path not found: /orgs/{org}"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in %q", expected, err)
+		}
+	}
+
+	if err := Run(context.Background(), newOptions(t, "")); err == nil || !strings.Contains(err.Error(), "no RestDefinition found") {
+		t.Errorf("expected the missing definition to be reported, got %v", err)
+	}
+}
+
+func TestRunConnectivity(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	repo := &unstructured.Unstructured{}
+	repo.SetAPIVersion("gen.github.com/v1alpha1")
+	repo.SetKind("Repo")
+	repo.SetNamespace("default")
+	repo.SetName("repo1")
+	def := definition("def", verb("get", "GET", "/repos/{owner}/{repo}"))
+
+	for token, expectErr := range map[string]bool{"valid": false, "expired": true} {
+		opts := newOptions(t, srv.URL, def, repo)
+		opts.Connectivity = true
+		opts.SwaggerInfoGetter = staticAuth{info: &getter.Info{Auth: &httplib.TokenAuth{Token: token}}}
+		err := Run(context.Background(), opts)
+		if (err != nil) != expectErr {
+			t.Errorf("token %s: unexpected result %v", token, err)
+		}
+	}
+}
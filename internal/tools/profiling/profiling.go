@@ -0,0 +1,52 @@
+// Package profiling exposes the pprof endpoints of the controller on a dedicated address,
+// so that CPU and heap profiles can be captured without exposing them on other ports.
+package profiling
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// Handler returns the handler serving the pprof endpoints under /debug/pprof/.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// Serve serves the pprof endpoints on the address in background until the context is done.
+// An empty address disables the profiling.
+func Serve(ctx context.Context, log logging.Logger, addr string) {
+	if addr == "" {
+		return
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		log.Info("Serving pprof endpoints.", "address", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Debug("Serving pprof endpoints.", "error", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+}
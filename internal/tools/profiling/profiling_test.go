@@ -0,0 +1,32 @@
+package profiling
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	srv := httptest.NewServer(Handler())
+	defer srv.Close()
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{path: "/debug/pprof/", code: http.StatusOK},
+		{path: "/debug/pprof/heap", code: http.StatusOK},
+		{path: "/debug/pprof/cmdline", code: http.StatusOK},
+		{path: "/metrics", code: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		res, err := http.Get(srv.URL + tt.path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+		if res.StatusCode != tt.code {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.code, res.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Package provenance stamps the mutations of the external resources with headers telling where they originate
+// from (the cluster, the custom resource and the reconcile), optionally signed with an HMAC of a shared secret,
+// so that the owners of the external APIs can trace and verify the changes made by the controller.
+package provenance
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// HeaderCluster is the name of the cluster of the controller
+	HeaderCluster = "X-Krateo-Cluster"
+	// HeaderResourceUID is the UID of the custom resource the mutation is made for
+	HeaderResourceUID = "X-Krateo-Resource-UID"
+	// HeaderReconcileID identifies the reconcile the mutation is made by
+	HeaderReconcileID = "X-Krateo-Reconcile-ID"
+	// HeaderTimestamp is the Unix time the mutation is signed at
+	HeaderTimestamp = "X-Krateo-Timestamp"
+	// HeaderSignature is the HMAC-SHA256 signature of the mutation, as sha256=<hex>
+	HeaderSignature = "X-Krateo-Signature"
+)
+
+// Stamper stamps the mutations with the provenance headers.
+type Stamper struct {
+	// ClusterName is the name of the cluster of the controller
+	ClusterName string
+	// Secret signs the mutations, unsigned if empty
+	Secret []byte
+
+	now func() time.Time
+}
+
+// New returns the stamper of the mutations made from the given cluster, signed with the secret if not empty.
+func New(clusterName string, secret []byte) *Stamper {
+	return &Stamper{ClusterName: clusterName, Secret: secret, now: time.Now}
+}
+
+// Stamp sets the provenance headers of the request made for the custom resource with the given UID by the given reconcile.
+func (s *Stamper) Stamp(req *http.Request, uid, reconcileID string) {
+	if s.ClusterName != "" {
+		req.Header.Set(HeaderCluster, s.ClusterName)
+	}
+	req.Header.Set(HeaderResourceUID, uid)
+	req.Header.Set(HeaderReconcileID, reconcileID)
+	if len(s.Secret) == 0 {
+		return
+	}
+
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	timestamp := strconv.FormatInt(now().Unix(), 10)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, "sha256="+Sign(s.Secret, req.Method, req.URL.RequestURI(), timestamp, s.ClusterName, uid, reconcileID, hashBody(req)))
+}
+
+// Sign returns the hex encoded HMAC-SHA256, with the secret, of the canonical form of a mutation: the method,
+// the request URI (path and query), the timestamp, the cluster name, the UID of the custom resource, the reconcile
+// ID and the hex encoded SHA-256 of the body (empty if none), each followed by a newline but the last one.
+// The owners of the APIs verify the signatures by computing it from the request and its headers.
+func Sign(secret []byte, method, requestURI, timestamp, cluster, uid, reconcileID, bodyHash string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join([]string{method, requestURI, timestamp, cluster, uid, reconcileID, bodyHash}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// hashBody returns the hex encoded SHA-256 of the request body, empty if none.
+func hashBody(req *http.Request) string {
+	if req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil || body == nil || body == http.NoBody {
+		return ""
+	}
+	defer body.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+// Transport is an http.RoundTripper stamping the non read-only requests performed through it.
+type Transport struct {
+	Base        http.RoundTripper
+	Stamper     *Stamper
+	UID         string
+	ReconcileID string
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return base.RoundTrip(req)
+	}
+
+	// A RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+	t.Stamper.Stamp(req, t.UID, t.ReconcileID)
+	return base.RoundTrip(req)
+}
+
+// Client returns a copy of the http client stamping the mutations made for the custom resource by the reconcile
+// of the context; the client itself if the stamper is nil.
+func Client(ctx context.Context, s *Stamper, cli *http.Client, mg *unstructured.Unstructured) *http.Client {
+	if s == nil {
+		return cli
+	}
+	if cli == nil {
+		cli = http.DefaultClient
+	}
+	c := *cli
+	c.Transport = &Transport{
+		Base:        cli.Transport,
+		Stamper:     s,
+		UID:         string(mg.GetUID()),
+		ReconcileID: ReconcileID(ctx),
+	}
+	return &c
+}
+
+type reconcileKey struct{}
+
+// WithReconcileID returns the context of a reconcile, identified by a random ID.
+func WithReconcileID(ctx context.Context) context.Context {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return context.WithValue(ctx, reconcileKey{}, hex.EncodeToString(b))
+}
+
+// ReconcileID returns the ID of the reconcile of the context, empty if none.
+func ReconcileID(ctx context.Context) string {
+	id, _ := ctx.Value(reconcileKey{}).(string)
+	return id
+}
@@ -0,0 +1,76 @@
+package provenance
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestClient(t *testing.T) {
+	secret := []byte("shared-secret")
+	var received []*http.Request
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r)
+		bodies = append(bodies, string(body))
+	}))
+	defer srv.Close()
+
+	s := New("prod-eu", secret)
+	s.now = func() time.Time { return time.Unix(1700000000, 0) }
+	mg := &unstructured.Unstructured{}
+	mg.SetUID(types.UID("6f1c2a"))
+	ctx := WithReconcileID(context.Background())
+	cli := Client(ctx, s, srv.Client(), mg)
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/repos?org=krateo", strings.NewReader(`{"name":"repo"}`))
+	if _, err := cli.Do(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Header.Get(HeaderSignature) != "" {
+		t.Errorf("expected the original request not to be modified")
+	}
+	get, _ := http.NewRequest(http.MethodGet, srv.URL+"/repos/1", nil)
+	if _, err := cli.Do(get); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	post := received[0]
+	if post.Header.Get(HeaderCluster) != "prod-eu" || post.Header.Get(HeaderResourceUID) != "6f1c2a" ||
+		post.Header.Get(HeaderReconcileID) != ReconcileID(ctx) || ReconcileID(ctx) == "" {
+		t.Errorf("unexpected provenance headers: %v", post.Header)
+	}
+	hash := sha256.Sum256([]byte(bodies[0]))
+	expected := "sha256=" + Sign(secret, http.MethodPost, "/repos?org=krateo", "1700000000", "prod-eu", "6f1c2a", ReconcileID(ctx), hex.EncodeToString(hash[:]))
+	if post.Header.Get(HeaderTimestamp) != "1700000000" || post.Header.Get(HeaderSignature) != expected {
+		t.Errorf("expected signature %s, got %s", expected, post.Header.Get(HeaderSignature))
+	}
+	if received[1].Header.Get(HeaderReconcileID) != "" {
+		t.Errorf("expected the read-only requests not to be stamped")
+	}
+
+	if Client(ctx, nil, srv.Client(), mg) != srv.Client() {
+		t.Errorf("expected the client to be returned as is without stamper")
+	}
+}
+
+func TestUnsigned(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/repos/1", nil)
+	New("", nil).Stamp(req, "6f1c2a", "r1")
+	if req.Header.Get(HeaderSignature) != "" || req.Header.Get(HeaderTimestamp) != "" || req.Header.Get(HeaderCluster) != "" {
+		t.Errorf("expected an unsigned stamp without cluster, got %v", req.Header)
+	}
+	if req.Header.Get(HeaderResourceUID) != "6f1c2a" || req.Header.Get(HeaderReconcileID) != "r1" {
+		t.Errorf("unexpected provenance headers: %v", req.Header)
+	}
+}
@@ -0,0 +1,111 @@
+// Package quota limits the external calls made for each resource within a sliding window, protecting the
+// pay-per-call APIs from the runaway reconcile loops caused by a bad RestDefinition.
+package quota
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultWindow is the period the calls are counted in
+const DefaultWindow = time.Hour
+
+// spent is a number of calls made at the same time.
+type spent struct {
+	at    time.Time
+	calls int
+}
+
+// Budget tracks the calls made for each key (e.g. a resource) within the window.
+type Budget struct {
+	// Limit is the number of calls allowed per key within Window, unless the check tells another one
+	Limit int
+	// Window is the period the calls are counted in
+	Window time.Duration
+
+	mu    sync.Mutex
+	spent map[string][]spent
+}
+
+// New returns the budget allowing limit calls per key within the window. A limit lower than or equal to zero
+// allows unlimited calls, unless the check tells another limit.
+func New(limit int, window time.Duration) *Budget {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Budget{
+		Limit:  limit,
+		Window: window,
+		spent:  map[string][]spent{},
+	}
+}
+
+// Spend records the given number of calls made for the key.
+func (b *Budget) Spend(key string, calls int, now time.Time) {
+	if b == nil || calls <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.spent[key] = append(b.recent(key, now), spent{at: now, calls: calls})
+}
+
+// Exceeded returns true if the calls made for the key within the window reached the limit, the Limit of the budget
+// if lower than or equal to zero, with the number of calls made and the time until a call leaves the window.
+func (b *Budget) Exceeded(key string, limit int, now time.Time) (bool, int, time.Duration) {
+	if b == nil {
+		return false, 0, 0
+	}
+	if limit <= 0 {
+		limit = b.Limit
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	recent := b.recent(key, now)
+	if len(recent) == 0 {
+		delete(b.spent, key)
+		return false, 0, 0
+	}
+	b.spent[key] = recent
+
+	used := 0
+	for _, s := range recent {
+		used += s.calls
+	}
+	if limit <= 0 || used < limit {
+		return false, used, 0
+	}
+	// The budget is available again once enough calls left the window
+	over := used - limit
+	for _, s := range recent {
+		over -= s.calls
+		if over < 0 {
+			return true, used, s.at.Add(b.Window).Sub(now)
+		}
+	}
+	return true, used, 0
+}
+
+// Forget forgets the calls made for the key, e.g. once the resource is deleted.
+func (b *Budget) Forget(key string) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.spent, key)
+}
+
+// recent returns the calls made for the key within the window.
+func (b *Budget) recent(key string, now time.Time) []spent {
+	recent := []spent{}
+	for _, s := range b.spent[key] {
+		if now.Sub(s.at) < b.Window {
+			recent = append(recent, s)
+		}
+	}
+	return recent
+}
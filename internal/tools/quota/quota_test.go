@@ -0,0 +1,59 @@
+package quota
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBudget(t *testing.T) {
+	b := New(10, time.Hour)
+	start := time.Now()
+
+	b.Spend("repo1", 6, start)
+	if exceeded, used, _ := b.Exceeded("repo1", 0, start); exceeded || used != 6 {
+		t.Errorf("expected the budget not to be exceeded with %d calls", used)
+	}
+	b.Spend("repo1", 5, start.Add(20*time.Minute))
+	exceeded, used, retryAfter := b.Exceeded("repo1", 0, start.Add(30*time.Minute))
+	if !exceeded || used != 11 {
+		t.Fatalf("expected the budget to be exceeded, got %v with %d calls", exceeded, used)
+	}
+	if retryAfter != 30*time.Minute {
+		t.Errorf("expected the budget to be available once the first calls leave the window, got %s", retryAfter)
+	}
+	if exceeded, _, _ := b.Exceeded("repo1", 20, start.Add(30*time.Minute)); exceeded {
+		t.Errorf("expected the limit of the check to override the one of the budget")
+	}
+	if exceeded, _, _ := b.Exceeded("repo2", 0, start); exceeded {
+		t.Errorf("expected the budget to be tracked per key")
+	}
+
+	if exceeded, used, _ := b.Exceeded("repo1", 0, start.Add(time.Hour)); exceeded || used != 5 {
+		t.Errorf("expected the calls to leave the window, got %v with %d calls", exceeded, used)
+	}
+	b.Forget("repo1")
+	if _, used, _ := b.Exceeded("repo1", 0, start.Add(time.Hour)); used != 0 {
+		t.Errorf("expected the calls to be forgotten, got %d", used)
+	}
+}
+
+func TestUnlimited(t *testing.T) {
+	b := New(0, 0)
+	if b.Window != DefaultWindow {
+		t.Errorf("expected the default window, got %s", b.Window)
+	}
+	b.Spend("repo1", 1000, time.Now())
+	if exceeded, _, _ := b.Exceeded("repo1", 0, time.Now()); exceeded {
+		t.Errorf("expected no limit")
+	}
+	if exceeded, _, _ := b.Exceeded("repo1", 100, time.Now()); !exceeded {
+		t.Errorf("expected the limit of the check to apply")
+	}
+
+	var nilBudget *Budget
+	nilBudget.Spend("repo1", 1, time.Now())
+	nilBudget.Forget("repo1")
+	if exceeded, _, _ := nilBudget.Exceeded("repo1", 1, time.Now()); exceeded {
+		t.Errorf("expected a nil budget to allow every call")
+	}
+}
@@ -0,0 +1,133 @@
+// Package requeue reconciles the resources again after a given delay, sooner than the resync interval
+// (e.g. to poll a resource still being provisioned, or to retry after the delay asked by a rate-limited API).
+// The runtime has no way to requeue a resource after a delay, so the resources are queued in a delaying
+// queue of their own and reconciled by its worker as the runtime does, through the external client.
+package requeue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/controller"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/meta"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// reconcileTimeout is the maximum duration of a requeued reconcile.
+const reconcileTimeout = 5 * time.Minute
+
+// ResolveGVR returns the GVR of the resources of the given GVK.
+type ResolveGVR func(gvk schema.GroupVersionKind) (schema.GroupVersionResource, error)
+
+type ref struct {
+	gvk       schema.GroupVersionKind
+	namespace string
+	name      string
+}
+
+// Requeuer schedules the requeues of the resources, keeping the earliest one for each resource.
+type Requeuer struct {
+	dynamic dynamic.Interface
+	resolve ResolveGVR
+	log     logging.Logger
+
+	queue workqueue.TypedDelayingInterface[ref]
+}
+
+// New returns the requeuer getting the resources through the dynamic client. The requeues are
+// scheduled right away, but the resources are reconciled only once the requeuer runs.
+func New(dyn dynamic.Interface, resolve ResolveGVR, log logging.Logger) *Requeuer {
+	return &Requeuer{
+		dynamic: dyn,
+		resolve: resolve,
+		log:     log,
+		queue:   workqueue.NewTypedDelayingQueueWithConfig(workqueue.TypedDelayingQueueConfig[ref]{Name: "requeue"}),
+	}
+}
+
+// After requeues the resource after the delay, unless an earlier requeue is already scheduled.
+func (r *Requeuer) After(mg *unstructured.Unstructured, delay time.Duration, reason string) {
+	if r == nil || mg == nil || delay <= 0 {
+		return
+	}
+	key := ref{gvk: mg.GroupVersionKind(), namespace: mg.GetNamespace(), name: mg.GetName()}
+	r.log.Debug("Requeuing resource", "name", key.name, "namespace", key.namespace, "after", delay, "reason", reason)
+	r.queue.AddAfter(key, delay)
+}
+
+// Add requeues the resource right away, to be reconciled as soon as the requeuer is free.
+func (r *Requeuer) Add(mg *unstructured.Unstructured, reason string) {
+	if r == nil || mg == nil {
+		return
+	}
+	key := ref{gvk: mg.GroupVersionKind(), namespace: mg.GetNamespace(), name: mg.GetName()}
+	r.log.Debug("Requeuing resource", "name", key.name, "namespace", key.namespace, "reason", reason)
+	r.queue.Add(key)
+}
+
+// Run reconciles the requeued resources through the external client until the context is done,
+// a resource being reconciled once at a time.
+func (r *Requeuer) Run(ctx context.Context, client controller.ExternalClient) {
+	if r == nil {
+		return
+	}
+	go func() {
+		<-ctx.Done()
+		r.queue.ShutDown()
+	}()
+
+	for {
+		key, shutdown := r.queue.Get()
+		if shutdown {
+			return
+		}
+		if err := r.reconcile(ctx, client, key); err != nil {
+			r.log.Debug("Reconciling requeued resource", "name", key.name, "namespace", key.namespace, "error", err)
+		}
+		r.queue.Done(key)
+	}
+}
+
+// reconcile observes the resource, then creates or updates it as the runtime does, ignoring the resources
+// deleted, being deleted or paused meanwhile, whose reconciles are left to the runtime.
+func (r *Requeuer) reconcile(ctx context.Context, client controller.ExternalClient, key ref) error {
+	gvr, err := r.resolve(key.gvk)
+	if err != nil {
+		return fmt.Errorf("resolving GVR of %s: %w", key.gvk, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, reconcileTimeout)
+	defer cancel()
+	mg, err := r.dynamic.Resource(gvr).Namespace(key.namespace).Get(ctx, key.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !mg.GetDeletionTimestamp().IsZero() || meta.IsPaused(mg) {
+		return nil
+	}
+
+	obs, err := client.Observe(ctx, mg)
+	if apierrors.IsNotFound(err) {
+		return client.Update(ctx, mg)
+	}
+	if err != nil {
+		return err
+	}
+	if !obs.ResourceExists {
+		return client.Create(ctx, mg)
+	}
+	if !obs.ResourceUpToDate {
+		return client.Update(ctx, mg)
+	}
+	return nil
+}
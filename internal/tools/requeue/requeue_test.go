@@ -0,0 +1,182 @@
+package requeue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/controller"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/event"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/meta"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/shortid"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+var gvr = schema.GroupVersionResource{Group: "gen.github.com", Version: "v1alpha1", Resource: "repos"}
+
+func resolve(schema.GroupVersionKind) (schema.GroupVersionResource, error) {
+	return gvr, nil
+}
+
+func newRepo() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("gen.github.com/v1alpha1")
+	obj.SetKind("Repo")
+	obj.SetNamespace("default")
+	obj.SetName("repo1")
+	return obj
+}
+
+func newFakeClient(objects ...runtime.Object) *fake.FakeDynamicClient {
+	return fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		gvr: "RepoList",
+	}, objects...)
+}
+
+// recorder records the calls of the external client, the observations telling the resource exists if found.
+type recorder struct {
+	found  bool
+	onCall func(call string, mg *unstructured.Unstructured)
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) record(call string, mg *unstructured.Unstructured) {
+	r.mu.Lock()
+	r.calls = append(r.calls, call)
+	r.mu.Unlock()
+	if r.onCall != nil {
+		r.onCall(call, mg)
+	}
+}
+
+func (r *recorder) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+func (r *recorder) Observe(_ context.Context, mg *unstructured.Unstructured) (controller.ExternalObservation, error) {
+	r.record("observe", mg)
+	return controller.ExternalObservation{ResourceExists: r.found, ResourceUpToDate: r.found}, nil
+}
+
+func (r *recorder) Create(_ context.Context, mg *unstructured.Unstructured) error {
+	r.record("create", mg)
+	return nil
+}
+
+func (r *recorder) Update(_ context.Context, mg *unstructured.Unstructured) error {
+	r.record("update", mg)
+	return nil
+}
+
+func (r *recorder) Delete(_ context.Context, mg *unstructured.Unstructured) error {
+	r.record("delete", mg)
+	return nil
+}
+
+// waitCalls waits for the recorder to record n calls, returning the calls recorded.
+func waitCalls(t *testing.T, rec *recorder, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if calls := rec.recorded(); len(calls) >= n {
+			return calls
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected %d calls, got %v", n, rec.recorded())
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	mg := newRepo()
+	r := New(newFakeClient(mg), resolve, logging.NewNopLogger())
+	rec := &recorder{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx, rec)
+
+	start := time.Now()
+	r.After(mg, time.Hour, "pending")
+	r.After(mg, 20*time.Millisecond, "retry-after")
+	r.After(mg, time.Hour, "pending")
+
+	// The earliest requeue is kept, the resource missing being created as by the runtime
+	calls := waitCalls(t, rec, 2)
+	if time.Since(start) > time.Second || calls[0] != "observe" || calls[1] != "create" {
+		t.Errorf("expected the resource to be observed and created, got %v", calls)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if calls := rec.recorded(); len(calls) != 2 {
+		t.Errorf("expected the resource to be reconciled once, got %v", calls)
+	}
+}
+
+func TestRunSkipped(t *testing.T) {
+	paused := newRepo()
+	paused.SetName("paused")
+	paused.SetAnnotations(map[string]string{meta.AnnotationKeyReconciliationPaused: "true"})
+	r := New(newFakeClient(paused), resolve, logging.NewNopLogger())
+	rec := &recorder{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx, rec)
+
+	r.After(newRepo(), time.Millisecond, "pending")
+	r.After(paused, time.Millisecond, "pending")
+	time.Sleep(50 * time.Millisecond)
+	if calls := rec.recorded(); len(calls) != 0 {
+		t.Errorf("expected the deleted and paused resources to be skipped, got %v", calls)
+	}
+
+	var nilRequeuer *Requeuer
+	nilRequeuer.After(newRepo(), time.Second, "pending")
+	nilRequeuer.Add(newRepo(), "pending")
+}
+
+// TestRequeueBeforeResync runs the controller of the runtime, whose resync is far away: the resource is
+// observed once added to the informer, and observed again as soon as requeued.
+func TestRequeueBeforeResync(t *testing.T) {
+	mg := newRepo()
+	dyn := newFakeClient(mg)
+	r := New(dyn, resolve, logging.NewNopLogger())
+
+	rec := &recorder{found: true}
+	var once sync.Once
+	rec.onCall = func(call string, mg *unstructured.Unstructured) {
+		once.Do(func() { r.After(mg, 50*time.Millisecond, "pending") })
+	}
+
+	ctrl := controller.New(shortid.MustNew(1, shortid.DefaultABC, 1), controller.Options{
+		Client:         dyn,
+		GVR:            gvr,
+		Namespace:      "default",
+		ResyncInterval: time.Hour,
+		Recorder:       event.NewNopRecorder(),
+		Logger:         logging.NewNopLogger(),
+		ExternalClient: rec,
+	})
+	if ctrl == nil {
+		t.Fatalf("expected the controller to be created")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ctrl.Run(ctx, 1)
+	go r.Run(ctx, rec)
+
+	calls := waitCalls(t, rec, 2)
+	if calls[0] != "observe" || calls[1] != "observe" {
+		t.Errorf("expected the resource to be observed twice, got %v", calls)
+	}
+}
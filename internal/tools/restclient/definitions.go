@@ -0,0 +1,220 @@
+package getter
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+var definitionsGVR = schema.GroupVersionResource{
+	Group:    "swaggergen.krateo.io",
+	Version:  "v1alpha1",
+	Resource: "restdefinitions",
+}
+
+// AnnotationKeyRestDefinition selects by name the RestDefinition managing the resource, among the ones
+// declaring its kind and group.
+const AnnotationKeyRestDefinition = "krateo.io/rest-definition"
+
+type definitionKey struct {
+	namespace string
+	gk        schema.GroupKind
+	// name: the RestDefinition selected by the annotation, empty if none
+	name string
+}
+
+type definitionEntry struct {
+	// url: the OAS path
+	url string
+	// resource: the JSON of spec.resource
+	resource []byte
+	// ambiguity: the RestDefinitions managing the resource besides the selected one, empty if none
+	ambiguity string
+}
+
+// noDefinitionsError is returned when the namespace has no RestDefinition at all.
+type noDefinitionsError struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+}
+
+func (e *noDefinitionsError) Error() string {
+	return fmt.Sprintf("no definitions found for '%v' in namespace: %s", e.gvr, e.namespace)
+}
+
+// unselectedDefinitionError is returned when the RestDefinition selected by the annotation does not manage the resource.
+type unselectedDefinitionError struct {
+	name      string
+	gk        schema.GroupKind
+	namespace string
+}
+
+func (e *unselectedDefinitionError) Error() string {
+	return fmt.Sprintf("definition %q selected by the %s annotation does not manage kind %s of group %s in namespace: %s",
+		e.name, AnnotationKeyRestDefinition, e.gk.Kind, e.gk.Group, e.namespace)
+}
+
+// selectDefinition returns the RestDefinition selected by the annotation of the resource, otherwise the newest one,
+// and the message reporting the other candidates if selected implicitly.
+func selectDefinition(un *unstructured.Unstructured, namespace string, candidates []unstructured.Unstructured) (*unstructured.Unstructured, string, error) {
+	if name := un.GetAnnotations()[AnnotationKeyRestDefinition]; name != "" {
+		for i := range candidates {
+			if candidates[i].GetName() == name {
+				return &candidates[i], "", nil
+			}
+		}
+		return nil, "", &unselectedDefinitionError{name: name, gk: un.GroupVersionKind().GroupKind(), namespace: namespace}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ti, tj := candidates[i].GetCreationTimestamp(), candidates[j].GetCreationTimestamp()
+		if !ti.Equal(&tj) {
+			return tj.Before(&ti)
+		}
+		return candidates[i].GetName() < candidates[j].GetName()
+	})
+	if len(candidates) == 1 {
+		return &candidates[0], "", nil
+	}
+	names := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		names = append(names, c.GetName())
+	}
+	ambiguity := fmt.Sprintf("RestDefinitions %s manage kind %s of group %s, using the newest one (%s); select one with the %s annotation",
+		strings.Join(names, ", "), un.GetKind(), un.GroupVersionKind().Group, candidates[0].GetName(), AnnotationKeyRestDefinition)
+	return &candidates[0], ambiguity, nil
+}
+
+// definitionCache caches the RestDefinitions looked up for the resources, invalidating the ones
+// of a namespace as soon as a RestDefinition in the namespace, or in the shared namespace, changes.
+type definitionCache struct {
+	ctx     context.Context
+	dynamic dynamic.Interface
+	log     logging.Logger
+	shared  string
+
+	mu      sync.Mutex
+	entries map[definitionKey]*definitionEntry
+	// generation: the number of invalidations, not to cache the lookups raced by a change
+	generation uint64
+	watched    map[string]struct{}
+}
+
+func newDefinitionCache(ctx context.Context, dyn dynamic.Interface, log logging.Logger, shared string) *definitionCache {
+	if log == nil {
+		log = logging.NewNopLogger()
+	}
+	return &definitionCache{
+		ctx:     ctx,
+		dynamic: dyn,
+		log:     log,
+		shared:  shared,
+		entries: map[definitionKey]*definitionEntry{},
+		watched: map[string]struct{}{},
+	}
+}
+
+// get returns the cached definition, looking it up with fetch if not cached.
+// The errors and the missing definitions are not cached.
+func (c *definitionCache) get(key definitionKey, fetch func() (*definitionEntry, error)) (*definitionEntry, error) {
+	if c == nil {
+		return fetch()
+	}
+
+	c.mu.Lock()
+	if def, ok := c.entries[key]; ok {
+		c.mu.Unlock()
+		return def, nil
+	}
+	// Watching before the lookup, so that the changes made meanwhile invalidate it
+	c.watch(key.namespace)
+	if c.shared != "" {
+		c.watch(c.shared)
+	}
+	generation := c.generation
+	c.mu.Unlock()
+
+	def, err := fetch()
+	if err != nil || def == nil {
+		return def, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation == generation {
+		c.entries[key] = def
+	}
+	return def, nil
+}
+
+// invalidate drops the definitions looked up in the namespace and in all the namespaces,
+// or every definition if the namespace is the shared one.
+func (c *definitionCache) invalidate(namespace string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if key.namespace == namespace || key.namespace == "" || namespace == c.shared {
+			delete(c.entries, key)
+		}
+	}
+	c.generation++
+}
+
+// watch starts the informer of the RestDefinitions in the namespace (all if empty), if not already started.
+func (c *definitionCache) watch(namespace string) {
+	if _, ok := c.watched[namespace]; ok || c.dynamic == nil {
+		return
+	}
+	c.watched[namespace] = struct{}{}
+
+	changed := func(obj interface{}) {
+		def, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+			if !ok {
+				return
+			}
+			if def, ok = tombstone.Obj.(*unstructured.Unstructured); !ok {
+				return
+			}
+		}
+		c.invalidate(def.GetNamespace())
+	}
+
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(c.dynamic, 0, namespace, nil)
+	informer := factory.ForResource(definitionsGVR).Informer()
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: changed,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldDef, ok := oldObj.(*unstructured.Unstructured)
+			if !ok {
+				return
+			}
+			newDef, ok := newObj.(*unstructured.Unstructured)
+			if !ok {
+				return
+			}
+			if reflect.DeepEqual(oldDef.Object["spec"], newDef.Object["spec"]) {
+				return
+			}
+			changed(newDef)
+		},
+		DeleteFunc: changed,
+	})
+	if err != nil {
+		c.log.Debug("Watching RestDefinitions", "namespace", namespace, "error", err)
+		return
+	}
+	factory.Start(c.ctx.Done())
+	c.log.Debug("Watching RestDefinitions for changes", "namespace", namespace)
+}
@@ -0,0 +1,217 @@
+package getter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestDefinitionCache(t *testing.T) {
+	c := newDefinitionCache(context.Background(), nil, logging.NewNopLogger(), "")
+	repos := definitionKey{namespace: "default", gk: schema.GroupKind{Group: "gen.github.com", Kind: "Repo"}}
+	teams := definitionKey{namespace: "", gk: schema.GroupKind{Group: "gen.github.com", Kind: "Team"}}
+
+	fetches := 0
+	fetch := func() (*definitionEntry, error) {
+		fetches++
+		return &definitionEntry{url: "https://example.com/openapi.yaml"}, nil
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := c.get(repos, fetch); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := c.get(teams, fetch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fetches != 2 {
+		t.Errorf("expected a lookup by key, got %d", fetches)
+	}
+
+	c.invalidate("other")
+	c.get(repos, fetch)
+	if fetches != 2 {
+		t.Errorf("expected the definitions of other namespaces to be kept, got %d lookups", fetches)
+	}
+	c.get(teams, fetch)
+	if fetches != 3 {
+		t.Errorf("expected the definitions looked up in all the namespaces to be invalidated, got %d lookups", fetches)
+	}
+
+	c.invalidate("default")
+	c.get(repos, fetch)
+	if fetches != 4 {
+		t.Errorf("expected the invalidated definition to be looked up again, got %d lookups", fetches)
+	}
+
+	failing := func() (*definitionEntry, error) {
+		fetches++
+		return nil, errors.New("unavailable")
+	}
+	missing := definitionKey{namespace: "default", gk: schema.GroupKind{Group: "gen.github.com", Kind: "Hook"}}
+	for i := 0; i < 2; i++ {
+		c.get(missing, failing)
+	}
+	if fetches != 6 {
+		t.Errorf("expected the failed lookups not to be cached, got %d lookups", fetches)
+	}
+}
+
+func restDefinition(oasPath string) *unstructured.Unstructured {
+	def := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"oasPath":       oasPath,
+			"resourceGroup": "gen.github.com",
+			"resource": map[string]interface{}{
+				"kind":        "Repo",
+				"identifiers": []interface{}{"id"},
+			},
+		},
+	}}
+	def.SetAPIVersion("swaggergen.krateo.io/v1alpha1")
+	def.SetKind("RestDefinition")
+	def.SetNamespace("default")
+	def.SetName("repos")
+	return def
+}
+
+func TestCachedDynamicGetter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dyn := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		definitionsGVR: "RestDefinitionList",
+	}, restDefinition("https://example.com/v1.yaml"))
+	g := &dynamicGetter{dynamicClient: dyn, definitions: newDefinitionCache(ctx, dyn, logging.NewNopLogger(), "")}
+
+	mg := &unstructured.Unstructured{}
+	mg.SetAPIVersion("gen.github.com/v1alpha1")
+	mg.SetKind("Repo")
+	mg.SetNamespace("default")
+	mg.SetName("repo1")
+
+	info, err := g.Get(mg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.URL != "https://example.com/v1.yaml" || len(info.Resource.Identifiers) != 1 {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+
+	_, err = dyn.Resource(definitionsGVR).Namespace("default").Update(ctx, restDefinition("https://example.com/v2.yaml"), metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		info, err = g.Get(mg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if info.URL == "https://example.com/v2.yaml" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the changed definition to be looked up again, got %s", info.URL)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSelectDefinition(t *testing.T) {
+	older := restDefinition("https://example.com/v1.yaml")
+	older.SetCreationTimestamp(metav1.NewTime(time.Now().Add(-time.Hour)))
+	newer := restDefinition("https://example.com/v2.yaml")
+	newer.SetName("repos-v2")
+	newer.SetCreationTimestamp(metav1.NewTime(time.Now()))
+
+	mg := &unstructured.Unstructured{}
+	mg.SetAPIVersion("gen.github.com/v1alpha1")
+	mg.SetKind("Repo")
+	mg.SetNamespace("default")
+
+	def, ambiguity, err := selectDefinition(mg, "default", []unstructured.Unstructured{*older, *newer})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if def.GetName() != "repos-v2" {
+		t.Errorf("expected the newest definition, got %s", def.GetName())
+	}
+	if ambiguity == "" {
+		t.Errorf("expected the ambiguity to be reported")
+	}
+
+	def, ambiguity, err = selectDefinition(mg, "default", []unstructured.Unstructured{*older})
+	if err != nil || def.GetName() != "repos" || ambiguity != "" {
+		t.Errorf("expected the single definition without ambiguity, got %s (%q, %v)", def.GetName(), ambiguity, err)
+	}
+
+	mg.SetAnnotations(map[string]string{AnnotationKeyRestDefinition: "repos"})
+	def, ambiguity, err = selectDefinition(mg, "default", []unstructured.Unstructured{*older, *newer})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if def.GetName() != "repos" || ambiguity != "" {
+		t.Errorf("expected the selected definition without ambiguity, got %s (%q)", def.GetName(), ambiguity)
+	}
+
+	mg.SetAnnotations(map[string]string{AnnotationKeyRestDefinition: "repos-v3"})
+	if _, _, err := selectDefinition(mg, "default", []unstructured.Unstructured{*older, *newer}); err == nil {
+		t.Errorf("expected an error for a definition not managing the resource")
+	}
+}
+
+func TestSharedNamespace(t *testing.T) {
+	shared := restDefinition("https://example.com/shared.yaml")
+	shared.SetNamespace("krateo-system")
+	team := restDefinition("https://example.com/team-a.yaml")
+	team.SetNamespace("team-a")
+	dyn := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		definitionsGVR: "RestDefinitionList",
+	}, shared, team)
+
+	for _, cached := range []bool{false, true} {
+		g := &dynamicGetter{dynamicClient: dyn, sharedNamespace: "krateo-system"}
+		if cached {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			g.definitions = newDefinitionCache(ctx, dyn, logging.NewNopLogger(), "krateo-system")
+		}
+
+		for namespace, expected := range map[string]string{
+			"team-a":        "https://example.com/team-a.yaml",
+			"team-b":        "https://example.com/shared.yaml",
+			"krateo-system": "https://example.com/shared.yaml",
+		} {
+			mg := &unstructured.Unstructured{}
+			mg.SetAPIVersion("gen.github.com/v1alpha1")
+			mg.SetKind("Repo")
+			mg.SetNamespace(namespace)
+			mg.SetName("repo1")
+
+			info, err := g.Get(mg)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", namespace, err)
+			}
+			if info == nil || info.URL != expected {
+				t.Errorf("%s: expected %s, got %+v", namespace, expected, info)
+			}
+		}
+	}
+
+	g := &dynamicGetter{dynamicClient: dyn}
+	mg := &unstructured.Unstructured{}
+	mg.SetAPIVersion("gen.github.com/v1alpha1")
+	mg.SetKind("Repo")
+	mg.SetNamespace("team-b")
+	if _, err := g.Get(mg); err == nil {
+		t.Errorf("expected no definition without a shared namespace")
+	}
+}
@@ -4,12 +4,17 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/gobuffalo/flect"
 	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
 	"github.com/krateoplatformops/rest-dynamic-controller/internal/text"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/vault"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/workloadidentity"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
 	unstructuredtools "github.com/krateoplatformops/unstructured-runtime/pkg/tools/unstructured"
 	"github.com/lucasepe/httplib"
 
@@ -20,6 +25,60 @@ import (
 	"k8s.io/client-go/rest"
 )
 
+type RequestFieldMapping struct {
+	// InCustomResource: the dot separated path of the CR field providing the value (e.g. spec.projectId)
+	// +optional
+	InCustomResource string `json:"inCustomResource,omitempty"`
+	// FromStatus: the value is read from the status, InCustomResource being the dot separated path of the status field
+	// (e.g. id), defaulting to the target of the mapping; the identifiers of the existing resource override the spec
+	// values and the other mappings of the same target, and the mapping is skipped until the status field is set
+	// +optional
+	FromStatus bool `json:"fromStatus,omitempty"`
+	// ConfigMapKeyRef: the ConfigMap key providing the value instead of a CR field, for the non-sensitive
+	// configuration shared by the CRs (e.g. environment-specific base IDs, org names); the namespace defaults to the CR one
+	// +optional
+	ConfigMapKeyRef *ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+	// Value: the value resolved from the ConfigMap key
+	Value string `json:"-"`
+	// Condition: the CEL expression over spec and status that must be true for the mapping to apply (e.g. spec.enabled == true)
+	// +optional
+	Condition string `json:"condition,omitempty"`
+	// InPath: the name of the path parameter to populate
+	// +optional
+	InPath string `json:"inPath,omitempty"`
+	// InQuery: the name of the query parameter to populate
+	// +optional
+	InQuery string `json:"inQuery,omitempty"`
+	// InBody: the dot separated path of the body field to populate
+	// +optional
+	InBody string `json:"inBody,omitempty"`
+	// InHeader: the name of the request header to populate (e.g. X-Project-Id)
+	// +optional
+	InHeader string `json:"inHeader,omitempty"`
+	// InCookie: the name of the request cookie to populate
+	// +optional
+	InCookie string `json:"inCookie,omitempty"`
+}
+
+type ConfigMapKeySelector struct {
+	// Name: the name of the ConfigMap
+	Name string `json:"name"`
+	// Namespace: the namespace of the ConfigMap
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Key: the key of the value in the ConfigMap data
+	Key string `json:"key"`
+}
+
+type BodyTemplate struct {
+	// Type: the template language [gotemplate, cel], defaults to gotemplate
+	// +optional
+	Type string `json:"type,omitempty"`
+	// Template: the Go template producing the JSON body or the CEL expression producing the body,
+	// evaluated over the spec and status of the CR
+	Template string `json:"template"`
+}
+
 type VerbsDescription struct {
 	// Name of the action to perform when this api is called
 	Action string `json:"action"`
@@ -27,10 +86,184 @@ type VerbsDescription struct {
 	Method string `json:"method"`
 	// Path: the path to the api
 	Path string `json:"path"`
+	// Condition: the CEL expression over spec and status selecting this description among the ones of the same action,
+	// the first one whose condition is true (or empty) is used (e.g. spec.type == "org")
+	Condition string `json:"condition,omitempty"`
+	// RawMethod: if true, the method is sent as is without looking up the operation in the OAS,
+	// allowing non-standard methods (e.g. PROPFIND, REPORT)
+	RawMethod bool `json:"rawMethod,omitempty"`
+	// MethodOverrideHeader: if set, the request is sent as POST with the method in this header (e.g. X-HTTP-Method-Override)
+	MethodOverrideHeader string `json:"methodOverrideHeader,omitempty"`
+	// RequestFieldMapping: the explicit mapping of the CR fields to the request parameters, body fields, headers and cookies
+	RequestFieldMapping []RequestFieldMapping `json:"requestFieldMapping,omitempty"`
+	// BodyTemplate: the template of the request body, replacing the body built from the CR fields
+	BodyTemplate *BodyTemplate `json:"bodyTemplate,omitempty"`
+	// BodyRootPath: the dot separated path under which the request body is nested (e.g. repository or data.attributes)
+	BodyRootPath string `json:"bodyRootPath,omitempty"`
+	// ResponseRootPath: the dot separated path of the object wrapped in the response (e.g. data or result), unwrapped
+	// before populating the status and comparing it with the CR; the item found for the findby action
+	ResponseRootPath string `json:"responseRootPath,omitempty"`
+	// ItemsPath: the dot separated path of the array holding the items of the collection searched by the findby action
+	// (e.g. data.repos); defaults to the items, data, results or value array, or else the first array of the response
+	ItemsPath string `json:"itemsPath,omitempty"`
+	// NotFoundAsEmpty: if true, a 404 answer listing the collection searched by the findby action is taken as an empty
+	// collection (as answered by some APIs when nothing matches the search), so that the resource is created;
+	// otherwise the observation fails with the ExternalError condition
+	NotFoundAsEmpty bool `json:"notFoundAsEmpty,omitempty"`
+	// ForbiddenAsNotFound: if true, a 403 answer to the get, exists and delete actions is taken as a 404, for the APIs
+	// answering 403 when the resource does not exist or is no longer accessible once deleted; the resource is then
+	// searched or created again by the observation, and taken as already deleted by the deletion (a 404 answer to the
+	// deletion still fails it)
+	ForbiddenAsNotFound bool `json:"forbiddenAsNotFound,omitempty"`
+	// StatusCodes: the outcome of the response status codes, for the OAS documents omitting some of the codes the API
+	// answers with (e.g. 201 or 204) or describing as successes the codes telling the resource is still pending (e.g. 202)
+	StatusCodes []restclient.StatusCode `json:"statusCodes,omitempty"`
+	// VerifyAfterCreate: for the create action, if true the resource is got by the identifiers of the create response
+	// right after its creation, confirming it materialized and populating the status from its authoritative
+	// representation, for the APIs answering the creation with a partial one
+	VerifyAfterCreate bool `json:"verifyAfterCreate,omitempty"`
+	// CheckBeforeCreate: for the create action, if true the resource is searched with the findby action right before
+	// its creation, even if the observation did not find it, closing the window in which another actor may have
+	// created it meanwhile: the resource found is adopted instead of created again
+	CheckBeforeCreate bool `json:"checkBeforeCreate,omitempty"`
+	// IncludeFields: if set, only these fields are sent in the request body
+	IncludeFields []string `json:"includeFields,omitempty"`
+	// ExcludeFields: the fields never sent in the request body (e.g. server managed fields like etag or timestamps)
+	ExcludeFields []string `json:"excludeFields,omitempty"`
+	// UseResponseLinks: if true, the request is sent to the HAL link (_links) of the observed resource
+	// instead of the URL built from the path (the delete link for the delete action, the self link otherwise)
+	UseResponseLinks bool `json:"useResponseLinks,omitempty"`
+	// SparseFields: for APIs supporting sparse fieldsets (e.g. ?fields=, ?$select=), the fields requested when observing
+	// the resource; the identifiers and the compared spec fields are always included
+	SparseFields *SparseFields `json:"sparseFields,omitempty"`
+	// Trigger: for auxiliary actions (e.g. enable, rotate, restart), the dot separated path of the CR field
+	// whose changes trigger the action (e.g. spec.restartedAt); defaults to the krateo.io/trigger-<action> annotation
+	Trigger string `json:"trigger,omitempty"`
+	// IdempotencyKeyHeader: if set, the requests carry in this header (e.g. Idempotency-Key) an idempotency key derived
+	// from the UID and the generation of the CR, so that the APIs mutating with POST (e.g. the updates sent to an
+	// /update or /set endpoint) do not apply twice the retries of the same change
+	IdempotencyKeyHeader string `json:"idempotencyKeyHeader,omitempty"`
 	// // AltFieldMapping: the alternative mapping of the fields to use in the request
 	// AltFieldMapping map[string]string `json:"altFieldMapping,omitempty"`
 }
 
+type Normalizer string
+
+const (
+	// NormalizerTimestamp: RFC3339 (and similar) timestamps and epochs in seconds or milliseconds
+	NormalizerTimestamp Normalizer = "timestamp"
+	// NormalizerDuration: durations (e.g. 1h) and numbers of seconds (e.g. 3600)
+	NormalizerDuration Normalizer = "duration"
+	// NormalizerURL: URLs, ignoring the case of scheme and host, the default ports and the trailing slash
+	NormalizerURL Normalizer = "url"
+	// NormalizerLowercase: strings, ignoring their case
+	NormalizerLowercase Normalizer = "lowercase"
+)
+
+type Coercion string
+
+const (
+	// CoercionString: the values are sent and compared as strings (e.g. false as "false")
+	CoercionString Coercion = "string"
+	// CoercionBoolean: the values are sent and compared as booleans (e.g. "true" as true)
+	CoercionBoolean Coercion = "boolean"
+	// CoercionNumber: the values are sent and compared as numbers (e.g. "42" as 42)
+	CoercionNumber Coercion = "number"
+)
+
+type ValueSource string
+
+const (
+	// ValueSourceMappings: the CR fields of the field mappings of the verb
+	ValueSourceMappings ValueSource = "mappings"
+	// ValueSourceConfig: the ConfigMap values of the field mappings of the verb
+	ValueSourceConfig ValueSource = "config"
+	// ValueSourceStatus: the status fields named as the parameters and body fields
+	ValueSourceStatus ValueSource = "status"
+	// ValueSourceSpec: the spec fields named as the parameters and body fields
+	ValueSourceSpec ValueSource = "spec"
+)
+
+type Precedence struct {
+	// Parameters: the sources [mappings, config, status, spec] of the path and query parameters, headers and cookies,
+	// from the highest precedence, defaults to mappings, config, status, spec
+	Parameters []ValueSource `json:"parameters,omitempty"`
+	// Body: the sources [mappings, config, status, spec] of the body fields, from the highest precedence,
+	// defaults to mappings, config, spec, status
+	Body []ValueSource `json:"body,omitempty"`
+}
+
+type Comparison struct {
+	// Tolerant: if true, the fields that cannot be compared with the remote ones (e.g. having different types)
+	// are reported in the conditions instead of failing the observation
+	Tolerant bool `json:"tolerant,omitempty"`
+	// ArrayKeys: the key field of the arrays of objects compared by key instead of by index, by the dot separated
+	// path of the array in the spec (e.g. pipelines: id); the elements are matched regardless of their position
+	ArrayKeys map[string]string `json:"arrayKeys,omitempty"`
+	// Normalizers: the normalizer [timestamp, duration, url, lowercase] applied to the spec and remote values
+	// before comparing them, by the dot separated path of the field in the spec (e.g. authorizedOn: timestamp)
+	Normalizers map[string]Normalizer `json:"normalizers,omitempty"`
+}
+
+type NullPolicy string
+
+const (
+	// NullPolicyUnset: null means the field is not set, it is not sent in the request bodies and ignored in the comparison
+	NullPolicyUnset NullPolicy = "unset"
+	// NullPolicyValue: null is a meaningful value, it is sent in the request bodies and compared with the remote one,
+	// a missing remote field differs from a null one
+	NullPolicyValue NullPolicy = "value"
+)
+
+type SparseFields struct {
+	// Param: the query parameter holding the requested fields (e.g. fields, $select, fields[articles])
+	Param string `json:"param"`
+	// Fields: the additional fields to request (e.g. the ones read into the status)
+	Fields []string `json:"fields,omitempty"`
+	// Separator: the separator of the requested fields, defaults to a comma
+	Separator string `json:"separator,omitempty"`
+}
+
+type HeaderIdentifier struct {
+	// Identifier: the identifier to populate in the status
+	Identifier string `json:"identifier"`
+	// Header: the response header holding the identifier (e.g. X-Resource-Id, Location)
+	Header string `json:"header"`
+	// Segment: for headers holding a URL or a path (e.g. Location), the index of the path segment holding the identifier,
+	// negative values count from the end (e.g. -1 for the last segment)
+	// +optional
+	Segment *int `json:"segment,omitempty"`
+}
+
+type OptimisticLocking struct {
+	// ETag: if true, the ETag of the observed resource is sent in the If-Match header of the updates
+	ETag bool `json:"etag,omitempty"`
+	// VersionField: the response field holding the version of the resource (e.g. revision), stored in the status and sent with the updates
+	VersionField string `json:"versionField,omitempty"`
+	// VersionParam: the path or query parameter, or body field, the version is sent as; defaults to VersionField
+	VersionParam string `json:"versionParam,omitempty"`
+	// MaxRetries: the number of times an update rejected with 412 or 409 is retried after getting the resource again, defaults to 3
+	MaxRetries int `json:"maxRetries,omitempty"`
+}
+
+type DriftPolicy string
+
+const (
+	// DriftPolicyRemediate: the external resource is updated whenever it differs from the CR
+	DriftPolicyRemediate DriftPolicy = "Remediate"
+	// DriftPolicyObserveOnly: the external resource is updated only when the spec of the CR changes (a new generation),
+	// the drift of the external resource is otherwise only reported in the Drifted condition
+	DriftPolicyObserveOnly DriftPolicy = "ObserveOnly"
+)
+
+type Pending struct {
+	// Condition: the CEL expression on the response of the observed resource telling it is still being provisioned
+	// (e.g. response.state == "provisioning"); spec and status are available too
+	Condition string `json:"condition"`
+	// RequeueAfter: the delay after which the pending resource is observed again (e.g. 10s), defaults to 10s
+	RequeueAfter string `json:"requeueAfter,omitempty"`
+}
+
 type Resource struct {
 	// Name: the name of the resource to manage
 	Kind string `json:"kind"`
@@ -38,6 +271,60 @@ type Resource struct {
 	Identifiers []string `json:"identifiers"`
 	// VerbsDescription: the list of verbs to use on this resource
 	VerbsDescription []VerbsDescription `json:"verbsDescription"`
+	// IdentifiersFromHeaders: the identifiers to extract from the response headers,
+	// for APIs returning them only in a header (e.g. X-Resource-Id) or in the Location path
+	IdentifiersFromHeaders []HeaderIdentifier `json:"identifiersFromHeaders,omitempty"`
+	// AnnotationsFromResponse: the annotations to set on the CR from the response fields,
+	// mapping the annotation key to the dot separated path of the field in the response (e.g. krateo.io/web-url: html_url)
+	AnnotationsFromResponse map[string]string `json:"annotationsFromResponse,omitempty"`
+	// OwnershipField: the dot separated path of the object field of the external resource holding its tags or labels
+	// (e.g. tags or metadata.labels), where the ownership markers of the CR (the cluster name, the CR namespace and name)
+	// are written on create and update, and read by the findby action to tell apart the resources with the same identifiers
+	OwnershipField string `json:"ownershipField,omitempty"`
+	// LateInitialize: if true, the remote values of the spec fields left empty by the user, nested fields included,
+	// are written back into the CR spec
+	LateInitialize bool `json:"lateInitialize,omitempty"`
+	// Pagination: how the API paginates the collection searched by the findby action
+	Pagination *restclient.Pagination `json:"pagination,omitempty"`
+	// JSONAPI: if set, the API is spoken in the JSON:API protocol, wrapping the request bodies in the
+	// resource object envelope and flattening the attributes and relationships of the responses
+	JSONAPI *restclient.JSONAPI `json:"jsonapi,omitempty"`
+	// NullFields: the null policy [unset, value] of the fields, by their dot separated path in the spec (e.g. settings.homepage);
+	// the null fields without a policy are sent in the request bodies and ignored in the comparison
+	NullFields map[string]NullPolicy `json:"nullFields,omitempty"`
+	// Coercions: the type [string, boolean, number] the API expects for the fields, by their dot separated path in the spec;
+	// the spec values are converted to it when building the requests and comparing the responses
+	Coercions map[string]Coercion `json:"coercions,omitempty"`
+	// Comparison: how the CR fields are compared with the remote ones to detect drift
+	Comparison *Comparison `json:"comparison,omitempty"`
+	// Precedence: which source wins when several ones provide the value of the same parameter or body field;
+	// the sources omitted have the lowest precedence, in the default order
+	Precedence *Precedence `json:"precedence,omitempty"`
+	// OptimisticLocking: how the updates are made conditional on the observed version of the resource, preventing lost updates
+	OptimisticLocking *OptimisticLocking `json:"optimisticLocking,omitempty"`
+	// Pending: when the observed resource is still being provisioned, to be observed again sooner than the resync interval
+	Pending *Pending `json:"pending,omitempty"`
+	// CreateOnlyFields: the dot separated paths of the spec fields accepted at creation but rejected on update (e.g. name, region),
+	// never sent in the update requests nor compared to detect drift; the request body properties marked with the
+	// x-create-only (or x-immutable) extension in the OAS are create-only as well
+	CreateOnlyFields []string `json:"createOnlyFields,omitempty"`
+	// DriftPolicy: how the drift of the external resource is handled [Remediate, ObserveOnly], defaults to Remediate
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+	// DataSource: if true, the kind is a read-only data source: the external resource is only observed with the get or findby
+	// verbs and projected into the status, never created, updated nor deleted (e.g. to reference external data in compositions)
+	DataSource bool `json:"dataSource,omitempty"`
+	// ServerURL: the base URL of the API, taking precedence over the servers of the OAS document (e.g. a staging host)
+	ServerURL string `json:"serverURL,omitempty"`
+	// ServerURLRef: the ConfigMap key providing the base URL of the API, taking precedence over ServerURL, so that the same
+	// RestDefinition targets a different host per namespace (e.g. staging and production tenants); the namespace defaults to the CR one
+	ServerURLRef *ConfigMapKeySelector `json:"serverURLRef,omitempty"`
+	// HealthPath: the path, relative to the server URL, of the endpoint the server health is probed with (e.g. /healthz);
+	// if empty the server URL itself is probed with a HEAD request
+	HealthPath string `json:"healthPath,omitempty"`
+	// ActionAuthenticationRefs: the authentication objects overriding the authenticationRefs of the CR for some actions
+	// (e.g. a token with write permissions for create, update and delete), by action; the refs are keyed like the
+	// authenticationRefs of the CR (e.g. bearerAuthRef: my-token) and looked up in the namespace of the CR
+	ActionAuthenticationRefs map[string]map[string]string `json:"actionAuthenticationRefs,omitempty"`
 }
 
 type GVK struct {
@@ -74,6 +361,53 @@ type Info struct {
 
 	// Verbose: if true, the client will dump verbose output
 	Verbose bool `json:"verbose,omitempty"`
+
+	// AuthRef: the authentication object the credentials are resolved from, nil if none
+	AuthRef *AuthRef `json:"-"`
+
+	// AuthSecrets: the secrets the credentials are read from, including the ones of the action overrides
+	AuthSecrets []SecretKeySelector `json:"-"`
+
+	// ActionAuth: the authentication methods overriding Auth, by lowercase action
+	ActionAuth map[string]httplib.AuthMethod `json:"-"`
+
+	// Ambiguity: the RestDefinitions managing the resource besides the one selected, empty if none
+	Ambiguity string `json:"-"`
+}
+
+// AuthFor returns the authentication method of the given action: its override, if any, or else Auth.
+func (i *Info) AuthFor(action string) httplib.AuthMethod {
+	if auth, ok := i.ActionAuth[strings.ToLower(action)]; ok {
+		return auth
+	}
+	return i.Auth
+}
+
+// AuthRef identifies the authentication object (e.g. a BearerAuth) referenced by a resource.
+type AuthRef struct {
+	GVR       schema.GroupVersionResource
+	Namespace string
+	Name      string
+}
+
+func (r AuthRef) String() string {
+	return fmt.Sprintf("%s/%s/%s", r.GVR.Resource, r.Namespace, r.Name)
+}
+
+// AuthError is returned when the credentials cannot be resolved from the authentication object.
+type AuthError struct {
+	Ref AuthRef
+	// Secrets the credentials are read from, if known
+	Secrets []SecretKeySelector
+	Err     error
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("resolving credentials from %s: %v", e.Ref, e.Err)
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
 }
 
 type Getter interface {
@@ -95,6 +429,111 @@ func Dynamic(cfg *rest.Config) (Getter, error) {
 	}, nil
 }
 
+// CachedDynamic returns the getter caching the RestDefinitions by namespace and kind of the resources, until they
+// change: the RestDefinitions are watched, in the namespaces they are looked up in, until the context is done.
+// The credentials and the values of the ConfigMaps are read on every call.
+func CachedDynamic(ctx context.Context, cfg *rest.Config, log logging.Logger) (Getter, error) {
+	return NewDynamic(cfg, DynamicOptions{Context: ctx, Logger: log, Cache: true})
+}
+
+// DynamicOptions configures the getter reading the RestDefinitions from the cluster.
+type DynamicOptions struct {
+	// Context bounds the watches of the cached RestDefinitions
+	Context context.Context
+	// Logger logs the watches of the cached RestDefinitions
+	Logger logging.Logger
+	// Cache caches the RestDefinitions until they change (see CachedDynamic)
+	Cache bool
+	// SharedNamespace is the namespace of the RestDefinitions shared by the other namespaces: a RestDefinition in
+	// the namespace of the resource is preferred, the shared ones are used otherwise. Empty if none
+	SharedNamespace string
+	// ServiceAccount is the ServiceAccount of the controller, whose tokens are exchanged for the API tokens of the
+	// WorkloadIdentityAuth objects; the workload identities are not supported if empty
+	ServiceAccount workloadidentity.ServiceAccount
+	// WorkloadIdentityPolicy restricts the token URLs and the audiences of the WorkloadIdentityAuth objects
+	WorkloadIdentityPolicy workloadidentity.Policy
+	// Vault reads the credentials of the authentication objects referencing a Vault secret with their vaultRef;
+	// they are not supported if nil
+	Vault *vault.Client
+}
+
+// NewDynamic returns the getter reading the RestDefinitions from the cluster with the given options.
+func NewDynamic(cfg *rest.Config, opts DynamicOptions) (Getter, error) {
+	dyn, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &dynamicGetter{
+		dynamicClient:   dyn,
+		sharedNamespace: opts.SharedNamespace,
+	}
+	if opts.ServiceAccount.Name != "" {
+		g.tokens = workloadidentity.New(dyn, opts.ServiceAccount)
+		g.tokens.Policy = opts.WorkloadIdentityPolicy
+	}
+	g.vault = opts.Vault
+	if opts.Cache {
+		ctx := opts.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		g.definitions = newDefinitionCache(ctx, dyn, opts.Logger, opts.SharedNamespace)
+	}
+	return g, nil
+}
+
+// Definition is a RestDefinition, describing how a kind of resources is managed.
+type Definition struct {
+	Name      string
+	Namespace string
+	// URL of the OAS document
+	URL      string
+	Resource Resource
+}
+
+// ListDefinitions returns the RestDefinitions managing the resources of the given group and kind,
+// in the namespace or in all the namespaces if empty.
+func ListDefinitions(ctx context.Context, dyn dynamic.Interface, namespace, group, kind string) ([]Definition, error) {
+	all, err := dyn.Resource(definitionsGVR).
+		Namespace(namespace).
+		List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	definitions := []Definition{}
+	for _, item := range all.Items {
+		resourceGroup, _, _ := unstructured.NestedString(item.Object, "spec", "resourceGroup")
+		resourceKind, _, _ := unstructured.NestedString(item.Object, "spec", "resource", "kind")
+		if resourceGroup != group || resourceKind != kind {
+			continue
+		}
+
+		oasPath, ok, err := unstructured.NestedString(item.Object, "spec", "oasPath")
+		if err != nil || !ok {
+			return nil, fmt.Errorf("missing spec.oasPath in definition %s/%s", item.GetNamespace(), item.GetName())
+		}
+		res, _, _ := unstructured.NestedFieldNoCopy(item.Object, "spec", "resource")
+		jsonData, err := json.Marshal(res)
+		if err != nil {
+			return nil, err
+		}
+		var resource Resource
+		if err := json.Unmarshal(jsonData, &resource); err != nil {
+			return nil, fmt.Errorf("decoding spec.resource in definition %s/%s: %w", item.GetNamespace(), item.GetName(), err)
+		}
+
+		definitions = append(definitions, Definition{
+			Name:      item.GetName(),
+			Namespace: item.GetNamespace(),
+			URL:       oasPath,
+			Resource:  resource,
+		})
+	}
+	return definitions, nil
+}
+
 var _ Getter = (*staticGetter)(nil)
 
 type staticGetter struct {
@@ -111,6 +550,14 @@ var _ Getter = (*dynamicGetter)(nil)
 
 type dynamicGetter struct {
 	dynamicClient dynamic.Interface
+	// definitions: the cache of the RestDefinitions, nil if disabled
+	definitions *definitionCache
+	// sharedNamespace: the namespace of the RestDefinitions used when none in the namespace of the resource matches
+	sharedNamespace string
+	// tokens: the exchanger of the ServiceAccount tokens of the workload identities, nil if not configured
+	tokens *workloadidentity.Exchanger
+	// vault: the client reading the credentials from Vault, nil if not configured
+	vault *vault.Client
 }
 
 func (g *dynamicGetter) Get(un *unstructured.Unstructured) (*Info, error) {
@@ -119,31 +566,94 @@ func (g *dynamicGetter) Get(un *unstructured.Unstructured) (*Info, error) {
 		return nil, err
 	}
 
+	key := definitionKey{
+		namespace: un.GetNamespace(),
+		gk:        un.GroupVersionKind().GroupKind(),
+		name:      un.GetAnnotations()[AnnotationKeyRestDefinition],
+	}
+	def, err := g.definitions.get(key, func() (*definitionEntry, error) {
+		return g.lookup(un, gvr)
+	})
+	if err != nil || def == nil {
+		return nil, err
+	}
+
+	// Decoding the resource on every call, since it is then modified (e.g. with the values of the ConfigMaps)
+	var resource Resource
+	err = json.Unmarshal(def.resource, &resource)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, authRef, authSecrets, err := g.getAuth(un)
+	if err != nil {
+		return nil, err
+	}
+	actionAuth, actionSecrets, err := g.getActionAuth(un, &resource)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resolveConfigMapValues(context.Background(), g.dynamicClient, &resource, un.GetNamespace()); err != nil {
+		return nil, err
+	}
+	return &Info{
+		URL:         def.url,
+		Resource:    resource,
+		Auth:        auth,
+		AuthRef:     authRef,
+		AuthSecrets: append(authSecrets, actionSecrets...),
+		ActionAuth:  actionAuth,
+		Ambiguity:   def.ambiguity,
+	}, nil
+}
+
+// lookup returns the OAS path and the spec.resource of the RestDefinition managing the resource, nil if none.
+// The RestDefinitions in the namespace of the resource are preferred to the ones in the shared namespace.
+func (g *dynamicGetter) lookup(un *unstructured.Unstructured, gvr schema.GroupVersionResource) (*definitionEntry, error) {
+	def, err := g.lookupIn(un.GetNamespace(), un, gvr)
+	if def != nil || g.sharedNamespace == "" || g.sharedNamespace == un.GetNamespace() {
+		return def, err
+	}
+	var noDefs *noDefinitionsError
+	if err != nil && !errors.As(err, &noDefs) && !errors.As(err, new(*unselectedDefinitionError)) {
+		return nil, err
+	}
+	shared, sharedErr := g.lookupIn(g.sharedNamespace, un, gvr)
+	if shared != nil {
+		return shared, nil
+	}
+	if sharedErr != nil && !errors.As(sharedErr, &noDefs) && !errors.As(sharedErr, new(*unselectedDefinitionError)) {
+		return nil, sharedErr
+	}
+	// Reporting the outcome in the namespace of the resource
+	return nil, err
+}
+
+// lookupIn returns the RestDefinition managing the resource in the namespace, nil if none.
+// Among the RestDefinitions declaring the same kind and group (e.g. during a migration), the one named by
+// the krateo.io/rest-definition annotation of the resource is selected, otherwise the newest one.
+func (g *dynamicGetter) lookupIn(namespace string, un *unstructured.Unstructured, gvr schema.GroupVersionResource) (*definitionEntry, error) {
 	// sel, err := selectorForGroup(gvr)
 	// if err != nil {
 	// 	return nil, err
 	// }
 
-	gvrForDefinitions := schema.GroupVersionResource{
-		Group:    "swaggergen.krateo.io",
-		Version:  "v1alpha1",
-		Resource: "restdefinitions",
-	}
-
-	all, err := g.dynamicClient.Resource(gvrForDefinitions).
-		Namespace(un.GetNamespace()).
+	all, err := g.dynamicClient.Resource(definitionsGVR).
+		Namespace(namespace).
 		List(context.Background(), metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
 	if len(all.Items) == 0 {
-		return nil, fmt.Errorf("no definitions found for '%v' in namespace: %s", gvr, un.GetNamespace())
+		return nil, &noDefinitionsError{gvr: gvr, namespace: namespace}
 	}
 
+	candidates := []unstructured.Unstructured{}
 	for _, item := range all.Items {
-		res, ok, err := unstructured.NestedFieldNoCopy(item.Object, "spec", "resource")
+		_, ok, err := unstructured.NestedFieldNoCopy(item.Object, "spec", "resource")
 		if !ok {
-			return nil, fmt.Errorf("missing spec.resources in definition for '%v' in namespace: %s", gvr, un.GetNamespace())
+			return nil, fmt.Errorf("missing spec.resources in definition for '%v' in namespace: %s", gvr, namespace)
 		}
 		if err != nil {
 			return nil, err
@@ -151,7 +661,7 @@ func (g *dynamicGetter) Get(un *unstructured.Unstructured) (*Info, error) {
 
 		group, ok, err := unstructured.NestedString(item.Object, "spec", "resourceGroup")
 		if !ok {
-			return nil, fmt.Errorf("missing spec.resourceGroup in definition for '%v' in namespace: %s", gvr, un.GetNamespace())
+			return nil, fmt.Errorf("missing spec.resourceGroup in definition for '%v' in namespace: %s", gvr, namespace)
 		}
 		if err != nil {
 			return nil, err
@@ -159,7 +669,7 @@ func (g *dynamicGetter) Get(un *unstructured.Unstructured) (*Info, error) {
 
 		kind, ok, err := unstructured.NestedString(item.Object, "spec", "resource", "kind")
 		if !ok {
-			return nil, fmt.Errorf("missing kind in definition for '%v' in namespace: %s", gvr, un.GetNamespace())
+			return nil, fmt.Errorf("missing kind in definition for '%v' in namespace: %s", gvr, namespace)
 		}
 		if err != nil {
 			return nil, err
@@ -168,97 +678,149 @@ func (g *dynamicGetter) Get(un *unstructured.Unstructured) (*Info, error) {
 			continue
 		}
 
-		oasPath, ok, err := unstructured.NestedString(item.Object, "spec", "oasPath")
+		_, ok, err = unstructured.NestedString(item.Object, "spec", "oasPath")
 		if !ok {
-			return nil, fmt.Errorf("missing spec.oasPath in definition for '%v' in namespace: %s", gvr, un.GetNamespace())
+			return nil, fmt.Errorf("missing spec.oasPath in definition for '%v' in namespace: %s", gvr, namespace)
 		}
 		if err != nil {
 			return nil, err
 		}
 
 		if group == gvr.Group {
-			gvk := un.GroupVersionKind()
-			// Convert the map to JSON
-			jsonData, err := json.Marshal(res)
-			if err != nil {
-				return nil, err
-			}
-			// Convert the JSON to a struct
-			var resource Resource
-			err = json.Unmarshal(jsonData, &resource)
-			if err != nil {
-				return nil, err
-			}
-
-			auth, err := g.getAuth(un)
-			if err != nil {
-				return nil, err
-			}
-
-			if resource.Kind == gvk.Kind {
-				return &Info{
-					URL:      oasPath,
-					Resource: resource,
-					Auth:     auth,
-				}, nil
-			}
+			candidates = append(candidates, item)
 		}
 	}
-	return nil, nil
-}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
 
-// getAuth returns the authentication method for the given resource.
-// It returns an error if the authentication object is not valid.
-func (g *dynamicGetter) getAuth(un *unstructured.Unstructured) (httplib.AuthMethod, error) {
-	gvr, err := unstructuredtools.GVR(un)
+	item, ambiguity, err := selectDefinition(un, namespace, candidates)
 	if err != nil {
 		return nil, err
 	}
+	oasPath, _, _ := unstructured.NestedString(item.Object, "spec", "oasPath")
+	res, _, _ := unstructured.NestedFieldNoCopy(item.Object, "spec", "resource")
+	// Convert the map to JSON
+	jsonData, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	// Checking the JSON converts to the struct
+	var resource Resource
+	err = json.Unmarshal(jsonData, &resource)
+	if err != nil {
+		return nil, err
+	}
+	return &definitionEntry{url: oasPath, resource: jsonData, ambiguity: ambiguity}, nil
+}
 
-	var authRef string
-	var authType restclient.AuthType = restclient.AuthTypeBasic
+// getAuth returns the authentication method for the given resource, with the object and the secrets it is resolved from.
+// It returns an error if the authentication object is not valid, an AuthError once the object is known.
+func (g *dynamicGetter) getAuth(un *unstructured.Unstructured) (httplib.AuthMethod, *AuthRef, []SecretKeySelector, error) {
+	gvr, err := unstructuredtools.GVR(un)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
 	authenticationRefsMap, ok, err := unstructured.NestedStringMap(un.Object, "spec", "authenticationRefs")
 	if err != nil {
-		return nil, fmt.Errorf("error getting spec.authenticationRefs for '%v' in namespace: %s", gvr, un.GetNamespace())
+		return nil, nil, nil, fmt.Errorf("error getting spec.authenticationRefs for '%v' in namespace: %s", gvr, un.GetNamespace())
 	}
 	if !ok {
-		return nil, nil
+		return nil, nil, nil, nil
+	}
+	return g.resolveAuth(gvr.Group, un.GetNamespace(), authenticationRefsMap)
+}
+
+// getActionAuth returns the authentication methods overriding the one of the given resource, by lowercase action,
+// with the secrets they are read from. It returns an AuthError if any of the authentication objects is not valid.
+func (g *dynamicGetter) getActionAuth(un *unstructured.Unstructured, resource *Resource) (map[string]httplib.AuthMethod, []SecretKeySelector, error) {
+	if len(resource.ActionAuthenticationRefs) == 0 {
+		return nil, nil, nil
+	}
+	gvr, err := unstructuredtools.GVR(un)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	for key := range authenticationRefsMap {
-		authRef, ok, err = unstructured.NestedString(un.Object, "spec", "authenticationRefs", key)
+	methods := make(map[string]httplib.AuthMethod, len(resource.ActionAuthenticationRefs))
+	var secrets []SecretKeySelector
+	for action, refs := range resource.ActionAuthenticationRefs {
+		if len(refs) == 0 {
+			continue
+		}
+		method, _, actionSecrets, err := g.resolveAuth(gvr.Group, un.GetNamespace(), refs)
 		if err != nil {
-			return nil, fmt.Errorf("error getting spec.authenticationRefs.%s for '%v' in namespace: %s", key, gvr, un.GetNamespace())
+			return nil, nil, fmt.Errorf("error resolving the authentication of action %s: %w", action, err)
 		}
-		if ok {
-			authType, err = restclient.ToType(strings.Split(key, "AuthRef")[0])
-			if err != nil {
-				return nil, err
-			}
-			break
+		methods[strings.ToLower(action)] = method
+		secrets = append(secrets, actionSecrets...)
+	}
+	return methods, secrets, nil
+}
+
+// resolveAuth returns the authentication method resolved from the authentication object referenced by the given
+// refs (e.g. bearerAuthRef: my-token), looked up in the given group and namespace, with the secrets it is read from.
+func (g *dynamicGetter) resolveAuth(group, namespace string, refs map[string]string) (httplib.AuthMethod, *AuthRef, []SecretKeySelector, error) {
+	var authRef string
+	var authType restclient.AuthType = restclient.AuthTypeBasic
+	var err error
+
+	for key, name := range refs {
+		authRef = name
+		authType, err = restclient.ToType(strings.Split(key, "AuthRef")[0])
+		if err != nil {
+			return nil, nil, nil, err
 		}
+		break
 	}
 
 	gvrForAuthentication := schema.GroupVersionResource{
-		Group:    gvr.Group,
+		Group:    group,
 		Version:  "v1alpha1",
 		Resource: strings.ToLower(flect.Pluralize(fmt.Sprintf("%sAuth", text.ToGolangName(authType.String())))),
 	}
 
+	ref := &AuthRef{GVR: gvrForAuthentication, Namespace: namespace, Name: authRef}
 	auth, err := g.dynamicClient.Resource(gvrForAuthentication).
-		Namespace(un.GetNamespace()).
+		Namespace(namespace).
 		Get(context.Background(), authRef, metav1.GetOptions{})
 	if err != nil {
-		return nil, err
+		return nil, ref, nil, &AuthError{Ref: *ref, Err: err}
 	}
 
-	return parseAuthentication(auth, authType, g.dynamicClient)
+	secrets := authSecrets(auth, authType)
+	method, err := g.parseAuthentication(auth, authType)
+	if err != nil {
+		return nil, ref, secrets, &AuthError{Ref: *ref, Secrets: secrets, Err: err}
+	}
+	return method, ref, secrets, nil
+}
+
+// authSecrets returns the secrets referenced by the authentication object, defaulting their namespace to the one of the object.
+func authSecrets(un *unstructured.Unstructured, authType restclient.AuthType) []SecretKeySelector {
+	field := "tokenRef"
+	if authType == restclient.AuthTypeBasic {
+		field = "passwordRef"
+	}
+	secretRef, ok, err := unstructured.NestedStringMap(un.Object, "spec", field)
+	if err != nil || !ok || secretRef["name"] == "" {
+		return nil
+	}
+	namespace := secretRef["namespace"]
+	if namespace == "" {
+		namespace = un.GetNamespace()
+	}
+	return []SecretKeySelector{{
+		Name:      secretRef["name"],
+		Namespace: namespace,
+		Key:       secretRef["key"],
+	}}
 }
 
 // parseAuthentication parses the authentication object and returns the appropriate AuthMethod for the given AuthType.
 // It returns an error if the authentication object is not valid.
-func parseAuthentication(un *unstructured.Unstructured, authType restclient.AuthType, dyn dynamic.Interface) (httplib.AuthMethod, error) {
+func (g *dynamicGetter) parseAuthentication(un *unstructured.Unstructured, authType restclient.AuthType) (httplib.AuthMethod, error) {
 	gvr, err := unstructuredtools.GVR(un)
 	if err != nil {
 		return nil, err
@@ -271,19 +833,7 @@ func parseAuthentication(un *unstructured.Unstructured, authType restclient.Auth
 		if !ok {
 			return nil, fmt.Errorf("missing spec.username in definition for '%v' in namespace: %s", gvr, un.GetNamespace())
 		}
-		passwordRef, ok, err := unstructured.NestedStringMap(un.Object, "spec", "passwordRef")
-		if err != nil {
-			return nil, err
-		}
-		if !ok {
-			return nil, fmt.Errorf("missing spec.passwordRef in definition for '%v' in namespace: %s", gvr, un.GetNamespace())
-		}
-
-		password, err := GetSecret(context.Background(), dyn, SecretKeySelector{
-			Name:      passwordRef["name"],
-			Namespace: passwordRef["namespace"],
-			Key:       passwordRef["key"],
-		})
+		password, err := g.credential(un, gvr, "passwordRef")
 		if err != nil {
 			return nil, fmt.Errorf("error getting password for '%v' in namespace: %s - %w", gvr, un.GetNamespace(), err)
 		}
@@ -293,22 +843,34 @@ func parseAuthentication(un *unstructured.Unstructured, authType restclient.Auth
 			Password: password,
 		}, nil
 	} else if authType == restclient.AuthTypeBearer {
-		tokenRef, ok, err := unstructured.NestedStringMap(un.Object, "spec", "tokenRef")
+		token, err := g.credential(un, gvr, "tokenRef")
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("error getting token for '%v' in namespace: %s - %w", gvr, un.GetNamespace(), err)
 		}
-		if !ok {
-			return nil, fmt.Errorf("missing spec.tokenRef in definition for '%v' in namespace: %s", gvr, un.GetNamespace())
+
+		return &httplib.TokenAuth{
+			Token: token,
+		}, nil
+	} else if authType == restclient.AuthTypeWorkloadIdentity {
+		if g.tokens == nil {
+			return nil, fmt.Errorf("workload identity is not configured for '%v' in namespace: %s", gvr, un.GetNamespace())
 		}
-		token, err := GetSecret(context.Background(), dyn, SecretKeySelector{
-			Name:      tokenRef["name"],
-			Namespace: tokenRef["namespace"],
-			Key:       tokenRef["key"],
-		})
+		spec, _, err := unstructured.NestedMap(un.Object, "spec")
+		if err != nil {
+			return nil, err
+		}
+		cfg := workloadidentity.Config{}
+		cfg.Audience, _, _ = unstructured.NestedString(spec, "audience")
+		cfg.TokenURL, _, _ = unstructured.NestedString(spec, "tokenURL")
+		cfg.GrantType, _, _ = unstructured.NestedString(spec, "grantType")
+		cfg.ClientID, _, _ = unstructured.NestedString(spec, "clientID")
+		cfg.Scope, _, _ = unstructured.NestedString(spec, "scope")
+		cfg.ExpirationSeconds, _, _ = unstructured.NestedInt64(spec, "expirationSeconds")
+
+		token, err := g.tokens.Token(context.Background(), cfg)
 		if err != nil {
 			return nil, fmt.Errorf("error getting token for '%v' in namespace: %s - %w", gvr, un.GetNamespace(), err)
 		}
-
 		return &httplib.TokenAuth{
 			Token: token,
 		}, nil
@@ -316,12 +878,108 @@ func parseAuthentication(un *unstructured.Unstructured, authType restclient.Auth
 	return nil, fmt.Errorf("unknown auth type: %s", authType)
 }
 
+// credential returns the secret value of the authentication object, read from the Kubernetes secret referenced by
+// the given field (e.g. tokenRef) or else from the Vault secret referenced by its vaultRef.
+func (g *dynamicGetter) credential(un *unstructured.Unstructured, gvr schema.GroupVersionResource, field string) (string, error) {
+	secretRef, ok, err := unstructured.NestedStringMap(un.Object, "spec", field)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return GetSecret(context.Background(), g.dynamicClient, SecretKeySelector{
+			Name:      secretRef["name"],
+			Namespace: secretRef["namespace"],
+			Key:       secretRef["key"],
+		})
+	}
+
+	vaultRef, ok, err := unstructured.NestedStringMap(un.Object, "spec", "vaultRef")
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("missing spec.%s in definition for '%v' in namespace: %s", field, gvr, un.GetNamespace())
+	}
+	if g.vault == nil {
+		return "", fmt.Errorf("vault is not configured")
+	}
+	return g.vault.Read(context.Background(), vault.Ref{
+		Address:   vaultRef["address"],
+		Role:      vaultRef["role"],
+		AuthMount: vaultRef["authMount"],
+		Path:      vaultRef["path"],
+		Key:       vaultRef["key"],
+	})
+}
+
 type SecretKeySelector struct {
 	Name      string
 	Namespace string
 	Key       string
 }
 
+// resolveConfigMapValues sets the values of the request field mappings taken from the ConfigMap keys.
+func resolveConfigMapValues(ctx context.Context, dyn dynamic.Interface, resource *Resource, namespace string) error {
+	for i := range resource.VerbsDescription {
+		mappings := resource.VerbsDescription[i].RequestFieldMapping
+		for j := range mappings {
+			ref := mappings[j].ConfigMapKeyRef
+			if ref == nil {
+				continue
+			}
+			sel := *ref
+			if sel.Namespace == "" {
+				sel.Namespace = namespace
+			}
+			value, err := GetConfigMapValue(ctx, dyn, sel)
+			if err != nil {
+				return fmt.Errorf("error resolving request field mapping of action '%s': %w", resource.VerbsDescription[i].Action, err)
+			}
+			mappings[j].Value = value
+		}
+	}
+	if ref := resource.ServerURLRef; ref != nil {
+		sel := *ref
+		if sel.Namespace == "" {
+			sel.Namespace = namespace
+		}
+		value, err := GetConfigMapValue(ctx, dyn, sel)
+		if err != nil {
+			return fmt.Errorf("error resolving server URL: %w", err)
+		}
+		resource.ServerURL = strings.TrimSpace(value)
+	}
+	if resource.ServerURL != "" {
+		u, err := url.Parse(resource.ServerURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid server URL %q: must be an absolute URL", resource.ServerURL)
+		}
+	}
+	return nil
+}
+
+// GetConfigMapValue returns the value of the key in the ConfigMap.
+func GetConfigMapValue(ctx context.Context, client dynamic.Interface, selector ConfigMapKeySelector) (string, error) {
+	gvr := schema.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "configmaps",
+	}
+
+	cm, err := client.Resource(gvr).Namespace(selector.Namespace).Get(ctx, selector.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	value, ok, err := unstructured.NestedString(cm.Object, "data", selector.Key)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("key '%s' not found in configmap %s/%s", selector.Key, selector.Namespace, selector.Name)
+	}
+	return value, nil
+}
+
 func GetSecret(ctx context.Context, client dynamic.Interface, secretKeySelector SecretKeySelector) (string, error) {
 	gvr := schema.GroupVersionResource{
 		Group:    "",
@@ -0,0 +1,188 @@
+package getter
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/vault"
+	"github.com/lucasepe/httplib"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func configMap(namespace, serverURL string) *unstructured.Unstructured {
+	cm := &unstructured.Unstructured{Object: map[string]interface{}{
+		"data": map[string]interface{}{"serverURL": serverURL},
+	}}
+	cm.SetAPIVersion("v1")
+	cm.SetKind("ConfigMap")
+	cm.SetNamespace(namespace)
+	cm.SetName("api")
+	return cm
+}
+
+func TestResolveServerURL(t *testing.T) {
+	dyn := fake.NewSimpleDynamicClient(runtime.NewScheme(),
+		configMap("staging", "https://staging.example.com/v1\n"),
+		configMap("production", "https://api.example.com/v1"),
+		configMap("broken", "api.example.com"),
+	)
+
+	for namespace, expected := range map[string]string{
+		"staging":    "https://staging.example.com/v1",
+		"production": "https://api.example.com/v1",
+	} {
+		resource := Resource{
+			ServerURL:    "https://default.example.com",
+			ServerURLRef: &ConfigMapKeySelector{Name: "api", Key: "serverURL"},
+		}
+		if err := resolveConfigMapValues(context.Background(), dyn, &resource, namespace); err != nil {
+			t.Fatalf("%s: unexpected error: %v", namespace, err)
+		}
+		if resource.ServerURL != expected {
+			t.Errorf("%s: expected %s, got %s", namespace, expected, resource.ServerURL)
+		}
+	}
+
+	resource := Resource{ServerURLRef: &ConfigMapKeySelector{Name: "api", Key: "serverURL"}}
+	if err := resolveConfigMapValues(context.Background(), dyn, &resource, "broken"); err == nil {
+		t.Errorf("expected an error for a relative server URL")
+	}
+	resource = Resource{ServerURLRef: &ConfigMapKeySelector{Name: "api", Key: "serverURL"}}
+	if err := resolveConfigMapValues(context.Background(), dyn, &resource, "missing"); err == nil {
+		t.Errorf("expected an error for a missing ConfigMap")
+	}
+}
+
+func bearerAuth(name, token string) (*unstructured.Unstructured, *unstructured.Unstructured) {
+	auth := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"tokenRef": map[string]interface{}{"name": name, "namespace": "default", "key": "token"},
+		},
+	}}
+	auth.SetAPIVersion("gen.github.com/v1alpha1")
+	auth.SetKind("BearerAuth")
+	auth.SetNamespace("default")
+	auth.SetName(name)
+
+	sec := &unstructured.Unstructured{Object: map[string]interface{}{
+		"data": map[string]interface{}{"token": base64.StdEncoding.EncodeToString([]byte(token))},
+	}}
+	sec.SetAPIVersion("v1")
+	sec.SetKind("Secret")
+	sec.SetNamespace("default")
+	sec.SetName(name)
+	return auth, sec
+}
+
+func TestActionAuth(t *testing.T) {
+	readAuth, readSecret := bearerAuth("read-only", "read-token")
+	writeAuth, writeSecret := bearerAuth("write", "write-token")
+	def := restDefinition("https://example.com/v1.yaml")
+	err := unstructured.SetNestedField(def.Object, map[string]interface{}{
+		"Create": map[string]interface{}{"bearerAuthRef": "write"},
+		"delete": map[string]interface{}{"bearerAuthRef": "write"},
+	}, "spec", "resource", "actionAuthenticationRefs")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dyn := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		definitionsGVR: "RestDefinitionList",
+		{Group: "gen.github.com", Version: "v1alpha1", Resource: "bearerauths"}: "BearerAuthList",
+	}, def, readAuth, readSecret, writeAuth, writeSecret)
+	g := &dynamicGetter{dynamicClient: dyn}
+
+	mg := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"authenticationRefs": map[string]interface{}{"bearerAuthRef": "read-only"},
+		},
+	}}
+	mg.SetAPIVersion("gen.github.com/v1alpha1")
+	mg.SetKind("Repo")
+	mg.SetNamespace("default")
+	mg.SetName("repo1")
+
+	info, err := g.Get(mg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for action, expected := range map[string]string{
+		"get":    "read-token",
+		"findby": "read-token",
+		"create": "write-token",
+		"delete": "write-token",
+	} {
+		auth, ok := info.AuthFor(action).(*httplib.TokenAuth)
+		if !ok || auth.Token != expected {
+			t.Errorf("%s: expected %s, got %+v", action, expected, info.AuthFor(action))
+		}
+	}
+	if len(info.AuthSecrets) != 3 {
+		t.Errorf("expected the secrets of the overrides to be tracked, got %+v", info.AuthSecrets)
+	}
+
+	_ = unstructured.SetNestedField(def.Object, map[string]interface{}{
+		"update": map[string]interface{}{"bearerAuthRef": "missing"},
+	}, "spec", "resource", "actionAuthenticationRefs")
+	if _, err := dyn.Resource(definitionsGVR).Namespace("default").Update(context.Background(), def, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = g.Get(mg)
+	var authErr *AuthError
+	if !errors.As(err, &authErr) || authErr.Ref.Name != "missing" {
+		t.Errorf("expected an AuthError for the missing override, got %v", err)
+	}
+}
+
+func TestVaultCredential(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/kubernetes/login":
+			fmt.Fprint(w, `{"auth": {"client_token": "vault-token", "lease_duration": 3600}}`)
+		case "/v1/secret/data/github":
+			fmt.Fprint(w, `{"data": {"data": {"token": "gh-token"}, "metadata": {}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	auth := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"vaultRef": map[string]interface{}{"role": "controller", "path": "secret/data/github", "key": "token"},
+		},
+	}}
+	auth.SetAPIVersion("gen.github.com/v1alpha1")
+	auth.SetKind("BearerAuth")
+	auth.SetNamespace("default")
+	auth.SetName("vault")
+
+	g := &dynamicGetter{dynamicClient: fake.NewSimpleDynamicClient(runtime.NewScheme())}
+	if _, err := g.parseAuthentication(auth, restclient.AuthTypeBearer); err == nil {
+		t.Errorf("expected an error without Vault client")
+	}
+
+	g.vault = vault.New(srv.URL)
+	g.vault.ServiceAccountToken = func() (string, error) { return "sa-jwt", nil }
+	method, err := g.parseAuthentication(auth, restclient.AuthTypeBearer)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token, ok := method.(*httplib.TokenAuth); !ok || token.Token != "gh-token" {
+		t.Errorf("expected the token read from Vault, got %+v", method)
+	}
+
+	_ = unstructured.SetNestedField(auth.Object, "https://attacker.example.com", "spec", "vaultRef", "address")
+	if _, err := g.parseAuthentication(auth, restclient.AuthTypeBearer); err == nil {
+		t.Errorf("expected an error for a Vault address not allowed")
+	}
+}
@@ -0,0 +1,190 @@
+// Package rotation detects the rotation of the secrets the credentials of the resources are read from,
+// so that the resources are reconciled with the new credentials as soon as the secrets change, instead of
+// after the calls made with the old ones fail or at the next resync.
+// The secrets are watched only in the namespaces where a tracked resource references one.
+package rotation
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+var secretsGVR = schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+
+// Func is called for each resource whose credentials are read from a rotated secret.
+type Func func(mg *unstructured.Unstructured, ref getter.AuthRef)
+
+type secretKey struct {
+	namespace string
+	name      string
+}
+
+type resourceKey struct {
+	gvk       schema.GroupVersionKind
+	namespace string
+	name      string
+}
+
+type tracked struct {
+	ref     getter.AuthRef
+	secrets []secretKey
+}
+
+// Watcher watches the secrets referenced by the tracked resources, calling the registered funcs when they change.
+type Watcher struct {
+	ctx     context.Context
+	dynamic dynamic.Interface
+	log     logging.Logger
+
+	mu        sync.Mutex
+	funcs     []Func
+	resources map[resourceKey]tracked
+	secrets   map[secretKey]map[resourceKey]struct{}
+	watched   map[string]struct{}
+}
+
+// New returns the watcher of the secrets, whose informers run until the context is done.
+func New(ctx context.Context, dyn dynamic.Interface, log logging.Logger) *Watcher {
+	return &Watcher{
+		ctx:       ctx,
+		dynamic:   dyn,
+		log:       log,
+		resources: map[resourceKey]tracked{},
+		secrets:   map[secretKey]map[resourceKey]struct{}{},
+		watched:   map[string]struct{}{},
+	}
+}
+
+// OnRotation registers the func called for each resource whose credentials are read from a rotated secret.
+func (w *Watcher) OnRotation(fn Func) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.funcs = append(w.funcs, fn)
+}
+
+// Track records that the credentials of the resource are resolved from the authentication object and read
+// from the secrets, replacing the secrets previously tracked for the resource.
+func (w *Watcher) Track(mg *unstructured.Unstructured, ref *getter.AuthRef, secrets []getter.SecretKeySelector) {
+	if w == nil || mg == nil || ref == nil || len(secrets) == 0 {
+		return
+	}
+	key := resourceKey{gvk: mg.GroupVersionKind(), namespace: mg.GetNamespace(), name: mg.GetName()}
+	keys := make([]secretKey, 0, len(secrets))
+	for _, sel := range secrets {
+		keys = append(keys, secretKey{namespace: sel.Namespace, name: sel.Name})
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if prev, ok := w.resources[key]; ok && prev.ref == *ref && reflect.DeepEqual(prev.secrets, keys) {
+		return
+	}
+	w.untrack(key)
+	w.resources[key] = tracked{ref: *ref, secrets: keys}
+	for _, sk := range keys {
+		if w.secrets[sk] == nil {
+			w.secrets[sk] = map[resourceKey]struct{}{}
+		}
+		w.secrets[sk][key] = struct{}{}
+		w.watch(sk.namespace)
+	}
+}
+
+// Untrack stops tracking the secrets of the resource (e.g. once deleted).
+func (w *Watcher) Untrack(mg *unstructured.Unstructured) {
+	if w == nil || mg == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.untrack(resourceKey{gvk: mg.GroupVersionKind(), namespace: mg.GetNamespace(), name: mg.GetName()})
+}
+
+func (w *Watcher) untrack(key resourceKey) {
+	prev, ok := w.resources[key]
+	if !ok {
+		return
+	}
+	for _, sk := range prev.secrets {
+		delete(w.secrets[sk], key)
+		if len(w.secrets[sk]) == 0 {
+			delete(w.secrets, sk)
+		}
+	}
+	delete(w.resources, key)
+}
+
+// watch starts the informer of the secrets in the namespace, if not already started.
+func (w *Watcher) watch(namespace string) {
+	if _, ok := w.watched[namespace]; ok || w.dynamic == nil {
+		return
+	}
+	w.watched[namespace] = struct{}{}
+
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(w.dynamic, 0, namespace, nil)
+	informer := factory.ForResource(secretsGVR).Informer()
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldSec, ok := oldObj.(*unstructured.Unstructured)
+			if !ok {
+				return
+			}
+			newSec, ok := newObj.(*unstructured.Unstructured)
+			if !ok {
+				return
+			}
+			if reflect.DeepEqual(oldSec.Object["data"], newSec.Object["data"]) {
+				return
+			}
+			w.rotated(secretKey{namespace: newSec.GetNamespace(), name: newSec.GetName()})
+		},
+	})
+	if err != nil {
+		w.log.Debug("Watching secrets", "namespace", namespace, "error", err)
+		return
+	}
+	factory.Start(w.ctx.Done())
+	w.log.Debug("Watching secrets for rotations", "namespace", namespace)
+}
+
+// rotated calls the registered funcs for each resource reading its credentials from the secret.
+func (w *Watcher) rotated(sk secretKey) {
+	type notification struct {
+		mg  *unstructured.Unstructured
+		ref getter.AuthRef
+	}
+
+	w.mu.Lock()
+	funcs := append([]Func(nil), w.funcs...)
+	var notifications []notification
+	for key := range w.secrets[sk] {
+		mg := &unstructured.Unstructured{}
+		mg.SetGroupVersionKind(key.gvk)
+		mg.SetNamespace(key.namespace)
+		mg.SetName(key.name)
+		notifications = append(notifications, notification{mg: mg, ref: w.resources[key].ref})
+	}
+	w.mu.Unlock()
+
+	if len(notifications) == 0 {
+		return
+	}
+	w.log.Info("Secret rotated, reconciling the resources using it", "namespace", sk.namespace, "name", sk.name, "resources", len(notifications))
+	for _, n := range notifications {
+		for _, fn := range funcs {
+			fn(n.mg, n.ref)
+		}
+	}
+}
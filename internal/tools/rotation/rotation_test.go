@@ -0,0 +1,129 @@
+package rotation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+var authRef = getter.AuthRef{
+	GVR:       schema.GroupVersionResource{Group: "gen.github.com", Version: "v1alpha1", Resource: "bearerauths"},
+	Namespace: "default",
+	Name:      "bearer-gh-ref",
+}
+
+func newSecret(token string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("Secret")
+	obj.SetNamespace("default")
+	obj.SetName("gh-token")
+	obj.Object["data"] = map[string]interface{}{"token": token}
+	return obj
+}
+
+func newRepo(name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("gen.github.com/v1alpha1")
+	obj.SetKind("Repo")
+	obj.SetNamespace("default")
+	obj.SetName(name)
+	return obj
+}
+
+func newWatcher(t *testing.T) (*Watcher, *fake.FakeDynamicClient, chan string) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	dyn := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		secretsGVR: "SecretList",
+	}, newSecret("dG9rZW4x"))
+	w := New(ctx, dyn, logging.NewNopLogger())
+
+	notified := make(chan string, 10)
+	w.OnRotation(func(mg *unstructured.Unstructured, ref getter.AuthRef) {
+		if ref != authRef {
+			t.Errorf("unexpected auth ref: %v", ref)
+		}
+		notified <- mg.GetName()
+	})
+	return w, dyn, notified
+}
+
+var tokenSecret = []getter.SecretKeySelector{{Name: "gh-token", Namespace: "default", Key: "token"}}
+
+// rotate updates the secret until the watcher notifies a rotation, since the informer starts asynchronously.
+func rotate(t *testing.T, dyn *fake.FakeDynamicClient, notified chan string) string {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for i := 0; ; i++ {
+		sec := newSecret("dG9rZW4y" + string(rune('a'+i%26)))
+		if _, err := dyn.Resource(secretsGVR).Namespace("default").Update(context.Background(), sec, metav1.UpdateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		select {
+		case name := <-notified:
+			return name
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatalf("expected the rotation to be notified")
+		}
+	}
+}
+
+func TestRotation(t *testing.T) {
+	w, dyn, notified := newWatcher(t)
+	w.Track(newRepo("repo1"), &authRef, tokenSecret)
+
+	if name := rotate(t, dyn, notified); name != "repo1" {
+		t.Errorf("expected repo1 to be notified, got %s", name)
+	}
+}
+
+func TestUntrack(t *testing.T) {
+	w, dyn, notified := newWatcher(t)
+	w.Track(newRepo("repo1"), &authRef, tokenSecret)
+	w.Track(newRepo("repo2"), &authRef, tokenSecret)
+	w.Untrack(newRepo("repo1"))
+
+	if name := rotate(t, dyn, notified); name != "repo2" {
+		t.Errorf("expected repo2 to be notified, got %s", name)
+	}
+	select {
+	case name := <-notified:
+		t.Errorf("expected no other resource to be notified, got %s", name)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestTrackReplaces(t *testing.T) {
+	w, _, _ := newWatcher(t)
+	mg := newRepo("repo1")
+	w.Track(mg, &authRef, tokenSecret)
+	w.Track(mg, &authRef, []getter.SecretKeySelector{{Name: "other", Namespace: "default", Key: "token"}})
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.secrets[secretKey{namespace: "default", name: "gh-token"}]; ok {
+		t.Errorf("expected the previous secret to be untracked")
+	}
+	if len(w.secrets[secretKey{namespace: "default", name: "other"}]) != 1 {
+		t.Errorf("expected the new secret to be tracked")
+	}
+}
+
+func TestNilWatcher(t *testing.T) {
+	var w *Watcher
+	w.OnRotation(func(*unstructured.Unstructured, getter.AuthRef) {})
+	w.Track(newRepo("repo1"), &authRef, tokenSecret)
+	w.Untrack(newRepo("repo1"))
+}
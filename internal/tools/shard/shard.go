@@ -0,0 +1,99 @@
+// Package shard allows to split the resources of a GVR across multiple controller replicas,
+// each one reconciling only the resources whose name hash falls in its shard.
+package shard
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/controller"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Shard identifies the portion of the resources reconciled by a replica.
+type Shard struct {
+	// Index of the shard owned by the replica, in [0, Count)
+	Index int
+	// Count is the total number of shards; values lower than 2 disable sharding
+	Count int
+}
+
+// New returns the shard with the given index and count.
+func New(index, count int) (Shard, error) {
+	if count > 1 && (index < 0 || index >= count) {
+		return Shard{}, fmt.Errorf("shard index %d out of range [0, %d)", index, count)
+	}
+	return Shard{Index: index, Count: count}, nil
+}
+
+// Enabled returns true if the resources are split across more than one shard.
+func (s Shard) Enabled() bool {
+	return s.Count > 1
+}
+
+// Owns returns true if the resource, identified by its namespace and name, belongs to the shard.
+func (s Shard) Owns(namespace, name string) bool {
+	if !s.Enabled() {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(namespace + "/" + name))
+	return int(h.Sum32()%uint32(s.Count)) == s.Index
+}
+
+func (s Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
+
+var _ controller.ExternalClient = (*filter)(nil)
+
+type filter struct {
+	shard  Shard
+	client controller.ExternalClient
+}
+
+// Filter wraps the external client so that only the resources owned by the shard are reconciled.
+// Resources owned by other shards are reported as existing and up-to-date, and their creation,
+// update and deletion are skipped, the owning replica deleting their external resources.
+func Filter(client controller.ExternalClient, s Shard) controller.ExternalClient {
+	if !s.Enabled() {
+		return client
+	}
+	return &filter{shard: s, client: client}
+}
+
+func (f *filter) owns(mg *unstructured.Unstructured) bool {
+	return f.shard.Owns(mg.GetNamespace(), mg.GetName())
+}
+
+func (f *filter) Observe(ctx context.Context, mg *unstructured.Unstructured) (controller.ExternalObservation, error) {
+	if !f.owns(mg) {
+		return controller.ExternalObservation{
+			ResourceExists:   true,
+			ResourceUpToDate: true,
+		}, nil
+	}
+	return f.client.Observe(ctx, mg)
+}
+
+func (f *filter) Create(ctx context.Context, mg *unstructured.Unstructured) error {
+	if !f.owns(mg) {
+		return nil
+	}
+	return f.client.Create(ctx, mg)
+}
+
+func (f *filter) Update(ctx context.Context, mg *unstructured.Unstructured) error {
+	if !f.owns(mg) {
+		return nil
+	}
+	return f.client.Update(ctx, mg)
+}
+
+func (f *filter) Delete(ctx context.Context, mg *unstructured.Unstructured) error {
+	if !f.owns(mg) {
+		return nil
+	}
+	return f.client.Delete(ctx, mg)
+}
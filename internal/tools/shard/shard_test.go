@@ -0,0 +1,124 @@
+package shard
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/controller"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestOwns(t *testing.T) {
+	const count = 3
+
+	shards := make([]Shard, count)
+	for i := range shards {
+		s, err := New(i, count)
+		if err != nil {
+			t.Fatalf("creating shard: %v", err)
+		}
+		shards[i] = s
+	}
+
+	owned := make([]int, count)
+	for i := 0; i < 300; i++ {
+		name := fmt.Sprintf("repo-%d", i)
+		owners := 0
+		for j, s := range shards {
+			if s.Owns("default", name) {
+				owners++
+				owned[j]++
+			}
+		}
+		if owners != 1 {
+			t.Fatalf("expected %s to be owned by exactly one shard, got %d", name, owners)
+		}
+	}
+
+	for i, n := range owned {
+		if n == 0 {
+			t.Errorf("expected shard %d to own some resources", i)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(3, 3); err == nil {
+		t.Errorf("expected error for out of range shard index")
+	}
+
+	s, err := New(0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Enabled() || !s.Owns("default", "repo") {
+		t.Errorf("expected disabled shard to own every resource")
+	}
+}
+
+type recorder struct {
+	calls []string
+}
+
+func (r *recorder) Observe(_ context.Context, mg *unstructured.Unstructured) (controller.ExternalObservation, error) {
+	r.calls = append(r.calls, "observe "+mg.GetName())
+	return controller.ExternalObservation{}, nil
+}
+
+func (r *recorder) Create(_ context.Context, mg *unstructured.Unstructured) error {
+	r.calls = append(r.calls, "create "+mg.GetName())
+	return nil
+}
+
+func (r *recorder) Update(_ context.Context, mg *unstructured.Unstructured) error {
+	r.calls = append(r.calls, "update "+mg.GetName())
+	return nil
+}
+
+func (r *recorder) Delete(_ context.Context, mg *unstructured.Unstructured) error {
+	r.calls = append(r.calls, "delete "+mg.GetName())
+	return nil
+}
+
+func TestFilter(t *testing.T) {
+	s, err := New(0, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var owned, other *unstructured.Unstructured
+	for i := 0; owned == nil || other == nil; i++ {
+		mg := &unstructured.Unstructured{}
+		mg.SetNamespace("default")
+		mg.SetName(fmt.Sprintf("repo-%d", i))
+		if s.Owns(mg.GetNamespace(), mg.GetName()) {
+			owned = mg
+		} else {
+			other = mg
+		}
+	}
+
+	rec := &recorder{}
+	client := Filter(rec, s)
+	ctx := context.Background()
+
+	obs, err := client.Observe(ctx, other)
+	if err != nil || !obs.ResourceExists || !obs.ResourceUpToDate {
+		t.Errorf("expected the resource of another shard to be up-to-date, got %+v (%v)", obs, err)
+	}
+	for _, call := range []func(context.Context, *unstructured.Unstructured) error{client.Create, client.Update, client.Delete} {
+		if err := call(ctx, other); err != nil {
+			t.Errorf("expected the resource of another shard to be skipped, got %v", err)
+		}
+	}
+	if len(rec.calls) != 0 {
+		t.Fatalf("expected no call for the resource of another shard, got %v", rec.calls)
+	}
+
+	if err := client.Delete(ctx, owned); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rec.calls) != 1 || rec.calls[0] != "delete "+owned.GetName() {
+		t.Errorf("expected the owned resource to be deleted, got %v", rec.calls)
+	}
+}
@@ -0,0 +1,125 @@
+// Package shutdown drains the reconciles in progress when the controller is stopped, so that the
+// external calls and the status updates they perform are not interrupted halfway.
+package shutdown
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/controller"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// cancelTimeout is the time the reconciles are given to return once their context is cancelled.
+const cancelTimeout = 5 * time.Second
+
+var _ controller.ExternalClient = (*Drainer)(nil)
+
+// Drainer wraps the external client tracking the reconciles in progress. The reconciles are run with
+// their own context, which is not cancelled when the controller is stopped but only when the drain times out.
+type Drainer struct {
+	client controller.ExternalClient
+
+	mu       sync.RWMutex
+	draining bool
+	inflight sync.WaitGroup
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// New returns the drainer wrapping the external client.
+func New(client controller.ExternalClient) *Drainer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Drainer{client: client, ctx: ctx, cancel: cancel}
+}
+
+// Drain stops accepting new reconciles and waits for the ones in progress to complete, up to the timeout;
+// then it cancels their context, cancelling the external calls still in flight.
+// It returns true if all the reconciles completed before the timeout.
+func (d *Drainer) Drain(timeout time.Duration) bool {
+	d.mu.Lock()
+	d.draining = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return true
+	case <-time.After(timeout):
+	}
+
+	d.cancel()
+	select {
+	case <-done:
+	case <-time.After(cancelTimeout):
+	}
+	return false
+}
+
+// begin tracks a new reconcile, returning its context and the function to call once it completes;
+// false if the drainer is draining.
+func (d *Drainer) begin(ctx context.Context) (context.Context, func(), bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.draining {
+		return nil, nil, false
+	}
+
+	d.inflight.Add(1)
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(d.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+		d.inflight.Done()
+	}, true
+}
+
+// Observe reports the resources as existing and up-to-date while draining, so that no action is taken on them.
+func (d *Drainer) Observe(ctx context.Context, mg *unstructured.Unstructured) (controller.ExternalObservation, error) {
+	ctx, end, ok := d.begin(ctx)
+	if !ok {
+		return controller.ExternalObservation{
+			ResourceExists:   true,
+			ResourceUpToDate: true,
+		}, nil
+	}
+	defer end()
+	return d.client.Observe(ctx, mg)
+}
+
+func (d *Drainer) Create(ctx context.Context, mg *unstructured.Unstructured) error {
+	ctx, end, ok := d.begin(ctx)
+	if !ok {
+		return nil
+	}
+	defer end()
+	return d.client.Create(ctx, mg)
+}
+
+func (d *Drainer) Update(ctx context.Context, mg *unstructured.Unstructured) error {
+	ctx, end, ok := d.begin(ctx)
+	if !ok {
+		return nil
+	}
+	defer end()
+	return d.client.Update(ctx, mg)
+}
+
+// Delete skips the deletion while draining, the next controller instance deleting the resource.
+func (d *Drainer) Delete(ctx context.Context, mg *unstructured.Unstructured) error {
+	ctx, end, ok := d.begin(ctx)
+	if !ok {
+		return nil
+	}
+	defer end()
+	return d.client.Delete(ctx, mg)
+}
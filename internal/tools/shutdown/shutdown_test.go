@@ -0,0 +1,106 @@
+package shutdown
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/controller"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// blockingClient blocks its calls until released or until their context is cancelled.
+type blockingClient struct {
+	started  chan struct{}
+	release  chan struct{}
+	canceled chan struct{}
+	calls    int
+}
+
+func newBlockingClient() *blockingClient {
+	return &blockingClient{
+		started:  make(chan struct{}, 1),
+		release:  make(chan struct{}),
+		canceled: make(chan struct{}, 1),
+	}
+}
+
+func (c *blockingClient) Observe(ctx context.Context, _ *unstructured.Unstructured) (controller.ExternalObservation, error) {
+	c.calls++
+	c.started <- struct{}{}
+	select {
+	case <-c.release:
+	case <-ctx.Done():
+		c.canceled <- struct{}{}
+		return controller.ExternalObservation{}, ctx.Err()
+	}
+	return controller.ExternalObservation{ResourceExists: true}, nil
+}
+
+func (c *blockingClient) Create(context.Context, *unstructured.Unstructured) error { return nil }
+func (c *blockingClient) Update(context.Context, *unstructured.Unstructured) error { return nil }
+func (c *blockingClient) Delete(context.Context, *unstructured.Unstructured) error {
+	c.calls++
+	return nil
+}
+
+func TestDrainWaitsInFlight(t *testing.T) {
+	cli := newBlockingClient()
+	d := New(cli)
+
+	// The reconcile must survive the cancellation of the controller context
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		_, err := d.Observe(ctx, &unstructured.Unstructured{})
+		result <- err
+	}()
+	<-cli.started
+	cancel()
+
+	drained := make(chan bool, 1)
+	go func() {
+		drained <- d.Drain(time.Second)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(cli.release)
+
+	if !<-drained {
+		t.Errorf("expected the reconcile to be drained")
+	}
+	if err := <-result; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// New reconciles are not accepted while draining
+	obs, err := d.Observe(context.Background(), &unstructured.Unstructured{})
+	if err != nil || !obs.ResourceExists || !obs.ResourceUpToDate {
+		t.Errorf("expected a no-op observation, got %+v (%v)", obs, err)
+	}
+	if cli.calls != 1 {
+		t.Errorf("expected 1 call, got %d", cli.calls)
+	}
+	if err := d.Delete(context.Background(), &unstructured.Unstructured{}); err != nil {
+		t.Errorf("expected the deletion to be skipped, got %v", err)
+	}
+	if cli.calls != 1 {
+		t.Errorf("expected 1 call, got %d", cli.calls)
+	}
+}
+
+func TestDrainTimeoutCancels(t *testing.T) {
+	cli := newBlockingClient()
+	d := New(cli)
+
+	go d.Observe(context.Background(), &unstructured.Unstructured{})
+	<-cli.started
+
+	if d.Drain(10 * time.Millisecond) {
+		t.Errorf("expected the drain to time out")
+	}
+	select {
+	case <-cli.canceled:
+	case <-time.After(time.Second):
+		t.Errorf("expected the in-flight reconcile to be cancelled")
+	}
+}
@@ -0,0 +1,178 @@
+// Package statusschema reads the schema of the status of the custom resources from their CRD, telling apart the
+// status fields the API server accepts from the ones it would reject or prune: the CRDs whose status schema does not
+// preserve the unknown fields (x-kubernetes-preserve-unknown-fields) accept only the declared properties.
+package statusschema
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// DefaultRefreshInterval is the interval the schemas are read again from the CRDs at
+const DefaultRefreshInterval = 10 * time.Minute
+
+var crdsGVR = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+// Schema is the schema of a status field.
+type Schema struct {
+	// preserve: the unknown fields are accepted
+	preserve bool
+	// properties: the schema of the declared fields
+	properties map[string]*Schema
+	// additional: the schema of the fields not declared, nil if not accepted
+	additional *Schema
+}
+
+// Split returns the status fields accepted by the schema, and the others by their dot separated path.
+func (s *Schema) Split(status map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	overflow := map[string]interface{}{}
+	if s == nil {
+		return status, overflow
+	}
+	return s.split("", status, overflow), overflow
+}
+
+func (s *Schema) split(prefix string, fields map[string]interface{}, overflow map[string]interface{}) map[string]interface{} {
+	if s.preserve {
+		return fields
+	}
+	kept := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		child, ok := s.properties[key]
+		if !ok {
+			child = s.additional
+		}
+		if child == nil {
+			overflow[prefix+key] = value
+			continue
+		}
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			kept[key] = value
+			continue
+		}
+		kept[key] = child.split(prefix+key+".", nested, overflow)
+	}
+	return kept
+}
+
+// parse returns the schema described by the OpenAPI v3 schema of the CRD.
+func parse(props map[string]interface{}) *Schema {
+	s := &Schema{properties: map[string]*Schema{}}
+	if preserve, _ := props["x-kubernetes-preserve-unknown-fields"].(bool); preserve {
+		s.preserve = true
+		return s
+	}
+	if fields, ok := props["properties"].(map[string]interface{}); ok {
+		for key, value := range fields {
+			if child, ok := value.(map[string]interface{}); ok {
+				s.properties[key] = parse(child)
+			}
+		}
+	}
+	switch additional := props["additionalProperties"].(type) {
+	case bool:
+		if additional {
+			s.additional = &Schema{preserve: true}
+		}
+	case map[string]interface{}:
+		s.additional = parse(additional)
+	}
+	// Fields without a type nor properties (e.g. int-or-string ones) are left to the API server
+	if _, ok := props["type"]; !ok && len(s.properties) == 0 && s.additional == nil {
+		s.preserve = true
+	}
+	return s
+}
+
+type entry struct {
+	schema  *Schema
+	fetched time.Time
+}
+
+// Reader reads the status schemas from the CRDs, caching them for the refresh interval.
+type Reader struct {
+	dynamic  dynamic.Interface
+	log      logging.Logger
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	schemas map[schema.GroupVersionResource]entry
+}
+
+// New returns the reader of the status schemas through the dynamic client.
+func New(dyn dynamic.Interface, log logging.Logger, interval time.Duration) *Reader {
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+	return &Reader{
+		dynamic:  dyn,
+		log:      log,
+		interval: interval,
+		now:      time.Now,
+		schemas:  map[schema.GroupVersionResource]entry{},
+	}
+}
+
+// Status returns the status schema of the resources, nil if every field is accepted or the CRD cannot be read
+// (e.g. for lack of permissions), in which case the status is written as is.
+func (r *Reader) Status(ctx context.Context, gvr schema.GroupVersionResource) *Schema {
+	if r == nil || r.dynamic == nil {
+		return nil
+	}
+	r.mu.Lock()
+	cached, ok := r.schemas[gvr]
+	r.mu.Unlock()
+	if ok && r.now().Sub(cached.fetched) < r.interval {
+		return cached.schema
+	}
+
+	s, err := r.fetch(ctx, gvr)
+	if err != nil {
+		r.log.Debug("Reading status schema from CRD", "resource", gvr.String(), "error", err)
+	}
+	r.mu.Lock()
+	r.schemas[gvr] = entry{schema: s, fetched: r.now()}
+	r.mu.Unlock()
+	return s
+}
+
+func (r *Reader) fetch(ctx context.Context, gvr schema.GroupVersionResource) (*Schema, error) {
+	name := gvr.Resource + "." + gvr.Group
+	crd, err := r.dynamic.Resource(crdsGVR).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	versions, _, err := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	if err != nil {
+		return nil, err
+	}
+	for _, el := range versions {
+		version, ok := el.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, _ := version["name"].(string); !strings.EqualFold(name, gvr.Version) {
+			continue
+		}
+		status, ok, _ := unstructured.NestedMap(version, "schema", "openAPIV3Schema", "properties", "status")
+		if !ok {
+			return nil, nil
+		}
+		s := parse(status)
+		if s.preserve {
+			return nil, nil
+		}
+		return s, nil
+	}
+	return nil, nil
+}
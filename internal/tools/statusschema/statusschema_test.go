@@ -0,0 +1,116 @@
+package statusschema
+
+import (
+	"context"
+	"testing"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+var reposGVR = schema.GroupVersionResource{Group: "gen.github.com", Version: "v1alpha1", Resource: "repos"}
+
+func newCRD(status map[string]interface{}) *unstructured.Unstructured {
+	crd := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"versions": []interface{}{
+				map[string]interface{}{
+					"name": "v1alpha1",
+					"schema": map[string]interface{}{
+						"openAPIV3Schema": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"status": status,
+							},
+						},
+					},
+				},
+			},
+		},
+	}}
+	crd.SetAPIVersion("apiextensions.k8s.io/v1")
+	crd.SetKind("CustomResourceDefinition")
+	crd.SetName("repos.gen.github.com")
+	return crd
+}
+
+var strictStatus = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"id": map[string]interface{}{"type": "string"},
+		"conditions": map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"type": "object"},
+		},
+		"links": map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"type": "string"},
+		},
+		"settings": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"visibility": map[string]interface{}{"type": "string"},
+			},
+		},
+	},
+}
+
+func newReader(t *testing.T, status map[string]interface{}) *Reader {
+	t.Helper()
+	dyn := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		crdsGVR: "CustomResourceDefinitionList",
+	}, newCRD(status))
+	return New(dyn, logging.NewNopLogger(), 0)
+}
+
+func TestSplit(t *testing.T) {
+	sch := newReader(t, strictStatus).Status(context.Background(), reposGVR)
+	if sch == nil {
+		t.Fatalf("expected a strict status schema")
+	}
+
+	kept, overflow := sch.Split(map[string]interface{}{
+		"id":         "42",
+		"conditions": []interface{}{map[string]interface{}{"type": "Ready"}},
+		"links":      map[string]interface{}{"self": "/repos/42"},
+		"settings":   map[string]interface{}{"visibility": "public", "archived": false},
+		"findBy":     map[string]interface{}{"page": "3"},
+	})
+	if len(kept) != 4 || kept["id"] != "42" {
+		t.Errorf("unexpected kept fields: %v", kept)
+	}
+	if settings := kept["settings"].(map[string]interface{}); len(settings) != 1 {
+		t.Errorf("expected only the declared settings to be kept: %v", settings)
+	}
+	if len(overflow) != 2 || overflow["settings.archived"] != false {
+		t.Errorf("unexpected overflow: %v", overflow)
+	}
+	if page, ok := overflow["findBy"].(map[string]interface{}); !ok || page["page"] != "3" {
+		t.Errorf("expected the undeclared findBy field in the overflow: %v", overflow)
+	}
+}
+
+func TestPreserveUnknownFields(t *testing.T) {
+	status := map[string]interface{}{
+		"type":                                 "object",
+		"x-kubernetes-preserve-unknown-fields": true,
+	}
+	if sch := newReader(t, status).Status(context.Background(), reposGVR); sch != nil {
+		t.Errorf("expected every status field to be accepted")
+	}
+}
+
+func TestMissingCRD(t *testing.T) {
+	r := newReader(t, strictStatus)
+	gvr := schema.GroupVersionResource{Group: "gen.example.com", Version: "v1", Resource: "widgets"}
+	if sch := r.Status(context.Background(), gvr); sch != nil {
+		t.Errorf("expected the status to be written as is")
+	}
+	var nilReader *Reader
+	if sch := nilReader.Status(context.Background(), reposGVR); sch != nil {
+		t.Errorf("expected a nil reader to accept every field")
+	}
+}
@@ -0,0 +1,147 @@
+// Package template renders the request bodies from Go templates or CEL expressions
+// evaluated over the fields of the custom resource.
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	gotemplate "text/template"
+
+	"github.com/google/cel-go/cel"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type Type string
+
+const (
+	// TypeGoTemplate: a Go template producing the JSON body
+	TypeGoTemplate Type = "gotemplate"
+	// TypeCEL: a CEL expression producing the body
+	TypeCEL Type = "cel"
+)
+
+func (t Type) String() string {
+	return string(t)
+}
+
+// ToType returns the template type, defaulting to TypeGoTemplate.
+func ToType(ty string) (Type, error) {
+	switch strings.ToLower(ty) {
+	case "", TypeGoTemplate.String():
+		return TypeGoTemplate, nil
+	case TypeCEL.String():
+		return TypeCEL, nil
+	}
+	return "", fmt.Errorf("unknown template type: %s", ty)
+}
+
+// Render evaluates the template over the given data (e.g. spec and status) and returns the resulting value.
+func Render(ty string, src string, data map[string]interface{}) (interface{}, error) {
+	t, err := ToType(ty)
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case TypeCEL:
+		return Eval(src, data)
+	default:
+		return renderGoTemplate(src, data)
+	}
+}
+
+func renderGoTemplate(src string, data map[string]interface{}) (interface{}, error) {
+	tpl, err := gotemplate.New("body").
+		Option("missingkey=zero").
+		Funcs(gotemplate.FuncMap{
+			"toJson": func(v interface{}) (string, error) {
+				b, err := json.Marshal(v)
+				return string(b), err
+			},
+		}).
+		Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("executing template: %w", err)
+	}
+
+	var res interface{}
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+		return nil, fmt.Errorf("decoding rendered template: %w", err)
+	}
+	return res, nil
+}
+
+var programs sync.Map
+
+// Eval evaluates the CEL expression over the given data, each top level key being a variable.
+func Eval(expr string, data map[string]interface{}) (interface{}, error) {
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	prg, err := program(expr, names)
+	if err != nil {
+		return nil, err
+	}
+
+	out, _, err := prg.Eval(data)
+	if err != nil {
+		return nil, fmt.Errorf("evaluating expression: %w", err)
+	}
+	val, err := out.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+	if err != nil {
+		return nil, fmt.Errorf("converting expression result: %w", err)
+	}
+	return val.(*structpb.Value).AsInterface(), nil
+}
+
+// EvalBool evaluates the CEL expression over the given data, expecting a boolean result.
+func EvalBool(expr string, data map[string]interface{}) (bool, error) {
+	res, err := Eval(expr, data)
+	if err != nil {
+		return false, err
+	}
+	b, ok := res.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q does not evaluate to a boolean", expr)
+	}
+	return b, nil
+}
+
+// program returns the compiled CEL program, caching it by expression and variables.
+func program(expr string, names []string) (cel.Program, error) {
+	key := strings.Join(names, ",") + "|" + expr
+	if prg, ok := programs.Load(key); ok {
+		return prg.(cel.Program), nil
+	}
+
+	opts := make([]cel.EnvOption, 0, len(names))
+	for _, name := range names {
+		opts = append(opts, cel.Variable(name, cel.DynType))
+	}
+	env, err := cel.NewEnv(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating CEL environment: %w", err)
+	}
+	ast, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		return nil, fmt.Errorf("compiling expression: %w", iss.Err())
+	}
+	prg, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("creating CEL program: %w", err)
+	}
+	programs.Store(key, prg)
+	return prg, nil
+}
@@ -0,0 +1,55 @@
+package template
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	data := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"name":    "repo",
+			"private": true,
+			"topics":  []interface{}{"a", "b"},
+		},
+		"status": map[string]interface{}{},
+	}
+	expected := map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "repositories",
+			"attributes": map[string]interface{}{
+				"name":       "repo",
+				"visibility": "private",
+				"topics":     []interface{}{"a", "b"},
+			},
+		},
+	}
+
+	tests := []struct {
+		ty  string
+		src string
+	}{
+		{
+			ty:  "gotemplate",
+			src: `{"data": {"type": "repositories", "attributes": {"name": "{{ .spec.name }}", "visibility": "{{ if .spec.private }}private{{ else }}public{{ end }}", "topics": {{ toJson .spec.topics }}}}}`,
+		},
+		{
+			ty:  "cel",
+			src: `{"data": {"type": "repositories", "attributes": {"name": spec.name, "visibility": spec.private ? "private" : "public", "topics": spec.topics}}}`,
+		},
+	}
+
+	for _, tc := range tests {
+		got, err := Render(tc.ty, tc.src, data)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.ty, err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected %v, got %v", tc.ty, expected, got)
+		}
+	}
+
+	if _, err := Render("jsonnet", "{}", data); err == nil {
+		t.Errorf("expected error for unknown template type")
+	}
+}
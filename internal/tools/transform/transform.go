@@ -0,0 +1,118 @@
+// Package transform reduces the memory held by the informer cache, stripping from the listed and watched
+// resources the fields not needed to enqueue them (e.g. the managed fields and the large annotations).
+// The resources are always reconciled from their latest version got from the API server, so the
+// stripped fields are never lost on update.
+package transform
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// Options configures the fields stripped from the cached resources.
+type Options struct {
+	// StripManagedFields: if true, the managed fields are removed
+	StripManagedFields bool
+	// MaxAnnotationSize is the size in bytes above which the annotations are removed, 0 keeps them all
+	MaxAnnotationSize int
+}
+
+// Enabled returns true if any field is stripped.
+func (o Options) Enabled() bool {
+	return o.StripManagedFields || o.MaxAnnotationSize > 0
+}
+
+// Strip removes from the resource the fields configured by the options.
+func (o Options) Strip(obj *unstructured.Unstructured) {
+	if o.StripManagedFields {
+		obj.SetManagedFields(nil)
+	}
+	if o.MaxAnnotationSize > 0 {
+		annotations := obj.GetAnnotations()
+		stripped := false
+		for key, value := range annotations {
+			if len(key)+len(value) > o.MaxAnnotationSize {
+				delete(annotations, key)
+				stripped = true
+			}
+		}
+		if stripped {
+			obj.SetAnnotations(annotations)
+		}
+	}
+}
+
+// Client wraps the dynamic client so that the resources it lists and watches are stripped according to the options.
+// The other requests (e.g. get and update) are left untouched.
+func Client(client dynamic.Interface, opts Options) dynamic.Interface {
+	if !opts.Enabled() {
+		return client
+	}
+	return &transformClient{Interface: client, opts: opts}
+}
+
+type transformClient struct {
+	dynamic.Interface
+	opts Options
+}
+
+func (c *transformClient) Resource(resource schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &namespaceableResource{NamespaceableResourceInterface: c.Interface.Resource(resource), opts: c.opts}
+}
+
+type namespaceableResource struct {
+	dynamic.NamespaceableResourceInterface
+	opts Options
+}
+
+func (r *namespaceableResource) Namespace(ns string) dynamic.ResourceInterface {
+	return &resource{ResourceInterface: r.NamespaceableResourceInterface.Namespace(ns), opts: r.opts}
+}
+
+func (r *namespaceableResource) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	return r.opts.list(r.NamespaceableResourceInterface.List(ctx, opts))
+}
+
+func (r *namespaceableResource) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	return r.opts.watch(r.NamespaceableResourceInterface.Watch(ctx, opts))
+}
+
+type resource struct {
+	dynamic.ResourceInterface
+	opts Options
+}
+
+func (r *resource) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	return r.opts.list(r.ResourceInterface.List(ctx, opts))
+}
+
+func (r *resource) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	return r.opts.watch(r.ResourceInterface.Watch(ctx, opts))
+}
+
+func (o Options) list(list *unstructured.UnstructuredList, err error) (*unstructured.UnstructuredList, error) {
+	if err != nil {
+		return list, err
+	}
+	for i := range list.Items {
+		o.Strip(&list.Items[i])
+	}
+	return list, nil
+}
+
+func (o Options) watch(w watch.Interface, err error) (watch.Interface, error) {
+	if err != nil {
+		return w, err
+	}
+	return watch.Filter(w, func(in watch.Event) (watch.Event, bool) {
+		if obj, ok := in.Object.(*unstructured.Unstructured); ok {
+			o.Strip(obj)
+		}
+		return in, true
+	}), nil
+}
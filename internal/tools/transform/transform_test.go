@@ -0,0 +1,85 @@
+package transform
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+var gvr = schema.GroupVersionResource{Group: "gen.github.com", Version: "v1alpha1", Resource: "repos"}
+
+func newRepo(name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("gen.github.com/v1alpha1")
+	obj.SetKind("Repo")
+	obj.SetNamespace("default")
+	obj.SetName(name)
+	obj.SetAnnotations(map[string]string{
+		"krateo.io/connector-verbose": "true",
+		"krateo.io/last-applied":      strings.Repeat("x", 1024),
+	})
+	obj.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationUpdate}})
+	return obj
+}
+
+func assertStripped(t *testing.T, obj *unstructured.Unstructured) {
+	t.Helper()
+	if len(obj.GetManagedFields()) > 0 {
+		t.Errorf("expected the managed fields to be stripped from %s", obj.GetName())
+	}
+	annotations := obj.GetAnnotations()
+	if _, ok := annotations["krateo.io/last-applied"]; ok {
+		t.Errorf("expected the large annotation to be stripped from %s", obj.GetName())
+	}
+	if annotations["krateo.io/connector-verbose"] != "true" {
+		t.Errorf("expected the small annotation to be kept in %s", obj.GetName())
+	}
+}
+
+func TestClient(t *testing.T) {
+	scheme := runtime.NewScheme()
+	dyn := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, map[schema.GroupVersionResource]string{gvr: "RepoList"}, newRepo("repo1"))
+	cli := Client(dyn, Options{StripManagedFields: true, MaxAnnotationSize: 256})
+	ctx := context.Background()
+
+	list, err := cli.Resource(gvr).Namespace("default").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list.Items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(list.Items))
+	}
+	assertStripped(t, &list.Items[0])
+
+	w, err := cli.Resource(gvr).Namespace("default").Watch(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Stop()
+	if _, err := dyn.Resource(gvr).Namespace("default").Create(ctx, newRepo("repo2"), metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ev := <-w.ResultChan()
+	assertStripped(t, ev.Object.(*unstructured.Unstructured))
+
+	got, err := cli.Resource(gvr).Namespace("default").Get(ctx, "repo1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.GetManagedFields()) == 0 || len(got.GetAnnotations()) != 2 {
+		t.Errorf("expected the got resource to be left untouched")
+	}
+}
+
+func TestClientDisabled(t *testing.T) {
+	dyn := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	if cli := Client(dyn, Options{}); cli != dyn {
+		t.Errorf("expected the client to be returned as is")
+	}
+}
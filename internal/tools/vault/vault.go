@@ -0,0 +1,291 @@
+// Package vault reads the credentials of the resources from HashiCorp Vault, logging in with the Kubernetes
+// auth method and the token of the ServiceAccount of the controller.
+// The login tokens and the secrets are cached until their lease expires, so that the secrets are read again
+// once renewed (e.g. the dynamic secrets); the secrets without a lease (e.g. KV) are cached for DefaultTTL.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultAuthMount is the path the Kubernetes auth method is mounted at
+	DefaultAuthMount = "kubernetes"
+	// DefaultTTL is how long the secrets without a lease are cached
+	DefaultTTL = 5 * time.Minute
+
+	// refreshMargin is how long before their lease expires the tokens and the secrets are read again
+	refreshMargin = 30 * time.Second
+
+	// tokenPath is the path of the token of the ServiceAccount mounted in the pods
+	tokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// Ref identifies a value of a Vault secret.
+type Ref struct {
+	// Address of the Vault server (e.g. https://vault.example.com:8200), defaults to the one of the client;
+	// it must be the one of the client or one of its AllowedAddresses
+	Address string
+	// Role the controller logs in with
+	Role string
+	// AuthMount is the path the Kubernetes auth method is mounted at, defaults to DefaultAuthMount
+	AuthMount string
+	// Path of the secret, as read with the API (e.g. secret/data/github for a KV v2 engine mounted at secret)
+	Path string
+	// Key of the value in the secret
+	Key string
+}
+
+// login identifies a login to a Vault server.
+type login struct {
+	address, mount, role string
+}
+
+// lease is a value cached until its expiry.
+type lease struct {
+	value  string
+	expiry time.Time
+}
+
+// Client reads the secrets from Vault.
+type Client struct {
+	// Address of the Vault server used when a ref does not tell it, if any
+	Address string
+	// AllowedAddresses are the other Vault servers the refs may tell, so that the authentication objects
+	// cannot send the token of the ServiceAccount of the controller to any server
+	AllowedAddresses []string
+	// TTL of the secrets without a lease, defaults to DefaultTTL
+	TTL time.Duration
+	// HTTPClient calls the Vault servers, defaults to http.DefaultClient
+	HTTPClient *http.Client
+	// ServiceAccountToken returns the JWT the controller logs in with, defaults to the token mounted in the pod
+	ServiceAccountToken func() (string, error)
+
+	now     func() time.Time
+	mu      sync.Mutex
+	tokens  map[login]lease
+	secrets map[Ref]lease
+}
+
+// New returns the client of the Vault server at the given default address.
+func New(address string) *Client {
+	return &Client{
+		Address: address,
+		now:     time.Now,
+		tokens:  map[login]lease{},
+		secrets: map[Ref]lease{},
+	}
+}
+
+// Read returns the value of the secret, the cached one if its lease is not about to expire.
+// Once the login token is rejected (e.g. revoked), the controller logs in again.
+func (c *Client) Read(ctx context.Context, ref Ref) (string, error) {
+	if ref.Address == "" {
+		ref.Address = c.Address
+	}
+	if ref.AuthMount == "" {
+		ref.AuthMount = DefaultAuthMount
+	}
+	if ref.Address == "" || ref.Role == "" || ref.Path == "" || ref.Key == "" {
+		return "", fmt.Errorf("the address, role, path and key of the Vault secret must be specified")
+	}
+	ref.Address = strings.TrimSuffix(ref.Address, "/")
+	if !c.allowed(ref.Address) {
+		return "", fmt.Errorf("the Vault address %s is not allowed, it must be the configured one or one of the allowed addresses", ref.Address)
+	}
+
+	if v, ok := cached(c, c.secrets, ref); ok {
+		return v, nil
+	}
+
+	value, err := c.read(ctx, ref)
+	var denied *deniedError
+	if errors.As(err, &denied) {
+		c.mu.Lock()
+		delete(c.tokens, login{address: ref.Address, mount: ref.AuthMount, role: ref.Role})
+		c.mu.Unlock()
+		value, err = c.read(ctx, ref)
+	}
+	return value, err
+}
+
+// allowed returns true if the address is the one of the client or one of its allowed addresses.
+func (c *Client) allowed(address string) bool {
+	for _, el := range append([]string{c.Address}, c.AllowedAddresses...) {
+		if el != "" && strings.TrimSuffix(el, "/") == address {
+			return true
+		}
+	}
+	return false
+}
+
+// read reads the secret with the login token of the ref, caching it until its lease expires.
+func (c *Client) read(ctx context.Context, ref Ref) (string, error) {
+	token, err := c.login(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	var res struct {
+		LeaseDuration int64                  `json:"lease_duration"`
+		Data          map[string]interface{} `json:"data"`
+	}
+	path := strings.TrimPrefix(ref.Path, "/")
+	if err := c.call(ctx, http.MethodGet, ref.Address+"/v1/"+path, token, nil, &res); err != nil {
+		return "", fmt.Errorf("reading the Vault secret %s: %w", path, err)
+	}
+	data := res.Data
+	// KV v2 engines nest the values of the secret under data, next to its metadata
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+	v, ok := data[ref.Key]
+	if !ok || v == nil {
+		return "", fmt.Errorf("key %s not found in the Vault secret %s", ref.Key, path)
+	}
+	value, ok := v.(string)
+	if !ok {
+		value = fmt.Sprint(v)
+	}
+
+	ttl := time.Duration(res.LeaseDuration) * time.Second
+	if ttl <= 0 {
+		ttl = c.TTL
+		if ttl <= 0 {
+			ttl = DefaultTTL
+		}
+	}
+	c.mu.Lock()
+	c.secrets[ref] = lease{value: value, expiry: c.now().Add(ttl)}
+	c.mu.Unlock()
+	return value, nil
+}
+
+// login returns the token the controller logged in with to the server of the ref, the cached one if not about to expire.
+func (c *Client) login(ctx context.Context, ref Ref) (string, error) {
+	key := login{address: ref.Address, mount: ref.AuthMount, role: ref.Role}
+	if v, ok := cached(c, c.tokens, key); ok {
+		return v, nil
+	}
+
+	readJWT := c.ServiceAccountToken
+	if readJWT == nil {
+		readJWT = mountedToken
+	}
+	jwt, err := readJWT()
+	if err != nil {
+		return "", fmt.Errorf("reading the ServiceAccount token: %w", err)
+	}
+
+	var res struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int64  `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	body := map[string]string{"role": ref.Role, "jwt": jwt}
+	uri := fmt.Sprintf("%s/v1/auth/%s/login", ref.Address, strings.Trim(ref.AuthMount, "/"))
+	if err := c.call(ctx, http.MethodPost, uri, "", body, &res); err != nil {
+		return "", fmt.Errorf("logging in to Vault with role %s: %w", ref.Role, err)
+	}
+	if res.Auth.ClientToken == "" {
+		return "", fmt.Errorf("no token returned logging in to Vault with role %s", ref.Role)
+	}
+	if res.Auth.LeaseDuration > 0 {
+		c.mu.Lock()
+		c.tokens[key] = lease{value: res.Auth.ClientToken, expiry: c.now().Add(time.Duration(res.Auth.LeaseDuration) * time.Second)}
+		c.mu.Unlock()
+	}
+	return res.Auth.ClientToken, nil
+}
+
+// call sends the request to Vault and decodes its response into out.
+func (c *Client) call(ctx context.Context, method, uri, token string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, uri, reader)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	cli := c.HTTPClient
+	if cli == nil {
+		cli = http.DefaultClient
+	}
+	res, err := cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		var errs struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.Unmarshal(data, &errs)
+		msg := strings.Join(errs.Errors, "; ")
+		if msg == "" {
+			msg = http.StatusText(res.StatusCode)
+		}
+		if res.StatusCode == http.StatusForbidden && token != "" {
+			return &deniedError{msg: msg}
+		}
+		return fmt.Errorf("%d %s", res.StatusCode, msg)
+	}
+	return json.Unmarshal(data, out)
+}
+
+// cached returns the value cached under the key, if its lease is not about to expire.
+func cached[K comparable](c *Client, leases map[K]lease, key K) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok := leases[key]
+	if !ok || !c.now().Add(refreshMargin).Before(l.expiry) {
+		return "", false
+	}
+	return l.value, true
+}
+
+// deniedError is returned when Vault rejects the login token.
+type deniedError struct {
+	msg string
+}
+
+func (e *deniedError) Error() string {
+	return fmt.Sprintf("%d %s", http.StatusForbidden, e.msg)
+}
+
+func mountedToken() (string, error) {
+	data, err := os.ReadFile(tokenPath)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
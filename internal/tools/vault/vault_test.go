@@ -0,0 +1,146 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// vaultServer serves the Kubernetes login of the controller role and the secrets, counting the logins and reads.
+type vaultServer struct {
+	logins, reads int
+	// token is the login token currently accepted
+	token string
+}
+
+func (s *vaultServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/v1/auth/kubernetes/login":
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["role"] != "controller" || body["jwt"] != "sa-jwt" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"errors": ["invalid role or jwt"]}`)
+			return
+		}
+		s.logins++
+		s.token = fmt.Sprintf("vault-token-%d", s.logins)
+		fmt.Fprintf(w, `{"auth": {"client_token": %q, "lease_duration": 3600}}`, s.token)
+	case "/v1/secret/data/github":
+		if r.Header.Get("X-Vault-Token") != s.token {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errors": ["permission denied"]}`)
+			return
+		}
+		s.reads++
+		fmt.Fprint(w, `{"lease_duration": 0, "data": {"data": {"token": "gh-token"}, "metadata": {"version": 2}}}`)
+	case "/v1/database/creds/app":
+		s.reads++
+		fmt.Fprintf(w, `{"lease_duration": 600, "data": {"username": "app", "password": "password-%d"}}`, s.reads)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newClient(url string) (*Client, *time.Time) {
+	c := New(url)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	c.ServiceAccountToken = func() (string, error) { return "sa-jwt", nil }
+	return c, &now
+}
+
+func TestReadKV(t *testing.T) {
+	s := &vaultServer{}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	c, now := newClient(srv.URL)
+	ref := Ref{Role: "controller", Path: "secret/data/github", Key: "token"}
+
+	for i := 0; i < 2; i++ {
+		v, err := c.Read(context.Background(), ref)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if v != "gh-token" {
+			t.Errorf("expected gh-token, got %s", v)
+		}
+	}
+	if s.logins != 1 || s.reads != 1 {
+		t.Errorf("expected the token and the secret to be cached, got %d logins and %d reads", s.logins, s.reads)
+	}
+
+	*now = now.Add(DefaultTTL)
+	s.token = "revoked"
+	if _, err := c.Read(context.Background(), ref); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.logins != 2 || s.reads != 2 {
+		t.Errorf("expected to log in again once the token is rejected, got %d logins and %d reads", s.logins, s.reads)
+	}
+
+	if _, err := c.Read(context.Background(), Ref{Role: "controller", Path: "secret/data/github", Key: "missing"}); err == nil {
+		t.Errorf("expected an error for a missing key")
+	}
+	if _, err := c.Read(context.Background(), Ref{Role: "other", Path: "secret/data/github", Key: "token"}); err == nil {
+		t.Errorf("expected an error for a rejected login")
+	}
+}
+
+func TestReadLease(t *testing.T) {
+	s := &vaultServer{}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	c, now := newClient("")
+	c.AllowedAddresses = []string{srv.URL + "/"}
+	ref := Ref{Address: srv.URL, Role: "controller", Path: "database/creds/app", Key: "password"}
+
+	v, _ := c.Read(context.Background(), ref)
+	*now = now.Add(5 * time.Minute)
+	cached, _ := c.Read(context.Background(), ref)
+	if v != "password-1" || cached != v {
+		t.Errorf("expected the secret to be cached during its lease, got %s and %s", v, cached)
+	}
+
+	*now = now.Add(5 * time.Minute)
+	renewed, err := c.Read(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if renewed != "password-2" {
+		t.Errorf("expected the secret to be read again once its lease expires, got %s", renewed)
+	}
+
+	if _, err := New("").Read(context.Background(), Ref{Role: "controller", Path: "database/creds/app", Key: "password"}); err == nil {
+		t.Errorf("expected an error without address")
+	}
+}
+
+func TestReadAddressNotAllowed(t *testing.T) {
+	s := &vaultServer{}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	other := &vaultServer{}
+	otherSrv := httptest.NewServer(other)
+	defer otherSrv.Close()
+	c, _ := newClient(srv.URL)
+
+	if _, err := c.Read(context.Background(), Ref{Address: srv.URL + "/", Role: "controller", Path: "secret/data/github", Key: "token"}); err != nil {
+		t.Fatalf("expected the configured address to be allowed, got %v", err)
+	}
+	if _, err := c.Read(context.Background(), Ref{Address: otherSrv.URL, Role: "controller", Path: "secret/data/github", Key: "token"}); err == nil {
+		t.Errorf("expected an error for an address not allowed")
+	}
+	if other.logins != 0 {
+		t.Errorf("expected no login to the server not allowed, got %d", other.logins)
+	}
+
+	c.AllowedAddresses = []string{otherSrv.URL}
+	if _, err := c.Read(context.Background(), Ref{Address: otherSrv.URL, Role: "controller", Path: "secret/data/github", Key: "token"}); err != nil {
+		t.Fatalf("expected the allowed address to be read, got %v", err)
+	}
+}
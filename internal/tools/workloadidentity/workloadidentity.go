@@ -0,0 +1,352 @@
+// Package workloadidentity exchanges a token of the ServiceAccount of the controller, requested for an audience
+// with the TokenRequest API, at an external STS or OIDC endpoint for an API token (e.g. GCP workload identity
+// federation, Azure federated credentials), so that no static secret is stored for the cloud-backed APIs.
+// The API tokens are cached until shortly before they expire.
+package workloadidentity
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// GrantTypeTokenExchange exchanges the ServiceAccount token per RFC 8693 (e.g. the GCP STS)
+	GrantTypeTokenExchange = "tokenExchange"
+	// GrantTypeClientAssertion presents the ServiceAccount token as the client assertion of a client credentials
+	// grant per RFC 7523 (e.g. Azure federated credentials)
+	GrantTypeClientAssertion = "clientAssertion"
+
+	// DefaultExpirationSeconds is the validity requested for the ServiceAccount tokens
+	DefaultExpirationSeconds = 3600
+
+	// refreshMargin is how long before their expiry the API tokens are exchanged again
+	refreshMargin = time.Minute
+
+	// tokenPath is the path of the token of the ServiceAccount mounted in the pods
+	tokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+var serviceAccountsGVR = schema.GroupVersionResource{Version: "v1", Resource: "serviceaccounts"}
+
+// DefaultAPIServerAudiences are the audiences the API servers accept by default, refused for the exchanges since
+// their tokens would grant access to the cluster as the controller.
+var DefaultAPIServerAudiences = []string{
+	"https://kubernetes.default.svc",
+	"https://kubernetes.default.svc.cluster.local",
+	"kubernetes.default.svc",
+	"kubernetes.default.svc.cluster.local",
+}
+
+// Config tells how the API token is obtained.
+type Config struct {
+	// Audience of the ServiceAccount token, sent as the audience of the exchange as well
+	// (e.g. //iam.googleapis.com/projects/123/locations/global/workloadIdentityPools/pool/providers/krateo)
+	Audience string
+	// TokenURL of the STS or OIDC endpoint the token is exchanged at (e.g. https://sts.googleapis.com/v1/token)
+	TokenURL string
+	// GrantType of the exchange [tokenExchange, clientAssertion], defaults to tokenExchange
+	GrantType string
+	// ClientID the token is exchanged for, required by the clientAssertion grant
+	ClientID string
+	// Scope of the API token, if any (e.g. https://management.azure.com/.default)
+	Scope string
+	// ExpirationSeconds of the ServiceAccount token, defaults to DefaultExpirationSeconds
+	ExpirationSeconds int64
+}
+
+// ServiceAccount identifies the ServiceAccount the tokens are requested for.
+type ServiceAccount struct {
+	Namespace string
+	Name      string
+}
+
+// Policy restricts the configurations the tokens are exchanged with, so that the authentication objects cannot send
+// the tokens of the ServiceAccount of the controller to any endpoint, nor request them for any audience.
+type Policy struct {
+	// TokenURLs the configurations may tell; none is allowed if empty
+	TokenURLs []string
+	// Audiences the configurations may tell; none is allowed if empty
+	Audiences []string
+	// APIServerAudiences are the audiences accepted by the API server, refused even if allowed;
+	// DefaultAPIServerAudiences are refused anyway
+	APIServerAudiences []string
+}
+
+// check returns an error if the configuration is not allowed by the policy.
+func (p Policy) check(cfg Config) error {
+	if contains(DefaultAPIServerAudiences, cfg.Audience) || contains(p.APIServerAudiences, cfg.Audience) {
+		return fmt.Errorf("the audience %s of the workload identity is an audience of the API server", cfg.Audience)
+	}
+	if !contains(p.Audiences, cfg.Audience) {
+		return fmt.Errorf("the audience %s of the workload identity is not allowed", cfg.Audience)
+	}
+	if !contains(p.TokenURLs, cfg.TokenURL) {
+		return fmt.Errorf("the token URL %s of the workload identity is not allowed", cfg.TokenURL)
+	}
+	return nil
+}
+
+// contains returns true if the list contains the value, ignoring the trailing slashes.
+func contains(list []string, value string) bool {
+	for _, el := range list {
+		if strings.TrimSuffix(el, "/") == strings.TrimSuffix(value, "/") {
+			return true
+		}
+	}
+	return false
+}
+
+// InClusterServiceAccount returns the ServiceAccount of the pod, read from the subject of its mounted token.
+func InClusterServiceAccount() (ServiceAccount, error) {
+	data, err := os.ReadFile(tokenPath)
+	if err != nil {
+		return ServiceAccount{}, err
+	}
+	return serviceAccountOf(strings.TrimSpace(string(data)))
+}
+
+// InClusterAudiences returns the audiences of the token mounted in the pod, the ones the API server accepts.
+func InClusterAudiences() ([]string, error) {
+	data, err := os.ReadFile(tokenPath)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := claimsOf(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, err
+	}
+	return claims.audiences(), nil
+}
+
+// claims are the claims of a ServiceAccount token read by the controller.
+type claims struct {
+	Sub string          `json:"sub"`
+	Aud json.RawMessage `json:"aud"`
+}
+
+// audiences returns the audiences of the token, a single string or a list.
+func (c claims) audiences() []string {
+	var list []string
+	if err := json.Unmarshal(c.Aud, &list); err == nil {
+		return list
+	}
+	var single string
+	if err := json.Unmarshal(c.Aud, &single); err == nil && single != "" {
+		return []string{single}
+	}
+	return nil
+}
+
+// claimsOf decodes the claims of the JWT, without verifying it.
+func claimsOf(token string) (claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims{}, fmt.Errorf("the ServiceAccount token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return claims{}, fmt.Errorf("decoding the ServiceAccount token: %w", err)
+	}
+	var res claims
+	if err := json.Unmarshal(payload, &res); err != nil {
+		return claims{}, fmt.Errorf("decoding the ServiceAccount token: %w", err)
+	}
+	return res, nil
+}
+
+// serviceAccountOf returns the ServiceAccount of the subject of the JWT (system:serviceaccount:<namespace>:<name>).
+func serviceAccountOf(token string) (ServiceAccount, error) {
+	claims, err := claimsOf(token)
+	if err != nil {
+		return ServiceAccount{}, err
+	}
+	fields := strings.Split(claims.Sub, ":")
+	if len(fields) != 4 || fields[0] != "system" || fields[1] != "serviceaccount" {
+		return ServiceAccount{}, fmt.Errorf("the subject %q of the token is not a ServiceAccount", claims.Sub)
+	}
+	return ServiceAccount{Namespace: fields[2], Name: fields[3]}, nil
+}
+
+// token is an API token cached until its expiry.
+type token struct {
+	value  string
+	expiry time.Time
+}
+
+// Exchanger exchanges the tokens of a ServiceAccount for API tokens.
+type Exchanger struct {
+	dynamicClient  dynamic.Interface
+	serviceAccount ServiceAccount
+	// HTTPClient calls the token endpoints, defaults to http.DefaultClient
+	HTTPClient *http.Client
+	// Policy restricts the token URLs and the audiences of the configurations
+	Policy Policy
+
+	now    func() time.Time
+	mu     sync.Mutex
+	tokens map[Config]token
+}
+
+// New returns the exchanger of the tokens of the given ServiceAccount, requested with the dynamic client.
+func New(dyn dynamic.Interface, sa ServiceAccount) *Exchanger {
+	return &Exchanger{
+		dynamicClient:  dyn,
+		serviceAccount: sa,
+		now:            time.Now,
+		tokens:         map[Config]token{},
+	}
+}
+
+// Token returns the API token obtained with the given configuration, the cached one if not about to expire.
+func (e *Exchanger) Token(ctx context.Context, cfg Config) (string, error) {
+	if cfg.Audience == "" || cfg.TokenURL == "" {
+		return "", fmt.Errorf("the audience and the token URL of the workload identity must be specified")
+	}
+	if err := e.Policy.check(cfg); err != nil {
+		return "", err
+	}
+	if cfg.GrantType == "" {
+		cfg.GrantType = GrantTypeTokenExchange
+	}
+	if cfg.ExpirationSeconds <= 0 {
+		cfg.ExpirationSeconds = DefaultExpirationSeconds
+	}
+
+	e.mu.Lock()
+	cached, ok := e.tokens[cfg]
+	e.mu.Unlock()
+	if ok && e.now().Add(refreshMargin).Before(cached.expiry) {
+		return cached.value, nil
+	}
+
+	subject, err := e.serviceAccountToken(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	tok, err := e.exchange(ctx, cfg, subject)
+	if err != nil {
+		return "", err
+	}
+	if !tok.expiry.IsZero() {
+		e.mu.Lock()
+		e.tokens[cfg] = tok
+		e.mu.Unlock()
+	}
+	return tok.value, nil
+}
+
+// serviceAccountToken requests a token of the ServiceAccount for the audience of the configuration.
+func (e *Exchanger) serviceAccountToken(ctx context.Context, cfg Config) (string, error) {
+	if e.serviceAccount.Name == "" || e.serviceAccount.Namespace == "" {
+		return "", fmt.Errorf("the ServiceAccount of the controller is unknown")
+	}
+	req := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "authentication.k8s.io/v1",
+		"kind":       "TokenRequest",
+		"spec": map[string]interface{}{
+			"audiences":         []interface{}{cfg.Audience},
+			"expirationSeconds": cfg.ExpirationSeconds,
+		},
+	}}
+	req.SetName(e.serviceAccount.Name)
+	req.SetNamespace(e.serviceAccount.Namespace)
+
+	res, err := e.dynamicClient.Resource(serviceAccountsGVR).
+		Namespace(e.serviceAccount.Namespace).
+		Create(ctx, req, metav1.CreateOptions{}, "token")
+	if err != nil {
+		return "", fmt.Errorf("requesting a token of the ServiceAccount %s/%s: %w", e.serviceAccount.Namespace, e.serviceAccount.Name, err)
+	}
+	tok, _, _ := unstructured.NestedString(res.Object, "status", "token")
+	if tok == "" {
+		return "", fmt.Errorf("no token issued for the ServiceAccount %s/%s", e.serviceAccount.Namespace, e.serviceAccount.Name)
+	}
+	return tok, nil
+}
+
+// exchange exchanges the ServiceAccount token for an API token at the token URL of the configuration.
+func (e *Exchanger) exchange(ctx context.Context, cfg Config, subject string) (token, error) {
+	form := url.Values{}
+	switch cfg.GrantType {
+	case GrantTypeTokenExchange:
+		form.Set("grant_type", "urn:ietf:params:oauth:grant-type:token-exchange")
+		form.Set("subject_token", subject)
+		form.Set("subject_token_type", "urn:ietf:params:oauth:token-type:jwt")
+		form.Set("requested_token_type", "urn:ietf:params:oauth:token-type:access_token")
+		form.Set("audience", cfg.Audience)
+	case GrantTypeClientAssertion:
+		if cfg.ClientID == "" {
+			return token{}, fmt.Errorf("the client ID must be specified for the %s grant", GrantTypeClientAssertion)
+		}
+		form.Set("grant_type", "client_credentials")
+		form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+		form.Set("client_assertion", subject)
+	default:
+		return token{}, fmt.Errorf("unknown grant type: %s", cfg.GrantType)
+	}
+	if cfg.ClientID != "" {
+		form.Set("client_id", cfg.ClientID)
+	}
+	if cfg.Scope != "" {
+		form.Set("scope", cfg.Scope)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return token{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	cli := e.HTTPClient
+	if cli == nil {
+		cli = http.DefaultClient
+	}
+	res, err := cli.Do(req)
+	if err != nil {
+		return token{}, fmt.Errorf("exchanging the ServiceAccount token at %s: %w", cfg.TokenURL, err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return token{}, err
+	}
+
+	var body struct {
+		AccessToken      string      `json:"access_token"`
+		ExpiresIn        json.Number `json:"expires_in"`
+		Error            string      `json:"error"`
+		ErrorDescription string      `json:"error_description"`
+	}
+	_ = json.Unmarshal(data, &body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg := strings.TrimSpace(strings.Join([]string{body.Error, body.ErrorDescription}, " "))
+		if msg == "" {
+			msg = http.StatusText(res.StatusCode)
+		}
+		return token{}, fmt.Errorf("exchanging the ServiceAccount token at %s: %d %s", cfg.TokenURL, res.StatusCode, msg)
+	}
+	if body.AccessToken == "" {
+		return token{}, fmt.Errorf("no access token returned by %s", cfg.TokenURL)
+	}
+
+	tok := token{value: body.AccessToken}
+	if secs, err := body.ExpiresIn.Int64(); err == nil && secs > 0 {
+		tok.expiry = e.now().Add(time.Duration(secs) * time.Second)
+	}
+	return tok, nil
+}
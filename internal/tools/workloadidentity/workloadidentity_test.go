@@ -0,0 +1,178 @@
+package workloadidentity
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newExchanger returns the exchanger of the tokens of the krateo-system/controller ServiceAccount, the issued
+// tokens recording the audiences they are requested for.
+func newExchanger(t *testing.T) (*Exchanger, *int) {
+	t.Helper()
+	dyn := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	requests := 0
+	dyn.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		create := action.(k8stesting.CreateAction)
+		if create.GetSubresource() != "token" || action.GetNamespace() != "krateo-system" {
+			return true, nil, fmt.Errorf("unexpected request: %v", action)
+		}
+		requests++
+		req := create.GetObject().(*unstructured.Unstructured)
+		audiences, _, _ := unstructured.NestedStringSlice(req.Object, "spec", "audiences")
+		res := req.DeepCopy()
+		_ = unstructured.SetNestedField(res.Object, "sa-token-"+audiences[0], "status", "token")
+		return true, res, nil
+	})
+	return New(dyn, ServiceAccount{Namespace: "krateo-system", Name: "controller"}), &requests
+}
+
+func TestTokenExchange(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:token-exchange" ||
+			r.Form.Get("subject_token") != "sa-token-gcp" || r.Form.Get("audience") != "gcp" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": "invalid_grant", "error_description": "unexpected subject token"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "api-token", "expires_in": 3600}`)
+	}))
+	defer srv.Close()
+
+	e, requests := newExchanger(t)
+	e.Policy = Policy{TokenURLs: []string{srv.URL}, Audiences: []string{"gcp", "other"}}
+	now := time.Now()
+	e.now = func() time.Time { return now }
+	cfg := Config{Audience: "gcp", TokenURL: srv.URL, Scope: "https://www.googleapis.com/auth/cloud-platform"}
+
+	for i := 0; i < 2; i++ {
+		tok, err := e.Token(context.Background(), cfg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if tok != "api-token" {
+			t.Errorf("expected api-token, got %s", tok)
+		}
+	}
+	if *requests != 1 {
+		t.Errorf("expected the API token to be cached, got %d ServiceAccount token requests", *requests)
+	}
+
+	now = now.Add(59*time.Minute + 30*time.Second)
+	if _, err := e.Token(context.Background(), cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *requests != 2 {
+		t.Errorf("expected the API token about to expire to be exchanged again, got %d ServiceAccount token requests", *requests)
+	}
+
+	_, err := e.Token(context.Background(), Config{Audience: "other", TokenURL: srv.URL})
+	if err == nil {
+		t.Errorf("expected the rejected exchange to fail")
+	}
+}
+
+func TestClientAssertion(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("client_id") != "app" ||
+			r.Form.Get("client_assertion") != "sa-token-api://AzureADTokenExchange" ||
+			r.Form.Get("client_assertion_type") != "urn:ietf:params:oauth:client-assertion-type:jwt-bearer" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"access_token": "azure-token", "expires_in": "3599"}`)
+	}))
+	defer srv.Close()
+
+	e, _ := newExchanger(t)
+	e.Policy = Policy{TokenURLs: []string{srv.URL}, Audiences: []string{"api://AzureADTokenExchange"}}
+	cfg := Config{Audience: "api://AzureADTokenExchange", TokenURL: srv.URL, GrantType: GrantTypeClientAssertion, ClientID: "app"}
+	tok, err := e.Token(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tok != "azure-token" {
+		t.Errorf("expected azure-token, got %s", tok)
+	}
+
+	cfg.ClientID = ""
+	if _, err := e.Token(context.Background(), cfg); err == nil {
+		t.Errorf("expected an error without client ID")
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	e, requests := newExchanger(t)
+	e.Policy = Policy{
+		TokenURLs:          []string{"https://sts.googleapis.com/v1/token"},
+		Audiences:          []string{"gcp", "https://kubernetes.default.svc", "https://10.0.0.1:6443"},
+		APIServerAudiences: []string{"https://10.0.0.1:6443"},
+	}
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "token URL not allowed", cfg: Config{Audience: "gcp", TokenURL: "https://attacker.example.com/token"}},
+		{name: "audience not allowed", cfg: Config{Audience: "other", TokenURL: "https://sts.googleapis.com/v1/token"}},
+		{name: "default API server audience", cfg: Config{Audience: "https://kubernetes.default.svc/", TokenURL: "https://sts.googleapis.com/v1/token"}},
+		{name: "API server audience", cfg: Config{Audience: "https://10.0.0.1:6443", TokenURL: "https://sts.googleapis.com/v1/token"}},
+	}
+	for _, tt := range tests {
+		if _, err := e.Token(context.Background(), tt.cfg); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+	if *requests != 0 {
+		t.Errorf("expected no ServiceAccount token requested, got %d", *requests)
+	}
+
+	if _, err := New(nil, ServiceAccount{}).Token(context.Background(), Config{Audience: "gcp", TokenURL: "https://sts.googleapis.com/v1/token"}); err == nil {
+		t.Errorf("expected an error without allowed token URLs nor audiences")
+	}
+}
+
+func TestClaimsAudiences(t *testing.T) {
+	for _, aud := range []string{`["https://kubernetes.default.svc.cluster.local","k3s"]`, `"https://kubernetes.default.svc.cluster.local"`} {
+		c := claims{Aud: []byte(aud)}
+		if got := c.audiences(); len(got) == 0 || got[0] != "https://kubernetes.default.svc.cluster.local" {
+			t.Errorf("%s: unexpected audiences %v", aud, got)
+		}
+	}
+	if got := (claims{}).audiences(); got != nil {
+		t.Errorf("expected no audiences, got %v", got)
+	}
+}
+
+func TestServiceAccountOf(t *testing.T) {
+	enc := base64.RawURLEncoding
+	jwt := func(sub string) string {
+		return enc.EncodeToString([]byte(`{"alg":"RS256"}`)) + "." + enc.EncodeToString([]byte(`{"sub":"`+sub+`"}`)) + ".sig"
+	}
+
+	sa, err := serviceAccountOf(jwt("system:serviceaccount:krateo-system:controller"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sa != (ServiceAccount{Namespace: "krateo-system", Name: "controller"}) {
+		t.Errorf("unexpected ServiceAccount: %+v", sa)
+	}
+	for _, token := range []string{jwt("user"), "opaque"} {
+		if _, err := serviceAccountOf(token); err == nil {
+			t.Errorf("%s: expected an error", token)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -14,9 +15,30 @@ import (
 	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
 	restResources "github.com/krateoplatformops/rest-dynamic-controller/internal/restResources"
 	"github.com/krateoplatformops/rest-dynamic-controller/internal/support"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/audit"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/authstatus"
+	customcondition "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/condition"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/gvrcache"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/healthprobe"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/hotloop"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/logsampling"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/metrics"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/preflight"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/profiling"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/provenance"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/quota"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/requeue"
 	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/rotation"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/shard"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/shutdown"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/statusschema"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/transform"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/vault"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/workloadidentity"
 	"github.com/krateoplatformops/unstructured-runtime/pkg/controller"
 	"github.com/krateoplatformops/unstructured-runtime/pkg/pluralizer"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -24,7 +46,9 @@ import (
 	memory "k8s.io/client-go/discovery/cached"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
 )
 
 const (
@@ -44,6 +68,8 @@ func main() {
 	workers := flag.Int("workers", support.EnvInt("REST_CONTROLLER_WORKERS", 1), "number of workers")
 	resyncInterval := flag.Duration("resync-interval",
 		support.EnvDuration("REST_CONTROLLER_RESYNC_INTERVAL", time.Minute*1), "resync interval")
+	resyncJitter := flag.Int("resync-jitter",
+		support.EnvInt("REST_CONTROLLER_RESYNC_JITTER", 0), "percentage of the resync interval the observations of the resources are randomly spread by (0 disables the jitter)")
 	resourceGroup := flag.String("group",
 		support.EnvString("REST_CONTROLLER_GROUP", ""), "resource api group")
 	resourceVersion := flag.String("version",
@@ -54,6 +80,102 @@ func main() {
 		support.EnvString("REST_CONTROLLER_NAMESPACE", "default"), "namespace")
 	urlplurals := flag.String("urlplurals",
 		support.EnvString("URL_PLURALS", "http://bff.krateo-system.svc.cluster.local:8081/api-info/names"), "url plurals")
+	labelSelector := flag.String("label-selector",
+		support.EnvString("REST_CONTROLLER_LABEL_SELECTOR", ""), "label selector restricting the reconciled resources (e.g. tier=prod)")
+	shardIndex := flag.Int("shard-index",
+		support.EnvInt("REST_CONTROLLER_SHARD_INDEX", 0), "index of the shard reconciled by this replica")
+	shardCount := flag.Int("shard-count",
+		support.EnvInt("REST_CONTROLLER_SHARD_COUNT", 1), "number of shards the resources are split into (1 disables sharding)")
+	auditSinkType := flag.String("audit-sink",
+		support.EnvString("REST_CONTROLLER_AUDIT_SINK", "none"), "audit sink for external mutations [none, stdout, file, configmap]")
+	auditFile := flag.String("audit-file",
+		support.EnvString("REST_CONTROLLER_AUDIT_FILE", ""), "path of the audit file (file sink)")
+	auditConfigMap := flag.String("audit-configmap",
+		support.EnvString("REST_CONTROLLER_AUDIT_CONFIGMAP", "rest-dynamic-controller-audit"), "name of the audit configmap (configmap sink)")
+	hotLoopWindow := flag.Duration("hotloop-window",
+		support.EnvDuration("REST_CONTROLLER_HOTLOOP_WINDOW", time.Minute*10), "period in which updates of the same resource are counted to detect update loops")
+	hotLoopThreshold := flag.Int("hotloop-threshold",
+		support.EnvInt("REST_CONTROLLER_HOTLOOP_THRESHOLD", 0), "number of updates within the hotloop window that flags a possible update loop (0 disables the detection)")
+	hotLoopCooldown := flag.Duration("hotloop-cooldown",
+		support.EnvDuration("REST_CONTROLLER_HOTLOOP_COOLDOWN", time.Minute*15), "period during which updates are skipped once an update loop is detected")
+	conditionVocabulary := flag.String("condition-vocabulary",
+		support.EnvString("REST_CONTROLLER_CONDITION_VOCABULARY", ""), "path of the file mapping the controller conditions to a custom vocabulary")
+	auditConfigMapSize := flag.Int("audit-configmap-size",
+		support.EnvInt("REST_CONTROLLER_AUDIT_CONFIGMAP_SIZE", 100), "number of records kept in the audit configmap (configmap sink)")
+	cacheStripManagedFields := flag.Bool("cache-strip-managed-fields",
+		support.EnvBool("REST_CONTROLLER_CACHE_STRIP_MANAGED_FIELDS", false), "strip the managed fields from the resources held by the informer cache")
+	cacheMaxAnnotationSize := flag.Int("cache-max-annotation-size",
+		support.EnvInt("REST_CONTROLLER_CACHE_MAX_ANNOTATION_SIZE", 0), "size in bytes above which the annotations are stripped from the resources held by the informer cache (0 keeps them all)")
+	pprofAddress := flag.String("pprof-address",
+		support.EnvString("REST_CONTROLLER_PPROF_ADDRESS", ""), "address serving the pprof endpoints (e.g. :6060), disabled if empty")
+	healthProbeInterval := flag.Duration("health-probe-interval",
+		support.EnvDuration("REST_CONTROLLER_HEALTH_PROBE_INTERVAL", healthprobe.DefaultInterval), "interval the servers of the external APIs are probed at (0 disables the probes)")
+	healthProbeTimeout := flag.Duration("health-probe-timeout",
+		support.EnvDuration("REST_CONTROLLER_HEALTH_PROBE_TIMEOUT", healthprobe.DefaultTimeout), "time a probe of a server of the external APIs is given to answer")
+	metricsAddress := flag.String("metrics-address",
+		support.EnvString("REST_CONTROLLER_METRICS_ADDRESS", ""), "address serving the metrics (e.g. :8080), disabled if empty")
+	shutdownTimeout := flag.Duration("shutdown-timeout",
+		support.EnvDuration("REST_CONTROLLER_SHUTDOWN_TIMEOUT", time.Second*25), "time the reconciles in progress are given to complete on shutdown, before their external calls are cancelled")
+	preflightChecks := flag.Bool("preflight",
+		support.EnvBool("REST_CONTROLLER_PREFLIGHT", false), "verify the RestDefinitions and their OAS on startup, exiting if any check fails")
+	preflightConnectivity := flag.Bool("preflight-connectivity",
+		support.EnvBool("REST_CONTROLLER_PREFLIGHT_CONNECTIVITY", false), "call the servers of the RestDefinitions on startup with the credentials of the first resource found (requires --preflight)")
+	exportResources := flag.Bool("export",
+		support.EnvBool("REST_CONTROLLER_EXPORT", false), "list the external resources with the findby verb of the RestDefinition and write a manifest of custom resource for each of them, then exit")
+	exportSpec := flag.String("export-spec",
+		support.EnvString("REST_CONTROLLER_EXPORT_SPEC", ""), "YAML or JSON spec fields shared by the exported manifests, i.e. their authenticationRefs and the parameters of the findby verb (requires --export)")
+	exportOutput := flag.String("export-output",
+		support.EnvString("REST_CONTROLLER_EXPORT_OUTPUT", ""), "file the exported manifests are written to, the standard output if empty (requires --export)")
+	authStatusEnabled := flag.Bool("auth-status",
+		support.EnvBool("REST_CONTROLLER_AUTH_STATUS", true), "report the state of the credentials in the status of the authentication objects")
+	authStatusInterval := flag.Duration("auth-status-interval",
+		support.EnvDuration("REST_CONTROLLER_AUTH_STATUS_INTERVAL", authstatus.DefaultInterval), "minimum interval between the reports of an unchanged credentials state")
+	secretRotation := flag.Bool("secret-rotation",
+		support.EnvBool("REST_CONTROLLER_SECRET_ROTATION", false), "watch the secrets the credentials are read from, reconciling the resources as soon as they are rotated")
+	statusPhase := flag.Bool("status-phase",
+		support.EnvBool("REST_CONTROLLER_STATUS_PHASE", true), "write status.phase and status.message summarizing the conditions (disable for CRDs whose status schema does not allow them)")
+	statusOverflow := flag.Bool("status-overflow",
+		support.EnvBool("REST_CONTROLLER_STATUS_OVERFLOW", true), "store the status fields not allowed by the status schema of the CRD in an annotation")
+	logSamplingWindow := flag.Duration("log-sampling-window",
+		support.EnvDuration("REST_CONTROLLER_LOG_SAMPLING_WINDOW", time.Minute), "window within which the identical log messages are sampled")
+	logSamplingBurst := flag.Int("log-sampling-burst",
+		support.EnvInt("REST_CONTROLLER_LOG_SAMPLING_BURST", 0), "number of identical log messages logged per sampling window (0 disables the sampling)")
+	definitionCache := flag.Bool("definition-cache",
+		support.EnvBool("REST_CONTROLLER_DEFINITION_CACHE", true), "cache the RestDefinitions of the resources, watching them for changes")
+	definitionsNamespace := flag.String("definitions-namespace",
+		support.EnvString("REST_CONTROLLER_DEFINITIONS_NAMESPACE", ""), "namespace of the RestDefinitions shared by the namespaces without their own (empty for none)")
+	serviceAccount := flag.String("service-account",
+		support.EnvString("REST_CONTROLLER_SERVICE_ACCOUNT", ""), "namespace/name of the ServiceAccount whose tokens are exchanged for the API tokens of the workload identities, the one of the pod if empty")
+	workloadIdentityTokenURLs := flag.String("workload-identity-token-urls",
+		support.EnvString("REST_CONTROLLER_WORKLOAD_IDENTITY_TOKEN_URLS", ""), "comma separated token URLs the WorkloadIdentityAuth objects may exchange the ServiceAccount tokens at (none if empty)")
+	workloadIdentityAudiences := flag.String("workload-identity-audiences",
+		support.EnvString("REST_CONTROLLER_WORKLOAD_IDENTITY_AUDIENCES", ""), "comma separated audiences the WorkloadIdentityAuth objects may request the ServiceAccount tokens for (none if empty), the ones of the API server being refused anyway")
+	vaultAddress := flag.String("vault-address",
+		support.EnvString("REST_CONTROLLER_VAULT_ADDRESS", ""), "address of the Vault server the credentials referenced by a vaultRef are read from, unless they tell one of the allowed addresses")
+	vaultAllowedAddresses := flag.String("vault-allowed-addresses",
+		support.EnvString("REST_CONTROLLER_VAULT_ALLOWED_ADDRESSES", ""), "comma separated addresses of the other Vault servers the vaultRef of the credentials may tell")
+	vaultTTL := flag.Duration("vault-ttl",
+		support.EnvDuration("REST_CONTROLLER_VAULT_TTL", vault.DefaultTTL), "time the Vault secrets without a lease (e.g. KV) are cached for")
+	provenanceEnabled := flag.Bool("provenance",
+		support.EnvBool("REST_CONTROLLER_PROVENANCE", false), "stamp the mutations of the external resources with the provenance headers (cluster name, resource UID, reconcile ID)")
+	clusterName := flag.String("cluster-name",
+		support.EnvString("REST_CONTROLLER_CLUSTER_NAME", ""), "name of the cluster stamped on the mutations (requires --provenance) and written in the ownership markers of the external resources")
+	provenanceSecretFile := flag.String("provenance-secret-file",
+		support.EnvString("REST_CONTROLLER_PROVENANCE_SECRET_FILE", ""), "path of the file holding the shared secret the mutations are signed with, unsigned if empty (requires --provenance)")
+	callBudget := flag.Int("call-budget",
+		support.EnvInt("REST_CONTROLLER_CALL_BUDGET", 0), "number of external calls allowed per resource within the call budget window, unless its krateo.io/call-budget annotation tells a lower one (0 for unlimited)")
+	callBudgetWindow := flag.Duration("call-budget-window",
+		support.EnvDuration("REST_CONTROLLER_CALL_BUDGET_WINDOW", quota.DefaultWindow), "period the external calls of the call budget are counted in")
+	capabilityProbeTTL := flag.Duration("capability-probe-ttl",
+		support.EnvDuration("REST_CONTROLLER_CAPABILITY_PROBE_TTL", 0), "time the methods allowed by the APIs, probed with OPTIONS before the updates, are cached for (0 disables the probes)")
+	orphanScanInterval := flag.Duration("orphan-scan-interval",
+		support.EnvDuration("REST_CONTROLLER_ORPHAN_SCAN_INTERVAL", 0), "interval the external resources are scanned at for the orphans left behind by the deleted resources, by their ownership markers (0 disables the scans)")
+	orphanScanDelete := flag.Bool("orphan-scan-delete",
+		support.EnvBool("REST_CONTROLLER_ORPHAN_SCAN_DELETE", false), "delete the orphaned external resources found by the scans instead of only reporting them (requires --orphan-scan-interval and --cluster-name)")
+	discoveryCacheTTL := flag.Duration("discovery-cache-ttl",
+		support.EnvDuration("REST_CONTROLLER_DISCOVERY_CACHE_TTL", gvrcache.DefaultTTL), "time the API discovery and the GVK to GVR resolutions are cached for (0 disables the cache)")
+	reconcileSummary := flag.String("reconcile-summary",
+		support.EnvString("REST_CONTROLLER_RECONCILE_SUMMARY", string(restResources.SummaryChanges)), "reconciles summarized at Info level: none, changes (creations, updates, deletions and failures) or all")
 
 	flag.Usage = func() {
 		fmt.Fprintln(flag.CommandLine.Output(), "Flags:")
@@ -63,7 +185,7 @@ func main() {
 	flag.Parse()
 
 	zl := zap.New(zap.UseDevMode(*debug))
-	log := logging.NewLogrLogger(zl.WithName(serviceName))
+	log := logsampling.New(logging.NewLogrLogger(zl.WithName(serviceName)), *logSamplingWindow, *logSamplingBurst)
 
 	// Kubernetes configuration
 	var cfg *rest.Config
@@ -91,8 +213,37 @@ func main() {
 
 	var handler controller.ExternalClient
 
+	definitionsCtx, stopDefinitions := context.WithCancel(context.Background())
+	defer stopDefinitions()
+	var sa workloadidentity.ServiceAccount
+	if ns, name, ok := strings.Cut(*serviceAccount, "/"); ok {
+		sa = workloadidentity.ServiceAccount{Namespace: ns, Name: name}
+	} else if sa, err = workloadidentity.InClusterServiceAccount(); err != nil {
+		log.Debug("Reading the ServiceAccount of the pod, workload identities disabled.", "error", err)
+	}
+	wiPolicy := workloadidentity.Policy{
+		TokenURLs: support.SplitList(*workloadIdentityTokenURLs),
+		Audiences: support.SplitList(*workloadIdentityAudiences),
+	}
+	if cfg != nil {
+		wiPolicy.APIServerAudiences = append(wiPolicy.APIServerAudiences, cfg.Host)
+	}
+	if auds, err := workloadidentity.InClusterAudiences(); err == nil {
+		wiPolicy.APIServerAudiences = append(wiPolicy.APIServerAudiences, auds...)
+	}
+	vaultClient := vault.New(*vaultAddress)
+	vaultClient.TTL = *vaultTTL
+	vaultClient.AllowedAddresses = support.SplitList(*vaultAllowedAddresses)
 	var swg getter.Getter
-	swg, err = getter.Dynamic(cfg)
+	swg, err = getter.NewDynamic(cfg, getter.DynamicOptions{
+		Context:                definitionsCtx,
+		Logger:                 log,
+		Cache:                  *definitionCache,
+		SharedNamespace:        *definitionsNamespace,
+		ServiceAccount:         sa,
+		WorkloadIdentityPolicy: wiPolicy,
+		Vault:                  vaultClient,
+	})
 	if err != nil {
 		log.Debug("Creating chart url info getter.", "error", err)
 	}
@@ -103,43 +254,295 @@ func main() {
 		WithValues("group", *resourceGroup).
 		WithValues("version", *resourceVersion).
 		WithValues("resource", *resourceName).
+		WithValues("labelSelector", *labelSelector).
+		WithValues("shard", fmt.Sprintf("%d/%d", *shardIndex, *shardCount)).
 		Info("Starting.", "serviceName", serviceName)
 
-	pluralizer := pluralizer.New(urlplurals, http.DefaultClient)
+	plurals := gvrcache.New(http.DefaultTransport, *discoveryCacheTTL)
+	pluralizer := pluralizer.New(urlplurals, plurals.Client())
 
-	handler = restResources.NewHandler(cfg, log, swg, *pluralizer)
+	sinkType, err := audit.ToSinkType(*auditSinkType)
+	if err != nil {
+		log.Info("Parsing audit sink type.", "error", err.Error())
+		os.Exit(1)
+	}
+	auditSink, err := audit.New(audit.Options{
+		Type:          sinkType,
+		Path:          *auditFile,
+		Namespace:     *namespace,
+		Name:          *auditConfigMap,
+		Size:          *auditConfigMapSize,
+		DynamicClient: dyn,
+	})
+	if err != nil {
+		log.Info("Creating audit sink.", "error", err.Error())
+		os.Exit(1)
+	}
+
+	hotLoop := hotloop.New(*hotLoopWindow, *hotLoopThreshold, *hotLoopCooldown)
+
+	var conditions *customcondition.Vocabulary
+	if len(*conditionVocabulary) > 0 {
+		conditions, err = customcondition.LoadVocabulary(*conditionVocabulary)
+		if err != nil {
+			log.Info("Loading condition vocabulary.", "error", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	listWatcher, err := support.ListWatcher(*labelSelector)
+	if err != nil {
+		log.Info("Parsing label selector.", "error", err.Error())
+		os.Exit(1)
+	}
+
+	// Without the name of the cluster, the orphans of the other clusters sharing the API would be deleted as well
+	if *orphanScanDelete && *clusterName == "" {
+		log.Info("Deleting the orphans requires the name of the cluster (--cluster-name).")
+		os.Exit(1)
+	}
+
+	gvr := schema.GroupVersionResource{
+		Group:    *resourceGroup,
+		Version:  *resourceVersion,
+		Resource: *resourceName,
+	}
+
+	if *preflightChecks {
+		gvk, err := restmapper.NewDeferredDiscoveryRESTMapper(cachedDisc).KindFor(gvr)
+		if err != nil {
+			log.Info("Preflight checks failed.", "error", fmt.Sprintf("resolving kind of %s: %v", gvr, err))
+			os.Exit(1)
+		}
+		err = preflight.Run(context.Background(), preflight.Options{
+			DynamicClient:     dyn,
+			GVR:               gvr,
+			Kind:              gvk.Kind,
+			Namespace:         *namespace,
+			Connectivity:      *preflightConnectivity,
+			SwaggerInfoGetter: swg,
+			Logger:            log,
+		})
+		if err != nil {
+			log.Info("Preflight checks failed.", "error", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	if *exportResources {
+		os.Exit(export(log, dyn, cachedDisc, swg, gvr, *namespace, *exportSpec, *exportOutput))
+	}
+
+	sh, err := shard.New(*shardIndex, *shardCount)
+	if err != nil {
+		log.Info("Creating shard.", "error", err.Error())
+		os.Exit(1)
+	}
+
+	var authStatus *authstatus.Reporter
+	if *authStatusEnabled {
+		authStatus = authstatus.New(dyn, log, *authStatusInterval)
+	}
+
+	var rotationWatcher *rotation.Watcher
+	if *secretRotation {
+		rotationCtx, stopRotation := context.WithCancel(context.Background())
+		defer stopRotation()
+		rotationWatcher = rotation.New(rotationCtx, dyn, log)
+	}
+
+	summaries, err := restResources.ParseSummaryLevel(*reconcileSummary)
+	if err != nil {
+		log.Info("Parsing reconcile summary level, summaries disabled.", "error", err.Error())
+	}
+
+	var statusSchema *statusschema.Reader
+	if *statusOverflow {
+		statusSchema = statusschema.New(dyn, log, statusschema.DefaultRefreshInterval)
+	}
+
+	registry := metrics.NewRegistry()
+	restclient.RegisterDocumentMetrics(registry)
+	restResources.RegisterPanicMetrics(registry)
+
+	var healthProbe *healthprobe.Prober
+	if *healthProbeInterval > 0 {
+		healthProbe = healthprobe.New(log, *healthProbeInterval, *healthProbeTimeout)
+		healthProbe.Register(registry)
+	}
+
+	var stamper *provenance.Stamper
+	if *provenanceEnabled {
+		var secret []byte
+		var readErr error
+		if *provenanceSecretFile != "" {
+			secret, readErr = os.ReadFile(*provenanceSecretFile)
+			secret = []byte(strings.TrimSpace(string(secret)))
+		}
+		if readErr != nil {
+			log.Info("Reading the provenance secret, mutations not stamped.", "error", readErr.Error())
+		} else {
+			stamper = provenance.New(*clusterName, secret)
+		}
+	}
+
+	// Shared by the handler and the orphan scanner
+	budget := quota.New(*callBudget, *callBudgetWindow)
+
+	var capabilities *restclient.CapabilityCache
+	if *capabilityProbeTTL > 0 {
+		capabilities = restclient.NewCapabilityCache(*capabilityProbeTTL)
+	}
+
+	requeuer := requeue.New(dyn, pluralizer.GVKtoGVR, log)
+	handler = restResources.New(restResources.Options{
+		Config:            cfg,
+		Logger:            log,
+		SwaggerInfoGetter: swg,
+		Pluralizer:        *pluralizer,
+		Discovery:         cachedDisc,
+		AuditSink:         auditSink,
+		HotLoop:           hotLoop,
+		Conditions:        conditions,
+		ResyncInterval:    *resyncInterval,
+		ResyncJitter:      *resyncJitter,
+		Requeue:           requeuer,
+		AuthStatus:        authStatus,
+		SecretRotation:    rotationWatcher,
+		Summaries:         summaries,
+		StatusPhase:       *statusPhase,
+		StatusSchema:      statusSchema,
+		HealthProbe:       healthProbe,
+		Provenance:        stamper,
+		CallBudget:        budget,
+		Capabilities:      capabilities,
+		ClusterName:       *clusterName,
+	})
+	// The orphans are scanned by a single replica, the one of the first shard
+	var orphanScanner *restResources.OrphanScanner
+	if *orphanScanInterval > 0 && sh.Index == 0 {
+		orphanScanner = restResources.NewOrphanScanner(restResources.OrphanScanOptions{
+			DynamicClient:     dyn,
+			SwaggerInfoGetter: swg,
+			GVR:               gvr,
+			Namespace:         *namespace,
+			ClusterName:       *clusterName,
+			Interval:          *orphanScanInterval,
+			Delete:            *orphanScanDelete,
+			AuditSink:         auditSink,
+			Provenance:        stamper,
+			CallBudget:        budget,
+			Logger:            log,
+		})
+		orphanScanner.Register(registry)
+	}
+
+	handler = shard.Filter(handler, sh)
+	drainer := shutdown.New(handler)
+
+	// The informer cache only needs the spec and the deletion timestamp to enqueue the resources,
+	// which are reconciled from their latest version got through the dynamic client
+	cacheClient := transform.Client(dyn, transform.Options{
+		StripManagedFields: *cacheStripManagedFields,
+		MaxAnnotationSize:  *cacheMaxAnnotationSize,
+	})
 
 	controller := genctrl.New(genctrl.Options{
 		Discovery:      cachedDisc,
-		Client:         dyn,
+		Client:         cacheClient,
 		ResyncInterval: *resyncInterval,
-		GVR: schema.GroupVersionResource{
-			Group:    *resourceGroup,
-			Version:  *resourceVersion,
-			Resource: *resourceName,
-		},
-		Namespace:    *namespace,
-		Config:       cfg,
-		Debug:        *debug,
-		Logger:       log,
-		ProviderName: serviceName,
-		ListWatcher:  controller.ListWatcherConfiguration{},
-		Pluralizer:   *pluralizer,
+		GVR:            gvr,
+		Namespace:      *namespace,
+		Config:         cfg,
+		Debug:          *debug,
+		Logger:         log,
+		ProviderName:   serviceName,
+		ListWatcher:    listWatcher,
+		Pluralizer:     *pluralizer,
 	})
-	controller.SetExternalClient(handler)
+	controller.SetExternalClient(drainer)
 
+	// SIGKILL cannot be caught, the pod termination grace period must exceed the shutdown timeout
 	ctx, cancel := signal.NotifyContext(context.Background(), []os.Signal{
 		os.Interrupt,
-		syscall.SIGINT,
 		syscall.SIGTERM,
-		syscall.SIGKILL,
 		syscall.SIGHUP,
 		syscall.SIGQUIT,
 	}...)
 	defer cancel()
 
-	err = controller.Run(ctx, *workers)
+	profiling.Serve(ctx, log, *pprofAddress)
+	metrics.Serve(ctx, log, *metricsAddress, registry)
+	go healthProbe.Run(ctx)
+	go orphanScanner.Run(ctx)
+
+	// Picking up the CRDs installed or changed meanwhile
+	go gvrcache.InvalidateEvery(ctx, *discoveryCacheTTL, cachedDisc, plurals)
+
+	// The controller is stopped only once the reconciles in progress are drained,
+	// so that their external calls and status updates are not interrupted halfway
+	runCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	done := make(chan error, 1)
+	go func() {
+		done <- controller.Run(runCtx, *workers)
+	}()
+	go requeuer.Run(runCtx, drainer)
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		log.Info("Shutting down.", "timeout", *shutdownTimeout)
+		if !drainer.Drain(*shutdownTimeout) {
+			log.Info("Shutdown timed out, in-flight reconciles cancelled.")
+		}
+		stop()
+		err = <-done
+	}
 	if err != nil {
 		log.Debug("Running controller.", "error", err)
 	}
 }
+
+// export writes the manifests of the external resources of the kind, returning the exit code.
+func export(log logging.Logger, dyn dynamic.Interface, disc discovery.CachedDiscoveryInterface, swg getter.Getter, gvr schema.GroupVersionResource, namespace, spec, output string) int {
+	if namespace == "" {
+		log.Info("Exporting the external resources failed.", "error", "the namespace of the manifests must be specified")
+		return 1
+	}
+	gvk, err := restmapper.NewDeferredDiscoveryRESTMapper(disc).KindFor(gvr)
+	if err != nil {
+		log.Info("Exporting the external resources failed.", "error", fmt.Sprintf("resolving kind of %s: %v", gvr, err))
+		return 1
+	}
+	var specFields map[string]interface{}
+	if err := yaml.Unmarshal([]byte(spec), &specFields); err != nil {
+		log.Info("Exporting the external resources failed.", "error", fmt.Sprintf("parsing the spec: %v", err))
+		return 1
+	}
+
+	out := os.Stdout
+	if output != "" {
+		out, err = os.Create(output)
+		if err != nil {
+			log.Info("Exporting the external resources failed.", "error", err.Error())
+			return 1
+		}
+		defer out.Close()
+	}
+	n, err := restResources.Export(context.Background(), restResources.ExportOptions{
+		DynamicClient:     dyn,
+		SwaggerInfoGetter: swg,
+		GVK:               gvk,
+		Namespace:         namespace,
+		Spec:              specFields,
+		Output:            out,
+		Logger:            log,
+	})
+	if err != nil {
+		log.Info("Exporting the external resources failed.", "exported", n, "error", err.Error())
+		return 1
+	}
+	log.Info("Exported the external resources.", "exported", n, "kind", gvk.Kind)
+	return 0
+}
@@ -0,0 +1,314 @@
+// Package conformance runs the lifecycle of a custom resource against the handler of the rest-dynamic-controller,
+// reporting which capabilities the REST API of a provider supports.
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/controller"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// Capability is a step of the lifecycle of a resource.
+type Capability string
+
+const (
+	CapabilityCreate  Capability = "create"
+	CapabilityObserve Capability = "observe"
+	CapabilityUpdate  Capability = "update"
+	CapabilityDrift   Capability = "drift"
+	CapabilityAdopt   Capability = "adopt-existing"
+	CapabilityAsync   Capability = "async"
+	CapabilityDelete  Capability = "delete"
+)
+
+// Status is the outcome of a capability check.
+type Status string
+
+const (
+	StatusPassed  Status = "passed"
+	StatusFailed  Status = "failed"
+	StatusSkipped Status = "skipped"
+)
+
+const (
+	defaultTimeout  = 2 * time.Minute
+	defaultInterval = 5 * time.Second
+)
+
+// Suite describes the lifecycle run against a resource.
+type Suite struct {
+	// Client is the handler under test, e.g. the one returned by restresources.New.
+	Client controller.ExternalClient
+	// Resource is the custom resource driven through the lifecycle, it must exist in the cluster.
+	Resource *unstructured.Unstructured
+	// Refresh returns the latest version of the resource from the cluster, optional.
+	Refresh func(ctx context.Context, mg *unstructured.Unstructured) (*unstructured.Unstructured, error)
+	// Mutate changes the spec of the resource to check the update, optional.
+	// It must store the change in the cluster when Refresh is set.
+	Mutate func(ctx context.Context, mg *unstructured.Unstructured) (*unstructured.Unstructured, error)
+	// Drift changes the external resource out of band to check the drift detection, optional.
+	Drift func(ctx context.Context) error
+	// Adopt returns a new custom resource, existing in the cluster, matching the external resource
+	// created by the suite to check its adoption, optional.
+	Adopt func(ctx context.Context, mg *unstructured.Unstructured) (*unstructured.Unstructured, error)
+	// Timeout is the time waited for the external resource to converge after each mutation.
+	Timeout time.Duration
+	// Interval is the time between two observations while waiting.
+	Interval time.Duration
+}
+
+// Result is the outcome of the check of a capability.
+type Result struct {
+	Capability Capability
+	Status     Status
+	Message    string
+	Duration   time.Duration
+}
+
+// Report collects the results of a run.
+type Report struct {
+	Results []Result
+}
+
+// Supported returns true if the capability check passed.
+func (r *Report) Supported(c Capability) bool {
+	for _, res := range r.Results {
+		if res.Capability == c {
+			return res.Status == StatusPassed
+		}
+	}
+	return false
+}
+
+// Failed returns the results of the failed checks.
+func (r *Report) Failed() []Result {
+	var failed []Result
+	for _, res := range r.Results {
+		if res.Status == StatusFailed {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// String returns the report as a table.
+func (r *Report) String() string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CAPABILITY\tSTATUS\tDURATION\tMESSAGE")
+	for _, res := range r.Results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", res.Capability, res.Status, res.Duration.Round(time.Millisecond), res.Message)
+	}
+	w.Flush()
+	return sb.String()
+}
+
+func (r *Report) add(c Capability, start time.Time, err error) {
+	res := Result{Capability: c, Status: StatusPassed, Duration: time.Since(start)}
+	if err != nil {
+		res.Status = StatusFailed
+		res.Message = err.Error()
+	}
+	r.Results = append(r.Results, res)
+}
+
+func (r *Report) skip(c Capability, msg string) {
+	r.Results = append(r.Results, Result{Capability: c, Status: StatusSkipped, Message: msg})
+}
+
+// LoadResource loads the custom resource from the manifest at the given path.
+func LoadResource(path string) (*unstructured.Unstructured, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	obj := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("decoding manifest %s: %w", path, err)
+	}
+	return &unstructured.Unstructured{Object: obj}, nil
+}
+
+// Run drives the resource through the lifecycle, creating, observing, updating and deleting the external resource.
+// The checks depending on a failed one are reported as skipped.
+func (s *Suite) Run(ctx context.Context) *Report {
+	report := &Report{}
+	mg := s.Resource.DeepCopy()
+
+	start := time.Now()
+	obs, err := s.observe(ctx, mg)
+	if err == nil && obs.ResourceExists {
+		err = fmt.Errorf("external resource already exists before the creation")
+	}
+	if err != nil {
+		report.add(CapabilityCreate, start, err)
+		s.skipAll(report, "creation failed", CapabilityObserve, CapabilityUpdate, CapabilityDrift, CapabilityAdopt, CapabilityAsync, CapabilityDelete)
+		return report
+	}
+
+	mg, err = s.refresh(ctx, mg)
+	if err == nil {
+		err = s.Client.Create(ctx, mg)
+	}
+	var asyncCreate bool
+	if err == nil {
+		mg, asyncCreate, err = s.waitFor(ctx, mg, func(obs controller.ExternalObservation) bool {
+			return obs.ResourceExists
+		})
+	}
+	report.add(CapabilityCreate, start, err)
+	if err != nil {
+		s.skipAll(report, "creation failed", CapabilityObserve, CapabilityUpdate, CapabilityDrift, CapabilityAdopt, CapabilityAsync, CapabilityDelete)
+		return report
+	}
+
+	start = time.Now()
+	mg, _, err = s.waitFor(ctx, mg, func(obs controller.ExternalObservation) bool {
+		return obs.ResourceExists && obs.ResourceUpToDate
+	})
+	report.add(CapabilityObserve, start, err)
+
+	if s.Mutate == nil {
+		report.skip(CapabilityUpdate, "no mutation configured")
+	} else {
+		start = time.Now()
+		mg, err = s.update(ctx, mg, s.Mutate)
+		report.add(CapabilityUpdate, start, err)
+	}
+
+	if s.Drift == nil {
+		report.skip(CapabilityDrift, "no drift configured")
+	} else {
+		start = time.Now()
+		mg, err = s.update(ctx, mg, func(ctx context.Context, mg *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+			return mg, s.Drift(ctx)
+		})
+		report.add(CapabilityDrift, start, err)
+	}
+
+	if s.Adopt == nil {
+		report.skip(CapabilityAdopt, "no adoption configured")
+	} else {
+		start = time.Now()
+		adopted, err := s.Adopt(ctx, mg.DeepCopy())
+		if err == nil {
+			_, _, err = s.waitFor(ctx, adopted, func(obs controller.ExternalObservation) bool {
+				return obs.ResourceExists
+			})
+		}
+		report.add(CapabilityAdopt, start, err)
+	}
+
+	start = time.Now()
+	mg, err = s.refresh(ctx, mg)
+	if err == nil {
+		err = s.Client.Delete(ctx, mg)
+	}
+	var asyncDelete bool
+	if err == nil {
+		_, asyncDelete, err = s.waitFor(ctx, mg, func(obs controller.ExternalObservation) bool {
+			return !obs.ResourceExists
+		})
+	}
+	report.add(CapabilityDelete, start, err)
+
+	if asyncCreate || asyncDelete {
+		report.Results = append(report.Results, Result{Capability: CapabilityAsync, Status: StatusPassed,
+			Message: "external resource converged after the request completed"})
+	} else {
+		report.skip(CapabilityAsync, "the API completed every request synchronously")
+	}
+
+	return report
+}
+
+// update applies the change, checks that the resource is reported as not up-to-date
+// and that it converges after the update.
+func (s *Suite) update(ctx context.Context, mg *unstructured.Unstructured, change func(context.Context, *unstructured.Unstructured) (*unstructured.Unstructured, error)) (*unstructured.Unstructured, error) {
+	mg, err := s.refresh(ctx, mg)
+	if err != nil {
+		return mg, err
+	}
+	mg, err = change(ctx, mg)
+	if err != nil {
+		return mg, err
+	}
+	mg, _, err = s.waitFor(ctx, mg, func(obs controller.ExternalObservation) bool {
+		return obs.ResourceExists && !obs.ResourceUpToDate
+	})
+	if err != nil {
+		return mg, fmt.Errorf("change not detected: %w", err)
+	}
+	if err := s.Client.Update(ctx, mg); err != nil {
+		return mg, err
+	}
+	mg, _, err = s.waitFor(ctx, mg, func(obs controller.ExternalObservation) bool {
+		return obs.ResourceExists && obs.ResourceUpToDate
+	})
+	return mg, err
+}
+
+// waitFor observes the resource until the condition is met, returning whether more than one observation was needed.
+func (s *Suite) waitFor(ctx context.Context, mg *unstructured.Unstructured, cond func(controller.ExternalObservation) bool) (*unstructured.Unstructured, bool, error) {
+	timeout, interval := s.Timeout, s.Interval
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var last controller.ExternalObservation
+	for attempt := 0; ; attempt++ {
+		var err error
+		mg, err = s.refresh(ctx, mg)
+		if err != nil {
+			return mg, attempt > 0, err
+		}
+		last, err = s.observe(ctx, mg)
+		if err != nil {
+			return mg, attempt > 0, err
+		}
+		if cond(last) {
+			return mg, attempt > 0, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return mg, attempt > 0, fmt.Errorf("timed out after %s (exists: %t, up-to-date: %t)", timeout, last.ResourceExists, last.ResourceUpToDate)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// observe calls the handler Observe, which reports the resources not up-to-date with a not found error.
+func (s *Suite) observe(ctx context.Context, mg *unstructured.Unstructured) (controller.ExternalObservation, error) {
+	obs, err := s.Client.Observe(ctx, mg)
+	if apierrors.IsNotFound(err) {
+		return obs, nil
+	}
+	return obs, err
+}
+
+func (s *Suite) refresh(ctx context.Context, mg *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	if s.Refresh == nil {
+		return mg, nil
+	}
+	return s.Refresh(ctx, mg)
+}
+
+func (s *Suite) skipAll(report *Report, msg string, caps ...Capability) {
+	for _, c := range caps {
+		report.skip(c, msg)
+	}
+}
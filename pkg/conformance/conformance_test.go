@@ -0,0 +1,118 @@
+package conformance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/krateoplatformops/unstructured-runtime/pkg/controller"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// fakeClient simulates an API completing the creation after a number of observations.
+type fakeClient struct {
+	remote  map[string]interface{}
+	pending int
+}
+
+func (c *fakeClient) Observe(_ context.Context, mg *unstructured.Unstructured) (controller.ExternalObservation, error) {
+	if c.remote == nil {
+		return controller.ExternalObservation{}, nil
+	}
+	if c.pending > 0 {
+		c.pending--
+		return controller.ExternalObservation{}, nil
+	}
+	spec, _, _ := unstructured.NestedString(mg.Object, "spec", "description")
+	if spec != c.remote["description"] {
+		return controller.ExternalObservation{ResourceExists: true},
+			apierrors.NewNotFound(schema.GroupResource{Resource: "repos"}, mg.GetName())
+	}
+	return controller.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+}
+
+func (c *fakeClient) Create(_ context.Context, mg *unstructured.Unstructured) error {
+	spec, _, _ := unstructured.NestedString(mg.Object, "spec", "description")
+	c.remote = map[string]interface{}{"description": spec}
+	c.pending = 1
+	return nil
+}
+
+func (c *fakeClient) Update(_ context.Context, mg *unstructured.Unstructured) error {
+	spec, _, _ := unstructured.NestedString(mg.Object, "spec", "description")
+	c.remote["description"] = spec
+	return nil
+}
+
+func (c *fakeClient) Delete(_ context.Context, _ *unstructured.Unstructured) error {
+	c.remote = nil
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	mg, err := LoadResource("testdata/repo.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cli := &fakeClient{}
+	suite := &Suite{
+		Client:   cli,
+		Resource: mg,
+		Mutate: func(_ context.Context, mg *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+			return mg, unstructured.SetNestedField(mg.Object, "updated", "spec", "description")
+		},
+		Drift: func(context.Context) error {
+			cli.remote["description"] = "changed out of band"
+			return nil
+		},
+		Timeout:  time.Second,
+		Interval: time.Millisecond,
+	}
+
+	report := suite.Run(context.Background())
+	if failed := report.Failed(); len(failed) > 0 {
+		t.Fatalf("unexpected failures:\n%s", report)
+	}
+
+	for _, c := range []Capability{CapabilityCreate, CapabilityObserve, CapabilityUpdate, CapabilityDrift, CapabilityAsync, CapabilityDelete} {
+		if !report.Supported(c) {
+			t.Errorf("expected %s to be supported:\n%s", c, report)
+		}
+	}
+	if report.Supported(CapabilityAdopt) {
+		t.Errorf("expected %s to be skipped", CapabilityAdopt)
+	}
+	if cli.remote != nil {
+		t.Errorf("expected the external resource to be deleted")
+	}
+}
+
+func TestRunUndetectedChange(t *testing.T) {
+	mg, err := LoadResource("testdata/repo.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cli := &fakeClient{}
+	suite := &Suite{
+		Client:   cli,
+		Resource: mg,
+		Drift: func(context.Context) error {
+			return nil
+		},
+		Timeout:  10 * time.Millisecond,
+		Interval: time.Millisecond,
+	}
+
+	report := suite.Run(context.Background())
+	failed := report.Failed()
+	if len(failed) != 1 || failed[0].Capability != CapabilityDrift {
+		t.Fatalf("expected only the drift check to fail:\n%s", report)
+	}
+	if !report.Supported(CapabilityDelete) {
+		t.Errorf("expected the external resource to be deleted:\n%s", report)
+	}
+}
@@ -0,0 +1,239 @@
+// Package restresources exposes the REST reconciliation logic of the rest-dynamic-controller,
+// allowing other components to embed it programmatically instead of running the standalone binary.
+package restresources
+
+import (
+	"context"
+	"time"
+
+	restclient "github.com/krateoplatformops/rest-dynamic-controller/internal/client"
+	restResources "github.com/krateoplatformops/rest-dynamic-controller/internal/restResources"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/audit"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/authstatus"
+	customcondition "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/condition"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/gvrcache"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/healthprobe"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/hotloop"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/logsampling"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/metrics"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/provenance"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/quota"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/requeue"
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/rotation"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/shutdown"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/statusschema"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/transform"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/vault"
+	"github.com/krateoplatformops/rest-dynamic-controller/internal/tools/workloadidentity"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/controller"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+type (
+	// Options configures the handler reconciling the custom resources with the external REST API.
+	Options = restResources.Options
+	// Getter resolves the RestDefinition info of the custom resources.
+	Getter = getter.Getter
+	// Info is the RestDefinition info of a custom resource.
+	Info = getter.Info
+	// Client is the REST client built from an OAS document.
+	Client = restclient.UnstructuredClient
+	// AuditSink records the mutations of the external resources.
+	AuditSink = audit.Sink
+	// AuditOptions configures the audit sink.
+	AuditOptions = audit.Options
+	// HotLoopDetector detects the update loops.
+	HotLoopDetector = hotloop.Detector
+	// ConditionVocabulary maps the conditions to a custom vocabulary.
+	ConditionVocabulary = customcondition.Vocabulary
+	// CacheOptions configures the fields stripped from the resources held by the informer cache.
+	CacheOptions = transform.Options
+	// Drainer drains the reconciles in progress on shutdown.
+	Drainer = shutdown.Drainer
+	// Requeuer reconciles the resources again sooner than the resync interval.
+	Requeuer = requeue.Requeuer
+	// AuthStatusReporter reports the state of the credentials in the authentication objects.
+	AuthStatusReporter = authstatus.Reporter
+	// SecretRotationWatcher watches the secrets the credentials are read from.
+	SecretRotationWatcher = rotation.Watcher
+	// SummaryLevel selects the reconciles summarized at Info level.
+	SummaryLevel = restResources.SummaryLevel
+	// StatusSchemaReader reads the status schemas of the CRDs.
+	StatusSchemaReader = statusschema.Reader
+	// GetterOptions configures the getter resolving the RestDefinition info from the cluster.
+	GetterOptions = getter.DynamicOptions
+	// GVRCache caches the GVK to GVR resolutions of the pluralizer.
+	GVRCache = gvrcache.Cache
+	// HealthProber probes the servers of the external APIs.
+	HealthProber = healthprobe.Prober
+	// MetricsRegistry collects the metrics of the controller.
+	MetricsRegistry = metrics.Registry
+	// ServiceAccount identifies the ServiceAccount whose tokens are exchanged for the API tokens of the workload identities.
+	ServiceAccount = workloadidentity.ServiceAccount
+	// VaultClient reads the credentials referenced by a vaultRef from HashiCorp Vault.
+	VaultClient = vault.Client
+	// ProvenanceStamper stamps the mutations of the external resources with the provenance headers.
+	ProvenanceStamper = provenance.Stamper
+	// CallBudget limits the external calls made for each resource within a window.
+	CallBudget = quota.Budget
+	// CapabilityCache caches the methods allowed by the APIs, probed before the updates.
+	CapabilityCache = restclient.CapabilityCache
+	// OrphanScanOptions configures the scan of the external resources left behind by the deleted custom resources.
+	OrphanScanOptions = restResources.OrphanScanOptions
+	// OrphanScanner reports or deletes the external resources left behind by the deleted custom resources.
+	OrphanScanner = restResources.OrphanScanner
+	// Orphan is an external resource whose ownership markers name a custom resource which no longer exists.
+	Orphan = restResources.Orphan
+)
+
+const (
+	// SummaryNone disables the reconcile summaries.
+	SummaryNone = restResources.SummaryNone
+	// SummaryChanges summarizes the mutations of the external resources and the failed observations.
+	SummaryChanges = restResources.SummaryChanges
+	// SummaryAll summarizes every reconcile.
+	SummaryAll = restResources.SummaryAll
+)
+
+// New returns the handler reconciling the custom resources with the external REST API,
+// to be registered as the external client of an unstructured-runtime controller.
+func New(opts Options) controller.ExternalClient {
+	return restResources.New(opts)
+}
+
+// NewGetter returns the getter resolving the RestDefinition info from the cluster.
+func NewGetter(cfg *rest.Config) (Getter, error) {
+	return getter.Dynamic(cfg)
+}
+
+// NewGetterWithOptions returns the getter resolving the RestDefinition info from the cluster with the given options
+// (e.g. caching the RestDefinitions, or sharing the ones of a namespace).
+func NewGetterWithOptions(cfg *rest.Config, opts GetterOptions) (Getter, error) {
+	return getter.NewDynamic(cfg, opts)
+}
+
+// NewCachedGetter returns the getter resolving the RestDefinition info from the cluster, caching the RestDefinitions
+// and watching them for changes until the context is done.
+func NewCachedGetter(ctx context.Context, cfg *rest.Config, log logging.Logger) (Getter, error) {
+	return getter.CachedDynamic(ctx, cfg, log)
+}
+
+// NewClient returns the REST client for the OAS document at the given path.
+func NewClient(ctx context.Context, dyn dynamic.Interface, oasPath string) (*Client, error) {
+	return restclient.BuildClient(ctx, dyn, oasPath)
+}
+
+// NewAuditSink returns the audit sink described by the options, nil if the audit is disabled.
+func NewAuditSink(opts AuditOptions) (AuditSink, error) {
+	return audit.New(opts)
+}
+
+// NewHotLoopDetector returns the detector flagging the resources updated at least threshold times within the window,
+// skipping their updates for the cooldown period.
+func NewHotLoopDetector(window time.Duration, threshold int, cooldown time.Duration) *HotLoopDetector {
+	return hotloop.New(window, threshold, cooldown)
+}
+
+// NewVaultClient returns the client reading the credentials from the Vault server at the given default address.
+func NewVaultClient(address string) *VaultClient {
+	return vault.New(address)
+}
+
+// NewProvenanceStamper returns the stamper of the mutations made from the given cluster, signed with the secret if not empty.
+func NewProvenanceStamper(clusterName string, secret []byte) *ProvenanceStamper {
+	return provenance.New(clusterName, secret)
+}
+
+// NewCallBudget returns the budget allowing limit external calls per resource within the window (0 for unlimited,
+// unless the krateo.io/call-budget annotation of the resource tells another limit).
+func NewCallBudget(limit int, window time.Duration) *CallBudget {
+	return quota.New(limit, window)
+}
+
+// NewCapabilityCache returns the cache of the methods allowed by the APIs, probed again once older than ttl.
+func NewCapabilityCache(ttl time.Duration) *CapabilityCache {
+	return restclient.NewCapabilityCache(ttl)
+}
+
+// NewOrphanScanner returns the scanner of the external resources left behind by the deleted custom resources,
+// to be run in background with its Run method.
+func NewOrphanScanner(opts OrphanScanOptions) *OrphanScanner {
+	return restResources.NewOrphanScanner(opts)
+}
+
+// LoadConditionVocabulary loads the condition vocabulary from the file at the given path.
+func LoadConditionVocabulary(path string) (*ConditionVocabulary, error) {
+	return customcondition.LoadVocabulary(path)
+}
+
+// NewCacheClient wraps the dynamic client given to the controller so that the resources it lists and watches,
+// held by the informer cache, are stripped of the fields configured by the options.
+func NewCacheClient(dyn dynamic.Interface, opts CacheOptions) dynamic.Interface {
+	return transform.Client(dyn, opts)
+}
+
+// NewDrainer wraps the handler so that the reconciles in progress can be drained on shutdown,
+// running them with a context cancelled only when the drain times out.
+func NewDrainer(handler controller.ExternalClient) *Drainer {
+	return shutdown.New(handler)
+}
+
+// NewRequeuer returns the requeuer getting the resources through the dynamic client, resolving their GVR with
+// the given function (e.g. the GVKtoGVR method of the pluralizer); set in the options, it makes the handler
+// reconcile the resources again sooner than the resync interval. Run it in background with the external client
+// registered in the controller.
+func NewRequeuer(dyn dynamic.Interface, resolve func(schema.GroupVersionKind) (schema.GroupVersionResource, error), log logging.Logger) *Requeuer {
+	return requeue.New(dyn, resolve, log)
+}
+
+// NewAuthStatusReporter returns the reporter writing the state of the credentials in the status of the
+// authentication objects, reporting an unchanged state at most once per interval.
+func NewAuthStatusReporter(dyn dynamic.Interface, log logging.Logger, interval time.Duration) *AuthStatusReporter {
+	return authstatus.New(dyn, log, interval)
+}
+
+// NewSecretRotationWatcher returns the watcher of the secrets the credentials are read from, whose informers
+// run until the context is done; set in the options, it makes the handler reconcile the resources as soon as
+// their secrets are rotated.
+func NewSecretRotationWatcher(ctx context.Context, dyn dynamic.Interface, log logging.Logger) *SecretRotationWatcher {
+	return rotation.New(ctx, dyn, log)
+}
+
+// NewSampledLogger wraps the logger so that at most burst identical messages are logged per window,
+// reporting the number of the ones dropped with the first message of the next window.
+func NewSampledLogger(log logging.Logger, window time.Duration, burst int) logging.Logger {
+	return logsampling.New(log, window, burst)
+}
+
+// NewStatusSchemaReader returns the reader of the status schemas of the CRDs, read again every interval; set in the
+// options, it makes the handler store the status fields not allowed by the schemas in an annotation.
+func NewStatusSchemaReader(dyn dynamic.Interface, log logging.Logger, interval time.Duration) *StatusSchemaReader {
+	return statusschema.New(dyn, log, interval)
+}
+
+// NewGVRCache returns the cache of the GVK to GVR resolutions, expiring after the ttl; the pluralizer
+// set in the options resolves through its client.
+func NewGVRCache(ttl time.Duration) *GVRCache {
+	return gvrcache.New(nil, ttl)
+}
+
+// NewHealthProber returns the prober of the servers of the external APIs, probed at every interval within the timeout;
+// set in the options, it makes the handler attribute the failures of the resources to the outage of their server.
+// Run it in background and register its results in the metrics registry, if any.
+func NewHealthProber(log logging.Logger, interval, timeout time.Duration) *HealthProber {
+	return healthprobe.New(log, interval, timeout)
+}
+
+// NewMetricsRegistry returns the registry of the metrics of the controller, served in the Prometheus text format by its handler.
+func NewMetricsRegistry() *MetricsRegistry {
+	return metrics.NewRegistry()
+}
+
+// RegisterOASCacheMetrics registers the hits and the misses of the cache of the OAS documents the clients are built from.
+func RegisterOASCacheMetrics(r *MetricsRegistry) {
+	restclient.RegisterDocumentMetrics(r)
+}
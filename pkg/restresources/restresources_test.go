@@ -0,0 +1,152 @@
+package restresources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	getter "github.com/krateoplatformops/rest-dynamic-controller/internal/tools/restclient"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/logging"
+	"github.com/krateoplatformops/unstructured-runtime/pkg/pluralizer"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+)
+
+const reposOAS = `openapi: 3.0.0
+info:
+  title: repos
+  version: "1.0"
+servers:
+  - url: %s
+paths:
+  /repos/{id}:
+    get:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  id:
+                    type: string
+                  name:
+                    type: string
+`
+
+type staticGetter struct {
+	info *Info
+	err  error
+}
+
+func (g staticGetter) Get(*unstructured.Unstructured) (*Info, error) {
+	return g.info, g.err
+}
+
+// server serves the OAS document, the repos API and, echoing the objects written, the Kubernetes API;
+// it returns the Kubernetes API writes.
+func server(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	writes := &[]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/openapi.yaml":
+			fmt.Fprintf(w, reposOAS, "http://"+r.Host)
+		case r.URL.Path == "/plurals":
+			fmt.Fprint(w, `{"plural":"repos","singular":"repo"}`)
+		case r.URL.Path == "/repos/42":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"id":"42","name":"repo1"}`)
+		case strings.HasPrefix(r.URL.Path, "/api"):
+			mu.Lock()
+			*writes = append(*writes, r.Method+" "+r.URL.Path)
+			mu.Unlock()
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(body)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, writes
+}
+
+func repo() *unstructured.Unstructured {
+	mg := &unstructured.Unstructured{}
+	mg.SetAPIVersion("gen.github.com/v1alpha1")
+	mg.SetKind("Repo")
+	mg.SetNamespace("default")
+	mg.SetName("repo1")
+	mg.SetGeneration(1)
+	_ = unstructured.SetNestedField(mg.Object, "repo1", "spec", "name")
+	_ = unstructured.SetNestedField(mg.Object, "42", "status", "id")
+	return mg
+}
+
+func TestNew(t *testing.T) {
+	srv, writes := server(t)
+	plurals := srv.URL + "/plurals"
+
+	h := New(Options{
+		Config: &rest.Config{Host: srv.URL},
+		Logger: logging.NewNopLogger(),
+		SwaggerInfoGetter: staticGetter{info: &Info{
+			URL: srv.URL + "/openapi.yaml",
+			Resource: getter.Resource{
+				Identifiers:      []string{"id"},
+				VerbsDescription: []getter.VerbsDescription{{Action: "get", Method: "GET", Path: "/repos/{id}"}},
+			},
+		}},
+		Pluralizer: *pluralizer.New(&plurals, srv.Client()),
+	})
+
+	obs, err := h.Observe(context.Background(), repo())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !obs.ResourceExists || !obs.ResourceUpToDate {
+		t.Errorf("expected the resource to exist and be up-to-date, got %+v", obs)
+	}
+	// The status is written through the cluster of the configuration
+	expected := "PUT /apis/gen.github.com/v1alpha1/namespaces/default/repos/repo1/status"
+	found := false
+	for _, w := range *writes {
+		found = found || w == expected
+	}
+	if !found {
+		t.Errorf("expected %q, got %v", expected, *writes)
+	}
+}
+
+func TestNewGetterError(t *testing.T) {
+	srv, _ := server(t)
+	plurals := srv.URL + "/plurals"
+	getterErr := errors.New("restdefinition not found")
+
+	h := New(Options{
+		Config:            &rest.Config{Host: srv.URL},
+		Logger:            logging.NewNopLogger(),
+		SwaggerInfoGetter: staticGetter{err: getterErr},
+		Pluralizer:        *pluralizer.New(&plurals, srv.Client()),
+	})
+
+	_, err := h.Observe(context.Background(), repo())
+	if !errors.Is(err, getterErr) {
+		t.Errorf("expected the getter error, got %v", err)
+	}
+}